@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/actiontoken"
+	handler "github.com/MezeLaw/iris-services/internal/handler/appointments"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/notify"
+	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	waitlistRepository "github.com/MezeLaw/iris-services/internal/repository/waitlist"
+	service "github.com/MezeLaw/iris-services/internal/service/appointments"
+	waitlistService "github.com/MezeLaw/iris-services/internal/service/waitlist"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// page es lo que ve el paciente. El GET sólo muestra el turno con un botón:
+// los previsualizadores de enlaces de SMS y WhatsApp abren los links, y si el
+// GET aplicara la acción la consumirían antes que el paciente.
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="es"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Turno</title></head><body>
+<p>{{.Message}}</p>
+{{if .Token}}<form method="post"><input type="hidden" name="token" value="{{.Token}}"><button type="submit">{{.Button}}</button></form>{{end}}
+</body></html>`))
+
+type view struct {
+	Message string
+	Token   string
+	Button  string
+}
+
+var verbs = map[string]string{actiontoken.ActionConfirm: "confirmar", actiontoken.ActionCancel: "cancelar"}
+
+// Endpoint público de los enlaces de los recordatorios. No requiere
+// autenticación: el token firmado identifica el turno y la acción.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	secret := os.Getenv("ACTION_TOKEN_SECRET")
+	if secret == "" {
+		sugar.Fatal("ACTION_TOKEN_SECRET is required")
+	}
+	cutoffs, err := service.ParseCancellationCutoffs(os.Getenv("CANCELLATION_CUTOFFS"))
+	if err != nil {
+		sugar.Fatalf("error loading CANCELLATION_CUTOFFS: %v", err)
+	}
+	defaultCutoff := 24 * time.Hour
+	if raw := os.Getenv("CANCELLATION_CUTOFF"); raw != "" {
+		if defaultCutoff, err = time.ParseDuration(raw); err != nil {
+			sugar.Fatalf("invalid CANCELLATION_CUTOFF: %v", err)
+		}
+	}
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	// Con WAITLIST_CONFIG los turnos cancelados se ofrecen a la lista de espera
+	var waitlist service.WaitlistOfferer
+	if raw := os.Getenv("WAITLIST_CONFIG"); raw != "" {
+		waitlistConfig, err := waitlistService.ParseConfig(raw)
+		if err != nil {
+			sugar.Fatalf("error loading WAITLIST_CONFIG: %v", err)
+		}
+		waitlistRepo := waitlistRepository.New(dynamoClient, sugar, "WaitlistTable", "doctor_id_index", "WaitlistOffersTable", "status_index")
+		waitlist = waitlistService.New(sugar, waitlistRepo, repo, nil, nil, patientsRepo, notify.FromEnv(cfg, sugar), waitlistConfig)
+	}
+	svc := service.NewWithOptions(sugar, repo, service.Options{Metrics: m, Waitlist: waitlist, Events: repo, Actions: &service.Actions{
+		Signer:                    actiontoken.NewSigner([]byte(secret)),
+		Tokens:                    repo,
+		CancellationCutoffs:       cutoffs,
+		DefaultCancellationCutoff: defaultCutoff,
+	}})
+	h := handler.New(svc, sugar)
+
+	// Un mismo Lambda atiende las dos operaciones; cada una tiene su span, su logger y sus métricas
+	apply := tracing.Wrap("applyAppointmentAction", logging.Wrap(sugar, "applyAppointmentAction", metrics.Wrap(m, "applyAppointmentAction", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		token := formToken(req)
+		result, err := h.ApplyAction(ctx, token)
+		if err != nil {
+			return errorPage(err), nil
+		}
+		message := "Tu turno del " + formatDate(result.Date) + " quedó confirmado. ¡Te esperamos!"
+		if result.Action == actiontoken.ActionCancel {
+			message = "Tu turno del " + formatDate(result.Date) + " quedó cancelado."
+		}
+		return render(200, view{Message: message}), nil
+	})))
+	show := tracing.Wrap("showAppointmentAction", logging.Wrap(sugar, "showAppointmentAction", metrics.Wrap(m, "showAppointmentAction", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		token := req.QueryStringParameters["token"]
+		result, err := h.CheckAction(ctx, token)
+		if err != nil {
+			return errorPage(err), nil
+		}
+		verb := verbs[result.Action]
+		return render(200, view{
+			Message: "¿Querés " + verb + " tu turno del " + formatDate(result.Date) + "?",
+			Token:   token,
+			Button:  "Sí, " + verb,
+		}), nil
+	})))
+
+	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if req.HTTPMethod == "POST" {
+			return apply(ctx, req)
+		}
+		return show(ctx, req)
+	})
+}
+
+func formToken(req events.APIGatewayProxyRequest) string {
+	body := req.Body
+	if req.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return ""
+		}
+		body = string(decoded)
+	}
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return ""
+	}
+	return values.Get("token")
+}
+
+func errorPage(err error) events.APIGatewayProxyResponse {
+	switch {
+	case errors.Is(err, actiontoken.ErrExpired):
+		return render(410, view{Message: "El enlace venció."})
+	case errors.Is(err, actiontoken.ErrInvalid):
+		return render(400, view{Message: "El enlace no es válido."})
+	case errors.Is(err, service.ErrActionTokenUsed):
+		return render(409, view{Message: "Este enlace ya se usó."})
+	case errors.Is(err, service.ErrPatchConflict):
+		return render(409, view{Message: "El turno cambió mientras procesábamos el pedido. Probá de nuevo."})
+	case errors.Is(err, service.ErrActionNotAllowed):
+		return render(409, view{Message: "Este turno ya no se puede modificar desde el enlace. Comunicate con la clínica."})
+	default:
+		return render(500, view{Message: "No pudimos procesar el pedido. Probá de nuevo en unos minutos."})
+	}
+}
+
+func render(status int, v view) events.APIGatewayProxyResponse {
+	var body bytes.Buffer
+	_ = page.Execute(&body, v)
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Body:       body.String(),
+		Headers: map[string]string{
+			"Content-Type":  "text/html; charset=utf-8",
+			"Cache-Control": "no-store",
+		},
+	}
+}
+
+// formatDate muestra la fecha en el offset con que se guardó el turno.
+func formatDate(date string) string {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return date
+	}
+	return t.Format("02/01/2006 a las 15:04")
+}
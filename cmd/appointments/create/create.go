@@ -3,38 +3,69 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"time"
 
 	handler "github.com/MezeLaw/iris-services/internal/handler/appointments"
+	"github.com/MezeLaw/iris-services/internal/idempotency"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
 	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
 	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	slotholds "github.com/MezeLaw/iris-services/internal/repository/slotholds"
+	"github.com/MezeLaw/iris-services/internal/response"
 	service "github.com/MezeLaw/iris-services/internal/service/appointments"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"go.uber.org/zap"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
-	sugar := logger.Sugar()
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		sugar.Fatalf("error loading AWS config: %v", err)
 	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
 	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
 
-	repo := repository.New(dynamoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
-	svc := service.New(sugar, repo)
+	repo := repository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	noShows, err := service.NoShowsFromEnv(patientsRepo, repo)
+	if err != nil {
+		sugar.Fatalf("error loading no-show policy: %v", err)
+	}
+	// Las altas toman las franjas de la agenda igual que las reservas
+	holds, err := service.HoldsFromEnv(slotholds.New(repoClient, sugar, "SlotHoldsTable", "doctor_id_index", "AppointmentsTable", "OutboxTable"))
+	if err != nil {
+		sugar.Fatalf("error loading slot hold config: %v", err)
+	}
+	svc := service.NewWithOptions(sugar, repo, service.Options{NoShows: noShows, Holds: holds, Events: repo, Metrics: m})
 	h := handler.New(svc, sugar)
 
-	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	idempotent, err := idempotency.FromEnv(dynamoClient, sugar, "appointments/create")
+	if err != nil {
+		sugar.Fatalf("error loading idempotency config: %v", err)
+	}
+
+	lambda.Start(tracing.Wrap("createAppointment", logging.Wrap(sugar, "createAppointment", metrics.Wrap(m, "createAppointment", idempotent.Wrap(openapi.Validate("createAppointment", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		var request models.AppointmentRequest
 		if err := json.Unmarshal([]byte(req.Body), &request); err != nil {
-			sugar.Errorf("Error unmarshalling request: %v", err.Error())
-			return events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error":"invalid request body"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
 		}
 
 		now := time.Now().Format(time.RFC3339)
@@ -42,16 +73,14 @@ func main() {
 		request.UpdatedAt = now
 
 		created, err := h.Create(ctx, &request)
+		if errors.Is(err, service.ErrPatientBlocked) || errors.Is(err, service.ErrSlotHeld) {
+			return response.Error(req, 409, err.Error()), nil
+		}
 		if err != nil {
-			sugar.Errorf("Error creating appointment: %v", err.Error())
-			return events.APIGatewayProxyResponse{StatusCode: 500, Body: `{"error":"could not create appointment"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error creating appointment: %v", err.Error())
+			return response.Error(req, 500, "could not create appointment"), nil
 		}
 
-		respBody, _ := json.Marshal(created)
-		return events.APIGatewayProxyResponse{
-			StatusCode: 201,
-			Body:       string(respBody),
-			Headers:    map[string]string{"Content-Type": "application/json"},
-		}, nil
-	})
+		return response.Created(req, req.Path+"/"+created.ID, created), nil
+	}))))))
 }
@@ -2,47 +2,71 @@ package main
 
 import (
 	"context"
+	"log"
+	"os"
 
 	handler "github.com/MezeLaw/iris-services/internal/handler/appointments"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/notify"
 	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	waitlistRepository "github.com/MezeLaw/iris-services/internal/repository/waitlist"
+	"github.com/MezeLaw/iris-services/internal/response"
 	service "github.com/MezeLaw/iris-services/internal/service/appointments"
+	waitlistService "github.com/MezeLaw/iris-services/internal/service/waitlist"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"go.uber.org/zap"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
-	sugar := logger.Sugar()
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		sugar.Fatalf("error loading AWS config: %v", err)
 	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
 	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
 
-	repo := repository.New(dynamoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
-	svc := service.New(sugar, repo)
+	repo := repository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	// Con WAITLIST_CONFIG los turnos cancelados se ofrecen a la lista de espera
+	var waitlist service.WaitlistOfferer
+	if raw := os.Getenv("WAITLIST_CONFIG"); raw != "" {
+		waitlistConfig, err := waitlistService.ParseConfig(raw)
+		if err != nil {
+			sugar.Fatalf("error loading WAITLIST_CONFIG: %v", err)
+		}
+		waitlistRepo := waitlistRepository.New(dynamoClient, sugar, "WaitlistTable", "doctor_id_index", "WaitlistOffersTable", "status_index")
+		waitlist = waitlistService.New(sugar, waitlistRepo, repo, nil, nil, patientsRepo, notify.FromEnv(cfg, sugar), waitlistConfig)
+	}
+	svc := service.NewWithOptions(sugar, repo, service.Options{Waitlist: waitlist, Events: repo, Metrics: m})
 	h := handler.New(svc, sugar)
 
-	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	lambda.Start(tracing.Wrap("deleteAppointment", logging.Wrap(sugar, "deleteAppointment", metrics.Wrap(m, "deleteAppointment", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		appointmentID := req.PathParameters["id"]
 		if appointmentID == "" {
-			sugar.Error("Missing appointment ID in request")
-			return events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error":"missing appointment ID"}`}, nil
+			logging.FromContext(ctx, sugar).Error("Missing appointment ID in request")
+			return response.Error(req, 400, "missing appointment ID"), nil
 		}
 
 		err := h.Delete(ctx, appointmentID)
 		if err != nil {
-			sugar.Errorf("Error deleting appointment: %v", err.Error())
-			return events.APIGatewayProxyResponse{StatusCode: 500, Body: `{"error":"could not delete appointment"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error deleting appointment: %v", err.Error())
+			return response.Error(req, 500, "could not delete appointment"), nil
 		}
 
-		return events.APIGatewayProxyResponse{
-			StatusCode: 204,
-			Headers:    map[string]string{"Content-Type": "application/json"},
-		}, nil
-	})
+		return response.NoContent(req), nil
+	}))))
 }
@@ -2,34 +2,44 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"log"
 
 	handler "github.com/MezeLaw/iris-services/internal/handler/appointments"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
 	"github.com/MezeLaw/iris-services/internal/models"
 	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	"github.com/MezeLaw/iris-services/internal/response"
 	service "github.com/MezeLaw/iris-services/internal/service/appointments"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"go.uber.org/zap"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
-	sugar := logger.Sugar()
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		sugar.Fatalf("error loading AWS config: %v", err)
 	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
 	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
 
-	repo := repository.New(dynamoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	repo := repository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
 	svc := service.New(sugar, repo)
 	h := handler.New(svc, sugar)
 
-	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	lambda.Start(tracing.Wrap("getAppointment", logging.Wrap(sugar, "getAppointment", metrics.Wrap(m, "getAppointment", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		// Crear el request con los parámetros disponibles
 		getRequest := &models.GetAppointmentRequest{}
 
@@ -51,21 +61,16 @@ func main() {
 
 		// Verificar que al menos un parámetro de búsqueda esté presente
 		if getRequest.ID == "" && getRequest.ClientID == "" && getRequest.PatientID == "" && getRequest.DoctorID == "" {
-			sugar.Error("No search parameters provided")
-			return events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error":"at least one search parameter is required"}`}, nil
+			logging.FromContext(ctx, sugar).Error("No search parameters provided")
+			return response.Error(req, 400, "at least one search parameter is required"), nil
 		}
 
 		appointment, err := h.Get(ctx, getRequest)
 		if err != nil {
-			sugar.Errorf("Error retrieving appointment: %v", err.Error())
-			return events.APIGatewayProxyResponse{StatusCode: 500, Body: `{"error":"could not retrieve appointment"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error retrieving appointment: %v", err.Error())
+			return response.Error(req, 500, "could not retrieve appointment"), nil
 		}
 
-		respBody, _ := json.Marshal(appointment)
-		return events.APIGatewayProxyResponse{
-			StatusCode: 200,
-			Body:       string(respBody),
-			Headers:    map[string]string{"Content-Type": "application/json"},
-		}, nil
-	})
+		return response.OK(req, appointment), nil
+	}))))
 }
@@ -2,50 +2,72 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
 
 	handler "github.com/MezeLaw/iris-services/internal/handler/appointments"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/pagination"
 	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	"github.com/MezeLaw/iris-services/internal/response"
 	service "github.com/MezeLaw/iris-services/internal/service/appointments"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"go.uber.org/zap"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
-	sugar := logger.Sugar()
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		sugar.Fatalf("error loading AWS config: %v", err)
 	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
 	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
 
-	repo := repository.New(dynamoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	repo := repository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
 	svc := service.New(sugar, repo)
 	h := handler.New(svc, sugar)
 
-	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	lambda.Start(tracing.Wrap("listAppointments", logging.Wrap(sugar, "listAppointments", metrics.Wrap(m, "listAppointments", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		clientID := req.QueryStringParameters["clientId"]
 		if clientID == "" {
-			sugar.Error("Missing clientId parameter in request")
-			return events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error":"missing clientId parameter"}`}, nil
+			logging.FromContext(ctx, sugar).Error("Missing clientId parameter in request")
+			return response.Error(req, 400, "missing clientId parameter"), nil
+		}
+
+		var limit int
+		if raw := req.QueryStringParameters["limit"]; raw != "" {
+			if limit, err = strconv.Atoi(raw); err != nil {
+				return response.Error(req, 400, "invalid limit parameter"), nil
+			}
 		}
 
-		appointments, err := h.GetAll(ctx, clientID)
+		appointments, next, err := h.GetAll(ctx, clientID, req.QueryStringParameters["cursor"], limit)
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return response.Error(req, 400, "invalid cursor parameter"), nil
+		}
 		if err != nil {
-			sugar.Errorf("Error retrieving appointments: %v", err)
-			return events.APIGatewayProxyResponse{StatusCode: 500, Body: `{"error":"could not retrieve appointments"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error retrieving appointments: %v", err)
+			return response.Error(req, 500, "could not retrieve appointments"), nil
 		}
 
-		respBody, _ := json.Marshal(appointments)
-		return events.APIGatewayProxyResponse{
-			StatusCode: 200,
-			Body:       string(respBody),
-			Headers:    map[string]string{"Content-Type": "application/json"},
-		}, nil
-	})
+		if appointments == nil {
+			appointments = []*models.AppointmentRequest{}
+		}
+		return response.Page(req, appointments, len(appointments), next), nil
+	}))))
 }
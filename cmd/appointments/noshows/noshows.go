@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	service "github.com/MezeLaw/iris-services/internal/service/appointments"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Se dispara con una regla programada de EventBridge (por ejemplo cada hora)
+// y marca las ausencias de todos los tenants. La gracia y la política de cada
+// tenant salen de NO_SHOW_CONFIG ("{}" usa los valores por defecto):
+//
+//	{"grace":"1h","lookback":"168h","tenants":{"c1":{"threshold":3,"action":"block"},"c2":{}}}
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-workers")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	// Los NO_SHOW se publican (HL7 incluido) desde el outbox
+	repo := repository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	noShows, err := service.NoShowsFromEnv(patientsRepo, repo)
+	if err != nil {
+		sugar.Fatalf("error loading no-show policy: %v", err)
+	}
+	if noShows == nil {
+		sugar.Fatal("NO_SHOW_CONFIG is required")
+	}
+	s := service.NewWithOptions(sugar, repo, service.Options{NoShows: noShows})
+
+	lambda.Start(tracing.WrapEvent("markNoShows", func(ctx context.Context, event events.CloudWatchEvent) (*models.NoShowReport, error) {
+		now := event.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		return s.MarkNoShows(ctx, now)
+	}))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"os"
+	"strings"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/appointments"
+	"github.com/MezeLaw/iris-services/internal/jsonpatch"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/notify"
+	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	waitlistRepository "github.com/MezeLaw/iris-services/internal/repository/waitlist"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/appointments"
+	waitlistService "github.com/MezeLaw/iris-services/internal/service/waitlist"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// PATCH /appointments/{id} con un JSON Merge Patch
+// (application/merge-patch+json o application/json) o un JSON Patch
+// (application/json-patch+json). Sólo se escriben los campos que cambian.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	noShows, err := service.NoShowsFromEnv(patientsRepo, repo)
+	if err != nil {
+		sugar.Fatalf("error loading no-show policy: %v", err)
+	}
+	// Con WAITLIST_CONFIG los turnos cancelados se ofrecen a la lista de espera
+	var waitlist service.WaitlistOfferer
+	if raw := os.Getenv("WAITLIST_CONFIG"); raw != "" {
+		waitlistConfig, err := waitlistService.ParseConfig(raw)
+		if err != nil {
+			sugar.Fatalf("error loading WAITLIST_CONFIG: %v", err)
+		}
+		waitlistRepo := waitlistRepository.New(dynamoClient, sugar, "WaitlistTable", "doctor_id_index", "WaitlistOffersTable", "status_index")
+		waitlist = waitlistService.New(sugar, waitlistRepo, repo, nil, nil, patientsRepo, notify.FromEnv(cfg, sugar), waitlistConfig)
+	}
+	svc := service.NewWithOptions(sugar, repo, service.Options{NoShows: noShows, Waitlist: waitlist, Events: repo, Metrics: m})
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("patchAppointment", logging.Wrap(sugar, "patchAppointment", metrics.Wrap(m, "patchAppointment", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		appointmentID := req.PathParameters["id"]
+		if appointmentID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing appointment ID in patch request")
+			return response.Error(req, 400, "missing appointment ID"), nil
+		}
+		body := []byte(req.Body)
+		if req.IsBase64Encoded {
+			if body, err = base64.StdEncoding.DecodeString(req.Body); err != nil {
+				return response.Error(req, 400, "invalid request body"), nil
+			}
+		}
+
+		patched, err := h.Patch(ctx, appointmentID, contentType(req.Headers), body)
+		if err != nil {
+			return errorResponse(req, err), nil
+		}
+
+		return response.OK(req, patched), nil
+	}))))
+}
+
+func errorResponse(req events.APIGatewayProxyRequest, err error) events.APIGatewayProxyResponse {
+	var status int
+	switch {
+	case errors.Is(err, jsonpatch.ErrUnsupportedType):
+		resp := response.Error(req, 415, "unsupported patch media type")
+		resp.Headers["Accept-Patch"] = jsonpatch.MergePatchType + ", " + jsonpatch.JSONPatchType
+		return resp
+	case errors.Is(err, service.ErrAppointmentNotFound):
+		status = 404
+	case errors.Is(err, service.ErrPatchConflict), errors.Is(err, jsonpatch.ErrTestFailed):
+		status = 409
+	case errors.Is(err, jsonpatch.ErrInvalid), errors.Is(err, jsonpatch.ErrReadOnly):
+		status = 400
+	default:
+		return response.Error(req, 500, "could not patch appointment")
+	}
+	return response.Error(req, status, err.Error())
+}
+
+func contentType(headers map[string]string) string {
+	for name, value := range headers {
+		if strings.EqualFold(name, "Content-Type") {
+			return strings.ToLower(value)
+		}
+	}
+	return ""
+}
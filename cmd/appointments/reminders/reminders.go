@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/actiontoken"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/notify"
+	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	service "github.com/MezeLaw/iris-services/internal/service/reminders"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Se dispara con una regla programada de EventBridge (por ejemplo cada 15
+// minutos). Las ventanas y canales por cliente salen de REMINDER_CONFIG:
+//
+//	{"windows":["48h","2h"],"channels":["sms","email"],
+//	 "tenants":[{"client_id":"c1"},{"client_id":"c2","channels":["whatsapp"]}]}
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-workers")
+	tracing.InstrumentAWS(&cfg)
+	reminderConfig, err := service.ParseConfig(os.Getenv("REMINDER_CONFIG"))
+	if err != nil {
+		sugar.Fatalf("error loading REMINDER_CONFIG: %v", err)
+	}
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	// Con ACTION_TOKEN_SECRET y ACTION_BASE_URL el recordatorio lleva enlaces
+	// para confirmar o cancelar
+	var links service.ActionLinks
+	if secret, baseURL := os.Getenv("ACTION_TOKEN_SECRET"), os.Getenv("ACTION_BASE_URL"); secret != "" && baseURL != "" {
+		links = &actiontoken.Links{Signer: actiontoken.NewSigner([]byte(secret)), BaseURL: baseURL}
+	}
+	s := service.New(sugar, repo, patientsRepo, notify.FromEnv(cfg, sugar), reminderConfig, links)
+
+	lambda.Start(tracing.WrapEvent("sendReminders", func(ctx context.Context, event events.CloudWatchEvent) (*models.ReminderReport, error) {
+		now := event.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		return s.Dispatch(ctx, now)
+	}))
+}
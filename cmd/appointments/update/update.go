@@ -3,59 +3,93 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
+	"os"
 	"time"
 
 	handler "github.com/MezeLaw/iris-services/internal/handler/appointments"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
 	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/notify"
+	"github.com/MezeLaw/iris-services/internal/openapi"
 	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	waitlistRepository "github.com/MezeLaw/iris-services/internal/repository/waitlist"
+	"github.com/MezeLaw/iris-services/internal/response"
 	service "github.com/MezeLaw/iris-services/internal/service/appointments"
+	waitlistService "github.com/MezeLaw/iris-services/internal/service/waitlist"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"go.uber.org/zap"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
-	sugar := logger.Sugar()
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		sugar.Fatalf("error loading AWS config: %v", err)
 	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
 	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
 
-	repo := repository.New(dynamoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
-	svc := service.New(sugar, repo)
+	repo := repository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	noShows, err := service.NoShowsFromEnv(patientsRepo, repo)
+	if err != nil {
+		sugar.Fatalf("error loading no-show policy: %v", err)
+	}
+	// Con WAITLIST_CONFIG los turnos cancelados se ofrecen a la lista de espera
+	var waitlist service.WaitlistOfferer
+	if raw := os.Getenv("WAITLIST_CONFIG"); raw != "" {
+		waitlistConfig, err := waitlistService.ParseConfig(raw)
+		if err != nil {
+			sugar.Fatalf("error loading WAITLIST_CONFIG: %v", err)
+		}
+		waitlistRepo := waitlistRepository.New(dynamoClient, sugar, "WaitlistTable", "doctor_id_index", "WaitlistOffersTable", "status_index")
+		waitlist = waitlistService.New(sugar, waitlistRepo, repo, nil, nil, patientsRepo, notify.FromEnv(cfg, sugar), waitlistConfig)
+	}
+	svc := service.NewWithOptions(sugar, repo, service.Options{NoShows: noShows, Waitlist: waitlist, Events: repo, Metrics: m})
 	h := handler.New(svc, sugar)
 
-	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	lambda.Start(tracing.Wrap("updateAppointment", logging.Wrap(sugar, "updateAppointment", metrics.Wrap(m, "updateAppointment", openapi.Validate("updateAppointment", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		var request models.AppointmentRequest
 		if err := json.Unmarshal([]byte(req.Body), &request); err != nil {
-			sugar.Errorf("Error unmarshalling request: %v", err.Error())
-			return events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error":"invalid request body"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
 		}
 
 		// Asegurarse de que el ID esté presente
 		if request.ID == "" {
-			sugar.Error("Missing appointment ID in request")
-			return events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error":"missing appointment ID"}`}, nil
+			logging.FromContext(ctx, sugar).Error("Missing appointment ID in request")
+			return response.Error(req, 400, "missing appointment ID"), nil
 		}
 
 		request.UpdatedAt = time.Now().Format(time.RFC3339)
 
 		updated, err := h.Update(ctx, &request)
 		if err != nil {
-			sugar.Errorf("Error updating appointment: %v", err.Error())
-			return events.APIGatewayProxyResponse{StatusCode: 500, Body: `{"error":"could not update appointment"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error updating appointment: %v", err.Error())
+			switch {
+			case errors.Is(err, service.ErrAppointmentNotFound):
+				return response.Error(req, 404, "appointment not found"), nil
+			case errors.Is(err, service.ErrPatchConflict):
+				return response.Error(req, 409, "appointment was modified concurrently"), nil
+			}
+			return response.Error(req, 500, "could not update appointment"), nil
 		}
 
-		respBody, _ := json.Marshal(updated)
-		return events.APIGatewayProxyResponse{
-			StatusCode: 200,
-			Body:       string(respBody),
-			Headers:    map[string]string{"Content-Type": "application/json"},
-		}, nil
-	})
+		return response.OK(req, updated), nil
+	})))))
 }
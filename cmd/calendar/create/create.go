@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	_ "time/tzdata"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/calendar"
+	"github.com/MezeLaw/iris-services/internal/idempotency"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	repository "github.com/MezeLaw/iris-services/internal/repository/calendarfeeds"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/calendar"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "CalendarFeedsTable", "owner_key_index")
+	appointmentsRepo := appointmentsRepository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	svc := service.New(sugar, repo, appointmentsRepo, patientsRepo, os.Getenv("CALENDAR_FEED_BASE_URL"))
+	h := handler.New(svc, sugar)
+
+	idempotent, err := idempotency.FromEnv(dynamoClient, sugar, "calendar/create")
+	if err != nil {
+		sugar.Fatalf("error loading idempotency config: %v", err)
+	}
+
+	lambda.Start(tracing.Wrap("createCalendarFeed", logging.Wrap(sugar, "createCalendarFeed", metrics.Wrap(m, "createCalendarFeed", idempotent.Wrap(openapi.Validate("createCalendarFeed", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		var request models.CalendarFeedRequest
+		if err := json.Unmarshal([]byte(req.Body), &request); err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
+		}
+
+		created, err := h.CreateFeed(ctx, &request)
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error creating calendar feed: %v", err.Error())
+			return response.Error(req, 500, "could not create calendar feed"), nil
+		}
+
+		return response.Created(req, req.Path+"/"+created.ID, created), nil
+	}))))))
+}
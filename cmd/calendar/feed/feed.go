@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	_ "time/tzdata"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/calendar"
+	"github.com/MezeLaw/iris-services/internal/ical"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	repository "github.com/MezeLaw/iris-services/internal/repository/calendarfeeds"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	service "github.com/MezeLaw/iris-services/internal/service/calendar"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "CalendarFeedsTable", "owner_key_index")
+	appointmentsRepo := appointmentsRepository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	svc := service.New(sugar, repo, appointmentsRepo, patientsRepo, os.Getenv("CALENDAR_FEED_BASE_URL"))
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("getCalendarFeed", logging.Wrap(sugar, "getCalendarFeed", metrics.Wrap(m, "getCalendarFeed", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		// Comparte la ruta /calendar/feeds/{id} con la revocación: acá el
+		// parámetro es el token completo de la URL, <id>.<secreto>.ics
+		token := req.PathParameters["id"]
+		if token == "" {
+			return events.APIGatewayProxyResponse{StatusCode: 404}, nil
+		}
+
+		body, err := h.RenderFeed(ctx, token)
+		if errors.Is(err, service.ErrFeedNotFound) {
+			return events.APIGatewayProxyResponse{StatusCode: 404}, nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error rendering calendar feed: %v", err.Error())
+			return events.APIGatewayProxyResponse{StatusCode: 500}, nil
+		}
+
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Body:       string(body),
+			Headers: map[string]string{
+				"Content-Type":  ical.ContentType,
+				"Cache-Control": "private, max-age=300",
+			},
+		}, nil
+	}))))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/calendar"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	repository "github.com/MezeLaw/iris-services/internal/repository/calendarfeeds"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/calendar"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "CalendarFeedsTable", "owner_key_index")
+	appointmentsRepo := appointmentsRepository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	svc := service.New(sugar, repo, appointmentsRepo, patientsRepo, os.Getenv("CALENDAR_FEED_BASE_URL"))
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("listCalendarFeeds", logging.Wrap(sugar, "listCalendarFeeds", metrics.Wrap(m, "listCalendarFeeds", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		ownerType := req.QueryStringParameters["ownerType"]
+		ownerID := req.QueryStringParameters["ownerId"]
+		if ownerType == "" || ownerID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing ownerType or ownerId parameter in request")
+			return response.Error(req, 400, "missing ownerType or ownerId parameter"), nil
+		}
+
+		feeds, err := h.GetFeeds(ctx, ownerType, ownerID)
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error retrieving calendar feeds: %v", err)
+			return response.Error(req, 500, "could not retrieve calendar feeds"), nil
+		}
+
+		return response.List(req, feeds), nil
+	}))))
+}
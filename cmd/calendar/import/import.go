@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	_ "time/tzdata"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/calendarimport"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	appointmentsService "github.com/MezeLaw/iris-services/internal/service/appointments"
+	service "github.com/MezeLaw/iris-services/internal/service/calendarimport"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	appointmentsRepo := appointmentsRepository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	svc := service.New(sugar, patientsRepo, appointmentsRepo, appointmentsService.NewWithOptions(sugar, appointmentsRepo, appointmentsService.Options{Events: appointmentsRepo, Metrics: m}))
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("importCalendar", logging.Wrap(sugar, "importCalendar", metrics.Wrap(m, "importCalendar", openapi.Validate("importCalendar", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		body := []byte(req.Body)
+		if req.IsBase64Encoded {
+			if body, err = base64.StdEncoding.DecodeString(req.Body); err != nil {
+				return response.Error(req, 400, "invalid request body"), nil
+			}
+		}
+
+		var request models.CalendarImportRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
+		}
+		// ?dryRun=true permite pedir el reporte sin tocar el body
+		if req.QueryStringParameters["dryRun"] == "true" {
+			request.DryRun = true
+		}
+
+		report, err := h.Import(ctx, &request)
+		if errors.Is(err, service.ErrInvalidImport) {
+			return response.Error(req, 400, err.Error()), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error importing calendar: %v", err.Error())
+			return response.Error(req, 500, "could not import calendar"), nil
+		}
+
+		return response.OK(req, report), nil
+	})))))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/calendar"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	repository "github.com/MezeLaw/iris-services/internal/repository/calendarfeeds"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/calendar"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "CalendarFeedsTable", "owner_key_index")
+	appointmentsRepo := appointmentsRepository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	svc := service.New(sugar, repo, appointmentsRepo, patientsRepo, os.Getenv("CALENDAR_FEED_BASE_URL"))
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("revokeCalendarFeed", logging.Wrap(sugar, "revokeCalendarFeed", metrics.Wrap(m, "revokeCalendarFeed", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		feedID := req.PathParameters["id"]
+		if feedID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing calendar feed ID in request")
+			return response.Error(req, 400, "missing calendar feed ID"), nil
+		}
+
+		err := h.RevokeFeed(ctx, feedID)
+		if errors.Is(err, service.ErrFeedNotFound) {
+			return response.Error(req, 404, "calendar feed not found"), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error revoking calendar feed: %v", err.Error())
+			return response.Error(req, 500, "could not revoke calendar feed"), nil
+		}
+
+		return response.NoContent(req), nil
+	}))))
+}
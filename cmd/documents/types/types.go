@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/MezeLaw/iris-services/internal/documents"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/response"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// Devuelve el catálogo de tipos de documento, filtrado por ?country=AR si
+// se indica, para armar los formularios de alta.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	tracing.Setup("iris-api")
+	m := metrics.FromEnv()
+
+	lambda.Start(tracing.Wrap("listDocumentTypes", logging.Wrap(sugar, "listDocumentTypes", metrics.Wrap(m, "listDocumentTypes", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return response.List(req, documents.Types(req.QueryStringParameters["country"])), nil
+	}))))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/MezeLaw/iris-services/internal/blobstore"
+	handler "github.com/MezeLaw/iris-services/internal/handler/exports"
+	"github.com/MezeLaw/iris-services/internal/idempotency"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	repository "github.com/MezeLaw/iris-services/internal/repository/exports"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/exports"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "ExportsTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	appointmentsRepo := appointmentsRepository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	svc := service.New(sugar, repo, patientsRepo, appointmentsRepo, blobstore.FromEnv(cfg), 0)
+	h := handler.New(svc, sugar)
+
+	idempotent, err := idempotency.FromEnv(dynamoClient, sugar, "exports/create")
+	if err != nil {
+		sugar.Fatalf("error loading idempotency config: %v", err)
+	}
+
+	lambda.Start(tracing.Wrap("createExport", logging.Wrap(sugar, "createExport", metrics.Wrap(m, "createExport", idempotent.Wrap(openapi.Validate("createExport", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		var request models.ExportRequest
+		if err := json.Unmarshal([]byte(req.Body), &request); err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
+		}
+
+		result, err := h.Create(ctx, &request)
+		if errors.Is(err, service.ErrInvalidExport) {
+			return response.Error(req, 400, err.Error()), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error creating export: %v", err.Error())
+			return response.Error(req, 500, "could not create export"), nil
+		}
+
+		// El archivo se genera en cmd/exports/run; el cliente consulta el
+		// estado con GET hasta que esté COMPLETED.
+		return response.Accepted(req, req.Path+"/"+result.ID, result), nil
+	}))))))
+}
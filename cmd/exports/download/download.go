@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/MezeLaw/iris-services/internal/blobstore"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/response"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// Sirve los archivos de FileStore cuando no se usa S3 (EXPORT_BUCKET vacío).
+// Con S3 el enlace es una URL prefirmada y no pasa por acá.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	store := blobstore.FileStoreFromEnv()
+
+	tracing.Setup("iris-api")
+	m := metrics.FromEnv()
+
+	lambda.Start(tracing.Wrap("downloadExport", logging.Wrap(sugar, "downloadExport", metrics.Wrap(m, "downloadExport", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		key := req.PathParameters["key"]
+		f, err := store.Open(key, req.QueryStringParameters["expires"], req.QueryStringParameters["signature"])
+		if errors.Is(err, blobstore.ErrExpired) {
+			return response.Error(req, 403, "download link expired or invalid"), nil
+		}
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return response.Error(req, 404, "file not found"), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error opening export file: %v", err.Error())
+			return response.Error(req, 500, "could not download file"), nil
+		}
+		defer f.Close()
+
+		content, err := io.ReadAll(f)
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error reading export file: %v", err.Error())
+			return response.Error(req, 500, "could not download file"), nil
+		}
+		contentType := "text/csv; charset=utf-8"
+		if strings.HasSuffix(key, ".ndjson") {
+			contentType = "application/x-ndjson"
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode:      200,
+			Body:            base64.StdEncoding.EncodeToString(content),
+			IsBase64Encoded: true,
+			Headers: map[string]string{
+				"Content-Type":        contentType,
+				"Content-Disposition": `attachment; filename="` + path.Base(key) + `"`,
+			},
+		}, nil
+	}))))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/blobstore"
+	handler "github.com/MezeLaw/iris-services/internal/handler/exports"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	repository "github.com/MezeLaw/iris-services/internal/repository/exports"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/exports"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	// EXPORT_URL_TTL acepta duraciones de Go ("15m", "1h")
+	ttl, _ := time.ParseDuration(os.Getenv("EXPORT_URL_TTL"))
+
+	repo := repository.New(dynamoClient, sugar, "ExportsTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	appointmentsRepo := appointmentsRepository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	svc := service.New(sugar, repo, patientsRepo, appointmentsRepo, blobstore.FromEnv(cfg), ttl)
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("getExport", logging.Wrap(sugar, "getExport", metrics.Wrap(m, "getExport", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		exportID := req.PathParameters["id"]
+		if exportID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing export ID in request")
+			return response.Error(req, 400, "missing export ID"), nil
+		}
+
+		result, err := h.Get(ctx, exportID)
+		if errors.Is(err, service.ErrExportNotFound) {
+			return response.Error(req, 404, "export not found"), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error getting export: %v", err.Error())
+			return response.Error(req, 500, "could not get export"), nil
+		}
+
+		return response.OK(req, result), nil
+	}))))
+}
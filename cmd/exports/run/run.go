@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/MezeLaw/iris-services/internal/blobstore"
+	handler "github.com/MezeLaw/iris-services/internal/handler/exports"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	repository "github.com/MezeLaw/iris-services/internal/repository/exports"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	service "github.com/MezeLaw/iris-services/internal/service/exports"
+	"github.com/MezeLaw/iris-services/internal/streams"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Se dispara con el stream de ExportsTable (NEW_IMAGE o NEW_AND_OLD_IMAGES):
+// cada INSERT es una exportación nueva a generar.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-workers")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "ExportsTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	appointmentsRepo := appointmentsRepository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	svc := service.New(sugar, repo, patientsRepo, appointmentsRepo, blobstore.FromEnv(cfg), 0)
+	h := handler.New(svc, sugar)
+
+	// Con ReportBatchItemFailures en el event source mapping: una
+	// exportación que falla se reintenta sin repetir las anteriores del lote.
+	processor := streams.NewProcessor(sugar, map[string]string{"ExportsTable": streams.EntityExport})
+	processor.Register(streams.EntityExport, &streams.ExportProjection{Run: func(ctx context.Context, id string) error {
+		if err := h.Run(ctx, id); err != nil && !errors.Is(err, service.ErrExportNotFound) {
+			return err
+		}
+		return nil
+	}})
+
+	lambda.Start(tracing.WrapEvent("runExports", func(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+		return processor.Handle(ctx, event), nil
+	}))
+}
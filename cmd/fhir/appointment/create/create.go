@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/MezeLaw/iris-services/internal/availability"
+	"github.com/MezeLaw/iris-services/internal/fhir"
+	handler "github.com/MezeLaw/iris-services/internal/handler/fhir"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	slotholds "github.com/MezeLaw/iris-services/internal/repository/slotholds"
+	appointmentsService "github.com/MezeLaw/iris-services/internal/service/appointments"
+	service "github.com/MezeLaw/iris-services/internal/service/fhir"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	noShows, err := appointmentsService.NoShowsFromEnv(patientsRepo, repo)
+	if err != nil {
+		sugar.Fatalf("error loading no-show policy: %v", err)
+	}
+	holdStore := slotholds.New(repoClient, sugar, "SlotHoldsTable", "doctor_id_index", "AppointmentsTable", "OutboxTable")
+	holds, err := appointmentsService.HoldsFromEnv(holdStore)
+	if err != nil {
+		sugar.Fatalf("error loading slot hold config: %v", err)
+	}
+	appointments := appointmentsService.NewWithOptions(sugar, repo, appointmentsService.Options{NoShows: noShows, Holds: holds, Events: repo, Metrics: m})
+	svc := service.New(sugar, repo, appointments, availability.DefaultWorkingHours(), holdStore)
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("fhirCreateAppointment", logging.Wrap(sugar, "fhirCreateAppointment", metrics.Wrap(m, "fhirCreateAppointment", openapi.Validate("fhirCreateAppointment", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		var resource fhir.Appointment
+		if err := json.Unmarshal([]byte(req.Body), &resource); err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return outcome(400, "structure", "invalid request body"), nil
+		}
+
+		// El tenant puede venir en el tag de meta o como query param
+		if clientID := req.QueryStringParameters["clientId"]; clientID != "" {
+			resource.Meta = &fhir.Meta{Tag: []fhir.Coding{{System: fhir.ClientTagSystem, Code: clientID}}}
+		}
+
+		booked, err := h.BookAppointment(ctx, &resource)
+		if errors.Is(err, service.ErrSlotNotAvailable) || errors.Is(err, appointmentsService.ErrPatientBlocked) {
+			return outcome(409, "conflict", err.Error()), nil
+		}
+		if errors.Is(err, service.ErrInvalidResource) {
+			return outcome(400, "invalid", err.Error()), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error booking FHIR Appointment: %v", err.Error())
+			return outcome(500, "exception", "could not book appointment"), nil
+		}
+
+		respBody, _ := json.Marshal(booked)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 201,
+			Body:       string(respBody),
+			Headers:    map[string]string{"Content-Type": fhir.ContentType},
+		}, nil
+	})))))
+}
+
+func outcome(statusCode int, code, diagnostics string) events.APIGatewayProxyResponse {
+	body, _ := json.Marshal(fhir.NewOperationOutcome(code, diagnostics))
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Body:       string(body),
+		Headers:    map[string]string{"Content-Type": fhir.ContentType},
+	}
+}
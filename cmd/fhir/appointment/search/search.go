@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/MezeLaw/iris-services/internal/availability"
+	"github.com/MezeLaw/iris-services/internal/fhir"
+	handler "github.com/MezeLaw/iris-services/internal/handler/fhir"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	appointmentsService "github.com/MezeLaw/iris-services/internal/service/appointments"
+	service "github.com/MezeLaw/iris-services/internal/service/fhir"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	svc := service.New(sugar, repo, appointmentsService.New(sugar, repo), availability.DefaultWorkingHours(), nil)
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("fhirSearchAppointments", logging.Wrap(sugar, "fhirSearchAppointments", metrics.Wrap(m, "fhirSearchAppointments", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		search, err := fhir.ParseAppointmentSearch(queryParameters(req))
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Invalid search parameters: %v", err.Error())
+			return outcome(400, "invalid", err.Error()), nil
+		}
+
+		bundle, err := h.SearchAppointments(ctx, search)
+		if errors.Is(err, service.ErrInvalidParameters) {
+			return outcome(400, "invalid", err.Error()), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error searching FHIR Appointments: %v", err.Error())
+			return outcome(500, "exception", "could not search appointments"), nil
+		}
+
+		respBody, _ := json.Marshal(bundle)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Body:       string(respBody),
+			Headers:    map[string]string{"Content-Type": fhir.ContentType},
+		}, nil
+	}))))
+}
+
+// queryParameters combina los parámetros simples y multi-valor de API Gateway.
+func queryParameters(req events.APIGatewayProxyRequest) map[string][]string {
+	params := make(map[string][]string, len(req.MultiValueQueryStringParameters))
+	for name, values := range req.MultiValueQueryStringParameters {
+		params[name] = values
+	}
+	for name, value := range req.QueryStringParameters {
+		if _, ok := params[name]; !ok {
+			params[name] = []string{value}
+		}
+	}
+	return params
+}
+
+func outcome(statusCode int, code, diagnostics string) events.APIGatewayProxyResponse {
+	body, _ := json.Marshal(fhir.NewOperationOutcome(code, diagnostics))
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Body:       string(body),
+		Headers:    map[string]string{"Content-Type": fhir.ContentType},
+	}
+}
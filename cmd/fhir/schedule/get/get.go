@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/MezeLaw/iris-services/internal/availability"
+	"github.com/MezeLaw/iris-services/internal/fhir"
+	handler "github.com/MezeLaw/iris-services/internal/handler/fhir"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	appointmentsService "github.com/MezeLaw/iris-services/internal/service/appointments"
+	service "github.com/MezeLaw/iris-services/internal/service/fhir"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	svc := service.New(sugar, repo, appointmentsService.New(sugar, repo), availability.DefaultWorkingHours(), nil)
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("fhirGetSchedule", logging.Wrap(sugar, "fhirGetSchedule", metrics.Wrap(m, "fhirGetSchedule", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		// El id del Schedule es el id del médico
+		doctorID := req.PathParameters["id"]
+		if doctorID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing schedule ID in request")
+			return outcome(400, "required", "missing schedule ID"), nil
+		}
+
+		schedule, err := h.ReadSchedule(ctx, req.QueryStringParameters["clientId"], doctorID)
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error reading FHIR Schedule: %v", err.Error())
+			return outcome(500, "exception", "could not retrieve schedule"), nil
+		}
+
+		respBody, _ := json.Marshal(schedule)
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Body:       string(respBody),
+			Headers:    map[string]string{"Content-Type": fhir.ContentType},
+		}, nil
+	}))))
+}
+
+func outcome(statusCode int, code, diagnostics string) events.APIGatewayProxyResponse {
+	body, _ := json.Marshal(fhir.NewOperationOutcome(code, diagnostics))
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Body:       string(body),
+		Headers:    map[string]string{"Content-Type": fhir.ContentType},
+	}
+}
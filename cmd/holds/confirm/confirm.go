@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/appointments"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	slotholds "github.com/MezeLaw/iris-services/internal/repository/slotholds"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/appointments"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	noShows, err := service.NoShowsFromEnv(patientsRepo, repo)
+	if err != nil {
+		sugar.Fatalf("error loading no-show policy: %v", err)
+	}
+	holds, err := service.HoldsFromEnv(slotholds.New(repoClient, sugar, "SlotHoldsTable", "doctor_id_index", "AppointmentsTable", "OutboxTable"))
+	if err != nil {
+		sugar.Fatalf("error loading slot hold config: %v", err)
+	}
+	svc := service.NewWithOptions(sugar, repo, service.Options{NoShows: noShows, Holds: holds, Events: repo, Metrics: m})
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("confirmHold", logging.Wrap(sugar, "confirmHold", metrics.Wrap(m, "confirmHold", openapi.Validate("confirmHold", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		holdID := req.PathParameters["id"]
+		if holdID == "" {
+			return response.Error(req, 400, "missing hold ID"), nil
+		}
+		// El cuerpo es un HoldConfirmRequest: médico, fecha y duración salen de la reserva
+		var request models.AppointmentRequest
+		if err := json.Unmarshal([]byte(req.Body), &request); err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
+		}
+
+		created, err := h.ConfirmHold(ctx, holdID, &request)
+		switch {
+		case errors.Is(err, service.ErrHoldNotFound):
+			return response.Error(req, 410, "slot hold not found or expired"), nil
+		case errors.Is(err, service.ErrPatientBlocked):
+			return response.Error(req, 409, err.Error()), nil
+		case err != nil:
+			logging.FromContext(ctx, sugar).Errorf("Error confirming slot hold: %v", err.Error())
+			return response.Error(req, 500, "could not confirm slot hold"), nil
+		}
+
+		// El hold se convierte en un turno: Location apunta al turno creado
+		return response.Created(req, "/appointments/"+created.ID, created), nil
+	})))))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/appointments"
+	"github.com/MezeLaw/iris-services/internal/idempotency"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	slotholds "github.com/MezeLaw/iris-services/internal/repository/slotholds"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/appointments"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	holds, err := service.HoldsFromEnv(slotholds.New(repoClient, sugar, "SlotHoldsTable", "doctor_id_index", "AppointmentsTable", "OutboxTable"))
+	if err != nil {
+		sugar.Fatalf("error loading slot hold config: %v", err)
+	}
+	svc := service.NewWithOptions(sugar, repo, service.Options{Holds: holds})
+	h := handler.New(svc, sugar)
+
+	idempotent, err := idempotency.FromEnv(dynamoClient, sugar, "holds/create")
+	if err != nil {
+		sugar.Fatalf("error loading idempotency config: %v", err)
+	}
+
+	lambda.Start(tracing.Wrap("createHold", logging.Wrap(sugar, "createHold", metrics.Wrap(m, "createHold", idempotent.Wrap(openapi.Validate("createHold", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		var request models.SlotHoldRequest
+		if err := json.Unmarshal([]byte(req.Body), &request); err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
+		}
+
+		created, err := h.HoldSlot(ctx, &request)
+		switch {
+		case errors.Is(err, service.ErrInvalidHold):
+			return response.Error(req, 400, err.Error()), nil
+		case errors.Is(err, service.ErrSlotHeld):
+			return response.Error(req, 409, "slot is already held or booked"), nil
+		case err != nil:
+			logging.FromContext(ctx, sugar).Errorf("Error holding slot: %v", err.Error())
+			return response.Error(req, 500, "could not hold slot"), nil
+		}
+
+		return response.Created(req, req.Path+"/"+created.ID, created), nil
+	}))))))
+}
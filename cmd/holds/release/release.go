@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/appointments"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	repository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	slotholds "github.com/MezeLaw/iris-services/internal/repository/slotholds"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/appointments"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	holds, err := service.HoldsFromEnv(slotholds.New(repoClient, sugar, "SlotHoldsTable", "doctor_id_index", "AppointmentsTable", "OutboxTable"))
+	if err != nil {
+		sugar.Fatalf("error loading slot hold config: %v", err)
+	}
+	svc := service.NewWithOptions(sugar, repo, service.Options{Holds: holds})
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("releaseHold", logging.Wrap(sugar, "releaseHold", metrics.Wrap(m, "releaseHold", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		holdID := req.PathParameters["id"]
+		if holdID == "" {
+			return response.Error(req, 400, "missing hold ID"), nil
+		}
+
+		err := h.ReleaseHold(ctx, holdID)
+		if errors.Is(err, service.ErrHoldNotFound) {
+			return response.Error(req, 404, "slot hold not found"), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error releasing slot hold: %v", err.Error())
+			return response.Error(req, 500, "could not release slot hold"), nil
+		}
+
+		return response.NoContent(req), nil
+	}))))
+}
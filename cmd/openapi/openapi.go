@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	"github.com/MezeLaw/iris-services/internal/response"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// GET /openapi.json: el documento OpenAPI de la API, sin el sobre de
+// response para que lo puedan leer directamente Swagger UI, Postman o los
+// generadores de clientes.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	tracing.Setup("iris-api")
+	m := metrics.FromEnv()
+
+	lambda.Start(tracing.Wrap("getOpenAPI", logging.Wrap(sugar, "getOpenAPI", metrics.Wrap(m, "getOpenAPI", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Body:       string(openapi.JSON()),
+			Headers: map[string]string{
+				"Content-Type":           response.ContentType,
+				response.RequestIDHeader: response.RequestID(req),
+				"Cache-Control":          "public, max-age=300",
+			},
+		}, nil
+	}))))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	domainEvents "github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/hl7"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	repository "github.com/MezeLaw/iris-services/internal/repository/outbox"
+	webhooksRepository "github.com/MezeLaw/iris-services/internal/repository/webhooks"
+	service "github.com/MezeLaw/iris-services/internal/service/outbox"
+	webhooksService "github.com/MezeLaw/iris-services/internal/service/webhooks"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Se dispara con una regla programada de EventBridge (por ejemplo cada
+// minuto). El destino sale de EVENTS_PUBLISHER: eventbridge con
+// EVENTS_BUS_NAME o sns con EVENTS_TOPIC_ARN. Con WEBHOOKS_ENABLED=true
+// además deja las entregas de los webhooks de las clínicas y con
+// HL7_MLLP_ADDR manda los mensajes HL7 de altas y cambios.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-workers")
+	tracing.InstrumentAWS(&cfg)
+	publisher, err := domainEvents.PublisherFromEnv(cfg)
+	if err != nil {
+		sugar.Fatalf("error loading events publisher: %v", err)
+	}
+
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	// Los nombres quedan guardados en el outbox con cada entrega: no cambiarlos
+	destinations := []domainEvents.Destination{{Name: "bus", Publisher: publisher}}
+	if os.Getenv("WEBHOOKS_ENABLED") == "true" {
+		webhooksRepo := webhooksRepository.New(dynamoClient, sugar, "WebhookSubscriptionsTable", "client_id_index", "WebhookDeliveriesTable", "client_id_index", "status_index")
+		webhooks := webhooksService.New(sugar, webhooksRepo, nil, webhooksService.Retry{}, nil)
+		destinations = append(destinations, domainEvents.Destination{Name: "webhooks", Publisher: webhooks})
+	}
+	if addr, hl7Config := hl7.ConfigFromEnv(); addr != "" {
+		// Un solo intento por evento: los que fallan se reintentan en la
+		// próxima corrida del relay
+		sender := hl7.NewMLLPSender(addr)
+		sender.MaxAttempts = 1
+		destinations = append(destinations, domainEvents.Destination{Name: "hl7", Publisher: hl7.NewPublisher(hl7.NewOutbound(sender, hl7Config, sugar))})
+	}
+
+	repo := repository.New(dynamoClient, sugar, "OutboxTable", "status_index")
+	s := service.New(sugar, repo, destinations...)
+
+	lambda.Start(tracing.WrapEvent("relayOutbox", func(ctx context.Context, event events.CloudWatchEvent) (*models.OutboxReport, error) {
+		now := event.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		return s.Relay(ctx, now)
+	}))
+}
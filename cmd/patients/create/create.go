@@ -3,38 +3,59 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"time"
 
+	"github.com/MezeLaw/iris-services/internal/documents"
 	handler "github.com/MezeLaw/iris-services/internal/handler/patients"
+	"github.com/MezeLaw/iris-services/internal/idempotency"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
 	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	"github.com/MezeLaw/iris-services/internal/phones"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
 	repository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
 	service "github.com/MezeLaw/iris-services/internal/service/patients"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"go.uber.org/zap"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
-	sugar := logger.Sugar()
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		sugar.Fatalf("error loading AWS config: %v", err)
 	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
 	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
 
-	repo := repository.New(dynamoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index")
-	svc := service.New(sugar, repo)
+	repo := repository.NewWithOutbox(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable", "OutboxTable")
+	svc := service.NewWithOptions(sugar, repo, service.Options{Events: repo})
 	h := handler.New(svc, sugar)
 
-	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	idempotent, err := idempotency.FromEnv(dynamoClient, sugar, "patients/create")
+	if err != nil {
+		sugar.Fatalf("error loading idempotency config: %v", err)
+	}
+
+	lambda.Start(tracing.Wrap("createPatient", logging.Wrap(sugar, "createPatient", metrics.Wrap(m, "createPatient", idempotent.Wrap(openapi.Validate("createPatient", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		var request models.PatientRequest
 		if err := json.Unmarshal([]byte(req.Body), &request); err != nil {
-			sugar.Errorf("Error unmarshalling request: %v", err.Error())
-			return events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error":"invalid request body"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
 		}
 
 		now := time.Now().Format(time.RFC3339)
@@ -42,16 +63,15 @@ func main() {
 		request.UpdatedAt = now
 
 		created, err := h.Create(ctx, &request)
+		if errors.Is(err, documents.ErrUnknownType) || errors.Is(err, documents.ErrInvalidNumber) ||
+			errors.Is(err, phones.ErrUnknownCountry) || errors.Is(err, phones.ErrInvalidNumber) {
+			return response.Error(req, 400, err.Error()), nil
+		}
 		if err != nil {
-			sugar.Errorf("Error creating patient: %v", err.Error())
-			return events.APIGatewayProxyResponse{StatusCode: 500, Body: `{"error":"could not create patient"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error creating patient: %v", err.Error())
+			return response.Error(req, 500, "could not create patient"), nil
 		}
 
-		respBody, _ := json.Marshal(created)
-		return events.APIGatewayProxyResponse{
-			StatusCode: 201,
-			Body:       string(respBody),
-			Headers:    map[string]string{"Content-Type": "application/json"},
-		}, nil
-	})
+		return response.Created(req, req.Path+"/"+created.ID, created), nil
+	}))))))
 }
@@ -2,47 +2,59 @@ package main
 
 import (
 	"context"
+	"log"
 
 	handler "github.com/MezeLaw/iris-services/internal/handler/patients"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
 	repository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
 	service "github.com/MezeLaw/iris-services/internal/service/patients"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"go.uber.org/zap"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
-	sugar := logger.Sugar()
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		sugar.Fatalf("error loading AWS config: %v", err)
 	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
 	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
 
-	repo := repository.New(dynamoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index")
-	svc := service.New(sugar, repo)
+	repo := repository.NewWithOutbox(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable", "OutboxTable")
+	svc := service.NewWithOptions(sugar, repo, service.Options{Events: repo})
 	h := handler.New(svc, sugar)
 
-	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-		patientID := req.QueryStringParameters["id"]
+	lambda.Start(tracing.Wrap("deletePatient", logging.Wrap(sugar, "deletePatient", metrics.Wrap(m, "deletePatient", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		// DELETE /patients/{id}; ?id= se sigue aceptando para los clientes viejos
+		patientID := req.PathParameters["id"]
+		if patientID == "" {
+			patientID = req.QueryStringParameters["id"]
+		}
 		if patientID == "" {
-			sugar.Error("Missing patient ID in delete request")
-			return events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error":"patient ID is required for deletion"}`}, nil
+			logging.FromContext(ctx, sugar).Error("Missing patient ID in delete request")
+			return response.Error(req, 400, "patient ID is required for deletion"), nil
 		}
 
 		err := h.Delete(ctx, patientID)
 		if err != nil {
-			sugar.Errorf("Error deleting patient: %v", err.Error())
-			return events.APIGatewayProxyResponse{StatusCode: 500, Body: `{"error":"could not delete patient"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error deleting patient: %v", err.Error())
+			return response.Error(req, 500, "could not delete patient"), nil
 		}
 
-		return events.APIGatewayProxyResponse{
-			StatusCode: 204,
-			Headers:    map[string]string{"Content-Type": "application/json"},
-		}, nil
-	})
+		return response.NoContent(req), nil
+	}))))
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/duplicates"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	repository "github.com/MezeLaw/iris-services/internal/repository/patientmerges"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/duplicates"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "PatientMergesTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	appointmentsRepo := appointmentsRepository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	svc := service.New(sugar, patientsRepo, appointmentsRepo, repo)
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("listDuplicatePatients", logging.Wrap(sugar, "listDuplicatePatients", metrics.Wrap(m, "listDuplicatePatients", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		clientID := req.QueryStringParameters["clientId"]
+		if clientID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing clientId parameter in request")
+			return response.Error(req, 400, "missing clientId parameter"), nil
+		}
+		var threshold float64
+		if value := req.QueryStringParameters["threshold"]; value != "" {
+			if threshold, err = strconv.ParseFloat(value, 64); err != nil || threshold <= 0 || threshold > 1 {
+				return response.Error(req, 400, "threshold must be a number between 0 and 1"), nil
+			}
+		}
+
+		candidates, err := h.Find(ctx, clientID, threshold)
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error finding duplicate patients: %v", err)
+			return response.Error(req, 500, "could not find duplicate patients"), nil
+		}
+
+		return response.List(req, candidates), nil
+	}))))
+}
@@ -2,41 +2,55 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"log"
 
 	handler "github.com/MezeLaw/iris-services/internal/handler/patients"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
 	"github.com/MezeLaw/iris-services/internal/models"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
 	repository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
 	service "github.com/MezeLaw/iris-services/internal/service/patients"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"go.uber.org/zap"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
-	sugar := logger.Sugar()
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		sugar.Fatalf("error loading AWS config: %v", err)
 	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
 	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
 
-	repo := repository.New(dynamoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index")
+	repo := repository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
 	svc := service.New(sugar, repo)
 	h := handler.New(svc, sugar)
 
-	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-		patientID := req.QueryStringParameters["id"]
+	lambda.Start(tracing.Wrap("getPatient", logging.Wrap(sugar, "getPatient", metrics.Wrap(m, "getPatient", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		// GET /patients/{id}; ?id= se sigue aceptando para los clientes viejos
+		patientID := req.PathParameters["id"]
+		if patientID == "" {
+			patientID = req.QueryStringParameters["id"]
+		}
 		docType := req.QueryStringParameters["docType"]
 		docNumber := req.QueryStringParameters["docNumber"]
 
 		if patientID == "" && (docType == "" || docNumber == "") {
-			sugar.Errorf("Missing required parameters in request")
-			return events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error":"missing required parameters"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Missing required parameters in request")
+			return response.Error(req, 400, "missing required parameters"), nil
 		}
 
 		request := &models.GetPatientRequest{
@@ -47,15 +61,10 @@ func main() {
 
 		patient, err := h.Get(ctx, request)
 		if err != nil {
-			sugar.Errorf("Error retrieving patient: %v", err.Error())
-			return events.APIGatewayProxyResponse{StatusCode: 500, Body: `{"error":"could not retrieve patient"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error retrieving patient: %v", err.Error())
+			return response.Error(req, 500, "could not retrieve patient"), nil
 		}
 
-		respBody, _ := json.Marshal(patient)
-		return events.APIGatewayProxyResponse{
-			StatusCode: 200,
-			Body:       string(respBody),
-			Headers:    map[string]string{"Content-Type": "application/json"},
-		}, nil
-	})
+		return response.OK(req, patient), nil
+	}))))
 }
@@ -2,50 +2,72 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
 
 	handler "github.com/MezeLaw/iris-services/internal/handler/patients"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/pagination"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
 	repository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
 	service "github.com/MezeLaw/iris-services/internal/service/patients"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"go.uber.org/zap"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
-	sugar := logger.Sugar()
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		sugar.Fatalf("error loading AWS config: %v", err)
 	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
 	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
 
-	repo := repository.New(dynamoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index")
+	repo := repository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
 	svc := service.New(sugar, repo)
 	h := handler.New(svc, sugar)
 
-	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	lambda.Start(tracing.Wrap("listPatients", logging.Wrap(sugar, "listPatients", metrics.Wrap(m, "listPatients", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		clientID := req.QueryStringParameters["clientId"]
 		if clientID == "" {
-			sugar.Error("Missing clientId parameter in request")
-			return events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error":"missing clientId parameter"}`}, nil
+			logging.FromContext(ctx, sugar).Error("Missing clientId parameter in request")
+			return response.Error(req, 400, "missing clientId parameter"), nil
+		}
+
+		var limit int
+		if raw := req.QueryStringParameters["limit"]; raw != "" {
+			if limit, err = strconv.Atoi(raw); err != nil {
+				return response.Error(req, 400, "invalid limit parameter"), nil
+			}
 		}
 
-		patients, err := h.GetAll(ctx, clientID)
+		patients, next, err := h.GetAll(ctx, clientID, req.QueryStringParameters["cursor"], limit)
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return response.Error(req, 400, "invalid cursor parameter"), nil
+		}
 		if err != nil {
-			sugar.Errorf("Error retrieving patients: %v", err)
-			return events.APIGatewayProxyResponse{StatusCode: 500, Body: `{"error":"could not retrieve patients"}`}, nil
+			logging.FromContext(ctx, sugar).Errorf("Error retrieving patients: %v", err)
+			return response.Error(req, 500, "could not retrieve patients"), nil
 		}
 
-		respBody, _ := json.Marshal(patients)
-		return events.APIGatewayProxyResponse{
-			StatusCode: 200,
-			Body:       string(respBody),
-			Headers:    map[string]string{"Content-Type": "application/json"},
-		}, nil
-	})
+		if patients == nil {
+			patients = []*models.PatientRequest{}
+		}
+		return response.Page(req, patients, len(patients), next), nil
+	}))))
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/patients"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	repository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/patients"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.NewWithOutbox(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable", "OutboxTable")
+	svc := service.NewWithOptions(sugar, repo, service.Options{Events: repo})
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("importPatients", logging.Wrap(sugar, "importPatients", metrics.Wrap(m, "importPatients", openapi.Validate("importPatients", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		body := []byte(req.Body)
+		if req.IsBase64Encoded {
+			if body, err = base64.StdEncoding.DecodeString(req.Body); err != nil {
+				return response.Error(req, 400, "invalid request body"), nil
+			}
+		}
+
+		// Se acepta el CSV crudo (text/csv, con clientId como query param) o
+		// un JSON con el CSV y el mapeo de columnas
+		var request models.PatientImportRequest
+		if strings.HasPrefix(contentType(req.Headers), "text/csv") {
+			request = models.PatientImportRequest{
+				ClientID:  req.QueryStringParameters["clientId"],
+				CSV:       string(body),
+				Delimiter: req.QueryStringParameters["delimiter"],
+			}
+		} else if err := json.Unmarshal(body, &request); err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
+		}
+
+		report, err := h.Import(ctx, &request)
+		if errors.Is(err, service.ErrInvalidImport) {
+			return response.Error(req, 400, err.Error()), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error importing patients: %v", err.Error())
+			return response.Error(req, 500, "could not import patients"), nil
+		}
+
+		return response.OK(req, report), nil
+	})))))
+}
+
+func contentType(headers map[string]string) string {
+	for name, value := range headers {
+		if strings.EqualFold(name, "Content-Type") {
+			return strings.ToLower(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/duplicates"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	repository "github.com/MezeLaw/iris-services/internal/repository/patientmerges"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/duplicates"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "PatientMergesTable")
+	patientsRepo := patientsRepository.NewWithOutbox(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable", "OutboxTable")
+	appointmentsRepo := appointmentsRepository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	svc := service.New(sugar, patientsRepo, appointmentsRepo, repo)
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("mergePatients", logging.Wrap(sugar, "mergePatients", metrics.Wrap(m, "mergePatients", openapi.Validate("mergePatients", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		var request models.PatientMergeRequest
+		if err := json.Unmarshal([]byte(req.Body), &request); err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
+		}
+
+		result, err := h.Merge(ctx, &request)
+		if errors.Is(err, service.ErrInvalidMerge) {
+			return response.Error(req, 400, err.Error()), nil
+		}
+		if errors.Is(err, service.ErrPatientNotFound) {
+			return response.Error(req, 404, "patient not found"), nil
+		}
+		if errors.Is(err, service.ErrMergeConflict) {
+			return response.Error(req, 409, err.Error()), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error merging patients: %v", err.Error())
+			return response.Error(req, 500, "could not merge patients"), nil
+		}
+
+		return response.OK(req, result), nil
+	})))))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	repository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	service "github.com/MezeLaw/iris-services/internal/service/patients"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Normaliza a E.164 los teléfonos de los pacientes de un cliente guardados
+// antes de la validación. Se invoca a mano, una vez por cliente; conviene
+// correrlo primero con dry_run para revisar los que no se pueden interpretar.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-workers")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.NewWithOutbox(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable", "OutboxTable")
+	s := service.NewWithOptions(sugar, repo, service.Options{Events: repo})
+
+	lambda.Start(tracing.WrapEvent("migratePhones", func(ctx context.Context, request models.PhoneMigrationRequest) (*models.PhoneMigrationReport, error) {
+		return s.NormalizePhones(ctx, &request)
+	}))
+}
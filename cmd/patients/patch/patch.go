@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/MezeLaw/iris-services/internal/documents"
+	handler "github.com/MezeLaw/iris-services/internal/handler/patients"
+	"github.com/MezeLaw/iris-services/internal/jsonpatch"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/phones"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	repository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/patients"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// PATCH /patients/{id} con un JSON Merge Patch (application/merge-patch+json
+// o application/json) o un JSON Patch (application/json-patch+json). Sólo se
+// escriben los campos que cambian.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.NewWithOutbox(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable", "OutboxTable")
+	svc := service.NewWithOptions(sugar, repo, service.Options{Events: repo})
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("patchPatient", logging.Wrap(sugar, "patchPatient", metrics.Wrap(m, "patchPatient", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		patientID := req.PathParameters["id"]
+		if patientID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing patient ID in patch request")
+			return response.Error(req, 400, "patient ID is required for patch"), nil
+		}
+		body := []byte(req.Body)
+		if req.IsBase64Encoded {
+			if body, err = base64.StdEncoding.DecodeString(req.Body); err != nil {
+				return response.Error(req, 400, "invalid request body"), nil
+			}
+		}
+
+		patched, err := h.Patch(ctx, patientID, contentType(req.Headers), body)
+		if err != nil {
+			return errorResponse(req, err), nil
+		}
+
+		return response.OK(req, patched), nil
+	}))))
+}
+
+func errorResponse(req events.APIGatewayProxyRequest, err error) events.APIGatewayProxyResponse {
+	var status int
+	switch {
+	case errors.Is(err, jsonpatch.ErrUnsupportedType):
+		resp := response.Error(req, 415, "unsupported patch media type")
+		resp.Headers["Accept-Patch"] = jsonpatch.MergePatchType + ", " + jsonpatch.JSONPatchType
+		return resp
+	case errors.Is(err, service.ErrPatientNotFound):
+		status = 404
+	case errors.Is(err, service.ErrPatchConflict), errors.Is(err, jsonpatch.ErrTestFailed):
+		status = 409
+	case errors.Is(err, jsonpatch.ErrInvalid), errors.Is(err, jsonpatch.ErrReadOnly), errors.Is(err, service.ErrRequiredField),
+		errors.Is(err, documents.ErrUnknownType), errors.Is(err, documents.ErrInvalidNumber),
+		errors.Is(err, phones.ErrUnknownCountry), errors.Is(err, phones.ErrInvalidNumber):
+		status = 400
+	default:
+		return response.Error(req, 500, "could not patch patient")
+	}
+	return response.Error(req, status, err.Error())
+}
+
+func contentType(headers map[string]string) string {
+	for name, value := range headers {
+		if strings.EqualFold(name, "Content-Type") {
+			return strings.ToLower(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	repository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+const pageSize = 200
+
+type reindexEvent struct {
+	ClientID string `json:"client_id"`
+	Cursor   string `json:"cursor,omitempty"`
+}
+
+type reindexResult struct {
+	Indexed int    `json:"indexed"`
+	Cursor  string `json:"cursor,omitempty"` // Para continuar si se cortó por timeout
+}
+
+// Carga el índice de búsqueda con los pacientes de un cliente. Se invoca a
+// mano, una vez por cliente, para los pacientes creados antes del índice.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-workers")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+
+	lambda.Start(tracing.WrapEvent("reindexPatients", func(ctx context.Context, event reindexEvent) (*reindexResult, error) {
+		if event.ClientID == "" {
+			return nil, fmt.Errorf("client_id is required")
+		}
+		result := &reindexResult{Cursor: event.Cursor}
+		for {
+			patients, next, err := repo.GetPageByClientID(ctx, event.ClientID, result.Cursor, pageSize)
+			if err != nil {
+				sugar.Errorf("Error reading patients to reindex: %v", err)
+				return result, err
+			}
+			for _, p := range patients {
+				if err := repo.Reindex(ctx, p); err != nil {
+					sugar.Errorf("Error reindexing patient %s: %v", p.ID, err)
+					return result, err
+				}
+				result.Indexed++
+			}
+			result.Cursor = next
+			if next == "" {
+				sugar.Infof("Reindexed %d patients for client %s", result.Indexed, event.ClientID)
+				return result, nil
+			}
+		}
+	}))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/patients"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/pagination"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	repository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/patients"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	svc := service.New(sugar, repo)
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("searchPatients", logging.Wrap(sugar, "searchPatients", metrics.Wrap(m, "searchPatients", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		params := req.QueryStringParameters
+		request := models.PatientSearchRequest{
+			ClientID: params["clientId"],
+			Name:     params["name"],
+			Phone:    params["phone"],
+			Email:    params["email"],
+			Cursor:   params["cursor"],
+		}
+		if request.ClientID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing clientId parameter in request")
+			return response.Error(req, 400, "missing clientId parameter"), nil
+		}
+		if limit := params["limit"]; limit != "" {
+			if request.Limit, err = strconv.Atoi(limit); err != nil {
+				return response.Error(req, 400, "invalid limit parameter"), nil
+			}
+		}
+
+		result, err := h.Search(ctx, &request)
+		if errors.Is(err, service.ErrInvalidSearch) || errors.Is(err, pagination.ErrInvalidCursor) {
+			return response.Error(req, 400, err.Error()), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error searching patients: %v", err)
+			return response.Error(req, 500, "could not search patients"), nil
+		}
+
+		patients := result.Patients
+		if patients == nil {
+			patients = []*models.PatientRequest{}
+		}
+		return response.Page(req, patients, len(patients), result.NextCursor), nil
+	}))))
+}
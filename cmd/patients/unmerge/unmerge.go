@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/duplicates"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	repository "github.com/MezeLaw/iris-services/internal/repository/patientmerges"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/duplicates"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "PatientMergesTable")
+	patientsRepo := patientsRepository.NewWithOutbox(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable", "OutboxTable")
+	appointmentsRepo := appointmentsRepository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	svc := service.New(sugar, patientsRepo, appointmentsRepo, repo)
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("unmergePatients", logging.Wrap(sugar, "unmergePatients", metrics.Wrap(m, "unmergePatients", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		mergeID := req.PathParameters["id"]
+		if mergeID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing merge ID in request")
+			return response.Error(req, 400, "missing merge ID"), nil
+		}
+
+		result, err := h.Revert(ctx, mergeID)
+		if errors.Is(err, service.ErrMergeNotFound) {
+			return response.Error(req, 404, "patient merge not found"), nil
+		}
+		if errors.Is(err, service.ErrMergeReverted) || errors.Is(err, service.ErrDocumentConflict) || errors.Is(err, service.ErrMergeConflict) {
+			return response.Error(req, 409, err.Error()), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error reverting patient merge: %v", err.Error())
+			return response.Error(req, 500, "could not revert patient merge"), nil
+		}
+
+		return response.OK(req, result), nil
+	}))))
+}
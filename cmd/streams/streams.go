@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/streams"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// Consume los streams de PatientsTable y AppointmentsTable (NEW_AND_OLD_IMAGES)
+// con ReportBatchItemFailures habilitado en el event source mapping.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+	tracing.Setup("iris-workers")
+
+	processor := streams.NewProcessor(sugar, map[string]string{
+		"PatientsTable":     streams.EntityPatient,
+		"AppointmentsTable": streams.EntityAppointment,
+	})
+	processor.Register(streams.EntityPatient, &streams.LogProjection{Logger: sugar})
+	processor.Register(streams.EntityAppointment, &streams.LogProjection{Logger: sugar})
+	processor.Register(streams.EntityAppointment, &streams.StatusMetrics{Metrics: metrics.FromEnv()})
+
+	lambda.Start(tracing.WrapEvent("processStreams", func(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+		return processor.Handle(ctx, event), nil
+	}))
+}
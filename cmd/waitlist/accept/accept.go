@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/waitlist"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	slotholds "github.com/MezeLaw/iris-services/internal/repository/slotholds"
+	repository "github.com/MezeLaw/iris-services/internal/repository/waitlist"
+	appointmentsService "github.com/MezeLaw/iris-services/internal/service/appointments"
+	service "github.com/MezeLaw/iris-services/internal/service/waitlist"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// page es lo que ve el paciente. Como en los enlaces de los recordatorios, el
+// GET sólo muestra el turno: los previsualizadores de enlaces no lo aceptan
+// en nombre del paciente.
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="es"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Turno disponible</title></head><body>
+<p>{{.Message}}</p>
+{{if .OfferID}}<form method="post"><input type="hidden" name="offer_id" value="{{.OfferID}}"><input type="hidden" name="entry_id" value="{{.EntryID}}"><button type="submit">Sí, lo quiero</button></form>{{end}}
+</body></html>`))
+
+type view struct {
+	Message string
+	OfferID string
+	EntryID string
+}
+
+// Endpoint público del enlace de las ofertas de la lista de espera
+// (accept_url en WAITLIST_CONFIG). Los IDs de la oferta y de la entrada son
+// UUID aleatorios y sólo los recibe el paciente.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	waitlistConfig, err := service.ParseConfig(os.Getenv("WAITLIST_CONFIG"))
+	if err != nil {
+		sugar.Fatalf("error loading WAITLIST_CONFIG: %v", err)
+	}
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "WaitlistTable", "doctor_id_index", "WaitlistOffersTable", "status_index")
+	appointmentsRepo := appointmentsRepository.NewWithOutbox(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index", "OutboxTable")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	noShows, err := appointmentsService.NoShowsFromEnv(patientsRepo, appointmentsRepo)
+	if err != nil {
+		sugar.Fatalf("error loading no-show policy: %v", err)
+	}
+	holdsRepo := slotholds.New(repoClient, sugar, "SlotHoldsTable", "doctor_id_index", "AppointmentsTable", "OutboxTable")
+	holds, err := appointmentsService.HoldsFromEnv(holdsRepo)
+	if err != nil {
+		sugar.Fatalf("error loading slot hold config: %v", err)
+	}
+	appointments := appointmentsService.NewWithOptions(sugar, appointmentsRepo, appointmentsService.Options{NoShows: noShows, Holds: holds, Events: appointmentsRepo, Metrics: m})
+	svc := service.New(sugar, repo, appointmentsRepo, holdsRepo, appointments, patientsRepo, nil, waitlistConfig)
+	h := handler.New(svc, sugar)
+
+	// Un mismo Lambda atiende las dos operaciones; cada una tiene su span, su logger y sus métricas
+	accept := tracing.Wrap("acceptWaitlistOffer", logging.Wrap(sugar, "acceptWaitlistOffer", metrics.Wrap(m, "acceptWaitlistOffer", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		request := formRequest(req)
+		created, err := h.Accept(ctx, request)
+		if err != nil {
+			return errorPage(err), nil
+		}
+		return render(200, view{Message: "Listo, el turno del " + formatDate(created.Date) + " es tuyo. ¡Te esperamos!"}), nil
+	})))
+	show := tracing.Wrap("showWaitlistOffer", logging.Wrap(sugar, "showWaitlistOffer", metrics.Wrap(m, "showWaitlistOffer", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		request := &models.WaitlistAcceptRequest{
+			OfferID: req.QueryStringParameters["offer_id"],
+			EntryID: req.QueryStringParameters["entry_id"],
+		}
+		preview, err := h.CheckAccept(ctx, request)
+		if err != nil {
+			return errorPage(err), nil
+		}
+		return render(200, view{
+			Message: "Se liberó un turno el " + formatDate(preview.Date) + ". ¿Lo querés?",
+			OfferID: request.OfferID,
+			EntryID: request.EntryID,
+		}), nil
+	})))
+
+	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if req.HTTPMethod == "POST" {
+			return accept(ctx, req)
+		}
+		return show(ctx, req)
+	})
+}
+
+func formRequest(req events.APIGatewayProxyRequest) *models.WaitlistAcceptRequest {
+	body := req.Body
+	if req.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return &models.WaitlistAcceptRequest{}
+		}
+		body = string(decoded)
+	}
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return &models.WaitlistAcceptRequest{}
+	}
+	return &models.WaitlistAcceptRequest{OfferID: values.Get("offer_id"), EntryID: values.Get("entry_id")}
+}
+
+func errorPage(err error) events.APIGatewayProxyResponse {
+	switch {
+	case errors.Is(err, service.ErrOfferNotFound), errors.Is(err, service.ErrEntryNotFound):
+		return render(404, view{Message: "El enlace no es válido."})
+	case errors.Is(err, service.ErrOfferExpired):
+		return render(410, view{Message: "La oferta venció y el turno se ofreció a otro paciente."})
+	case errors.Is(err, service.ErrOfferTaken):
+		return render(409, view{Message: "Otro paciente tomó el turno antes. Seguís en la lista de espera."})
+	case errors.Is(err, appointmentsService.ErrPatientBlocked):
+		return render(409, view{Message: "No pudimos reservar el turno. Comunicate con la clínica."})
+	default:
+		return render(500, view{Message: "No pudimos procesar el pedido. Probá de nuevo en unos minutos."})
+	}
+}
+
+func render(status int, v view) events.APIGatewayProxyResponse {
+	var body bytes.Buffer
+	_ = page.Execute(&body, v)
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Body:       body.String(),
+		Headers: map[string]string{
+			"Content-Type":  "text/html; charset=utf-8",
+			"Cache-Control": "no-store",
+		},
+	}
+}
+
+// formatDate muestra la fecha en el offset con que se guardó el turno.
+func formatDate(date string) string {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return date
+	}
+	return t.Format("02/01/2006 a las 15:04")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/waitlist"
+	"github.com/MezeLaw/iris-services/internal/idempotency"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	repository "github.com/MezeLaw/iris-services/internal/repository/waitlist"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/waitlist"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	waitlistConfig, err := service.ParseConfig(os.Getenv("WAITLIST_CONFIG"))
+	if err != nil {
+		sugar.Fatalf("error loading WAITLIST_CONFIG: %v", err)
+	}
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "WaitlistTable", "doctor_id_index", "WaitlistOffersTable", "status_index")
+	appointmentsRepo := appointmentsRepository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	svc := service.New(sugar, repo, appointmentsRepo, nil, nil, patientsRepo, nil, waitlistConfig)
+	h := handler.New(svc, sugar)
+
+	idempotent, err := idempotency.FromEnv(dynamoClient, sugar, "waitlist/create")
+	if err != nil {
+		sugar.Fatalf("error loading idempotency config: %v", err)
+	}
+
+	lambda.Start(tracing.Wrap("createWaitlistEntry", logging.Wrap(sugar, "createWaitlistEntry", metrics.Wrap(m, "createWaitlistEntry", idempotent.Wrap(openapi.Validate("createWaitlistEntry", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		var request models.WaitlistEntryRequest
+		if err := json.Unmarshal([]byte(req.Body), &request); err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
+		}
+
+		created, err := h.Join(ctx, &request)
+		switch {
+		case errors.Is(err, service.ErrInvalidEntry):
+			return response.Error(req, 400, err.Error()), nil
+		case errors.Is(err, service.ErrAlreadyWaiting):
+			return response.Error(req, 409, "patient is already on the waitlist"), nil
+		case err != nil:
+			logging.FromContext(ctx, sugar).Errorf("Error joining waitlist: %v", err.Error())
+			return response.Error(req, 500, "could not join waitlist"), nil
+		}
+
+		return response.Created(req, req.Path+"/"+created.ID, created), nil
+	}))))))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/waitlist"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	appointmentsRepository "github.com/MezeLaw/iris-services/internal/repository/appointments"
+	capacity "github.com/MezeLaw/iris-services/internal/repository/capacity"
+	patientsRepository "github.com/MezeLaw/iris-services/internal/repository/patients"
+	repository "github.com/MezeLaw/iris-services/internal/repository/waitlist"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/waitlist"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	waitlistConfig, err := service.ParseConfig(os.Getenv("WAITLIST_CONFIG"))
+	if err != nil {
+		sugar.Fatalf("error loading WAITLIST_CONFIG: %v", err)
+	}
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repoClient := capacity.New(dynamoClient, m)
+
+	repo := repository.New(dynamoClient, sugar, "WaitlistTable", "doctor_id_index", "WaitlistOffersTable", "status_index")
+	appointmentsRepo := appointmentsRepository.New(repoClient, sugar, "AppointmentsTable", "client_id_index", "patient_id_index", "doctor_id_index")
+	patientsRepo := patientsRepository.New(repoClient, sugar, "PatientsTable", "client_id_index", "doc_key_index", "PatientSearchTable")
+	svc := service.New(sugar, repo, appointmentsRepo, nil, nil, patientsRepo, nil, waitlistConfig)
+	h := handler.New(svc, sugar)
+
+	lambda.Start(tracing.Wrap("listWaitlist", logging.Wrap(sugar, "listWaitlist", metrics.Wrap(m, "listWaitlist", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		doctorID := req.QueryStringParameters["doctorId"]
+		if doctorID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing doctorId parameter in request")
+			return response.Error(req, 400, "missing doctorId parameter"), nil
+		}
+
+		entries, err := h.GetByDoctorID(ctx, doctorID)
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error retrieving waitlist: %v", err)
+			return response.Error(req, 500, "could not retrieve waitlist"), nil
+		}
+
+		return response.List(req, entries), nil
+	}))))
+}
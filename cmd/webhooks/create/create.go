@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/webhooks"
+	"github.com/MezeLaw/iris-services/internal/idempotency"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	repository "github.com/MezeLaw/iris-services/internal/repository/webhooks"
+	"github.com/MezeLaw/iris-services/internal/response"
+	"github.com/MezeLaw/iris-services/internal/secrets"
+	service "github.com/MezeLaw/iris-services/internal/service/webhooks"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// La respuesta es la única vez que se devuelve el secreto de la suscripción.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	retry, err := service.RetryFromEnv()
+	if err != nil {
+		sugar.Fatalf("error loading webhook retry policy: %v", err)
+	}
+
+	dynamoClient := dynamodb.NewFromConfig(cfg)
+	m := metrics.FromEnv()
+	repo := repository.New(dynamoClient, sugar, "WebhookSubscriptionsTable", "client_id_index", "WebhookDeliveriesTable", "client_id_index", "status_index")
+	cipher, err := secrets.FromEnv(cfg)
+	if err != nil {
+		sugar.Fatalf("error loading webhook secrets key: %v", err)
+	}
+	svc := service.New(sugar, repo, nil, retry, cipher)
+	h := handler.New(svc, sugar)
+
+	idempotent, err := idempotency.FromEnv(dynamoClient, sugar, "webhooks/create")
+	if err != nil {
+		sugar.Fatalf("error loading idempotency config: %v", err)
+	}
+
+	lambda.Start(tracing.Wrap("createWebhook", logging.Wrap(sugar, "createWebhook", metrics.Wrap(m, "createWebhook", idempotent.Wrap(openapi.Validate("createWebhook", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		var request models.WebhookSubscriptionRequest
+		if err := json.Unmarshal([]byte(req.Body), &request); err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error unmarshalling request: %v", err.Error())
+			return response.Error(req, 400, "invalid request body"), nil
+		}
+
+		created, err := h.Subscribe(ctx, &request)
+		if errors.Is(err, service.ErrInvalidSubscription) {
+			return response.Error(req, 400, err.Error()), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error creating webhook subscription: %v", err.Error())
+			return response.Error(req, 500, "could not create webhook subscription"), nil
+		}
+
+		resp := response.Created(req, req.Path+"/"+created.ID, created)
+		// La respuesta incluye el secreto de firma
+		resp.Headers["Cache-Control"] = "no-store"
+		return resp, nil
+	}))))))
+}
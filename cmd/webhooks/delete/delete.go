@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/webhooks"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	repository "github.com/MezeLaw/iris-services/internal/repository/webhooks"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/webhooks"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	retry, err := service.RetryFromEnv()
+	if err != nil {
+		sugar.Fatalf("error loading webhook retry policy: %v", err)
+	}
+
+	repo := repository.New(dynamodb.NewFromConfig(cfg), sugar, "WebhookSubscriptionsTable", "client_id_index", "WebhookDeliveriesTable", "client_id_index", "status_index")
+	svc := service.New(sugar, repo, nil, retry, nil)
+	h := handler.New(svc, sugar)
+
+	m := metrics.FromEnv()
+
+	lambda.Start(tracing.Wrap("deleteWebhook", logging.Wrap(sugar, "deleteWebhook", metrics.Wrap(m, "deleteWebhook", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		id := req.PathParameters["id"]
+		clientID := req.QueryStringParameters["clientId"]
+		if id == "" || clientID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing subscription ID or clientId in request")
+			return response.Error(req, 400, "missing subscription ID or clientId parameter"), nil
+		}
+
+		err := h.Unsubscribe(ctx, clientID, id)
+		if errors.Is(err, service.ErrSubscriptionNotFound) {
+			return response.Error(req, 404, "webhook subscription not found"), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error removing webhook subscription: %v", err.Error())
+			return response.Error(req, 500, "could not remove webhook subscription"), nil
+		}
+
+		return response.NoContent(req), nil
+	}))))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	repository "github.com/MezeLaw/iris-services/internal/repository/webhooks"
+	"github.com/MezeLaw/iris-services/internal/secrets"
+	service "github.com/MezeLaw/iris-services/internal/service/webhooks"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Se dispara con una regla programada de EventBridge (por ejemplo cada
+// minuto): envía las entregas pendientes y reprograma las que fallan según
+// WEBHOOK_MAX_ATTEMPTS, WEBHOOK_BACKOFF_BASE y WEBHOOK_BACKOFF_MAX.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-workers")
+	tracing.InstrumentAWS(&cfg)
+	retry, err := service.RetryFromEnv()
+	if err != nil {
+		sugar.Fatalf("error loading webhook retry policy: %v", err)
+	}
+
+	repo := repository.New(dynamodb.NewFromConfig(cfg), sugar, "WebhookSubscriptionsTable", "client_id_index", "WebhookDeliveriesTable", "client_id_index", "status_index")
+	cipher, err := secrets.FromEnv(cfg)
+	if err != nil {
+		sugar.Fatalf("error loading webhook secrets key: %v", err)
+	}
+	svc := service.New(sugar, repo, nil, retry, cipher)
+
+	lambda.Start(tracing.WrapEvent("deliverWebhooks", func(ctx context.Context, event events.CloudWatchEvent) (*models.WebhookReport, error) {
+		now := event.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		return svc.Deliver(ctx, now)
+	}))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/webhooks"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	repository "github.com/MezeLaw/iris-services/internal/repository/webhooks"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/webhooks"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	retry, err := service.RetryFromEnv()
+	if err != nil {
+		sugar.Fatalf("error loading webhook retry policy: %v", err)
+	}
+
+	repo := repository.New(dynamodb.NewFromConfig(cfg), sugar, "WebhookSubscriptionsTable", "client_id_index", "WebhookDeliveriesTable", "client_id_index", "status_index")
+	svc := service.New(sugar, repo, nil, retry, nil)
+	h := handler.New(svc, sugar)
+
+	m := metrics.FromEnv()
+
+	lambda.Start(tracing.Wrap("listWebhooks", logging.Wrap(sugar, "listWebhooks", metrics.Wrap(m, "listWebhooks", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		clientID := req.QueryStringParameters["clientId"]
+		if clientID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing clientId parameter in request")
+			return response.Error(req, 400, "missing clientId parameter"), nil
+		}
+
+		subscriptions, err := h.GetSubscriptions(ctx, clientID)
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error retrieving webhook subscriptions: %v", err)
+			return response.Error(req, 500, "could not retrieve webhook subscriptions"), nil
+		}
+
+		return response.List(req, subscriptions), nil
+	}))))
+}
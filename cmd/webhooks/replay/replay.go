@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	handler "github.com/MezeLaw/iris-services/internal/handler/webhooks"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	repository "github.com/MezeLaw/iris-services/internal/repository/webhooks"
+	"github.com/MezeLaw/iris-services/internal/response"
+	service "github.com/MezeLaw/iris-services/internal/service/webhooks"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Reenvía una entrega del registro. La respuesta es la entrega nueva con el
+// resultado del primer intento; si falló sigue los reintentos normales.
+func main() {
+	sugar, err := logging.NewLogger()
+	if err != nil {
+		log.Fatalf("error building logger: %v", err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		sugar.Fatalf("error loading AWS config: %v", err)
+	}
+	tracing.Setup("iris-api")
+	tracing.InstrumentAWS(&cfg)
+	retry, err := service.RetryFromEnv()
+	if err != nil {
+		sugar.Fatalf("error loading webhook retry policy: %v", err)
+	}
+
+	repo := repository.New(dynamodb.NewFromConfig(cfg), sugar, "WebhookSubscriptionsTable", "client_id_index", "WebhookDeliveriesTable", "client_id_index", "status_index")
+	svc := service.New(sugar, repo, nil, retry, nil)
+	h := handler.New(svc, sugar)
+
+	m := metrics.FromEnv()
+
+	lambda.Start(tracing.Wrap("replayWebhookDelivery", logging.Wrap(sugar, "replayWebhookDelivery", metrics.Wrap(m, "replayWebhookDelivery", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		id := req.PathParameters["id"]
+		clientID := req.QueryStringParameters["clientId"]
+		if id == "" || clientID == "" {
+			logging.FromContext(ctx, sugar).Error("Missing delivery ID or clientId in request")
+			return response.Error(req, 400, "missing delivery ID or clientId parameter"), nil
+		}
+
+		replayed, err := h.Replay(ctx, clientID, id)
+		if errors.Is(err, service.ErrDeliveryNotFound) {
+			return response.Error(req, 404, "webhook delivery not found"), nil
+		}
+		if err != nil {
+			logging.FromContext(ctx, sugar).Errorf("Error replaying webhook delivery: %v", err.Error())
+			return response.Error(req, 500, "could not replay webhook delivery"), nil
+		}
+
+		return response.JSON(req, 201, replayed), nil
+	}))))
+}
@@ -0,0 +1,117 @@
+package actiontoken
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Tokens firmados que permiten a un paciente confirmar o cancelar un turno
+// desde un enlace, sin iniciar sesión. El token lleva el turno, la acción, el
+// vencimiento y un nonce; el servicio registra el nonce al usarlo para que
+// sirva una sola vez.
+
+const (
+	ActionConfirm = "confirm"
+	ActionCancel  = "cancel"
+)
+
+var (
+	ErrInvalid = errors.New("invalid action token")
+	ErrExpired = errors.New("action token expired")
+)
+
+type Claims struct {
+	AppointmentID string `json:"a"`
+	Action        string `json:"x"`
+	ExpiresAt     int64  `json:"e"`
+	Nonce         string `json:"n"`
+}
+
+type Signer struct {
+	Secret []byte
+	Now    func() time.Time
+}
+
+func NewSigner(secret []byte) *Signer {
+	return &Signer{Secret: secret}
+}
+
+// Sign devuelve el token: payload y firma en base64url separados por un punto.
+func (s *Signer) Sign(appointmentID, action string, expiresAt time.Time) (string, error) {
+	if len(s.Secret) == 0 {
+		return "", errors.New("action token secret is not configured")
+	}
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(Claims{
+		AppointmentID: appointmentID,
+		Action:        action,
+		ExpiresAt:     expiresAt.Unix(),
+		Nonce:         hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded), nil
+}
+
+// Verify controla firma y vencimiento. No sabe si el token ya se usó.
+func (s *Signer) Verify(token string) (*Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || len(s.Secret) == 0 || !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return nil, ErrInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.AppointmentID == "" || claims.Nonce == "" {
+		return nil, ErrInvalid
+	}
+	if claims.Action != ActionConfirm && claims.Action != ActionCancel {
+		return nil, ErrInvalid
+	}
+	if !s.now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, ErrExpired
+	}
+	return &claims, nil
+}
+
+func (s *Signer) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Signer) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+// Links arma los enlaces públicos a cmd/appointments/action.
+type Links struct {
+	Signer  *Signer
+	BaseURL string
+}
+
+func (l *Links) URL(appointmentID, action string, expiresAt time.Time) (string, error) {
+	token, err := l.Signer.Sign(appointmentID, action, expiresAt)
+	if err != nil {
+		return "", err
+	}
+	return l.BaseURL + "?" + url.Values{"token": {token}}.Encode(), nil
+}
@@ -0,0 +1,58 @@
+package actiontoken
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	signer := &Signer{Secret: []byte("secret"), Now: func() time.Time { return now }}
+
+	token, err := signer.Sign("a1", ActionCancel, now.Add(time.Hour))
+	require.NoError(t, err)
+
+	claims, err := signer.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "a1", claims.AppointmentID)
+	assert.Equal(t, ActionCancel, claims.Action)
+	assert.NotEmpty(t, claims.Nonce)
+
+	// Cada token es distinto aunque sea el mismo turno y acción
+	other, _ := signer.Sign("a1", ActionCancel, now.Add(time.Hour))
+	assert.NotEqual(t, token, other)
+
+	now = now.Add(2 * time.Hour)
+	_, err = signer.Verify(token)
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestVerify_Invalid(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	token, _ := signer.Sign("a1", ActionConfirm, time.Now().Add(time.Hour))
+	payload, signature, _ := strings.Cut(token, ".")
+
+	forged, _ := NewSigner([]byte("other")).Sign("a1", ActionCancel, time.Now().Add(time.Hour))
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	for _, tt := range []string{"", "garbage", payload, payload + ".x", forgedPayload + "." + signature, forged} {
+		_, err := signer.Verify(tt)
+		assert.ErrorIs(t, err, ErrInvalid, tt)
+	}
+
+	_, err := (&Signer{}).Sign("a1", ActionConfirm, time.Now())
+	assert.Error(t, err)
+}
+
+func TestLinks_URL(t *testing.T) {
+	links := &Links{Signer: NewSigner([]byte("secret")), BaseURL: "https://iris.example.com/appointments/action"}
+
+	link, err := links.URL("a1", ActionConfirm, time.Now().Add(time.Hour))
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(link, "https://iris.example.com/appointments/action?token="))
+}
@@ -0,0 +1,159 @@
+package availability
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+)
+
+// WorkingHours describe la agenda de un profesional: franja diaria, días
+// laborables y duración de cada turno.
+type WorkingHours struct {
+	Start       string // Format: HH:MM
+	End         string // Format: HH:MM
+	Weekdays    []time.Weekday
+	SlotMinutes int
+	Location    *time.Location
+}
+
+// Interval es un rango de tiempo semiabierto [Start, End).
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Slot es un turno de la grilla de la agenda, libre u ocupado.
+type Slot struct {
+	Start time.Time
+	End   time.Time
+	Busy  bool
+}
+
+func DefaultWorkingHours() WorkingHours {
+	return WorkingHours{
+		Start:       "09:00",
+		End:         "18:00",
+		Weekdays:    []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		SlotMinutes: 30,
+		Location:    time.UTC,
+	}
+}
+
+func (i Interval) Overlaps(other Interval) bool {
+	return i.Start.Before(other.End) && other.Start.Before(i.End)
+}
+
+// AppointmentInterval devuelve el rango ocupado por un turno a partir de su
+// fecha RFC3339 y su duración en minutos.
+func AppointmentInterval(a *models.Appointment) (Interval, error) {
+	start, err := time.Parse(time.RFC3339, a.Date)
+	if err != nil {
+		return Interval{}, fmt.Errorf("invalid appointment date %q: %w", a.Date, err)
+	}
+	return Interval{Start: start, End: start.Add(time.Duration(a.Duration) * time.Minute)}, nil
+}
+
+// BusyFromAppointments convierte los turnos en intervalos ocupados. Los turnos
+// cancelados no ocupan la agenda y los que tienen fecha inválida se ignoran.
+func BusyFromAppointments(appointments []*models.Appointment) []Interval {
+	busy := make([]Interval, 0, len(appointments))
+	for _, a := range appointments {
+		if a == nil || a.Status == models.AppointmentStatusCancelled {
+			continue
+		}
+		interval, err := AppointmentInterval(a)
+		if err != nil {
+			continue
+		}
+		busy = append(busy, interval)
+	}
+	return busy
+}
+
+// BusyFromHolds convierte las reservas temporales en intervalos ocupados.
+// Quien llama tiene que pasar sólo las vigentes.
+func BusyFromHolds(holds []*models.SlotHold) []Interval {
+	busy := make([]Interval, 0, len(holds))
+	for _, h := range holds {
+		if h == nil {
+			continue
+		}
+		interval, err := AppointmentInterval(&models.Appointment{Date: h.Date, Duration: h.Duration})
+		if err != nil {
+			continue
+		}
+		busy = append(busy, interval)
+	}
+	return busy
+}
+
+// Slots arma la grilla de turnos entre from y to según la agenda, marcando
+// como ocupados los que se superponen con algún intervalo de busy.
+func Slots(from, to time.Time, hours WorkingHours, busy []Interval) ([]Slot, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("invalid range: start must be before end")
+	}
+	if hours.SlotMinutes <= 0 {
+		return nil, fmt.Errorf("invalid slot duration: %d", hours.SlotMinutes)
+	}
+	loc := hours.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	dayStart, err := parseClock(hours.Start)
+	if err != nil {
+		return nil, err
+	}
+	dayEnd, err := parseClock(hours.End)
+	if err != nil {
+		return nil, err
+	}
+	if dayEnd <= dayStart {
+		return nil, fmt.Errorf("invalid working hours: %s-%s", hours.Start, hours.End)
+	}
+
+	slotLength := time.Duration(hours.SlotMinutes) * time.Minute
+	var slots []Slot
+	from, to = from.In(loc), to.In(loc)
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !worksOn(hours.Weekdays, day.Weekday()) {
+			continue
+		}
+		end := day.Add(dayEnd)
+		for start := day.Add(dayStart); !start.Add(slotLength).After(end); start = start.Add(slotLength) {
+			slot := Interval{Start: start, End: start.Add(slotLength)}
+			if slot.Start.Before(from) || slot.End.After(to) {
+				continue
+			}
+			slots = append(slots, Slot{Start: slot.Start, End: slot.End, Busy: overlapsAny(slot, busy)})
+		}
+	}
+	return slots, nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", value, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func worksOn(weekdays []time.Weekday, day time.Weekday) bool {
+	for _, w := range weekdays {
+		if w == day {
+			return true
+		}
+	}
+	return false
+}
+
+func overlapsAny(slot Interval, busy []Interval) bool {
+	for _, b := range busy {
+		if slot.Overlaps(b) {
+			return true
+		}
+	}
+	return false
+}
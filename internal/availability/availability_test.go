@@ -0,0 +1,66 @@
+package availability
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlots_MarksBusySlots(t *testing.T) {
+	// 2024-01-15 es lunes
+	from := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+	hours := WorkingHours{Start: "09:00", End: "11:00", Weekdays: []time.Weekday{time.Monday}, SlotMinutes: 30}
+
+	busy := BusyFromAppointments([]*models.Appointment{
+		{ID: "a1", Date: "2024-01-15T09:30:00Z", Duration: 45, Status: models.AppointmentStatusScheduled},
+		{ID: "a2", Date: "2024-01-15T10:30:00Z", Duration: 30, Status: models.AppointmentStatusCancelled},
+	})
+
+	slots, err := Slots(from, to, hours, busy)
+
+	assert.NoError(t, err)
+	assert.Len(t, slots, 4)
+	assert.False(t, slots[0].Busy)
+	assert.True(t, slots[1].Busy)
+	assert.True(t, slots[2].Busy)
+	assert.False(t, slots[3].Busy)
+}
+
+func TestSlots_SkipsNonWorkingDays(t *testing.T) {
+	// 2024-01-13 es sábado
+	from := time.Date(2024, 1, 13, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 2)
+
+	slots, err := Slots(from, to, DefaultWorkingHours(), nil)
+
+	assert.NoError(t, err)
+	assert.Empty(t, slots)
+}
+
+func TestSlots_RespectsLocation(t *testing.T) {
+	loc := time.FixedZone("ART", -3*60*60)
+	from := time.Date(2024, 1, 15, 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 0, 1)
+	hours := WorkingHours{Start: "09:00", End: "10:00", Weekdays: []time.Weekday{time.Monday}, SlotMinutes: 60, Location: loc}
+
+	slots, err := Slots(from, to, hours, nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, slots, 1)
+	assert.Equal(t, time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), slots[0].Start.UTC())
+}
+
+func TestSlots_InvalidParameters(t *testing.T) {
+	now := time.Now()
+
+	_, err := Slots(now, now, DefaultWorkingHours(), nil)
+	assert.Error(t, err)
+
+	hours := DefaultWorkingHours()
+	hours.End = "08:00"
+	_, err = Slots(now, now.Add(time.Hour), hours, nil)
+	assert.Error(t, err)
+}
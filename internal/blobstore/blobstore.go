@@ -0,0 +1,30 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// Almacenamiento de archivos generados (exportaciones) con enlaces de
+// descarga que vencen.
+
+var (
+	ErrNotFound = errors.New("blob not found")
+	ErrExpired  = errors.New("download link expired or invalid")
+)
+
+// Writer recibe el contenido en streaming. El objeto sólo queda visible al
+// llamar a Close; Abort descarta lo escrito.
+type Writer interface {
+	io.Writer
+	Close() error
+	Abort() error
+}
+
+type Store interface {
+	Create(ctx context.Context, key, contentType string) (Writer, error)
+	// URL devuelve una referencia de descarga válida durante ttl.
+	URL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
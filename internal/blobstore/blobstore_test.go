@@ -0,0 +1,162 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := &FileStore{Dir: t.TempDir(), BaseURL: "https://files.example.com/", Secret: []byte("secret"), Now: func() time.Time { return now }}
+	ctx := context.Background()
+
+	w, err := store.Create(ctx, "exports/client1/job.csv", "text/csv")
+	require.NoError(t, err)
+	_, err = io.WriteString(w, "id,name\n1,Ana\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	link, err := store.URL(ctx, "exports/client1/job.csv", 15*time.Minute)
+	require.NoError(t, err)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "/exports/client1/job.csv", u.Path)
+	expires, signature := u.Query().Get("expires"), u.Query().Get("signature")
+
+	f, err := store.Open("exports/client1/job.csv", expires, signature)
+	require.NoError(t, err)
+	content, _ := io.ReadAll(f)
+	f.Close()
+	assert.Equal(t, "id,name\n1,Ana\n", string(content))
+
+	_, err = store.Open("exports/client1/job.csv", expires, "bad")
+	assert.ErrorIs(t, err, ErrExpired)
+	_, err = store.Open("exports/client2/job.csv", expires, signature)
+	assert.ErrorIs(t, err, ErrExpired)
+
+	now = now.Add(16 * time.Minute)
+	_, err = store.Open("exports/client1/job.csv", expires, signature)
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestFileStore_AbortAndInvalidKeys(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir, "", []byte("secret"))
+	ctx := context.Background()
+
+	w, err := store.Create(ctx, "a/b.csv", "text/csv")
+	require.NoError(t, err)
+	io.WriteString(w, "partial")
+	require.NoError(t, w.Abort())
+	entries, _ := os.ReadDir(dir + "/a")
+	assert.Empty(t, entries)
+
+	for _, key := range []string{"", "../etc/passwd", "a/../../b", "/abs"} {
+		_, err := store.Create(ctx, key, "text/csv")
+		assert.Error(t, err, key)
+	}
+}
+
+type MockS3Client struct {
+	mock.Mock
+	uploaded bytes.Buffer
+}
+
+func (m *MockS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	io.Copy(&m.uploaded, params.Body)
+	args := m.Called(ctx, params)
+	return &s3.PutObjectOutput{}, args.Error(0)
+}
+
+func (m *MockS3Client) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	args := m.Called(ctx, params)
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, args.Error(0)
+}
+
+func (m *MockS3Client) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	io.Copy(&m.uploaded, params.Body)
+	args := m.Called(ctx, params)
+	return &s3.UploadPartOutput{ETag: aws.String("etag")}, args.Error(0)
+}
+
+func (m *MockS3Client) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	args := m.Called(ctx, params)
+	return &s3.CompleteMultipartUploadOutput{}, args.Error(0)
+}
+
+func (m *MockS3Client) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	args := m.Called(ctx, params)
+	return &s3.AbortMultipartUploadOutput{}, args.Error(0)
+}
+
+type MockPresigner struct {
+	mock.Mock
+}
+
+func (m *MockPresigner) PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+	options := s3.PresignOptions{}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	args := m.Called(*params.Key, options.Expires)
+	return &v4.PresignedHTTPRequest{URL: args.String(0)}, args.Error(1)
+}
+
+func TestS3Store_SmallObjectUsesPutObject(t *testing.T) {
+	client := new(MockS3Client)
+	store := &S3Store{Client: client, Bucket: "exports"}
+	client.On("PutObject", mock.Anything, mock.Anything).Return(nil)
+
+	w, _ := store.Create(context.Background(), "k.csv", "text/csv")
+	io.WriteString(w, "small")
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "small", client.uploaded.String())
+	client.AssertNotCalled(t, "CreateMultipartUpload", mock.Anything, mock.Anything)
+}
+
+func TestS3Store_LargeObjectUsesMultipart(t *testing.T) {
+	client := new(MockS3Client)
+	store := &S3Store{Client: client, Bucket: "exports"}
+	client.On("CreateMultipartUpload", mock.Anything, mock.Anything).Return(nil).Once()
+	client.On("UploadPart", mock.Anything, mock.Anything).Return(nil).Twice()
+	client.On("CompleteMultipartUpload", mock.Anything, mock.MatchedBy(func(in *s3.CompleteMultipartUploadInput) bool {
+		return len(in.MultipartUpload.Parts) == 2 && *in.MultipartUpload.Parts[1].PartNumber == 2
+	})).Return(nil).Once()
+
+	data := strings.Repeat("x", partSize+100)
+	w, _ := store.Create(context.Background(), "k.csv", "text/csv")
+	for i := 0; i < len(data); i += 1000 {
+		end := min(i+1000, len(data))
+		_, err := io.WriteString(w, data[i:end])
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, len(data), client.uploaded.Len())
+	client.AssertExpectations(t)
+}
+
+func TestS3Store_URL(t *testing.T) {
+	presigner := new(MockPresigner)
+	store := &S3Store{Presigner: presigner, Bucket: "exports"}
+	presigner.On("PresignGetObject", "k.csv", 15*time.Minute).Return("https://s3.example.com/k.csv?X-Amz-Expires=900", nil)
+
+	link, err := store.URL(context.Background(), "k.csv", 15*time.Minute)
+
+	require.NoError(t, err)
+	assert.Contains(t, link, "X-Amz-Expires=900")
+}
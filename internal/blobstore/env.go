@@ -0,0 +1,27 @@
+package blobstore
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// FromEnv usa S3 si está EXPORT_BUCKET; si no, un FileStore en EXPORT_DIR
+// cuyos enlaces se sirven desde EXPORT_BASE_URL.
+func FromEnv(cfg aws.Config) Store {
+	if bucket := os.Getenv("EXPORT_BUCKET"); bucket != "" {
+		return NewS3Store(s3.NewFromConfig(cfg), bucket)
+	}
+	return FileStoreFromEnv()
+}
+
+// FileStoreFromEnv devuelve el FileStore local; cmd/exports/download lo usa
+// para validar los enlaces y servir los archivos.
+func FileStoreFromEnv() *FileStore {
+	dir := os.Getenv("EXPORT_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return NewFileStore(dir, os.Getenv("EXPORT_BASE_URL"), []byte(os.Getenv("EXPORT_SIGNING_SECRET")))
+}
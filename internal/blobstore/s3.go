@@ -0,0 +1,139 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// partSize es el tamaño de cada parte del multipart upload; S3 exige al
+// menos 5 MiB salvo en la última.
+const partSize = 8 << 20
+
+type S3Client interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+type S3Presigner interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+type S3Store struct {
+	Client    S3Client
+	Presigner S3Presigner
+	Bucket    string
+}
+
+func NewS3Store(client *s3.Client, bucket string) *S3Store {
+	return &S3Store{Client: client, Presigner: s3.NewPresignClient(client), Bucket: bucket}
+}
+
+func (s *S3Store) Create(ctx context.Context, key, contentType string) (Writer, error) {
+	return &s3Writer{ctx: ctx, store: s, key: key, contentType: contentType}, nil
+}
+
+// URL firma un GET del objeto que vence a los ttl.
+func (s *S3Store) URL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	req, err := s.Presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+// s3Writer acumula hasta partSize en memoria. Si el contenido entra en una
+// parte se sube con PutObject; si no, con multipart upload.
+type s3Writer struct {
+	ctx         context.Context
+	store       *S3Store
+	key         string
+	contentType string
+	buf         bytes.Buffer
+	uploadID    *string
+	parts       []types.CompletedPart
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	n, _ := w.buf.Write(p)
+	for w.buf.Len() >= partSize {
+		if err := w.uploadPart(w.buf.Next(partSize)); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (w *s3Writer) uploadPart(data []byte) error {
+	if w.uploadID == nil {
+		out, err := w.store.Client.CreateMultipartUpload(w.ctx, &s3.CreateMultipartUploadInput{
+			Bucket:      aws.String(w.store.Bucket),
+			Key:         aws.String(w.key),
+			ContentType: aws.String(w.contentType),
+		})
+		if err != nil {
+			return err
+		}
+		w.uploadID = out.UploadId
+	}
+	number := int32(len(w.parts) + 1)
+	out, err := w.store.Client.UploadPart(w.ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(w.store.Bucket),
+		Key:        aws.String(w.key),
+		UploadId:   w.uploadID,
+		PartNumber: aws.Int32(number),
+		Body:       bytes.NewReader(data),
+	})
+	if err != nil {
+		return err
+	}
+	w.parts = append(w.parts, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(number)})
+	return nil
+}
+
+func (w *s3Writer) Close() error {
+	if w.uploadID == nil {
+		_, err := w.store.Client.PutObject(w.ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(w.store.Bucket),
+			Key:         aws.String(w.key),
+			ContentType: aws.String(w.contentType),
+			Body:        bytes.NewReader(w.buf.Bytes()),
+		})
+		return err
+	}
+	if w.buf.Len() > 0 {
+		if err := w.uploadPart(w.buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	_, err := w.store.Client.CompleteMultipartUpload(w.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(w.store.Bucket),
+		Key:             aws.String(w.key),
+		UploadId:        w.uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: w.parts},
+	})
+	return err
+}
+
+func (w *s3Writer) Abort() error {
+	if w.uploadID == nil {
+		return nil
+	}
+	_, err := w.store.Client.AbortMultipartUpload(w.ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(w.store.Bucket),
+		Key:      aws.String(w.key),
+		UploadId: w.uploadID,
+	})
+	return err
+}
@@ -0,0 +1,109 @@
+package documents
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Catálogo de tipos de documento por país. Normalize unifica cómo se
+// escriben ("D.N.I. 30.123.456" → DNI 30123456) y valida el dígito
+// verificador cuando el documento lo tiene.
+
+var (
+	ErrUnknownType   = errors.New("unknown document type")
+	ErrInvalidNumber = errors.New("invalid document number")
+)
+
+const (
+	DNI      = "DNI"
+	CUIT     = "CUIT"
+	CUIL     = "CUIL"
+	CPF      = "CPF"
+	RUT      = "RUT"
+	CI       = "CI"
+	Passport = "PASSPORT"
+)
+
+// Type describe un tipo de documento. Country es el código ISO 3166-1
+// alpha-2, vacío para los que no dependen del país (pasaporte).
+type Type struct {
+	Code    string `json:"code"`
+	Country string `json:"country,omitempty"`
+	Name    string `json:"name"`
+	Format  string `json:"format"` // Cómo queda guardado el número
+
+	normalize func(string) (string, error)
+}
+
+var catalog = []Type{
+	{Code: DNI, Country: "AR", Name: "Documento Nacional de Identidad", Format: "12345678", normalize: normalizeDNI},
+	{Code: CUIT, Country: "AR", Name: "Clave Única de Identificación Tributaria", Format: "20123456786", normalize: normalizeCUIT},
+	{Code: CUIL, Country: "AR", Name: "Código Único de Identificación Laboral", Format: "20123456786", normalize: normalizeCUIT},
+	{Code: CPF, Country: "BR", Name: "Cadastro de Pessoas Físicas", Format: "12345678909", normalize: normalizeCPF},
+	{Code: RUT, Country: "CL", Name: "Rol Único Tributario", Format: "12345678-5", normalize: normalizeRUT},
+	{Code: CI, Country: "UY", Name: "Cédula de Identidad", Format: "12345672", normalize: normalizeCI},
+	{Code: Passport, Name: "Pasaporte", Format: "AAA123456", normalize: normalizePassport},
+}
+
+// aliases son otras formas en que llegan los tipos, ya sin puntos ni
+// espacios y en mayúsculas.
+var aliases = map[string]string{
+	"DOCUMENTO": DNI,
+	"RUN":       RUT,
+	"CEDULA":    CI,
+	"CÉDULA":    CI,
+	"PASAPORTE": Passport,
+	"PASS":      Passport,
+	"PAS":       Passport,
+}
+
+// Types devuelve el catálogo. Si country no está vacío, sólo los tipos de
+// ese país y los que valen para cualquiera.
+func Types(country string) []Type {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	var types []Type
+	for _, t := range catalog {
+		if country == "" || t.Country == "" || t.Country == country {
+			types = append(types, t)
+		}
+	}
+	sort.SliceStable(types, func(i, j int) bool { return types[i].Country > types[j].Country })
+	return types
+}
+
+// Lookup devuelve el tipo correspondiente a code, aceptando variantes como
+// "dni" o "D.N.I.".
+func Lookup(code string) (Type, bool) {
+	key := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, code)
+	if alias, ok := aliases[key]; ok {
+		key = alias
+	}
+	for _, t := range catalog {
+		if t.Code == key {
+			return t, true
+		}
+	}
+	return Type{}, false
+}
+
+// Normalize devuelve el código canónico del tipo y el número sin formato.
+// Falla con ErrUnknownType o ErrInvalidNumber.
+func Normalize(docType, docNumber string) (string, string, error) {
+	t, ok := Lookup(docType)
+	if !ok {
+		return "", "", fmt.Errorf("%w: %q", ErrUnknownType, docType)
+	}
+	number, err := t.normalize(strings.TrimSpace(docNumber))
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %s %s: %v", ErrInvalidNumber, t.Code, docNumber, err)
+	}
+	return t.Code, number, nil
+}
@@ -0,0 +1,59 @@
+package documents
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		docType, docNumber string
+		wantType, wantNum  string
+	}{
+		{"D.N.I.", "30.123.456", DNI, "30123456"},
+		{"dni", " 07123456 ", DNI, "7123456"},
+		{"cuit", "20-12345678-6", CUIT, "20123456786"},
+		{"C.U.I.L.", "20123456786", CUIL, "20123456786"},
+		{"CPF", "529.982.247-25", CPF, "52998224725"},
+		{"rut", "12.345.678-5", RUT, "12345678-5"},
+		{"RUN", "10.000.013-k", RUT, "10000013-K"},
+		{"Cédula", "1.234.567-2", CI, "12345672"},
+		{"pasaporte", "aab 123456", Passport, "AAB123456"},
+	}
+	for _, tt := range tests {
+		docType, number, err := Normalize(tt.docType, tt.docNumber)
+		if assert.NoError(t, err, tt.docType+" "+tt.docNumber) {
+			assert.Equal(t, tt.wantType, docType)
+			assert.Equal(t, tt.wantNum, number)
+		}
+	}
+}
+
+func TestNormalize_Invalid(t *testing.T) {
+	_, _, err := Normalize("LE", "1234567")
+	assert.ErrorIs(t, err, ErrUnknownType)
+
+	for _, tt := range []struct{ docType, docNumber string }{
+		{"DNI", "123"},
+		{"DNI", "30123456a"},
+		{"CUIT", "20-12345678-5"},
+		{"CPF", "529.982.247-26"},
+		{"CPF", "111.111.111-11"},
+		{"RUT", "12.345.678-K"},
+		{"CI", "1.234.567-3"},
+		{"PASSPORT", "A1"},
+	} {
+		_, _, err := Normalize(tt.docType, tt.docNumber)
+		assert.ErrorIs(t, err, ErrInvalidNumber, tt.docType+" "+tt.docNumber)
+	}
+}
+
+func TestTypes(t *testing.T) {
+	var codes []string
+	for _, tp := range Types("ar") {
+		codes = append(codes, tp.Code)
+	}
+	assert.Equal(t, []string{DNI, CUIT, CUIL, Passport}, codes)
+	assert.Len(t, Types(""), len(catalog))
+}
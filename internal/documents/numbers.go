@@ -0,0 +1,173 @@
+package documents
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var (
+	errLength   = errors.New("wrong length")
+	errChecksum = errors.New("check digit does not match")
+	errFormat   = errors.New("unexpected characters")
+)
+
+// digits quita los separadores habituales (puntos, guiones, espacios,
+// barras) y falla si queda algo que no sea un dígito.
+func digits(s string) (string, error) {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '.' || r == '-' || r == '/' || unicode.IsSpace(r):
+		default:
+			return "", errFormat
+		}
+	}
+	return b.String(), nil
+}
+
+func normalizeDNI(s string) (string, error) {
+	d, err := digits(s)
+	if err != nil {
+		return "", err
+	}
+	d = strings.TrimLeft(d, "0")
+	if len(d) < 6 || len(d) > 8 {
+		return "", errLength
+	}
+	return d, nil
+}
+
+// normalizeCUIT valida CUIT y CUIL: 11 dígitos con verificador módulo 11.
+func normalizeCUIT(s string) (string, error) {
+	d, err := digits(s)
+	if err != nil {
+		return "", err
+	}
+	if len(d) != 11 {
+		return "", errLength
+	}
+	weights := []int{5, 4, 3, 2, 7, 6, 5, 4, 3, 2}
+	sum := 0
+	for i, w := range weights {
+		sum += int(d[i]-'0') * w
+	}
+	check := 11 - sum%11
+	switch check {
+	case 11:
+		check = 0
+	case 10:
+		// AFIP nunca asigna un verificador 10: cambia el prefijo
+		return "", errChecksum
+	}
+	if int(d[10]-'0') != check {
+		return "", errChecksum
+	}
+	return d, nil
+}
+
+func normalizeCPF(s string) (string, error) {
+	d, err := digits(s)
+	if err != nil {
+		return "", err
+	}
+	if len(d) != 11 {
+		return "", errLength
+	}
+	if strings.Count(d, d[:1]) == len(d) {
+		// 000.000.000-00, 111.111.111-11, etc. pasan el cálculo pero no existen
+		return "", errChecksum
+	}
+	for _, n := range []int{9, 10} {
+		sum := 0
+		for i := 0; i < n; i++ {
+			sum += int(d[i]-'0') * (n + 1 - i)
+		}
+		check := sum * 10 % 11 % 10
+		if int(d[n]-'0') != check {
+			return "", errChecksum
+		}
+	}
+	return d, nil
+}
+
+// normalizeRUT devuelve el RUT como "cuerpo-DV", con K mayúscula.
+func normalizeRUT(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return "", errLength
+	}
+	dv := s[len(s)-1:]
+	body, err := digits(strings.TrimRight(s[:len(s)-1], "-"))
+	if err != nil {
+		return "", err
+	}
+	body = strings.TrimLeft(body, "0")
+	if len(body) < 6 || len(body) > 8 {
+		return "", errLength
+	}
+	sum, weight := 0, 2
+	for i := len(body) - 1; i >= 0; i-- {
+		sum += int(body[i]-'0') * weight
+		weight++
+		if weight > 7 {
+			weight = 2
+		}
+	}
+	expected := ""
+	switch check := 11 - sum%11; check {
+	case 11:
+		expected = "0"
+	case 10:
+		expected = "K"
+	default:
+		expected = string(rune('0' + check))
+	}
+	if dv != expected {
+		return "", errChecksum
+	}
+	return body + "-" + dv, nil
+}
+
+// normalizeCI valida la cédula uruguaya; las de 6 dígitos de cuerpo se
+// completan con un cero adelante.
+func normalizeCI(s string) (string, error) {
+	d, err := digits(s)
+	if err != nil {
+		return "", err
+	}
+	if len(d) == 7 {
+		d = "0" + d
+	}
+	if len(d) != 8 {
+		return "", errLength
+	}
+	weights := []int{2, 9, 8, 7, 6, 3, 4}
+	sum := 0
+	for i, w := range weights {
+		sum += int(d[i]-'0') * w
+	}
+	if int(d[7]-'0') != (10-sum%10)%10 {
+		return "", errChecksum
+	}
+	return d, nil
+}
+
+func normalizePassport(s string) (string, error) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(s) {
+		switch {
+		case r >= '0' && r <= '9' || r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		case r == '-' || unicode.IsSpace(r):
+		default:
+			return "", errFormat
+		}
+	}
+	if b.Len() < 5 || b.Len() > 20 {
+		return "", errLength
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,9 @@
+package events
+
+// Destination es un destino del relay. El outbox registra por Name a qué
+// destinos ya se entregó cada evento, así que el nombre tiene que ser estable
+// entre despliegues: si cambia, el destino vuelve a recibir los pendientes.
+type Destination struct {
+	Name      string
+	Publisher Publisher
+}
@@ -0,0 +1,34 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// PublisherFromEnv arma el Publisher según EVENTS_PUBLISHER: "eventbridge"
+// publica en EVENTS_BUS_NAME, "sns" en EVENTS_TOPIC_ARN (FIFO si termina en
+// .fifo) y "memory" no sale del proceso.
+func PublisherFromEnv(cfg aws.Config) (Publisher, error) {
+	switch kind := os.Getenv("EVENTS_PUBLISHER"); kind {
+	case "eventbridge":
+		source := os.Getenv("EVENTS_SOURCE")
+		if source == "" {
+			source = "iris-services"
+		}
+		return &EventBridgePublisher{Client: NewEventBridgeClient(cfg), BusName: os.Getenv("EVENTS_BUS_NAME"), Source: source}, nil
+	case "sns":
+		arn := os.Getenv("EVENTS_TOPIC_ARN")
+		if arn == "" {
+			return nil, fmt.Errorf("EVENTS_TOPIC_ARN is required for the sns publisher")
+		}
+		return &SNSPublisher{Client: sns.NewFromConfig(cfg), TopicARN: arn, FIFO: strings.HasSuffix(arn, ".fifo")}, nil
+	case "memory":
+		return &MemoryPublisher{}, nil
+	default:
+		return nil, fmt.Errorf("invalid EVENTS_PUBLISHER %q", kind)
+	}
+}
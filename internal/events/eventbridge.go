@@ -0,0 +1,147 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// eventBridgeBatchLimit es el máximo de entradas por PutEvents
+const eventBridgeBatchLimit = 10
+
+// EventBridgeEntry y EventBridgeResult son las entradas y resultados de
+// PutEvents, en el orden en que se mandaron.
+type EventBridgeEntry struct {
+	Source       string `json:"Source"`
+	DetailType   string `json:"DetailType"`
+	Detail       string `json:"Detail"`
+	EventBusName string `json:"EventBusName,omitempty"`
+}
+
+type EventBridgeResult struct {
+	EventID      string `json:"EventId,omitempty"`
+	ErrorCode    string `json:"ErrorCode,omitempty"`
+	ErrorMessage string `json:"ErrorMessage,omitempty"`
+}
+
+type EventBridgeAPI interface {
+	PutEvents(ctx context.Context, entries []EventBridgeEntry) ([]EventBridgeResult, error)
+}
+
+// EventBridgePublisher publica cada evento en BusName con el tipo como
+// detail-type, así las reglas del bus filtran por tipo sin abrir el detalle.
+type EventBridgePublisher struct {
+	Client  EventBridgeAPI
+	BusName string
+	Source  string
+}
+
+func (e *EventBridgePublisher) Publish(ctx context.Context, events []*Event) (map[string]error, error) {
+	failed := map[string]error{}
+	for start := 0; start < len(events); start += eventBridgeBatchLimit {
+		batch := events[start:min(start+eventBridgeBatchLimit, len(events))]
+		entries := make([]EventBridgeEntry, 0, len(batch))
+		sent := make([]*Event, 0, len(batch))
+		for _, event := range batch {
+			detail, err := json.Marshal(event)
+			if err != nil {
+				failed[event.ID] = err
+				continue
+			}
+			entries = append(entries, EventBridgeEntry{
+				Source:       e.Source,
+				DetailType:   event.Type,
+				Detail:       string(detail),
+				EventBusName: e.BusName,
+			})
+			sent = append(sent, event)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		results, err := e.Client.PutEvents(ctx, entries)
+		if err != nil {
+			return nil, err
+		}
+		for i, event := range sent {
+			if i >= len(results) {
+				failed[event.ID] = fmt.Errorf("eventbridge: missing result")
+				continue
+			}
+			if results[i].ErrorCode != "" {
+				failed[event.ID] = fmt.Errorf("eventbridge: %s: %s", results[i].ErrorCode, results[i].ErrorMessage)
+			}
+		}
+	}
+	return failed, nil
+}
+
+// EventBridgeClient llama a PutEvents por HTTP firmando con SigV4 y las
+// credenciales de la configuración de AWS.
+type EventBridgeClient struct {
+	HTTPClient  *http.Client
+	Endpoint    string // Si está vacío se usa el endpoint regional
+	Region      string
+	Credentials aws.CredentialsProvider
+}
+
+func NewEventBridgeClient(cfg aws.Config) *EventBridgeClient {
+	return &EventBridgeClient{Region: cfg.Region, Credentials: cfg.Credentials}
+}
+
+func (c *EventBridgeClient) PutEvents(ctx context.Context, entries []EventBridgeEntry) ([]EventBridgeResult, error) {
+	body, err := json.Marshal(map[string]interface{}{"Entries": entries})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = "https://events." + c.Region + ".amazonaws.com/"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AWSEvents.PutEvents")
+
+	credentials, err := c.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), "events", c.Region, time.Now()); err != nil {
+		return nil, err
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("eventbridge API returned %d: %s", resp.StatusCode, detail)
+	}
+	var out struct {
+		Entries []EventBridgeResult `json:"Entries"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return out.Entries, nil
+}
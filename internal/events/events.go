@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Eventos de dominio que emiten los servicios. Se guardan en el outbox en la
+// misma transacción que la entidad y un relay los publica después, así que
+// un evento existe si y sólo si el cambio quedó guardado.
+
+const (
+	PatientRegistered      = "PatientRegistered"
+	PatientUpdated         = "PatientUpdated"
+	PatientDeleted         = "PatientDeleted"
+	AppointmentBooked      = "AppointmentBooked"
+	AppointmentRescheduled = "AppointmentRescheduled"
+	AppointmentUpdated     = "AppointmentUpdated"
+	AppointmentCancelled   = "AppointmentCancelled"
+	AppointmentNoShow      = "AppointmentNoShow"
+	AppointmentDeleted     = "AppointmentDeleted"
+)
+
+// Types son todos los tipos de evento, para validar filtros de suscripción.
+var Types = []string{
+	PatientRegistered, PatientUpdated, PatientDeleted,
+	AppointmentBooked, AppointmentRescheduled, AppointmentUpdated, AppointmentCancelled, AppointmentNoShow, AppointmentDeleted,
+}
+
+const (
+	AggregatePatient     = "patient"
+	AggregateAppointment = "appointment"
+)
+
+// Version es la versión del esquema de Data. Un cambio incompatible en el
+// payload de un tipo se publica con una versión nueva para que los
+// consumidores puedan convivir con las dos.
+const Version = 1
+
+type Event struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	ClientID      string          `json:"client_id"`
+	OccurredAt    string          `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Publisher entrega los eventos al bus. Devuelve por ID los que no pudo
+// publicar; el error es para las fallas que afectan a todo el lote.
+type Publisher interface {
+	Publish(ctx context.Context, events []*Event) (map[string]error, error)
+}
+
+// New arma un evento con data serializado como payload.
+func New(eventType, aggregateType, aggregateID, clientID string, data interface{}) (*Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{
+		ID:            uuid.NewString(),
+		Type:          eventType,
+		Version:       Version,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		ClientID:      clientID,
+		OccurredAt:    time.Now().UTC().Format(time.RFC3339Nano),
+		Data:          payload,
+	}, nil
+}
@@ -0,0 +1,164 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleEvents(n int) []*Event {
+	evts := make([]*Event, 0, n)
+	for i := 0; i < n; i++ {
+		event, _ := New(AppointmentBooked, AggregateAppointment, fmt.Sprintf("a%d", i), "client1", map[string]int{"n": i})
+		evts = append(evts, event)
+	}
+	return evts
+}
+
+func TestNew(t *testing.T) {
+	event, err := New(PatientRegistered, AggregatePatient, "p1", "client1", map[string]string{"id": "p1"})
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, event.ID)
+	assert.Equal(t, Version, event.Version)
+	assert.Equal(t, "p1", event.AggregateID)
+	assert.JSONEq(t, `{"id":"p1"}`, string(event.Data))
+	assert.NotEmpty(t, event.OccurredAt)
+}
+
+func TestMemoryPublisher(t *testing.T) {
+	publisher := &MemoryPublisher{}
+	evts := sampleEvents(2)
+
+	failed, err := publisher.Publish(context.Background(), evts)
+
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+	assert.Equal(t, evts, publisher.Events())
+}
+
+type fakeSNS struct{ inputs []*sns.PublishBatchInput }
+
+func (f *fakeSNS) PublishBatch(_ context.Context, params *sns.PublishBatchInput, _ ...func(*sns.Options)) (*sns.PublishBatchOutput, error) {
+	f.inputs = append(f.inputs, params)
+	// El primer mensaje de cada lote se rechaza
+	return &sns.PublishBatchOutput{Failed: []types.BatchResultErrorEntry{{
+		Id:      params.PublishBatchRequestEntries[0].Id,
+		Code:    aws.String("InternalError"),
+		Message: aws.String("boom"),
+	}}}, nil
+}
+
+func TestSNSPublisher_Publish(t *testing.T) {
+	client := &fakeSNS{}
+	publisher := &SNSPublisher{Client: client, TopicARN: "arn:aws:sns:us-east-1:1:events.fifo", FIFO: true}
+	evts := sampleEvents(12)
+
+	failed, err := publisher.Publish(context.Background(), evts)
+
+	require.NoError(t, err)
+	require.Len(t, client.inputs, 2)
+	assert.Len(t, client.inputs[0].PublishBatchRequestEntries, 10)
+	assert.Len(t, client.inputs[1].PublishBatchRequestEntries, 2)
+	entry := client.inputs[0].PublishBatchRequestEntries[1]
+	assert.Equal(t, AppointmentBooked, *entry.MessageAttributes["event_type"].StringValue)
+	assert.Equal(t, "appointment#a1", *entry.MessageGroupId)
+	assert.Equal(t, evts[1].ID, *entry.MessageDeduplicationId)
+	assert.Len(t, failed, 2)
+	assert.Contains(t, failed, evts[0].ID)
+	assert.Contains(t, failed, evts[10].ID)
+}
+
+type fakeEventBridge struct{ calls [][]EventBridgeEntry }
+
+func (f *fakeEventBridge) PutEvents(_ context.Context, entries []EventBridgeEntry) ([]EventBridgeResult, error) {
+	f.calls = append(f.calls, entries)
+	results := make([]EventBridgeResult, len(entries))
+	for i := range results {
+		results[i].EventID = fmt.Sprint(i)
+	}
+	results[len(results)-1] = EventBridgeResult{ErrorCode: "ThrottlingException", ErrorMessage: "slow down"}
+	return results, nil
+}
+
+func TestEventBridgePublisher_Publish(t *testing.T) {
+	client := &fakeEventBridge{}
+	publisher := &EventBridgePublisher{Client: client, BusName: "iris", Source: "iris-services"}
+	evts := sampleEvents(11)
+
+	failed, err := publisher.Publish(context.Background(), evts)
+
+	require.NoError(t, err)
+	require.Len(t, client.calls, 2)
+	assert.Len(t, client.calls[0], 10)
+	assert.Equal(t, AppointmentBooked, client.calls[0][0].DetailType)
+	assert.Equal(t, "iris", client.calls[0][0].EventBusName)
+	var detail Event
+	require.NoError(t, json.Unmarshal([]byte(client.calls[0][0].Detail), &detail))
+	assert.Equal(t, evts[0].ID, detail.ID)
+	assert.Len(t, failed, 2)
+	assert.ErrorContains(t, failed[evts[9].ID], "ThrottlingException")
+	assert.Contains(t, failed, evts[10].ID)
+}
+
+func TestEventBridgeClient_PutEvents(t *testing.T) {
+	var body map[string][]EventBridgeEntry
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "AWSEvents.PutEvents", r.Header.Get("X-Amz-Target"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"FailedEntryCount":0,"Entries":[{"EventId":"e1"}]}`))
+	}))
+	defer server.Close()
+	client := &EventBridgeClient{
+		Endpoint:    server.URL,
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+	}
+
+	results, err := client.PutEvents(context.Background(), []EventBridgeEntry{{Source: "iris-services", DetailType: "X", Detail: "{}"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, []EventBridgeResult{{EventID: "e1"}}, results)
+	assert.Equal(t, "X", body["Entries"][0].DetailType)
+}
+
+func TestEventBridgeClient_PutEventsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"__type":"AccessDeniedException"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+	client := &EventBridgeClient{Endpoint: server.URL, Region: "us-east-1", Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")}
+
+	_, err := client.PutEvents(context.Background(), []EventBridgeEntry{{Source: "iris-services"}})
+
+	assert.ErrorContains(t, err, "400")
+}
+
+func TestPublisherFromEnv(t *testing.T) {
+	t.Setenv("EVENTS_PUBLISHER", "sns")
+	t.Setenv("EVENTS_TOPIC_ARN", "arn:aws:sns:us-east-1:1:events.fifo")
+	publisher, err := PublisherFromEnv(aws.Config{})
+	require.NoError(t, err)
+	assert.True(t, publisher.(*SNSPublisher).FIFO)
+
+	t.Setenv("EVENTS_PUBLISHER", "eventbridge")
+	publisher, err = PublisherFromEnv(aws.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, "iris-services", publisher.(*EventBridgePublisher).Source)
+
+	t.Setenv("EVENTS_PUBLISHER", "kafka")
+	_, err = PublisherFromEnv(aws.Config{})
+	assert.Error(t, err)
+}
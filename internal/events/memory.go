@@ -0,0 +1,27 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryPublisher guarda los eventos en memoria. Sirve para tests y para
+// correr local sin bus.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+func (m *MemoryPublisher) Publish(_ context.Context, events []*Event) (map[string]error, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, events...)
+	return nil, nil
+}
+
+// Events devuelve los eventos publicados, en orden.
+func (m *MemoryPublisher) Events() []*Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Event(nil), m.events...)
+}
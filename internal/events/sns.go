@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// snsBatchLimit es el máximo de mensajes por PublishBatch
+const snsBatchLimit = 10
+
+type SNSClient interface {
+	PublishBatch(ctx context.Context, params *sns.PublishBatchInput, optFns ...func(*sns.Options)) (*sns.PublishBatchOutput, error)
+}
+
+// SNSPublisher publica cada evento como un mensaje en TopicARN, con el tipo y
+// la versión como atributos para que las suscripciones filtren. En un topic
+// FIFO los eventos de una misma entidad comparten grupo y llegan en orden.
+type SNSPublisher struct {
+	Client   SNSClient
+	TopicARN string
+	FIFO     bool
+}
+
+func (s *SNSPublisher) Publish(ctx context.Context, events []*Event) (map[string]error, error) {
+	failed := map[string]error{}
+	for start := 0; start < len(events); start += snsBatchLimit {
+		end := min(start+snsBatchLimit, len(events))
+		entries := make([]types.PublishBatchRequestEntry, 0, end-start)
+		for _, event := range events[start:end] {
+			body, err := json.Marshal(event)
+			if err != nil {
+				failed[event.ID] = err
+				continue
+			}
+			entry := types.PublishBatchRequestEntry{
+				Id:      aws.String(event.ID),
+				Message: aws.String(string(body)),
+				MessageAttributes: map[string]types.MessageAttributeValue{
+					"event_type":    {DataType: aws.String("String"), StringValue: aws.String(event.Type)},
+					"event_version": {DataType: aws.String("Number"), StringValue: aws.String(fmt.Sprint(event.Version))},
+				},
+			}
+			if s.FIFO {
+				entry.MessageGroupId = aws.String(event.AggregateType + "#" + event.AggregateID)
+				entry.MessageDeduplicationId = aws.String(event.ID)
+			}
+			entries = append(entries, entry)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		resp, err := s.Client.PublishBatch(ctx, &sns.PublishBatchInput{
+			TopicArn:                   aws.String(s.TopicARN),
+			PublishBatchRequestEntries: entries,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range resp.Failed {
+			failed[aws.ToString(entry.Id)] = fmt.Errorf("sns: %s: %s", aws.ToString(entry.Code), aws.ToString(entry.Message))
+		}
+	}
+	return failed, nil
+}
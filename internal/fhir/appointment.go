@@ -0,0 +1,198 @@
+package fhir
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+)
+
+// Valores de Appointment.status de FHIR R4 que soportamos.
+const (
+	AppointmentStatusBooked    = "booked"
+	AppointmentStatusArrived   = "arrived"
+	AppointmentStatusFulfilled = "fulfilled"
+	AppointmentStatusCancelled = "cancelled"
+	AppointmentStatusNoShow    = "noshow"
+)
+
+const (
+	patientResource      = "Patient"
+	practitionerResource = "Practitioner"
+
+	participationTypeSystem = "http://terminology.hl7.org/CodeSystem/v3-ParticipationType"
+)
+
+func AppointmentStatusFromModel(status models.AppointmentStatus) string {
+	switch status {
+	case models.AppointmentStatusInProgress:
+		return AppointmentStatusArrived
+	case models.AppointmentStatusCompleted:
+		return AppointmentStatusFulfilled
+	case models.AppointmentStatusCancelled:
+		return AppointmentStatusCancelled
+	case models.AppointmentStatusNoShow:
+		return AppointmentStatusNoShow
+	default:
+		return AppointmentStatusBooked
+	}
+}
+
+func AppointmentStatusToModel(status string) (models.AppointmentStatus, error) {
+	switch status {
+	case AppointmentStatusBooked:
+		return models.AppointmentStatusScheduled, nil
+	case AppointmentStatusArrived:
+		return models.AppointmentStatusInProgress, nil
+	case AppointmentStatusFulfilled:
+		return models.AppointmentStatusCompleted, nil
+	case AppointmentStatusCancelled:
+		return models.AppointmentStatusCancelled, nil
+	case AppointmentStatusNoShow:
+		return models.AppointmentStatusNoShow, nil
+	default:
+		return "", fmt.Errorf("unsupported appointment status: %s. Must be one of: %s, %s, %s, %s, %s",
+			status,
+			AppointmentStatusBooked,
+			AppointmentStatusArrived,
+			AppointmentStatusFulfilled,
+			AppointmentStatusCancelled,
+			AppointmentStatusNoShow)
+	}
+}
+
+func PatientReference(id string) Reference {
+	return Reference{Reference: patientResource + "/" + id}
+}
+
+func PractitionerReference(id string) Reference {
+	return Reference{Reference: practitionerResource + "/" + id}
+}
+
+// ParseReference separa una referencia relativa "Tipo/id". Si la referencia
+// no tiene tipo se devuelve el valor completo como id.
+func ParseReference(reference string) (resourceType, id string) {
+	if i := strings.LastIndex(reference, "/"); i >= 0 {
+		prefix := reference[:i]
+		if j := strings.LastIndex(prefix, "/"); j >= 0 {
+			prefix = prefix[j+1:]
+		}
+		return prefix, reference[i+1:]
+	}
+	return "", reference
+}
+
+func clientMeta(clientID, lastUpdated string) *Meta {
+	meta := &Meta{LastUpdated: lastUpdated}
+	if clientID != "" {
+		meta.Tag = []Coding{{System: ClientTagSystem, Code: clientID}}
+	}
+	return meta
+}
+
+func clientFromMeta(meta *Meta) string {
+	if meta == nil {
+		return ""
+	}
+	for _, tag := range meta.Tag {
+		if tag.System == ClientTagSystem {
+			return tag.Code
+		}
+	}
+	return ""
+}
+
+func participant(code, display string, actor Reference) AppointmentParticipant {
+	return AppointmentParticipant{
+		Type: []CodeableConcept{{
+			Coding: []Coding{{System: participationTypeSystem, Code: code, Display: display}},
+		}},
+		Actor:    &actor,
+		Required: "required",
+		Status:   "accepted",
+	}
+}
+
+// AppointmentFromModel mapea un turno persistido a un recurso FHIR Appointment,
+// con el paciente y el médico como participantes.
+func AppointmentFromModel(a *models.Appointment) *Appointment {
+	resource := &Appointment{
+		ResourceType:    "Appointment",
+		ID:              a.ID,
+		Meta:            clientMeta(a.ClientID, a.UpdatedAt),
+		Status:          AppointmentStatusFromModel(a.Status),
+		Start:           a.Date,
+		MinutesDuration: a.Duration,
+		Created:         a.CreatedAt,
+		Comment:         a.Notes,
+		Participant: []AppointmentParticipant{
+			participant("SBJ", "subject", PatientReference(a.PatientID)),
+			participant("PPRF", "primary performer", PractitionerReference(a.DoctorID)),
+		},
+	}
+	if start, err := time.Parse(time.RFC3339, a.Date); err == nil {
+		resource.End = start.Add(time.Duration(a.Duration) * time.Minute).Format(time.RFC3339)
+	}
+	return resource
+}
+
+// AppointmentToRequest mapea un Appointment recibido de un partner al request
+// que entiende service.Appointments. El tenant se toma del tag de meta.
+func AppointmentToRequest(resource *Appointment) (*models.AppointmentRequest, error) {
+	if resource.ResourceType != "Appointment" {
+		return nil, fmt.Errorf("invalid resourceType: %s", resource.ResourceType)
+	}
+	status, err := AppointmentStatusToModel(resource.Status)
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.Parse(time.RFC3339, resource.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+
+	duration := resource.MinutesDuration
+	if resource.End != "" {
+		end, err := time.Parse(time.RFC3339, resource.End)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end: %w", err)
+		}
+		if !end.After(start) {
+			return nil, fmt.Errorf("end must be after start")
+		}
+		if duration == 0 {
+			duration = int(end.Sub(start).Minutes())
+		}
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("minutesDuration or end is required")
+	}
+
+	request := &models.AppointmentRequest{
+		ID:       resource.ID,
+		ClientID: clientFromMeta(resource.Meta),
+		Date:     start.Format(time.RFC3339),
+		Duration: duration,
+		Status:   status,
+		Notes:    resource.Comment,
+	}
+	for _, p := range resource.Participant {
+		if p.Actor == nil {
+			continue
+		}
+		switch resourceType, id := ParseReference(p.Actor.Reference); resourceType {
+		case patientResource:
+			request.PatientID = id
+		case practitionerResource:
+			request.DoctorID = id
+		}
+	}
+	if request.PatientID == "" {
+		return nil, fmt.Errorf("a Patient participant is required")
+	}
+	if request.DoctorID == "" {
+		return nil, fmt.Errorf("a Practitioner participant is required")
+	}
+	return request, nil
+}
@@ -0,0 +1,174 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/availability"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleAppointment() *models.Appointment {
+	return &models.Appointment{
+		ID:        "appointment123",
+		ClientID:  "client123",
+		PatientID: "patient123",
+		DoctorID:  "doctor123",
+		Date:      "2024-01-15T10:00:00Z",
+		Duration:  30,
+		Status:    models.AppointmentStatusScheduled,
+		Notes:     "Regular checkup",
+		CreatedAt: "2024-01-01T00:00:00Z",
+		UpdatedAt: "2024-01-02T00:00:00Z",
+	}
+}
+
+func TestAppointmentStatus_RoundTrip(t *testing.T) {
+	tests := []struct {
+		model models.AppointmentStatus
+		fhir  string
+	}{
+		{models.AppointmentStatusScheduled, AppointmentStatusBooked},
+		{models.AppointmentStatusInProgress, AppointmentStatusArrived},
+		{models.AppointmentStatusCompleted, AppointmentStatusFulfilled},
+		{models.AppointmentStatusCancelled, AppointmentStatusCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.model), func(t *testing.T) {
+			assert.Equal(t, tt.fhir, AppointmentStatusFromModel(tt.model))
+			status, err := AppointmentStatusToModel(tt.fhir)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.model, status)
+		})
+	}
+
+	_, err := AppointmentStatusToModel("proposed")
+	assert.Error(t, err)
+}
+
+func TestAppointmentFromModel(t *testing.T) {
+	resource := AppointmentFromModel(sampleAppointment())
+
+	assert.Equal(t, "Appointment", resource.ResourceType)
+	assert.Equal(t, "appointment123", resource.ID)
+	assert.Equal(t, AppointmentStatusBooked, resource.Status)
+	assert.Equal(t, "2024-01-15T10:30:00Z", resource.End)
+	assert.Equal(t, "client123", clientFromMeta(resource.Meta))
+	assert.Len(t, resource.Participant, 2)
+	assert.Equal(t, "Patient/patient123", resource.Participant[0].Actor.Reference)
+	assert.Equal(t, "Practitioner/doctor123", resource.Participant[1].Actor.Reference)
+
+	body, err := json.Marshal(resource)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"resourceType":"Appointment"`)
+}
+
+func TestAppointmentToRequest(t *testing.T) {
+	resource := AppointmentFromModel(sampleAppointment())
+	resource.MinutesDuration = 0
+
+	request, err := AppointmentToRequest(resource)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "client123", request.ClientID)
+	assert.Equal(t, "patient123", request.PatientID)
+	assert.Equal(t, "doctor123", request.DoctorID)
+	assert.Equal(t, 30, request.Duration)
+	assert.Equal(t, models.AppointmentStatusScheduled, request.Status)
+}
+
+func TestAppointmentToRequest_MissingParticipants(t *testing.T) {
+	resource := AppointmentFromModel(sampleAppointment())
+	resource.Participant = resource.Participant[1:]
+
+	_, err := AppointmentToRequest(resource)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Patient participant")
+}
+
+func TestParseReference(t *testing.T) {
+	resourceType, id := ParseReference("https://example.org/fhir/Patient/123")
+	assert.Equal(t, "Patient", resourceType)
+	assert.Equal(t, "123", id)
+
+	resourceType, id = ParseReference("123")
+	assert.Equal(t, "", resourceType)
+	assert.Equal(t, "123", id)
+}
+
+func TestDateParam_Matches(t *testing.T) {
+	at := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		match bool
+	}{
+		{"2024-01-15", true},
+		{"2024-01-16", false},
+		{"ge2024-01-15", true},
+		{"gt2024-01-15", false},
+		{"lt2024-01-15", false},
+		{"le2024-01-15", true},
+		{"ne2024-01-14", true},
+		{"eq2024-01-15T10:00:00Z", true},
+		{"lt2024-01-15T10:00:00Z", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			param, err := ParseDateParam(tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.match, param.Matches(at))
+		})
+	}
+
+	_, err := ParseDateParam("yesterday")
+	assert.Error(t, err)
+}
+
+func TestParseAppointmentSearch(t *testing.T) {
+	search, err := ParseAppointmentSearch(map[string][]string{
+		"patient": {"Patient/patient123"},
+		"date":    {"ge2024-01-01", "lt2024-02-01"},
+		"status":  {"booked,arrived"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "patient123", search.PatientID)
+	assert.Len(t, search.Dates, 2)
+	assert.True(t, search.Matches(sampleAppointment()))
+
+	cancelled := sampleAppointment()
+	cancelled.Status = models.AppointmentStatusCancelled
+	assert.False(t, search.Matches(cancelled))
+
+	_, err = ParseAppointmentSearch(map[string][]string{"status": {"waitlist"}})
+	assert.Error(t, err)
+}
+
+func TestParseSlotSearch(t *testing.T) {
+	now := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	search, err := ParseSlotSearch(map[string][]string{
+		"schedule": {"Schedule/doctor123"},
+		"status":   {"free"},
+	}, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "doctor123", search.ScheduleID)
+	assert.Equal(t, now, search.Start)
+	assert.Equal(t, now.Add(defaultSlotHorizon), search.End)
+
+	_, err = ParseSlotSearch(map[string][]string{}, now)
+	assert.Error(t, err)
+}
+
+func TestSlotFromAvailability(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	slot := SlotFromAvailability("doctor123", availability.Slot{Start: start, End: start.Add(30 * time.Minute), Busy: true})
+
+	assert.Equal(t, "Schedule/doctor123", slot.Schedule.Reference)
+	assert.Equal(t, SlotStatusBusy, slot.Status)
+	assert.Equal(t, "2024-01-15T10:30:00Z", slot.End)
+}
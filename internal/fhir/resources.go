@@ -0,0 +1,124 @@
+package fhir
+
+// Subconjunto de los recursos FHIR R4 que exponemos a partners.
+// Ver https://hl7.org/fhir/R4/resourcelist.html
+
+const (
+	ContentType = "application/fhir+json"
+
+	// ClientTagSystem identifica el tag de meta que lleva el tenant (ClientID).
+	ClientTagSystem = "https://iris.mezelaw.com/fhir/client-id"
+)
+
+type Meta struct {
+	LastUpdated string   `json:"lastUpdated,omitempty"`
+	Tag         []Coding `json:"tag,omitempty"`
+}
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+type Period struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+type AppointmentParticipant struct {
+	Type     []CodeableConcept `json:"type,omitempty"`
+	Actor    *Reference        `json:"actor,omitempty"`
+	Required string            `json:"required,omitempty"`
+	Status   string            `json:"status"`
+}
+
+type Appointment struct {
+	ResourceType    string                   `json:"resourceType"`
+	ID              string                   `json:"id,omitempty"`
+	Meta            *Meta                    `json:"meta,omitempty"`
+	Status          string                   `json:"status"`
+	Start           string                   `json:"start,omitempty"`
+	End             string                   `json:"end,omitempty"`
+	MinutesDuration int                      `json:"minutesDuration,omitempty"`
+	Created         string                   `json:"created,omitempty"`
+	Comment         string                   `json:"comment,omitempty"`
+	Slot            []Reference              `json:"slot,omitempty"`
+	Participant     []AppointmentParticipant `json:"participant"`
+}
+
+type Schedule struct {
+	ResourceType    string      `json:"resourceType"`
+	ID              string      `json:"id,omitempty"`
+	Meta            *Meta       `json:"meta,omitempty"`
+	Active          bool        `json:"active"`
+	Actor           []Reference `json:"actor"`
+	PlanningHorizon *Period     `json:"planningHorizon,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type Slot struct {
+	ResourceType string    `json:"resourceType"`
+	ID           string    `json:"id,omitempty"`
+	Schedule     Reference `json:"schedule"`
+	Status       string    `json:"status"`
+	Start        string    `json:"start"`
+	End          string    `json:"end"`
+}
+
+type BundleEntry struct {
+	FullURL  string      `json:"fullUrl,omitempty"`
+	Resource interface{} `json:"resource"`
+}
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Total        int           `json:"total"`
+	Entry        []BundleEntry `json:"entry"`
+}
+
+type OperationOutcomeIssue struct {
+	Severity    string `json:"severity"`
+	Code        string `json:"code"`
+	Diagnostics string `json:"diagnostics,omitempty"`
+}
+
+type OperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+}
+
+func NewSearchBundle(entries []BundleEntry) *Bundle {
+	if entries == nil {
+		entries = []BundleEntry{}
+	}
+	return &Bundle{
+		ResourceType: "Bundle",
+		Type:         "searchset",
+		Total:        len(entries),
+		Entry:        entries,
+	}
+}
+
+// NewOperationOutcome arma la respuesta de error estándar de FHIR.
+func NewOperationOutcome(code, diagnostics string) *OperationOutcome {
+	return &OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue: []OperationOutcomeIssue{{
+			Severity:    "error",
+			Code:        code,
+			Diagnostics: diagnostics,
+		}},
+	}
+}
@@ -0,0 +1,57 @@
+package fhir
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/availability"
+)
+
+// Valores de Slot.status de FHIR R4 que exponemos.
+const (
+	SlotStatusFree = "free"
+	SlotStatusBusy = "busy"
+)
+
+const scheduleResource = "Schedule"
+
+// ScheduleFromDoctor arma el Schedule de un médico. Cada médico tiene una única
+// agenda, por lo que el id del Schedule coincide con el del Practitioner.
+func ScheduleFromDoctor(clientID, doctorID string, hours availability.WorkingHours, horizon availability.Interval) *Schedule {
+	schedule := &Schedule{
+		ResourceType: scheduleResource,
+		ID:           doctorID,
+		Meta:         clientMeta(clientID, ""),
+		Active:       true,
+		Actor:        []Reference{PractitionerReference(doctorID)},
+		Comment:      fmt.Sprintf("%s-%s, %d minute slots", hours.Start, hours.End, hours.SlotMinutes),
+	}
+	if !horizon.Start.IsZero() && !horizon.End.IsZero() {
+		schedule.PlanningHorizon = &Period{
+			Start: horizon.Start.Format(time.RFC3339),
+			End:   horizon.End.Format(time.RFC3339),
+		}
+	}
+	return schedule
+}
+
+func ScheduleReference(doctorID string) Reference {
+	return Reference{Reference: scheduleResource + "/" + doctorID}
+}
+
+// SlotFromAvailability mapea un turno de la grilla a un recurso Slot. El id es
+// estable para un mismo médico y horario.
+func SlotFromAvailability(doctorID string, slot availability.Slot) *Slot {
+	status := SlotStatusFree
+	if slot.Busy {
+		status = SlotStatusBusy
+	}
+	return &Slot{
+		ResourceType: "Slot",
+		ID:           fmt.Sprintf("%s-%d", doctorID, slot.Start.Unix()),
+		Schedule:     ScheduleReference(doctorID),
+		Status:       status,
+		Start:        slot.Start.Format(time.RFC3339),
+		End:          slot.End.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,216 @@
+package fhir
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+)
+
+// DateParam es un parámetro de búsqueda de tipo date de FHIR, p. ej.
+// "ge2024-01-01" o "2024-01-15T10:00:00Z". El valor se interpreta como un
+// rango según su precisión: un día completo o un instante.
+type DateParam struct {
+	Prefix string
+	Start  time.Time
+	End    time.Time
+}
+
+var datePrefixes = []string{"eq", "ne", "gt", "lt", "ge", "le"}
+
+func ParseDateParam(value string) (DateParam, error) {
+	param := DateParam{Prefix: "eq"}
+	for _, prefix := range datePrefixes {
+		if strings.HasPrefix(value, prefix) {
+			param.Prefix = prefix
+			value = strings.TrimPrefix(value, prefix)
+			break
+		}
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		param.Start, param.End = t, t
+		return param, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		param.Start, param.End = t, t.AddDate(0, 0, 1)
+		return param, nil
+	}
+	return DateParam{}, fmt.Errorf("invalid date parameter: %s", value)
+}
+
+// Matches aplica el prefijo sobre el instante t.
+func (d DateParam) Matches(t time.Time) bool {
+	instant := d.Start.Equal(d.End)
+	switch d.Prefix {
+	case "gt":
+		if instant {
+			return t.After(d.Start)
+		}
+		return !t.Before(d.End)
+	case "ge":
+		return !t.Before(d.Start)
+	case "lt":
+		return t.Before(d.Start)
+	case "le":
+		if instant {
+			return !t.After(d.Start)
+		}
+		return t.Before(d.End)
+	case "ne":
+		return !d.within(t)
+	default:
+		return d.within(t)
+	}
+}
+
+func (d DateParam) within(t time.Time) bool {
+	if d.Start.Equal(d.End) {
+		return t.Equal(d.Start)
+	}
+	return !t.Before(d.Start) && t.Before(d.End)
+}
+
+// AppointmentSearch reúne los parámetros de búsqueda de Appointment que
+// soportamos: patient, practitioner, date y status.
+type AppointmentSearch struct {
+	ClientID       string
+	PatientID      string
+	PractitionerID string
+	Dates          []DateParam
+	Statuses       []string
+}
+
+// ParseAppointmentSearch interpreta los query params (multi-valor) de una
+// búsqueda de Appointment. status admite valores separados por coma.
+func ParseAppointmentSearch(params map[string][]string) (*AppointmentSearch, error) {
+	search := &AppointmentSearch{}
+	for name, values := range params {
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			switch name {
+			case "clientId":
+				search.ClientID = value
+			case "patient", "actor:Patient":
+				search.PatientID = referenceID(value)
+			case "practitioner", "actor:Practitioner":
+				search.PractitionerID = referenceID(value)
+			case "date":
+				date, err := ParseDateParam(value)
+				if err != nil {
+					return nil, err
+				}
+				search.Dates = append(search.Dates, date)
+			case "status":
+				for _, status := range strings.Split(value, ",") {
+					if _, err := AppointmentStatusToModel(status); err != nil {
+						return nil, err
+					}
+					search.Statuses = append(search.Statuses, status)
+				}
+			}
+		}
+	}
+	return search, nil
+}
+
+func referenceID(value string) string {
+	_, id := ParseReference(value)
+	return id
+}
+
+func (s *AppointmentSearch) Matches(a *models.Appointment) bool {
+	if s.ClientID != "" && a.ClientID != s.ClientID {
+		return false
+	}
+	if s.PatientID != "" && a.PatientID != s.PatientID {
+		return false
+	}
+	if s.PractitionerID != "" && a.DoctorID != s.PractitionerID {
+		return false
+	}
+	if len(s.Statuses) > 0 && !contains(s.Statuses, AppointmentStatusFromModel(a.Status)) {
+		return false
+	}
+	if len(s.Dates) > 0 {
+		start, err := time.Parse(time.RFC3339, a.Date)
+		if err != nil {
+			return false
+		}
+		for _, date := range s.Dates {
+			if !date.Matches(start) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// SlotSearch reúne los parámetros de búsqueda de Slot: schedule, start y
+// status. Sin rango explícito se buscan los próximos siete días.
+type SlotSearch struct {
+	ClientID   string
+	ScheduleID string
+	Start      time.Time
+	End        time.Time
+	Status     string
+}
+
+const defaultSlotHorizon = 7 * 24 * time.Hour
+
+func ParseSlotSearch(params map[string][]string, now time.Time) (*SlotSearch, error) {
+	search := &SlotSearch{}
+	for name, values := range params {
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			switch name {
+			case "clientId":
+				search.ClientID = value
+			case "schedule", "schedule.actor", "practitioner":
+				search.ScheduleID = referenceID(value)
+			case "status":
+				if value != SlotStatusFree && value != SlotStatusBusy {
+					return nil, fmt.Errorf("unsupported slot status: %s. Must be one of: %s, %s", value, SlotStatusFree, SlotStatusBusy)
+				}
+				search.Status = value
+			case "start":
+				date, err := ParseDateParam(value)
+				if err != nil {
+					return nil, err
+				}
+				switch date.Prefix {
+				case "ge", "gt":
+					search.Start = date.Start
+				case "le", "lt":
+					search.End = date.End
+				default:
+					search.Start, search.End = date.Start, date.End
+				}
+			}
+		}
+	}
+	if search.ScheduleID == "" {
+		return nil, fmt.Errorf("schedule parameter is required")
+	}
+	if search.Start.IsZero() {
+		search.Start = now
+	}
+	if search.End.IsZero() || !search.End.After(search.Start) {
+		search.End = search.Start.Add(defaultSlotHorizon)
+	}
+	return search, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
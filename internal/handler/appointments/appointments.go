@@ -4,24 +4,38 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/MezeLaw/iris-services/internal/logging"
 	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"go.uber.org/zap"
 )
 
 type AppointmentsHandler interface {
 	Create(context.Context, *models.AppointmentRequest) (*models.AppointmentRequest, error)
 	Get(ctx context.Context, getAppointment *models.GetAppointmentRequest) (*models.AppointmentRequest, error)
-	GetAll(ctx context.Context, clientID string) ([]*models.AppointmentRequest, error)
+	GetAll(ctx context.Context, clientID, cursor string, limit int) ([]*models.AppointmentRequest, string, error)
 	Update(ctx context.Context, appointment *models.AppointmentRequest) (*models.AppointmentRequest, error)
+	Patch(ctx context.Context, id, contentType string, patch []byte) (*models.AppointmentRequest, error)
 	Delete(ctx context.Context, appointmentID string) error
+	CheckAction(ctx context.Context, token string) (*models.AppointmentAction, error)
+	ApplyAction(ctx context.Context, token string) (*models.AppointmentAction, error)
+	HoldSlot(ctx context.Context, hold *models.SlotHoldRequest) (*models.SlotHoldRequest, error)
+	ConfirmHold(ctx context.Context, holdID string, appointment *models.AppointmentRequest) (*models.AppointmentRequest, error)
+	ReleaseHold(ctx context.Context, holdID string) error
 }
 
 type AppointmentsService interface {
 	CreateAppointment(context.Context, *models.AppointmentRequest) (*models.AppointmentRequest, error)
 	GetAppointment(context.Context, *models.GetAppointmentRequest) (*models.AppointmentRequest, error)
-	GetAllAppointments(context.Context, string) ([]*models.AppointmentRequest, error)
-	UpdateAppointment(context.Context, *models.AppointmentRequest) error
+	GetAllAppointments(ctx context.Context, clientID, cursor string, limit int) ([]*models.AppointmentRequest, string, error)
+	UpdateAppointment(context.Context, *models.AppointmentRequest) (*models.AppointmentRequest, error)
+	PatchAppointment(ctx context.Context, id, contentType string, patch []byte) (*models.AppointmentRequest, error)
 	DeleteAppointment(context.Context, string) error
+	CheckAction(context.Context, string) (*models.AppointmentAction, error)
+	ApplyAction(context.Context, string) (*models.AppointmentAction, error)
+	HoldSlot(context.Context, *models.SlotHoldRequest) (*models.SlotHoldRequest, error)
+	ConfirmHold(context.Context, string, *models.AppointmentRequest) (*models.AppointmentRequest, error)
+	ReleaseHold(context.Context, string) error
 }
 
 type Appointments struct {
@@ -34,78 +48,182 @@ func New(service AppointmentsService, logger *zap.SugaredLogger) AppointmentsHan
 }
 
 func (a *Appointments) Create(ctx context.Context, appointment *models.AppointmentRequest) (*models.AppointmentRequest, error) {
-	a.Logger.Infof("Creating appointment: %s", appointment)
+	ctx, span := tracing.Start(ctx, "handler.Appointments.Create")
+	defer span.End()
+	a.log(ctx).Infof("Creating appointment: %s", appointment)
 	if appointment.Status != models.AppointmentStatusScheduled &&
+		appointment.Status != models.AppointmentStatusConfirmed &&
 		appointment.Status != models.AppointmentStatusInProgress &&
 		appointment.Status != models.AppointmentStatusCompleted &&
-		appointment.Status != models.AppointmentStatusCancelled {
-		err := fmt.Errorf("invalid status value: %s. Must be one of: %s, %s, %s, %s",
+		appointment.Status != models.AppointmentStatusCancelled &&
+		appointment.Status != models.AppointmentStatusNoShow {
+		err := fmt.Errorf("invalid status value: %s. Must be one of: %s, %s, %s, %s, %s, %s",
 			appointment.Status,
 			models.AppointmentStatusScheduled,
+			models.AppointmentStatusConfirmed,
 			models.AppointmentStatusInProgress,
 			models.AppointmentStatusCompleted,
-			models.AppointmentStatusCancelled)
-		a.Logger.Error(err)
+			models.AppointmentStatusCancelled,
+			models.AppointmentStatusNoShow)
+		tracing.Error(span, err)
+		a.log(ctx).Error(err)
 		return nil, err
 	}
 	result, err := a.Service.CreateAppointment(ctx, appointment)
 	if err != nil {
-		a.Logger.Errorf("Error creating appointment: %s", err)
+		tracing.Error(span, err)
+		a.log(ctx).Errorf("Error creating appointment: %s", err)
 		return nil, err
 	}
 	return result, nil
 }
 
 func (a *Appointments) Get(ctx context.Context, getRequest *models.GetAppointmentRequest) (*models.AppointmentRequest, error) {
-	a.Logger.Infof("Getting appointment with params: %s", getRequest)
+	ctx, span := tracing.Start(ctx, "handler.Appointments.Get")
+	defer span.End()
+	a.log(ctx).Infof("Getting appointment with params: %s", getRequest)
 	result, err := a.Service.GetAppointment(ctx, getRequest)
 	if err != nil {
-		a.Logger.Errorf("Error getting appointment: %s", err)
+		tracing.Error(span, err)
+		a.log(ctx).Errorf("Error getting appointment: %s", err)
 		return nil, err
 	}
 	return result, nil
 }
 
-func (a *Appointments) GetAll(ctx context.Context, clientID string) ([]*models.AppointmentRequest, error) {
-	a.Logger.Infof("Getting all appointments with clientID: %s", clientID)
-	result, err := a.Service.GetAllAppointments(ctx, clientID)
+func (a *Appointments) GetAll(ctx context.Context, clientID, cursor string, limit int) ([]*models.AppointmentRequest, string, error) {
+	ctx, span := tracing.Start(ctx, "handler.Appointments.GetAll")
+	defer span.End()
+	a.log(ctx).Infof("Getting appointments with clientID: %s", clientID)
+	result, next, err := a.Service.GetAllAppointments(ctx, clientID, cursor, limit)
 	if err != nil {
-		a.Logger.Errorf("Error getting appointments by clientId: %s", err)
-		return nil, err
+		tracing.Error(span, err)
+		a.log(ctx).Errorf("Error getting appointments by clientId: %s", err)
+		return nil, "", err
 	}
-	return result, nil
+	return result, next, nil
 }
 
 func (a *Appointments) Update(ctx context.Context, appointment *models.AppointmentRequest) (*models.AppointmentRequest, error) {
-	a.Logger.Infof("Updating appointment: %s", appointment)
+	ctx, span := tracing.Start(ctx, "handler.Appointments.Update")
+	defer span.End()
+	a.log(ctx).Infof("Updating appointment: %s", appointment)
 	if appointment.Status != models.AppointmentStatusScheduled &&
+		appointment.Status != models.AppointmentStatusConfirmed &&
 		appointment.Status != models.AppointmentStatusInProgress &&
 		appointment.Status != models.AppointmentStatusCompleted &&
-		appointment.Status != models.AppointmentStatusCancelled {
-		err := fmt.Errorf("invalid status value: %s. Must be one of: %s, %s, %s, %s",
+		appointment.Status != models.AppointmentStatusCancelled &&
+		appointment.Status != models.AppointmentStatusNoShow {
+		err := fmt.Errorf("invalid status value: %s. Must be one of: %s, %s, %s, %s, %s, %s",
 			appointment.Status,
 			models.AppointmentStatusScheduled,
+			models.AppointmentStatusConfirmed,
 			models.AppointmentStatusInProgress,
 			models.AppointmentStatusCompleted,
-			models.AppointmentStatusCancelled)
-		a.Logger.Error(err)
+			models.AppointmentStatusCancelled,
+			models.AppointmentStatusNoShow)
+		tracing.Error(span, err)
+		a.log(ctx).Error(err)
 		return nil, err
 	}
-	err := a.Service.UpdateAppointment(ctx, appointment)
+	result, err := a.Service.UpdateAppointment(ctx, appointment)
 	if err != nil {
-		a.Logger.Errorf("Error updating appointment: %s", err)
+		tracing.Error(span, err)
+		a.log(ctx).Errorf("Error updating appointment: %s", err)
 		return nil, err
 	}
-	// Return the updated appointment data
-	return appointment, nil
+	return result, nil
+}
+
+// Patch aplica un parche parcial (JSON Merge Patch o JSON Patch) sobre el
+// turno guardado y devuelve cómo quedó.
+func (a *Appointments) Patch(ctx context.Context, id, contentType string, patch []byte) (*models.AppointmentRequest, error) {
+	ctx, span := tracing.Start(ctx, "handler.Appointments.Patch")
+	defer span.End()
+	a.log(ctx).Infof("Patching appointment %s with %s", id, contentType)
+	result, err := a.Service.PatchAppointment(ctx, id, contentType, patch)
+	if err != nil {
+		tracing.Error(span, err)
+		a.log(ctx).Errorf("Error patching appointment: %s", err)
+		return nil, err
+	}
+	return result, nil
 }
 
 func (a *Appointments) Delete(ctx context.Context, appointmentID string) error {
-	a.Logger.Infof("Deleting appointment with appointmentID: %s", appointmentID)
+	ctx, span := tracing.Start(ctx, "handler.Appointments.Delete")
+	defer span.End()
+	a.log(ctx).Infof("Deleting appointment with appointmentID: %s", appointmentID)
 	err := a.Service.DeleteAppointment(ctx, appointmentID)
 	if err != nil {
-		a.Logger.Errorf("Error deleting appointment: %s", err)
+		tracing.Error(span, err)
+		a.log(ctx).Errorf("Error deleting appointment: %s", err)
 		return err
 	}
 	return nil
 }
+
+func (a *Appointments) CheckAction(ctx context.Context, token string) (*models.AppointmentAction, error) {
+	ctx, span := tracing.Start(ctx, "handler.Appointments.CheckAction")
+	defer span.End()
+	result, err := a.Service.CheckAction(ctx, token)
+	if err != nil {
+		a.log(ctx).Infof("Rejected appointment action link: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *Appointments) ApplyAction(ctx context.Context, token string) (*models.AppointmentAction, error) {
+	ctx, span := tracing.Start(ctx, "handler.Appointments.ApplyAction")
+	defer span.End()
+	result, err := a.Service.ApplyAction(ctx, token)
+	if err != nil {
+		tracing.Error(span, err)
+		a.log(ctx).Errorf("Error applying appointment action: %s", err)
+		return nil, err
+	}
+	a.log(ctx).Infof("Applied %s to appointment %s", result.Action, result.AppointmentID)
+	return result, nil
+}
+
+func (a *Appointments) HoldSlot(ctx context.Context, hold *models.SlotHoldRequest) (*models.SlotHoldRequest, error) {
+	ctx, span := tracing.Start(ctx, "handler.Appointments.HoldSlot")
+	defer span.End()
+	a.log(ctx).Infof("Holding slot for doctor %s at %s", hold.DoctorID, hold.Date)
+	result, err := a.Service.HoldSlot(ctx, hold)
+	if err != nil {
+		a.log(ctx).Infof("Could not hold slot: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *Appointments) ConfirmHold(ctx context.Context, holdID string, appointment *models.AppointmentRequest) (*models.AppointmentRequest, error) {
+	ctx, span := tracing.Start(ctx, "handler.Appointments.ConfirmHold")
+	defer span.End()
+	result, err := a.Service.ConfirmHold(ctx, holdID, appointment)
+	if err != nil {
+		tracing.Error(span, err)
+		a.log(ctx).Errorf("Error confirming slot hold: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *Appointments) ReleaseHold(ctx context.Context, holdID string) error {
+	ctx, span := tracing.Start(ctx, "handler.Appointments.ReleaseHold")
+	defer span.End()
+	err := a.Service.ReleaseHold(ctx, holdID)
+	if err != nil {
+		tracing.Error(span, err)
+		a.log(ctx).Errorf("Error releasing slot hold: %s", err)
+		return err
+	}
+	return nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del handler.
+func (a *Appointments) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, a.Logger)
+}
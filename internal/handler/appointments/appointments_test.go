@@ -32,17 +32,28 @@ func (m *MockAppointmentsService) GetAppointment(ctx context.Context, req *model
 	return args.Get(0).(*models.AppointmentRequest), args.Error(1)
 }
 
-func (m *MockAppointmentsService) GetAllAppointments(ctx context.Context, clientID string) ([]*models.AppointmentRequest, error) {
-	args := m.Called(ctx, clientID)
+func (m *MockAppointmentsService) GetAllAppointments(ctx context.Context, clientID, cursor string, limit int) ([]*models.AppointmentRequest, string, error) {
+	args := m.Called(ctx, clientID, cursor, limit)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, "", args.Error(2)
 	}
-	return args.Get(0).([]*models.AppointmentRequest), args.Error(1)
+	return args.Get(0).([]*models.AppointmentRequest), args.String(1), args.Error(2)
 }
 
-func (m *MockAppointmentsService) UpdateAppointment(ctx context.Context, appointment *models.AppointmentRequest) error {
+func (m *MockAppointmentsService) UpdateAppointment(ctx context.Context, appointment *models.AppointmentRequest) (*models.AppointmentRequest, error) {
 	args := m.Called(ctx, appointment)
-	return args.Error(0)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AppointmentRequest), args.Error(1)
+}
+
+func (m *MockAppointmentsService) PatchAppointment(ctx context.Context, id, contentType string, patch []byte) (*models.AppointmentRequest, error) {
+	args := m.Called(ctx, id, contentType, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AppointmentRequest), args.Error(1)
 }
 
 func (m *MockAppointmentsService) DeleteAppointment(ctx context.Context, id string) error {
@@ -50,6 +61,43 @@ func (m *MockAppointmentsService) DeleteAppointment(ctx context.Context, id stri
 	return args.Error(0)
 }
 
+func (m *MockAppointmentsService) CheckAction(ctx context.Context, token string) (*models.AppointmentAction, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AppointmentAction), args.Error(1)
+}
+
+func (m *MockAppointmentsService) ApplyAction(ctx context.Context, token string) (*models.AppointmentAction, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AppointmentAction), args.Error(1)
+}
+
+func (m *MockAppointmentsService) HoldSlot(ctx context.Context, hold *models.SlotHoldRequest) (*models.SlotHoldRequest, error) {
+	args := m.Called(ctx, hold)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SlotHoldRequest), args.Error(1)
+}
+
+func (m *MockAppointmentsService) ConfirmHold(ctx context.Context, holdID string, appointment *models.AppointmentRequest) (*models.AppointmentRequest, error) {
+	args := m.Called(ctx, holdID, appointment)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AppointmentRequest), args.Error(1)
+}
+
+func (m *MockAppointmentsService) ReleaseHold(ctx context.Context, holdID string) error {
+	args := m.Called(ctx, holdID)
+	return args.Error(0)
+}
+
 func TestNew(t *testing.T) {
 	// Arrange
 	logger := zaptest.NewLogger(t).Sugar()
@@ -109,8 +157,8 @@ func TestAppointments_Create(t *testing.T) {
 			name:        "Invalid Status",
 			appointment: errorAppointment,
 			mockSetup:   func(m *MockAppointmentsService, a *models.AppointmentRequest) {},
-			expectedError: errors.New("invalid status value: INVALID_STATUS. Must be one of: SCHEDULED, IN_PROGRESS, COMPLETED, " +
-				"CANCELLED"),
+			expectedError: errors.New("invalid status value: INVALID_STATUS. Must be one of: SCHEDULED, CONFIRMED, IN_PROGRESS, COMPLETED, " +
+				"CANCELLED, NO_SHOW"),
 			expectedResult: nil,
 		},
 	}
@@ -248,7 +296,7 @@ func TestAppointments_GetAll(t *testing.T) {
 			name:     "Success",
 			clientID: "client123",
 			mockSetup: func(m *MockAppointmentsService) {
-				m.On("GetAllAppointments", mock.Anything, "client123").Return(sampleAppointments, nil)
+				m.On("GetAllAppointments", mock.Anything, "client123", "", 0).Return(sampleAppointments, "", nil)
 			},
 			expectedError:  nil,
 			expectedResult: sampleAppointments,
@@ -257,7 +305,7 @@ func TestAppointments_GetAll(t *testing.T) {
 			name:     "Not Found",
 			clientID: "nonexistent",
 			mockSetup: func(m *MockAppointmentsService) {
-				m.On("GetAllAppointments", mock.Anything, "nonexistent").Return(nil, errors.New("appointments not found"))
+				m.On("GetAllAppointments", mock.Anything, "nonexistent", "", 0).Return(nil, "", errors.New("appointments not found"))
 			},
 			expectedError:  errors.New("appointments not found"),
 			expectedResult: nil,
@@ -266,7 +314,7 @@ func TestAppointments_GetAll(t *testing.T) {
 			name:     "Service Error",
 			clientID: "client456",
 			mockSetup: func(m *MockAppointmentsService) {
-				m.On("GetAllAppointments", mock.Anything, "client456").Return(nil, errors.New("service error"))
+				m.On("GetAllAppointments", mock.Anything, "client456", "", 0).Return(nil, "", errors.New("service error"))
 			},
 			expectedError:  errors.New("service error"),
 			expectedResult: nil,
@@ -286,7 +334,7 @@ func TestAppointments_GetAll(t *testing.T) {
 			}
 
 			// Act
-			result, err := handler.GetAll(context.Background(), tt.clientID)
+			result, _, err := handler.GetAll(context.Background(), tt.clientID, "", 0)
 
 			// Assert
 			if tt.expectedError != nil {
@@ -324,7 +372,7 @@ func TestAppointments_Update(t *testing.T) {
 			name:        "Success",
 			appointment: sampleAppointment,
 			mockSetup: func(m *MockAppointmentsService) {
-				m.On("UpdateAppointment", mock.Anything, sampleAppointment).Return(nil)
+				m.On("UpdateAppointment", mock.Anything, sampleAppointment).Return(sampleAppointment, nil)
 			},
 			expectedError:  nil,
 			expectedResult: sampleAppointment,
@@ -339,14 +387,14 @@ func TestAppointments_Update(t *testing.T) {
 				Status:    "INVALID_STATUS",
 			},
 			mockSetup:      func(m *MockAppointmentsService) {},
-			expectedError:  errors.New("invalid status value: INVALID_STATUS. Must be one of: SCHEDULED, IN_PROGRESS, COMPLETED, CANCELLED"),
+			expectedError:  errors.New("invalid status value: INVALID_STATUS. Must be one of: SCHEDULED, CONFIRMED, IN_PROGRESS, COMPLETED, CANCELLED, NO_SHOW"),
 			expectedResult: nil,
 		},
 		{
 			name:        "Service Error",
 			appointment: sampleAppointment,
 			mockSetup: func(m *MockAppointmentsService) {
-				m.On("UpdateAppointment", mock.Anything, sampleAppointment).Return(errors.New("service error"))
+				m.On("UpdateAppointment", mock.Anything, sampleAppointment).Return(nil, errors.New("service error"))
 			},
 			expectedError:  errors.New("service error"),
 			expectedResult: nil,
@@ -442,3 +490,22 @@ func TestAppointments_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestAppointments_Patch(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	mockService := new(MockAppointmentsService)
+	handler := &Appointments{Service: mockService, Logger: logger}
+	patch := []byte(`{"notes":null}`)
+	patched := &models.AppointmentRequest{ID: "123", ClientID: "client123"}
+	mockService.On("PatchAppointment", mock.Anything, "123", "application/merge-patch+json", patch).Return(patched, nil)
+	mockService.On("PatchAppointment", mock.Anything, "456", "application/merge-patch+json", patch).Return(nil, errors.New("service error"))
+
+	result, err := handler.Patch(context.Background(), "123", "application/merge-patch+json", patch)
+	assert.NoError(t, err)
+	assert.Equal(t, patched, result)
+
+	result, err = handler.Patch(context.Background(), "456", "application/merge-patch+json", patch)
+	assert.EqualError(t, err, "service error")
+	assert.Nil(t, result)
+	mockService.AssertExpectations(t)
+}
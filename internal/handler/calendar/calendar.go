@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"go.uber.org/zap"
+)
+
+type CalendarHandler interface {
+	CreateFeed(context.Context, *models.CalendarFeedRequest) (*models.CalendarFeedRequest, error)
+	GetFeeds(ctx context.Context, ownerType, ownerID string) ([]*models.CalendarFeedRequest, error)
+	RevokeFeed(ctx context.Context, feedID string) error
+	RenderFeed(ctx context.Context, token string) ([]byte, error)
+}
+
+type CalendarService interface {
+	CreateFeed(context.Context, *models.CalendarFeedRequest) (*models.CalendarFeedRequest, error)
+	GetFeeds(ctx context.Context, ownerType, ownerID string) ([]*models.CalendarFeedRequest, error)
+	RevokeFeed(ctx context.Context, id string) error
+	RenderFeed(ctx context.Context, token string) ([]byte, error)
+}
+
+type Calendar struct {
+	Service CalendarService
+	Logger  *zap.SugaredLogger
+}
+
+func New(service CalendarService, logger *zap.SugaredLogger) CalendarHandler {
+	return &Calendar{Service: service, Logger: logger}
+}
+
+func (c *Calendar) CreateFeed(ctx context.Context, feed *models.CalendarFeedRequest) (*models.CalendarFeedRequest, error) {
+	c.log(ctx).Infof("Creating calendar feed for %s %s", feed.OwnerType, feed.OwnerID)
+	result, err := c.Service.CreateFeed(ctx, feed)
+	if err != nil {
+		c.log(ctx).Errorf("Error creating calendar feed: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Calendar) GetFeeds(ctx context.Context, ownerType, ownerID string) ([]*models.CalendarFeedRequest, error) {
+	c.log(ctx).Infof("Getting calendar feeds for %s %s", ownerType, ownerID)
+	result, err := c.Service.GetFeeds(ctx, ownerType, ownerID)
+	if err != nil {
+		c.log(ctx).Errorf("Error getting calendar feeds: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Calendar) RevokeFeed(ctx context.Context, feedID string) error {
+	c.log(ctx).Infof("Revoking calendar feed: %s", feedID)
+	err := c.Service.RevokeFeed(ctx, feedID)
+	if err != nil {
+		c.log(ctx).Errorf("Error revoking calendar feed: %s", err)
+		return err
+	}
+	return nil
+}
+
+// RenderFeed no registra el token: es el secreto de la suscripción.
+func (c *Calendar) RenderFeed(ctx context.Context, token string) ([]byte, error) {
+	c.log(ctx).Info("Rendering calendar feed")
+	result, err := c.Service.RenderFeed(ctx, token)
+	if err != nil {
+		c.log(ctx).Errorf("Error rendering calendar feed: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del handler.
+func (c *Calendar) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, c.Logger)
+}
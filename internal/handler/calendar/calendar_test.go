@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+// MockCalendarService implementa la interfaz CalendarService para los tests
+type MockCalendarService struct {
+	mock.Mock
+}
+
+func (m *MockCalendarService) CreateFeed(ctx context.Context, feed *models.CalendarFeedRequest) (*models.CalendarFeedRequest, error) {
+	args := m.Called(ctx, feed)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CalendarFeedRequest), args.Error(1)
+}
+
+func (m *MockCalendarService) GetFeeds(ctx context.Context, ownerType, ownerID string) ([]*models.CalendarFeedRequest, error) {
+	args := m.Called(ctx, ownerType, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.CalendarFeedRequest), args.Error(1)
+}
+
+func (m *MockCalendarService) RevokeFeed(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockCalendarService) RenderFeed(ctx context.Context, token string) ([]byte, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func TestCalendar_CreateFeed(t *testing.T) {
+	svc := new(MockCalendarService)
+	h := New(svc, zaptest.NewLogger(t).Sugar())
+	ctx := context.Background()
+	request := &models.CalendarFeedRequest{OwnerType: models.CalendarOwnerDoctor, OwnerID: "doc1"}
+	expected := &models.CalendarFeedRequest{ID: "feed1", URL: "https://example.com/calendar/feeds/feed1.s.ics"}
+
+	svc.On("CreateFeed", ctx, request).Return(expected, nil)
+
+	result, err := h.CreateFeed(ctx, request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestCalendar_RenderFeed_Error(t *testing.T) {
+	svc := new(MockCalendarService)
+	h := New(svc, zaptest.NewLogger(t).Sugar())
+	ctx := context.Background()
+	expectedErr := errors.New("calendar feed not found")
+
+	svc.On("RenderFeed", ctx, "feed1.bad.ics").Return(nil, expectedErr)
+
+	result, err := h.RenderFeed(ctx, "feed1.bad.ics")
+
+	assert.Nil(t, result)
+	assert.Equal(t, expectedErr, err)
+}
+
+func TestCalendar_RevokeFeed(t *testing.T) {
+	svc := new(MockCalendarService)
+	h := New(svc, zaptest.NewLogger(t).Sugar())
+	ctx := context.Background()
+
+	svc.On("RevokeFeed", ctx, "feed1").Return(nil)
+
+	assert.NoError(t, h.RevokeFeed(ctx, "feed1"))
+	svc.AssertExpectations(t)
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"go.uber.org/zap"
+)
+
+type CalendarImportHandler interface {
+	Import(context.Context, *models.CalendarImportRequest) (*models.CalendarImportReport, error)
+}
+
+type CalendarImportService interface {
+	Import(context.Context, *models.CalendarImportRequest) (*models.CalendarImportReport, error)
+}
+
+type CalendarImport struct {
+	Service CalendarImportService
+	Logger  *zap.SugaredLogger
+}
+
+func New(service CalendarImportService, logger *zap.SugaredLogger) CalendarImportHandler {
+	return &CalendarImport{Service: service, Logger: logger}
+}
+
+func (c *CalendarImport) Import(ctx context.Context, request *models.CalendarImportRequest) (*models.CalendarImportReport, error) {
+	c.log(ctx).Infof("Importing calendar for doctor %s (dry run: %t)", request.DoctorID, request.DryRun)
+	result, err := c.Service.Import(ctx, request)
+	if err != nil {
+		c.log(ctx).Errorf("Error importing calendar: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del handler.
+func (c *CalendarImport) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, c.Logger)
+}
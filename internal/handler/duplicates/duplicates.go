@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"go.uber.org/zap"
+)
+
+type DuplicatesHandler interface {
+	Find(ctx context.Context, clientID string, threshold float64) ([]*models.DuplicateCandidate, error)
+	Merge(context.Context, *models.PatientMergeRequest) (*models.PatientMergeRequest, error)
+	Revert(ctx context.Context, id string) (*models.PatientMergeRequest, error)
+}
+
+type DuplicatesService interface {
+	FindDuplicates(ctx context.Context, clientID string, threshold float64) ([]*models.DuplicateCandidate, error)
+	MergePatients(context.Context, *models.PatientMergeRequest) (*models.PatientMergeRequest, error)
+	RevertMerge(ctx context.Context, id string) (*models.PatientMergeRequest, error)
+}
+
+type Duplicates struct {
+	Service DuplicatesService
+	Logger  *zap.SugaredLogger
+}
+
+func New(service DuplicatesService, logger *zap.SugaredLogger) DuplicatesHandler {
+	return &Duplicates{Service: service, Logger: logger}
+}
+
+func (d *Duplicates) Find(ctx context.Context, clientID string, threshold float64) ([]*models.DuplicateCandidate, error) {
+	d.log(ctx).Infof("Finding duplicate patients for client %s", clientID)
+	result, err := d.Service.FindDuplicates(ctx, clientID, threshold)
+	if err != nil {
+		d.log(ctx).Errorf("Error finding duplicate patients: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (d *Duplicates) Merge(ctx context.Context, request *models.PatientMergeRequest) (*models.PatientMergeRequest, error) {
+	d.log(ctx).Infof("Merging patient %s into %s", request.MergedID, request.SurvivorID)
+	result, err := d.Service.MergePatients(ctx, request)
+	if err != nil {
+		d.log(ctx).Errorf("Error merging patients: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (d *Duplicates) Revert(ctx context.Context, id string) (*models.PatientMergeRequest, error) {
+	d.log(ctx).Infof("Reverting patient merge %s", id)
+	result, err := d.Service.RevertMerge(ctx, id)
+	if err != nil {
+		d.log(ctx).Errorf("Error reverting patient merge: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del handler.
+func (d *Duplicates) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, d.Logger)
+}
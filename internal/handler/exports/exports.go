@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"go.uber.org/zap"
+)
+
+type ExportsHandler interface {
+	Create(context.Context, *models.ExportRequest) (*models.ExportRequest, error)
+	Get(ctx context.Context, id string) (*models.ExportRequest, error)
+	Run(ctx context.Context, id string) error
+}
+
+type ExportsService interface {
+	CreateExport(context.Context, *models.ExportRequest) (*models.ExportRequest, error)
+	GetExport(ctx context.Context, id string) (*models.ExportRequest, error)
+	RunExport(ctx context.Context, id string) error
+}
+
+type Exports struct {
+	Service ExportsService
+	Logger  *zap.SugaredLogger
+}
+
+func New(service ExportsService, logger *zap.SugaredLogger) ExportsHandler {
+	return &Exports{Service: service, Logger: logger}
+}
+
+func (e *Exports) Create(ctx context.Context, request *models.ExportRequest) (*models.ExportRequest, error) {
+	e.log(ctx).Infof("Creating %s export for client %s", request.Resource, request.ClientID)
+	result, err := e.Service.CreateExport(ctx, request)
+	if err != nil {
+		e.log(ctx).Errorf("Error creating export: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (e *Exports) Get(ctx context.Context, id string) (*models.ExportRequest, error) {
+	e.log(ctx).Infof("Getting export %s", id)
+	result, err := e.Service.GetExport(ctx, id)
+	if err != nil {
+		e.log(ctx).Errorf("Error getting export: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (e *Exports) Run(ctx context.Context, id string) error {
+	e.log(ctx).Infof("Running export %s", id)
+	if err := e.Service.RunExport(ctx, id); err != nil {
+		e.log(ctx).Errorf("Error running export: %s", err)
+		return err
+	}
+	return nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del handler.
+func (e *Exports) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, e.Logger)
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/fhir"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"go.uber.org/zap"
+)
+
+type FHIRHandler interface {
+	ReadAppointment(ctx context.Context, id string) (*fhir.Appointment, error)
+	SearchAppointments(ctx context.Context, search *fhir.AppointmentSearch) (*fhir.Bundle, error)
+	BookAppointment(ctx context.Context, resource *fhir.Appointment) (*fhir.Appointment, error)
+	ReadSchedule(ctx context.Context, clientID, doctorID string) (*fhir.Schedule, error)
+	SearchSlots(ctx context.Context, search *fhir.SlotSearch) (*fhir.Bundle, error)
+}
+
+type FHIRService interface {
+	ReadAppointment(ctx context.Context, id string) (*fhir.Appointment, error)
+	SearchAppointments(ctx context.Context, search *fhir.AppointmentSearch) (*fhir.Bundle, error)
+	BookAppointment(ctx context.Context, resource *fhir.Appointment) (*fhir.Appointment, error)
+	ReadSchedule(ctx context.Context, clientID, doctorID string) (*fhir.Schedule, error)
+	SearchSlots(ctx context.Context, search *fhir.SlotSearch) (*fhir.Bundle, error)
+}
+
+type FHIR struct {
+	Service FHIRService
+	Logger  *zap.SugaredLogger
+}
+
+func New(service FHIRService, logger *zap.SugaredLogger) FHIRHandler {
+	return &FHIR{Service: service, Logger: logger}
+}
+
+func (f *FHIR) ReadAppointment(ctx context.Context, id string) (*fhir.Appointment, error) {
+	f.log(ctx).Infof("Reading FHIR Appointment: %s", id)
+	result, err := f.Service.ReadAppointment(ctx, id)
+	if err != nil {
+		f.log(ctx).Errorf("Error reading FHIR Appointment: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (f *FHIR) SearchAppointments(ctx context.Context, search *fhir.AppointmentSearch) (*fhir.Bundle, error) {
+	f.log(ctx).Infof("Searching FHIR Appointments with params: %+v", search)
+	result, err := f.Service.SearchAppointments(ctx, search)
+	if err != nil {
+		f.log(ctx).Errorf("Error searching FHIR Appointments: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (f *FHIR) BookAppointment(ctx context.Context, resource *fhir.Appointment) (*fhir.Appointment, error) {
+	f.log(ctx).Infof("Booking FHIR Appointment: %+v", resource)
+	result, err := f.Service.BookAppointment(ctx, resource)
+	if err != nil {
+		f.log(ctx).Errorf("Error booking FHIR Appointment: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (f *FHIR) ReadSchedule(ctx context.Context, clientID, doctorID string) (*fhir.Schedule, error) {
+	f.log(ctx).Infof("Reading FHIR Schedule: %s", doctorID)
+	result, err := f.Service.ReadSchedule(ctx, clientID, doctorID)
+	if err != nil {
+		f.log(ctx).Errorf("Error reading FHIR Schedule: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (f *FHIR) SearchSlots(ctx context.Context, search *fhir.SlotSearch) (*fhir.Bundle, error) {
+	f.log(ctx).Infof("Searching FHIR Slots with params: %+v", search)
+	result, err := f.Service.SearchSlots(ctx, search)
+	if err != nil {
+		f.log(ctx).Errorf("Error searching FHIR Slots: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del handler.
+func (f *FHIR) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, f.Logger)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/fhir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+// MockFHIRService implementa la interfaz FHIRService para los tests
+type MockFHIRService struct {
+	mock.Mock
+}
+
+func (m *MockFHIRService) ReadAppointment(ctx context.Context, id string) (*fhir.Appointment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*fhir.Appointment), args.Error(1)
+}
+
+func (m *MockFHIRService) SearchAppointments(ctx context.Context, search *fhir.AppointmentSearch) (*fhir.Bundle, error) {
+	args := m.Called(ctx, search)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*fhir.Bundle), args.Error(1)
+}
+
+func (m *MockFHIRService) BookAppointment(ctx context.Context, resource *fhir.Appointment) (*fhir.Appointment, error) {
+	args := m.Called(ctx, resource)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*fhir.Appointment), args.Error(1)
+}
+
+func (m *MockFHIRService) ReadSchedule(ctx context.Context, clientID, doctorID string) (*fhir.Schedule, error) {
+	args := m.Called(ctx, clientID, doctorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*fhir.Schedule), args.Error(1)
+}
+
+func (m *MockFHIRService) SearchSlots(ctx context.Context, search *fhir.SlotSearch) (*fhir.Bundle, error) {
+	args := m.Called(ctx, search)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*fhir.Bundle), args.Error(1)
+}
+
+func TestNew(t *testing.T) {
+	handler := New(new(MockFHIRService), zaptest.NewLogger(t).Sugar())
+
+	assert.NotNil(t, handler)
+	assert.IsType(t, &FHIR{}, handler)
+}
+
+func TestFHIR_SearchAppointments(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockResult    *fhir.Bundle
+		mockError     error
+		expectedError error
+	}{
+		{
+			name:       "Success",
+			mockResult: fhir.NewSearchBundle(nil),
+		},
+		{
+			name:          "Service Error",
+			mockError:     errors.New("service error"),
+			expectedError: errors.New("service error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockFHIRService)
+			search := &fhir.AppointmentSearch{PatientID: "patient123"}
+			if tt.mockResult != nil {
+				mockService.On("SearchAppointments", mock.Anything, search).Return(tt.mockResult, nil)
+			} else {
+				mockService.On("SearchAppointments", mock.Anything, search).Return(nil, tt.mockError)
+			}
+			handler := &FHIR{Service: mockService, Logger: zaptest.NewLogger(t).Sugar()}
+
+			result, err := handler.SearchAppointments(context.Background(), search)
+
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.mockResult, result)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFHIR_BookAppointment(t *testing.T) {
+	mockService := new(MockFHIRService)
+	resource := &fhir.Appointment{ResourceType: "Appointment", Status: fhir.AppointmentStatusBooked}
+	booked := &fhir.Appointment{ResourceType: "Appointment", ID: "appointment123", Status: fhir.AppointmentStatusBooked}
+	mockService.On("BookAppointment", mock.Anything, resource).Return(booked, nil)
+	handler := &FHIR{Service: mockService, Logger: zaptest.NewLogger(t).Sugar()}
+
+	result, err := handler.BookAppointment(context.Background(), resource)
+
+	assert.NoError(t, err)
+	assert.Equal(t, booked, result)
+	mockService.AssertExpectations(t)
+}
@@ -4,24 +4,32 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/MezeLaw/iris-services/internal/logging"
 	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"go.uber.org/zap"
 )
 
 type PatientsHandler interface {
 	Create(context.Context, *models.PatientRequest) (*models.PatientRequest, error)
 	Get(ctx context.Context, getPatient *models.GetPatientRequest) (*models.PatientRequest, error)
-	GetAll(ctx context.Context, clientID string) ([]*models.PatientRequest, error)
+	GetAll(ctx context.Context, clientID, cursor string, limit int) ([]*models.PatientRequest, string, error)
 	Update(ctx context.Context, patient *models.PatientRequest) (*models.PatientRequest, error)
+	Patch(ctx context.Context, id, contentType string, patch []byte) (*models.PatientRequest, error)
 	Delete(ctx context.Context, userID string) error
+	Import(ctx context.Context, request *models.PatientImportRequest) (*models.PatientImportReport, error)
+	Search(ctx context.Context, request *models.PatientSearchRequest) (*models.PatientSearchResult, error)
 }
 
 type PatientsService interface {
 	CreatePatient(context.Context, *models.PatientRequest) (*models.PatientRequest, error)
 	GetPatient(context.Context, *models.GetPatientRequest) (*models.PatientRequest, error)
-	GetAllPatients(context.Context, string) ([]*models.PatientRequest, error)
-	UpdatePatient(context.Context, *models.PatientRequest) error
+	GetAllPatients(ctx context.Context, clientID, cursor string, limit int) ([]*models.PatientRequest, string, error)
+	UpdatePatient(context.Context, *models.PatientRequest) (*models.PatientRequest, error)
+	PatchPatient(ctx context.Context, id, contentType string, patch []byte) (*models.PatientRequest, error)
 	DeletePatient(context.Context, string) error
+	ImportPatients(context.Context, *models.PatientImportRequest) (*models.PatientImportReport, error)
+	SearchPatients(context.Context, *models.PatientSearchRequest) (*models.PatientSearchResult, error)
 }
 
 type Patients struct {
@@ -34,62 +42,124 @@ func New(service PatientsService, logger *zap.SugaredLogger) PatientsHandler {
 }
 
 func (p *Patients) Create(ctx context.Context, patient *models.PatientRequest) (*models.PatientRequest, error) {
-	p.Logger.Infof("Creating patient: %s", patient)
+	ctx, span := tracing.Start(ctx, "handler.Patients.Create")
+	defer span.End()
+	p.log(ctx).Infof("Creating patient: %s", patient)
 	if patient.Gender != models.GenderMale && patient.Gender != models.GenderFemale && patient.Gender != models.GenderNonBinary {
 		err := fmt.Errorf("invalid gender value: %s. Must be one of: %s, %s, %s", patient.Gender, models.GenderMale, models.GenderFemale, models.GenderNonBinary)
-		p.Logger.Error(err)
+		tracing.Error(span, err)
+		p.log(ctx).Error(err)
 		return nil, err
 	}
 	result, err := p.Service.CreatePatient(ctx, patient)
 	if err != nil {
-		p.Logger.Errorf("Error creating patient: %s", err)
+		tracing.Error(span, err)
+		p.log(ctx).Errorf("Error creating patient: %s", err)
 		return nil, err
 	}
 	return result, nil
 }
 
 func (p *Patients) Get(ctx context.Context, getRequest *models.GetPatientRequest) (*models.PatientRequest, error) {
-	p.Logger.Infof("Getting patient with params: %s", getRequest)
+	ctx, span := tracing.Start(ctx, "handler.Patients.Get")
+	defer span.End()
+	p.log(ctx).Infof("Getting patient with params: %s", getRequest)
 	result, err := p.Service.GetPatient(ctx, getRequest)
 	if err != nil {
-		p.Logger.Errorf("Error getting patient: %s", err)
+		tracing.Error(span, err)
+		p.log(ctx).Errorf("Error getting patient: %s", err)
 		return nil, err
 	}
 	return result, nil
 }
 
-func (p *Patients) GetAll(ctx context.Context, clientID string) ([]*models.PatientRequest, error) {
-	p.Logger.Infof("Getting all patients with clientID: %s", clientID)
-	result, err := p.Service.GetAllPatients(ctx, clientID)
+func (p *Patients) GetAll(ctx context.Context, clientID, cursor string, limit int) ([]*models.PatientRequest, string, error) {
+	ctx, span := tracing.Start(ctx, "handler.Patients.GetAll")
+	defer span.End()
+	p.log(ctx).Infof("Getting patients with clientID: %s", clientID)
+	result, next, err := p.Service.GetAllPatients(ctx, clientID, cursor, limit)
 	if err != nil {
-		p.Logger.Errorf("Error getting patients by clientId: %s", err)
-		return nil, err
+		tracing.Error(span, err)
+		p.log(ctx).Errorf("Error getting patients by clientId: %s", err)
+		return nil, "", err
 	}
-	return result, nil
+	return result, next, nil
 }
 
 func (p *Patients) Update(ctx context.Context, patient *models.PatientRequest) (*models.PatientRequest, error) {
-	p.Logger.Infof("Updating patient: %s", patient)
+	ctx, span := tracing.Start(ctx, "handler.Patients.Update")
+	defer span.End()
+	p.log(ctx).Infof("Updating patient: %s", patient)
 	if patient.Gender != models.GenderMale && patient.Gender != models.GenderFemale && patient.Gender != models.GenderNonBinary {
 		err := fmt.Errorf("invalid gender value: %s. Must be one of: %s, %s, %s", patient.Gender, models.GenderMale, models.GenderFemale, models.GenderNonBinary)
-		p.Logger.Error(err)
+		tracing.Error(span, err)
+		p.log(ctx).Error(err)
+		return nil, err
+	}
+	result, err := p.Service.UpdatePatient(ctx, patient)
+	if err != nil {
+		tracing.Error(span, err)
+		p.log(ctx).Errorf("Error updating patient: %s", err)
 		return nil, err
 	}
-	err := p.Service.UpdatePatient(ctx, patient)
+	return result, nil
+}
+
+// Patch aplica un parche parcial (JSON Merge Patch o JSON Patch) sobre el
+// paciente guardado y devuelve cómo quedó.
+func (p *Patients) Patch(ctx context.Context, id, contentType string, patch []byte) (*models.PatientRequest, error) {
+	ctx, span := tracing.Start(ctx, "handler.Patients.Patch")
+	defer span.End()
+	p.log(ctx).Infof("Patching patient %s with %s", id, contentType)
+	result, err := p.Service.PatchPatient(ctx, id, contentType, patch)
 	if err != nil {
-		p.Logger.Errorf("Error updating patient: %s", err)
+		tracing.Error(span, err)
+		p.log(ctx).Errorf("Error patching patient: %s", err)
 		return nil, err
 	}
-	// Return the updated patient data
-	return patient, nil
+	return result, nil
 }
 
 func (p *Patients) Delete(ctx context.Context, userID string) error {
-	p.Logger.Infof("Deleting patient with userID: %s", userID)
+	ctx, span := tracing.Start(ctx, "handler.Patients.Delete")
+	defer span.End()
+	p.log(ctx).Infof("Deleting patient with userID: %s", userID)
 	err := p.Service.DeletePatient(ctx, userID)
 	if err != nil {
-		p.Logger.Errorf("Error deleting patient: %s", err)
+		tracing.Error(span, err)
+		p.log(ctx).Errorf("Error deleting patient: %s", err)
 		return err
 	}
 	return nil
 }
+
+func (p *Patients) Import(ctx context.Context, request *models.PatientImportRequest) (*models.PatientImportReport, error) {
+	ctx, span := tracing.Start(ctx, "handler.Patients.Import")
+	defer span.End()
+	p.log(ctx).Infof("Importing patients for clientID: %s", request.ClientID)
+	result, err := p.Service.ImportPatients(ctx, request)
+	if err != nil {
+		tracing.Error(span, err)
+		p.log(ctx).Errorf("Error importing patients: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (p *Patients) Search(ctx context.Context, request *models.PatientSearchRequest) (*models.PatientSearchResult, error) {
+	ctx, span := tracing.Start(ctx, "handler.Patients.Search")
+	defer span.End()
+	p.log(ctx).Infof("Searching patients for clientID: %s", request.ClientID)
+	result, err := p.Service.SearchPatients(ctx, request)
+	if err != nil {
+		tracing.Error(span, err)
+		p.log(ctx).Errorf("Error searching patients: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del handler.
+func (p *Patients) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, p.Logger)
+}
@@ -32,17 +32,28 @@ func (m *MockPatientsService) GetPatient(ctx context.Context, req *models.GetPat
 	return args.Get(0).(*models.PatientRequest), args.Error(1)
 }
 
-func (m *MockPatientsService) GetAllPatients(ctx context.Context, clientID string) ([]*models.PatientRequest, error) {
-	args := m.Called(ctx, clientID)
+func (m *MockPatientsService) GetAllPatients(ctx context.Context, clientID, cursor string, limit int) ([]*models.PatientRequest, string, error) {
+	args := m.Called(ctx, clientID, cursor, limit)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, "", args.Error(2)
 	}
-	return args.Get(0).([]*models.PatientRequest), args.Error(1)
+	return args.Get(0).([]*models.PatientRequest), args.String(1), args.Error(2)
 }
 
-func (m *MockPatientsService) UpdatePatient(ctx context.Context, patient *models.PatientRequest) error {
+func (m *MockPatientsService) UpdatePatient(ctx context.Context, patient *models.PatientRequest) (*models.PatientRequest, error) {
 	args := m.Called(ctx, patient)
-	return args.Error(0)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PatientRequest), args.Error(1)
+}
+
+func (m *MockPatientsService) PatchPatient(ctx context.Context, id, contentType string, patch []byte) (*models.PatientRequest, error) {
+	args := m.Called(ctx, id, contentType, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PatientRequest), args.Error(1)
 }
 
 func (m *MockPatientsService) DeletePatient(ctx context.Context, id string) error {
@@ -50,6 +61,22 @@ func (m *MockPatientsService) DeletePatient(ctx context.Context, id string) erro
 	return args.Error(0)
 }
 
+func (m *MockPatientsService) ImportPatients(ctx context.Context, request *models.PatientImportRequest) (*models.PatientImportReport, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PatientImportReport), args.Error(1)
+}
+
+func (m *MockPatientsService) SearchPatients(ctx context.Context, request *models.PatientSearchRequest) (*models.PatientSearchResult, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PatientSearchResult), args.Error(1)
+}
+
 func TestNew(t *testing.T) {
 	// Arrange
 	logger := zaptest.NewLogger(t).Sugar()
@@ -323,7 +350,7 @@ func TestPatients_GetAll(t *testing.T) {
 			name:     "Success",
 			clientID: "client123",
 			mockSetup: func(m *MockPatientsService) {
-				m.On("GetAllPatients", mock.Anything, "client123").Return(samplePatients, nil)
+				m.On("GetAllPatients", mock.Anything, "client123", "", 0).Return(samplePatients, "", nil)
 			},
 			expectedError:  nil,
 			expectedResult: samplePatients,
@@ -332,7 +359,7 @@ func TestPatients_GetAll(t *testing.T) {
 			name:     "Not Found",
 			clientID: "nonexistent",
 			mockSetup: func(m *MockPatientsService) {
-				m.On("GetAllPatients", mock.Anything, "nonexistent").Return(nil, errors.New("patients not found"))
+				m.On("GetAllPatients", mock.Anything, "nonexistent", "", 0).Return(nil, "", errors.New("patients not found"))
 			},
 			expectedError:  errors.New("patients not found"),
 			expectedResult: nil,
@@ -341,7 +368,7 @@ func TestPatients_GetAll(t *testing.T) {
 			name:     "Service Error",
 			clientID: "client456",
 			mockSetup: func(m *MockPatientsService) {
-				m.On("GetAllPatients", mock.Anything, "client456").Return(nil, errors.New("service error"))
+				m.On("GetAllPatients", mock.Anything, "client456", "", 0).Return(nil, "", errors.New("service error"))
 			},
 			expectedError:  errors.New("service error"),
 			expectedResult: nil,
@@ -361,7 +388,7 @@ func TestPatients_GetAll(t *testing.T) {
 			}
 
 			// Act
-			result, err := handler.GetAll(context.Background(), tt.clientID)
+			result, _, err := handler.GetAll(context.Background(), tt.clientID, "", 0)
 
 			// Assert
 			if tt.expectedError != nil {
@@ -396,7 +423,7 @@ func TestPatients_Update(t *testing.T) {
 				Gender:    "M", // Agregando género
 			},
 			mockSetup: func(m *MockPatientsService, p *models.PatientRequest) {
-				m.On("UpdatePatient", mock.Anything, p).Return(nil)
+				m.On("UpdatePatient", mock.Anything, p).Return(p, nil)
 			},
 			expectedError: nil,
 		},
@@ -411,7 +438,7 @@ func TestPatients_Update(t *testing.T) {
 				Gender:    "F", // Agregando género
 			},
 			mockSetup: func(m *MockPatientsService, p *models.PatientRequest) {
-				m.On("UpdatePatient", mock.Anything, p).Return(errors.New("service error"))
+				m.On("UpdatePatient", mock.Anything, p).Return(nil, errors.New("service error"))
 			},
 			expectedError: errors.New("service error"),
 		},
@@ -506,3 +533,22 @@ func TestPatients_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestPatients_Patch(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	mockService := new(MockPatientsService)
+	handler := &Patients{Service: mockService, Logger: logger}
+	patch := []byte(`{"notes":null}`)
+	patched := &models.PatientRequest{ID: "123", ClientID: "client123"}
+	mockService.On("PatchPatient", mock.Anything, "123", "application/merge-patch+json", patch).Return(patched, nil)
+	mockService.On("PatchPatient", mock.Anything, "456", "application/merge-patch+json", patch).Return(nil, errors.New("service error"))
+
+	result, err := handler.Patch(context.Background(), "123", "application/merge-patch+json", patch)
+	assert.NoError(t, err)
+	assert.Equal(t, patched, result)
+
+	result, err = handler.Patch(context.Background(), "456", "application/merge-patch+json", patch)
+	assert.EqualError(t, err, "service error")
+	assert.Nil(t, result)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"go.uber.org/zap"
+)
+
+type WaitlistHandler interface {
+	Join(context.Context, *models.WaitlistEntryRequest) (*models.WaitlistEntryRequest, error)
+	GetByDoctorID(ctx context.Context, doctorID string) ([]*models.WaitlistEntryRequest, error)
+	Leave(ctx context.Context, id string) error
+	CheckAccept(context.Context, *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error)
+	Accept(context.Context, *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error)
+}
+
+type WaitlistService interface {
+	Join(context.Context, *models.WaitlistEntryRequest) (*models.WaitlistEntryRequest, error)
+	GetByDoctorID(ctx context.Context, doctorID string) ([]*models.WaitlistEntryRequest, error)
+	Leave(ctx context.Context, id string) error
+	CheckAccept(context.Context, *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error)
+	Accept(context.Context, *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error)
+}
+
+type Waitlist struct {
+	Service WaitlistService
+	Logger  *zap.SugaredLogger
+}
+
+func New(service WaitlistService, logger *zap.SugaredLogger) WaitlistHandler {
+	return &Waitlist{Service: service, Logger: logger}
+}
+
+func (w *Waitlist) Join(ctx context.Context, entry *models.WaitlistEntryRequest) (*models.WaitlistEntryRequest, error) {
+	w.log(ctx).Infof("Adding patient %s to waitlist of doctor %s", entry.PatientID, entry.DoctorID)
+	result, err := w.Service.Join(ctx, entry)
+	if err != nil {
+		w.log(ctx).Errorf("Error joining waitlist: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (w *Waitlist) GetByDoctorID(ctx context.Context, doctorID string) ([]*models.WaitlistEntryRequest, error) {
+	w.log(ctx).Infof("Getting waitlist for doctor %s", doctorID)
+	result, err := w.Service.GetByDoctorID(ctx, doctorID)
+	if err != nil {
+		w.log(ctx).Errorf("Error getting waitlist: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (w *Waitlist) Leave(ctx context.Context, id string) error {
+	w.log(ctx).Infof("Removing waitlist entry: %s", id)
+	if err := w.Service.Leave(ctx, id); err != nil {
+		w.log(ctx).Errorf("Error leaving waitlist: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (w *Waitlist) CheckAccept(ctx context.Context, request *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error) {
+	w.log(ctx).Infof("Checking waitlist offer %s for entry %s", request.OfferID, request.EntryID)
+	result, err := w.Service.CheckAccept(ctx, request)
+	if err != nil {
+		w.log(ctx).Errorf("Error checking waitlist offer: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (w *Waitlist) Accept(ctx context.Context, request *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error) {
+	w.log(ctx).Infof("Accepting waitlist offer %s for entry %s", request.OfferID, request.EntryID)
+	result, err := w.Service.Accept(ctx, request)
+	if err != nil {
+		w.log(ctx).Errorf("Error accepting waitlist offer: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del handler.
+func (w *Waitlist) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, w.Logger)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+// MockWaitlistService implementa la interfaz WaitlistService para los tests
+type MockWaitlistService struct {
+	mock.Mock
+}
+
+func (m *MockWaitlistService) Join(ctx context.Context, entry *models.WaitlistEntryRequest) (*models.WaitlistEntryRequest, error) {
+	args := m.Called(ctx, entry)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WaitlistEntryRequest), args.Error(1)
+}
+
+func (m *MockWaitlistService) GetByDoctorID(ctx context.Context, doctorID string) ([]*models.WaitlistEntryRequest, error) {
+	args := m.Called(ctx, doctorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.WaitlistEntryRequest), args.Error(1)
+}
+
+func (m *MockWaitlistService) Leave(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockWaitlistService) CheckAccept(ctx context.Context, request *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AppointmentRequest), args.Error(1)
+}
+
+func (m *MockWaitlistService) Accept(ctx context.Context, request *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AppointmentRequest), args.Error(1)
+}
+
+func TestWaitlist_Join(t *testing.T) {
+	svc := new(MockWaitlistService)
+	h := New(svc, zaptest.NewLogger(t).Sugar())
+	ctx := context.Background()
+	request := &models.WaitlistEntryRequest{ClientID: "client1", DoctorID: "doc1", PatientID: "patient1"}
+	expected := &models.WaitlistEntryRequest{ID: "entry1", Status: models.WaitlistStatusWaiting}
+
+	svc.On("Join", ctx, request).Return(expected, nil)
+
+	result, err := h.Join(ctx, request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestWaitlist_Accept_Error(t *testing.T) {
+	svc := new(MockWaitlistService)
+	h := New(svc, zaptest.NewLogger(t).Sugar())
+	ctx := context.Background()
+	request := &models.WaitlistAcceptRequest{OfferID: "offer1", EntryID: "entry1"}
+	expectedErr := errors.New("waitlist offer already taken")
+
+	svc.On("Accept", ctx, request).Return(nil, expectedErr)
+
+	result, err := h.Accept(ctx, request)
+
+	assert.Nil(t, result)
+	assert.Equal(t, expectedErr, err)
+}
+
+func TestWaitlist_Leave(t *testing.T) {
+	svc := new(MockWaitlistService)
+	h := New(svc, zaptest.NewLogger(t).Sugar())
+	ctx := context.Background()
+
+	svc.On("Leave", ctx, "entry1").Return(nil)
+
+	assert.NoError(t, h.Leave(ctx, "entry1"))
+	svc.AssertExpectations(t)
+}
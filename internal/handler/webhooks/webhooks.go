@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"go.uber.org/zap"
+)
+
+type WebhooksHandler interface {
+	Subscribe(context.Context, *models.WebhookSubscriptionRequest) (*models.WebhookSubscriptionRequest, error)
+	GetSubscriptions(ctx context.Context, clientID string) ([]*models.WebhookSubscriptionRequest, error)
+	Unsubscribe(ctx context.Context, clientID, id string) error
+	GetDeliveries(ctx context.Context, clientID, status string) ([]*models.WebhookDeliveryRequest, error)
+	Replay(ctx context.Context, clientID, id string) (*models.WebhookDeliveryRequest, error)
+}
+
+type WebhooksService interface {
+	Subscribe(context.Context, *models.WebhookSubscriptionRequest) (*models.WebhookSubscriptionRequest, error)
+	GetSubscriptions(ctx context.Context, clientID string) ([]*models.WebhookSubscriptionRequest, error)
+	Unsubscribe(ctx context.Context, clientID, id string) error
+	GetDeliveries(ctx context.Context, clientID, status string) ([]*models.WebhookDeliveryRequest, error)
+	Replay(ctx context.Context, clientID, id string) (*models.WebhookDeliveryRequest, error)
+}
+
+type Webhooks struct {
+	Service WebhooksService
+	Logger  *zap.SugaredLogger
+}
+
+func New(service WebhooksService, logger *zap.SugaredLogger) WebhooksHandler {
+	return &Webhooks{Service: service, Logger: logger}
+}
+
+func (w *Webhooks) Subscribe(ctx context.Context, request *models.WebhookSubscriptionRequest) (*models.WebhookSubscriptionRequest, error) {
+	w.log(ctx).Infof("Creating webhook subscription for client %s", request.ClientID)
+	result, err := w.Service.Subscribe(ctx, request)
+	if err != nil {
+		w.log(ctx).Errorf("Error creating webhook subscription: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (w *Webhooks) GetSubscriptions(ctx context.Context, clientID string) ([]*models.WebhookSubscriptionRequest, error) {
+	w.log(ctx).Infof("Getting webhook subscriptions for client %s", clientID)
+	result, err := w.Service.GetSubscriptions(ctx, clientID)
+	if err != nil {
+		w.log(ctx).Errorf("Error getting webhook subscriptions: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (w *Webhooks) Unsubscribe(ctx context.Context, clientID, id string) error {
+	w.log(ctx).Infof("Removing webhook subscription %s", id)
+	if err := w.Service.Unsubscribe(ctx, clientID, id); err != nil {
+		w.log(ctx).Errorf("Error removing webhook subscription: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (w *Webhooks) GetDeliveries(ctx context.Context, clientID, status string) ([]*models.WebhookDeliveryRequest, error) {
+	w.log(ctx).Infof("Getting webhook deliveries for client %s", clientID)
+	result, err := w.Service.GetDeliveries(ctx, clientID, status)
+	if err != nil {
+		w.log(ctx).Errorf("Error getting webhook deliveries: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (w *Webhooks) Replay(ctx context.Context, clientID, id string) (*models.WebhookDeliveryRequest, error) {
+	w.log(ctx).Infof("Replaying webhook delivery %s", id)
+	result, err := w.Service.Replay(ctx, clientID, id)
+	if err != nil {
+		w.log(ctx).Errorf("Error replaying webhook delivery: %s", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del handler.
+func (w *Webhooks) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, w.Logger)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+// MockWebhooksService implementa la interfaz WebhooksService para los tests
+type MockWebhooksService struct {
+	mock.Mock
+}
+
+func (m *MockWebhooksService) Subscribe(ctx context.Context, request *models.WebhookSubscriptionRequest) (*models.WebhookSubscriptionRequest, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookSubscriptionRequest), args.Error(1)
+}
+
+func (m *MockWebhooksService) GetSubscriptions(ctx context.Context, clientID string) ([]*models.WebhookSubscriptionRequest, error) {
+	args := m.Called(ctx, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.WebhookSubscriptionRequest), args.Error(1)
+}
+
+func (m *MockWebhooksService) Unsubscribe(ctx context.Context, clientID, id string) error {
+	return m.Called(ctx, clientID, id).Error(0)
+}
+
+func (m *MockWebhooksService) GetDeliveries(ctx context.Context, clientID, status string) ([]*models.WebhookDeliveryRequest, error) {
+	args := m.Called(ctx, clientID, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.WebhookDeliveryRequest), args.Error(1)
+}
+
+func (m *MockWebhooksService) Replay(ctx context.Context, clientID, id string) (*models.WebhookDeliveryRequest, error) {
+	args := m.Called(ctx, clientID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookDeliveryRequest), args.Error(1)
+}
+
+func TestWebhooks_Subscribe(t *testing.T) {
+	svc := new(MockWebhooksService)
+	h := New(svc, zaptest.NewLogger(t).Sugar())
+	ctx := context.Background()
+	request := &models.WebhookSubscriptionRequest{ClientID: "client1", URL: "https://clinic.example.com/hooks", Events: []string{"*"}}
+	expected := &models.WebhookSubscriptionRequest{ID: "sub1", Secret: "whsec_x"}
+
+	svc.On("Subscribe", ctx, request).Return(expected, nil)
+
+	result, err := h.Subscribe(ctx, request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestWebhooks_Replay_Error(t *testing.T) {
+	svc := new(MockWebhooksService)
+	h := New(svc, zaptest.NewLogger(t).Sugar())
+	ctx := context.Background()
+
+	svc.On("Replay", ctx, "client1", "delivery1").Return(nil, errors.New("not found"))
+
+	result, err := h.Replay(ctx, "client1", "delivery1")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestWebhooks_GetDeliveries(t *testing.T) {
+	svc := new(MockWebhooksService)
+	h := New(svc, zaptest.NewLogger(t).Sugar())
+	ctx := context.Background()
+	expected := []*models.WebhookDeliveryRequest{{ID: "delivery1", Status: models.WebhookDeliveryDead}}
+
+	svc.On("GetDeliveries", ctx, "client1", models.WebhookDeliveryDead).Return(expected, nil)
+
+	result, err := h.GetDeliveries(ctx, "client1", models.WebhookDeliveryDead)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
@@ -0,0 +1,285 @@
+package hl7
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func sampleAppointment() *models.Appointment {
+	return &models.Appointment{
+		ID:        "appointment123",
+		ClientID:  "client123",
+		PatientID: "patient123",
+		DoctorID:  "doctor123",
+		Date:      "2024-01-15T10:00:00Z",
+		Duration:  30,
+		Status:    models.AppointmentStatusScheduled,
+		Notes:     "Control | anual",
+	}
+}
+
+func samplePatient() *models.Patient {
+	return &models.Patient{
+		ID:             "patient123",
+		ClientID:       "client123",
+		FirstName:      "Juan",
+		LastName:       "Pérez",
+		DocType:        "DNI",
+		DocNumber:      "12345678",
+		BirthDate:      "1990-01-01",
+		Gender:         models.GenderNonBinary,
+		CountryCode:    "54",
+		PhoneNumber:    "1155555555",
+		Email:          "juan@example.com",
+		AddressStreet:  "Main St",
+		AddressNumber:  "123",
+		AddressCity:    "CABA",
+		AddressCountry: "AR",
+		ZipCode:        "1000",
+	}
+}
+
+func segments(msg Message) map[string]string {
+	result := map[string]string{}
+	for _, seg := range strings.Split(strings.TrimSuffix(string(msg.Body), segmentSep), segmentSep) {
+		result[seg[:3]] = seg
+	}
+	return result
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `a\F\b\S\c\T\d\R\e\E\f\.br\g`, Escape("a|b^c&d~e\\f\ng"))
+}
+
+func TestNewSIU(t *testing.T) {
+	now := time.Date(2024, 1, 10, 8, 0, 0, 0, time.UTC)
+	msg := NewSIU(Config{ReceivingApplication: "RIS", ReceivingFacility: "CLINIC"}, TriggerNewAppointment, sampleAppointment(), now)
+	segs := segments(msg)
+
+	assert.Equal(t, "SIU^S12^SIU_S12", msg.Type)
+	assert.Len(t, msg.ControlID, 20)
+	assert.Equal(t, `MSH|^~\&|IRIS|client123|RIS|CLINIC|20240110080000+0000||SIU^S12^SIU_S12|`+msg.ControlID+`|P|2.5.1`, segs["MSH"])
+
+	sch := strings.Split(segs["SCH"], "|")
+	assert.Equal(t, "appointment123", sch[1])
+	assert.Equal(t, "30", sch[9])
+	assert.Equal(t, "^^30^20240115100000+0000^20240115103000+0000", sch[11])
+	assert.Equal(t, "Booked", sch[25])
+	assert.Equal(t, `NTE|1||Control \F\ anual`, segs["NTE"])
+	assert.Equal(t, "PID|1||patient123^^^IRIS^MR", segs["PID"])
+	assert.Contains(t, segs["AIP"], "|doctor123|")
+}
+
+func TestNewSIU_Cancel(t *testing.T) {
+	appointment := sampleAppointment()
+	appointment.Status = models.AppointmentStatusCancelled
+
+	msg := NewSIU(Config{}, TriggerCancelAppointment, appointment, time.Now())
+
+	assert.Equal(t, "SIU^S15^SIU_S12", msg.Type)
+	assert.True(t, strings.HasSuffix(segments(msg)["SCH"], "|Cancelled"))
+}
+
+func TestNewADT(t *testing.T) {
+	msg := NewADT(Config{}, TriggerUpdatePatient, samplePatient(), time.Now())
+	segs := segments(msg)
+
+	assert.Equal(t, "ADT^A08^ADT_A01", msg.Type)
+	pid := strings.Split(segs["PID"], "|")
+	assert.Equal(t, "patient123^^^IRIS^MR~12345678^^^DNI^NI", pid[3])
+	assert.Equal(t, "Pérez^Juan", pid[5])
+	assert.Equal(t, "19900101", pid[7])
+	assert.Equal(t, "O", pid[8])
+	assert.Equal(t, "Main St 123^^CABA^^1000^AR", pid[11])
+	assert.Equal(t, "^PRN^PH^^54^^1155555555~^NET^Internet^juan@example.com", pid[13])
+	assert.True(t, strings.HasPrefix(segs["EVN"], "EVN|A08|"))
+}
+
+func TestNewADT_E164Phone(t *testing.T) {
+	patient := samplePatient()
+	patient.PhoneNumber = "+5491155555555"
+
+	pid := strings.Split(segments(NewADT(Config{}, TriggerRegisterPatient, patient, time.Now()))["PID"], "|")
+
+	assert.Equal(t, "^PRN^PH^^54^^91155555555~^NET^Internet^juan@example.com", pid[13])
+}
+
+func TestReadFrame(t *testing.T) {
+	reader := bufio.NewReader(bytes.NewReader(append([]byte("noise"), Frame([]byte("MSH|x\r"))...)))
+
+	body, err := ReadFrame(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "MSH|x\r", string(body))
+}
+
+func TestCheckACK(t *testing.T) {
+	msg := NewADT(Config{}, TriggerRegisterPatient, samplePatient(), time.Now())
+
+	assert.NoError(t, CheckACK(NewACK(msg.Body, AckAccept, ""), msg.ControlID))
+	assert.ErrorIs(t, CheckACK(NewACK(msg.Body, AckError, "busy"), msg.ControlID), ErrNotAcknowledged)
+	assert.ErrorIs(t, CheckACK(NewACK(msg.Body, AckReject, "bad"), msg.ControlID), ErrRejected)
+	assert.ErrorIs(t, CheckACK(NewACK(msg.Body, AckAccept, ""), "other"), ErrNotAcknowledged)
+	assert.ErrorIs(t, CheckACK([]byte("MSH|^~\\&\r"), msg.ControlID), ErrNotAcknowledged)
+}
+
+// startListener levanta un listener MLLP local que responde con respond.
+func startListener(t *testing.T, respond func(msg []byte) []byte) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go ServeMLLP(ln, respond)
+	return ln.Addr().String()
+}
+
+func TestMLLPSender_Accepted(t *testing.T) {
+	var received atomic.Value
+	addr := startListener(t, func(msg []byte) []byte {
+		received.Store(string(msg))
+		return NewACK(msg, AckAccept, "")
+	})
+	msg := NewSIU(Config{}, TriggerNewAppointment, sampleAppointment(), time.Now())
+
+	err := NewMLLPSender(addr).Send(context.Background(), msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, string(msg.Body), received.Load())
+}
+
+func TestMLLPSender_RetriesOnNAK(t *testing.T) {
+	var calls int32
+	addr := startListener(t, func(msg []byte) []byte {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return NewACK(msg, AckError, "try again")
+		}
+		return NewACK(msg, AckAccept, "")
+	})
+	sender := NewMLLPSender(addr)
+	sender.Backoff = time.Millisecond
+
+	err := sender.Send(context.Background(), NewSIU(Config{}, TriggerReschedule, sampleAppointment(), time.Now()))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestMLLPSender_DoesNotRetryReject(t *testing.T) {
+	var calls int32
+	addr := startListener(t, func(msg []byte) []byte {
+		atomic.AddInt32(&calls, 1)
+		return NewACK(msg, AckReject, "unknown patient")
+	})
+	sender := NewMLLPSender(addr)
+	sender.Backoff = time.Millisecond
+
+	err := sender.Send(context.Background(), NewADT(Config{}, TriggerRegisterPatient, samplePatient(), time.Now()))
+
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestMLLPSender_GivesUpAfterMaxAttempts(t *testing.T) {
+	addr := startListener(t, func(msg []byte) []byte { return nil })
+	sender := NewMLLPSender(addr)
+	sender.Backoff = time.Millisecond
+	sender.Timeout = 200 * time.Millisecond
+
+	err := sender.Send(context.Background(), NewSIU(Config{}, TriggerNewAppointment, sampleAppointment(), time.Now()))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "after 3 attempts")
+}
+
+type failingSender struct{}
+
+func (failingSender) Send(context.Context, Message) error { return errors.New("down") }
+
+func TestOutbound_PropagatesSenderError(t *testing.T) {
+	outbound := NewOutbound(failingSender{}, Config{}, zaptest.NewLogger(t).Sugar())
+
+	assert.Error(t, outbound.AppointmentCancelled(context.Background(), sampleAppointment()))
+	assert.NoError(t, NewOutbound(&LogSender{Logger: zaptest.NewLogger(t).Sugar()}, Config{}, zaptest.NewLogger(t).Sugar()).PatientRegistered(context.Background(), samplePatient()))
+}
+
+type recordingSender struct {
+	sent []Message
+	err  error
+}
+
+func (r *recordingSender) Send(_ context.Context, msg Message) error {
+	r.sent = append(r.sent, msg)
+	return r.err
+}
+
+func event(t *testing.T, eventType string, data interface{}) *events.Event {
+	event, err := events.New(eventType, events.AggregateAppointment, "appointment123", "client123", data)
+	require.NoError(t, err)
+	return event
+}
+
+func TestPublisher_TranslatesEvents(t *testing.T) {
+	sender := &recordingSender{}
+	publisher := NewPublisher(NewOutbound(sender, Config{}, zaptest.NewLogger(t).Sugar()))
+
+	failed, err := publisher.Publish(context.Background(), []*events.Event{
+		event(t, events.PatientRegistered, samplePatient()),
+		event(t, events.AppointmentBooked, sampleAppointment()),
+		event(t, events.AppointmentRescheduled, map[string]interface{}{"appointment": sampleAppointment(), "previous_date": "2024-01-14T10:00:00Z"}),
+		event(t, events.AppointmentUpdated, sampleAppointment()),
+		event(t, events.AppointmentCancelled, sampleAppointment()),
+		event(t, events.AppointmentNoShow, sampleAppointment()),
+		event(t, events.AppointmentDeleted, sampleAppointment()),
+	})
+
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+	var types []string
+	for _, msg := range sender.sent {
+		types = append(types, msg.Type)
+	}
+	assert.Equal(t, []string{"ADT^A04^ADT_A01", "SIU^S12^SIU_S12", "SIU^S13^SIU_S12", "SIU^S14^SIU_S12", "SIU^S15^SIU_S12", "SIU^S26^SIU_S12"}, types)
+	assert.Contains(t, segments(sender.sent[1])["PID"], "patient123")
+}
+
+func TestPublisher_Failures(t *testing.T) {
+	booked := event(t, events.AppointmentBooked, sampleAppointment())
+
+	rejected := &recordingSender{err: ErrRejected}
+	failed, err := NewPublisher(NewOutbound(rejected, Config{}, zaptest.NewLogger(t).Sugar())).Publish(context.Background(), []*events.Event{booked})
+	require.NoError(t, err)
+	assert.Empty(t, failed, "a reject is not retried")
+
+	nak := &recordingSender{err: ErrNotAcknowledged}
+	failed, err = NewPublisher(NewOutbound(nak, Config{}, zaptest.NewLogger(t).Sugar())).Publish(context.Background(), []*events.Event{booked})
+	require.NoError(t, err)
+	assert.ErrorIs(t, failed[booked.ID], ErrNotAcknowledged)
+}
+
+func TestPublisher_StopsWhenUnreachable(t *testing.T) {
+	sender := &recordingSender{err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+	publisher := NewPublisher(NewOutbound(sender, Config{}, zaptest.NewLogger(t).Sugar()))
+	evts := []*events.Event{
+		event(t, events.AppointmentBooked, sampleAppointment()),
+		event(t, events.AppointmentUpdated, sampleAppointment()),
+		event(t, events.AppointmentCancelled, sampleAppointment()),
+	}
+
+	failed, err := publisher.Publish(context.Background(), evts)
+
+	require.NoError(t, err)
+	assert.Len(t, failed, 3)
+	assert.Len(t, sender.sent, 1)
+}
@@ -0,0 +1,247 @@
+package hl7
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/phones"
+	"github.com/google/uuid"
+)
+
+// Eventos HL7 v2.5.1 que generamos.
+const (
+	TriggerNewAppointment    = "S12"
+	TriggerReschedule        = "S13"
+	TriggerModifyAppointment = "S14"
+	TriggerCancelAppointment = "S15"
+	TriggerNoShow            = "S26"
+	TriggerRegisterPatient   = "A04"
+	TriggerUpdatePatient     = "A08"
+)
+
+const (
+	version         = "2.5.1"
+	segmentSep      = "\r"
+	timestampFormat = "20060102150405-0700"
+	dateFormat      = "20060102"
+)
+
+// Config completa los campos de MSH que dependen de la integración.
+type Config struct {
+	SendingApplication   string
+	ReceivingApplication string
+	ReceivingFacility    string
+	ProcessingID         string // P (producción), T (training) o D (debug)
+}
+
+func (c Config) withDefaults() Config {
+	if c.SendingApplication == "" {
+		c.SendingApplication = "IRIS"
+	}
+	if c.ProcessingID == "" {
+		c.ProcessingID = "P"
+	}
+	return c
+}
+
+// Message es un mensaje HL7 v2 ya codificado (segmentos separados por CR).
+type Message struct {
+	ControlID string
+	Type      string
+	Body      []byte
+}
+
+// Escape aplica las secuencias de escape de HL7 v2 sobre un valor de texto.
+func Escape(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\E\`,
+		"|", `\F\`,
+		"^", `\S\`,
+		"&", `\T\`,
+		"~", `\R\`,
+		"\r\n", `\.br\`,
+		"\n", `\.br\`,
+		"\r", `\.br\`,
+	)
+	return replacer.Replace(value)
+}
+
+// segment arma un segmento a partir de sus campos. Los campos ya vienen
+// escapados y pueden contener componentes (^) o repeticiones (~).
+func segment(name string, fields ...string) string {
+	// Quitar campos vacíos al final
+	last := len(fields)
+	for last > 0 && fields[last-1] == "" {
+		last--
+	}
+	return strings.Join(append([]string{name}, fields[:last]...), "|")
+}
+
+func components(values ...string) string {
+	last := len(values)
+	for last > 0 && values[last-1] == "" {
+		last--
+	}
+	return strings.Join(values[:last], "^")
+}
+
+func newControlID() string {
+	return strings.ReplaceAll(uuid.NewString(), "-", "")[:20]
+}
+
+func formatTimestamp(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return ""
+	}
+	return t.Format(timestampFormat)
+}
+
+func formatDate(value string) string {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return ""
+	}
+	return t.Format(dateFormat)
+}
+
+func msh(cfg Config, sendingFacility, messageType, controlID string, now time.Time) string {
+	// MSH-1 es el separador de campos, por eso MSH-2 va primero
+	return "MSH|" + strings.Join([]string{
+		`^~\&`,
+		Escape(cfg.SendingApplication),
+		Escape(sendingFacility),
+		Escape(cfg.ReceivingApplication),
+		Escape(cfg.ReceivingFacility),
+		now.Format(timestampFormat),
+		"",
+		messageType,
+		controlID,
+		cfg.ProcessingID,
+		version,
+	}, "|")
+}
+
+func encode(segments ...string) []byte {
+	return []byte(strings.Join(segments, segmentSep) + segmentSep)
+}
+
+func fillerStatus(status models.AppointmentStatus) string {
+	switch status {
+	case models.AppointmentStatusInProgress:
+		return "Started"
+	case models.AppointmentStatusCompleted:
+		return "Complete"
+	case models.AppointmentStatusCancelled:
+		return "Cancelled"
+	case models.AppointmentStatusNoShow:
+		return "Noshow"
+	default:
+		return "Booked"
+	}
+}
+
+func administrativeSex(gender string) string {
+	switch gender {
+	case models.GenderMale, models.GenderFemale:
+		return gender
+	case models.GenderNonBinary:
+		return "O"
+	default:
+		return "U"
+	}
+}
+
+// NewSIU arma un SIU^S12/S13/S14/S15/S26 para un turno.
+func NewSIU(cfg Config, trigger string, a *models.Appointment, now time.Time) Message {
+	cfg = cfg.withDefaults()
+	controlID := newControlID()
+	messageType := components("SIU", trigger, "SIU_S12")
+
+	start := formatTimestamp(a.Date)
+	end := ""
+	if t, err := time.Parse(time.RFC3339, a.Date); err == nil {
+		end = t.Add(time.Duration(a.Duration) * time.Minute).Format(timestampFormat)
+	}
+	duration := strconv.Itoa(a.Duration)
+	status := fillerStatus(a.Status)
+
+	segments := []string{
+		msh(cfg, a.ClientID, messageType, controlID, now),
+		segment("SCH",
+			Escape(a.ID),
+			Escape(a.ID),
+			"", "", "", "",
+			"", "",
+			duration,
+			"MIN",
+			components("", "", duration, start, end),
+			"", "", "", "", "", "", "", "", "", "", "", "", "",
+			status,
+		),
+	}
+	if a.Notes != "" {
+		segments = append(segments, segment("NTE", "1", "", Escape(a.Notes)))
+	}
+	segments = append(segments,
+		segment("PID", "1", "", components(Escape(a.PatientID), "", "", "IRIS", "MR")),
+		segment("RGS", "1", "A"),
+		segment("AIP", "1", "A", Escape(a.DoctorID), "", "", start, "", "", duration, "MIN", "", status),
+	)
+
+	return Message{ControlID: controlID, Type: messageType, Body: encode(segments...)}
+}
+
+// NewADT arma un ADT^A04/A08 para un paciente.
+func NewADT(cfg Config, trigger string, p *models.Patient, now time.Time) Message {
+	cfg = cfg.withDefaults()
+	controlID := newControlID()
+	// A04 y A08 comparten la estructura de A01
+	messageType := components("ADT", trigger, "ADT_A01")
+
+	identifiers := components(Escape(p.ID), "", "", "IRIS", "MR")
+	if p.DocNumber != "" {
+		identifiers += "~" + components(Escape(p.DocNumber), "", "", Escape(p.DocType), "NI")
+	}
+	address := components(
+		Escape(strings.TrimSpace(p.AddressStreet+" "+p.AddressNumber)),
+		"",
+		Escape(p.AddressCity),
+		"",
+		Escape(p.ZipCode),
+		Escape(p.AddressCountry),
+	)
+	countryCode, phone := p.CountryCode, p.PhoneNumber
+	if strings.HasPrefix(phone, "+") {
+		// XTN lleva el código de país y el número local por separado
+		countryCode, phone = phones.Split(phone)
+	}
+	telecom := components("", "PRN", "PH", "", Escape(countryCode), "", Escape(phone))
+	if p.Email != "" {
+		telecom += "~" + components("", "NET", "Internet", Escape(p.Email))
+	}
+
+	segments := []string{
+		msh(cfg, p.ClientID, messageType, controlID, now),
+		segment("EVN", trigger, now.Format(timestampFormat)),
+		segment("PID",
+			"1",
+			"",
+			identifiers,
+			"",
+			components(Escape(p.LastName), Escape(p.FirstName)),
+			"",
+			formatDate(p.BirthDate),
+			administrativeSex(p.Gender),
+			"", "",
+			address,
+			"",
+			telecom,
+		),
+		segment("PV1", "1", "O"),
+	}
+
+	return Message{ControlID: controlID, Type: messageType, Body: encode(segments...)}
+}
@@ -0,0 +1,242 @@
+package hl7
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Marcas de framing de MLLP (Minimal Lower Layer Protocol).
+const (
+	startBlock     = 0x0b
+	endBlock       = 0x1c
+	carriageReturn = 0x0d
+)
+
+// Códigos de MSA-1. AA/CA son aceptaciones, el resto son NAK.
+const (
+	AckAccept       = "AA"
+	AckError        = "AE"
+	AckReject       = "AR"
+	AckCommitAccept = "CA"
+	AckCommitError  = "CE"
+	AckCommitReject = "CR"
+)
+
+const (
+	defaultAttempts  = 3
+	defaultTimeout   = 10 * time.Second
+	defaultBackoff   = 500 * time.Millisecond
+	maxResponseBytes = 1 << 20
+)
+
+var (
+	// ErrRejected indica un NAK definitivo (AR/CR): reintentar no sirve.
+	ErrRejected = errors.New("hl7 message rejected")
+	// ErrNotAcknowledged indica un NAK recuperable (AE/CE) o un ACK inválido.
+	ErrNotAcknowledged = errors.New("hl7 message not acknowledged")
+)
+
+// Sender entrega mensajes HL7 a un sistema externo.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// MLLPSender envía cada mensaje por una conexión TCP nueva con framing MLLP,
+// espera el ACK y reintenta ante errores de red o NAK recuperables.
+type MLLPSender struct {
+	Addr        string
+	MaxAttempts int
+	Timeout     time.Duration
+	Backoff     time.Duration
+	Dialer      net.Dialer
+}
+
+func NewMLLPSender(addr string) *MLLPSender {
+	return &MLLPSender{
+		Addr:        addr,
+		MaxAttempts: defaultAttempts,
+		Timeout:     defaultTimeout,
+		Backoff:     defaultBackoff,
+	}
+}
+
+func (s *MLLPSender) Send(ctx context.Context, msg Message) error {
+	attempts := s.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = s.sendOnce(ctx, msg)
+		if err == nil || errors.Is(err, ErrRejected) {
+			return err
+		}
+		if attempt == attempts {
+			break
+		}
+		// Backoff exponencial: Backoff, 2*Backoff, 4*Backoff...
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.Backoff << (attempt - 1)):
+		}
+	}
+	return fmt.Errorf("sending %s after %d attempts: %w", msg.ControlID, attempts, err)
+}
+
+func (s *MLLPSender) sendOnce(ctx context.Context, msg Message) error {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := s.Dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(Frame(msg.Body)); err != nil {
+		return err
+	}
+	response, err := ReadFrame(bufio.NewReader(conn))
+	if err != nil {
+		return fmt.Errorf("reading ACK: %w", err)
+	}
+	return CheckACK(response, msg.ControlID)
+}
+
+// Frame envuelve un mensaje con los delimitadores MLLP.
+func Frame(body []byte) []byte {
+	framed := make([]byte, 0, len(body)+3)
+	framed = append(framed, startBlock)
+	framed = append(framed, body...)
+	return append(framed, endBlock, carriageReturn)
+}
+
+// ReadFrame lee un bloque MLLP completo y devuelve el mensaje sin delimitadores.
+func ReadFrame(r *bufio.Reader) ([]byte, error) {
+	// Descartar cualquier byte previo al inicio de bloque
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == startBlock {
+			break
+		}
+	}
+
+	var body bytes.Buffer
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == endBlock {
+			next, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if next == carriageReturn {
+				return body.Bytes(), nil
+			}
+			body.WriteByte(b)
+			b = next
+		}
+		if body.Len() >= maxResponseBytes {
+			return nil, fmt.Errorf("mllp frame exceeds %d bytes", maxResponseBytes)
+		}
+		body.WriteByte(b)
+	}
+}
+
+// CheckACK valida el MSA de una respuesta contra el control ID enviado.
+func CheckACK(response []byte, controlID string) error {
+	for _, seg := range strings.Split(string(response), segmentSep) {
+		seg = strings.TrimLeft(seg, "\n")
+		if !strings.HasPrefix(seg, "MSA|") {
+			continue
+		}
+		fields := strings.Split(seg, "|")
+		code := field(fields, 1)
+		if ackedID := field(fields, 2); controlID != "" && ackedID != controlID {
+			return fmt.Errorf("%w: ACK for control ID %q, expected %q", ErrNotAcknowledged, ackedID, controlID)
+		}
+		switch code {
+		case AckAccept, AckCommitAccept:
+			return nil
+		case AckReject, AckCommitReject:
+			return fmt.Errorf("%w: %s %s", ErrRejected, code, field(fields, 3))
+		default:
+			return fmt.Errorf("%w: %s %s", ErrNotAcknowledged, code, field(fields, 3))
+		}
+	}
+	return fmt.Errorf("%w: response without MSA segment", ErrNotAcknowledged)
+}
+
+func field(fields []string, i int) string {
+	if i < len(fields) {
+		return fields[i]
+	}
+	return ""
+}
+
+// NewACK arma el ACK para un mensaje recibido con el código indicado.
+func NewACK(received []byte, code, text string) []byte {
+	var header []string
+	for _, seg := range strings.Split(string(received), segmentSep) {
+		if strings.HasPrefix(seg, "MSH|") {
+			header = strings.Split(seg, "|")
+			break
+		}
+	}
+	controlID := field(header, 9)
+	ack := strings.Join([]string{
+		"MSH", `^~\&`,
+		field(header, 4), field(header, 5), field(header, 2), field(header, 3),
+		time.Now().Format(timestampFormat), "",
+		"ACK", newControlID(), field(header, 10), field(header, 11),
+	}, "|")
+	return encode(ack, segment("MSA", code, controlID, Escape(text)))
+}
+
+// ServeMLLP acepta conexiones en ln y responde cada mensaje con lo que
+// devuelva handle. Sirve como listener local para desarrollo y tests.
+func ServeMLLP(ln net.Listener, handle func(msg []byte) []byte) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				msg, err := ReadFrame(reader)
+				if err != nil {
+					return
+				}
+				response := handle(msg)
+				if response == nil {
+					return
+				}
+				if _, err := conn.Write(Frame(response)); err != nil {
+					return
+				}
+			}
+		}(conn)
+	}
+}
@@ -0,0 +1,90 @@
+package hl7
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"go.uber.org/zap"
+)
+
+// Outbound traduce los cambios de turnos y pacientes a mensajes SIU/ADT y
+// los entrega por el Sender configurado.
+type Outbound struct {
+	Sender Sender
+	Config Config
+	Logger *zap.SugaredLogger
+}
+
+func NewOutbound(sender Sender, cfg Config, logger *zap.SugaredLogger) *Outbound {
+	return &Outbound{Sender: sender, Config: cfg, Logger: logger}
+}
+
+func (o *Outbound) AppointmentBooked(ctx context.Context, a *models.Appointment) error {
+	return o.send(ctx, NewSIU(o.Config, TriggerNewAppointment, a, time.Now()))
+}
+
+func (o *Outbound) AppointmentRescheduled(ctx context.Context, a *models.Appointment) error {
+	return o.send(ctx, NewSIU(o.Config, TriggerReschedule, a, time.Now()))
+}
+
+// AppointmentModified avisa un cambio que no mueve el horario, como el
+// estado o las notas.
+func (o *Outbound) AppointmentModified(ctx context.Context, a *models.Appointment) error {
+	return o.send(ctx, NewSIU(o.Config, TriggerModifyAppointment, a, time.Now()))
+}
+
+func (o *Outbound) AppointmentCancelled(ctx context.Context, a *models.Appointment) error {
+	return o.send(ctx, NewSIU(o.Config, TriggerCancelAppointment, a, time.Now()))
+}
+
+func (o *Outbound) AppointmentNoShow(ctx context.Context, a *models.Appointment) error {
+	return o.send(ctx, NewSIU(o.Config, TriggerNoShow, a, time.Now()))
+}
+
+func (o *Outbound) PatientRegistered(ctx context.Context, p *models.Patient) error {
+	return o.send(ctx, NewADT(o.Config, TriggerRegisterPatient, p, time.Now()))
+}
+
+func (o *Outbound) PatientUpdated(ctx context.Context, p *models.Patient) error {
+	return o.send(ctx, NewADT(o.Config, TriggerUpdatePatient, p, time.Now()))
+}
+
+func (o *Outbound) send(ctx context.Context, msg Message) error {
+	if err := o.Sender.Send(ctx, msg); err != nil {
+		o.log(ctx).Errorw("error sending HL7 message", "type", msg.Type, "controlID", msg.ControlID, "error", err)
+		return err
+	}
+	o.log(ctx).Infow("HL7 message acknowledged", "type", msg.Type, "controlID", msg.ControlID)
+	return nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el de Outbound.
+func (o *Outbound) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, o.Logger)
+}
+
+// LogSender sólo registra los mensajes. Útil en local, donde no hay MLLP.
+type LogSender struct {
+	Logger *zap.SugaredLogger
+}
+
+// Send registra el tipo y el control ID; el cuerpo no, porque lleva datos
+// del paciente.
+func (l *LogSender) Send(ctx context.Context, msg Message) error {
+	logging.FromContext(ctx, l.Logger).Infow("HL7 message not sent (log sender)", "type", msg.Type, "controlID", msg.ControlID)
+	return nil
+}
+
+// ConfigFromEnv lee la dirección MLLP (HL7_MLLP_ADDR) y los datos de MSH de la
+// integración. Sin dirección, la integración HL7 queda deshabilitada.
+func ConfigFromEnv() (string, Config) {
+	return os.Getenv("HL7_MLLP_ADDR"), Config{
+		SendingApplication:   os.Getenv("HL7_SENDING_APPLICATION"),
+		ReceivingApplication: os.Getenv("HL7_RECEIVING_APPLICATION"),
+		ReceivingFacility:    os.Getenv("HL7_RECEIVING_FACILITY"),
+		ProcessingID:         os.Getenv("HL7_PROCESSING_ID"),
+	}
+}
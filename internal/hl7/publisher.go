@@ -0,0 +1,100 @@
+package hl7
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+)
+
+// Publisher traduce los eventos de dominio del outbox a mensajes HL7. Corre en
+// el relay, así un HIS lento o caído no demora los pedidos a la API: los
+// envíos que fallan quedan pendientes en el outbox y salen en la próxima
+// corrida. Los eventos sin mensaje HL7, como las bajas, se ignoran.
+type Publisher struct {
+	Outbound *Outbound
+}
+
+func NewPublisher(outbound *Outbound) *Publisher {
+	return &Publisher{Outbound: outbound}
+}
+
+func (p *Publisher) Publish(ctx context.Context, evts []*events.Event) (map[string]error, error) {
+	failed := map[string]error{}
+	var unreachable error
+	for _, event := range evts {
+		if !handles(event.Type) {
+			continue
+		}
+		// Si el HIS no responde, el resto del lote fallaría igual después de
+		// esperar el timeout de cada uno
+		if unreachable != nil {
+			failed[event.ID] = unreachable
+			continue
+		}
+		err := p.publish(ctx, event)
+		if err == nil || errors.Is(err, ErrRejected) {
+			// Un NAK definitivo no se arregla reintentando; Outbound ya lo registró
+			continue
+		}
+		failed[event.ID] = err
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			unreachable = err
+		}
+	}
+	return failed, nil
+}
+
+func handles(eventType string) bool {
+	switch eventType {
+	case events.PatientRegistered, events.PatientUpdated,
+		events.AppointmentBooked, events.AppointmentRescheduled, events.AppointmentUpdated, events.AppointmentCancelled, events.AppointmentNoShow:
+		return true
+	}
+	return false
+}
+
+func (p *Publisher) publish(ctx context.Context, event *events.Event) error {
+	switch event.Type {
+	case events.PatientRegistered, events.PatientUpdated:
+		var patient models.Patient
+		if err := json.Unmarshal(event.Data, &patient); err != nil {
+			return err
+		}
+		if event.Type == events.PatientRegistered {
+			return p.Outbound.PatientRegistered(ctx, &patient)
+		}
+		return p.Outbound.PatientUpdated(ctx, &patient)
+	case events.AppointmentRescheduled:
+		// El payload es el turno como quedó más el horario anterior
+		var data struct {
+			Appointment *models.Appointment `json:"appointment"`
+		}
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return err
+		}
+		if data.Appointment == nil {
+			return errors.New("rescheduled event without appointment")
+		}
+		return p.Outbound.AppointmentRescheduled(ctx, data.Appointment)
+	default:
+		var appointment models.Appointment
+		if err := json.Unmarshal(event.Data, &appointment); err != nil {
+			return err
+		}
+		switch event.Type {
+		case events.AppointmentBooked:
+			return p.Outbound.AppointmentBooked(ctx, &appointment)
+		case events.AppointmentCancelled:
+			return p.Outbound.AppointmentCancelled(ctx, &appointment)
+		case events.AppointmentNoShow:
+			return p.Outbound.AppointmentNoShow(ctx, &appointment)
+		default:
+			return p.Outbound.AppointmentModified(ctx, &appointment)
+		}
+	}
+}
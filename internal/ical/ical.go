@@ -0,0 +1,226 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Generación de iCalendar (RFC 5545) para las agendas suscribibles.
+
+const (
+	ContentType = "text/calendar; charset=utf-8"
+
+	crlf          = "\r\n"
+	maxLineOctets = 75
+	utcFormat     = "20060102T150405Z"
+	localFormat   = "20060102T150405"
+)
+
+// Valores de STATUS de VEVENT.
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+)
+
+type Event struct {
+	UID          string
+	Sequence     int
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Status       string
+	Created      time.Time
+	LastModified time.Time
+
+	// Campos que sólo completa Parse; Encode los ignora.
+	AllDay         bool
+	Attendees      []Attendee
+	RRule          string
+	ExceptionDates []time.Time
+	RecurrenceID   time.Time
+}
+
+type Attendee struct {
+	Email string
+	Name  string
+}
+
+type Calendar struct {
+	ProductID string
+	Name      string
+	Location  *time.Location
+	Events    []Event
+}
+
+// Encode serializa el calendario con CRLF y plegado de líneas a 75 octetos.
+// Si la zona horaria no es UTC se incluye su VTIMEZONE y las fechas de los
+// eventos se expresan con TZID.
+func (c *Calendar) Encode(now time.Time) []byte {
+	loc := c.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	productID := c.ProductID
+	if productID == "" {
+		productID = "-//MezeLaw//Iris//ES"
+	}
+
+	w := &writer{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + productID)
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		w.line("X-WR-CALNAME:" + EscapeText(c.Name))
+	}
+	if loc != time.UTC {
+		w.line("X-WR-TIMEZONE:" + loc.String())
+		from, to := eventRange(c.Events, now)
+		writeTimezone(w, loc, from, to)
+	}
+
+	for _, e := range c.Events {
+		w.line("BEGIN:VEVENT")
+		w.line("UID:" + e.UID)
+		w.line("DTSTAMP:" + now.UTC().Format(utcFormat))
+		w.line(dateProperty("DTSTART", e.Start, loc))
+		w.line(dateProperty("DTEND", e.End, loc))
+		w.line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+		if e.Status != "" {
+			w.line("STATUS:" + e.Status)
+		}
+		if e.Summary != "" {
+			w.line("SUMMARY:" + EscapeText(e.Summary))
+		}
+		if e.Description != "" {
+			w.line("DESCRIPTION:" + EscapeText(e.Description))
+		}
+		if !e.Created.IsZero() {
+			w.line("CREATED:" + e.Created.UTC().Format(utcFormat))
+		}
+		if !e.LastModified.IsZero() {
+			w.line("LAST-MODIFIED:" + e.LastModified.UTC().Format(utcFormat))
+		}
+		if e.Status == StatusCancelled {
+			w.line("TRANSP:TRANSPARENT")
+		}
+		w.line("END:VEVENT")
+	}
+
+	w.line("END:VCALENDAR")
+	return w.buf.Bytes()
+}
+
+func dateProperty(name string, t time.Time, loc *time.Location) string {
+	if loc == time.UTC {
+		return name + ":" + t.UTC().Format(utcFormat)
+	}
+	return name + ";TZID=" + loc.String() + ":" + t.In(loc).Format(localFormat)
+}
+
+func eventRange(events []Event, now time.Time) (time.Time, time.Time) {
+	from, to := now, now
+	for _, e := range events {
+		if e.Start.Before(from) {
+			from = e.Start
+		}
+		if e.End.After(to) {
+			to = e.End
+		}
+	}
+	return from, to
+}
+
+// writeTimezone arma el VTIMEZONE con las transiciones reales de la zona
+// entre from y to, tomadas de la base de zonas de Go.
+func writeTimezone(w *writer, loc *time.Location, from, to time.Time) {
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + loc.String())
+
+	t := from.In(loc)
+	name, offset := t.Zone()
+	start, _ := t.ZoneBounds()
+	if start.IsZero() {
+		start = time.Date(1970, 1, 1, 0, 0, 0, 0, loc)
+	}
+	writeObservance(w, t.IsDST(), name, offset, offset, start.In(time.FixedZone("", offset)))
+
+	for {
+		_, end := t.ZoneBounds()
+		if end.IsZero() || end.After(to) {
+			break
+		}
+		previousOffset := offset
+		t = end.In(loc)
+		name, offset = t.Zone()
+		// DTSTART de la transición se expresa en la hora local previa
+		writeObservance(w, t.IsDST(), name, previousOffset, offset, end.In(time.FixedZone("", previousOffset)))
+	}
+
+	w.line("END:VTIMEZONE")
+}
+
+func writeObservance(w *writer, dst bool, name string, offsetFrom, offsetTo int, start time.Time) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	w.line("BEGIN:" + kind)
+	w.line("DTSTART:" + start.Format(localFormat))
+	w.line("TZOFFSETFROM:" + formatOffset(offsetFrom))
+	w.line("TZOFFSETTO:" + formatOffset(offsetTo))
+	if name != "" {
+		w.line("TZNAME:" + EscapeText(name))
+	}
+	w.line("END:" + kind)
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, (seconds%3600)/60)
+}
+
+// EscapeText aplica el escape de valores TEXT de RFC 5545.
+func EscapeText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return replacer.Replace(value)
+}
+
+type writer struct {
+	buf bytes.Buffer
+}
+
+// line escribe una content line plegándola sin cortar caracteres UTF-8.
+func (w *writer) line(value string) {
+	limit := maxLineOctets
+	for len(value) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(value[cut]) {
+			cut--
+		}
+		w.buf.WriteString(value[:cut])
+		w.buf.WriteString(crlf + " ")
+		value = value[cut:]
+		// Las líneas de continuación llevan un espacio inicial
+		limit = maxLineOctets - 1
+	}
+	w.buf.WriteString(value)
+	w.buf.WriteString(crlf)
+}
@@ -0,0 +1,87 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscapeText(t *testing.T) {
+	assert.Equal(t, `a\\b\;c\,d\ne`, EscapeText("a\\b;c,d\ne"))
+}
+
+func TestWriterFoldsLongLines(t *testing.T) {
+	w := &writer{}
+	w.line("DESCRIPTION:" + strings.Repeat("ñ", 60))
+
+	lines := strings.Split(strings.TrimSuffix(w.buf.String(), crlf), crlf)
+	assert.Greater(t, len(lines), 1)
+	for i, line := range lines {
+		assert.LessOrEqual(t, len(line), maxLineOctets)
+		if i > 0 {
+			assert.True(t, strings.HasPrefix(line, " "))
+		}
+	}
+	// Desplegar devuelve el contenido original
+	unfolded := strings.ReplaceAll(strings.TrimSuffix(w.buf.String(), crlf), crlf+" ", "")
+	assert.Equal(t, "DESCRIPTION:"+strings.Repeat("ñ", 60), unfolded)
+}
+
+func TestCalendarEncode_UTC(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	calendar := &Calendar{
+		Name: "Agenda",
+		Events: []Event{{
+			UID:      "a1@iris",
+			Sequence: 2,
+			Start:    start,
+			End:      start.Add(30 * time.Minute),
+			Summary:  "Turno, control",
+			Status:   StatusCancelled,
+		}},
+	}
+
+	out := string(calendar.Encode(start))
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.NotContains(t, out, "VTIMEZONE")
+	assert.Contains(t, out, "UID:a1@iris\r\n")
+	assert.Contains(t, out, "DTSTART:20240115T100000Z\r\n")
+	assert.Contains(t, out, "DTEND:20240115T103000Z\r\n")
+	assert.Contains(t, out, "SEQUENCE:2\r\n")
+	assert.Contains(t, out, "STATUS:CANCELLED\r\n")
+	assert.Contains(t, out, `SUMMARY:Turno\, control`)
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+}
+
+func TestCalendarEncode_TimezoneWithDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	start := time.Date(2024, 7, 1, 9, 0, 0, 0, loc)
+	calendar := &Calendar{
+		Location: loc,
+		Events:   []Event{{UID: "a1@iris", Start: start, End: start.Add(time.Hour)}},
+	}
+
+	out := string(calendar.Encode(time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)))
+
+	assert.Contains(t, out, "BEGIN:VTIMEZONE\r\nTZID:America/New_York\r\n")
+	assert.Contains(t, out, "BEGIN:DAYLIGHT\r\nDTSTART:20240310T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\n")
+	assert.Contains(t, out, "DTSTART;TZID=America/New_York:20240701T090000\r\n")
+}
+
+func TestCalendarEncode_TimezoneWithoutDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/Argentina/Buenos_Aires")
+	require.NoError(t, err)
+	start := time.Date(2024, 7, 1, 9, 0, 0, 0, loc)
+	calendar := &Calendar{Location: loc, Events: []Event{{UID: "a1@iris", Start: start, End: start.Add(time.Hour)}}}
+
+	out := string(calendar.Encode(start))
+
+	assert.Equal(t, 1, strings.Count(out, "BEGIN:STANDARD"))
+	assert.Contains(t, out, "TZOFFSETTO:-0300\r\n")
+	assert.NotContains(t, out, "DAYLIGHT")
+}
@@ -0,0 +1,316 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lectura de archivos .ics exportados por otros sistemas de agenda.
+
+const dateFormat = "20060102"
+
+type property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Parse lee los VEVENT de un calendario. Las horas flotantes y los TZID que
+// Go no reconoce (por ejemplo los nombres de Windows que exporta Outlook) se
+// interpretan en loc. Los componentes anidados como VALARM se ignoran.
+func Parse(r io.Reader, loc *time.Location) ([]Event, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events []Event
+		stack  []string
+		props  []property
+	)
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		p, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		switch p.Name {
+		case "BEGIN":
+			stack = append(stack, strings.ToUpper(p.Value))
+			if strings.EqualFold(p.Value, "VEVENT") {
+				props = nil
+			}
+		case "END":
+			if len(stack) == 0 || !strings.EqualFold(stack[len(stack)-1], p.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", i+1, p.Value)
+			}
+			stack = stack[:len(stack)-1]
+			if strings.EqualFold(p.Value, "VEVENT") {
+				event, err := eventFromProperties(props, loc)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", i+1, err)
+				}
+				events = append(events, event)
+			}
+		default:
+			if len(stack) > 0 && stack[len(stack)-1] == "VEVENT" {
+				props = append(props, p)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unterminated %s", stack[len(stack)-1])
+	}
+	return events, nil
+}
+
+// unfold separa las content lines y une las líneas de continuación. Acepta
+// también LF sin CR, que generan algunos exportadores.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) > 0 {
+		lines[0] = strings.TrimPrefix(lines[0], "\ufeff")
+	}
+	return lines, nil
+}
+
+// parseProperty separa nombre, parámetros y valor respetando los parámetros
+// entre comillas, que pueden contener ':' y ';'.
+func parseProperty(line string) (property, error) {
+	p := property{Params: map[string]string{}}
+	var (
+		buf      bytes.Buffer
+		quoted   bool
+		param    string
+		haveName bool
+	)
+	flush := func() {
+		if !haveName {
+			p.Name = strings.ToUpper(buf.String())
+			haveName = true
+		} else if param != "" {
+			p.Params[param] = buf.String()
+		}
+		buf.Reset()
+		param = ""
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+			buf.WriteByte(c)
+		case c == ';':
+			flush()
+		case c == '=' && haveName && param == "":
+			param = strings.ToUpper(buf.String())
+			buf.Reset()
+		case c == ':':
+			flush()
+			p.Value = line[i+1:]
+			return p, nil
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return p, fmt.Errorf("malformed content line %q", line)
+}
+
+func eventFromProperties(props []property, loc *time.Location) (Event, error) {
+	var (
+		event    Event
+		duration time.Duration
+		hasEnd   bool
+	)
+	for _, p := range props {
+		var err error
+		switch p.Name {
+		case "UID":
+			event.UID = p.Value
+		case "SUMMARY":
+			event.Summary = UnescapeText(p.Value)
+		case "DESCRIPTION":
+			event.Description = UnescapeText(p.Value)
+		case "STATUS":
+			event.Status = strings.ToUpper(p.Value)
+		case "SEQUENCE":
+			event.Sequence, _ = strconv.Atoi(p.Value)
+		case "DTSTART":
+			event.Start, event.AllDay, err = parseDateTime(p, loc)
+		case "DTEND":
+			event.End, _, err = parseDateTime(p, loc)
+			hasEnd = true
+		case "DURATION":
+			duration, err = ParseDuration(p.Value)
+		case "RRULE":
+			event.RRule = p.Value
+		case "EXDATE":
+			for _, value := range strings.Split(p.Value, ",") {
+				var t time.Time
+				t, _, err = parseDateTime(property{Params: p.Params, Value: value}, loc)
+				if err != nil {
+					break
+				}
+				event.ExceptionDates = append(event.ExceptionDates, t)
+			}
+		case "RECURRENCE-ID":
+			event.RecurrenceID, _, err = parseDateTime(p, loc)
+		case "ATTENDEE":
+			event.Attendees = append(event.Attendees, Attendee{
+				Email: strings.TrimSpace(trimMailto(p.Value)),
+				Name:  p.Params["CN"],
+			})
+		case "CREATED":
+			event.Created, _, _ = parseDateTime(p, loc)
+		case "LAST-MODIFIED":
+			event.LastModified, _, _ = parseDateTime(p, loc)
+		}
+		if err != nil {
+			return Event{}, fmt.Errorf("invalid %s in event %q: %w", p.Name, event.UID, err)
+		}
+	}
+
+	if event.Start.IsZero() {
+		return Event{}, fmt.Errorf("event %q has no DTSTART", event.UID)
+	}
+	if !hasEnd {
+		switch {
+		case duration > 0:
+			event.End = event.Start.Add(duration)
+		case event.AllDay:
+			event.End = event.Start.AddDate(0, 0, 1)
+		default:
+			event.End = event.Start
+		}
+	}
+	return event, nil
+}
+
+func trimMailto(value string) string {
+	if len(value) >= 7 && strings.EqualFold(value[:7], "mailto:") {
+		return value[7:]
+	}
+	return value
+}
+
+// parseDateTime interpreta DATE, DATE-TIME en UTC, con TZID o flotante.
+func parseDateTime(p property, loc *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.Value)
+	if strings.EqualFold(p.Params["VALUE"], "DATE") || len(value) == len(dateFormat) {
+		t, err := time.ParseInLocation(dateFormat, value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcFormat, value)
+		return t, false, err
+	}
+	if tzid := p.Params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = tz
+		}
+	}
+	t, err := time.ParseInLocation(localFormat, value, loc)
+	return t, false, err
+}
+
+// ParseDuration interpreta un DURATION de RFC 5545 (por ejemplo PT45M o P1D).
+func ParseDuration(value string) (time.Duration, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign = -1
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var (
+		total  time.Duration
+		number int
+		digits bool
+		inTime bool
+	)
+	for _, c := range s[1:] {
+		if c >= '0' && c <= '9' {
+			number = number*10 + int(c-'0')
+			digits = true
+			continue
+		}
+		if c == 'T' {
+			inTime = true
+			continue
+		}
+		if !digits {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		var unit time.Duration
+		switch {
+		case c == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case c == 'H' && inTime:
+			unit = time.Hour
+		case c == 'M' && inTime:
+			unit = time.Minute
+		case c == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		total += time.Duration(number) * unit
+		number, digits = 0, false
+	}
+	if digits {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return sign * total, nil
+}
+
+// UnescapeText revierte EscapeText.
+func UnescapeText(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const outlookExport = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:evt-1\r\n" +
+	"DTSTART;TZID=America/Argentina/Buenos_Aires:20240115T100000\r\n" +
+	"DURATION:PT45M\r\n" +
+	"SUMMARY:Control\\, Ana García\r\n" +
+	"DESCRIPTION:Traer estudios\\nen ayunas\r\n" +
+	"ATTENDEE;CN=\"García, Ana\";ROLE=REQ-PARTICIPANT:mailto:ana@example.com\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"DESCRIPTION:Recordatorio\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:evt-2\r\n" +
+	"DTSTART;TZID=Argentina Standard Time:20240116T0900\r\n" +
+	" 00\r\n" +
+	"DTEND;TZID=Argentina Standard Time:20240116T093000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:evt-3\r\n" +
+	"DTSTART;VALUE=DATE:20240117\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	fallback := time.FixedZone("ART", -3*3600)
+
+	events, err := Parse(strings.NewReader(outlookExport), fallback)
+
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	first := events[0]
+	assert.Equal(t, "evt-1", first.UID)
+	assert.Equal(t, "2024-01-15T13:00:00Z", first.Start.UTC().Format(time.RFC3339))
+	assert.Equal(t, 45*time.Minute, first.End.Sub(first.Start))
+	assert.Equal(t, "Control, Ana García", first.Summary)
+	assert.Equal(t, "Traer estudios\nen ayunas", first.Description)
+	assert.Equal(t, []Attendee{{Email: "ana@example.com", Name: "García, Ana"}}, first.Attendees)
+
+	// TZID desconocido y línea plegada: se usa la zona por defecto
+	assert.Equal(t, "2024-01-16T12:00:00Z", events[1].Start.UTC().Format(time.RFC3339))
+	assert.Equal(t, 30*time.Minute, events[1].End.Sub(events[1].Start))
+
+	assert.True(t, events[2].AllDay)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"unterminated": "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\n",
+		"no dtstart":   "BEGIN:VEVENT\r\nUID:x\r\nEND:VEVENT\r\n",
+		"bad date":     "BEGIN:VEVENT\r\nDTSTART:2024-01-01\r\nEND:VEVENT\r\n",
+		"malformed":    "BEGIN:VEVENT\r\nnot a property\r\nEND:VEVENT\r\n",
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(input), nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"PT30M":    30 * time.Minute,
+		"PT1H30M":  90 * time.Minute,
+		"P1D":      24 * time.Hour,
+		"P1W":      7 * 24 * time.Hour,
+		"-PT15M":   -15 * time.Minute,
+		"P1DT2H5S": 26*time.Hour + 5*time.Second,
+	}
+	for input, expected := range tests {
+		got, err := ParseDuration(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, got, input)
+	}
+
+	for _, input := range []string{"", "PT", "P1H", "PT5", "30M"} {
+		_, err := ParseDuration(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestUnescapeText(t *testing.T) {
+	original := "a\\b;c,d\ne"
+	assert.Equal(t, original, UnescapeText(EscapeText(original)))
+}
@@ -0,0 +1,389 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expansión de RRULE para importar series recurrentes. Se soportan
+// FREQ=DAILY/WEEKLY/MONTHLY/YEARLY con INTERVAL, COUNT, UNTIL, BYDAY,
+// BYMONTHDAY y BYMONTH, que es lo que generan Google, Outlook y Apple.
+
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+
+	// maxPeriods corta reglas que nunca producen ocurrencias (por ejemplo
+	// BYMONTHDAY=31;BYMONTH=2).
+	maxPeriods = 10000
+)
+
+// WeekdayNum es un valor de BYDAY: N=0 significa todos esos días del
+// período; N=2 el segundo y N=-1 el último.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+type Recurrence struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRecurrence interpreta el valor de un RRULE. Un UNTIL sin hora se toma
+// en loc, incluyendo todo ese día.
+func ParseRecurrence(rule string, loc *time.Location) (*Recurrence, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	r := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err == nil && r.Interval < 1 {
+				err = fmt.Errorf("must be positive")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+		case "UNTIL":
+			var allDay bool
+			r.Until, allDay, err = parseDateTime(property{Value: value}, loc)
+			if err == nil && allDay {
+				r.Until = r.Until.AddDate(0, 0, 1).Add(-time.Second)
+			}
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(value, -31, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(value, 1, 12)
+			for _, m := range months {
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "WKST":
+			// Sólo se soporta semana de lunes a domingo
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid RRULE %s: %w", key, err)
+		}
+	}
+	switch r.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	default:
+		return nil, fmt.Errorf("unsupported RRULE FREQ %q", r.Freq)
+	}
+	return r, nil
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var result []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid weekday %q", item)
+		}
+		day, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", item)
+		}
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid weekday %q", item)
+			}
+		}
+		result = append(result, WeekdayNum{N: n, Day: day})
+	}
+	return result, nil
+}
+
+func parseInts(value string, min, max int) ([]int, error) {
+	var result []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("invalid value %q", item)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// Occurrences devuelve los inicios de la serie desde start (que siempre es
+// la primera ocurrencia) hasta limit inclusive, con un máximo de max.
+func (r *Recurrence) Occurrences(start, limit time.Time, max int) []time.Time {
+	if !r.Until.IsZero() && r.Until.Before(limit) {
+		limit = r.Until
+	}
+	if start.After(limit) || max <= 0 {
+		return nil
+	}
+
+	result := []time.Time{start}
+	for period := 0; period < maxPeriods; period++ {
+		candidates, periodStart := r.period(start, period)
+		if periodStart.After(limit) {
+			break
+		}
+		for _, t := range candidates {
+			if !t.After(start) {
+				continue
+			}
+			if t.After(limit) || (r.Count > 0 && len(result) >= r.Count) || len(result) >= max {
+				return result
+			}
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// period devuelve las ocurrencias candidatas del período n (ordenadas) y el
+// comienzo del período, que sirve para cortar la iteración.
+func (r *Recurrence) period(start time.Time, n int) ([]time.Time, time.Time) {
+	loc := start.Location()
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	at := func(year int, month time.Month, day int) (time.Time, bool) {
+		t := time.Date(year, month, day, hh, mm, ss, 0, loc)
+		// time.Date normaliza el 31 de febrero a marzo: no es una ocurrencia
+		return t, t.Day() == day && t.Month() == month
+	}
+
+	var candidates []time.Time
+	var periodStart time.Time
+	switch r.Freq {
+	case FreqDaily:
+		t, _ := at(y, m, d+n*r.Interval)
+		periodStart = t
+		if r.matchesDay(t) && r.matchesMonth(t) && r.matchesMonthDay(t) {
+			candidates = append(candidates, t)
+		}
+	case FreqWeekly:
+		offset := (int(start.Weekday()) + 6) % 7 // días desde el lunes
+		monday, _ := at(y, m, d-offset+7*n*r.Interval)
+		periodStart = monday
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []WeekdayNum{{Day: start.Weekday()}}
+		}
+		for _, wd := range days {
+			my, mm, md := monday.Date()
+			t, _ := at(my, mm, md+(int(wd.Day)+6)%7)
+			if r.matchesMonth(t) {
+				candidates = append(candidates, t)
+			}
+		}
+	case FreqMonthly:
+		first := time.Date(y, m+time.Month(n*r.Interval), 1, 0, 0, 0, 0, loc)
+		periodStart = first
+		if r.matchesMonth(first) {
+			candidates = r.daysInMonth(first.Year(), first.Month(), d, at)
+		}
+	case FreqYearly:
+		year := y + n*r.Interval
+		periodStart = time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{m}
+		}
+		for _, month := range months {
+			candidates = append(candidates, r.daysInMonth(year, month, d, at)...)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	return candidates, periodStart
+}
+
+func (r *Recurrence) daysInMonth(year int, month time.Month, defaultDay int, at func(int, time.Month, int) (time.Time, bool)) []time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	var result []time.Time
+	add := func(day int) {
+		if t, ok := at(year, month, day); ok {
+			result = append(result, t)
+		}
+	}
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, day := range r.ByMonthDay {
+			if day < 0 {
+				day = last + day + 1
+			}
+			add(day)
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			firstWeekday := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Weekday()
+			first := 1 + (int(wd.Day)-int(firstWeekday)+7)%7
+			var days []int
+			for day := first; day <= last; day += 7 {
+				days = append(days, day)
+			}
+			switch {
+			case wd.N == 0:
+				for _, day := range days {
+					add(day)
+				}
+			case wd.N > 0 && wd.N <= len(days):
+				add(days[wd.N-1])
+			case wd.N < 0 && -wd.N <= len(days):
+				add(days[len(days)+wd.N])
+			}
+		}
+	default:
+		add(defaultDay)
+	}
+	return result
+}
+
+func (r *Recurrence) matchesDay(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recurrence) matchesMonth(t time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if m == t.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recurrence) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, day := range r.ByMonthDay {
+		if day == t.Day() || last+day+1 == t.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandError indica que la serie con ese UID no se pudo expandir.
+type ExpandError struct {
+	UID string
+	Err error
+}
+
+func (e *ExpandError) Error() string {
+	return fmt.Sprintf("event %q: %v", e.UID, e.Err)
+}
+
+func (e *ExpandError) Unwrap() error { return e.Err }
+
+// Expand reemplaza cada serie recurrente por sus ocurrencias hasta limit
+// (como máximo max por serie), quitando los EXDATE y aplicando las
+// instancias modificadas (RECURRENCE-ID). Cada ocurrencia conserva el UID de
+// la serie y lleva su inicio original en RecurrenceID. Las series con un
+// RRULE inválido se devuelven como errores y no se incluyen.
+func Expand(events []Event, limit time.Time, max int) ([]Event, []error) {
+	overrides := map[string]map[int64]Event{}
+	for _, e := range events {
+		if !e.RecurrenceID.IsZero() {
+			if overrides[e.UID] == nil {
+				overrides[e.UID] = map[int64]Event{}
+			}
+			overrides[e.UID][e.RecurrenceID.Unix()] = e
+		}
+	}
+
+	var (
+		result []Event
+		errs   []error
+	)
+	for _, master := range events {
+		if !master.RecurrenceID.IsZero() {
+			continue
+		}
+		if master.RRule == "" {
+			result = append(result, master)
+			continue
+		}
+		rule, err := ParseRecurrence(master.RRule, master.Start.Location())
+		if err != nil {
+			errs = append(errs, &ExpandError{UID: master.UID, Err: err})
+			continue
+		}
+
+		excluded := map[int64]bool{}
+		for _, t := range master.ExceptionDates {
+			excluded[t.Unix()] = true
+		}
+		duration := master.End.Sub(master.Start)
+		for _, start := range rule.Occurrences(master.Start, limit, max) {
+			if excluded[start.Unix()] {
+				continue
+			}
+			if override, ok := overrides[master.UID][start.Unix()]; ok {
+				delete(overrides[master.UID], start.Unix())
+				result = append(result, override)
+				continue
+			}
+			occurrence := master
+			occurrence.Start = start
+			occurrence.End = start.Add(duration)
+			occurrence.RecurrenceID = start
+			occurrence.RRule = ""
+			occurrence.ExceptionDates = nil
+			result = append(result, occurrence)
+		}
+	}
+
+	// Instancias modificadas cuya serie no está en el archivo o que quedaron
+	// fuera de las ocurrencias generadas
+	for _, byStart := range overrides {
+		for _, e := range byStart {
+			if !e.Start.After(limit) {
+				result = append(result, e)
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Start.Before(result[j].Start) })
+	return result, errs
+}
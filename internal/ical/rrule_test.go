@@ -0,0 +1,127 @@
+package ical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func formatDates(times []time.Time) []string {
+	result := make([]string, 0, len(times))
+	for _, t := range times {
+		result = append(result, t.Format("2006-01-02 15:04"))
+	}
+	return result
+}
+
+func TestRecurrenceOccurrences(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	limit := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		rule     string
+		start    time.Time
+		expected []string
+	}{
+		{
+			name:     "weekly by day with count",
+			rule:     "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5",
+			start:    time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC), // miércoles
+			expected: []string{"2024-01-03 10:00", "2024-01-08 10:00", "2024-01-10 10:00", "2024-01-15 10:00", "2024-01-17 10:00"},
+		},
+		{
+			name:     "daily interval until date",
+			rule:     "FREQ=DAILY;INTERVAL=2;UNTIL=20240107",
+			start:    time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+			expected: []string{"2024-01-01 09:00", "2024-01-03 09:00", "2024-01-05 09:00", "2024-01-07 09:00"},
+		},
+		{
+			name:     "monthly skips short months",
+			rule:     "FREQ=MONTHLY;COUNT=3",
+			start:    time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+			expected: []string{"2024-01-31 09:00", "2024-03-31 09:00", "2024-05-31 09:00"},
+		},
+		{
+			name:     "monthly last friday",
+			rule:     "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			start:    time.Date(2024, 1, 26, 9, 0, 0, 0, time.UTC),
+			expected: []string{"2024-01-26 09:00", "2024-02-23 09:00", "2024-03-29 09:00"},
+		},
+		{
+			name:     "keeps wall clock across DST",
+			rule:     "FREQ=WEEKLY;COUNT=3",
+			start:    time.Date(2024, 3, 3, 9, 0, 0, 0, ny),
+			expected: []string{"2024-03-03 09:00", "2024-03-10 09:00", "2024-03-17 09:00"},
+		},
+		{
+			name:     "yearly",
+			rule:     "FREQ=YEARLY;COUNT=2",
+			start:    time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC),
+			expected: []string{"2024-06-01 09:00", "2025-06-01 09:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRecurrence(tt.rule, tt.start.Location())
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, formatDates(rule.Occurrences(tt.start, limit, 100)))
+		})
+	}
+}
+
+func TestRecurrenceOccurrences_LimitAndMax(t *testing.T) {
+	rule, err := ParseRecurrence("FREQ=DAILY", time.UTC)
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	assert.Len(t, rule.Occurrences(start, start.AddDate(0, 0, 9), 100), 10)
+	assert.Len(t, rule.Occurrences(start, start.AddDate(1, 0, 0), 5), 5)
+}
+
+func TestParseRecurrence_Invalid(t *testing.T) {
+	for _, rule := range []string{"FREQ=HOURLY", "FREQ=DAILY;INTERVAL=0", "FREQ=WEEKLY;BYDAY=XX", "FREQ=DAILY;BYSETPOS=1"} {
+		_, err := ParseRecurrence(rule, nil)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	events := []Event{
+		{
+			UID:            "series",
+			Start:          start,
+			End:            start.Add(30 * time.Minute),
+			RRule:          "FREQ=DAILY;COUNT=4",
+			ExceptionDates: []time.Time{start.AddDate(0, 0, 1)},
+		},
+		{
+			UID:          "series",
+			RecurrenceID: start.AddDate(0, 0, 2),
+			Start:        start.AddDate(0, 0, 2).Add(2 * time.Hour),
+			End:          start.AddDate(0, 0, 2).Add(3 * time.Hour),
+			Summary:      "moved",
+		},
+		{UID: "single", Start: start.Add(time.Hour), End: start.Add(2 * time.Hour)},
+		{UID: "broken", Start: start, End: start, RRule: "FREQ=SECONDLY"},
+	}
+
+	expanded, errs := Expand(events, start.AddDate(1, 0, 0), 100)
+
+	require.Len(t, errs, 1)
+	var expandErr *ExpandError
+	require.ErrorAs(t, errs[0], &expandErr)
+	assert.Equal(t, "broken", expandErr.UID)
+
+	var got []string
+	for _, e := range expanded {
+		got = append(got, e.UID+" "+e.Start.Format("01-02 15:04"))
+	}
+	assert.Equal(t, []string{"series 01-01 09:00", "single 01-01 10:00", "series 01-03 11:00", "series 01-04 09:00"}, got)
+	assert.Equal(t, start, expanded[0].RecurrenceID)
+	assert.Equal(t, "moved", expanded[2].Summary)
+}
@@ -0,0 +1,96 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type DynamoDBClient interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// DynamoStore guarda las claves en TableName, con TTL sobre expires_at. El
+// TTL de DynamoDB borra con demora, así que Begin también pisa los vencidos.
+type DynamoStore struct {
+	Client    DynamoDBClient
+	TableName string
+}
+
+func NewDynamoStore(client DynamoDBClient, tableName string) *DynamoStore {
+	return &DynamoStore{Client: client, TableName: tableName}
+}
+
+func (d *DynamoStore) Begin(ctx context.Context, r *Record, now time.Time) (*Record, error) {
+	item, err := attributevalue.MarshalMap(r)
+	if err != nil {
+		return nil, err
+	}
+	cond := expression.AttributeNotExists(expression.Name("id")).
+		Or(expression.Name("expires_at").LessThan(expression.Value(now.Unix()))).
+		Or(expression.Name("status").Equal(expression.Value(StatusInProgress)).
+			And(expression.Name("locked_until").LessThan(expression.Value(now.Unix()))))
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return nil, err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                           &d.TableName,
+		Item:                                item,
+		ConditionExpression:                 expr.Condition(),
+		ExpressionAttributeNames:            expr.Names(),
+		ExpressionAttributeValues:           expr.Values(),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		var existing Record
+		if err := attributevalue.UnmarshalMap(conditionFailed.Item, &existing); err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (d *DynamoStore) Complete(ctx context.Context, r *Record) error {
+	item, err := attributevalue.MarshalMap(r)
+	if err != nil {
+		return err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &d.TableName,
+		Item:      item,
+	})
+	return err
+}
+
+func (d *DynamoStore) Release(ctx context.Context, key string) error {
+	itemKey, _ := attributevalue.MarshalMap(map[string]string{"id": key})
+	cond := expression.Name("status").Equal(expression.Value(StatusInProgress))
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return err
+	}
+	_, err = d.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 &d.TableName,
+		Key:                       itemKey,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,23 @@
+package idempotency
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FromEnv arma el Middleware de scope sobre IdempotencyTable. IDEMPOTENCY_TTL
+// (duración de Go, por defecto 24h) es cuánto se recuerda cada clave.
+func FromEnv(client DynamoDBClient, logger *zap.SugaredLogger, scope string) (*Middleware, error) {
+	ttl := defaultTTL
+	if raw := os.Getenv("IDEMPOTENCY_TTL"); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid IDEMPOTENCY_TTL %q", raw)
+		}
+		ttl = value
+	}
+	return New(NewDynamoStore(client, "IdempotencyTable"), logger, scope, ttl), nil
+}
@@ -0,0 +1,220 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/response"
+	"github.com/aws/aws-lambda-go/events"
+	"go.uber.org/zap"
+)
+
+// Claves de idempotencia para los endpoints que crean recursos. Si el
+// cliente reintenta con el mismo Idempotency-Key se devuelve la respuesta
+// original en lugar de crear otro registro; si reusa la clave con otro
+// pedido se rechaza. Las claves son de cada cliente (tenant): dos clínicas
+// que generan la misma clave no ven ni bloquean los pedidos de la otra.
+
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+
+	StatusInProgress = "IN_PROGRESS"
+	StatusCompleted  = "COMPLETED"
+
+	defaultTTL = 24 * time.Hour
+	// lockTimeout es cuánto se espera a un pedido en curso antes de dejar que
+	// otro lo retome; cubre el timeout de API Gateway
+	lockTimeout  = 30 * time.Second
+	maxKeyLength = 255
+)
+
+// Record es lo que queda guardado por clave: la huella del pedido y, cuando
+// terminó, la respuesta.
+type Record struct {
+	Key         string            `dynamodbav:"id"`
+	Fingerprint string            `dynamodbav:"fingerprint"`
+	Status      string            `dynamodbav:"status"`
+	StatusCode  int               `dynamodbav:"status_code,omitempty"`
+	Headers     map[string]string `dynamodbav:"headers,omitempty"`
+	Body        string            `dynamodbav:"body,omitempty"`
+	LockedUntil int64             `dynamodbav:"locked_until,omitempty"`
+	ExpiresAt   int64             `dynamodbav:"expires_at"`
+}
+
+type Store interface {
+	// Begin registra r como IN_PROGRESS si la clave está libre (no existe,
+	// venció o quedó trabada). Si no, devuelve el registro que ya estaba.
+	Begin(ctx context.Context, r *Record, now time.Time) (*Record, error)
+	// Complete guarda la respuesta del pedido.
+	Complete(ctx context.Context, r *Record) error
+	// Release libera la clave para que el cliente pueda reintentar.
+	Release(ctx context.Context, key string) error
+}
+
+type Handler func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Middleware aplica las claves a un endpoint. Scope separa las claves de
+// cada endpoint: la misma clave en dos endpoints son pedidos distintos.
+type Middleware struct {
+	Store  Store
+	Logger *zap.SugaredLogger
+	Scope  string
+	TTL    time.Duration
+	Now    func() time.Time
+}
+
+func New(store Store, logger *zap.SugaredLogger, scope string, ttl time.Duration) *Middleware {
+	return &Middleware{Store: store, Logger: logger, Scope: scope, TTL: ttl, Now: time.Now}
+}
+
+// Wrap envuelve next. Sin Idempotency-Key el pedido pasa directo. Las
+// respuestas 5xx no se guardan: liberan la clave para que el reintento
+// vuelva a ejecutarse.
+func (m *Middleware) Wrap(next Handler) Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		key := header(req, Header)
+		if key == "" {
+			return next(ctx, req)
+		}
+		if len(key) > maxKeyLength {
+			return response.Error(req, 400, "Idempotency-Key is too long"), nil
+		}
+
+		now := m.now()
+		clientID := clientID(req)
+		record := &Record{
+			Key:         recordKey(m.Scope, clientID, key),
+			Fingerprint: fingerprint(clientID, req),
+			Status:      StatusInProgress,
+			LockedUntil: now.Add(lockTimeout).Unix(),
+			ExpiresAt:   now.Add(m.ttl()).Unix(),
+		}
+		existing, err := m.Store.Begin(ctx, record, now)
+		if err != nil {
+			m.log(ctx).Error("Error reserving idempotency key", zap.String("scope", m.Scope), zap.Error(err))
+			return response.Error(req, 500, "could not process request"), nil
+		}
+		if existing != nil {
+			return m.replay(req, existing, record.Fingerprint), nil
+		}
+
+		resp, err := next(ctx, req)
+		if err != nil || resp.StatusCode >= 500 {
+			if releaseErr := m.Store.Release(ctx, record.Key); releaseErr != nil {
+				// La clave se libera sola cuando vence el lock
+				m.log(ctx).Error("Error releasing idempotency key", zap.String("scope", m.Scope), zap.Error(releaseErr))
+			}
+			return resp, err
+		}
+
+		record.Status = StatusCompleted
+		record.StatusCode = resp.StatusCode
+		record.Headers = resp.Headers
+		record.Body = resp.Body
+		record.LockedUntil = 0
+		if err := m.Store.Complete(ctx, record); err != nil {
+			// El recurso ya se creó: se responde igual y un reintento
+			// posterior al lock lo volvería a crear
+			m.log(ctx).Error("Error saving idempotent response", zap.String("scope", m.Scope), zap.Error(err))
+		}
+		return resp, nil
+	}
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del middleware.
+func (m *Middleware) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, m.Logger)
+}
+
+func (m *Middleware) replay(req events.APIGatewayProxyRequest, existing *Record, fingerprint string) events.APIGatewayProxyResponse {
+	if existing.Fingerprint != fingerprint {
+		return response.Error(req, 422, "Idempotency-Key was already used with a different request")
+	}
+	if existing.Status != StatusCompleted {
+		resp := response.Error(req, 409, "a request with this Idempotency-Key is still in progress")
+		resp.Headers["Retry-After"] = "1"
+		return resp
+	}
+	headers := make(map[string]string, len(existing.Headers)+1)
+	for name, value := range existing.Headers {
+		headers[name] = value
+	}
+	headers[ReplayedHeader] = "true"
+	return events.APIGatewayProxyResponse{StatusCode: existing.StatusCode, Headers: headers, Body: existing.Body}
+}
+
+func (m *Middleware) now() time.Time {
+	if m.Now == nil {
+		return time.Now()
+	}
+	return m.Now()
+}
+
+func (m *Middleware) ttl() time.Duration {
+	if m.TTL <= 0 {
+		return defaultTTL
+	}
+	return m.TTL
+}
+
+// recordKey es el ID del registro: endpoint, cliente y clave.
+func recordKey(scope, clientID, key string) string {
+	return scope + "#" + clientID + "#" + key
+}
+
+// fingerprint identifica el pedido: cliente, método, ruta y cuerpo tal cual
+// llegó.
+func fingerprint(clientID string, req events.APIGatewayProxyRequest) string {
+	h := sha256.New()
+	h.Write([]byte(clientID + "\n" + req.HTTPMethod + "\n" + req.Path + "\n"))
+	h.Write([]byte(req.Body))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// clientID es el cliente autenticado: el client_id que el authorizer de API
+// Gateway deja en el contexto (authorizer Lambda) o en los claims del token
+// (Cognito). Sin authorizer se toma el clientId del pedido, en la query o en
+// el cuerpo.
+func clientID(req events.APIGatewayProxyRequest) string {
+	authorizer := req.RequestContext.Authorizer
+	if clientID, ok := authorizer["client_id"].(string); ok && clientID != "" {
+		return clientID
+	}
+	if claims, ok := authorizer["claims"].(map[string]interface{}); ok {
+		if clientID, ok := claims["client_id"].(string); ok && clientID != "" {
+			return clientID
+		}
+	}
+	if clientID := req.QueryStringParameters["clientId"]; clientID != "" {
+		return clientID
+	}
+	var body struct {
+		ClientID string `json:"client_id"`
+	}
+	if req.IsBase64Encoded || json.Unmarshal([]byte(req.Body), &body) != nil {
+		return ""
+	}
+	return body.ClientID
+}
+
+// header busca sin distinguir mayúsculas: API Gateway pasa los headers como
+// los mandó el cliente.
+func header(req events.APIGatewayProxyRequest, name string) string {
+	for key, value := range req.Headers {
+		if strings.EqualFold(key, name) {
+			return strings.TrimSpace(value)
+		}
+	}
+	for key, values := range req.MultiValueHeaders {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return strings.TrimSpace(values[0])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,270 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// memoryStore aplica las mismas condiciones que DynamoStore.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+func (m *memoryStore) Begin(_ context.Context, r *Record, now time.Time) (*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.records[r.Key]; ok && existing.ExpiresAt >= now.Unix() &&
+		(existing.Status != StatusInProgress || existing.LockedUntil >= now.Unix()) {
+		return &existing, nil
+	}
+	m.records[r.Key] = *r
+	return nil, nil
+}
+
+func (m *memoryStore) Complete(_ context.Context, r *Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[r.Key] = *r
+	return nil
+}
+
+func (m *memoryStore) Release(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, key)
+	return nil
+}
+
+var testNow = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func setupMiddleware() (*Middleware, *memoryStore, *int) {
+	store := &memoryStore{records: map[string]Record{}}
+	m := New(store, zap.NewNop().Sugar(), "appointments/create", time.Hour)
+	m.Now = func() time.Time { return testNow }
+	calls := 0
+	return m, store, &calls
+}
+
+func created(calls *int) Handler {
+	return func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		*calls++
+		return events.APIGatewayProxyResponse{
+			StatusCode: 201,
+			Body:       `{"id":"new-` + strconv.Itoa(*calls) + `"}`,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+		}, nil
+	}
+}
+
+func request(key, body string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		HTTPMethod: "POST",
+		Path:       "/appointments",
+		Headers:    map[string]string{"idempotency-key": key},
+		Body:       body,
+	}
+}
+
+func TestWrap_ReplaysOriginalResponse(t *testing.T) {
+	m, _, calls := setupMiddleware()
+	handler := m.Wrap(created(calls))
+	ctx := context.Background()
+
+	first, err := handler(ctx, request("key-1", `{"patient_id":"p1"}`))
+	require.NoError(t, err)
+	retry, err := handler(ctx, request("key-1", `{"patient_id":"p1"}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, 201, retry.StatusCode)
+	assert.Equal(t, first.Body, retry.Body)
+	assert.Equal(t, "true", retry.Headers[ReplayedHeader])
+	assert.Equal(t, "application/json", retry.Headers["Content-Type"])
+	assert.Empty(t, first.Headers[ReplayedHeader])
+
+	// Otra clave es otro pedido
+	_, err = handler(ctx, request("key-2", `{"patient_id":"p1"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestWrap_RejectsDifferentBody(t *testing.T) {
+	m, _, calls := setupMiddleware()
+	handler := m.Wrap(created(calls))
+	ctx := context.Background()
+
+	_, err := handler(ctx, request("key-1", `{"patient_id":"p1"}`))
+	require.NoError(t, err)
+	resp, err := handler(ctx, request("key-1", `{"patient_id":"p2"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, 422, resp.StatusCode)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestWrap_SameKeyOtherTenant(t *testing.T) {
+	m, store, calls := setupMiddleware()
+	handler := m.Wrap(created(calls))
+	ctx := context.Background()
+	tenant := func(clientID string) events.APIGatewayProxyRequest {
+		req := request("key-1", `{"patient_id":"p1"}`)
+		req.RequestContext.Authorizer = map[string]interface{}{"client_id": clientID}
+		return req
+	}
+
+	first, err := handler(ctx, tenant("c1"))
+	require.NoError(t, err)
+	other, err := handler(ctx, tenant("c2"))
+	require.NoError(t, err)
+
+	// La misma clave y el mismo cuerpo de otro cliente no reciben la
+	// respuesta del primero
+	assert.Equal(t, 2, *calls)
+	assert.Empty(t, other.Headers[ReplayedHeader])
+	assert.NotEqual(t, first.Body, other.Body)
+	assert.Contains(t, store.records, "appointments/create#c1#key-1")
+	assert.Contains(t, store.records, "appointments/create#c2#key-1")
+
+	retry, err := handler(ctx, tenant("c1"))
+	require.NoError(t, err)
+	assert.Equal(t, first.Body, retry.Body)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestClientID(t *testing.T) {
+	req := request("key-1", `{"client_id":"from-body"}`)
+	assert.Equal(t, "from-body", clientID(req))
+
+	req.QueryStringParameters = map[string]string{"clientId": "from-query"}
+	assert.Equal(t, "from-query", clientID(req))
+
+	req.RequestContext.Authorizer = map[string]interface{}{"claims": map[string]interface{}{"client_id": "from-claims"}}
+	assert.Equal(t, "from-claims", clientID(req))
+
+	req.RequestContext.Authorizer = map[string]interface{}{"client_id": "from-authorizer"}
+	assert.Equal(t, "from-authorizer", clientID(req))
+}
+
+func TestWrap_InProgress(t *testing.T) {
+	m, store, calls := setupMiddleware()
+	handler := m.Wrap(created(calls))
+	req := request("key-1", `{}`)
+	store.records["appointments/create##key-1"] = Record{
+		Key:         "appointments/create##key-1",
+		Fingerprint: fingerprint("", req),
+		Status:      StatusInProgress,
+		LockedUntil: testNow.Add(10 * time.Second).Unix(),
+		ExpiresAt:   testNow.Add(time.Hour).Unix(),
+	}
+
+	resp, err := handler(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 409, resp.StatusCode)
+	assert.Equal(t, 0, *calls)
+
+	// Si el pedido en curso quedó trabado, vencido el lock se retoma
+	m.Now = func() time.Time { return testNow.Add(time.Minute) }
+	resp, err = handler(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 201, resp.StatusCode)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestWrap_ServerErrorReleasesKey(t *testing.T) {
+	m, store, _ := setupMiddleware()
+	calls := 0
+	handler := m.Wrap(func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		calls++
+		if calls == 1 {
+			return events.APIGatewayProxyResponse{StatusCode: 500, Body: `{"error":"boom"}`}, nil
+		}
+		return events.APIGatewayProxyResponse{StatusCode: 201, Body: `{"id":"1"}`}, nil
+	})
+	ctx := context.Background()
+
+	resp, err := handler(ctx, request("key-1", `{}`))
+	require.NoError(t, err)
+	assert.Equal(t, 500, resp.StatusCode)
+	assert.Empty(t, store.records)
+
+	resp, err = handler(ctx, request("key-1", `{}`))
+	require.NoError(t, err)
+	assert.Equal(t, 201, resp.StatusCode)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWrap_ExpiredKey(t *testing.T) {
+	m, _, calls := setupMiddleware()
+	handler := m.Wrap(created(calls))
+	ctx := context.Background()
+
+	_, err := handler(ctx, request("key-1", `{}`))
+	require.NoError(t, err)
+	m.Now = func() time.Time { return testNow.Add(2 * time.Hour) }
+	_, err = handler(ctx, request("key-1", `{}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, *calls)
+}
+
+func TestWrap_WithoutKey(t *testing.T) {
+	m, store, calls := setupMiddleware()
+	handler := m.Wrap(created(calls))
+	req := request("", `{}`)
+	req.Headers = nil
+
+	_, _ = handler(context.Background(), req)
+	_, _ = handler(context.Background(), req)
+
+	assert.Equal(t, 2, *calls)
+	assert.Empty(t, store.records)
+}
+
+type fakeDynamo struct {
+	put    *dynamodb.PutItemInput
+	putErr error
+}
+
+func (f *fakeDynamo) PutItem(_ context.Context, params *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.put = params
+	return &dynamodb.PutItemOutput{}, f.putErr
+}
+
+func (f *fakeDynamo) DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestDynamoStore_Begin(t *testing.T) {
+	client := &fakeDynamo{}
+	store := NewDynamoStore(client, "IdempotencyTable")
+	record := &Record{Key: "scope#key", Fingerprint: "abc", Status: StatusInProgress, ExpiresAt: testNow.Add(time.Hour).Unix()}
+
+	existing, err := store.Begin(context.Background(), record, testNow)
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+	assert.NotNil(t, client.put.ConditionExpression)
+
+	item, _ := attributevalue.MarshalMap(Record{Key: "scope#key", Fingerprint: "abc", Status: StatusCompleted, StatusCode: 201, Body: "{}"})
+	client.putErr = &types.ConditionalCheckFailedException{Item: item}
+	existing, err = store.Begin(context.Background(), record, testNow)
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	assert.Equal(t, 201, existing.StatusCode)
+
+	client.putErr = errors.New("dynamo down")
+	_, err = store.Begin(context.Background(), record, testNow)
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Ejemplos del apéndice A de RFC 7396
+	cases := []struct{ doc, patch, expected string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, c := range cases {
+		result, err := MergePatch([]byte(c.doc), []byte(c.patch))
+		require.NoError(t, err)
+		assert.JSONEq(t, c.expected, string(result), "patch %s", c.patch)
+	}
+
+	_, err := MergePatch([]byte(`{}`), []byte(`{`))
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestJSONPatch(t *testing.T) {
+	// Ejemplos del apéndice A de RFC 6902
+	cases := []struct{ doc, patch, expected string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{`{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`, `{"foo":{"bar":1},"baz":{"bar":2}}`},
+	}
+	for _, c := range cases {
+		result, err := JSONPatch([]byte(c.doc), []byte(c.patch))
+		require.NoError(t, err, "patch %s", c.patch)
+		assert.JSONEq(t, c.expected, string(result), "patch %s", c.patch)
+	}
+}
+
+func TestJSONPatch_Errors(t *testing.T) {
+	cases := []struct{ doc, patch string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+		{`{"foo":"bar"}`, `[{"op":"remove","path":"/missing"}]`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/5","value":"x"}]`},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/foo"}]`},
+		{`{"foo":"bar"}`, `[{"op":"explode","path":"/foo"}]`},
+		{`{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`},
+		{`{"foo":"bar"}`, `{"op":"add"}`},
+	}
+	for _, c := range cases {
+		_, err := JSONPatch([]byte(c.doc), []byte(c.patch))
+		assert.ErrorIs(t, err, ErrInvalid, "patch %s", c.patch)
+	}
+
+	_, err := JSONPatch([]byte(`{"baz":"qux"}`), []byte(`[{"op":"test","path":"/baz","value":"bar"}]`))
+	assert.ErrorIs(t, err, ErrTestFailed)
+}
+
+func TestApply(t *testing.T) {
+	doc := []byte(`{"a":"b","c":"d"}`)
+
+	result, err := Apply("application/merge-patch+json; charset=utf-8", doc, []byte(`{"a":null}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"c":"d"}`, string(result))
+
+	result, err = Apply(JSONPatchType, doc, []byte(`[{"op":"remove","path":"/c"}]`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":"b"}`, string(result))
+
+	_, err = Apply("text/plain", doc, []byte(`{}`))
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestDecode(t *testing.T) {
+	type resource struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	doc := []byte(`{"id":"1","name":"a"}`)
+
+	var r resource
+	require.NoError(t, Decode(doc, []byte(`{"id":"1","name":"b"}`), &r, "id"))
+	assert.Equal(t, resource{ID: "1", Name: "b"}, r)
+
+	assert.ErrorIs(t, Decode(doc, []byte(`{"id":"2","name":"a"}`), &r, "id"), ErrReadOnly)
+	assert.ErrorIs(t, Decode(doc, []byte(`{"name":"a"}`), &r, "id"), ErrReadOnly)
+	assert.ErrorIs(t, Decode(doc, []byte(`{"id":"1","nmae":"b"}`), &r, "id"), ErrInvalid)
+	assert.ErrorIs(t, Decode(doc, []byte(`{"id":"1","name":3}`), &r, "id"), ErrInvalid)
+}
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/MezeLaw/iris-services/internal/response"
+	"github.com/aws/aws-lambda-go/events"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// El logger de cada pedido viaja en el contexto con el ID del pedido, el
+// cliente (tenant), la operación y la ruta, para poder filtrar en CloudWatch
+// todas las líneas de un pedido o de un cliente.
+
+type contextKey struct{}
+
+// WithContext guarda logger en ctx.
+func WithContext(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext devuelve el logger del pedido en ctx o fallback si no hay.
+func FromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if logger, ok := ctx.Value(contextKey{}).(*zap.SugaredLogger); ok {
+		return logger
+	}
+	return fallback
+}
+
+type Handler = func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Wrap pone en el contexto de cada pedido un logger derivado de logger con
+// los datos del pedido. Si API Gateway no asignó un ID, el que se genera se
+// deja en el header X-Request-Id para que la respuesta use el mismo.
+func Wrap(logger *zap.SugaredLogger, operation string, next Handler) Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		requestID := response.RequestID(req)
+		if req.RequestContext.RequestID == "" {
+			headers := make(map[string]string, len(req.Headers)+1)
+			for name, value := range req.Headers {
+				headers[name] = value
+			}
+			headers[response.RequestIDHeader] = requestID
+			req.Headers = headers
+		}
+
+		fields := []interface{}{"request_id", requestID, "operation", operation}
+		if req.Resource != "" {
+			fields = append(fields, "route", req.Resource)
+		}
+		if tenant := tenant(req); tenant != "" {
+			fields = append(fields, "tenant", tenant)
+		}
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			fields = append(fields, "trace_id", span.TraceID().String())
+		}
+		return next(WithContext(ctx, logger.With(fields...)), req)
+	}
+}
+
+// tenant es el clientId del pedido: en la query para las lecturas o en el
+// cuerpo para las altas y modificaciones.
+func tenant(req events.APIGatewayProxyRequest) string {
+	if clientID := req.QueryStringParameters["clientId"]; clientID != "" {
+		return clientID
+	}
+	if req.Body == "" || req.IsBase64Encoded {
+		return ""
+	}
+	var body struct {
+		ClientID string `json:"client_id"`
+	}
+	if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
+		return ""
+	}
+	return body.ClientID
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/response"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// run pasa req por Wrap y devuelve lo que el handler registró con el logger
+// del contexto y el pedido que recibió.
+func run(t *testing.T, req events.APIGatewayProxyRequest) (map[string]interface{}, events.APIGatewayProxyRequest) {
+	core, logs := observer.New(zap.InfoLevel)
+	fallback := zap.NewNop().Sugar()
+
+	var received events.APIGatewayProxyRequest
+	handler := Wrap(zap.New(core).Sugar(), "createPatient", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		received = req
+		FromContext(ctx, fallback).Info("handled")
+		return events.APIGatewayProxyResponse{StatusCode: 201}, nil
+	})
+	_, err := handler(context.Background(), req)
+	require.NoError(t, err)
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	return entries[0].ContextMap(), received
+}
+
+func TestWrap_Fields(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		Resource: "/patients",
+		Body:     `{"client_id":"c1","name":"Ana"}`,
+	}
+	req.RequestContext.RequestID = "req-1"
+
+	fields, _ := run(t, req)
+	assert.Equal(t, map[string]interface{}{
+		"request_id": "req-1",
+		"operation":  "createPatient",
+		"route":      "/patients",
+		"tenant":     "c1",
+	}, fields)
+}
+
+func TestWrap_TenantFromQuery(t *testing.T) {
+	req := events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"clientId": "c2"}}
+	req.RequestContext.RequestID = "req-2"
+
+	fields, _ := run(t, req)
+	assert.Equal(t, "c2", fields["tenant"])
+}
+
+func TestWrap_GeneratedRequestID(t *testing.T) {
+	req := events.APIGatewayProxyRequest{Headers: map[string]string{"Accept": "application/json"}}
+
+	fields, received := run(t, req)
+	requestID, ok := fields["request_id"].(string)
+	require.True(t, ok)
+	assert.NotEmpty(t, requestID)
+	assert.Equal(t, requestID, response.RequestID(received))
+	assert.NotContains(t, req.Headers, response.RequestIDHeader)
+}
+
+func TestFromContext(t *testing.T) {
+	fallback := zap.NewNop().Sugar()
+	assert.Same(t, fallback, FromContext(context.Background(), fallback))
+
+	logger := zap.NewExample().Sugar()
+	assert.Same(t, logger, FromContext(WithContext(context.Background(), logger), fallback))
+}
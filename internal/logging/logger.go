@@ -1,14 +1,67 @@
 package logging
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// NewLogger arma el logger de los Lambdas según el entorno:
+//
+//	LOG_LEVEL     debug, info (por defecto), warn o error.
+//	LOG_FORMAT    json (por defecto) o console: texto con colores para
+//	              correr en local.
+//	LOG_SAMPLING  "inicial,después": por cada mensaje y segundo se escriben
+//	              los primeros inicial y luego uno de cada después. Por
+//	              defecto 100,100; "off" lo desactiva.
 func NewLogger() (*zap.SugaredLogger, error) {
-	logger, err := zap.NewProduction() // Para prod
-	// logger, err := zap.NewDevelopment() // Para local con colores
+	cfg, err := config(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := cfg.Build()
 	if err != nil {
 		return nil, err
 	}
 	return logger.Sugar(), nil
 }
+
+func config(getenv func(string) string) (zap.Config, error) {
+	var cfg zap.Config
+	switch format := getenv("LOG_FORMAT"); format {
+	case "", "json":
+		cfg = zap.NewProductionConfig()
+	case "console":
+		cfg = zap.NewDevelopmentConfig()
+		cfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	default:
+		return cfg, fmt.Errorf("invalid LOG_FORMAT %q", format)
+	}
+
+	if level := getenv("LOG_LEVEL"); level != "" {
+		parsed, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid LOG_LEVEL %q", level)
+		}
+		cfg.Level = zap.NewAtomicLevelAt(parsed)
+	}
+
+	switch sampling := getenv("LOG_SAMPLING"); sampling {
+	case "":
+	case "off":
+		cfg.Sampling = nil
+	default:
+		initial, thereafter, ok := strings.Cut(sampling, ",")
+		first, err1 := strconv.Atoi(strings.TrimSpace(initial))
+		then, err2 := strconv.Atoi(strings.TrimSpace(thereafter))
+		if !ok || err1 != nil || err2 != nil || first < 0 || then < 0 {
+			return cfg, fmt.Errorf("invalid LOG_SAMPLING %q", sampling)
+		}
+		cfg.Sampling = &zap.SamplingConfig{Initial: first, Thereafter: then}
+	}
+	return cfg, nil
+}
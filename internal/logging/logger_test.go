@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestConfig_Defaults(t *testing.T) {
+	cfg, err := config(env(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, "json", cfg.Encoding)
+	assert.Equal(t, zapcore.InfoLevel, cfg.Level.Level())
+	require.NotNil(t, cfg.Sampling)
+	assert.Equal(t, 100, cfg.Sampling.Initial)
+}
+
+func TestConfig_Env(t *testing.T) {
+	cfg, err := config(env(map[string]string{
+		"LOG_FORMAT":   "console",
+		"LOG_LEVEL":    "debug",
+		"LOG_SAMPLING": "10, 50",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "console", cfg.Encoding)
+	assert.Equal(t, zapcore.DebugLevel, cfg.Level.Level())
+	assert.Equal(t, &zap.SamplingConfig{Initial: 10, Thereafter: 50}, cfg.Sampling)
+}
+
+func TestConfig_SamplingOff(t *testing.T) {
+	cfg, err := config(env(map[string]string{"LOG_SAMPLING": "off"}))
+	require.NoError(t, err)
+	assert.Nil(t, cfg.Sampling)
+}
+
+func TestConfig_Invalid(t *testing.T) {
+	for _, values := range []map[string]string{
+		{"LOG_FORMAT": "xml"},
+		{"LOG_LEVEL": "verbose"},
+		{"LOG_SAMPLING": "100"},
+		{"LOG_SAMPLING": "a,b"},
+		{"LOG_SAMPLING": "-1,10"},
+	} {
+		_, err := config(env(values))
+		assert.Error(t, err, values)
+	}
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultNamespace = "Iris"
+
+// EMF escribe cada métrica como una línea en CloudWatch Embedded Metric
+// Format: https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+type EMF struct {
+	Namespace string
+	Writer    io.Writer
+	Now       func() time.Time
+	mu        sync.Mutex
+}
+
+func NewEMF(namespace string, w io.Writer) *EMF {
+	return &EMF{Namespace: namespace, Writer: w, Now: time.Now}
+}
+
+// FromEnv arma las métricas de los Lambdas: EMF por stdout con el namespace
+// de METRICS_NAMESPACE (Iris si no está). METRICS_DISABLED=true las apaga,
+// por ejemplo al correr los Lambdas en local.
+func FromEnv() Metrics {
+	if os.Getenv("METRICS_DISABLED") == "true" {
+		return Nop{}
+	}
+	namespace := os.Getenv("METRICS_NAMESPACE")
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	return NewEMF(namespace, os.Stdout)
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit Unit   `json:"Unit"`
+}
+
+func (e *EMF) Put(name string, value float64, unit Unit, dimensions Dimensions) {
+	keys := make([]string, 0, len(dimensions))
+	for key := range dimensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	line := make(map[string]interface{}, len(dimensions)+2)
+	for key, v := range dimensions {
+		line[key] = v
+	}
+	line[name] = value
+	line["_aws"] = emfMetadata{
+		Timestamp: e.now().UnixMilli(),
+		CloudWatchMetrics: []emfDirective{{
+			Namespace:  e.Namespace,
+			Dimensions: [][]string{keys},
+			Metrics:    []emfMetric{{Name: name, Unit: unit}},
+		}},
+	}
+	body, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+
+	// Una línea por métrica: las escrituras concurrentes no se mezclan
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, _ = e.Writer.Write(append(body, '\n'))
+}
+
+func (e *EMF) now() time.Time {
+	if e.Now == nil {
+		return time.Now()
+	}
+	return e.Now()
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Métricas de la API: latencia y errores por operación, contadores de
+// dominio y capacidad consumida de DynamoDB. Se publican con Metrics; en
+// Lambda la implementación es EMF, que las escribe por stdout y CloudWatch
+// las extrae de los logs sin llamadas de red.
+
+type Unit string
+
+const (
+	Milliseconds Unit = "Milliseconds"
+	Count        Unit = "Count"
+	None         Unit = "None"
+)
+
+// Nombres de las métricas y dimensiones comunes.
+const (
+	Latency          = "Latency"
+	Errors           = "Errors"
+	ConsumedCapacity = "ConsumedCapacity"
+
+	DimOperation  = "Operation"
+	DimErrorClass = "ErrorClass"
+	DimTable      = "TableName"
+	DimClientID   = "ClientId"
+)
+
+type Dimensions map[string]string
+
+type Metrics interface {
+	Put(name string, value float64, unit Unit, dimensions Dimensions)
+}
+
+// Nop descarta las métricas.
+type Nop struct{}
+
+func (Nop) Put(string, float64, Unit, Dimensions) {}
+
+type Handler = func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Wrap mide el Lambda de operation: la latencia de cada pedido y, si falla,
+// un error con la clase según el status de la respuesta.
+func Wrap(m Metrics, operation string, next Handler) Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		start := time.Now()
+		resp, err := next(ctx, req)
+		m.Put(Latency, float64(time.Since(start).Microseconds())/1000, Milliseconds, Dimensions{DimOperation: operation})
+
+		status := resp.StatusCode
+		if err != nil {
+			status = 500
+		}
+		if class := ErrorClass(status); class != "" {
+			m.Put(Errors, 1, Count, Dimensions{DimOperation: operation, DimErrorClass: class})
+		}
+		return resp, err
+	}
+}
+
+// ErrorClass agrupa los status de error para que la dimensión tenga pocos
+// valores. Devuelve "" para las respuestas exitosas.
+func ErrorClass(status int) string {
+	switch {
+	case status < 400:
+		return ""
+	case status == 400 || status == 415 || status == 422:
+		return "invalid"
+	case status == 401 || status == 403:
+		return "unauthorized"
+	case status == 404 || status == 410:
+		return "not_found"
+	case status == 409 || status == 412:
+		return "conflict"
+	case status == 429:
+		return "throttled"
+	case status < 500:
+		return "client"
+	default:
+		return "internal"
+	}
+}
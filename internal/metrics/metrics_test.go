@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorded struct {
+	name       string
+	value      float64
+	unit       Unit
+	dimensions Dimensions
+}
+
+type fakeMetrics struct {
+	puts []recorded
+}
+
+func (f *fakeMetrics) Put(name string, value float64, unit Unit, dimensions Dimensions) {
+	f.puts = append(f.puts, recorded{name, value, unit, dimensions})
+}
+
+func TestEMF_Put(t *testing.T) {
+	var out bytes.Buffer
+	emf := NewEMF("Iris", &out)
+	emf.Now = func() time.Time { return time.UnixMilli(1700000000000) }
+
+	emf.Put(Errors, 1, Count, Dimensions{DimOperation: "createPatient", DimErrorClass: "invalid"})
+	emf.Put(Latency, 12.5, Milliseconds, Dimensions{DimOperation: "createPatient"})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{
+		"_aws": {
+			"Timestamp": 1700000000000,
+			"CloudWatchMetrics": [{
+				"Namespace": "Iris",
+				"Dimensions": [["ErrorClass", "Operation"]],
+				"Metrics": [{"Name": "Errors", "Unit": "Count"}]
+			}]
+		},
+		"Operation": "createPatient",
+		"ErrorClass": "invalid",
+		"Errors": 1
+	}`, lines[0])
+
+	var latency map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &latency))
+	assert.Equal(t, 12.5, latency[Latency])
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+		class  string
+	}{
+		{"OK", 201, nil, ""},
+		{"NotFound", 404, nil, "not_found"},
+		{"Conflict", 409, nil, "conflict"},
+		{"Internal", 503, nil, "internal"},
+		{"LambdaError", 0, errors.New("boom"), "internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &fakeMetrics{}
+			h := Wrap(m, "createPatient", func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				return events.APIGatewayProxyResponse{StatusCode: tt.status}, tt.err
+			})
+
+			resp, err := h(context.Background(), events.APIGatewayProxyRequest{})
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+
+			require.NotEmpty(t, m.puts)
+			assert.Equal(t, Latency, m.puts[0].name)
+			assert.Equal(t, Dimensions{DimOperation: "createPatient"}, m.puts[0].dimensions)
+			if tt.class == "" {
+				assert.Len(t, m.puts, 1)
+				return
+			}
+			require.Len(t, m.puts, 2)
+			assert.Equal(t, recorded{Errors, 1, Count, Dimensions{DimOperation: "createPatient", DimErrorClass: tt.class}}, m.puts[1])
+		})
+	}
+}
@@ -4,19 +4,21 @@ type AppointmentStatus string
 
 const (
 	AppointmentStatusScheduled  AppointmentStatus = "SCHEDULED"
+	AppointmentStatusConfirmed  AppointmentStatus = "CONFIRMED"
 	AppointmentStatusInProgress AppointmentStatus = "IN_PROGRESS"
 	AppointmentStatusCompleted  AppointmentStatus = "COMPLETED"
 	AppointmentStatusCancelled  AppointmentStatus = "CANCELLED"
+	AppointmentStatusNoShow     AppointmentStatus = "NO_SHOW"
 )
 
 type AppointmentRequest struct {
 	ID        string                 `json:"id,omitempty"`
-	ClientID  string                 `json:"client_id"`
-	PatientID string                 `json:"patient_id"`
-	DoctorID  string                 `json:"doctor_id"`
-	Date      string                 `json:"date"`     // Format: RFC3339
-	Duration  int                    `json:"duration"` // Duration in minutes
-	Status    AppointmentStatus      `json:"status"`
+	ClientID  string                 `json:"client_id" required:"true"`
+	PatientID string                 `json:"patient_id" required:"true"`
+	DoctorID  string                 `json:"doctor_id" required:"true"`
+	Date      string                 `json:"date" required:"true" format:"date-time"` // Format: RFC3339
+	Duration  int                    `json:"duration" required:"true"`                // Duration in minutes
+	Status    AppointmentStatus      `json:"status" required:"true" enum:"SCHEDULED,CONFIRMED,IN_PROGRESS,COMPLETED,CANCELLED,NO_SHOW"`
 	Notes     string                 `json:"notes,omitempty"`
 	CreatedAt string                 `json:"created_at,omitempty"`
 	UpdatedAt string                 `json:"updated_at,omitempty"`
@@ -24,17 +26,22 @@ type AppointmentRequest struct {
 }
 
 type Appointment struct {
-	ID        string                 `dynamodbav:"id"`
-	ClientID  string                 `dynamodbav:"client_id"`
-	PatientID string                 `dynamodbav:"patient_id"`
-	DoctorID  string                 `dynamodbav:"doctor_id"`
-	Date      string                 `dynamodbav:"date"`
-	Duration  int                    `dynamodbav:"duration"`
-	Status    AppointmentStatus      `dynamodbav:"status"`
-	Notes     string                 `dynamodbav:"notes,omitempty"`
-	CreatedAt string                 `dynamodbav:"created_at"`
-	UpdatedAt string                 `dynamodbav:"updated_at"`
-	Metadata  map[string]interface{} `dynamodbav:"metadata,omitempty"`
+	ID        string            `dynamodbav:"id"`
+	ClientID  string            `dynamodbav:"client_id"`
+	PatientID string            `dynamodbav:"patient_id"`
+	DoctorID  string            `dynamodbav:"doctor_id"`
+	Date      string            `dynamodbav:"date"`
+	Duration  int               `dynamodbav:"duration"`
+	Status    AppointmentStatus `dynamodbav:"status"`
+	Notes     string            `dynamodbav:"notes,omitempty"`
+	CreatedAt string            `dynamodbav:"created_at"`
+	UpdatedAt string            `dynamodbav:"updated_at"`
+	Sequence  int               `dynamodbav:"sequence"` // Se incrementa en cada modificación (iCalendar SEQUENCE)
+	// Ventanas de recordatorio ya enviadas ("48h", "2h"); se vacía al reprogramar
+	RemindersSent []string `dynamodbav:"reminders_sent,stringset,omitempty"`
+	// Nonces de los enlaces de confirmación/cancelación ya usados
+	ActionTokensUsed []string               `dynamodbav:"action_tokens_used,stringset,omitempty"`
+	Metadata         map[string]interface{} `dynamodbav:"metadata,omitempty"`
 }
 
 type GetAppointmentRequest struct {
@@ -43,3 +50,18 @@ type GetAppointmentRequest struct {
 	PatientID string `json:"patient_id,omitempty"`
 	DoctorID  string `json:"doctor_id,omitempty"`
 }
+
+// AppointmentAction describe la acción de un enlace firmado sobre un turno.
+type AppointmentAction struct {
+	Action        string            `json:"action"`
+	AppointmentID string            `json:"appointment_id"`
+	Date          string            `json:"date"`
+	Status        AppointmentStatus `json:"status"`
+}
+
+// NoShowReport resume una corrida de la marca automática de ausencias.
+type NoShowReport struct {
+	Clients int `json:"clients"`
+	Marked  int `json:"marked"`
+	Failed  int `json:"failed"`
+}
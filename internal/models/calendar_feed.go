@@ -0,0 +1,33 @@
+package models
+
+const (
+	CalendarOwnerDoctor  = "DOCTOR"
+	CalendarOwnerPatient = "PATIENT"
+)
+
+type CalendarFeedRequest struct {
+	ID        string `json:"id,omitempty"`
+	ClientID  string `json:"client_id"`
+	OwnerType string `json:"owner_type"` // DOCTOR o PATIENT
+	OwnerID   string `json:"owner_id"`
+	Name      string `json:"name,omitempty"`
+	TimeZone  string `json:"time_zone,omitempty"` // Nombre IANA, p. ej. America/Argentina/Buenos_Aires
+	URL       string `json:"url,omitempty"`       // Sólo se devuelve al crear el feed
+	CreatedAt string `json:"created_at,omitempty"`
+	RevokedAt string `json:"revoked_at,omitempty"`
+}
+
+// CalendarFeed es una suscripción .ics. Sólo se guarda el hash del secreto:
+// la URL completa se muestra una única vez al crearla.
+type CalendarFeed struct {
+	ID         string `dynamodbav:"id"`
+	ClientID   string `dynamodbav:"client_id"`
+	OwnerType  string `dynamodbav:"owner_type"`
+	OwnerID    string `dynamodbav:"owner_id"`
+	OwnerKey   string `dynamodbav:"owner_key"` // OwnerType#OwnerID
+	Name       string `dynamodbav:"name,omitempty"`
+	TimeZone   string `dynamodbav:"time_zone,omitempty"`
+	SecretHash string `dynamodbav:"secret_hash"`
+	CreatedAt  string `dynamodbav:"created_at"`
+	RevokedAt  string `dynamodbav:"revoked_at,omitempty"`
+}
@@ -0,0 +1,48 @@
+package models
+
+// Reglas para identificar al paciente de cada evento importado.
+const (
+	CalendarImportMatchAttendeeEmail   = "ATTENDEE_EMAIL"   // email de un ATTENDEE == email del paciente
+	CalendarImportMatchSummaryName     = "SUMMARY_NAME"     // el SUMMARY contiene nombre y apellido
+	CalendarImportMatchSummaryDocument = "SUMMARY_DOCUMENT" // el SUMMARY contiene el número de documento
+)
+
+// Resultado de cada ocurrencia importada.
+const (
+	CalendarImportItemImported    = "IMPORTED"
+	CalendarImportItemWouldImport = "WOULD_IMPORT" // dry run
+	CalendarImportItemSkipped     = "SKIPPED"
+	CalendarImportItemFailed      = "FAILED"
+)
+
+type CalendarImportRequest struct {
+	ClientID string `json:"client_id"`
+	DoctorID string `json:"doctor_id"`
+	Calendar string `json:"calendar"`            // Contenido del .ics
+	TimeZone string `json:"time_zone,omitempty"` // Para horas flotantes y TZID desconocidos; default UTC
+	// MatchRule es una de las constantes CalendarImportMatch*. MatchPattern es
+	// una expresión regular opcional cuyo primer grupo extrae del SUMMARY el
+	// texto a comparar (por ejemplo `^Turno - (.+)$`).
+	MatchRule    string `json:"match_rule"`
+	MatchPattern string `json:"match_pattern,omitempty"`
+	Until        string `json:"until,omitempty"` // Límite para expandir recurrencias (RFC3339); default un año
+	DryRun       bool   `json:"dry_run"`
+}
+
+type CalendarImportItem struct {
+	UID         string              `json:"uid"`
+	Start       string              `json:"start,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Result      string              `json:"result"`
+	Reason      string              `json:"reason,omitempty"`
+	Appointment *AppointmentRequest `json:"appointment,omitempty"`
+}
+
+type CalendarImportReport struct {
+	DryRun   bool                  `json:"dry_run"`
+	Total    int                   `json:"total"`
+	Imported int                   `json:"imported"` // En dry run, los que se importarían
+	Skipped  int                   `json:"skipped"`
+	Failed   int                   `json:"failed"`
+	Items    []*CalendarImportItem `json:"items"`
+}
@@ -0,0 +1,45 @@
+package models
+
+const (
+	ExportResourcePatients     = "patients"
+	ExportResourceAppointments = "appointments"
+
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+
+	ExportStatusPending   = "PENDING"
+	ExportStatusRunning   = "RUNNING"
+	ExportStatusCompleted = "COMPLETED"
+	ExportStatusFailed    = "FAILED"
+)
+
+type ExportRequest struct {
+	ID                string `json:"id,omitempty"`
+	ClientID          string `json:"client_id"`
+	Resource          string `json:"resource"` // patients o appointments
+	Format            string `json:"format"`   // csv o ndjson
+	Status            string `json:"status,omitempty"`
+	Rows              int    `json:"rows,omitempty"`
+	Error             string `json:"error,omitempty"`
+	DownloadURL       string `json:"download_url,omitempty"`        // Sólo cuando está COMPLETED
+	DownloadExpiresAt string `json:"download_expires_at,omitempty"` // Vencimiento de DownloadURL
+	CreatedAt         string `json:"created_at,omitempty"`
+	CompletedAt       string `json:"completed_at,omitempty"`
+}
+
+// ExportJob es una exportación en curso o terminada; el archivo queda en el
+// blob store bajo Key.
+type ExportJob struct {
+	ID          string `dynamodbav:"id"`
+	ClientID    string `dynamodbav:"client_id"`
+	Resource    string `dynamodbav:"resource"`
+	Format      string `dynamodbav:"format"`
+	Status      string `dynamodbav:"status"`
+	Key         string `dynamodbav:"key,omitempty"`
+	Rows        int    `dynamodbav:"rows"`
+	Error       string `dynamodbav:"error,omitempty"`
+	CreatedAt   string `dynamodbav:"created_at"`
+	CompletedAt string `dynamodbav:"completed_at,omitempty"`
+	LeaseUntil  int64  `dynamodbav:"lease_until,omitempty"` // Epoch en segundos hasta el que la tiene tomada un worker
+	Attempts    int    `dynamodbav:"attempts,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "github.com/MezeLaw/iris-services/internal/events"
+
+// OutboxEvent es un evento pendiente del outbox. Delivered son los destinos
+// que ya lo recibieron en una corrida anterior y no lo vuelven a recibir.
+type OutboxEvent struct {
+	Event     *events.Event
+	Delivered []string
+}
+
+// OutboxReport resume una corrida del relay del outbox.
+type OutboxReport struct {
+	Pending   int `json:"pending"`
+	Published int `json:"published"`
+	Failed    int `json:"failed"`
+	// Dead son los que agotaron los intentos y pasaron al dead letter
+	Dead int `json:"dead"`
+	// More indica que quedaron pendientes más allá del lote
+	More bool `json:"more"`
+}
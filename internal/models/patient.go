@@ -14,9 +14,11 @@ type PatientRequest struct {
 	DocType        string                 `json:"doc_type" required:"true"`
 	DocNumber      string                 `json:"doc_number" required:"true"`
 	BirthDate      string                 `json:"birth_date" required:"true"`
-	Gender         string                 `json:"gender" required:"true"`
+	Gender         string                 `json:"gender" required:"true" enum:"M,F,NB"`
 	CountryCode    string                 `json:"country_code" required:"true"`
 	PhoneNumber    string                 `json:"phone_number" required:"true"`
+	CountryCodeRaw string                 `json:"country_code_raw,omitempty"`
+	PhoneNumberRaw string                 `json:"phone_number_raw,omitempty"`
 	Email          string                 `json:"email" required:"true"`
 	AddressStreet  string                 `json:"address_street" required:"true"`
 	AddressNumber  string                 `json:"address_number" required:"true"`
@@ -24,6 +26,7 @@ type PatientRequest struct {
 	AddressCountry string                 `json:"address_country" required:"true"`
 	ZipCode        string                 `json:"zip_code" required:"true"`
 	Metadata       map[string]interface{} `json:"metadata"`
+	NoShowCount    int                    `json:"no_show_count,omitempty"` // Sólo lectura
 	CreatedAt      string                 `json:"created_at,omitempty"`
 	UpdatedAt      string                 `json:"updated_at,omitempty"`
 }
@@ -46,14 +49,18 @@ type Patient struct {
 	BirthDate      string                 `dynamodbav:"birth_date"`
 	Gender         string                 `dynamodbav:"gender"`
 	CountryCode    string                 `dynamodbav:"country_code"`
-	PhoneNumber    string                 `dynamodbav:"phone_number"`
+	PhoneNumber    string                 `dynamodbav:"phone_number"` // E.164
+	CountryCodeRaw string                 `dynamodbav:"country_code_raw,omitempty"`
+	PhoneNumberRaw string                 `dynamodbav:"phone_number_raw,omitempty"` // Como lo escribió el usuario
 	Email          string                 `dynamodbav:"email"`
 	AddressStreet  string                 `dynamodbav:"address_street"`
 	AddressNumber  string                 `dynamodbav:"address_number"`
 	AddressCity    string                 `dynamodbav:"address_city"`
 	AddressCountry string                 `dynamodbav:"address_country"`
 	ZipCode        string                 `dynamodbav:"zip_code"`
+	NoShowCount    int                    `dynamodbav:"no_show_count,omitempty"` // Turnos a los que no se presentó
 	CreatedAt      string                 `dynamodbav:"created_at"`
 	UpdatedAt      string                 `dynamodbav:"updated_at"`
+	Version        int                    `dynamodbav:"version"` // Se incrementa en cada modificación; lo usa el bloqueo optimista
 	Metadata       map[string]interface{} `json:"metadata" dynamodbav:"metadata"`
 }
@@ -0,0 +1,35 @@
+package models
+
+// Resultado de cada fila de una importación de pacientes.
+const (
+	PatientImportCreated = "CREATED"
+	PatientImportSkipped = "SKIPPED"
+	PatientImportFailed  = "FAILED"
+)
+
+type PatientImportRequest struct {
+	ClientID  string `json:"client_id"`
+	CSV       string `json:"csv"`
+	Delimiter string `json:"delimiter,omitempty"` // "," o ";"; si se omite se detecta del encabezado
+	// Columns mapea encabezados del CSV a campos de PatientRequest (por su
+	// nombre JSON). Los encabezados que ya coinciden con un campo no hace
+	// falta mapearlos; los que no coinciden con ninguno van a Metadata.
+	Columns map[string]string `json:"columns,omitempty"`
+}
+
+type PatientImportRow struct {
+	Row       int    `json:"row"` // Número de línea en el CSV, contando el encabezado
+	Result    string `json:"result"`
+	Reason    string `json:"reason,omitempty"`
+	PatientID string `json:"patient_id,omitempty"`
+	DocType   string `json:"doc_type,omitempty"`
+	DocNumber string `json:"doc_number,omitempty"`
+}
+
+type PatientImportReport struct {
+	Total   int                 `json:"total"`
+	Created int                 `json:"created"`
+	Skipped int                 `json:"skipped"`
+	Failed  int                 `json:"failed"`
+	Rows    []*PatientImportRow `json:"rows"`
+}
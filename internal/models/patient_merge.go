@@ -0,0 +1,41 @@
+package models
+
+const (
+	PatientMergeStatusMerged   = "MERGED"
+	PatientMergeStatusReverted = "REVERTED"
+)
+
+// DuplicateCandidate es un par de pacientes del mismo tenant que
+// probablemente sean la misma persona.
+type DuplicateCandidate struct {
+	Patient   *PatientRequest `json:"patient"`
+	Duplicate *PatientRequest `json:"duplicate"`
+	Score     float64         `json:"score"`   // 0 a 1
+	Reasons   []string        `json:"reasons"` // name, birth_date, phone, email
+}
+
+type PatientMergeRequest struct {
+	ID           string   `json:"id,omitempty"`
+	ClientID     string   `json:"client_id"`
+	SurvivorID   string   `json:"survivor_id"` // Paciente que queda
+	MergedID     string   `json:"merged_id"`   // Paciente que se elimina
+	Status       string   `json:"status,omitempty"`
+	Appointments []string `json:"appointments,omitempty"` // Turnos que pasaron al sobreviviente
+	CreatedAt    string   `json:"created_at,omitempty"`
+	RevertedAt   string   `json:"reverted_at,omitempty"`
+}
+
+// PatientMerge es el registro de auditoría de una fusión. Guarda al paciente
+// eliminado completo y qué se cambió, para poder revertirla.
+type PatientMerge struct {
+	ID             string   `dynamodbav:"id"`
+	ClientID       string   `dynamodbav:"client_id"`
+	SurvivorID     string   `dynamodbav:"survivor_id"`
+	MergedID       string   `dynamodbav:"merged_id"`
+	Status         string   `dynamodbav:"status"`
+	MergedPatient  *Patient `dynamodbav:"merged_patient"`
+	AppointmentIDs []string `dynamodbav:"appointment_ids,omitempty"`
+	FilledFields   []string `dynamodbav:"filled_fields,omitempty"` // Campos del sobreviviente completados con los del eliminado
+	CreatedAt      string   `dynamodbav:"created_at"`
+	RevertedAt     string   `dynamodbav:"reverted_at,omitempty"`
+}
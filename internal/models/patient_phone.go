@@ -0,0 +1,26 @@
+package models
+
+// PhoneMigrationRequest normaliza a E.164 los teléfonos de los pacientes de un
+// cliente guardados antes de la validación.
+type PhoneMigrationRequest struct {
+	ClientID string `json:"client_id"`
+	Cursor   string `json:"cursor,omitempty"`
+	// DryRun sólo informa qué se cambiaría
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+type PhoneMigrationFailure struct {
+	PatientID   string `json:"patient_id"`
+	CountryCode string `json:"country_code"`
+	PhoneNumber string `json:"phone_number"`
+	Reason      string `json:"reason"`
+}
+
+type PhoneMigrationReport struct {
+	Scanned    int                      `json:"scanned"`
+	Normalized int                      `json:"normalized"`
+	Unchanged  int                      `json:"unchanged"`
+	Failed     int                      `json:"failed"`
+	Failures   []*PhoneMigrationFailure `json:"failures,omitempty"`
+	Cursor     string                   `json:"cursor,omitempty"` // Para continuar si se cortó por un error
+}
@@ -0,0 +1,32 @@
+package models
+
+const (
+	PatientSearchByName  = "name"
+	PatientSearchByPhone = "phone"
+	PatientSearchByEmail = "email"
+)
+
+// PatientSearchRequest busca por uno solo de Name (prefijo de nombre o
+// apellido), Phone o Email.
+type PatientSearchRequest struct {
+	ClientID string `json:"client_id"`
+	Name     string `json:"name,omitempty"`
+	Phone    string `json:"phone,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Cursor   string `json:"cursor,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+type PatientSearchResult struct {
+	Patients   []*PatientRequest `json:"patients"`
+	NextCursor string            `json:"next_cursor,omitempty"` // Vacío en la última página
+}
+
+// PatientSearchEntry es un item de la tabla de búsqueda: uno por token del
+// nombre, por teléfono y por email de cada paciente.
+type PatientSearchEntry struct {
+	ClientID  string `dynamodbav:"client_id"`
+	SearchKey string `dynamodbav:"search_key"` // n#<token>#<id>, p#<dígitos>#<id> o e#<email>#<id>
+	PatientID string `dynamodbav:"patient_id"`
+	Name      string `dynamodbav:"name"` // Tokens del nombre entre espacios, para filtrar por el resto de las palabras
+}
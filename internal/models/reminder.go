@@ -0,0 +1,18 @@
+package models
+
+const (
+	ReminderChannelSMS      = "sms"
+	ReminderChannelEmail    = "email"
+	ReminderChannelWhatsApp = "whatsapp"
+)
+
+// ReminderReport resume una corrida del despachador de recordatorios.
+type ReminderReport struct {
+	Clients int `json:"clients"`
+	Checked int `json:"checked"`
+	Sent    int `json:"sent"`
+	// Skipped cuenta los turnos cuya ventana ya se había enviado o cuyo
+	// paciente no tiene cómo ser contactado
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
@@ -0,0 +1,37 @@
+package models
+
+type SlotHoldRequest struct {
+	ID        string `json:"id,omitempty"` // Se usa para confirmar o liberar la reserva
+	ClientID  string `json:"client_id"`
+	DoctorID  string `json:"doctor_id"`
+	Date      string `json:"date"`
+	Duration  int    `json:"duration"`
+	Minutes   int    `json:"minutes,omitempty"` // Cuánto dura la reserva
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+// HoldConfirmRequest es el cuerpo de la confirmación de una reserva: el
+// médico, la fecha y la duración salen de la reserva, así que sólo se piden
+// el paciente y los datos propios del turno.
+type HoldConfirmRequest struct {
+	PatientID string                 `json:"patient_id" required:"true"`
+	Status    AppointmentStatus      `json:"status,omitempty" enum:"SCHEDULED,CONFIRMED,IN_PROGRESS,COMPLETED,CANCELLED,NO_SHOW"`
+	Notes     string                 `json:"notes,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// SlotHold reserva un horario de un médico mientras el paciente completa la
+// reserva. Hay una por horario: SlotKey es DoctorID#inicio en UTC y la
+// escritura es condicional. ExpiresAt es el atributo TTL de la tabla, en
+// segundos Unix; DynamoDB borra las vencidas con demora, así que quien lee
+// tiene que ignorarlas.
+type SlotHold struct {
+	SlotKey   string `dynamodbav:"slot_key"`
+	HoldID    string `dynamodbav:"hold_id"`
+	ClientID  string `dynamodbav:"client_id"`
+	DoctorID  string `dynamodbav:"doctor_id"`
+	Date      string `dynamodbav:"date"`
+	Duration  int    `dynamodbav:"duration"`
+	CreatedAt string `dynamodbav:"created_at"`
+	ExpiresAt int64  `dynamodbav:"expires_at"`
+}
@@ -0,0 +1,75 @@
+package models
+
+const (
+	WaitlistStatusWaiting = "WAITING"
+	WaitlistStatusBooked  = "BOOKED"
+
+	WaitlistOfferOpen    = "OPEN"
+	WaitlistOfferClaimed = "CLAIMED"
+	WaitlistOfferExpired = "EXPIRED"
+)
+
+type WaitlistEntryRequest struct {
+	ID            string `json:"id,omitempty"`
+	ClientID      string `json:"client_id"`
+	DoctorID      string `json:"doctor_id"`
+	PatientID     string `json:"patient_id"`
+	From          string `json:"from,omitempty"`     // YYYY-MM-DD, opcional
+	To            string `json:"to,omitempty"`       // YYYY-MM-DD, opcional
+	Priority      int    `json:"priority,omitempty"` // Mayor primero
+	Status        string `json:"status,omitempty"`
+	AppointmentID string `json:"appointment_id,omitempty"` // El turno que obtuvo
+	CreatedAt     string `json:"created_at,omitempty"`
+	UpdatedAt     string `json:"updated_at,omitempty"`
+}
+
+// WaitlistEntry es un paciente esperando un turno con un médico, en cualquier
+// fecha o dentro del rango From-To.
+type WaitlistEntry struct {
+	ID            string `dynamodbav:"id"`
+	ClientID      string `dynamodbav:"client_id"`
+	DoctorID      string `dynamodbav:"doctor_id"`
+	PatientID     string `dynamodbav:"patient_id"`
+	From          string `dynamodbav:"from,omitempty"`
+	To            string `dynamodbav:"to,omitempty"`
+	Priority      int    `dynamodbav:"priority"`
+	Status        string `dynamodbav:"status"`
+	AppointmentID string `dynamodbav:"appointment_id,omitempty"`
+	CreatedAt     string `dynamodbav:"created_at"`
+	UpdatedAt     string `dynamodbav:"updated_at"`
+}
+
+// WaitlistOffer es un turno liberado que se ofrece a la lista de espera.
+// Candidates está en orden de prioridad y se ofrece de a tandas de Batch
+// pacientes, cada una durante RoundSeconds desde CreatedAt. El primero que
+// acepta se lo queda.
+type WaitlistOffer struct {
+	ID                  string   `dynamodbav:"id"`
+	ClientID            string   `dynamodbav:"client_id"`
+	DoctorID            string   `dynamodbav:"doctor_id"`
+	Date                string   `dynamodbav:"date"`
+	Duration            int      `dynamodbav:"duration"`
+	SourceAppointmentID string   `dynamodbav:"source_appointment_id"`
+	Candidates          []string `dynamodbav:"candidates"`
+	Batch               int      `dynamodbav:"batch"`
+	RoundSeconds        int      `dynamodbav:"round_seconds"`
+	Notified            []string `dynamodbav:"notified,stringset,omitempty"`
+	Status              string   `dynamodbav:"status"`
+	ClaimedBy           string   `dynamodbav:"claimed_by,omitempty"`
+	CreatedAt           string   `dynamodbav:"created_at"`
+	ExpiresAt           string   `dynamodbav:"expires_at"`
+}
+
+// WaitlistAcceptRequest es la aceptación de una oferta por un paciente de la
+// lista.
+type WaitlistAcceptRequest struct {
+	OfferID string `json:"offer_id"`
+	EntryID string `json:"entry_id"`
+}
+
+type WaitlistReport struct {
+	Offers   int `json:"offers"`
+	Notified int `json:"notified"`
+	Expired  int `json:"expired"`
+	Failed   int `json:"failed"`
+}
@@ -0,0 +1,73 @@
+package models
+
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliveryDelivered = "DELIVERED"
+	WebhookDeliveryDead      = "DEAD"
+)
+
+type WebhookSubscriptionRequest struct {
+	ID        string   `json:"id,omitempty"`
+	ClientID  string   `json:"client_id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`           // Tipos de evento o "*" para todos
+	Secret    string   `json:"secret,omitempty"` // Sólo en la respuesta del alta
+	CreatedAt string   `json:"created_at,omitempty"`
+}
+
+// WebhookSubscription es un endpoint de una clínica que recibe los eventos
+// de Events firmados con su secreto. El secreto se guarda cifrado con KMS en
+// SecretCiphertext; Secret es el secreto en claro de las suscripciones
+// anteriores, que se cifra en la primera entrega.
+type WebhookSubscription struct {
+	ID               string   `dynamodbav:"id"`
+	ClientID         string   `dynamodbav:"client_id"`
+	URL              string   `dynamodbav:"url"`
+	Events           []string `dynamodbav:"events,stringset"`
+	Secret           string   `dynamodbav:"secret,omitempty"`
+	SecretCiphertext string   `dynamodbav:"secret_ciphertext,omitempty"`
+	CreatedAt        string   `dynamodbav:"created_at"`
+}
+
+type WebhookDeliveryRequest struct {
+	ID             string `json:"id"`
+	SubscriptionID string `json:"subscription_id"`
+	EventID        string `json:"event_id"`
+	EventType      string `json:"event_type"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	NextAttemptAt  string `json:"next_attempt_at,omitempty"` // Sólo si está PENDING
+	LastStatusCode int    `json:"last_status_code,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	ReplayOf       string `json:"replay_of,omitempty"`
+	CreatedAt      string `json:"created_at"`
+	DeliveredAt    string `json:"delivered_at,omitempty"`
+}
+
+// WebhookDelivery es el envío de un evento a una suscripción. Queda como
+// registro: las entregadas vencen por TTL (ExpiresAt) y las DEAD, que
+// agotaron los reintentos, quedan hasta que alguien las reenvíe.
+type WebhookDelivery struct {
+	ID             string `dynamodbav:"id"`
+	SubscriptionID string `dynamodbav:"subscription_id"`
+	ClientID       string `dynamodbav:"client_id"`
+	EventID        string `dynamodbav:"event_id"`
+	EventType      string `dynamodbav:"event_type"`
+	Payload        string `dynamodbav:"payload"`
+	Status         string `dynamodbav:"status"`
+	Attempts       int    `dynamodbav:"attempts"`
+	NextAttemptAt  string `dynamodbav:"next_attempt_at"`
+	LastStatusCode int    `dynamodbav:"last_status_code,omitempty"`
+	LastError      string `dynamodbav:"last_error,omitempty"`
+	ReplayOf       string `dynamodbav:"replay_of,omitempty"`
+	CreatedAt      string `dynamodbav:"created_at"`
+	DeliveredAt    string `dynamodbav:"delivered_at,omitempty"`
+	ExpiresAt      int64  `dynamodbav:"expires_at,omitempty"`
+}
+
+type WebhookReport struct {
+	Due       int `json:"due"`
+	Delivered int `json:"delivered"`
+	Retrying  int `json:"retrying"`
+	Dead      int `json:"dead"`
+}
@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"os"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"go.uber.org/zap"
+)
+
+// FromEnv arma un Notifier por canal. Con NOTIFY_PROVIDER=log todos son
+// LogNotifier; si no, cada canal usa su proveedor cuando está configurado:
+// SMS siempre por SNS, email por SES con NOTIFY_EMAIL_FROM y WhatsApp con
+// WHATSAPP_PHONE_NUMBER_ID y WHATSAPP_TOKEN. Un canal sin proveedor queda
+// fuera del mapa: el envío por ese canal falla y el recordatorio se libera
+// para la próxima corrida, en vez de darse por enviado sin salir.
+func FromEnv(cfg aws.Config, logger *zap.SugaredLogger) map[string]Notifier {
+	notifiers := map[string]Notifier{}
+	if os.Getenv("NOTIFY_PROVIDER") == "log" {
+		for _, channel := range []string{models.ReminderChannelSMS, models.ReminderChannelEmail, models.ReminderChannelWhatsApp} {
+			notifiers[channel] = &LogNotifier{Logger: logger, Channel: channel}
+		}
+		return notifiers
+	}
+
+	notifiers[models.ReminderChannelSMS] = &SMSNotifier{Client: sns.NewFromConfig(cfg), SenderID: os.Getenv("NOTIFY_SMS_SENDER_ID")}
+	if from := os.Getenv("NOTIFY_EMAIL_FROM"); from != "" {
+		notifiers[models.ReminderChannelEmail] = &EmailNotifier{Client: sesv2.NewFromConfig(cfg), From: from}
+	}
+	if id, token := os.Getenv("WHATSAPP_PHONE_NUMBER_ID"), os.Getenv("WHATSAPP_TOKEN"); id != "" && token != "" {
+		language := os.Getenv("WHATSAPP_TEMPLATE_LANGUAGE")
+		if language == "" {
+			language = "es_AR"
+		}
+		notifiers[models.ReminderChannelWhatsApp] = &WhatsAppNotifier{
+			PhoneNumberID: id,
+			Token:         token,
+			Template:      os.Getenv("WHATSAPP_TEMPLATE"),
+			Language:      language,
+		}
+	}
+	return notifiers
+}
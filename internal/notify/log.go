@@ -0,0 +1,24 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"go.uber.org/zap"
+)
+
+// LogNotifier no envía nada: registra que hubo un envío. Sirve para correr
+// local con NOTIFY_PROVIDER=log. No registra el cuerpo: lleva datos del turno
+// y enlaces de acción que valen como credencial.
+type LogNotifier struct {
+	Logger  *zap.SugaredLogger
+	Channel string
+}
+
+func (l *LogNotifier) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return ErrNoRecipient
+	}
+	logging.FromContext(ctx, l.Logger).Infow("Notification not sent (log provider)", "channel", l.Channel, "to", msg.To, "subject", msg.Subject)
+	return nil
+}
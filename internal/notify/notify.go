@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+)
+
+// Envío de notificaciones a pacientes por SMS, email o WhatsApp. Cada canal
+// tiene su proveedor; LogNotifier los reemplaza a todos en local.
+
+var ErrNoRecipient = errors.New("notification has no recipient")
+
+type Message struct {
+	To      string // Teléfono E.164 o email, según el canal
+	Subject string // Sólo email
+	Body    string
+}
+
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Recipient devuelve el destinatario del paciente para el canal, o "" si no
+// tiene el dato cargado.
+func Recipient(patient *models.Patient, channel string) string {
+	switch channel {
+	case models.ReminderChannelSMS, models.ReminderChannelWhatsApp:
+		// Sólo números ya normalizados; los anteriores a E.164 pasan por la migración
+		if strings.HasPrefix(patient.PhoneNumber, "+") {
+			return patient.PhoneNumber
+		}
+	case models.ReminderChannelEmail:
+		return strings.TrimSpace(patient.Email)
+	}
+	return ""
+}
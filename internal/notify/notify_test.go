@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSNS struct{ input *sns.PublishInput }
+
+func (f *fakeSNS) Publish(_ context.Context, params *sns.PublishInput, _ ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.input = params
+	return &sns.PublishOutput{}, nil
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("NOTIFY_PROVIDER", "")
+	t.Setenv("NOTIFY_EMAIL_FROM", "")
+	t.Setenv("WHATSAPP_PHONE_NUMBER_ID", "")
+	notifiers := FromEnv(aws.Config{}, nil)
+	assert.IsType(t, &SMSNotifier{}, notifiers[models.ReminderChannelSMS])
+	// Sin proveedor el canal no se registra: nunca se da por enviado
+	assert.NotContains(t, notifiers, models.ReminderChannelEmail)
+	assert.NotContains(t, notifiers, models.ReminderChannelWhatsApp)
+
+	t.Setenv("NOTIFY_PROVIDER", "log")
+	notifiers = FromEnv(aws.Config{}, nil)
+	assert.Len(t, notifiers, 3)
+	assert.IsType(t, &LogNotifier{}, notifiers[models.ReminderChannelEmail])
+}
+
+func TestSMSNotifier_Send(t *testing.T) {
+	client := &fakeSNS{}
+	notifier := &SMSNotifier{Client: client}
+
+	require.NoError(t, notifier.Send(context.Background(), Message{To: "+5491155551234", Body: "Recordatorio"}))
+	assert.Equal(t, "+5491155551234", *client.input.PhoneNumber)
+	assert.Equal(t, "Transactional", *client.input.MessageAttributes["AWS.SNS.SMS.SMSType"].StringValue)
+
+	assert.ErrorIs(t, notifier.Send(context.Background(), Message{Body: "x"}), ErrNoRecipient)
+}
+
+func TestWhatsAppNotifier_Send(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/123/messages", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	notifier := &WhatsAppNotifier{BaseURL: server.URL, PhoneNumberID: "123", Token: "token"}
+
+	require.NoError(t, notifier.Send(context.Background(), Message{To: "+5491155551234", Body: "Recordatorio"}))
+	assert.Equal(t, "5491155551234", payload["to"])
+	assert.Equal(t, "text", payload["type"])
+}
+
+func TestWhatsAppNotifier_SendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid token"}}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	notifier := &WhatsAppNotifier{BaseURL: server.URL, PhoneNumberID: "123", Token: "bad"}
+
+	err := notifier.Send(context.Background(), Message{To: "+5491155551234", Body: "x"})
+
+	assert.ErrorContains(t, err, "401")
+}
@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+type SNSClient interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// SMSNotifier manda SMS transaccionales con SNS.
+type SMSNotifier struct {
+	Client   SNSClient
+	SenderID string // Opcional; no todos los países lo permiten
+}
+
+func (s *SMSNotifier) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return ErrNoRecipient
+	}
+	attributes := map[string]types.MessageAttributeValue{
+		"AWS.SNS.SMS.SMSType": {DataType: aws.String("String"), StringValue: aws.String("Transactional")},
+	}
+	if s.SenderID != "" {
+		attributes["AWS.SNS.SMS.SenderID"] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(s.SenderID)}
+	}
+	_, err := s.Client.Publish(ctx, &sns.PublishInput{
+		PhoneNumber:       aws.String(msg.To),
+		Message:           aws.String(msg.Body),
+		MessageAttributes: attributes,
+	})
+	return err
+}
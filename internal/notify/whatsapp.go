@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const whatsAppBaseURL = "https://graph.facebook.com/v19.0"
+
+// WhatsAppNotifier manda mensajes de texto con la Cloud API de WhatsApp
+// Business. Fuera de la ventana de 24 h de conversación Meta sólo acepta
+// plantillas aprobadas; en ese caso se configura Template y el cuerpo va como
+// único parámetro.
+type WhatsAppNotifier struct {
+	HTTPClient    *http.Client
+	BaseURL       string
+	PhoneNumberID string
+	Token         string
+	Template      string
+	Language      string
+}
+
+func (w *WhatsAppNotifier) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return ErrNoRecipient
+	}
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                strings.TrimPrefix(msg.To, "+"),
+	}
+	if w.Template != "" {
+		payload["type"] = "template"
+		payload["template"] = map[string]interface{}{
+			"name":     w.Template,
+			"language": map[string]string{"code": w.Language},
+			"components": []map[string]interface{}{{
+				"type":       "body",
+				"parameters": []map[string]string{{"type": "text", "text": msg.Body}},
+			}},
+		}
+	} else {
+		payload["type"] = "text"
+		payload["text"] = map[string]string{"body": msg.Body}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	baseURL := w.BaseURL
+	if baseURL == "" {
+		baseURL = whatsAppBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/"+w.PhoneNumberID+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+w.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("whatsapp API returned %d: %s", resp.StatusCode, detail)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package openapi
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/MezeLaw/iris-services/internal/jsonpatch"
+	"github.com/MezeLaw/iris-services/internal/response"
+)
+
+// Documento OpenAPI 3.1 de la API, generado a partir de Routes y de los
+// modelos. Lo sirve cmd/openapi y lo usa Validate para rechazar pedidos que
+// no lo cumplen antes de llegar al handler.
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	operations map[string]*Schema  // Cuerpo de cada operación, por OperationID
+	schemas    schemas             // Para resolver los $ref al validar
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// PathItem agrupa las operaciones de una ruta por método en minúsculas.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Generate arma el documento de routes.
+func Generate(routes []Route) *Document {
+	s := schemas{}
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       Info{Title: "Iris API", Version: "1.0.0"},
+		Paths:      map[string]PathItem{},
+		operations: map[string]*Schema{},
+	}
+
+	meta := s.of(response.Meta{})
+	errorSchema := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"error": {Type: "string"}, "meta": meta},
+		Required:   []string{"error", "meta"},
+	}
+	s["Error"] = errorSchema
+
+	tags := map[string]bool{}
+	for _, route := range routes {
+		op := &Operation{
+			OperationID: route.OperationID,
+			Summary:     route.Summary,
+			Responses:   map[string]*Response{},
+		}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+			if !tags[route.Tag] {
+				tags[route.Tag] = true
+				doc.Tags = append(doc.Tags, Tag{Name: route.Tag})
+			}
+		}
+		for _, name := range pathParams(route.Path) {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+		for _, param := range route.Query {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: param.Name, In: "query", Description: param.Description, Required: param.Required, Schema: &Schema{Type: "string"},
+			})
+		}
+
+		switch {
+		case route.Patch:
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{
+				jsonpatch.MergePatchType: {Schema: &Schema{Type: "object"}},
+				jsonpatch.JSONPatchType:  {Schema: &Schema{Type: "array", Items: &Schema{Type: "object"}}},
+			}}
+		case route.Request != nil:
+			body := s.of(route.Request)
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{contentType(route): {Schema: body}}}
+			doc.operations[route.OperationID] = body
+		}
+		if route.Request != nil || route.Patch {
+			op.Responses["400"] = errorResponse(route, "Pedido inválido")
+		}
+
+		op.Responses[strconv.Itoa(status(route))] = successResponse(s, route, meta)
+		op.Responses["default"] = errorResponse(route, "Error")
+
+		item := doc.Paths[route.Path]
+		if item == nil {
+			item = PathItem{}
+			doc.Paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	doc.Components.Schemas = s
+	doc.schemas = s
+	return doc
+}
+
+func status(route Route) int {
+	if route.Status == 0 {
+		return 200
+	}
+	return route.Status
+}
+
+func contentType(route Route) string {
+	if route.ContentType == "" {
+		return response.ContentType
+	}
+	return route.ContentType
+}
+
+func successResponse(s schemas, route Route, meta *Schema) *Response {
+	resp := &Response{Description: "OK"}
+	if route.ContentType != "" {
+		// Sin sobre: el cuerpo es el recurso o el documento tal cual
+		body := &Schema{Type: "string"}
+		if route.Response != nil {
+			body = s.of(route.Response)
+		}
+		resp.Content = map[string]*MediaType{route.ContentType: {Schema: body}}
+		if route.Status == 201 {
+			resp.Description = "Creado"
+		}
+		return resp
+	}
+	if route.Response == nil {
+		if route.Status == 204 {
+			resp.Description = "Sin contenido"
+		}
+		return resp
+	}
+
+	data := s.of(route.Response)
+	if route.List {
+		data = &Schema{Type: "array", Items: data}
+	}
+	resp.Content = map[string]*MediaType{response.ContentType: {Schema: &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"data": data, "meta": meta},
+		Required:   []string{"data", "meta"},
+	}}}
+	resp.Headers = map[string]*Header{
+		response.RequestIDHeader: {Description: "ID del pedido, también en meta.request_id", Schema: &Schema{Type: "string"}},
+	}
+	switch route.Status {
+	case 201:
+		resp.Description = "Creado"
+		resp.Headers["Location"] = &Header{Description: "URL del recurso creado", Schema: &Schema{Type: "string"}}
+	case 202:
+		resp.Description = "Aceptado"
+		resp.Headers["Location"] = &Header{Description: "URL para consultar el estado", Schema: &Schema{Type: "string"}}
+	}
+	return resp
+}
+
+// errorResponse es la respuesta de error con el sobre JSON. Los endpoints
+// con otro ContentType responden sus propios errores (OperationOutcome en
+// FHIR, páginas en HTML) y sólo se describen.
+func errorResponse(route Route, description string) *Response {
+	if route.ContentType != "" {
+		return &Response{Description: description}
+	}
+	return &Response{
+		Description: description,
+		Content:     map[string]*MediaType{response.ContentType: {Schema: &Schema{Ref: refPrefix + "Error"}}},
+	}
+}
+
+// pathParams devuelve los nombres entre llaves de path, en orden.
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, strings.Trim(segment, "{}"))
+		}
+	}
+	return names
+}
+
+var (
+	specOnce sync.Once
+	spec     *Document
+	specJSON []byte
+)
+
+// Spec es el documento de Routes; se genera una vez por proceso.
+func Spec() *Document {
+	specOnce.Do(func() {
+		spec = Generate(Routes)
+		specJSON, _ = json.Marshal(spec)
+	})
+	return spec
+}
+
+// JSON es Spec serializado.
+func JSON() []byte {
+	Spec()
+	return specJSON
+}
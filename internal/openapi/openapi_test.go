@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpec(t *testing.T) {
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(JSON(), &doc))
+	assert.Equal(t, Version, doc["openapi"])
+
+	paths := doc["paths"].(map[string]interface{})
+	for _, route := range Routes {
+		require.Contains(t, paths, route.Path)
+	}
+	create := paths["/patients"].(map[string]interface{})["post"].(map[string]interface{})
+	assert.Equal(t, "createPatient", create["operationId"])
+	assert.Contains(t, create["responses"], "201")
+
+	patch := paths["/appointments/{id}"].(map[string]interface{})["patch"].(map[string]interface{})
+	params := patch["parameters"].([]interface{})
+	require.Len(t, params, 1)
+	assert.Equal(t, "path", params[0].(map[string]interface{})["in"])
+}
+
+func TestSpec_ContentTypes(t *testing.T) {
+	doc := Spec()
+
+	fhirCreate := doc.Paths["/fhir/Appointment"]["post"]
+	assert.Contains(t, fhirCreate.RequestBody.Content, "application/fhir+json")
+	assert.Equal(t, refPrefix+"Appointment", fhirCreate.Responses["201"].Content["application/fhir+json"].Schema.Ref)
+	assert.Nil(t, fhirCreate.Responses["default"].Content)
+
+	feed := doc.Paths["/calendar/feeds/{id}"]["get"]
+	assert.Contains(t, feed.Responses["200"].Content, "text/calendar; charset=utf-8")
+
+	export := doc.Paths["/exports"]["post"]
+	assert.Contains(t, export.Responses["202"].Headers, "Location")
+}
+
+func TestSpec_UniqueOperations(t *testing.T) {
+	seen := map[string]bool{}
+	for _, route := range Routes {
+		assert.False(t, seen[route.OperationID], route.OperationID)
+		seen[route.OperationID] = true
+		assert.Contains(t, Spec().Paths[route.Path], strings.ToLower(route.Method))
+	}
+}
+
+func TestSpec_Enums(t *testing.T) {
+	schemas := Spec().Components.Schemas
+
+	status := schemas["AppointmentRequest"].Properties["status"]
+	assert.Equal(t, []string{
+		string(models.AppointmentStatusScheduled),
+		string(models.AppointmentStatusConfirmed),
+		string(models.AppointmentStatusInProgress),
+		string(models.AppointmentStatusCompleted),
+		string(models.AppointmentStatusCancelled),
+		string(models.AppointmentStatusNoShow),
+	}, status.Enum)
+
+	gender := schemas["PatientRequest"].Properties["gender"]
+	assert.Equal(t, []string{models.GenderMale, models.GenderFemale, models.GenderNonBinary}, gender.Enum)
+}
+
+func TestSpec_Required(t *testing.T) {
+	patient := Spec().Components.Schemas["PatientRequest"]
+	assert.Contains(t, patient.Required, "first_name")
+	assert.Contains(t, patient.Required, "gender")
+	assert.NotContains(t, patient.Required, "id")
+	assert.NotContains(t, patient.Required, "metadata")
+
+	appointment := Spec().Components.Schemas["AppointmentRequest"]
+	assert.ElementsMatch(t, []string{"client_id", "patient_id", "doctor_id", "date", "duration", "status"}, appointment.Required)
+	assert.Equal(t, "date-time", appointment.Properties["date"].Format)
+}
+
+const validAppointment = `{"client_id":"c1","patient_id":"p1","doctor_id":"d1","date":"2026-11-02T10:00:00-03:00","duration":30,"status":"SCHEDULED","metadata":{"room":2}}`
+
+func TestValidateBody(t *testing.T) {
+	assert.NoError(t, Spec().ValidateBody("createAppointment", []byte(validAppointment)))
+	assert.NoError(t, Spec().ValidateBody("deleteAppointment", nil))
+
+	tests := []struct {
+		body    string
+		message string
+	}{
+		{`[]`, "body must be an object"},
+		{`{"client_id":"c1"}`, "patient_id is required"},
+		{`{"client_id":"","patient_id":"p1","doctor_id":"d1","date":"2026-11-02T10:00:00Z","duration":30,"status":"SCHEDULED"}`, "client_id is required"},
+		{`{"client_id":"c1","patient_id":"p1","doctor_id":"d1","date":"2026-11-02T10:00:00Z","duration":30,"status":"LATE"}`, "status must be one of"},
+		{`{"client_id":"c1","patient_id":"p1","doctor_id":"d1","date":"mañana","duration":30,"status":"SCHEDULED"}`, "date must be an RFC 3339 date-time"},
+		{`{"client_id":"c1","patient_id":"p1","doctor_id":"d1","date":"2026-11-02T10:00:00Z","duration":"30","status":"SCHEDULED"}`, "duration must be an integer"},
+		{`{"client_id":"c1","patient_id":"p1","doctor_id":"d1","date":"2026-11-02T10:00:00Z","duration":30.5,"status":"SCHEDULED"}`, "duration must be an integer"},
+		{`{"client_id":`, "invalid request body"},
+	}
+	for _, tt := range tests {
+		err := Spec().ValidateBody("createAppointment", []byte(tt.body))
+		require.Error(t, err, tt.body)
+		assert.True(t, errors.Is(err, ErrInvalid))
+		assert.Contains(t, err.Error(), tt.message)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	assert.Empty(t, Spec().Missing("createAppointment", []byte(validAppointment)))
+	assert.Equal(t, []string{"patient_id", "duration", "status"},
+		Spec().Missing("createAppointment", []byte(`{"client_id":"c1","patient_id":"","doctor_id":"d1","date":"2026-11-02T10:00:00Z"}`)))
+	assert.Nil(t, Spec().Missing("deleteAppointment", nil))
+}
+
+func TestValidate(t *testing.T) {
+	called := false
+	next := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		called = true
+		return events.APIGatewayProxyResponse{StatusCode: 201}, nil
+	}
+	h := Validate("createPatient", next)
+
+	resp, err := h(context.Background(), events.APIGatewayProxyRequest{Body: `{"first_name":"Ana","gender":"X"}`})
+	require.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+	assert.Contains(t, resp.Body, "is required")
+	assert.False(t, called)
+
+	resp, err = Validate("createAppointment", next)(context.Background(), events.APIGatewayProxyRequest{Body: validAppointment})
+	require.NoError(t, err)
+	assert.Equal(t, 201, resp.StatusCode)
+	assert.True(t, called)
+}
+
+func TestValidate_OperationFormats(t *testing.T) {
+	next := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: 200}, nil
+	}
+
+	// La confirmación de una reserva no pide médico ni fecha: salen de la reserva
+	resp, err := Validate("confirmHold", next)(context.Background(), events.APIGatewayProxyRequest{Body: `{"patient_id":"p1"}`})
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	resp, _ = Validate("confirmHold", next)(context.Background(), events.APIGatewayProxyRequest{Body: `{}`})
+	assert.Equal(t, 400, resp.StatusCode)
+
+	resp, _ = Validate("importPatients", next)(context.Background(), events.APIGatewayProxyRequest{
+		Headers: map[string]string{"content-type": "text/csv"}, Body: "first_name,last_name\nAna,Pérez",
+	})
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resp, _ = Validate("fhirCreateAppointment", next)(context.Background(), events.APIGatewayProxyRequest{Body: `{"status":1}`})
+	assert.Equal(t, 400, resp.StatusCode)
+	assert.Equal(t, "application/fhir+json", resp.Headers["Content-Type"])
+	assert.Contains(t, resp.Body, `"resourceType":"OperationOutcome"`)
+}
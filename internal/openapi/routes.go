@@ -0,0 +1,308 @@
+package openapi
+
+import (
+	"github.com/MezeLaw/iris-services/internal/documents"
+	"github.com/MezeLaw/iris-services/internal/fhir"
+	"github.com/MezeLaw/iris-services/internal/ical"
+	"github.com/MezeLaw/iris-services/internal/models"
+)
+
+// Route es un endpoint de la API. La tabla Routes es la fuente del documento
+// OpenAPI y de la validación de los pedidos: al sumar un Lambda a API Gateway
+// se suma acá con el mismo método y ruta.
+type Route struct {
+	Method      string
+	Path        string // Con los parámetros entre llaves: /patients/{id}
+	OperationID string
+	Summary     string
+	Tag         string
+	Query       []Param
+	// Request es el modelo del cuerpo; nil si el endpoint no recibe cuerpo.
+	Request interface{}
+	// Patch indica que el cuerpo es un JSON Merge Patch o un JSON Patch
+	// sobre Response en lugar de un Request completo.
+	Patch bool
+	// Response es el modelo de data en el sobre de la respuesta; nil si
+	// responde 204.
+	Response interface{}
+	// List indica que data es una lista de Response con meta.pagination.
+	List   bool
+	Status int
+	// ContentType es el tipo de los endpoints que no responden con el sobre
+	// JSON (FHIR, iCalendar, HTML, archivos): el cuerpo y la respuesta usan
+	// ese tipo y Response, si está, es el cuerpo completo.
+	ContentType string
+}
+
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+var (
+	clientIDParam = Param{Name: "clientId", Description: "Cliente (clínica) dueño de los datos", Required: true}
+	limitParam    = Param{Name: "limit", Description: "Tamaño de la página"}
+	cursorParam   = Param{Name: "cursor", Description: "meta.pagination.next_cursor de la página anterior"}
+)
+
+const (
+	htmlContentType = "text/html; charset=utf-8"
+	csvContentType  = "text/csv; charset=utf-8"
+)
+
+var Routes = []Route{
+	{
+		Method: "POST", Path: "/patients", OperationID: "createPatient", Tag: "patients",
+		Summary: "Crea un paciente", Request: models.PatientRequest{}, Response: models.PatientRequest{}, Status: 201,
+	},
+	{
+		Method: "GET", Path: "/patients", OperationID: "listPatients", Tag: "patients",
+		Summary: "Lista los pacientes de un cliente", Query: []Param{clientIDParam, limitParam, cursorParam},
+		Response: models.PatientRequest{}, List: true, Status: 200,
+	},
+	{
+		Method: "PUT", Path: "/patients", OperationID: "updatePatient", Tag: "patients",
+		Summary: "Reemplaza un paciente (el id va en el cuerpo)", Request: models.PatientRequest{}, Response: models.PatientRequest{}, Status: 200,
+	},
+	{
+		Method: "GET", Path: "/patients/search", OperationID: "searchPatients", Tag: "patients",
+		Summary: "Busca pacientes por nombre, teléfono o email",
+		Query: []Param{
+			clientIDParam,
+			{Name: "name", Description: "Palabras del nombre o apellido"},
+			{Name: "phone", Description: "Teléfono o parte de él"},
+			{Name: "email", Description: "Email exacto"},
+			limitParam,
+			cursorParam,
+		},
+		Response: models.PatientRequest{}, List: true, Status: 200,
+	},
+	{
+		Method: "GET", Path: "/patients/{id}", OperationID: "getPatient", Tag: "patients",
+		Summary: "Obtiene un paciente", Response: models.PatientRequest{}, Status: 200,
+	},
+	{
+		Method: "PATCH", Path: "/patients/{id}", OperationID: "patchPatient", Tag: "patients",
+		Summary: "Modifica parte de un paciente", Patch: true, Response: models.PatientRequest{}, Status: 200,
+	},
+	{
+		Method: "DELETE", Path: "/patients/{id}", OperationID: "deletePatient", Tag: "patients",
+		Summary: "Borra un paciente", Status: 204,
+	},
+	{
+		Method: "POST", Path: "/appointments", OperationID: "createAppointment", Tag: "appointments",
+		Summary: "Crea un turno", Request: models.AppointmentRequest{}, Response: models.AppointmentRequest{}, Status: 201,
+	},
+	{
+		Method: "GET", Path: "/appointments", OperationID: "listAppointments", Tag: "appointments",
+		Summary: "Lista los turnos de un cliente", Query: []Param{clientIDParam, limitParam, cursorParam},
+		Response: models.AppointmentRequest{}, List: true, Status: 200,
+	},
+	{
+		Method: "PUT", Path: "/appointments", OperationID: "updateAppointment", Tag: "appointments",
+		Summary: "Reemplaza un turno (el id va en el cuerpo)", Request: models.AppointmentRequest{}, Response: models.AppointmentRequest{}, Status: 200,
+	},
+	{
+		Method: "GET", Path: "/appointments/{id}", OperationID: "getAppointment", Tag: "appointments",
+		Summary: "Obtiene un turno", Response: models.AppointmentRequest{}, Status: 200,
+	},
+	{
+		Method: "PATCH", Path: "/appointments/{id}", OperationID: "patchAppointment", Tag: "appointments",
+		Summary: "Modifica parte de un turno", Patch: true, Response: models.AppointmentRequest{}, Status: 200,
+	},
+	{
+		Method: "DELETE", Path: "/appointments/{id}", OperationID: "deleteAppointment", Tag: "appointments",
+		Summary: "Borra un turno", Status: 204,
+	},
+	{
+		Method: "GET", Path: "/appointments/action", OperationID: "showAppointmentAction", Tag: "appointments",
+		Summary:     "Página del enlace de confirmación o cancelación de un recordatorio (pública)",
+		Query:       []Param{{Name: "token", Description: "Token firmado del enlace", Required: true}},
+		ContentType: htmlContentType,
+	},
+	{
+		Method: "POST", Path: "/appointments/action", OperationID: "applyAppointmentAction", Tag: "appointments",
+		Summary:     "Confirma o cancela el turno del enlace (pública)",
+		Query:       []Param{{Name: "token", Description: "Token firmado del enlace", Required: true}},
+		ContentType: htmlContentType,
+	},
+	{
+		Method: "POST", Path: "/patients/import", OperationID: "importPatients", Tag: "patients",
+		Summary: "Importa pacientes de un CSV; también acepta el CSV crudo como text/csv con clientId y delimiter en la query",
+		Query: []Param{
+			{Name: "clientId", Description: "Cliente, si el cuerpo es el CSV crudo"},
+			{Name: "delimiter", Description: "Separador, si el cuerpo es el CSV crudo"},
+		},
+		Request: models.PatientImportRequest{}, Response: models.PatientImportReport{}, Status: 200,
+	},
+	{
+		Method: "GET", Path: "/patients/duplicates", OperationID: "listDuplicatePatients", Tag: "patients",
+		Summary:  "Lista los posibles pacientes duplicados de un cliente",
+		Query:    []Param{clientIDParam, {Name: "threshold", Description: "Puntaje mínimo de similitud"}},
+		Response: models.DuplicateCandidate{}, List: true, Status: 200,
+	},
+	{
+		Method: "POST", Path: "/patients/merge", OperationID: "mergePatients", Tag: "patients",
+		Summary: "Fusiona un paciente duplicado en otro", Request: models.PatientMergeRequest{}, Response: models.PatientMergeRequest{}, Status: 200,
+	},
+	{
+		Method: "POST", Path: "/patients/merge/{id}/revert", OperationID: "unmergePatients", Tag: "patients",
+		Summary: "Revierte una fusión de pacientes", Response: models.PatientMergeRequest{}, Status: 200,
+	},
+	{
+		Method: "GET", Path: "/documents/types", OperationID: "listDocumentTypes", Tag: "documents",
+		Summary: "Lista los tipos de documento", Query: []Param{{Name: "country", Description: "Código de país, por ejemplo AR"}},
+		Response: documents.Type{}, List: true, Status: 200,
+	},
+	{
+		Method: "POST", Path: "/holds", OperationID: "createHold", Tag: "holds",
+		Summary: "Reserva un horario por unos minutos", Request: models.SlotHoldRequest{}, Response: models.SlotHoldRequest{}, Status: 201,
+	},
+	{
+		Method: "POST", Path: "/holds/{id}/confirm", OperationID: "confirmHold", Tag: "holds",
+		Summary: "Convierte la reserva en un turno", Request: models.HoldConfirmRequest{}, Response: models.AppointmentRequest{}, Status: 201,
+	},
+	{
+		Method: "DELETE", Path: "/holds/{id}", OperationID: "releaseHold", Tag: "holds",
+		Summary: "Libera la reserva", Status: 204,
+	},
+	{
+		Method: "POST", Path: "/waitlist", OperationID: "createWaitlistEntry", Tag: "waitlist",
+		Summary: "Anota a un paciente en la lista de espera", Request: models.WaitlistEntryRequest{}, Response: models.WaitlistEntryRequest{}, Status: 201,
+	},
+	{
+		Method: "GET", Path: "/waitlist", OperationID: "listWaitlist", Tag: "waitlist",
+		Summary: "Lista la lista de espera de un profesional", Query: []Param{{Name: "doctorId", Description: "Profesional", Required: true}},
+		Response: models.WaitlistEntryRequest{}, List: true, Status: 200,
+	},
+	{
+		Method: "DELETE", Path: "/waitlist/{id}", OperationID: "deleteWaitlistEntry", Tag: "waitlist",
+		Summary: "Saca una entrada de la lista de espera", Status: 204,
+	},
+	{
+		Method: "GET", Path: "/waitlist/accept", OperationID: "showWaitlistOffer", Tag: "waitlist",
+		Summary: "Página del enlace de una oferta de la lista de espera (pública)",
+		Query: []Param{
+			{Name: "offer_id", Description: "Oferta", Required: true},
+			{Name: "entry_id", Description: "Entrada de la lista de espera", Required: true},
+		},
+		ContentType: htmlContentType,
+	},
+	{
+		Method: "POST", Path: "/waitlist/accept", OperationID: "acceptWaitlistOffer", Tag: "waitlist",
+		Summary: "Acepta la oferta y reserva el turno (pública)", ContentType: htmlContentType,
+	},
+	{
+		Method: "POST", Path: "/webhooks", OperationID: "createWebhook", Tag: "webhooks",
+		Summary: "Suscribe un endpoint a eventos; es la única respuesta con el secreto",
+		Request: models.WebhookSubscriptionRequest{}, Response: models.WebhookSubscriptionRequest{}, Status: 201,
+	},
+	{
+		Method: "GET", Path: "/webhooks", OperationID: "listWebhooks", Tag: "webhooks",
+		Summary: "Lista las suscripciones de un cliente", Query: []Param{clientIDParam},
+		Response: models.WebhookSubscriptionRequest{}, List: true, Status: 200,
+	},
+	{
+		Method: "DELETE", Path: "/webhooks/{id}", OperationID: "deleteWebhook", Tag: "webhooks",
+		Summary: "Borra una suscripción", Query: []Param{clientIDParam}, Status: 204,
+	},
+	{
+		Method: "GET", Path: "/webhooks/deliveries", OperationID: "listWebhookDeliveries", Tag: "webhooks",
+		Summary:  "Registro de entregas de un cliente",
+		Query:    []Param{clientIDParam, {Name: "status", Description: "PENDING, DELIVERED o DEAD"}},
+		Response: models.WebhookDeliveryRequest{}, List: true, Status: 200,
+	},
+	{
+		Method: "POST", Path: "/webhooks/deliveries/{id}/replay", OperationID: "replayWebhookDelivery", Tag: "webhooks",
+		Summary: "Reenvía una entrega", Query: []Param{clientIDParam}, Response: models.WebhookDeliveryRequest{}, Status: 200,
+	},
+	{
+		Method: "POST", Path: "/exports", OperationID: "createExport", Tag: "exports",
+		Summary: "Pide una exportación; se genera en segundo plano", Request: models.ExportRequest{}, Response: models.ExportRequest{}, Status: 202,
+	},
+	{
+		Method: "GET", Path: "/exports/{id}", OperationID: "getExport", Tag: "exports",
+		Summary: "Estado de una exportación y, si terminó, su enlace de descarga", Response: models.ExportRequest{}, Status: 200,
+	},
+	{
+		Method: "GET", Path: "/exports/files/{key}", OperationID: "downloadExport", Tag: "exports",
+		Summary: "Descarga el archivo de una exportación desde el enlace firmado",
+		Query: []Param{
+			{Name: "expires", Description: "Vencimiento del enlace", Required: true},
+			{Name: "signature", Description: "Firma del enlace", Required: true},
+		},
+		ContentType: csvContentType,
+	},
+	{
+		Method: "POST", Path: "/calendar/feeds", OperationID: "createCalendarFeed", Tag: "calendar",
+		Summary: "Crea un feed iCalendar; es la única respuesta con la URL secreta",
+		Request: models.CalendarFeedRequest{}, Response: models.CalendarFeedRequest{}, Status: 201,
+	},
+	{
+		Method: "GET", Path: "/calendar/feeds", OperationID: "listCalendarFeeds", Tag: "calendar",
+		Summary: "Lista los feeds de un profesional o paciente",
+		Query: []Param{
+			{Name: "ownerType", Description: "doctor o patient", Required: true},
+			{Name: "ownerId", Description: "Profesional o paciente", Required: true},
+		},
+		Response: models.CalendarFeedRequest{}, List: true, Status: 200,
+	},
+	{
+		Method: "DELETE", Path: "/calendar/feeds/{id}", OperationID: "revokeCalendarFeed", Tag: "calendar",
+		Summary: "Revoca un feed", Status: 204,
+	},
+	{
+		// API Gateway no admite dos nombres de parámetro en el mismo nivel:
+		// acá {id} es el token de la URL del feed, <id>.<secreto>.ics
+		Method: "GET", Path: "/calendar/feeds/{id}", OperationID: "getCalendarFeed", Tag: "calendar",
+		Summary: "Feed iCalendar (público, autenticado por el secreto de la URL)", ContentType: ical.ContentType,
+	},
+	{
+		Method: "POST", Path: "/calendar/import", OperationID: "importCalendar", Tag: "calendar",
+		Summary: "Importa turnos de un archivo iCalendar", Query: []Param{{Name: "dryRun", Description: "true para validar sin guardar"}},
+		Request: models.CalendarImportRequest{}, Response: models.CalendarImportReport{}, Status: 200,
+	},
+	{
+		Method: "POST", Path: "/fhir/Appointment", OperationID: "fhirCreateAppointment", Tag: "fhir",
+		Summary: "Reserva un turno a partir de un recurso FHIR Appointment",
+		Query:   []Param{{Name: "clientId", Description: "Cliente, si no viene en meta.tag"}},
+		Request: fhir.Appointment{}, Response: fhir.Appointment{}, Status: 201, ContentType: fhir.ContentType,
+	},
+	{
+		Method: "GET", Path: "/fhir/Appointment", OperationID: "fhirSearchAppointments", Tag: "fhir",
+		Summary: "Busca turnos por clientId, patient, practitioner, date y status",
+		Query: []Param{
+			clientIDParam,
+			{Name: "patient", Description: "Paciente"},
+			{Name: "practitioner", Description: "Profesional"},
+			{Name: "date", Description: "Fecha con prefijo FHIR (ge, le, ...); se puede repetir"},
+			{Name: "status", Description: "Estado FHIR"},
+		},
+		Response: fhir.Bundle{}, ContentType: fhir.ContentType,
+	},
+	{
+		Method: "GET", Path: "/fhir/Appointment/{id}", OperationID: "fhirGetAppointment", Tag: "fhir",
+		Summary: "Obtiene un turno como recurso FHIR Appointment", Response: fhir.Appointment{}, ContentType: fhir.ContentType,
+	},
+	{
+		Method: "GET", Path: "/fhir/Schedule/{id}", OperationID: "fhirGetSchedule", Tag: "fhir",
+		Summary: "Agenda de un profesional como recurso FHIR Schedule", Query: []Param{clientIDParam},
+		Response: fhir.Schedule{}, ContentType: fhir.ContentType,
+	},
+	{
+		Method: "GET", Path: "/fhir/Slot", OperationID: "fhirSearchSlots", Tag: "fhir",
+		Summary: "Busca horarios libres por clientId, schedule, status y start",
+		Query: []Param{
+			clientIDParam,
+			{Name: "schedule", Description: "Agenda (profesional)", Required: true},
+			{Name: "status", Description: "free o busy"},
+			{Name: "start", Description: "Inicio con prefijo FHIR (ge, le); se puede repetir"},
+		},
+		Response: fhir.Bundle{}, ContentType: fhir.ContentType,
+	},
+	{
+		Method: "GET", Path: "/openapi.json", OperationID: "getOpenAPI", Tag: "docs",
+		Summary: "Este documento",
+	},
+}
@@ -0,0 +1,106 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Schema es el subconjunto de JSON Schema que usa la API. Se genera a
+// partir de los modelos con reflect: json da los nombres, required:"true"
+// los obligatorios, enum:"A,B" los valores posibles y format:"date-time"
+// el formato.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+const refPrefix = "#/components/schemas/"
+
+// schemas arma los componentes del documento: cada struct queda una sola
+// vez en components y el resto lo referencia con $ref.
+type schemas map[string]*Schema
+
+func (s schemas) of(v interface{}) *Schema {
+	return s.forType(reflect.TypeOf(v))
+}
+
+func (s schemas) forType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := s[name]; !ok {
+			s[name] = nil // Evita ciclos mientras se arma
+			s[name] = s.object(t)
+		}
+		return &Schema{Ref: refPrefix + name}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.forType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.forType(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		// interface{}: cualquier valor JSON
+		return &Schema{}
+	}
+}
+
+func (s schemas) object(t reflect.Type) *Schema {
+	object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.forType(field.Type)
+		if enum := field.Tag.Get("enum"); enum != "" {
+			property.Enum = strings.Split(enum, ",")
+		}
+		if format := field.Tag.Get("format"); format != "" {
+			property.Format = format
+		}
+		if field.Tag.Get("required") == "true" {
+			object.Required = append(object.Required, name)
+			if property.Type == "string" {
+				// Para el servicio un string vacío es un campo sin cargar
+				one := 1
+				property.MinLength = &one
+			}
+		}
+		object.Properties[name] = property
+	}
+	return object
+}
+
+// resolve sigue un $ref dentro de components.
+func (s schemas) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = s[strings.TrimPrefix(schema.Ref, refPrefix)]
+	}
+	return schema
+}
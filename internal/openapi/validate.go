@@ -0,0 +1,222 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/fhir"
+	"github.com/MezeLaw/iris-services/internal/response"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var ErrInvalid = errors.New("invalid request body")
+
+type Handler = func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Validate envuelve el Lambda de operationID: si el cuerpo no cumple el
+// esquema de Spec responde 400 sin llamar a next. Las operaciones sin
+// cuerpo JSON pasan directo, igual que el CSV crudo de la importación de
+// pacientes, que no tiene esquema y lo controla el handler.
+func Validate(operationID string, next Handler) Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		if strings.HasPrefix(header(req.Headers, "Content-Type"), "text/csv") {
+			return next(ctx, req)
+		}
+		body := req.Body
+		if req.IsBase64Encoded {
+			decoded, err := base64.StdEncoding.DecodeString(body)
+			if err != nil {
+				return invalid(req, operationID, ErrInvalid), nil
+			}
+			body = string(decoded)
+		}
+		if err := Spec().ValidateBody(operationID, []byte(body)); err != nil {
+			return invalid(req, operationID, err), nil
+		}
+		return next(ctx, req)
+	}
+}
+
+// invalid responde el 400 en el formato de la operación: los endpoints FHIR
+// devuelven un OperationOutcome y el resto el sobre de error de la API.
+func invalid(req events.APIGatewayProxyRequest, operationID string, err error) events.APIGatewayProxyResponse {
+	for _, route := range Routes {
+		if route.OperationID == operationID && route.ContentType == fhir.ContentType {
+			body, _ := json.Marshal(fhir.NewOperationOutcome("invalid", err.Error()))
+			return events.APIGatewayProxyResponse{
+				StatusCode: 400,
+				Body:       string(body),
+				Headers:    map[string]string{"Content-Type": fhir.ContentType},
+			}
+		}
+	}
+	return response.Error(req, 400, err.Error())
+}
+
+func header(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// ValidateBody controla body contra el esquema del cuerpo de operationID.
+// Los errores envuelven ErrInvalid y nombran el campo con su ruta JSON.
+func (d *Document) ValidateBody(operationID string, body []byte) error {
+	schema, ok := d.operations[operationID]
+	if !ok {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err := d.schemas.validate(schema, value, ""); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return nil
+}
+
+// Missing devuelve, en el orden del esquema, los campos obligatorios del
+// cuerpo de operationID que faltan o están vacíos en body. ValidateBody se
+// detiene en el primero; Missing sirve para informarlos todos juntos, como
+// en el reporte de la importación de pacientes.
+func (d *Document) Missing(operationID string, body []byte) []string {
+	schema := d.schemas.resolve(d.operations[operationID])
+	if schema == nil {
+		return nil
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return schema.Required
+	}
+	var missing []string
+	for _, name := range schema.Required {
+		value, ok := object[name]
+		if str, isString := value.(string); !ok || value == nil || (isString && str == "") {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// validate cubre lo que genera schemas: tipos, required, enum, minLength y
+// el formato date-time. Los campos que no están en el esquema se ignoran,
+// igual que al deserializar.
+func (s schemas) validate(schema *Schema, value interface{}, path string) error {
+	schema = s.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	if value == nil {
+		// null equivale a no mandar el campo; required lo controla el objeto
+		return nil
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return typeError(path, "an object")
+		}
+		for _, name := range schema.Required {
+			if v, ok := object[name]; !ok || v == nil {
+				return fmt.Errorf("%s is required", join(path, name))
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := schema.Properties[name]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			if property == nil {
+				continue
+			}
+			if err := s.validate(property, object[name], join(path, name)); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return typeError(path, "an array")
+		}
+		for i, item := range items {
+			if err := s.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return typeError(path, "a string")
+		}
+		if schema.MinLength != nil && len(str) < *schema.MinLength {
+			return fmt.Errorf("%s is required", path)
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, str) {
+			return fmt.Errorf("%s must be one of: %s", path, strings.Join(schema.Enum, ", "))
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s must be an RFC 3339 date-time", path)
+			}
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return typeError(path, "an integer")
+		}
+		if _, err := number.Int64(); err != nil {
+			return typeError(path, "an integer")
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return typeError(path, "a number")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeError(path, "a boolean")
+		}
+	}
+	return nil
+}
+
+func typeError(path, expected string) error {
+	if path == "" {
+		return fmt.Errorf("body must be %s", expected)
+	}
+	return fmt.Errorf("%s must be %s", path, expected)
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Cursores opacos para paginar consultas de DynamoDB desde la API sin
+// exponer el LastEvaluatedKey. Las claves de las tablas sólo usan atributos
+// string y number.
+
+type keyAttribute struct {
+	S string `json:"s,omitempty"`
+	N string `json:"n,omitempty"`
+}
+
+// ErrInvalidCursor se devuelve cuando el cursor no salió de Encode: la API
+// responde 400.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode convierte un LastEvaluatedKey en un cursor. Una clave vacía
+// devuelve "" (no hay más páginas).
+func Encode(key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	plain := make(map[string]keyAttribute, len(key))
+	for name, value := range key {
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			plain[name] = keyAttribute{S: v.Value}
+		case *types.AttributeValueMemberN:
+			plain[name] = keyAttribute{N: v.Value}
+		default:
+			return "", fmt.Errorf("unsupported key attribute %s", name)
+		}
+	}
+	b, err := json.Marshal(plain)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Decode convierte un cursor en ExclusiveStartKey. Un cursor vacío devuelve
+// nil (primera página).
+func Decode(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var plain map[string]keyAttribute
+	if err := json.Unmarshal(b, &plain); err != nil || len(plain) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	key := make(map[string]types.AttributeValue, len(plain))
+	for name, value := range plain {
+		if value.N != "" {
+			key[name] = &types.AttributeValueMemberN{Value: value.N}
+		} else {
+			key[name] = &types.AttributeValueMemberS{Value: value.S}
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,41 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	key := map[string]types.AttributeValue{
+		"id":        &types.AttributeValueMemberS{Value: "p1"},
+		"client_id": &types.AttributeValueMemberS{Value: "client123"},
+		"version":   &types.AttributeValueMemberN{Value: "3"},
+	}
+
+	cursor, err := Encode(key)
+	require.NoError(t, err)
+	decoded, err := Decode(cursor)
+
+	require.NoError(t, err)
+	assert.Equal(t, key, decoded)
+}
+
+func TestCursorEmpty(t *testing.T) {
+	cursor, err := Encode(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, cursor)
+
+	key, err := Decode("")
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, cursor := range []string{"%%%", "bm90LWpzb24", "e30"} {
+		_, err := Decode(cursor)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
@@ -0,0 +1,186 @@
+package phones
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Normalización de teléfonos a E.164 (+5491155551234). Cada país define
+// cuántos dígitos tiene el número nacional y cómo se quitan los prefijos de
+// discado local (el 0 de larga distancia, el 15 de los celulares
+// argentinos).
+
+var (
+	ErrInvalidNumber  = errors.New("invalid phone number")
+	ErrUnknownCountry = errors.New("unknown phone country code")
+)
+
+type country struct {
+	code string // Código de discado, sin "+"
+	iso  string
+	// national recibe los dígitos sin código de país ni ceros de discado y
+	// devuelve el número nacional significativo
+	national func(digits string) (string, error)
+}
+
+var countries = []country{
+	{code: "54", iso: "AR", national: argentina},
+	{code: "55", iso: "BR", national: length(10, 11)},
+	{code: "56", iso: "CL", national: length(9)},
+	{code: "598", iso: "UY", national: length(8)},
+	{code: "595", iso: "PY", national: length(9)},
+	{code: "591", iso: "BO", national: length(8)},
+	{code: "51", iso: "PE", national: length(8, 9)},
+	{code: "57", iso: "CO", national: length(10)},
+	{code: "52", iso: "MX", national: length(10)},
+	{code: "34", iso: "ES", national: length(9)},
+	{code: "1", iso: "US", national: length(10)},
+}
+
+// Normalize devuelve el número en E.164. countryCode puede venir como "54",
+// "+54" o "AR"; si number ya empieza con "+" o "00" se toma el código de
+// país del número.
+func Normalize(countryCode, number string) (string, error) {
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalidNumber)
+	}
+	international := strings.HasPrefix(number, "+") || strings.HasPrefix(number, "00")
+	digits := onlyDigits(number)
+	if digits == "" {
+		return "", fmt.Errorf("%w: %q has no digits", ErrInvalidNumber, number)
+	}
+
+	if international {
+		digits = strings.TrimPrefix(digits, "00")
+		for _, c := range countries {
+			if strings.HasPrefix(digits, c.code) {
+				return build(c, strings.TrimPrefix(digits, c.code), number)
+			}
+		}
+		// País sin reglas propias: sólo se controla el largo de E.164
+		if len(digits) < 8 || len(digits) > 15 {
+			return "", fmt.Errorf("%w: %q", ErrInvalidNumber, number)
+		}
+		return "+" + digits, nil
+	}
+
+	c, ok := lookup(countryCode)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownCountry, countryCode)
+	}
+	// Hay quien escribe el código de país sin "+" en el número
+	if national, err := c.national(strings.TrimPrefix(digits, c.code)); err == nil && strings.HasPrefix(digits, c.code) && !strings.HasPrefix(digits, "0") {
+		return "+" + c.code + national, nil
+	}
+	return build(c, strings.TrimLeft(digits, "0"), number)
+}
+
+// CountryCode devuelve el código de discado de un número E.164, o "" si no
+// corresponde a ninguno de los países conocidos.
+func CountryCode(e164 string) string {
+	digits := strings.TrimPrefix(e164, "+")
+	for _, c := range countries {
+		if strings.HasPrefix(digits, c.code) {
+			return c.code
+		}
+	}
+	return ""
+}
+
+// Split separa un número E.164 en código de país y número nacional.
+func Split(e164 string) (string, string) {
+	code := CountryCode(e164)
+	return code, strings.TrimPrefix(strings.TrimPrefix(e164, "+"), code)
+}
+
+func build(c country, digits, original string) (string, error) {
+	national, err := c.national(digits)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q for %s: %v", ErrInvalidNumber, original, c.iso, err)
+	}
+	return "+" + c.code + national, nil
+}
+
+func lookup(countryCode string) (country, bool) {
+	code := strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(countryCode), "+"))
+	for _, c := range countries {
+		if c.code == code || c.iso == code {
+			return c, true
+		}
+	}
+	return country{}, false
+}
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func length(valid ...int) func(string) (string, error) {
+	return func(digits string) (string, error) {
+		for _, n := range valid {
+			if len(digits) == n {
+				return digits, nil
+			}
+		}
+		return "", fmt.Errorf("expected %v digits, got %d", valid, len(digits))
+	}
+}
+
+// argentina maneja los celulares: en E.164 llevan un 9 después del código
+// de país y no llevan el 15 que se marca localmente después del código de
+// área ("011 15 5555-1234" → +54 9 11 5555-1234).
+func argentina(digits string) (string, error) {
+	switch {
+	case len(digits) == 10:
+		// Fijo, o celular escrito sin 9 ni 15: no hay forma de distinguirlos.
+		// Los códigos de área empiezan con 11, 2 o 3
+		if !strings.HasPrefix(digits, "11") && digits[0] != '2' && digits[0] != '3' {
+			return "", fmt.Errorf("unknown area code")
+		}
+		return digits, nil
+	case len(digits) == 11 && digits[0] == '9':
+		return digits, nil
+	case len(digits) == 12:
+		// Código de área (2 a 4 dígitos) + 15 + abonado; el área de Buenos
+		// Aires es la única de 2 dígitos
+		areaLengths := []int{3, 4}
+		if strings.HasPrefix(digits, "11") {
+			areaLengths = []int{2}
+		}
+		for _, n := range areaLengths {
+			if digits[n:n+2] == "15" {
+				return "9" + digits[:n] + digits[n+2:], nil
+			}
+		}
+		return "", fmt.Errorf("12 digits without a 15 mobile prefix")
+	case len(digits) == 13 && digits[0] == '9':
+		// +54 9 11 15 5555-1234: 9 y 15 a la vez
+		if national, err := argentina(digits[1:]); err == nil && national[0] == '9' {
+			return national, nil
+		}
+	}
+	return "", fmt.Errorf("expected 10 digits with area code, got %d", len(digits))
+}
+
+// Variants devuelve las formas en dígitos con las que se puede buscar un
+// número E.164: completo, sin código de país y, para los celulares
+// argentinos, también sin el 9 (como se los marca localmente).
+func Variants(e164 string) []string {
+	code, national := Split(e164)
+	if code == "" {
+		return []string{onlyDigits(e164)}
+	}
+	variants := []string{code + national, national}
+	if code == "54" && len(national) == 11 && national[0] == '9' {
+		variants = append(variants, national[1:])
+	}
+	return variants
+}
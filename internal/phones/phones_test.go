@@ -0,0 +1,61 @@
+package phones
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		countryCode, number, want string
+	}{
+		{"54", "+54 9 11 5555-1234", "+5491155551234"},
+		{"54", "011 15 5555-1234", "+5491155551234"},
+		{"+54", "11 15 5555 1234", "+5491155551234"},
+		{"AR", "(0341) 15-555-1234", "+5493415551234"},
+		{"54", "02966 15 55-1234", "+5492966551234"},
+		{"54", "011 4321-1234", "+541143211234"},
+		{"54", "54 9 11 5555 1234", "+5491155551234"},
+		{"54", "+54 9 11 15 5555 1234", "+5491155551234"},
+		{"", "0054 9 11 5555 1234", "+5491155551234"},
+		{"55", "(11) 91234-5678", "+5511912345678"},
+		{"56", "9 8765 4321", "+56987654321"},
+		{"UY", "099 123 456", "+59899123456"},
+		{"54", "+1 (415) 555-2671", "+14155552671"},
+		{"54", "+44 20 7946 0958", "+442079460958"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.countryCode, tt.number)
+		if assert.NoError(t, err, tt.number) {
+			assert.Equal(t, tt.want, got, tt.number)
+		}
+	}
+}
+
+func TestNormalize_Invalid(t *testing.T) {
+	for _, tt := range []struct{ countryCode, number string }{
+		{"54", "15 5555-1234"},
+		{"54", "123"},
+		{"56", "1234"},
+		{"54", "sin teléfono"},
+		{"54", ""},
+	} {
+		_, err := Normalize(tt.countryCode, tt.number)
+		assert.ErrorIs(t, err, ErrInvalidNumber, tt.number)
+	}
+
+	_, err := Normalize("999", "1234567")
+	assert.ErrorIs(t, err, ErrUnknownCountry)
+}
+
+func TestSplit(t *testing.T) {
+	code, national := Split("+5491155551234")
+	assert.Equal(t, "54", code)
+	assert.Equal(t, "91155551234", national)
+}
+
+func TestVariants(t *testing.T) {
+	assert.Equal(t, []string{"5491155551234", "91155551234", "1155551234"}, Variants("+5491155551234"))
+	assert.Equal(t, []string{"56987654321", "987654321"}, Variants("+56987654321"))
+}
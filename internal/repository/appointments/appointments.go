@@ -2,11 +2,20 @@ package repository
 
 import (
 	"context"
+	"errors"
 
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/logging"
 	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/pagination"
+	outbox "github.com/MezeLaw/iris-services/internal/repository/outbox"
+	patch "github.com/MezeLaw/iris-services/internal/repository/patch"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"go.uber.org/zap"
 )
 
@@ -17,6 +26,17 @@ type AppointmentsRepository interface {
 	GetByPatientID(ctx context.Context, patientID string) ([]*models.Appointment, error)
 	GetByDoctorID(ctx context.Context, doctorID string) ([]*models.Appointment, error)
 	Delete(ctx context.Context, id string) error
+	GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Appointment, string, error)
+	GetByClientIDBetween(ctx context.Context, clientID, from, to string) ([]*models.Appointment, error)
+	GetDueBetween(ctx context.Context, from, to, cursor string) ([]*models.Appointment, string, error)
+	ClaimReminder(ctx context.Context, id, window string) (bool, error)
+	ReleaseReminder(ctx context.Context, id, window string) error
+	MarkNoShow(ctx context.Context, id, updatedAt string, evts []*events.Event) (bool, error)
+	UseActionToken(ctx context.Context, id, nonce string) (bool, error)
+	ReleaseActionToken(ctx context.Context, id, nonce string) error
+	SaveWithEvents(ctx context.Context, a *models.Appointment, evts []*events.Event) error
+	DeleteWithEvents(ctx context.Context, id string, evts []*events.Event) error
+	Patch(ctx context.Context, before, after *models.Appointment, evts []*events.Event) (bool, error)
 }
 
 type DynamoDBClient interface {
@@ -24,6 +44,9 @@ type DynamoDBClient interface {
 	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
 	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
 }
 
 type DynamoAppointmentsRepository struct {
@@ -33,6 +56,9 @@ type DynamoAppointmentsRepository struct {
 	ClientIDIndex  string
 	PatientIDIndex string
 	DoctorIDIndex  string
+	// OutboxTableName es la tabla donde SaveWithEvents y DeleteWithEvents
+	// escriben los eventos. Si está vacía los eventos se descartan.
+	OutboxTableName string
 }
 
 func New(client DynamoDBClient, logger *zap.SugaredLogger, tableName, clientIDIndex, patientIDIndex, doctorIDIndex string) AppointmentsRepository {
@@ -46,10 +72,26 @@ func New(client DynamoDBClient, logger *zap.SugaredLogger, tableName, clientIDIn
 	}
 }
 
+// NewWithOutbox es New con los eventos de dominio escritos en
+// outboxTableName.
+func NewWithOutbox(client DynamoDBClient, logger *zap.SugaredLogger, tableName, clientIDIndex, patientIDIndex, doctorIDIndex, outboxTableName string) AppointmentsRepository {
+	return &DynamoAppointmentsRepository{
+		Client:          client,
+		Logger:          logger,
+		TableName:       tableName,
+		ClientIDIndex:   clientIDIndex,
+		PatientIDIndex:  patientIDIndex,
+		DoctorIDIndex:   doctorIDIndex,
+		OutboxTableName: outboxTableName,
+	}
+}
+
 func (d *DynamoAppointmentsRepository) Save(ctx context.Context, a *models.Appointment) error {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.Save")
+	defer span.End()
 	item, err := attributevalue.MarshalMap(a)
 	if err != nil {
-		d.Logger.Errorw("error marshalling appointment", "error", err)
+		d.log(ctx).Errorw("error marshalling appointment", "error", err)
 		return err
 	}
 	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
@@ -59,7 +101,98 @@ func (d *DynamoAppointmentsRepository) Save(ctx context.Context, a *models.Appoi
 	return err
 }
 
+// SaveWithEvents guarda el turno y sus eventos en una sola transacción.
+func (d *DynamoAppointmentsRepository) SaveWithEvents(ctx context.Context, a *models.Appointment, evts []*events.Event) error {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.SaveWithEvents")
+	defer span.End()
+	if d.OutboxTableName == "" || len(evts) == 0 {
+		return d.Save(ctx, a)
+	}
+	item, err := attributevalue.MarshalMap(a)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling appointment", "error", err)
+		return err
+	}
+	return d.transact(ctx, types.TransactWriteItem{Put: &types.Put{TableName: &d.TableName, Item: item}}, evts)
+}
+
+// DeleteWithEvents borra el turno y guarda sus eventos en una sola
+// transacción.
+func (d *DynamoAppointmentsRepository) DeleteWithEvents(ctx context.Context, id string, evts []*events.Event) error {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.DeleteWithEvents")
+	defer span.End()
+	if d.OutboxTableName == "" || len(evts) == 0 {
+		return d.Delete(ctx, id)
+	}
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	return d.transact(ctx, types.TransactWriteItem{Delete: &types.Delete{TableName: &d.TableName, Key: key}}, evts)
+}
+
+// Patch escribe sólo los atributos que cambian de before a after con un
+// UpdateItem, y los eventos en la misma transacción si hay outbox. Se
+// condiciona a que el turno siga en la secuencia de before: si otro proceso
+// lo modificó en el medio devuelve false y no escribe nada.
+func (d *DynamoAppointmentsRepository) Patch(ctx context.Context, before, after *models.Appointment, evts []*events.Event) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.Patch")
+	defer span.End()
+	update, changed, err := patch.Update(before, after, "id")
+	if err != nil {
+		d.log(ctx).Errorw("error building appointment update", "error", err)
+		return false, err
+	}
+	if !changed {
+		return true, nil
+	}
+	cond := patch.Version("sequence", before.Sequence)
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return false, err
+	}
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": before.ID})
+
+	if d.OutboxTableName != "" && len(evts) > 0 {
+		err = d.transact(ctx, types.TransactWriteItem{Update: &types.Update{
+			TableName:                 &d.TableName,
+			Key:                       key,
+			UpdateExpression:          expr.Update(),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}}, evts)
+	} else {
+		_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 &d.TableName,
+			Key:                       key,
+			UpdateExpression:          expr.Update(),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		})
+	}
+	if patch.ConditionFailed(err) {
+		d.log(ctx).Infow("appointment changed while patching", "id", before.ID)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (d *DynamoAppointmentsRepository) transact(ctx context.Context, write types.TransactWriteItem, evts []*events.Event) error {
+	puts, err := outbox.Puts(d.OutboxTableName, evts)
+	if err != nil {
+		return err
+	}
+	_, err = d.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: append([]types.TransactWriteItem{write}, puts...),
+	})
+	return err
+}
+
 func (d *DynamoAppointmentsRepository) GetByID(ctx context.Context, id string) (*models.Appointment, error) {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.GetByID")
+	defer span.End()
 	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
 	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
 		TableName: &d.TableName,
@@ -76,6 +209,8 @@ func (d *DynamoAppointmentsRepository) GetByID(ctx context.Context, id string) (
 }
 
 func (d *DynamoAppointmentsRepository) GetByClientID(ctx context.Context, clientID string) ([]*models.Appointment, error) {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.GetByClientID")
+	defer span.End()
 	keyCond := expression.Key("client_id").Equal(expression.Value(clientID))
 	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
 
@@ -97,7 +232,45 @@ func (d *DynamoAppointmentsRepository) GetByClientID(ctx context.Context, client
 	return results, nil
 }
 
+// GetPageByClientID devuelve una página de hasta limit turnos del cliente y
+// el cursor de la siguiente ("" cuando no hay más).
+func (d *DynamoAppointmentsRepository) GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Appointment, string, error) {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.GetPageByClientID")
+	defer span.End()
+	startKey, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	keyCond := expression.Key("client_id").Equal(expression.Value(clientID))
+	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+
+	resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 &d.TableName,
+		IndexName:                 &d.ClientIDIndex,
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ExclusiveStartKey:         startKey,
+		Limit:                     aws.Int32(limit),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var results []*models.Appointment
+	if err := attributevalue.UnmarshalListOfMaps(resp.Items, &results); err != nil {
+		return nil, "", err
+	}
+	next, err := pagination.Encode(resp.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return results, next, nil
+}
+
 func (d *DynamoAppointmentsRepository) GetByPatientID(ctx context.Context, patientID string) ([]*models.Appointment, error) {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.GetByPatientID")
+	defer span.End()
 	keyCond := expression.Key("patient_id").Equal(expression.Value(patientID))
 	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
 
@@ -120,6 +293,8 @@ func (d *DynamoAppointmentsRepository) GetByPatientID(ctx context.Context, patie
 }
 
 func (d *DynamoAppointmentsRepository) GetByDoctorID(ctx context.Context, doctorID string) ([]*models.Appointment, error) {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.GetByDoctorID")
+	defer span.End()
 	keyCond := expression.Key("doctor_id").Equal(expression.Value(doctorID))
 	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
 
@@ -142,6 +317,8 @@ func (d *DynamoAppointmentsRepository) GetByDoctorID(ctx context.Context, doctor
 }
 
 func (d *DynamoAppointmentsRepository) Delete(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.Delete")
+	defer span.End()
 	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
 	_, err := d.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
 		TableName: &d.TableName,
@@ -149,3 +326,219 @@ func (d *DynamoAppointmentsRepository) Delete(ctx context.Context, id string) er
 	})
 	return err
 }
+
+// GetByClientIDBetween devuelve los turnos del cliente con fecha en [from, to).
+// Las fechas se comparan como strings RFC3339: un turno guardado con otro
+// offset puede quedar corrido unas horas, así que quien llama tiene que
+// ampliar el rango y filtrar.
+func (d *DynamoAppointmentsRepository) GetByClientIDBetween(ctx context.Context, clientID, from, to string) ([]*models.Appointment, error) {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.GetByClientIDBetween")
+	defer span.End()
+	keyCond := expression.Key("client_id").Equal(expression.Value(clientID))
+	filter := expression.Name("date").GreaterThanEqual(expression.Value(from)).
+		And(expression.Name("date").LessThan(expression.Value(to)))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).WithFilter(filter).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*models.Appointment
+	var startKey map[string]types.AttributeValue
+	for {
+		resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 &d.TableName,
+			IndexName:                 &d.ClientIDIndex,
+			KeyConditionExpression:    expr.KeyCondition(),
+			FilterExpression:          expr.Filter(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var page []*models.Appointment
+		if err := attributevalue.UnmarshalListOfMaps(resp.Items, &page); err != nil {
+			return nil, err
+		}
+		results = append(results, page...)
+		if len(resp.LastEvaluatedKey) == 0 {
+			return results, nil
+		}
+		startKey = resp.LastEvaluatedKey
+	}
+}
+
+// GetDueBetween devuelve una página de turnos SCHEDULED o CONFIRMED de todos
+// los clientes con fecha en [from, to) y el cursor de la siguiente ("" cuando
+// no hay más). Recorre la tabla con un Scan filtrado porque no hay índice por
+// fecha sin cliente; lo usa sólo la marca de ausencias programada. Como en
+// GetByClientIDBetween, quien llama tiene que ampliar el rango por el offset.
+func (d *DynamoAppointmentsRepository) GetDueBetween(ctx context.Context, from, to, cursor string) ([]*models.Appointment, string, error) {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.GetDueBetween")
+	defer span.End()
+	startKey, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	filter := expression.Name("date").GreaterThanEqual(expression.Value(from)).
+		And(expression.Name("date").LessThan(expression.Value(to))).
+		And(expression.Name("status").In(
+			expression.Value(models.AppointmentStatusScheduled),
+			expression.Value(models.AppointmentStatusConfirmed)))
+	expr, err := expression.NewBuilder().WithFilter(filter).Build()
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := d.Client.Scan(ctx, &dynamodb.ScanInput{
+		TableName:                 &d.TableName,
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ExclusiveStartKey:         startKey,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var results []*models.Appointment
+	if err := attributevalue.UnmarshalListOfMaps(resp.Items, &results); err != nil {
+		return nil, "", err
+	}
+	next, err := pagination.Encode(resp.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return results, next, nil
+}
+
+// ClaimReminder marca la ventana de recordatorio como enviada sólo si no lo
+// estaba. Devuelve false si otra corrida ya la había marcado, así dos
+// ejecuciones superpuestas no mandan el mismo recordatorio.
+func (d *DynamoAppointmentsRepository) ClaimReminder(ctx context.Context, id, window string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.ClaimReminder")
+	defer span.End()
+	return d.addToSet(ctx, id, "reminders_sent", window)
+}
+
+// ReleaseReminder deshace ClaimReminder cuando el envío falló, para que la
+// próxima corrida lo reintente.
+func (d *DynamoAppointmentsRepository) ReleaseReminder(ctx context.Context, id, window string) error {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.ReleaseReminder")
+	defer span.End()
+	return d.deleteFromSet(ctx, id, "reminders_sent", window)
+}
+
+// MarkNoShow pasa el turno a NO_SHOW sólo si sigue SCHEDULED o CONFIRMED, y
+// guarda sus eventos en la misma transacción si hay outbox. Devuelve false si
+// alguien lo cambió antes (llegó el paciente, se canceló o ya lo marcó otra
+// corrida), así la ausencia no se cuenta dos veces.
+func (d *DynamoAppointmentsRepository) MarkNoShow(ctx context.Context, id, updatedAt string, evts []*events.Event) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.MarkNoShow")
+	defer span.End()
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	update := expression.Set(expression.Name("status"), expression.Value(models.AppointmentStatusNoShow)).
+		Set(expression.Name("updated_at"), expression.Value(updatedAt)).
+		Add(expression.Name("sequence"), expression.Value(1))
+	cond := expression.Name("status").In(
+		expression.Value(models.AppointmentStatusScheduled),
+		expression.Value(models.AppointmentStatusConfirmed))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return false, err
+	}
+
+	if d.OutboxTableName != "" && len(evts) > 0 {
+		err = d.transact(ctx, types.TransactWriteItem{Update: &types.Update{
+			TableName:                 &d.TableName,
+			Key:                       key,
+			UpdateExpression:          expr.Update(),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}}, evts)
+	} else {
+		_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 &d.TableName,
+			Key:                       key,
+			UpdateExpression:          expr.Update(),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		})
+	}
+	if patch.ConditionFailed(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UseActionToken registra el nonce de un enlace de confirmación o
+// cancelación. Devuelve false si ya se había usado.
+func (d *DynamoAppointmentsRepository) UseActionToken(ctx context.Context, id, nonce string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.UseActionToken")
+	defer span.End()
+	return d.addToSet(ctx, id, "action_tokens_used", nonce)
+}
+
+// ReleaseActionToken deshace UseActionToken si la acción no se pudo aplicar.
+func (d *DynamoAppointmentsRepository) ReleaseActionToken(ctx context.Context, id, nonce string) error {
+	ctx, span := tracing.Start(ctx, "repository.Appointments.ReleaseActionToken")
+	defer span.End()
+	return d.deleteFromSet(ctx, id, "action_tokens_used", nonce)
+}
+
+// addToSet agrega value al string set attribute del turno sólo si no estaba.
+func (d *DynamoAppointmentsRepository) addToSet(ctx context.Context, id, attribute, value string) (bool, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	update := expression.Add(expression.Name(attribute), expression.Value(types.AttributeValueMemberSS{Value: []string{value}}))
+	cond := expression.AttributeExists(expression.Name("id")).
+		And(expression.Not(expression.Contains(expression.Name(attribute), value)))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &d.TableName,
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (d *DynamoAppointmentsRepository) deleteFromSet(ctx context.Context, id, attribute, value string) error {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	update := expression.Delete(expression.Name(attribute), expression.Value(types.AttributeValueMemberSS{Value: []string{value}}))
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return err
+	}
+	_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &d.TableName,
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	return err
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del repositorio.
+func (d *DynamoAppointmentsRepository) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, d.Logger)
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockDynamoDBClient struct {
+	mock.Mock
+}
+
+func (m *MockDynamoDBClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.PutItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.ScanOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.GetItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.DeleteItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.QueryOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.UpdateItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.TransactWriteItemsOutput), args.Error(1)
+}
+
+func legacyAppointment() *models.Appointment {
+	// Guardado antes de que existiera sequence: el item no tiene el atributo
+	return &models.Appointment{ID: "a1", ClientID: "c1", PatientID: "p1", DoctorID: "d1", Date: "2024-01-15T10:00:00Z", Duration: 30, Status: models.AppointmentStatusScheduled}
+}
+
+func TestPatch_WithoutSequence(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := New(mockClient, zap.NewNop().Sugar(), "appointments", "client_id_index", "patient_id_index", "doctor_id_index")
+	before := legacyAppointment()
+	after := *before
+	after.Notes, after.Sequence = "Trae estudios", 1
+
+	var input *dynamodb.UpdateItemInput
+	mockClient.On("UpdateItem", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		input = args.Get(1).(*dynamodb.UpdateItemInput)
+	}).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+
+	ok, err := repo.Patch(context.Background(), before, &after, nil)
+
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "(attribute_not_exists (#0)) OR (#0 = :0)", aws.ToString(input.ConditionExpression))
+	assert.Equal(t, "sequence", input.ExpressionAttributeNames["#0"])
+	mockClient.AssertExpectations(t)
+}
+
+func TestPatch_SequenceConflict(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := NewWithOutbox(mockClient, zap.NewNop().Sugar(), "appointments", "client_id_index", "patient_id_index", "doctor_id_index", "outbox")
+	before := legacyAppointment()
+	before.Sequence = 2
+	after := *before
+	after.Status, after.Sequence = models.AppointmentStatusConfirmed, 3
+	event := &events.Event{ID: "evt1", Type: events.AppointmentUpdated, AggregateID: "a1", Data: []byte(`{"id":"a1"}`)}
+
+	mockClient.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		update := input.TransactItems[0].Update
+		return len(input.TransactItems) == 2 && update != nil && aws.ToString(update.ConditionExpression) == "#0 = :0"
+	})).Return(nil, &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+		{Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")},
+	}}).Once()
+
+	ok, err := repo.Patch(context.Background(), before, &after, []*events.Event{event})
+
+	require.NoError(t, err)
+	assert.False(t, ok)
+	mockClient.AssertExpectations(t)
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"go.uber.org/zap"
+)
+
+type CalendarFeedsRepository interface {
+	Save(ctx context.Context, f *models.CalendarFeed) error
+	GetByID(ctx context.Context, id string) (*models.CalendarFeed, error)
+	GetByOwner(ctx context.Context, ownerType, ownerID string) ([]*models.CalendarFeed, error)
+}
+
+type DynamoDBClient interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+}
+
+type DynamoCalendarFeedsRepository struct {
+	Client        DynamoDBClient
+	Logger        *zap.SugaredLogger
+	TableName     string
+	OwnerKeyIndex string
+}
+
+func New(client DynamoDBClient, logger *zap.SugaredLogger, tableName, ownerKeyIndex string) CalendarFeedsRepository {
+	return &DynamoCalendarFeedsRepository{
+		Client:        client,
+		Logger:        logger,
+		TableName:     tableName,
+		OwnerKeyIndex: ownerKeyIndex,
+	}
+}
+
+func ownerKey(ownerType, ownerID string) string {
+	return fmt.Sprintf("%s#%s", ownerType, ownerID)
+}
+
+func (d *DynamoCalendarFeedsRepository) Save(ctx context.Context, f *models.CalendarFeed) error {
+	f.OwnerKey = ownerKey(f.OwnerType, f.OwnerID)
+	item, err := attributevalue.MarshalMap(f)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling calendar feed", "error", err)
+		return err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &d.TableName,
+		Item:      item,
+	})
+	return err
+}
+
+func (d *DynamoCalendarFeedsRepository) GetByID(ctx context.Context, id string) (*models.CalendarFeed, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &d.TableName,
+		Key:       key,
+	})
+	if err != nil || resp.Item == nil {
+		return nil, err
+	}
+	var feed models.CalendarFeed
+	if err := attributevalue.UnmarshalMap(resp.Item, &feed); err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (d *DynamoCalendarFeedsRepository) GetByOwner(ctx context.Context, ownerType, ownerID string) ([]*models.CalendarFeed, error) {
+	keyCond := expression.Key("owner_key").Equal(expression.Value(ownerKey(ownerType, ownerID)))
+	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+
+	resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 &d.TableName,
+		IndexName:                 &d.OwnerKeyIndex,
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*models.CalendarFeed
+	if err := attributevalue.UnmarshalListOfMaps(resp.Items, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del repositorio.
+func (d *DynamoCalendarFeedsRepository) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, d.Logger)
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Client envuelve el cliente de DynamoDB de los repositorios de pacientes,
+// turnos y reservas de horario: pide ReturnConsumedCapacity en cada llamada
+// y publica las unidades consumidas por tabla y operación. Lo usa todo main
+// que arma alguno de esos repositorios, incluidos los workers.
+
+type DynamoDBClient interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+type Client struct {
+	Client  DynamoDBClient
+	Metrics metrics.Metrics
+}
+
+func New(client DynamoDBClient, m metrics.Metrics) *Client {
+	return &Client{Client: client, Metrics: m}
+}
+
+func (c *Client) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	input := *params
+	input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	out, err := c.Client.PutItem(ctx, &input, optFns...)
+	if out != nil {
+		c.record("PutItem", out.ConsumedCapacity)
+	}
+	return out, err
+}
+
+func (c *Client) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	input := *params
+	input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	out, err := c.Client.GetItem(ctx, &input, optFns...)
+	if out != nil {
+		c.record("GetItem", out.ConsumedCapacity)
+	}
+	return out, err
+}
+
+func (c *Client) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	input := *params
+	input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	out, err := c.Client.DeleteItem(ctx, &input, optFns...)
+	if out != nil {
+		c.record("DeleteItem", out.ConsumedCapacity)
+	}
+	return out, err
+}
+
+func (c *Client) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	input := *params
+	input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	out, err := c.Client.Query(ctx, &input, optFns...)
+	if out != nil {
+		c.record("Query", out.ConsumedCapacity)
+	}
+	return out, err
+}
+
+func (c *Client) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	input := *params
+	input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	out, err := c.Client.Scan(ctx, &input, optFns...)
+	if out != nil {
+		c.record("Scan", out.ConsumedCapacity)
+	}
+	return out, err
+}
+
+func (c *Client) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	input := *params
+	input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	out, err := c.Client.BatchGetItem(ctx, &input, optFns...)
+	if out != nil {
+		for i := range out.ConsumedCapacity {
+			c.record("BatchGetItem", &out.ConsumedCapacity[i])
+		}
+	}
+	return out, err
+}
+
+func (c *Client) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	input := *params
+	input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	out, err := c.Client.BatchWriteItem(ctx, &input, optFns...)
+	if out != nil {
+		for i := range out.ConsumedCapacity {
+			c.record("BatchWriteItem", &out.ConsumedCapacity[i])
+		}
+	}
+	return out, err
+}
+
+func (c *Client) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	input := *params
+	input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	out, err := c.Client.UpdateItem(ctx, &input, optFns...)
+	if out != nil {
+		c.record("UpdateItem", out.ConsumedCapacity)
+	}
+	return out, err
+}
+
+func (c *Client) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	input := *params
+	input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	out, err := c.Client.TransactWriteItems(ctx, &input, optFns...)
+	if out != nil {
+		// Una entrada por tabla de la transacción (incluido el outbox)
+		for i := range out.ConsumedCapacity {
+			c.record("TransactWriteItems", &out.ConsumedCapacity[i])
+		}
+	}
+	return out, err
+}
+
+func (c *Client) record(operation string, consumed *types.ConsumedCapacity) {
+	if consumed == nil || consumed.CapacityUnits == nil {
+		return
+	}
+	table := ""
+	if consumed.TableName != nil {
+		table = *consumed.TableName
+	}
+	c.Metrics.Put(metrics.ConsumedCapacity, *consumed.CapacityUnits, metrics.None, metrics.Dimensions{
+		metrics.DimTable:     table,
+		metrics.DimOperation: operation,
+	})
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDynamoDBClient struct {
+	mock.Mock
+	DynamoDBClient
+}
+
+func (m *MockDynamoDBClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.PutItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.TransactWriteItemsOutput), args.Error(1)
+}
+
+type MockMetrics struct {
+	mock.Mock
+}
+
+func (m *MockMetrics) Put(name string, value float64, unit metrics.Unit, dimensions metrics.Dimensions) {
+	m.Called(name, value, unit, dimensions)
+}
+
+func consumed(table string, units float64) types.ConsumedCapacity {
+	return types.ConsumedCapacity{TableName: aws.String(table), CapacityUnits: aws.Float64(units)}
+}
+
+func TestClient_PutItem(t *testing.T) {
+	inner, m := new(MockDynamoDBClient), new(MockMetrics)
+	c := New(inner, m)
+	ctx := context.Background()
+	input := &dynamodb.PutItemInput{TableName: aws.String("PatientsTable")}
+	capacity := consumed("PatientsTable", 1)
+	inner.On("PutItem", ctx, mock.MatchedBy(func(in *dynamodb.PutItemInput) bool {
+		return in.ReturnConsumedCapacity == types.ReturnConsumedCapacityTotal
+	})).Return(&dynamodb.PutItemOutput{ConsumedCapacity: &capacity}, nil)
+	m.On("Put", metrics.ConsumedCapacity, float64(1), metrics.None,
+		metrics.Dimensions{metrics.DimTable: "PatientsTable", metrics.DimOperation: "PutItem"}).Return()
+
+	_, err := c.PutItem(ctx, input)
+
+	require.NoError(t, err)
+	assert.Empty(t, input.ReturnConsumedCapacity, "no modifica el input del repositorio")
+	inner.AssertExpectations(t)
+	m.AssertExpectations(t)
+}
+
+func TestClient_TransactWriteItems(t *testing.T) {
+	inner, m := new(MockDynamoDBClient), new(MockMetrics)
+	c := New(inner, m)
+	ctx := context.Background()
+	inner.On("TransactWriteItems", ctx, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{
+		ConsumedCapacity: []types.ConsumedCapacity{consumed("AppointmentsTable", 2), consumed("OutboxTable", 2)},
+	}, nil)
+	m.On("Put", metrics.ConsumedCapacity, float64(2), metrics.None, mock.Anything).Return().Twice()
+
+	_, err := c.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{})
+
+	require.NoError(t, err)
+	m.AssertExpectations(t)
+}
+
+func TestClient_Error(t *testing.T) {
+	inner, m := new(MockDynamoDBClient), new(MockMetrics)
+	c := New(inner, m)
+	ctx := context.Background()
+	inner.On("PutItem", ctx, mock.Anything).Return(nil, assert.AnError)
+
+	_, err := c.PutItem(ctx, &dynamodb.PutItemInput{})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	m.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
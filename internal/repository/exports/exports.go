@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	patch "github.com/MezeLaw/iris-services/internal/repository/patch"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"go.uber.org/zap"
+)
+
+type ExportsRepository interface {
+	Save(ctx context.Context, job *models.ExportJob) error
+	GetByID(ctx context.Context, id string) (*models.ExportJob, error)
+	Claim(ctx context.Context, id, key string, now, leaseUntil int64) (bool, error)
+	Finish(ctx context.Context, job *models.ExportJob, leaseUntil int64) (bool, error)
+}
+
+type DynamoDBClient interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+type DynamoExportsRepository struct {
+	Client    DynamoDBClient
+	Logger    *zap.SugaredLogger
+	TableName string
+}
+
+func New(client DynamoDBClient, logger *zap.SugaredLogger, tableName string) ExportsRepository {
+	return &DynamoExportsRepository{
+		Client:    client,
+		Logger:    logger,
+		TableName: tableName,
+	}
+}
+
+func (d *DynamoExportsRepository) Save(ctx context.Context, job *models.ExportJob) error {
+	item, err := attributevalue.MarshalMap(job)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling export job", "error", err)
+		return err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &d.TableName,
+		Item:      item,
+	})
+	return err
+}
+
+func (d *DynamoExportsRepository) GetByID(ctx context.Context, id string) (*models.ExportJob, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &d.TableName,
+		Key:       key,
+	})
+	if err != nil || resp.Item == nil {
+		return nil, err
+	}
+	var job models.ExportJob
+	if err := attributevalue.UnmarshalMap(resp.Item, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Claim toma la exportación para generarla: la pasa a RUNNING con un lease
+// hasta leaseUntil y suma un intento, sólo si está PENDING o si quedó RUNNING
+// con el lease vencido antes de now (el worker anterior se cortó). Devuelve
+// false si otro worker la tiene tomada o ya terminó.
+func (d *DynamoExportsRepository) Claim(ctx context.Context, id, key string, now, leaseUntil int64) (bool, error) {
+	update := expression.Set(expression.Name("status"), expression.Value(models.ExportStatusRunning)).
+		Set(expression.Name("key"), expression.Value(key)).
+		Set(expression.Name("lease_until"), expression.Value(leaseUntil)).
+		Add(expression.Name("attempts"), expression.Value(1))
+	cond := expression.Name("status").Equal(expression.Value(models.ExportStatusPending)).
+		Or(expression.Name("status").Equal(expression.Value(models.ExportStatusRunning)).
+			And(expression.Name("lease_until").LessThan(expression.Value(now))))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return false, err
+	}
+	keyAttrs, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &d.TableName,
+		Key:                       keyAttrs,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if patch.ConditionFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Finish guarda el resultado sólo si la exportación sigue con el lease
+// leaseUntil: si el worker tardó más que el lease y otro la retomó, el
+// resultado de este se descarta y devuelve false.
+func (d *DynamoExportsRepository) Finish(ctx context.Context, job *models.ExportJob, leaseUntil int64) (bool, error) {
+	item, err := attributevalue.MarshalMap(job)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling export job", "error", err)
+		return false, err
+	}
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.Name("lease_until").Equal(expression.Value(leaseUntil))).
+		Build()
+	if err != nil {
+		return false, err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 &d.TableName,
+		Item:                      item,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if patch.ConditionFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del repositorio.
+func (d *DynamoExportsRepository) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, d.Logger)
+}
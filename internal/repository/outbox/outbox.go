@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"go.uber.org/zap"
+)
+
+const (
+	StatusPending   = "PENDING"
+	StatusPublished = "PUBLISHED"
+	// StatusFailed es el dead letter: eventos que agotaron los intentos. No
+	// vuelven a salir hasta que alguien los pase de nuevo a PENDING.
+	StatusFailed = "FAILED"
+
+	// publishedRetention es cuánto queda un evento publicado antes de que el
+	// TTL lo borre
+	publishedRetention = 7 * 24 * time.Hour
+)
+
+type OutboxRepository interface {
+	Pending(ctx context.Context, limit int32) ([]*models.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id string, now time.Time) error
+	MarkFailed(ctx context.Context, id string, delivered []string, cause error) (int, error)
+	MarkDead(ctx context.Context, id string) error
+}
+
+type DynamoDBClient interface {
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// DynamoOutboxRepository lee el outbox que escriben los repositorios de las
+// entidades con Puts. StatusIndex es un índice (status, occurred_at) para
+// leer los pendientes en orden.
+type DynamoOutboxRepository struct {
+	Client      DynamoDBClient
+	Logger      *zap.SugaredLogger
+	TableName   string
+	StatusIndex string
+}
+
+func New(client DynamoDBClient, logger *zap.SugaredLogger, tableName, statusIndex string) OutboxRepository {
+	return &DynamoOutboxRepository{
+		Client:      client,
+		Logger:      logger,
+		TableName:   tableName,
+		StatusIndex: statusIndex,
+	}
+}
+
+// record es un evento tal como queda en el outbox. Data se guarda como texto
+// para que se pueda leer desde la consola.
+type record struct {
+	ID            string `dynamodbav:"id"`
+	Type          string `dynamodbav:"type"`
+	Version       int    `dynamodbav:"version"`
+	AggregateType string `dynamodbav:"aggregate_type"`
+	AggregateID   string `dynamodbav:"aggregate_id"`
+	ClientID      string `dynamodbav:"client_id"`
+	OccurredAt    string `dynamodbav:"occurred_at"`
+	Data          string `dynamodbav:"data"`
+	Status        string `dynamodbav:"status"`
+	Attempts      int    `dynamodbav:"attempts,omitempty"`
+	LastError     string `dynamodbav:"last_error,omitempty"`
+	// Delivered son los destinos que ya recibieron el evento
+	Delivered   []string `dynamodbav:"delivered,stringset,omitempty"`
+	PublishedAt string   `dynamodbav:"published_at,omitempty"`
+	ExpiresAt   int64    `dynamodbav:"expires_at,omitempty"`
+}
+
+// Puts arma las escrituras de los eventos en el outbox tableName, para
+// sumarlas al TransactWriteItems que guarda la entidad.
+func Puts(tableName string, evts []*events.Event) ([]types.TransactWriteItem, error) {
+	items := make([]types.TransactWriteItem, 0, len(evts))
+	for _, event := range evts {
+		item, err := attributevalue.MarshalMap(record{
+			ID:            event.ID,
+			Type:          event.Type,
+			Version:       event.Version,
+			AggregateType: event.AggregateType,
+			AggregateID:   event.AggregateID,
+			ClientID:      event.ClientID,
+			OccurredAt:    event.OccurredAt,
+			Data:          string(event.Data),
+			Status:        StatusPending,
+		})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, types.TransactWriteItem{Put: &types.Put{TableName: aws.String(tableName), Item: item}})
+	}
+	return items, nil
+}
+
+// Pending devuelve hasta limit eventos sin publicar, del más viejo al más
+// nuevo, con los destinos que ya los recibieron.
+func (d *DynamoOutboxRepository) Pending(ctx context.Context, limit int32) ([]*models.OutboxEvent, error) {
+	keyCond := expression.Key("status").Equal(expression.Value(StatusPending))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 &d.TableName,
+		IndexName:                 &d.StatusIndex,
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ScanIndexForward:          aws.Bool(true),
+		Limit:                     aws.Int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	if err := attributevalue.UnmarshalListOfMaps(resp.Items, &records); err != nil {
+		return nil, err
+	}
+	results := make([]*models.OutboxEvent, 0, len(records))
+	for _, r := range records {
+		results = append(results, &models.OutboxEvent{
+			Event: &events.Event{
+				ID:            r.ID,
+				Type:          r.Type,
+				Version:       r.Version,
+				AggregateType: r.AggregateType,
+				AggregateID:   r.AggregateID,
+				ClientID:      r.ClientID,
+				OccurredAt:    r.OccurredAt,
+				Data:          []byte(r.Data),
+			},
+			Delivered: r.Delivered,
+		})
+	}
+	return results, nil
+}
+
+// MarkPublished saca el evento de los pendientes y le pone vencimiento. Si
+// otro relay ya lo marcó no es un error.
+func (d *DynamoOutboxRepository) MarkPublished(ctx context.Context, id string, now time.Time) error {
+	update := expression.Set(expression.Name("status"), expression.Value(StatusPublished)).
+		Set(expression.Name("published_at"), expression.Value(now.UTC().Format(time.RFC3339))).
+		Set(expression.Name("expires_at"), expression.Value(now.Add(publishedRetention).Unix())).
+		Remove(expression.Name("last_error"))
+	cond := expression.Name("status").Equal(expression.Value(StatusPending))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return err
+	}
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &d.TableName,
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+	return err
+}
+
+// MarkFailed deja el evento pendiente registrando el intento, el error y los
+// destinos que sí lo recibieron en esta corrida. Devuelve cuántos intentos
+// fallidos lleva.
+func (d *DynamoOutboxRepository) MarkFailed(ctx context.Context, id string, delivered []string, cause error) (int, error) {
+	update := expression.Add(expression.Name("attempts"), expression.Value(1)).
+		Set(expression.Name("last_error"), expression.Value(cause.Error()))
+	if len(delivered) > 0 {
+		update = update.Add(expression.Name("delivered"), expression.Value(&types.AttributeValueMemberSS{Value: delivered}))
+	}
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return 0, err
+	}
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	resp, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &d.TableName,
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		return 0, err
+	}
+	var updated struct {
+		Attempts int `dynamodbav:"attempts"`
+	}
+	if err := attributevalue.UnmarshalMap(resp.Attributes, &updated); err != nil {
+		return 0, err
+	}
+	return updated.Attempts, nil
+}
+
+// MarkDead pasa el evento al dead letter, fuera del índice de pendientes,
+// para que no trabe a los que vienen detrás. Si ya no estaba pendiente no es
+// un error.
+func (d *DynamoOutboxRepository) MarkDead(ctx context.Context, id string) error {
+	update := expression.Set(expression.Name("status"), expression.Value(StatusFailed))
+	cond := expression.Name("status").Equal(expression.Value(StatusPending))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return err
+	}
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &d.TableName,
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Update arma el UpdateItem que lleva un item de before a after: SET de los
+// atributos que cambiaron o aparecieron y REMOVE de los que ya no están. Los
+// atributos que no cambiaron no se escriben, así una modificación parcial no
+// pisa lo que otro proceso haya cambiado en esos campos. keys son los
+// atributos de la clave, que no se pueden actualizar. Devuelve false si no
+// hay nada que cambiar.
+func Update(before, after interface{}, keys ...string) (expression.UpdateBuilder, bool, error) {
+	var update expression.UpdateBuilder
+	old, err := attributevalue.MarshalMap(before)
+	if err != nil {
+		return update, false, err
+	}
+	updated, err := attributevalue.MarshalMap(after)
+	if err != nil {
+		return update, false, err
+	}
+	skip := map[string]bool{}
+	for _, key := range keys {
+		skip[key] = true
+	}
+
+	changed := false
+	for _, name := range sortedNames(updated) {
+		if skip[name] || reflect.DeepEqual(old[name], updated[name]) {
+			continue
+		}
+		update = update.Set(expression.Name(name), expression.Value(updated[name]))
+		changed = true
+	}
+	for _, name := range sortedNames(old) {
+		if _, ok := updated[name]; skip[name] || ok {
+			continue
+		}
+		update = update.Remove(expression.Name(name))
+		changed = true
+	}
+	return update, changed, nil
+}
+
+// Version es la condición del bloqueo optimista sobre el contador name: que
+// siga valiendo current. Los items guardados antes de que existiera el
+// contador no lo tienen y se leen con 0, así que en ese caso también vale
+// que no exista.
+func Version(name string, current int) expression.ConditionBuilder {
+	cond := expression.Name(name).Equal(expression.Value(current))
+	if current == 0 {
+		cond = expression.AttributeNotExists(expression.Name(name)).Or(cond)
+	}
+	return cond
+}
+
+// ConditionFailed dice si err es una condición que no se cumplió, tanto de
+// un UpdateItem como de un TransactWriteItems.
+func ConditionFailed(err error) bool {
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return true
+	}
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortedNames(item map[string]types.AttributeValue) []string {
+	names := make([]string, 0, len(item))
+	for name := range item {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
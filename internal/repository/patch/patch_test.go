@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	ID    string            `dynamodbav:"id"`
+	Name  string            `dynamodbav:"name"`
+	Notes string            `dynamodbav:"notes,omitempty"`
+	Tags  map[string]string `dynamodbav:"tags,omitempty"`
+}
+
+func TestUpdate(t *testing.T) {
+	before := item{ID: "1", Name: "Ana", Notes: "vieja", Tags: map[string]string{"a": "1"}}
+	after := item{ID: "1", Name: "Ana María", Tags: map[string]string{"a": "1"}}
+
+	update, changed, err := Update(before, after, "id")
+	require.NoError(t, err)
+	require.True(t, changed)
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "REMOVE #0\nSET #1 = :0\n", aws.ToString(expr.Update()))
+	assert.Equal(t, map[string]string{"#0": "notes", "#1": "name"}, expr.Names())
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "Ana María"}, expr.Values()[":0"])
+}
+
+func TestUpdate_NoChanges(t *testing.T) {
+	_, changed, err := Update(item{ID: "1", Name: "Ana"}, item{ID: "2", Name: "Ana"}, "id")
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestVersion(t *testing.T) {
+	expr, err := expression.NewBuilder().WithCondition(Version("sequence", 3)).Build()
+	require.NoError(t, err)
+	assert.Equal(t, "#0 = :0", aws.ToString(expr.Condition()))
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "3"}, expr.Values()[":0"])
+
+	// Un item sin el atributo es la versión 0
+	expr, err = expression.NewBuilder().WithCondition(Version("sequence", 0)).Build()
+	require.NoError(t, err)
+	assert.Equal(t, "(attribute_not_exists (#0)) OR (#0 = :0)", aws.ToString(expr.Condition()))
+}
+
+func TestConditionFailed(t *testing.T) {
+	assert.True(t, ConditionFailed(&types.ConditionalCheckFailedException{}))
+	assert.True(t, ConditionFailed(&types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+		{Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")},
+	}}))
+	assert.False(t, ConditionFailed(&types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{{Code: aws.String("ThrottlingError")}}}))
+	assert.False(t, ConditionFailed(nil))
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"go.uber.org/zap"
+)
+
+type PatientMergesRepository interface {
+	Save(ctx context.Context, m *models.PatientMerge) error
+	GetByID(ctx context.Context, id string) (*models.PatientMerge, error)
+}
+
+type DynamoDBClient interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+}
+
+type DynamoPatientMergesRepository struct {
+	Client    DynamoDBClient
+	Logger    *zap.SugaredLogger
+	TableName string
+}
+
+func New(client DynamoDBClient, logger *zap.SugaredLogger, tableName string) PatientMergesRepository {
+	return &DynamoPatientMergesRepository{
+		Client:    client,
+		Logger:    logger,
+		TableName: tableName,
+	}
+}
+
+func (d *DynamoPatientMergesRepository) Save(ctx context.Context, m *models.PatientMerge) error {
+	item, err := attributevalue.MarshalMap(m)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling patient merge", "error", err)
+		return err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &d.TableName,
+		Item:      item,
+	})
+	return err
+}
+
+func (d *DynamoPatientMergesRepository) GetByID(ctx context.Context, id string) (*models.PatientMerge, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &d.TableName,
+		Key:       key,
+	})
+	if err != nil || resp.Item == nil {
+		return nil, err
+	}
+	var merge models.PatientMerge
+	if err := attributevalue.UnmarshalMap(resp.Item, &merge); err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del repositorio.
+func (d *DynamoPatientMergesRepository) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, d.Logger)
+}
@@ -2,21 +2,50 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/documents"
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/logging"
 	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/pagination"
+	outbox "github.com/MezeLaw/iris-services/internal/repository/outbox"
+	patch "github.com/MezeLaw/iris-services/internal/repository/patch"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"go.uber.org/zap"
 )
 
+const (
+	// batchWriteLimit es el máximo de items por BatchWriteItem
+	batchWriteLimit = 25
+	// batchGetLimit es el máximo de claves por BatchGetItem
+	batchGetLimit       = 100
+	maxBatchAttempts    = 5
+	defaultBatchBackoff = 100 * time.Millisecond
+)
+
 type PatientsRepository interface {
 	Save(ctx context.Context, p *models.Patient) error
 	GetByID(ctx context.Context, id string) (*models.Patient, error)
 	GetByClientID(ctx context.Context, clientID string) ([]*models.Patient, error)
 	GetByDocument(ctx context.Context, docType, docNumber string) (*models.Patient, error)
 	Delete(ctx context.Context, id string) error
+	BatchSave(ctx context.Context, patients []*models.Patient) (map[string]error, error)
+	GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Patient, string, error)
+	Search(ctx context.Context, clientID, field, value, cursor string, limit int32) ([]*models.Patient, string, error)
+	Reindex(ctx context.Context, p *models.Patient) error
+	AddNoShows(ctx context.Context, id string, delta int) error
+	SaveWithEvents(ctx context.Context, p *models.Patient, evts []*events.Event) error
+	DeleteWithEvents(ctx context.Context, id string, evts []*events.Event) error
+	AppendEvents(ctx context.Context, evts []*events.Event) error
+	Patch(ctx context.Context, before, after *models.Patient, evts []*events.Event) (bool, error)
 }
 
 type DynamoDBClient interface {
@@ -24,6 +53,10 @@ type DynamoDBClient interface {
 	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
 	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
 }
 
 type DynamoPatientsRepository struct {
@@ -32,33 +65,222 @@ type DynamoPatientsRepository struct {
 	TableName     string
 	ClientIDIndex string
 	DocKeyIndex   string
+	// SearchTableName es la tabla con los tokens normalizados para Search.
+	// Si está vacía no se mantiene el índice.
+	SearchTableName string
+	// BatchBackoff es la espera inicial entre reintentos de BatchSave; se
+	// duplica en cada intento. Si es cero se usa defaultBatchBackoff.
+	BatchBackoff time.Duration
+	// OutboxTableName es la tabla donde SaveWithEvents y DeleteWithEvents
+	// escriben los eventos. Si está vacía los eventos se descartan.
+	OutboxTableName string
 }
 
-func New(client DynamoDBClient, logger *zap.SugaredLogger, tableName, clientIDIndex, docKeyIndex string) PatientsRepository {
+func New(client DynamoDBClient, logger *zap.SugaredLogger, tableName, clientIDIndex, docKeyIndex, searchTableName string) PatientsRepository {
 	return &DynamoPatientsRepository{
-		Client:        client,
-		Logger:        logger,
-		TableName:     tableName,
-		ClientIDIndex: clientIDIndex,
-		DocKeyIndex:   docKeyIndex,
+		Client:          client,
+		Logger:          logger,
+		TableName:       tableName,
+		ClientIDIndex:   clientIDIndex,
+		DocKeyIndex:     docKeyIndex,
+		SearchTableName: searchTableName,
+	}
+}
+
+// NewWithOutbox es New con los eventos de dominio escritos en
+// outboxTableName.
+func NewWithOutbox(client DynamoDBClient, logger *zap.SugaredLogger, tableName, clientIDIndex, docKeyIndex, searchTableName, outboxTableName string) PatientsRepository {
+	return &DynamoPatientsRepository{
+		Client:          client,
+		Logger:          logger,
+		TableName:       tableName,
+		ClientIDIndex:   clientIDIndex,
+		DocKeyIndex:     docKeyIndex,
+		SearchTableName: searchTableName,
+		OutboxTableName: outboxTableName,
 	}
 }
 
 func (d *DynamoPatientsRepository) Save(ctx context.Context, p *models.Patient) error {
-	p.DocKey = fmt.Sprintf("%s#%s", p.DocType, p.DocNumber)
+	ctx, span := tracing.Start(ctx, "repository.Patients.Save")
+	defer span.End()
+	if err := setDocKey(p); err != nil {
+		return err
+	}
 	item, err := attributevalue.MarshalMap(p)
 	if err != nil {
-		d.Logger.Errorw("error marshalling patient", "error", err)
+		d.log(ctx).Errorw("error marshalling patient", "error", err)
 		return err
 	}
-	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+	input := &dynamodb.PutItemInput{
 		TableName: &d.TableName,
 		Item:      item,
+	}
+	if d.SearchTableName != "" {
+		// La versión anterior dice qué entradas del índice quedaron viejas
+		input.ReturnValues = types.ReturnValueAllOld
+	}
+	resp, err := d.Client.PutItem(ctx, input)
+	if err != nil || d.SearchTableName == "" {
+		return err
+	}
+	return d.indexSearch(ctx, oldPatient(resp.Attributes), p)
+}
+
+// SaveWithEvents guarda el paciente y sus eventos en una sola transacción.
+// Una transacción no devuelve la versión anterior, así que para el índice de
+// búsqueda se lee antes.
+func (d *DynamoPatientsRepository) SaveWithEvents(ctx context.Context, p *models.Patient, evts []*events.Event) error {
+	ctx, span := tracing.Start(ctx, "repository.Patients.SaveWithEvents")
+	defer span.End()
+	if d.OutboxTableName == "" || len(evts) == 0 {
+		return d.Save(ctx, p)
+	}
+	if err := setDocKey(p); err != nil {
+		return err
+	}
+	item, err := attributevalue.MarshalMap(p)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling patient", "error", err)
+		return err
+	}
+	var before *models.Patient
+	if d.SearchTableName != "" {
+		if before, err = d.GetByID(ctx, p.ID); err != nil {
+			return err
+		}
+	}
+	if err := d.transact(ctx, types.TransactWriteItem{Put: &types.Put{TableName: &d.TableName, Item: item}}, evts); err != nil {
+		return err
+	}
+	if d.SearchTableName == "" {
+		return nil
+	}
+	return d.indexSearch(ctx, before, p)
+}
+
+// DeleteWithEvents borra el paciente y guarda sus eventos en una sola
+// transacción.
+func (d *DynamoPatientsRepository) DeleteWithEvents(ctx context.Context, id string, evts []*events.Event) error {
+	ctx, span := tracing.Start(ctx, "repository.Patients.DeleteWithEvents")
+	defer span.End()
+	if d.OutboxTableName == "" || len(evts) == 0 {
+		return d.Delete(ctx, id)
+	}
+	var before *models.Patient
+	var err error
+	if d.SearchTableName != "" {
+		if before, err = d.GetByID(ctx, id); err != nil {
+			return err
+		}
+	}
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	if err := d.transact(ctx, types.TransactWriteItem{Delete: &types.Delete{TableName: &d.TableName, Key: key}}, evts); err != nil {
+		return err
+	}
+	if d.SearchTableName == "" {
+		return nil
+	}
+	return d.indexSearch(ctx, before, nil)
+}
+
+// AppendEvents guarda eventos en el outbox sin escribir pacientes. Lo usa la
+// importación, que guarda los pacientes con BatchSave y no puede sumar los
+// eventos a una transacción.
+func (d *DynamoPatientsRepository) AppendEvents(ctx context.Context, evts []*events.Event) error {
+	ctx, span := tracing.Start(ctx, "repository.Patients.AppendEvents")
+	defer span.End()
+	if d.OutboxTableName == "" || len(evts) == 0 {
+		return nil
+	}
+	puts, err := outbox.Puts(d.OutboxTableName, evts)
+	if err != nil {
+		return err
+	}
+	failed := map[string]error{}
+	for start := 0; start < len(puts); start += batchWriteLimit {
+		requests := make([]types.WriteRequest, 0, batchWriteLimit)
+		for _, put := range puts[start:min(start+batchWriteLimit, len(puts))] {
+			requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: put.Put.Item}})
+		}
+		if err := d.batchWrite(ctx, d.OutboxTableName, requests, failed); err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d events were not written to the outbox", len(failed), len(evts))
+	}
+	return nil
+}
+
+// Patch escribe sólo los atributos que cambian de before a after con un
+// UpdateItem, y los eventos en la misma transacción si hay outbox. Se
+// condiciona a que el paciente siga con el updated_at de before: si otro
+// proceso lo modificó en el medio devuelve false y no escribe nada.
+func (d *DynamoPatientsRepository) Patch(ctx context.Context, before, after *models.Patient, evts []*events.Event) (bool, error) {
+	ctx, span := tracing.Start(ctx, "repository.Patients.Patch")
+	defer span.End()
+	if err := setDocKey(after); err != nil {
+		return false, err
+	}
+	update, changed, err := patch.Update(before, after, "id")
+	if err != nil {
+		d.log(ctx).Errorw("error building patient update", "error", err)
+		return false, err
+	}
+	if !changed {
+		return true, nil
+	}
+	cond := patch.Version("version", before.Version)
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return false, err
+	}
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": before.ID})
+
+	if d.OutboxTableName != "" && len(evts) > 0 {
+		err = d.transact(ctx, types.TransactWriteItem{Update: &types.Update{
+			TableName:                 &d.TableName,
+			Key:                       key,
+			UpdateExpression:          expr.Update(),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}}, evts)
+	} else {
+		_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 &d.TableName,
+			Key:                       key,
+			UpdateExpression:          expr.Update(),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		})
+	}
+	if patch.ConditionFailed(err) {
+		d.log(ctx).Infow("patient changed while patching", "id", before.ID)
+		return false, nil
+	}
+	if err != nil || d.SearchTableName == "" {
+		return err == nil, err
+	}
+	return true, d.indexSearch(ctx, before, after)
+}
+
+func (d *DynamoPatientsRepository) transact(ctx context.Context, write types.TransactWriteItem, evts []*events.Event) error {
+	puts, err := outbox.Puts(d.OutboxTableName, evts)
+	if err != nil {
+		return err
+	}
+	_, err = d.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: append([]types.TransactWriteItem{write}, puts...),
 	})
 	return err
 }
 
 func (d *DynamoPatientsRepository) Get(ctx context.Context, id string) (*models.Patient, error) {
+	ctx, span := tracing.Start(ctx, "repository.Patients.Get")
+	defer span.End()
 	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
 	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
 		TableName: &d.TableName,
@@ -75,7 +297,11 @@ func (d *DynamoPatientsRepository) Get(ctx context.Context, id string) (*models.
 }
 
 func (d *DynamoPatientsRepository) Update(ctx context.Context, p *models.Patient) error {
-	p.DocKey = fmt.Sprintf("%s#%s", p.DocType, p.DocNumber)
+	ctx, span := tracing.Start(ctx, "repository.Patients.Update")
+	defer span.End()
+	if err := setDocKey(p); err != nil {
+		return err
+	}
 	item, err := attributevalue.MarshalMap(p)
 	if err != nil {
 		return err
@@ -88,14 +314,25 @@ func (d *DynamoPatientsRepository) Update(ctx context.Context, p *models.Patient
 }
 
 func (d *DynamoPatientsRepository) Delete(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "repository.Patients.Delete")
+	defer span.End()
 	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
-	_, err := d.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+	input := &dynamodb.DeleteItemInput{
 		TableName: &d.TableName,
 		Key:       key,
-	})
-	return err
+	}
+	if d.SearchTableName != "" {
+		input.ReturnValues = types.ReturnValueAllOld
+	}
+	resp, err := d.Client.DeleteItem(ctx, input)
+	if err != nil || d.SearchTableName == "" {
+		return err
+	}
+	return d.indexSearch(ctx, oldPatient(resp.Attributes), nil)
 }
 func (d *DynamoPatientsRepository) GetByID(ctx context.Context, id string) (*models.Patient, error) {
+	ctx, span := tracing.Start(ctx, "repository.Patients.GetByID")
+	defer span.End()
 	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
 	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
 		TableName: &d.TableName,
@@ -112,6 +349,48 @@ func (d *DynamoPatientsRepository) GetByID(ctx context.Context, id string) (*mod
 }
 
 func (d *DynamoPatientsRepository) GetByClientID(ctx context.Context, clientID string) ([]*models.Patient, error) {
+	ctx, span := tracing.Start(ctx, "repository.Patients.GetByClientID")
+	defer span.End()
+	keyCond := expression.Key("client_id").Equal(expression.Value(clientID))
+	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+
+	// Se recorren todas las páginas: una clínica grande supera el MB por Query
+	var results []*models.Patient
+	var startKey map[string]types.AttributeValue
+	for {
+		resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 &d.TableName,
+			IndexName:                 &d.ClientIDIndex,
+			KeyConditionExpression:    expr.KeyCondition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var page []*models.Patient
+		if err := attributevalue.UnmarshalListOfMaps(resp.Items, &page); err != nil {
+			return nil, err
+		}
+		results = append(results, page...)
+		if len(resp.LastEvaluatedKey) == 0 {
+			return results, nil
+		}
+		startKey = resp.LastEvaluatedKey
+	}
+}
+
+// GetPageByClientID devuelve una página de hasta limit pacientes del cliente y
+// el cursor de la siguiente ("" cuando no hay más).
+func (d *DynamoPatientsRepository) GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Patient, string, error) {
+	ctx, span := tracing.Start(ctx, "repository.Patients.GetPageByClientID")
+	defer span.End()
+	startKey, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
 	keyCond := expression.Key("client_id").Equal(expression.Value(clientID))
 	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
 
@@ -121,20 +400,46 @@ func (d *DynamoPatientsRepository) GetByClientID(ctx context.Context, clientID s
 		KeyConditionExpression:    expr.KeyCondition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
+		ExclusiveStartKey:         startKey,
+		Limit:                     aws.Int32(limit),
 	})
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var results []*models.Patient
 	if err := attributevalue.UnmarshalListOfMaps(resp.Items, &results); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return results, nil
+	next, err := pagination.Encode(resp.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return results, next, nil
 }
 
+// GetByDocument busca por el documento normalizado y, si no lo encuentra,
+// tal como vino: los pacientes guardados antes del catálogo de documentos
+// pueden tener el número con puntos.
 func (d *DynamoPatientsRepository) GetByDocument(ctx context.Context, docType, docNumber string) (*models.Patient, error) {
-	docKey := fmt.Sprintf("%s#%s", docType, docNumber)
+	ctx, span := tracing.Start(ctx, "repository.Patients.GetByDocument")
+	defer span.End()
+	keys := []string{fmt.Sprintf("%s#%s", docType, docNumber)}
+	if normalizedType, normalizedNumber, err := documents.Normalize(docType, docNumber); err == nil {
+		if key := fmt.Sprintf("%s#%s", normalizedType, normalizedNumber); key != keys[0] {
+			keys = []string{key, keys[0]}
+		}
+	}
+	for _, docKey := range keys {
+		patient, err := d.getByDocKey(ctx, docKey)
+		if err != nil || patient != nil {
+			return patient, err
+		}
+	}
+	return nil, nil
+}
+
+func (d *DynamoPatientsRepository) getByDocKey(ctx context.Context, docKey string) (*models.Patient, error) {
 	keyCond := expression.Key("doc_key").Equal(expression.Value(docKey))
 	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
 
@@ -155,3 +460,202 @@ func (d *DynamoPatientsRepository) GetByDocument(ctx context.Context, docType, d
 	}
 	return &patient, nil
 }
+
+// setDocKey normaliza el documento según el catálogo y arma doc_key. Falla
+// si el tipo no existe o el número no pasa el dígito verificador.
+func setDocKey(p *models.Patient) error {
+	docType, docNumber, err := documents.Normalize(p.DocType, p.DocNumber)
+	if err != nil {
+		return err
+	}
+	p.DocType, p.DocNumber = docType, docNumber
+	p.DocKey = fmt.Sprintf("%s#%s", p.DocType, p.DocNumber)
+	return nil
+}
+
+// BatchSave guarda los pacientes con BatchWriteItem en lotes de 25 y
+// reintenta los UnprocessedItems con backoff exponencial. Devuelve, por ID,
+// los pacientes que no se pudieron guardar; el error sólo se usa si se
+// cancela el contexto.
+func (d *DynamoPatientsRepository) BatchSave(ctx context.Context, patients []*models.Patient) (map[string]error, error) {
+	ctx, span := tracing.Start(ctx, "repository.Patients.BatchSave")
+	defer span.End()
+	failed := map[string]error{}
+	for start := 0; start < len(patients); start += batchWriteLimit {
+		end := start + batchWriteLimit
+		if end > len(patients) {
+			end = len(patients)
+		}
+
+		var requests []types.WriteRequest
+		for _, p := range patients[start:end] {
+			if err := setDocKey(p); err != nil {
+				failed[p.ID] = err
+				continue
+			}
+			item, err := attributevalue.MarshalMap(p)
+			if err != nil {
+				d.log(ctx).Errorw("error marshalling patient", "id", p.ID, "error", err)
+				failed[p.ID] = err
+				continue
+			}
+			requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+		}
+		if err := d.batchWrite(ctx, d.TableName, requests, failed); err != nil {
+			return failed, err
+		}
+	}
+
+	if d.SearchTableName != "" {
+		for _, p := range patients {
+			if _, ok := failed[p.ID]; ok {
+				continue
+			}
+			if err := d.indexSearch(ctx, nil, p); err != nil {
+				d.log(ctx).Errorw("error indexing patient for search", "id", p.ID, "error", err)
+				failed[p.ID] = err
+			}
+		}
+	}
+	return failed, nil
+}
+
+func (d *DynamoPatientsRepository) batchWrite(ctx context.Context, table string, requests []types.WriteRequest, failed map[string]error) error {
+	backoff := d.BatchBackoff
+	if backoff <= 0 {
+		backoff = defaultBatchBackoff
+	}
+	for attempt := 1; len(requests) > 0; attempt++ {
+		resp, err := d.Client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{table: requests},
+		})
+		if err != nil {
+			d.log(ctx).Errorw("error on BatchWriteItem", "items", len(requests), "error", err)
+			markFailed(requests, err, failed)
+			return nil
+		}
+		requests = resp.UnprocessedItems[table]
+		if len(requests) == 0 {
+			return nil
+		}
+		if attempt == maxBatchAttempts {
+			markFailed(requests, fmt.Errorf("unprocessed after %d attempts", maxBatchAttempts), failed)
+			return nil
+		}
+
+		d.log(ctx).Warnw("retrying unprocessed items", "table", table, "items", len(requests), "attempt", attempt)
+		select {
+		case <-ctx.Done():
+			markFailed(requests, ctx.Err(), failed)
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return nil
+}
+
+// getByIDs trae los pacientes de ids con BatchGetItem, de a batchGetLimit
+// claves, y reintenta las UnprocessedKeys con el mismo backoff que
+// batchWrite. Los que no existen no aparecen en el resultado.
+func (d *DynamoPatientsRepository) getByIDs(ctx context.Context, ids []string) (map[string]*models.Patient, error) {
+	backoff := d.BatchBackoff
+	if backoff <= 0 {
+		backoff = defaultBatchBackoff
+	}
+	results := make(map[string]*models.Patient, len(ids))
+	for start := 0; start < len(ids); start += batchGetLimit {
+		keys := make([]map[string]types.AttributeValue, 0, batchGetLimit)
+		for _, id := range ids[start:min(start+batchGetLimit, len(ids))] {
+			key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+			keys = append(keys, key)
+		}
+		request := map[string]types.KeysAndAttributes{d.TableName: {Keys: keys}}
+		for attempt := 1; len(request) > 0; attempt++ {
+			resp, err := d.Client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+			if err != nil {
+				return nil, err
+			}
+			var page []*models.Patient
+			if err := attributevalue.UnmarshalListOfMaps(resp.Responses[d.TableName], &page); err != nil {
+				return nil, err
+			}
+			for _, patient := range page {
+				results[patient.ID] = patient
+			}
+			request = resp.UnprocessedKeys
+			if len(request) == 0 {
+				break
+			}
+			if attempt == maxBatchAttempts {
+				return nil, fmt.Errorf("%d patients unprocessed after %d attempts", len(request[d.TableName].Keys), maxBatchAttempts)
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+	return results, nil
+}
+
+func markFailed(requests []types.WriteRequest, err error, failed map[string]error) {
+	for _, r := range requests {
+		var item map[string]types.AttributeValue
+		switch {
+		case r.PutRequest != nil:
+			item = r.PutRequest.Item
+		case r.DeleteRequest != nil:
+			item = r.DeleteRequest.Key
+		default:
+			continue
+		}
+		var key struct {
+			ID string `dynamodbav:"id"`
+		}
+		if attributevalue.UnmarshalMap(item, &key) == nil {
+			failed[key.ID] = err
+		}
+	}
+}
+
+// AddNoShows suma delta (negativo al corregir una ausencia) al contador de
+// turnos perdidos del paciente. El contador nunca queda negativo y no se
+// crea el item si el paciente ya no existe.
+func (d *DynamoPatientsRepository) AddNoShows(ctx context.Context, id string, delta int) error {
+	ctx, span := tracing.Start(ctx, "repository.Patients.AddNoShows")
+	defer span.End()
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	update := expression.Add(expression.Name("no_show_count"), expression.Value(delta)).
+		Add(expression.Name("version"), expression.Value(1))
+	cond := expression.AttributeExists(expression.Name("id"))
+	if delta < 0 {
+		cond = cond.And(expression.Name("no_show_count").GreaterThanEqual(expression.Value(-delta)))
+	}
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return err
+	}
+
+	_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &d.TableName,
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		d.log(ctx).Infow("no-show count not updated", "id", id, "delta", delta)
+		return nil
+	}
+	return err
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del repositorio.
+func (d *DynamoPatientsRepository) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, d.Logger)
+}
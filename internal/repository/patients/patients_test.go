@@ -3,14 +3,21 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/MezeLaw/iris-services/internal/documents"
+	"github.com/MezeLaw/iris-services/internal/events"
 	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Mock for DynamoDB Client
@@ -35,9 +42,44 @@ func (m *MockDynamoDBClient) DeleteItem(ctx context.Context, params *dynamodb.De
 
 func (m *MockDynamoDBClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
 	args := m.Called(ctx, params)
+	if fn, ok := args.Get(0).(func(*dynamodb.QueryInput) *dynamodb.QueryOutput); ok {
+		return fn(params), args.Error(1)
+	}
 	return args.Get(0).(*dynamodb.QueryOutput), args.Error(1)
 }
 
+func (m *MockDynamoDBClient) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.BatchGetItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	args := m.Called(ctx, params)
+	if fn, ok := args.Get(0).(func(*dynamodb.BatchWriteItemInput) *dynamodb.BatchWriteItemOutput); ok {
+		return fn(params), args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.BatchWriteItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.UpdateItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.TransactWriteItemsOutput), args.Error(1)
+}
+
 // Utility function to create a test logger
 func createTestLogger() *zap.SugaredLogger {
 	logger, _ := zap.NewDevelopment()
@@ -87,7 +129,7 @@ func TestSave(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			// Setup
 			mockClient := new(MockDynamoDBClient)
-			repo := New(mockClient, createTestLogger(), "patients", "client_id-index", "doc_key-index")
+			repo := New(mockClient, createTestLogger(), "patients", "client_id-index", "doc_key-index", "")
 
 			// Expectations
 			mockClient.On("PutItem", mock.Anything, mock.Anything).Return(tc.mockResponse, tc.mockError)
@@ -618,3 +660,427 @@ func TestGet(t *testing.T) {
 		})
 	}
 }
+
+// TestGetByClientID_Paginates tests that GetByClientID follows LastEvaluatedKey
+func TestGetByClientID_Paginates(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := DynamoPatientsRepository{
+		Client:        mockClient,
+		Logger:        createTestLogger(),
+		TableName:     "patients",
+		ClientIDIndex: "client_id-index",
+	}
+	first, _ := attributevalue.MarshalMap(&models.Patient{ID: "1", ClientID: "client1"})
+	second, _ := attributevalue.MarshalMap(&models.Patient{ID: "2", ClientID: "client1"})
+	lastKey := map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "1"}}
+
+	mockClient.On("Query", mock.Anything, mock.MatchedBy(func(in *dynamodb.QueryInput) bool { return in.ExclusiveStartKey == nil })).
+		Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{first}, LastEvaluatedKey: lastKey}, nil).Once()
+	mockClient.On("Query", mock.Anything, mock.MatchedBy(func(in *dynamodb.QueryInput) bool { return in.ExclusiveStartKey != nil })).
+		Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{second}}, nil).Once()
+
+	patients, err := repo.GetByClientID(context.Background(), "client1")
+
+	assert.NoError(t, err)
+	assert.Len(t, patients, 2)
+	mockClient.AssertExpectations(t)
+}
+
+// TestBatchSave tests chunking and retries of unprocessed items
+func TestBatchSave(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := DynamoPatientsRepository{
+		Client:       mockClient,
+		Logger:       createTestLogger(),
+		TableName:    "patients",
+		BatchBackoff: time.Millisecond,
+	}
+	var patients []*models.Patient
+	for i := 0; i < 30; i++ {
+		patients = append(patients, &models.Patient{ID: fmt.Sprintf("p%d", i), DocType: "DNI", DocNumber: fmt.Sprint(30000000 + i)})
+	}
+
+	var calls []int
+	mockClient.On("BatchWriteItem", mock.Anything, mock.Anything).Return(func(in *dynamodb.BatchWriteItemInput) *dynamodb.BatchWriteItemOutput {
+		requests := in.RequestItems["patients"]
+		calls = append(calls, len(requests))
+		// El primer lote deja dos items sin procesar una vez
+		if len(calls) == 1 {
+			return &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{"patients": requests[:2]}}
+		}
+		return &dynamodb.BatchWriteItemOutput{}
+	}, nil)
+
+	failed, err := repo.BatchSave(context.Background(), patients)
+
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+	assert.Equal(t, []int{25, 2, 5}, calls)
+	assert.Equal(t, "DNI#30000003", patients[3].DocKey)
+}
+
+// TestBatchSave_GivesUp tests that items still unprocessed after the last attempt are reported
+func TestBatchSave_GivesUp(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := DynamoPatientsRepository{
+		Client:       mockClient,
+		Logger:       createTestLogger(),
+		TableName:    "patients",
+		BatchBackoff: time.Millisecond,
+	}
+	patients := []*models.Patient{{ID: "a", DocType: "DNI", DocNumber: "30000001"}, {ID: "b", DocType: "DNI", DocNumber: "30000002"}}
+
+	mockClient.On("BatchWriteItem", mock.Anything, mock.Anything).Return(func(in *dynamodb.BatchWriteItemInput) *dynamodb.BatchWriteItemOutput {
+		requests := in.RequestItems["patients"]
+		return &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{"patients": requests[len(requests)-1:]}}
+	}, nil)
+
+	failed, err := repo.BatchSave(context.Background(), patients)
+
+	assert.NoError(t, err)
+	assert.Len(t, failed, 1)
+	assert.Contains(t, failed, "b")
+	mockClient.AssertNumberOfCalls(t, "BatchWriteItem", maxBatchAttempts)
+}
+
+// TestSave_UpdatesSearchIndex tests that stale search entries are removed and new ones written
+func TestSave_UpdatesSearchIndex(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := DynamoPatientsRepository{
+		Client:          mockClient,
+		Logger:          createTestLogger(),
+		TableName:       "patients",
+		SearchTableName: "search",
+	}
+	old, _ := attributevalue.MarshalMap(&models.Patient{ID: "p1", ClientID: "c1", FirstName: "Ana", LastName: "Perez", Email: "ana@old.com"})
+	mockClient.On("PutItem", mock.Anything, mock.MatchedBy(func(in *dynamodb.PutItemInput) bool {
+		return in.ReturnValues == types.ReturnValueAllOld
+	})).Return(&dynamodb.PutItemOutput{Attributes: old}, nil)
+
+	var deleted, put []string
+	mockClient.On("BatchWriteItem", mock.Anything, mock.Anything).Return(func(in *dynamodb.BatchWriteItemInput) *dynamodb.BatchWriteItemOutput {
+		for _, r := range in.RequestItems["search"] {
+			if r.DeleteRequest != nil {
+				deleted = append(deleted, r.DeleteRequest.Key["search_key"].(*types.AttributeValueMemberS).Value)
+			} else {
+				put = append(put, r.PutRequest.Item["search_key"].(*types.AttributeValueMemberS).Value)
+			}
+		}
+		return &dynamodb.BatchWriteItemOutput{}
+	}, nil)
+
+	err := repo.Save(context.Background(), &models.Patient{ID: "p1", ClientID: "c1", DocType: "DNI", DocNumber: "30123456", FirstName: "Ana María", LastName: "Pérez", CountryCode: "+54", PhoneNumber: "11 5555-1234"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e#ana@old.com#p1"}, deleted)
+	assert.Equal(t, []string{"n#ana#p1", "n#maria#p1", "n#perez#p1", "p#1155551234#p1", "p#541155551234#p1"}, put)
+}
+
+// TestSearch_ByName tests prefix search on the longest token, filtering by the rest
+func TestSearch_ByName(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := DynamoPatientsRepository{
+		Client:          mockClient,
+		Logger:          createTestLogger(),
+		TableName:       "patients",
+		SearchTableName: "search",
+	}
+	entries := []models.PatientSearchEntry{
+		{ClientID: "c1", SearchKey: "n#martin#p1", PatientID: "p1"},
+		{ClientID: "c1", SearchKey: "n#martinez#p1", PatientID: "p1"},
+		{ClientID: "c1", SearchKey: "n#martinez#p2", PatientID: "p2"},
+	}
+	var items []map[string]types.AttributeValue
+	for _, e := range entries {
+		item, _ := attributevalue.MarshalMap(e)
+		items = append(items, item)
+	}
+	var query *dynamodb.QueryInput
+	mockClient.On("Query", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		query = args.Get(1).(*dynamodb.QueryInput)
+	}).Return(&dynamodb.QueryOutput{
+		Items:            items,
+		LastEvaluatedKey: items[2],
+	}, nil)
+	p1, _ := attributevalue.MarshalMap(&models.Patient{ID: "p1", ClientID: "c1", FirstName: "Ana", LastName: "Martin Martinez"})
+	p2, _ := attributevalue.MarshalMap(&models.Patient{ID: "p2", ClientID: "other"})
+	mockClient.On("BatchGetItem", mock.Anything, mock.MatchedBy(func(in *dynamodb.BatchGetItemInput) bool {
+		return len(in.RequestItems["patients"].Keys) == 2
+	})).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{"patients": {p2, p1}},
+	}, nil).Once()
+
+	patients, next, err := repo.Search(context.Background(), "c1", models.PatientSearchByName, "Ana MARTÍN", "", 10)
+
+	assert.NoError(t, err)
+	assert.Len(t, patients, 1)
+	assert.Equal(t, "p1", patients[0].ID)
+	assert.NotEmpty(t, next)
+	assert.Equal(t, "search", *query.TableName)
+	assert.NotNil(t, query.FilterExpression)
+	var prefix string
+	for _, v := range query.ExpressionAttributeValues {
+		if s, ok := v.(*types.AttributeValueMemberS); ok && strings.HasPrefix(s.Value, "n#") {
+			prefix = s.Value
+		}
+	}
+	assert.Equal(t, "n#martin", prefix)
+	mockClient.AssertExpectations(t)
+}
+
+// TestSearch_DedupesAcrossPages tests that a patient whose tokens span two pages is returned only once
+func TestSearch_DedupesAcrossPages(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := DynamoPatientsRepository{
+		Client:          mockClient,
+		Logger:          createTestLogger(),
+		TableName:       "patients",
+		SearchTableName: "search",
+		BatchBackoff:    time.Millisecond,
+	}
+	page := func(keys ...string) *dynamodb.QueryOutput {
+		out := &dynamodb.QueryOutput{}
+		for _, key := range keys {
+			id := key[strings.LastIndex(key, "#")+1:]
+			item, _ := attributevalue.MarshalMap(models.PatientSearchEntry{ClientID: "c1", SearchKey: key, PatientID: id})
+			out.Items = append(out.Items, item)
+		}
+		out.LastEvaluatedKey = out.Items[len(out.Items)-1]
+		return out
+	}
+	mockClient.On("Query", mock.Anything, mock.MatchedBy(func(in *dynamodb.QueryInput) bool {
+		return in.ExclusiveStartKey == nil
+	})).Return(page("n#martin#p1"), nil).Once()
+	mockClient.On("Query", mock.Anything, mock.Anything).Return(page("n#martinez#p1", "n#martinez#p2"), nil).Once()
+	p1, _ := attributevalue.MarshalMap(&models.Patient{ID: "p1", ClientID: "c1", FirstName: "Ana", LastName: "Martin Martinez"})
+	p2, _ := attributevalue.MarshalMap(&models.Patient{ID: "p2", ClientID: "c1", FirstName: "Luz", LastName: "Martinez"})
+	mockClient.On("BatchGetItem", mock.Anything, mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{"patients": {p1}},
+	}, nil).Once()
+	// Segunda página: p2 queda sin procesar en el primer intento
+	unprocessed, _ := attributevalue.MarshalMap(map[string]string{"id": "p2"})
+	mockClient.On("BatchGetItem", mock.Anything, mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]types.AttributeValue{"patients": {p1}},
+		UnprocessedKeys: map[string]types.KeysAndAttributes{"patients": {Keys: []map[string]types.AttributeValue{unprocessed}}},
+	}, nil).Once()
+	mockClient.On("BatchGetItem", mock.Anything, mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{"patients": {p2}},
+	}, nil).Once()
+
+	first, next, err := repo.Search(context.Background(), "c1", models.PatientSearchByName, "martin", "", 1)
+	require.NoError(t, err)
+	second, _, err := repo.Search(context.Background(), "c1", models.PatientSearchByName, "martin", next, 2)
+	require.NoError(t, err)
+
+	require.Len(t, first, 1)
+	assert.Equal(t, "p1", first[0].ID)
+	require.Len(t, second, 1)
+	assert.Equal(t, "p2", second[0].ID)
+	mockClient.AssertExpectations(t)
+}
+
+// TestSave_NormalizesDocument tests that doc_key is built from the normalized document
+func TestSave_NormalizesDocument(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := DynamoPatientsRepository{Client: mockClient, Logger: createTestLogger(), TableName: "patients"}
+	mockClient.On("PutItem", mock.Anything, mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+
+	patient := &models.Patient{ID: "p1", DocType: "c.u.i.t.", DocNumber: "20-12345678-6"}
+	assert.NoError(t, repo.Save(context.Background(), patient))
+	assert.Equal(t, "CUIT#20123456786", patient.DocKey)
+
+	err := repo.Save(context.Background(), &models.Patient{ID: "p2", DocType: "CUIT", DocNumber: "20-12345678-5"})
+	assert.ErrorIs(t, err, documents.ErrInvalidNumber)
+	mockClient.AssertNumberOfCalls(t, "PutItem", 1)
+}
+
+// TestGetByDocument_FallsBackToRawKey tests lookups of patients stored before normalization
+func TestGetByDocument_FallsBackToRawKey(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := DynamoPatientsRepository{Client: mockClient, Logger: createTestLogger(), TableName: "patients", DocKeyIndex: "doc_key-index"}
+	legacy, _ := attributevalue.MarshalMap(&models.Patient{ID: "p1", DocType: "DNI", DocNumber: "30.123.456"})
+	var keys []string
+	mockClient.On("Query", mock.Anything, mock.Anything).Return(func(in *dynamodb.QueryInput) *dynamodb.QueryOutput {
+		for _, v := range in.ExpressionAttributeValues {
+			keys = append(keys, v.(*types.AttributeValueMemberS).Value)
+		}
+		if len(keys) == 2 {
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{legacy}}
+		}
+		return &dynamodb.QueryOutput{}
+	}, nil)
+
+	patient, err := repo.GetByDocument(context.Background(), "DNI", "30.123.456")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "p1", patient.ID)
+	assert.Equal(t, []string{"DNI#30123456", "DNI#30.123.456"}, keys)
+}
+
+// TestSearchEntries_E164Phone tests that E.164 phones are indexed with and without country code
+func TestSearchEntries_E164Phone(t *testing.T) {
+	entries := searchEntries(&models.Patient{ID: "p1", ClientID: "c1", CountryCode: "54", PhoneNumber: "+5491155551234"})
+
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.SearchKey)
+	}
+	assert.Equal(t, []string{"p#5491155551234#p1", "p#91155551234#p1", "p#1155551234#p1"}, keys)
+}
+
+// TestAddNoShows tests the counter update and that a missing patient is not an error
+func TestAddNoShows(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := DynamoPatientsRepository{Client: mockClient, Logger: createTestLogger(), TableName: "patients"}
+
+	var inputs []*dynamodb.UpdateItemInput
+	mockClient.On("UpdateItem", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		inputs = append(inputs, args.Get(1).(*dynamodb.UpdateItemInput))
+	}).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+	mockClient.On("UpdateItem", mock.Anything, mock.Anything).Return(nil, &types.ConditionalCheckFailedException{}).Once()
+
+	assert.NoError(t, repo.AddNoShows(context.Background(), "p1", 1))
+	assert.NoError(t, repo.AddNoShows(context.Background(), "p1", -1))
+
+	assert.True(t, strings.HasPrefix(*inputs[0].UpdateExpression, "ADD "))
+	assert.Contains(t, *inputs[0].ConditionExpression, "attribute_exists")
+	var names []string
+	for _, name := range inputs[0].ExpressionAttributeNames {
+		names = append(names, name)
+	}
+	// El contador también sube la versión del bloqueo optimista
+	assert.ElementsMatch(t, []string{"id", "no_show_count", "version"}, names)
+	mockClient.AssertExpectations(t)
+}
+
+func TestSaveWithEvents(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := NewWithOutbox(mockClient, createTestLogger(), "patients", "client_id-index", "doc_key-index", "", "outbox")
+	patient := &models.Patient{ID: "123", ClientID: "client1", DocType: "DNI", DocNumber: "12345678"}
+	event := &events.Event{ID: "evt1", Type: events.PatientRegistered, AggregateID: "123", Data: []byte(`{"id":"123"}`)}
+
+	mockClient.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		if len(input.TransactItems) != 2 {
+			return false
+		}
+		entity, outbox := input.TransactItems[0].Put, input.TransactItems[1].Put
+		return *entity.TableName == "patients" && *outbox.TableName == "outbox" &&
+			outbox.Item["status"].(*types.AttributeValueMemberS).Value == "PENDING" &&
+			outbox.Item["data"].(*types.AttributeValueMemberS).Value == `{"id":"123"}`
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	err := repo.SaveWithEvents(context.Background(), patient, []*events.Event{event})
+
+	assert.NoError(t, err)
+	mockClient.AssertNotCalled(t, "PutItem", mock.Anything, mock.Anything)
+	mockClient.AssertExpectations(t)
+}
+
+func TestSaveWithEvents_WithoutOutbox(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := New(mockClient, createTestLogger(), "patients", "client_id-index", "doc_key-index", "")
+	mockClient.On("PutItem", mock.Anything, mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+
+	err := repo.SaveWithEvents(context.Background(), &models.Patient{ID: "123", DocType: "DNI", DocNumber: "12345678"}, []*events.Event{{ID: "evt1"}})
+
+	assert.NoError(t, err)
+	mockClient.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestAppendEvents(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := NewWithOutbox(mockClient, createTestLogger(), "patients", "client_id-index", "doc_key-index", "", "outbox")
+	evts := make([]*events.Event, 30)
+	for i := range evts {
+		evts[i] = &events.Event{ID: fmt.Sprintf("evt%d", i), Type: events.PatientRegistered}
+	}
+
+	var batches []int
+	mockClient.On("BatchWriteItem", mock.Anything, mock.Anything).Return(func(in *dynamodb.BatchWriteItemInput) *dynamodb.BatchWriteItemOutput {
+		batches = append(batches, len(in.RequestItems["outbox"]))
+		return &dynamodb.BatchWriteItemOutput{}
+	}, nil)
+
+	err := repo.AppendEvents(context.Background(), evts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{25, 5}, batches)
+}
+
+func TestPatch(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := New(mockClient, createTestLogger(), "patients", "client_id-index", "doc_key-index", "")
+	before := &models.Patient{ID: "123", ClientID: "client1", FirstName: "Ana", Email: "ana@example.com", DocType: "DNI", DocNumber: "12345678", UpdatedAt: "2024-01-01T00:00:00Z"}
+	after := *before
+	after.Email = ""
+	after.PhoneNumber = "+5491112345678"
+	after.UpdatedAt = "2024-01-02T00:00:00Z"
+
+	mockClient.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+		names := map[string]bool{}
+		for _, name := range input.ExpressionAttributeNames {
+			names[name] = true
+		}
+		// Sólo se escriben los atributos que cambiaron
+		return *input.TableName == "patients" && input.Key["id"].(*types.AttributeValueMemberS).Value == "123" &&
+			names["email"] && names["phone_number"] && names["updated_at"] && !names["first_name"] && !names["id"]
+	})).Return(&dynamodb.UpdateItemOutput{}, nil)
+
+	ok, err := repo.Patch(context.Background(), before, &after, nil)
+
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	mockClient.AssertNotCalled(t, "PutItem", mock.Anything, mock.Anything)
+	mockClient.AssertExpectations(t)
+}
+
+func TestPatch_Version(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := New(mockClient, createTestLogger(), "patients", "client_id-index", "doc_key-index", "")
+	var inputs []*dynamodb.UpdateItemInput
+	mockClient.On("UpdateItem", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		inputs = append(inputs, args.Get(1).(*dynamodb.UpdateItemInput))
+	}).Return(&dynamodb.UpdateItemOutput{}, nil)
+
+	// Un paciente guardado antes de la versión no tiene el atributo
+	legacy := &models.Patient{ID: "123", FirstName: "Ana", DocType: "DNI", DocNumber: "12345678", UpdatedAt: "2024-01-01T00:00:00Z"}
+	after := *legacy
+	after.FirstName, after.Version = "Ana María", 1
+	ok, err := repo.Patch(context.Background(), legacy, &after, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	current := after
+	next := current
+	next.LastName, next.Version = "Pérez", 2
+	ok, err = repo.Patch(context.Background(), &current, &next, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.Len(t, inputs, 2)
+	assert.Equal(t, "(attribute_not_exists (#0)) OR (#0 = :0)", *inputs[0].ConditionExpression)
+	assert.Equal(t, "version", inputs[0].ExpressionAttributeNames["#0"])
+	assert.Equal(t, "#0 = :0", *inputs[1].ConditionExpression)
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "1"}, inputs[1].ExpressionAttributeValues[":0"])
+}
+
+func TestPatch_Conflict(t *testing.T) {
+	mockClient := new(MockDynamoDBClient)
+	repo := NewWithOutbox(mockClient, createTestLogger(), "patients", "client_id-index", "doc_key-index", "", "outbox")
+	before := &models.Patient{ID: "123", FirstName: "Ana", DocType: "DNI", DocNumber: "12345678", UpdatedAt: "2024-01-01T00:00:00Z"}
+	after := *before
+	after.FirstName = "Ana María"
+	event := &events.Event{ID: "evt1", Type: events.PatientUpdated, AggregateID: "123", Data: []byte(`{"id":"123"}`)}
+
+	mockClient.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		return len(input.TransactItems) == 2 && input.TransactItems[0].Update != nil && input.TransactItems[1].Put != nil
+	})).Return(nil, &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+		{Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")},
+	}})
+
+	ok, err := repo.Patch(context.Background(), before, &after, []*events.Event{event})
+
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	mockClient.AssertExpectations(t)
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/pagination"
+	"github.com/MezeLaw/iris-services/internal/phones"
+	"github.com/MezeLaw/iris-services/internal/textnorm"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// La búsqueda usa una tabla aparte (client_id, search_key) con una entrada
+// por dato buscable. Los nombres se buscan por prefijo con begins_with y el
+// teléfono y el email por igualdad, siempre dentro del tenant.
+
+const (
+	searchPrefixName  = "n#"
+	searchPrefixPhone = "p#"
+	searchPrefixEmail = "e#"
+)
+
+var nonDigits = regexp.MustCompile(`\D`)
+
+// Search devuelve una página de pacientes del cliente que coinciden con
+// value según field (models.PatientSearchBy*). Con varias palabras en el
+// nombre se busca por la más larga y se filtra por las demás, así que una
+// página puede traer menos de limit resultados aunque haya más.
+func (d *DynamoPatientsRepository) Search(ctx context.Context, clientID, field, value, cursor string, limit int32) ([]*models.Patient, string, error) {
+	ctx, span := tracing.Start(ctx, "repository.Patients.Search")
+	defer span.End()
+	if d.SearchTableName == "" {
+		return nil, "", fmt.Errorf("patient search is not configured")
+	}
+	startKey, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyCond := expression.Key("client_id").Equal(expression.Value(clientID))
+	builder := expression.NewBuilder()
+	var prefix string
+	switch field {
+	case models.PatientSearchByName:
+		tokens := textnorm.Tokens(value)
+		if len(tokens) == 0 {
+			return []*models.Patient{}, "", nil
+		}
+		sort.SliceStable(tokens, func(i, j int) bool { return len(tokens[i]) > len(tokens[j]) })
+		prefix = searchPrefixName + tokens[0]
+		if len(tokens) > 1 {
+			filter := expression.Name("name").Contains(" " + tokens[1])
+			for _, token := range tokens[2:] {
+				filter = filter.And(expression.Name("name").Contains(" " + token))
+			}
+			builder = builder.WithFilter(filter)
+		}
+	case models.PatientSearchByPhone:
+		prefix = searchPrefixPhone + normalizePhone(value) + "#"
+	case models.PatientSearchByEmail:
+		prefix = searchPrefixEmail + normalizeEmail(value) + "#"
+	default:
+		return nil, "", fmt.Errorf("invalid search field %q", field)
+	}
+	keyCond = keyCond.And(expression.Key("search_key").BeginsWith(prefix))
+	expr, err := builder.WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 &d.SearchTableName,
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ExclusiveStartKey:         startKey,
+		Limit:                     aws.Int32(limit),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	var entries []models.PatientSearchEntry
+	if err := attributevalue.UnmarshalListOfMaps(resp.Items, &entries); err != nil {
+		return nil, "", err
+	}
+
+	var ids []string
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if !seen[entry.PatientID] {
+			seen[entry.PatientID] = true
+			ids = append(ids, entry.PatientID)
+		}
+	}
+	patients, err := d.getByIDs(ctx, ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Un paciente aparece una vez por token que empieza con el prefijo, y
+	// esas entradas pueden caer en páginas distintas: se devuelve sólo en la
+	// primera, la de search_key menor, así no se repite al pasar de página
+	results := []*models.Patient{}
+	returned := map[string]bool{}
+	for _, entry := range entries {
+		patient := patients[entry.PatientID]
+		// El índice se actualiza después del paciente; si quedó una entrada
+		// huérfana o de otro tenant se ignora
+		if returned[entry.PatientID] || patient == nil || patient.ClientID != clientID {
+			continue
+		}
+		if !firstMatch(patient, prefix, entry.SearchKey) {
+			continue
+		}
+		returned[entry.PatientID] = true
+		results = append(results, patient)
+	}
+	next, err := pagination.Encode(resp.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return results, next, nil
+}
+
+// firstMatch dice si key es la menor de las entradas de p que empiezan con
+// prefix, o sea la primera que devuelve la Query.
+func firstMatch(p *models.Patient, prefix, key string) bool {
+	for _, entry := range searchEntries(p) {
+		if strings.HasPrefix(entry.SearchKey, prefix) && entry.SearchKey < key {
+			return false
+		}
+	}
+	return true
+}
+
+// Reindex vuelve a escribir las entradas de búsqueda del paciente. Se usa
+// para cargar el índice de pacientes creados antes de que existiera.
+func (d *DynamoPatientsRepository) Reindex(ctx context.Context, p *models.Patient) error {
+	ctx, span := tracing.Start(ctx, "repository.Patients.Reindex")
+	defer span.End()
+	if d.SearchTableName == "" {
+		return nil
+	}
+	return d.indexSearch(ctx, nil, p)
+}
+
+// indexSearch borra las entradas de before que ya no corresponden y escribe
+// las de after. Cualquiera de los dos puede ser nil (alta o baja).
+func (d *DynamoPatientsRepository) indexSearch(ctx context.Context, before, after *models.Patient) error {
+	current := map[string]models.PatientSearchEntry{}
+	for _, entry := range searchEntries(after) {
+		current[entry.SearchKey] = entry
+	}
+
+	var requests []types.WriteRequest
+	for _, entry := range searchEntries(before) {
+		if _, ok := current[entry.SearchKey]; ok {
+			continue
+		}
+		key, err := attributevalue.MarshalMap(map[string]string{"client_id": entry.ClientID, "search_key": entry.SearchKey})
+		if err != nil {
+			return err
+		}
+		requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}})
+	}
+	for _, entry := range searchEntries(after) {
+		item, err := attributevalue.MarshalMap(entry)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+	}
+
+	for start := 0; start < len(requests); start += batchWriteLimit {
+		end := min(start+batchWriteLimit, len(requests))
+		failed := map[string]error{}
+		if err := d.batchWrite(ctx, d.SearchTableName, requests[start:end], failed); err != nil {
+			return err
+		}
+		for _, err := range failed {
+			return err
+		}
+	}
+	return nil
+}
+
+func searchEntries(p *models.Patient) []models.PatientSearchEntry {
+	if p == nil || p.ID == "" {
+		return nil
+	}
+	tokens := textnorm.Tokens(p.FirstName + " " + p.LastName)
+	name := " " + strings.Join(tokens, " ") + " "
+
+	var keys []string
+	for _, token := range tokens {
+		keys = append(keys, searchPrefixName+token)
+	}
+	if strings.HasPrefix(p.PhoneNumber, "+") {
+		// E.164: con y sin código de país, para encontrarlo se busque como se busque
+		for _, variant := range phones.Variants(p.PhoneNumber) {
+			keys = append(keys, searchPrefixPhone+variant)
+		}
+	} else if phone := normalizePhone(p.PhoneNumber); phone != "" {
+		keys = append(keys, searchPrefixPhone+phone)
+		if withCountry := normalizePhone(p.CountryCode + p.PhoneNumber); withCountry != phone {
+			keys = append(keys, searchPrefixPhone+withCountry)
+		}
+	}
+	if email := normalizeEmail(p.Email); email != "" {
+		keys = append(keys, searchPrefixEmail+email)
+	}
+
+	seen := map[string]bool{}
+	var entries []models.PatientSearchEntry
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		entries = append(entries, models.PatientSearchEntry{
+			ClientID:  p.ClientID,
+			SearchKey: key + "#" + p.ID,
+			PatientID: p.ID,
+			Name:      name,
+		})
+	}
+	return entries
+}
+
+func normalizePhone(phone string) string {
+	return nonDigits.ReplaceAllString(phone, "")
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func oldPatient(item map[string]types.AttributeValue) *models.Patient {
+	if len(item) == 0 {
+		return nil
+	}
+	var patient models.Patient
+	if err := attributevalue.UnmarshalMap(item, &patient); err != nil {
+		return nil
+	}
+	return &patient
+}
@@ -0,0 +1,395 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	outbox "github.com/MezeLaw/iris-services/internal/repository/outbox"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"go.uber.org/zap"
+)
+
+type SlotHoldsRepository interface {
+	Hold(ctx context.Context, h *models.SlotHold, now time.Time) (bool, error)
+	Get(ctx context.Context, slotKey string) (*models.SlotHold, error)
+	GetByDoctorID(ctx context.Context, doctorID string, now time.Time) ([]*models.SlotHold, error)
+	Confirm(ctx context.Context, h *models.SlotHold, appointment *models.Appointment, evts []*events.Event, now time.Time) (bool, error)
+	Book(ctx context.Context, appointment *models.Appointment, evts []*events.Event, now time.Time) (bool, error)
+	Release(ctx context.Context, slotKey, holdID string) error
+}
+
+// Locks de la agenda: cada reserva o turno nuevo toma un ítem por franja de
+// LockBucket que ocupa ("lock#<médico>#<inicio de la franja>"), en la misma
+// tabla y en la misma transacción en que se escribe. Dos pedidos que se
+// superponen, aunque empiecen a distinta hora, compiten por alguna franja y
+// sólo uno pasa. Los ítems no tienen doctor_id, así que no aparecen en el
+// índice de las reservas.
+//
+// El lock de una reserva dura lo que la reserva. El de un turno dura
+// BookingLease: una vez guardado, el turno ya ocupa la agenda para quien la
+// lee, y el lock sólo cubre el tiempo entre esa lectura y la escritura de un
+// pedido concurrente. Así cancelar o mover un turno no tiene que liberar nada.
+const (
+	LockBucket   = 5 * time.Minute
+	BookingLease = time.Minute
+	// Deja lugar en la transacción (máximo 100 ítems) para el turno, la
+	// reserva y los eventos.
+	maxLocks = 80
+)
+
+type lock struct {
+	SlotKey   string `dynamodbav:"slot_key"`
+	Owner     string `dynamodbav:"owner"`
+	ExpiresAt int64  `dynamodbav:"expires_at"`
+}
+
+// LockKeys devuelve las claves de las franjas que ocupa el intervalo. Las
+// franjas son de LockBucket en UTC: dos turnos contiguos no comparten
+// ninguna, pero uno que termina a mitad de una franja la toma entera.
+func LockKeys(doctorID, date string, duration int) ([]string, error) {
+	start, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return nil, err
+	}
+	end := start.Add(time.Duration(duration) * time.Minute)
+	var keys []string
+	for t := start.UTC().Truncate(LockBucket); t.Before(end); t = t.Add(LockBucket) {
+		keys = append(keys, "lock#"+doctorID+"#"+t.Format(time.RFC3339))
+	}
+	if len(keys) > maxLocks {
+		return nil, fmt.Errorf("interval spans %d slot locks, the limit is %d", len(keys), maxLocks)
+	}
+	return keys, nil
+}
+
+func holdOwner(holdID string) string { return "hold#" + holdID }
+
+func appointmentOwner(id string) string { return "appointment#" + id }
+
+// lockPuts toma las franjas para owner hasta expiresAt. Pasan las libres, las
+// vencidas y las que ya son de previousOwner (la reserva que se confirma).
+func (d *DynamoSlotHoldsRepository) lockPuts(keys []string, owner string, expiresAt int64, now time.Time, previousOwner string) ([]types.TransactWriteItem, error) {
+	cond := expression.AttributeNotExists(expression.Name("slot_key")).
+		Or(expression.Name("expires_at").LessThanEqual(expression.Value(now.Unix())))
+	if previousOwner != "" {
+		cond = cond.Or(expression.Name("owner").Equal(expression.Value(previousOwner)))
+	}
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return nil, err
+	}
+	writes := make([]types.TransactWriteItem, 0, len(keys))
+	for _, key := range keys {
+		item, err := attributevalue.MarshalMap(lock{SlotKey: key, Owner: owner, ExpiresAt: expiresAt})
+		if err != nil {
+			return nil, err
+		}
+		writes = append(writes, types.TransactWriteItem{Put: &types.Put{
+			TableName:                 &d.TableName,
+			Item:                      item,
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}})
+	}
+	return writes, nil
+}
+
+// transact escribe writes y devuelve false si alguna condición falló o la
+// transacción chocó con otra que tocaba los mismos ítems.
+func (d *DynamoSlotHoldsRepository) transact(ctx context.Context, writes []types.TransactWriteItem) (bool, error) {
+	_, err := d.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: writes})
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			if reason.Code != nil && (*reason.Code == "ConditionalCheckFailed" || *reason.Code == "TransactionConflict") {
+				return false, nil
+			}
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+type DynamoDBClient interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+// DynamoSlotHoldsRepository guarda las reservas temporales en TableName, con
+// TTL sobre expires_at. Confirm escribe también en AppointmentsTableName y,
+// si está configurado, los eventos en OutboxTableName.
+type DynamoSlotHoldsRepository struct {
+	Client                DynamoDBClient
+	Logger                *zap.SugaredLogger
+	TableName             string
+	DoctorIDIndex         string
+	AppointmentsTableName string
+	OutboxTableName       string
+}
+
+func New(client DynamoDBClient, logger *zap.SugaredLogger, tableName, doctorIDIndex, appointmentsTableName, outboxTableName string) SlotHoldsRepository {
+	return &DynamoSlotHoldsRepository{
+		Client:                client,
+		Logger:                logger,
+		TableName:             tableName,
+		DoctorIDIndex:         doctorIDIndex,
+		AppointmentsTableName: appointmentsTableName,
+		OutboxTableName:       outboxTableName,
+	}
+}
+
+// Hold guarda la reserva y toma las franjas que ocupa, si el horario está
+// libre o lo que lo ocupaba ya venció. Devuelve false si otra reserva o un
+// turno recién creado se superpone.
+func (d *DynamoSlotHoldsRepository) Hold(ctx context.Context, h *models.SlotHold, now time.Time) (bool, error) {
+	item, err := attributevalue.MarshalMap(h)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling slot hold", "error", err)
+		return false, err
+	}
+	cond := expression.AttributeNotExists(expression.Name("slot_key")).
+		Or(expression.Name("expires_at").LessThanEqual(expression.Value(now.Unix())))
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return false, err
+	}
+	keys, err := LockKeys(h.DoctorID, h.Date, h.Duration)
+	if err != nil {
+		return false, err
+	}
+	locks, err := d.lockPuts(keys, holdOwner(h.HoldID), h.ExpiresAt, now, "")
+	if err != nil {
+		return false, err
+	}
+
+	writes := append([]types.TransactWriteItem{{Put: &types.Put{
+		TableName:                 &d.TableName,
+		Item:                      item,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}}}, locks...)
+	return d.transact(ctx, writes)
+}
+
+func (d *DynamoSlotHoldsRepository) Get(ctx context.Context, slotKey string) (*models.SlotHold, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"slot_key": slotKey})
+	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &d.TableName,
+		Key:       key,
+	})
+	if err != nil || resp.Item == nil {
+		return nil, err
+	}
+	var hold models.SlotHold
+	if err := attributevalue.UnmarshalMap(resp.Item, &hold); err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// GetByDoctorID devuelve las reservas vigentes del médico.
+func (d *DynamoSlotHoldsRepository) GetByDoctorID(ctx context.Context, doctorID string, now time.Time) ([]*models.SlotHold, error) {
+	keyCond := expression.Key("doctor_id").Equal(expression.Value(doctorID))
+	filter := expression.Name("expires_at").GreaterThan(expression.Value(now.Unix()))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).WithFilter(filter).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*models.SlotHold
+	var startKey map[string]types.AttributeValue
+	for {
+		resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 &d.TableName,
+			IndexName:                 &d.DoctorIDIndex,
+			KeyConditionExpression:    expr.KeyCondition(),
+			FilterExpression:          expr.Filter(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var page []*models.SlotHold
+		if err := attributevalue.UnmarshalListOfMaps(resp.Items, &page); err != nil {
+			return nil, err
+		}
+		results = append(results, page...)
+		if len(resp.LastEvaluatedKey) == 0 {
+			return results, nil
+		}
+		startKey = resp.LastEvaluatedKey
+	}
+}
+
+// Confirm borra la reserva, crea el turno y guarda sus eventos en una sola
+// transacción. Devuelve false si la reserva ya no es de h.HoldID o venció: en
+// ese caso no se escribe nada.
+func (d *DynamoSlotHoldsRepository) Confirm(ctx context.Context, h *models.SlotHold, appointment *models.Appointment, evts []*events.Event, now time.Time) (bool, error) {
+	item, err := attributevalue.MarshalMap(appointment)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling appointment", "error", err)
+		return false, err
+	}
+	key, _ := attributevalue.MarshalMap(map[string]string{"slot_key": h.SlotKey})
+	holdCond, err := expression.NewBuilder().WithCondition(
+		expression.Name("hold_id").Equal(expression.Value(h.HoldID)).
+			And(expression.Name("expires_at").GreaterThan(expression.Value(now.Unix()))),
+	).Build()
+	if err != nil {
+		return false, err
+	}
+	appointmentCond, err := expression.NewBuilder().WithCondition(expression.AttributeNotExists(expression.Name("id"))).Build()
+	if err != nil {
+		return false, err
+	}
+
+	writes := []types.TransactWriteItem{
+		{Delete: &types.Delete{
+			TableName:                 &d.TableName,
+			Key:                       key,
+			ConditionExpression:       holdCond.Condition(),
+			ExpressionAttributeNames:  holdCond.Names(),
+			ExpressionAttributeValues: holdCond.Values(),
+		}},
+		{Put: &types.Put{
+			TableName:                &d.AppointmentsTableName,
+			Item:                     item,
+			ConditionExpression:      appointmentCond.Condition(),
+			ExpressionAttributeNames: appointmentCond.Names(),
+		}},
+	}
+	locks, err := d.bookingLocks(appointment, now, holdOwner(h.HoldID))
+	if err != nil {
+		return false, err
+	}
+	writes = append(writes, locks...)
+	if d.OutboxTableName != "" {
+		puts, err := outbox.Puts(d.OutboxTableName, evts)
+		if err != nil {
+			return false, err
+		}
+		writes = append(writes, puts...)
+	}
+	return d.transact(ctx, writes)
+}
+
+// Book crea un turno sin reserva previa: el turno, sus eventos y las franjas
+// que ocupa se escriben juntos. Devuelve false si alguna franja está tomada
+// por una reserva vigente o por otro turno que se está creando.
+func (d *DynamoSlotHoldsRepository) Book(ctx context.Context, appointment *models.Appointment, evts []*events.Event, now time.Time) (bool, error) {
+	item, err := attributevalue.MarshalMap(appointment)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling appointment", "error", err)
+		return false, err
+	}
+	appointmentCond, err := expression.NewBuilder().WithCondition(expression.AttributeNotExists(expression.Name("id"))).Build()
+	if err != nil {
+		return false, err
+	}
+	locks, err := d.bookingLocks(appointment, now, "")
+	if err != nil {
+		return false, err
+	}
+
+	writes := append([]types.TransactWriteItem{{Put: &types.Put{
+		TableName:                &d.AppointmentsTableName,
+		Item:                     item,
+		ConditionExpression:      appointmentCond.Condition(),
+		ExpressionAttributeNames: appointmentCond.Names(),
+	}}}, locks...)
+	if d.OutboxTableName != "" {
+		puts, err := outbox.Puts(d.OutboxTableName, evts)
+		if err != nil {
+			return false, err
+		}
+		writes = append(writes, puts...)
+	}
+	return d.transact(ctx, writes)
+}
+
+func (d *DynamoSlotHoldsRepository) bookingLocks(appointment *models.Appointment, now time.Time, previousOwner string) ([]types.TransactWriteItem, error) {
+	keys, err := LockKeys(appointment.DoctorID, appointment.Date, appointment.Duration)
+	if err != nil {
+		return nil, err
+	}
+	return d.lockPuts(keys, appointmentOwner(appointment.ID), now.Add(BookingLease).Unix(), now, previousOwner)
+}
+
+// Release borra la reserva si sigue siendo de holdID y libera sus franjas.
+func (d *DynamoSlotHoldsRepository) Release(ctx context.Context, slotKey, holdID string) error {
+	key, _ := attributevalue.MarshalMap(map[string]string{"slot_key": slotKey})
+	expr, err := expression.NewBuilder().WithCondition(expression.Name("hold_id").Equal(expression.Value(holdID))).Build()
+	if err != nil {
+		return err
+	}
+	resp, err := d.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 &d.TableName,
+		Key:                       key,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              types.ReturnValueAllOld,
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var released models.SlotHold
+	if err := attributevalue.UnmarshalMap(resp.Attributes, &released); err != nil {
+		return err
+	}
+	return d.releaseLocks(ctx, &released)
+}
+
+// releaseLocks borra las franjas que siguen siendo de la reserva. Si no se
+// pudieran borrar, vencen con ella.
+func (d *DynamoSlotHoldsRepository) releaseLocks(ctx context.Context, h *models.SlotHold) error {
+	keys, err := LockKeys(h.DoctorID, h.Date, h.Duration)
+	if err != nil {
+		return nil
+	}
+	expr, err := expression.NewBuilder().WithCondition(expression.Name("owner").Equal(expression.Value(holdOwner(h.HoldID)))).Build()
+	if err != nil {
+		return err
+	}
+	for _, lockKey := range keys {
+		key, _ := attributevalue.MarshalMap(map[string]string{"slot_key": lockKey})
+		_, err := d.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName:                 &d.TableName,
+			Key:                       key,
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		})
+		var conditionFailed *types.ConditionalCheckFailedException
+		if err != nil && !errors.As(err, &conditionFailed) {
+			return err
+		}
+	}
+	return nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del repositorio.
+func (d *DynamoSlotHoldsRepository) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, d.Logger)
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockDynamoDBClient struct {
+	mock.Mock
+}
+
+func (m *MockDynamoDBClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.PutItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.GetItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.QueryOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.DeleteItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dynamodb.TransactWriteItemsOutput), args.Error(1)
+}
+
+var now = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func setupTest() (*DynamoSlotHoldsRepository, *MockDynamoDBClient) {
+	client := new(MockDynamoDBClient)
+	repo := New(client, zap.NewNop().Sugar(), "holds", "doctor_id_index", "appointments", "outbox").(*DynamoSlotHoldsRepository)
+	return repo, client
+}
+
+func TestLockKeys(t *testing.T) {
+	keys, err := LockKeys("d1", "2024-01-16T10:15:00-03:00", 30)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"lock#d1#2024-01-16T13:15:00Z",
+		"lock#d1#2024-01-16T13:20:00Z",
+		"lock#d1#2024-01-16T13:25:00Z",
+		"lock#d1#2024-01-16T13:30:00Z",
+		"lock#d1#2024-01-16T13:35:00Z",
+		"lock#d1#2024-01-16T13:40:00Z",
+	}, keys)
+
+	// 13:00 por 30 minutos y 13:15 comparten franjas; 13:30 ya no
+	earlier, _ := LockKeys("d1", "2024-01-16T13:00:00Z", 30)
+	later, _ := LockKeys("d1", "2024-01-16T13:30:00Z", 30)
+	assert.Contains(t, earlier, keys[0])
+	assert.NotContains(t, later, earlier[len(earlier)-1])
+
+	// Un turno que no empieza en el borde de una franja la toma entera
+	odd, _ := LockKeys("d1", "2024-01-16T13:07:00Z", 10)
+	assert.Equal(t, []string{"lock#d1#2024-01-16T13:05:00Z", "lock#d1#2024-01-16T13:10:00Z", "lock#d1#2024-01-16T13:15:00Z"}, odd)
+
+	_, err = LockKeys("d1", "2024-01-16T13:00:00Z", 12*60)
+	assert.Error(t, err)
+}
+
+func TestHold_TakesLocks(t *testing.T) {
+	repo, client := setupTest()
+	h := &models.SlotHold{SlotKey: "d1#2024-01-16T13:00:00Z", HoldID: "h1", DoctorID: "d1", Date: "2024-01-16T13:00:00Z", Duration: 15, ExpiresAt: now.Add(10 * time.Minute).Unix()}
+
+	var input *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		input = args.Get(1).(*dynamodb.TransactWriteItemsInput)
+	}).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	held, err := repo.Hold(context.Background(), h, now)
+
+	require.NoError(t, err)
+	assert.True(t, held)
+	require.Len(t, input.TransactItems, 4)
+	lock := input.TransactItems[1].Put
+	assert.Equal(t, "lock#d1#2024-01-16T13:00:00Z", lock.Item["slot_key"].(*types.AttributeValueMemberS).Value)
+	assert.Equal(t, "hold#h1", lock.Item["owner"].(*types.AttributeValueMemberS).Value)
+	assert.Equal(t, "(attribute_not_exists (#0)) OR (#1 <= :0)", aws.ToString(lock.ConditionExpression))
+}
+
+func TestBook_LockTaken(t *testing.T) {
+	repo, client := setupTest()
+	appointment := &models.Appointment{ID: "a1", DoctorID: "d1", Date: "2024-01-16T13:10:00Z", Duration: 10}
+	event := &events.Event{ID: "evt1", Type: events.AppointmentBooked, AggregateID: "a1", Data: []byte(`{"id":"a1"}`)}
+
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		// turno, dos franjas y el evento
+		return len(input.TransactItems) == 4 && aws.ToString(input.TransactItems[0].Put.TableName) == "appointments"
+	})).Return(nil, &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+		{Code: aws.String("None")}, {Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")}, {Code: aws.String("None")},
+	}}).Once()
+
+	booked, err := repo.Book(context.Background(), appointment, []*events.Event{event}, now)
+
+	require.NoError(t, err)
+	assert.False(t, booked)
+	client.AssertExpectations(t)
+}
+
+// Al confirmar, las franjas de la propia reserva pasan al turno
+func TestConfirm_TakesOverHoldLocks(t *testing.T) {
+	repo, client := setupTest()
+	h := &models.SlotHold{SlotKey: "d1#2024-01-16T13:00:00Z", HoldID: "h1", DoctorID: "d1", Date: "2024-01-16T13:00:00Z", Duration: 5, ExpiresAt: now.Add(10 * time.Minute).Unix()}
+	appointment := &models.Appointment{ID: "a1", DoctorID: "d1", Date: h.Date, Duration: h.Duration}
+
+	var input *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		input = args.Get(1).(*dynamodb.TransactWriteItemsInput)
+	}).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	confirmed, err := repo.Confirm(context.Background(), h, appointment, nil, now)
+
+	require.NoError(t, err)
+	assert.True(t, confirmed)
+	require.Len(t, input.TransactItems, 3)
+	lock := input.TransactItems[2].Put
+	assert.Equal(t, "appointment#a1", lock.Item["owner"].(*types.AttributeValueMemberS).Value)
+	assert.Equal(t, "((attribute_not_exists (#0)) OR (#1 <= :0)) OR (#2 = :1)", aws.ToString(lock.ConditionExpression))
+	assert.Equal(t, "hold#h1", lock.ExpressionAttributeValues[":1"].(*types.AttributeValueMemberS).Value)
+}
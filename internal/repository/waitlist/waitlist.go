@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"go.uber.org/zap"
+)
+
+type WaitlistRepository interface {
+	Save(ctx context.Context, e *models.WaitlistEntry) error
+	GetByID(ctx context.Context, id string) (*models.WaitlistEntry, error)
+	GetByDoctorID(ctx context.Context, doctorID string) ([]*models.WaitlistEntry, error)
+	Delete(ctx context.Context, id string) error
+	SaveOffer(ctx context.Context, o *models.WaitlistOffer) error
+	GetOffer(ctx context.Context, id string) (*models.WaitlistOffer, error)
+	GetOpenOffers(ctx context.Context) ([]*models.WaitlistOffer, error)
+	ClaimOffer(ctx context.Context, id, entryID string) (bool, error)
+	ReleaseOffer(ctx context.Context, id string) error
+	ExpireOffer(ctx context.Context, id string) (bool, error)
+	MarkNotified(ctx context.Context, id, entryID string) (bool, error)
+	UnmarkNotified(ctx context.Context, id, entryID string) error
+}
+
+type DynamoDBClient interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// DynamoWaitlistRepository guarda los pacientes en espera en TableName y los
+// turnos ofrecidos en OffersTableName, con un índice por status para que la
+// tarea programada encuentre las ofertas abiertas.
+type DynamoWaitlistRepository struct {
+	Client            DynamoDBClient
+	Logger            *zap.SugaredLogger
+	TableName         string
+	DoctorIDIndex     string
+	OffersTableName   string
+	OffersStatusIndex string
+}
+
+func New(client DynamoDBClient, logger *zap.SugaredLogger, tableName, doctorIDIndex, offersTableName, offersStatusIndex string) WaitlistRepository {
+	return &DynamoWaitlistRepository{
+		Client:            client,
+		Logger:            logger,
+		TableName:         tableName,
+		DoctorIDIndex:     doctorIDIndex,
+		OffersTableName:   offersTableName,
+		OffersStatusIndex: offersStatusIndex,
+	}
+}
+
+func (d *DynamoWaitlistRepository) Save(ctx context.Context, e *models.WaitlistEntry) error {
+	item, err := attributevalue.MarshalMap(e)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling waitlist entry", "error", err)
+		return err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &d.TableName,
+		Item:      item,
+	})
+	return err
+}
+
+func (d *DynamoWaitlistRepository) GetByID(ctx context.Context, id string) (*models.WaitlistEntry, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &d.TableName,
+		Key:       key,
+	})
+	if err != nil || resp.Item == nil {
+		return nil, err
+	}
+	var entry models.WaitlistEntry
+	if err := attributevalue.UnmarshalMap(resp.Item, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (d *DynamoWaitlistRepository) GetByDoctorID(ctx context.Context, doctorID string) ([]*models.WaitlistEntry, error) {
+	keyCond := expression.Key("doctor_id").Equal(expression.Value(doctorID))
+	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+
+	var results []*models.WaitlistEntry
+	var startKey map[string]types.AttributeValue
+	for {
+		resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 &d.TableName,
+			IndexName:                 &d.DoctorIDIndex,
+			KeyConditionExpression:    expr.KeyCondition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var page []*models.WaitlistEntry
+		if err := attributevalue.UnmarshalListOfMaps(resp.Items, &page); err != nil {
+			return nil, err
+		}
+		results = append(results, page...)
+		if len(resp.LastEvaluatedKey) == 0 {
+			return results, nil
+		}
+		startKey = resp.LastEvaluatedKey
+	}
+}
+
+func (d *DynamoWaitlistRepository) Delete(ctx context.Context, id string) error {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	_, err := d.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &d.TableName,
+		Key:       key,
+	})
+	return err
+}
+
+func (d *DynamoWaitlistRepository) SaveOffer(ctx context.Context, o *models.WaitlistOffer) error {
+	item, err := attributevalue.MarshalMap(o)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling waitlist offer", "error", err)
+		return err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &d.OffersTableName,
+		Item:      item,
+	})
+	return err
+}
+
+func (d *DynamoWaitlistRepository) GetOffer(ctx context.Context, id string) (*models.WaitlistOffer, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &d.OffersTableName,
+		Key:       key,
+	})
+	if err != nil || resp.Item == nil {
+		return nil, err
+	}
+	var offer models.WaitlistOffer
+	if err := attributevalue.UnmarshalMap(resp.Item, &offer); err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+func (d *DynamoWaitlistRepository) GetOpenOffers(ctx context.Context) ([]*models.WaitlistOffer, error) {
+	keyCond := expression.Key("status").Equal(expression.Value(models.WaitlistOfferOpen))
+	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+
+	var results []*models.WaitlistOffer
+	var startKey map[string]types.AttributeValue
+	for {
+		resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 &d.OffersTableName,
+			IndexName:                 &d.OffersStatusIndex,
+			KeyConditionExpression:    expr.KeyCondition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var page []*models.WaitlistOffer
+		if err := attributevalue.UnmarshalListOfMaps(resp.Items, &page); err != nil {
+			return nil, err
+		}
+		results = append(results, page...)
+		if len(resp.LastEvaluatedKey) == 0 {
+			return results, nil
+		}
+		startKey = resp.LastEvaluatedKey
+	}
+}
+
+// ClaimOffer se queda con la oferta para entryID si sigue abierta. Devuelve
+// false si otro paciente la aceptó antes o ya venció.
+func (d *DynamoWaitlistRepository) ClaimOffer(ctx context.Context, id, entryID string) (bool, error) {
+	update := expression.Set(expression.Name("status"), expression.Value(models.WaitlistOfferClaimed)).
+		Set(expression.Name("claimed_by"), expression.Value(entryID))
+	return d.updateOffer(ctx, id, update, models.WaitlistOfferOpen)
+}
+
+// ReleaseOffer deshace ClaimOffer si no se pudo crear el turno.
+func (d *DynamoWaitlistRepository) ReleaseOffer(ctx context.Context, id string) error {
+	update := expression.Set(expression.Name("status"), expression.Value(models.WaitlistOfferOpen)).
+		Remove(expression.Name("claimed_by"))
+	_, err := d.updateOffer(ctx, id, update, models.WaitlistOfferClaimed)
+	return err
+}
+
+// ExpireOffer cierra una oferta abierta que ya nadie puede aceptar.
+func (d *DynamoWaitlistRepository) ExpireOffer(ctx context.Context, id string) (bool, error) {
+	update := expression.Set(expression.Name("status"), expression.Value(models.WaitlistOfferExpired))
+	return d.updateOffer(ctx, id, update, models.WaitlistOfferOpen)
+}
+
+// MarkNotified registra que se avisó a entryID de la oferta. Devuelve false si
+// ya estaba avisado.
+func (d *DynamoWaitlistRepository) MarkNotified(ctx context.Context, id, entryID string) (bool, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	update := expression.Add(expression.Name("notified"), expression.Value(types.AttributeValueMemberSS{Value: []string{entryID}}))
+	cond := expression.AttributeExists(expression.Name("id")).
+		And(expression.Not(expression.Contains(expression.Name("notified"), entryID)))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return false, err
+	}
+	return d.conditionalUpdate(ctx, key, expr)
+}
+
+// UnmarkNotified deshace MarkNotified cuando el aviso falló, para que la
+// próxima corrida lo reintente.
+func (d *DynamoWaitlistRepository) UnmarkNotified(ctx context.Context, id, entryID string) error {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	update := expression.Delete(expression.Name("notified"), expression.Value(types.AttributeValueMemberSS{Value: []string{entryID}}))
+	expr, err := expression.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return err
+	}
+	_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &d.OffersTableName,
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	return err
+}
+
+// updateOffer aplica update sólo si la oferta está en el status from.
+func (d *DynamoWaitlistRepository) updateOffer(ctx context.Context, id string, update expression.UpdateBuilder, from string) (bool, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	cond := expression.Name("status").Equal(expression.Value(from))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return false, err
+	}
+	return d.conditionalUpdate(ctx, key, expr)
+}
+
+func (d *DynamoWaitlistRepository) conditionalUpdate(ctx context.Context, key map[string]types.AttributeValue, expr expression.Expression) (bool, error) {
+	_, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &d.OffersTableName,
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del repositorio.
+func (d *DynamoWaitlistRepository) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, d.Logger)
+}
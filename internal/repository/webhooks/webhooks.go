@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"go.uber.org/zap"
+)
+
+type WebhooksRepository interface {
+	SaveSubscription(ctx context.Context, s *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error)
+	GetSubscriptionsByClientID(ctx context.Context, clientID string) ([]*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	SealSecret(ctx context.Context, id, ciphertext string) error
+	CreateDelivery(ctx context.Context, d *models.WebhookDelivery) (bool, error)
+	SaveDelivery(ctx context.Context, d *models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	GetDeliveriesByClientID(ctx context.Context, clientID, status string) ([]*models.WebhookDelivery, error)
+	GetDueDeliveries(ctx context.Context, now string, limit int32) ([]*models.WebhookDelivery, error)
+	ClaimDelivery(ctx context.Context, id, due, until string) (bool, error)
+}
+
+type DynamoDBClient interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// DynamoWebhooksRepository guarda las suscripciones en TableName y el
+// registro de entregas en DeliveriesTableName. Las entregas tienen un índice
+// (client_id, created_at) para el registro de cada clínica y otro (status,
+// next_attempt_at) para que la tarea programada encuentre las que tocan.
+type DynamoWebhooksRepository struct {
+	Client                  DynamoDBClient
+	Logger                  *zap.SugaredLogger
+	TableName               string
+	ClientIDIndex           string
+	DeliveriesTableName     string
+	DeliveriesClientIDIndex string
+	DeliveriesStatusIndex   string
+}
+
+func New(client DynamoDBClient, logger *zap.SugaredLogger, tableName, clientIDIndex, deliveriesTableName, deliveriesClientIDIndex, deliveriesStatusIndex string) WebhooksRepository {
+	return &DynamoWebhooksRepository{
+		Client:                  client,
+		Logger:                  logger,
+		TableName:               tableName,
+		ClientIDIndex:           clientIDIndex,
+		DeliveriesTableName:     deliveriesTableName,
+		DeliveriesClientIDIndex: deliveriesClientIDIndex,
+		DeliveriesStatusIndex:   deliveriesStatusIndex,
+	}
+}
+
+func (d *DynamoWebhooksRepository) SaveSubscription(ctx context.Context, s *models.WebhookSubscription) error {
+	item, err := attributevalue.MarshalMap(s)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling webhook subscription", "error", err)
+		return err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &d.TableName,
+		Item:      item,
+	})
+	return err
+}
+
+func (d *DynamoWebhooksRepository) GetSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &d.TableName,
+		Key:       key,
+	})
+	if err != nil || resp.Item == nil {
+		return nil, err
+	}
+	var subscription models.WebhookSubscription
+	if err := attributevalue.UnmarshalMap(resp.Item, &subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (d *DynamoWebhooksRepository) GetSubscriptionsByClientID(ctx context.Context, clientID string) ([]*models.WebhookSubscription, error) {
+	keyCond := expression.Key("client_id").Equal(expression.Value(clientID))
+	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+
+	var results []*models.WebhookSubscription
+	var startKey map[string]types.AttributeValue
+	for {
+		resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 &d.TableName,
+			IndexName:                 &d.ClientIDIndex,
+			KeyConditionExpression:    expr.KeyCondition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var page []*models.WebhookSubscription
+		if err := attributevalue.UnmarshalListOfMaps(resp.Items, &page); err != nil {
+			return nil, err
+		}
+		results = append(results, page...)
+		if len(resp.LastEvaluatedKey) == 0 {
+			return results, nil
+		}
+		startKey = resp.LastEvaluatedKey
+	}
+}
+
+func (d *DynamoWebhooksRepository) DeleteSubscription(ctx context.Context, id string) error {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	_, err := d.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &d.TableName,
+		Key:       key,
+	})
+	return err
+}
+
+// CreateDelivery guarda una entrega nueva. Devuelve false si ya existía: el
+// ID sale del evento y la suscripción, así que un evento que el relay
+// publica dos veces no se envía dos veces.
+// SealSecret reemplaza el secreto en claro de una suscripción anterior por
+// su versión cifrada. Si la suscripción se borró no hace nada.
+func (d *DynamoWebhooksRepository) SealSecret(ctx context.Context, id, ciphertext string) error {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	update := expression.Set(expression.Name("secret_ciphertext"), expression.Value(ciphertext)).
+		Remove(expression.Name("secret"))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(expression.AttributeExists(expression.Name("id"))).Build()
+	if err != nil {
+		return err
+	}
+	_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &d.TableName,
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+	return err
+}
+
+func (d *DynamoWebhooksRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) (bool, error) {
+	item, err := attributevalue.MarshalMap(delivery)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling webhook delivery", "error", err)
+		return false, err
+	}
+	cond := expression.AttributeNotExists(expression.Name("id"))
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return false, err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                &d.DeliveriesTableName,
+		Item:                     item,
+		ConditionExpression:      expr.Condition(),
+		ExpressionAttributeNames: expr.Names(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (d *DynamoWebhooksRepository) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	item, err := attributevalue.MarshalMap(delivery)
+	if err != nil {
+		d.log(ctx).Errorw("error marshalling webhook delivery", "error", err)
+		return err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &d.DeliveriesTableName,
+		Item:      item,
+	})
+	return err
+}
+
+func (d *DynamoWebhooksRepository) GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &d.DeliveriesTableName,
+		Key:       key,
+	})
+	if err != nil || resp.Item == nil {
+		return nil, err
+	}
+	var delivery models.WebhookDelivery
+	if err := attributevalue.UnmarshalMap(resp.Item, &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetDeliveriesByClientID devuelve el registro de entregas de la clínica, de
+// la más nueva a la más vieja. Con status filtra, por ejemplo las DEAD.
+func (d *DynamoWebhooksRepository) GetDeliveriesByClientID(ctx context.Context, clientID, status string) ([]*models.WebhookDelivery, error) {
+	builder := expression.NewBuilder().WithKeyCondition(expression.Key("client_id").Equal(expression.Value(clientID)))
+	if status != "" {
+		builder = builder.WithFilter(expression.Name("status").Equal(expression.Value(status)))
+	}
+	expr, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*models.WebhookDelivery
+	var startKey map[string]types.AttributeValue
+	for {
+		resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 &d.DeliveriesTableName,
+			IndexName:                 &d.DeliveriesClientIDIndex,
+			KeyConditionExpression:    expr.KeyCondition(),
+			FilterExpression:          expr.Filter(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ScanIndexForward:          aws.Bool(false),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var page []*models.WebhookDelivery
+		if err := attributevalue.UnmarshalListOfMaps(resp.Items, &page); err != nil {
+			return nil, err
+		}
+		results = append(results, page...)
+		if len(resp.LastEvaluatedKey) == 0 {
+			return results, nil
+		}
+		startKey = resp.LastEvaluatedKey
+	}
+}
+
+// GetDueDeliveries devuelve hasta limit entregas pendientes cuyo próximo
+// intento es anterior a now (RFC3339 en UTC), de la más atrasada a la más
+// reciente.
+func (d *DynamoWebhooksRepository) GetDueDeliveries(ctx context.Context, now string, limit int32) ([]*models.WebhookDelivery, error) {
+	keyCond := expression.Key("status").Equal(expression.Value(models.WebhookDeliveryPending)).
+		And(expression.Key("next_attempt_at").LessThanEqual(expression.Value(now)))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 &d.DeliveriesTableName,
+		IndexName:                 &d.DeliveriesStatusIndex,
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ScanIndexForward:          aws.Bool(true),
+		Limit:                     aws.Int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var results []*models.WebhookDelivery
+	if err := attributevalue.UnmarshalListOfMaps(resp.Items, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ClaimDelivery corre el próximo intento de una entrega pendiente de due a
+// until, para que otra corrida no la envíe mientras ésta lo hace. Devuelve
+// false si otra corrida la tomó antes.
+func (d *DynamoWebhooksRepository) ClaimDelivery(ctx context.Context, id, due, until string) (bool, error) {
+	key, _ := attributevalue.MarshalMap(map[string]string{"id": id})
+	update := expression.Set(expression.Name("next_attempt_at"), expression.Value(until))
+	cond := expression.Name("status").Equal(expression.Value(models.WebhookDeliveryPending)).
+		And(expression.Name("next_attempt_at").Equal(expression.Value(due)))
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(cond).Build()
+	if err != nil {
+		return false, err
+	}
+	_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &d.DeliveriesTableName,
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del repositorio.
+func (d *DynamoWebhooksRepository) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, d.Logger)
+}
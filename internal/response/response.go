@@ -0,0 +1,134 @@
+package response
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// Respuestas JSON de los Lambdas de la API. El cuerpo es siempre un sobre
+// {"data": ..., "meta": {...}}, o {"error": ..., "meta": {...}} si falló,
+// con el ID del pedido en meta.request_id y en el header X-Request-Id para
+// que el cliente lo pueda citar al reportar un problema. Los endpoints que
+// responden otros formatos (FHIR, iCalendar, HTML o archivos) no lo usan.
+
+const (
+	RequestIDHeader = "X-Request-Id"
+	ContentType     = "application/json"
+)
+
+type Envelope struct {
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+	Meta  Meta        `json:"meta"`
+}
+
+type Meta struct {
+	RequestID  string      `json:"request_id"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination acompaña a las listas. NextCursor va vacío en la última página.
+type Pagination struct {
+	Count      int    `json:"count"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// OK responde 200 con data.
+func OK(req events.APIGatewayProxyRequest, data interface{}) events.APIGatewayProxyResponse {
+	return JSON(req, 200, data)
+}
+
+// Created responde 201 con el recurso creado y su URL en Location.
+func Created(req events.APIGatewayProxyRequest, location string, data interface{}) events.APIGatewayProxyResponse {
+	resp := JSON(req, 201, data)
+	resp.Headers["Location"] = location
+	return resp
+}
+
+// Accepted responde 202 con el recurso que se sigue procesando y la URL
+// donde consultar su estado en Location.
+func Accepted(req events.APIGatewayProxyRequest, location string, data interface{}) events.APIGatewayProxyResponse {
+	resp := JSON(req, 202, data)
+	resp.Headers["Location"] = location
+	return resp
+}
+
+// Page responde 200 con una lista y los datos para pedir la página
+// siguiente.
+func Page(req events.APIGatewayProxyRequest, data interface{}, count int, nextCursor string) events.APIGatewayProxyResponse {
+	requestID := RequestID(req)
+	return build(requestID, 200, Envelope{
+		Data: data,
+		Meta: Meta{RequestID: requestID, Pagination: &Pagination{Count: count, NextCursor: nextCursor}},
+	})
+}
+
+// List es Page para las listas que se devuelven completas.
+func List[T any](req events.APIGatewayProxyRequest, items []T) events.APIGatewayProxyResponse {
+	if items == nil {
+		items = []T{}
+	}
+	return Page(req, items, len(items), "")
+}
+
+// NoContent responde 204 sin cuerpo.
+func NoContent(req events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: 204,
+		Headers:    map[string]string{RequestIDHeader: RequestID(req)},
+	}
+}
+
+// JSON responde status con data.
+func JSON(req events.APIGatewayProxyRequest, status int, data interface{}) events.APIGatewayProxyResponse {
+	requestID := RequestID(req)
+	return build(requestID, status, Envelope{Data: data, Meta: Meta{RequestID: requestID}})
+}
+
+// Error responde status con el mensaje de error.
+func Error(req events.APIGatewayProxyRequest, status int, message string) events.APIGatewayProxyResponse {
+	requestID := RequestID(req)
+	return build(requestID, status, Envelope{Error: message, Meta: Meta{RequestID: requestID}})
+}
+
+func build(requestID string, status int, envelope Envelope) events.APIGatewayProxyResponse {
+	body, _ := json.Marshal(envelope)
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Body:       string(body),
+		Headers: map[string]string{
+			"Content-Type":  ContentType,
+			RequestIDHeader: requestID,
+		},
+	}
+}
+
+// RequestID es el ID que API Gateway asignó al pedido. Si no lo hay (por
+// ejemplo, al invocar el Lambda directamente) se usa el X-Request-Id que
+// mandó el cliente o, en último caso, uno nuevo.
+func RequestID(req events.APIGatewayProxyRequest) string {
+	if req.RequestContext.RequestID != "" {
+		return req.RequestContext.RequestID
+	}
+	for name, value := range req.Headers {
+		if strings.EqualFold(name, RequestIDHeader) && validRequestID(value) {
+			return value
+		}
+	}
+	return uuid.NewString()
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
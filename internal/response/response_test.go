@@ -0,0 +1,67 @@
+package response
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func apiRequest(requestID string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{RequestContext: events.APIGatewayProxyRequestContext{RequestID: requestID}}
+}
+
+func TestCreated(t *testing.T) {
+	resp := Created(apiRequest("req-1"), "/patients/123", map[string]string{"id": "123"})
+
+	assert.Equal(t, 201, resp.StatusCode)
+	assert.Equal(t, "/patients/123", resp.Headers["Location"])
+	assert.Equal(t, "req-1", resp.Headers[RequestIDHeader])
+	assert.Equal(t, ContentType, resp.Headers["Content-Type"])
+	assert.JSONEq(t, `{"data":{"id":"123"},"meta":{"request_id":"req-1"}}`, resp.Body)
+}
+
+func TestList(t *testing.T) {
+	var none []string
+	resp := List(apiRequest("req-1"), none)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.JSONEq(t, `{"data":[],"meta":{"request_id":"req-1","pagination":{"count":0}}}`, resp.Body)
+
+	resp = Page(apiRequest("req-1"), []string{"a", "b"}, 2, "next")
+	assert.JSONEq(t, `{"data":["a","b"],"meta":{"request_id":"req-1","pagination":{"count":2,"next_cursor":"next"}}}`, resp.Body)
+}
+
+func TestError(t *testing.T) {
+	resp := Error(apiRequest("req-1"), 404, "patient not found")
+
+	assert.Equal(t, 404, resp.StatusCode)
+	assert.JSONEq(t, `{"error":"patient not found","meta":{"request_id":"req-1"}}`, resp.Body)
+}
+
+func TestNoContent(t *testing.T) {
+	resp := NoContent(apiRequest("req-1"))
+
+	assert.Equal(t, 204, resp.StatusCode)
+	assert.Empty(t, resp.Body)
+	assert.Equal(t, "req-1", resp.Headers[RequestIDHeader])
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Equal(t, "req-1", RequestID(apiRequest("req-1")))
+
+	fromClient := events.APIGatewayProxyRequest{Headers: map[string]string{"x-request-id": "client-42"}}
+	assert.Equal(t, "client-42", RequestID(fromClient))
+
+	invalid := events.APIGatewayProxyRequest{Headers: map[string]string{"X-Request-Id": "has spaces"}}
+	generated := RequestID(invalid)
+	assert.NotEqual(t, "has spaces", generated)
+	assert.Len(t, generated, 36)
+
+	// El ID generado es el mismo en el header y en el cuerpo
+	resp := OK(events.APIGatewayProxyRequest{}, "ok")
+	var envelope Envelope
+	require.NoError(t, json.Unmarshal([]byte(resp.Body), &envelope))
+	assert.Equal(t, resp.Headers[RequestIDHeader], envelope.Meta.RequestID)
+}
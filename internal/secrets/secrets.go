@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// Cifrado de secretos chicos, como los de firma de los webhooks, para no
+// guardarlos en claro en DynamoDB. El contexto de cifrado ata cada texto
+// cifrado al registro que lo guarda: descifrarlo con otro contexto falla.
+
+var ErrNotConfigured = errors.New("secrets key is not configured")
+
+type Cipher interface {
+	Encrypt(ctx context.Context, plaintext string, scope map[string]string) (string, error)
+	Decrypt(ctx context.Context, ciphertext string, scope map[string]string) (string, error)
+}
+
+type KMSClient interface {
+	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// KMSCipher cifra con la clave simétrica KeyID de KMS. El texto cifrado se
+// guarda en base64.
+type KMSCipher struct {
+	Client KMSClient
+	KeyID  string
+}
+
+func NewKMSCipher(client KMSClient, keyID string) *KMSCipher {
+	return &KMSCipher{Client: client, KeyID: keyID}
+}
+
+func (k *KMSCipher) Encrypt(ctx context.Context, plaintext string, scope map[string]string) (string, error) {
+	out, err := k.Client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:             aws.String(k.KeyID),
+		Plaintext:         []byte(plaintext),
+		EncryptionContext: scope,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(out.CiphertextBlob), nil
+}
+
+func (k *KMSCipher) Decrypt(ctx context.Context, ciphertext string, scope map[string]string) (string, error) {
+	blob, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	out, err := k.Client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(k.KeyID),
+		CiphertextBlob:    blob,
+		EncryptionContext: scope,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out.Plaintext), nil
+}
+
+// FromEnv usa la clave de KMS de SECRETS_KMS_KEY_ID (ID, ARN o alias).
+func FromEnv(cfg aws.Config) (Cipher, error) {
+	keyID := os.Getenv("SECRETS_KMS_KEY_ID")
+	if keyID == "" {
+		return nil, ErrNotConfigured
+	}
+	return NewKMSCipher(kms.NewFromConfig(cfg), keyID), nil
+}
@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMS "cifra" invirtiendo los bytes y exige el mismo contexto al
+// descifrar, como KMS.
+type fakeKMS struct {
+	contexts map[string]map[string]string
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
+
+func (f *fakeKMS) Encrypt(_ context.Context, params *kms.EncryptInput, _ ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	blob := reverse(params.Plaintext)
+	f.contexts[string(blob)] = params.EncryptionContext
+	return &kms.EncryptOutput{CiphertextBlob: blob}, nil
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, params *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	if !maps.Equal(f.contexts[string(params.CiphertextBlob)], params.EncryptionContext) {
+		return nil, errors.New("InvalidCiphertextException")
+	}
+	return &kms.DecryptOutput{Plaintext: reverse(params.CiphertextBlob)}, nil
+}
+
+func TestKMSCipher(t *testing.T) {
+	cipher := NewKMSCipher(&fakeKMS{contexts: map[string]map[string]string{}}, "alias/iris-secrets")
+	ctx := context.Background()
+	scope := map[string]string{"subscription_id": "s1"}
+
+	ciphertext, err := cipher.Encrypt(ctx, "whsec_abc", scope)
+	require.NoError(t, err)
+	assert.NotContains(t, ciphertext, "whsec_abc")
+
+	plaintext, err := cipher.Decrypt(ctx, ciphertext, scope)
+	require.NoError(t, err)
+	assert.Equal(t, "whsec_abc", plaintext)
+
+	_, err = cipher.Decrypt(ctx, ciphertext, map[string]string{"subscription_id": "s2"})
+	assert.Error(t, err)
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("SECRETS_KMS_KEY_ID", "")
+	_, err := FromEnv(aws.Config{})
+	assert.ErrorIs(t, err, ErrNotConfigured)
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/actiontoken"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrActionNotAllowed = errors.New("appointment action not allowed")
+	ErrActionTokenUsed  = errors.New("action token already used")
+)
+
+// ActionTokenStore registra los enlaces ya usados; lo implementa el
+// repositorio de turnos.
+type ActionTokenStore interface {
+	UseActionToken(ctx context.Context, id, nonce string) (bool, error)
+	ReleaseActionToken(ctx context.Context, id, nonce string) error
+}
+
+// Actions configura la confirmación y cancelación con enlaces firmados.
+// CancellationCutoffs es la anticipación mínima para cancelar por client_id;
+// DefaultCancellationCutoff aplica a los clientes que no están.
+type Actions struct {
+	Signer                    *actiontoken.Signer
+	Tokens                    ActionTokenStore
+	CancellationCutoffs       map[string]time.Duration
+	DefaultCancellationCutoff time.Duration
+	Now                       func() time.Time
+}
+
+// ParseCancellationCutoffs lee CANCELLATION_CUTOFFS: {"client_id": "24h"}.
+func ParseCancellationCutoffs(raw string) (map[string]time.Duration, error) {
+	cutoffs := map[string]time.Duration{}
+	if raw == "" {
+		return cutoffs, nil
+	}
+	var values map[string]string
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, err
+	}
+	for clientID, value := range values {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid cancellation cutoff %q for client %s", value, clientID)
+		}
+		cutoffs[clientID] = d
+	}
+	return cutoffs, nil
+}
+
+func (c *Actions) cutoff(clientID string) time.Duration {
+	if d, ok := c.CancellationCutoffs[clientID]; ok {
+		return d
+	}
+	return c.DefaultCancellationCutoff
+}
+
+func (c *Actions) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// CheckAction valida el enlace sin usarlo, para mostrarle al paciente qué va a
+// hacer antes de que lo confirme.
+func (a *Appointments) CheckAction(ctx context.Context, token string) (*models.AppointmentAction, error) {
+	ctx, span := tracing.Start(ctx, "service.Appointments.CheckAction")
+	defer span.End()
+	claims, appointment, err := a.checkAction(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return actionResult(claims, appointment), nil
+}
+
+// ApplyAction confirma o cancela el turno del enlace. El enlace queda usado
+// antes de modificar el turno, así dos clics simultáneos no lo aplican dos
+// veces; si la modificación falla se libera para poder reintentar.
+func (a *Appointments) ApplyAction(ctx context.Context, token string) (*models.AppointmentAction, error) {
+	ctx, span := tracing.Start(ctx, "service.Appointments.ApplyAction")
+	defer span.End()
+	claims, appointment, err := a.checkAction(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := a.Actions.Tokens.UseActionToken(ctx, appointment.ID, claims.Nonce)
+	if err != nil {
+		a.log(ctx).Error("Error marking action token as used", zap.String("id", appointment.ID), zap.Error(err))
+		return nil, err
+	}
+	if !used {
+		return nil, ErrActionTokenUsed
+	}
+
+	request := a.mapAppointmentToRequest(appointment)
+	request.Status = models.AppointmentStatusConfirmed
+	if claims.Action == actiontoken.ActionCancel {
+		request.Status = models.AppointmentStatusCancelled
+	}
+	if _, err := a.UpdateAppointment(ctx, request); err != nil {
+		if releaseErr := a.Actions.Tokens.ReleaseActionToken(ctx, appointment.ID, claims.Nonce); releaseErr != nil {
+			a.log(ctx).Error("Error releasing action token", zap.String("id", appointment.ID), zap.Error(releaseErr))
+		}
+		return nil, err
+	}
+
+	appointment.Status = request.Status
+	a.log(ctx).Info("Appointment action applied", zap.String("id", appointment.ID), zap.String("action", claims.Action))
+	return actionResult(claims, appointment), nil
+}
+
+func (a *Appointments) checkAction(ctx context.Context, token string) (*actiontoken.Claims, *models.Appointment, error) {
+	if a.Actions == nil {
+		return nil, nil, fmt.Errorf("appointment actions are not configured")
+	}
+	claims, err := a.Actions.Signer.Verify(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	appointment, err := a.AppointmentsRepository.GetByID(ctx, claims.AppointmentID)
+	if err != nil {
+		a.log(ctx).Error("Error fetching appointment for action", zap.String("id", claims.AppointmentID), zap.Error(err))
+		return nil, nil, err
+	}
+	if appointment == nil {
+		return nil, nil, fmt.Errorf("%w: appointment not found", actiontoken.ErrInvalid)
+	}
+	for _, nonce := range appointment.ActionTokensUsed {
+		if nonce == claims.Nonce {
+			return nil, nil, ErrActionTokenUsed
+		}
+	}
+
+	start, err := time.Parse(time.RFC3339, appointment.Date)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid appointment date %q: %w", appointment.Date, err)
+	}
+	now := a.Actions.now()
+	switch claims.Action {
+	case actiontoken.ActionConfirm:
+		if appointment.Status != models.AppointmentStatusScheduled {
+			return nil, nil, fmt.Errorf("%w: appointment is %s", ErrActionNotAllowed, appointment.Status)
+		}
+		if !now.Before(start) {
+			return nil, nil, fmt.Errorf("%w: appointment already started", ErrActionNotAllowed)
+		}
+	case actiontoken.ActionCancel:
+		if appointment.Status != models.AppointmentStatusScheduled && appointment.Status != models.AppointmentStatusConfirmed {
+			return nil, nil, fmt.Errorf("%w: appointment is %s", ErrActionNotAllowed, appointment.Status)
+		}
+		if cutoff := a.Actions.cutoff(appointment.ClientID); now.After(start.Add(-cutoff)) {
+			return nil, nil, fmt.Errorf("%w: cancellations close %s before the appointment", ErrActionNotAllowed, cutoff)
+		}
+	}
+	return claims, appointment, nil
+}
+
+func actionResult(claims *actiontoken.Claims, appointment *models.Appointment) *models.AppointmentAction {
+	return &models.AppointmentAction{
+		Action:        claims.Action,
+		AppointmentID: appointment.ID,
+		Date:          appointment.Date,
+		Status:        appointment.Status,
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/actiontoken"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockActionTokenStore struct {
+	mock.Mock
+}
+
+func (m *MockActionTokenStore) UseActionToken(ctx context.Context, id, nonce string) (bool, error) {
+	args := m.Called(ctx, id, nonce)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockActionTokenStore) ReleaseActionToken(ctx context.Context, id, nonce string) error {
+	args := m.Called(ctx, id, nonce)
+	return args.Error(0)
+}
+
+var actionNow = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func setupActionsTest() (*Appointments, *MockAppointmentsRepository, *MockActionTokenStore) {
+	service, mockRepo := setupTest()
+	tokens := new(MockActionTokenStore)
+	service.Actions = &Actions{
+		Signer:                    &actiontoken.Signer{Secret: []byte("secret"), Now: func() time.Time { return actionNow }},
+		Tokens:                    tokens,
+		CancellationCutoffs:       map[string]time.Duration{"strict": 48 * time.Hour},
+		DefaultCancellationCutoff: 24 * time.Hour,
+		Now:                       func() time.Time { return actionNow },
+	}
+	return service, mockRepo, tokens
+}
+
+func actionAppointment(clientID, date string, status models.AppointmentStatus) *models.Appointment {
+	appointment := createSampleAppointment("appointment123")
+	appointment.ClientID, appointment.Date, appointment.Status = clientID, date, status
+	return appointment
+}
+
+func TestAppointments_ApplyAction_Confirm(t *testing.T) {
+	service, mockRepo, tokens := setupActionsTest()
+	ctx := context.Background()
+	token, _ := service.Actions.Signer.Sign("appointment123", actiontoken.ActionConfirm, actionNow.Add(48*time.Hour))
+	mockRepo.On("GetByID", ctx, "appointment123").Return(actionAppointment("client123", "2024-01-16T10:00:00-03:00", models.AppointmentStatusScheduled), nil)
+	tokens.On("UseActionToken", ctx, "appointment123", mock.Anything).Return(true, nil).Once()
+	mockRepo.On("Patch", ctx, mock.Anything, mock.MatchedBy(func(a *models.Appointment) bool {
+		return a.Status == models.AppointmentStatusConfirmed
+	}), mock.Anything).Return(true, nil).Once()
+
+	result, err := service.ApplyAction(ctx, token)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.AppointmentStatusConfirmed, result.Status)
+	mockRepo.AssertExpectations(t)
+	tokens.AssertExpectations(t)
+}
+
+func TestAppointments_ApplyAction_CancellationCutoff(t *testing.T) {
+	service, mockRepo, tokens := setupActionsTest()
+	ctx := context.Background()
+	token, _ := service.Actions.Signer.Sign("appointment123", actiontoken.ActionCancel, actionNow.Add(48*time.Hour))
+	// Faltan 30 h: alcanza para el corte por defecto (24 h) pero no para "strict" (48 h)
+	mockRepo.On("GetByID", ctx, "appointment123").Return(actionAppointment("strict", "2024-01-16T18:00:00Z", models.AppointmentStatusConfirmed), nil).Once()
+
+	_, err := service.ApplyAction(ctx, token)
+	assert.ErrorIs(t, err, ErrActionNotAllowed)
+	tokens.AssertNotCalled(t, "UseActionToken", mock.Anything, mock.Anything, mock.Anything)
+
+	mockRepo.On("GetByID", ctx, "appointment123").Return(actionAppointment("client123", "2024-01-16T18:00:00Z", models.AppointmentStatusConfirmed), nil)
+	tokens.On("UseActionToken", ctx, "appointment123", mock.Anything).Return(true, nil).Once()
+	mockRepo.On("Patch", ctx, mock.Anything, mock.MatchedBy(func(a *models.Appointment) bool {
+		return a.Status == models.AppointmentStatusCancelled
+	}), mock.Anything).Return(true, nil).Once()
+
+	result, err := service.ApplyAction(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, models.AppointmentStatusCancelled, result.Status)
+}
+
+func TestAppointments_ApplyAction_SingleUse(t *testing.T) {
+	service, mockRepo, tokens := setupActionsTest()
+	ctx := context.Background()
+	token, _ := service.Actions.Signer.Sign("appointment123", actiontoken.ActionConfirm, actionNow.Add(48*time.Hour))
+	claims, _ := service.Actions.Signer.Verify(token)
+
+	used := actionAppointment("client123", "2024-01-16T10:00:00Z", models.AppointmentStatusScheduled)
+	used.ActionTokensUsed = []string{claims.Nonce}
+	mockRepo.On("GetByID", ctx, "appointment123").Return(used, nil).Once()
+	_, err := service.CheckAction(ctx, token)
+	assert.ErrorIs(t, err, ErrActionTokenUsed)
+
+	// Dos clics a la vez: el segundo pierde la escritura condicional
+	mockRepo.On("GetByID", ctx, "appointment123").Return(actionAppointment("client123", "2024-01-16T10:00:00Z", models.AppointmentStatusScheduled), nil)
+	tokens.On("UseActionToken", ctx, "appointment123", claims.Nonce).Return(false, nil)
+	_, err = service.ApplyAction(ctx, token)
+	assert.ErrorIs(t, err, ErrActionTokenUsed)
+	mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAppointments_ApplyAction_ReleasesTokenOnError(t *testing.T) {
+	service, mockRepo, tokens := setupActionsTest()
+	ctx := context.Background()
+	token, _ := service.Actions.Signer.Sign("appointment123", actiontoken.ActionConfirm, actionNow.Add(48*time.Hour))
+	mockRepo.On("GetByID", ctx, "appointment123").Return(actionAppointment("client123", "2024-01-16T10:00:00Z", models.AppointmentStatusScheduled), nil)
+	tokens.On("UseActionToken", ctx, "appointment123", mock.Anything).Return(true, nil)
+	mockRepo.On("Patch", ctx, mock.Anything, mock.Anything, mock.Anything).Return(false, errors.New("database error"))
+	tokens.On("ReleaseActionToken", ctx, "appointment123", mock.Anything).Return(nil).Once()
+
+	_, err := service.ApplyAction(ctx, token)
+
+	assert.Error(t, err)
+	tokens.AssertExpectations(t)
+}
+
+func TestAppointments_CheckAction_Invalid(t *testing.T) {
+	service, mockRepo, _ := setupActionsTest()
+	ctx := context.Background()
+
+	_, err := service.CheckAction(ctx, "forged.token")
+	assert.ErrorIs(t, err, actiontoken.ErrInvalid)
+
+	expired, _ := service.Actions.Signer.Sign("appointment123", actiontoken.ActionConfirm, actionNow.Add(-time.Minute))
+	_, err = service.CheckAction(ctx, expired)
+	assert.ErrorIs(t, err, actiontoken.ErrExpired)
+
+	cancelled, _ := service.Actions.Signer.Sign("appointment123", actiontoken.ActionConfirm, actionNow.Add(time.Hour))
+	mockRepo.On("GetByID", ctx, "appointment123").Return(actionAppointment("client123", "2024-01-16T10:00:00Z", models.AppointmentStatusCancelled), nil)
+	_, err = service.CheckAction(ctx, cancelled)
+	assert.ErrorIs(t, err, ErrActionNotAllowed)
+
+	service.Actions = nil
+	_, err = service.CheckAction(ctx, cancelled)
+	assert.Error(t, err)
+}
+
+func TestParseCancellationCutoffs(t *testing.T) {
+	cutoffs, err := ParseCancellationCutoffs(`{"client123":"12h"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 12*time.Hour, cutoffs["client123"])
+
+	_, err = ParseCancellationCutoffs(`{"client123":"medio día"}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"go.uber.org/zap"
+)
+
+// EventStore guarda el turno junto con sus eventos de dominio en una sola
+// transacción. Es opcional: sin él los turnos se guardan sin eventos.
+type EventStore interface {
+	SaveWithEvents(ctx context.Context, a *models.Appointment, evts []*events.Event) error
+	DeleteWithEvents(ctx context.Context, id string, evts []*events.Event) error
+}
+
+// AppointmentRescheduledData es el payload de AppointmentRescheduled: el
+// turno como quedó y el horario que tenía antes.
+type AppointmentRescheduledData struct {
+	Appointment      *models.Appointment `json:"appointment"`
+	PreviousDoctorID string              `json:"previous_doctor_id"`
+	PreviousDate     string              `json:"previous_date"`
+	PreviousDuration int                 `json:"previous_duration"`
+}
+
+// save guarda el turno con el evento eventType. data reemplaza al turno como
+// payload cuando no es nil.
+func (a *Appointments) save(ctx context.Context, appointment *models.Appointment, eventType string, data interface{}) error {
+	if a.Events == nil {
+		return a.AppointmentsRepository.Save(ctx, appointment)
+	}
+	evts, err := appointmentEvents(appointment, eventType, data)
+	if err != nil {
+		a.log(ctx).Error("Error building appointment event", zap.String("type", eventType), zap.Error(err))
+		return err
+	}
+	return a.Events.SaveWithEvents(ctx, appointment, evts)
+}
+
+func (a *Appointments) delete(ctx context.Context, existing *models.Appointment, id string) error {
+	if a.Events == nil || existing == nil {
+		return a.AppointmentsRepository.Delete(ctx, id)
+	}
+	evts, err := appointmentEvents(existing, events.AppointmentDeleted, nil)
+	if err != nil {
+		a.log(ctx).Error("Error building appointment event", zap.String("type", events.AppointmentDeleted), zap.Error(err))
+		return err
+	}
+	return a.Events.DeleteWithEvents(ctx, id, evts)
+}
+
+func appointmentEvents(appointment *models.Appointment, eventType string, data interface{}) ([]*events.Event, error) {
+	if data == nil {
+		data = appointment
+	}
+	event, err := events.New(eventType, events.AggregateAppointment, appointment.ID, appointment.ClientID, data)
+	if err != nil {
+		return nil, err
+	}
+	return []*events.Event{event}, nil
+}
+
+// updateEvent elige el evento de una modificación: la cancelación y la
+// ausencia pesan más que el cambio de horario y éste más que cualquier otro
+// cambio.
+func updateEvent(before, after *models.Appointment) (string, interface{}) {
+	if after.Status == models.AppointmentStatusCancelled && before.Status != models.AppointmentStatusCancelled {
+		return events.AppointmentCancelled, nil
+	}
+	if after.Status == models.AppointmentStatusNoShow && before.Status != models.AppointmentStatusNoShow {
+		return events.AppointmentNoShow, nil
+	}
+	if after.Date != before.Date || after.Duration != before.Duration || after.DoctorID != before.DoctorID {
+		return events.AppointmentRescheduled, &AppointmentRescheduledData{
+			Appointment:      after,
+			PreviousDoctorID: before.DoctorID,
+			PreviousDate:     before.Date,
+			PreviousDuration: before.Duration,
+		}
+	}
+	return events.AppointmentUpdated, nil
+}
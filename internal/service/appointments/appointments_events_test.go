@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockEventStore struct {
+	mock.Mock
+}
+
+func (m *MockEventStore) SaveWithEvents(ctx context.Context, a *models.Appointment, evts []*events.Event) error {
+	args := m.Called(ctx, a, evts)
+	return args.Error(0)
+}
+
+func (m *MockEventStore) DeleteWithEvents(ctx context.Context, id string, evts []*events.Event) error {
+	args := m.Called(ctx, id, evts)
+	return args.Error(0)
+}
+
+func setupEventsTest() (*Appointments, *MockAppointmentsRepository, *MockEventStore) {
+	service, mockRepo := setupTest()
+	store := new(MockEventStore)
+	service.Events = store
+	return service, mockRepo, store
+}
+
+// eventOfType captura el único evento guardado si es del tipo esperado.
+func eventOfType(eventType string, captured **events.Event) interface{} {
+	return mock.MatchedBy(func(evts []*events.Event) bool {
+		if len(evts) != 1 || evts[0].Type != eventType {
+			return false
+		}
+		*captured = evts[0]
+		return true
+	})
+}
+
+func TestAppointments_CreateAppointment_EmitsBooked(t *testing.T) {
+	service, mockRepo, store := setupEventsTest()
+	ctx := context.Background()
+	var event *events.Event
+	store.On("SaveWithEvents", ctx, mock.AnythingOfType("*models.Appointment"), eventOfType(events.AppointmentBooked, &event)).Return(nil)
+
+	result, err := service.CreateAppointment(ctx, createSampleAppointmentRequest())
+
+	require.NoError(t, err)
+	assert.Equal(t, result.ID, event.AggregateID)
+	assert.Equal(t, "client123", event.ClientID)
+	assert.Equal(t, events.AggregateAppointment, event.AggregateType)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestAppointments_UpdateAppointment_Events(t *testing.T) {
+	testCases := []struct {
+		name   string
+		update func(*models.AppointmentRequest)
+		want   string
+	}{
+		{"Updated", func(r *models.AppointmentRequest) { r.Notes = "Traer estudios" }, events.AppointmentUpdated},
+		{"Rescheduled", func(r *models.AppointmentRequest) { r.Date = "2024-02-01T10:00:00Z" }, events.AppointmentRescheduled},
+		{"Cancelled", func(r *models.AppointmentRequest) {
+			r.Date = "2024-02-01T10:00:00Z"
+			r.Status = models.AppointmentStatusCancelled
+		}, events.AppointmentCancelled},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockRepo, store := setupEventsTest()
+			ctx := context.Background()
+			existing := createSampleAppointment("appointment123")
+			existing.Date = "2024-01-20T10:00:00Z"
+			mockRepo.On("GetByID", ctx, "appointment123").Return(existing, nil)
+			var event *events.Event
+			mockRepo.On("Patch", ctx, existing, mock.AnythingOfType("*models.Appointment"), eventOfType(tc.want, &event)).Return(true, nil)
+
+			req := createSampleAppointmentRequest()
+			req.ID, req.Date = "appointment123", existing.Date
+			tc.update(req)
+			_, err := service.UpdateAppointment(ctx, req)
+			require.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+			store.AssertNotCalled(t, "SaveWithEvents", mock.Anything, mock.Anything, mock.Anything)
+
+			if tc.want == events.AppointmentRescheduled {
+				var data AppointmentRescheduledData
+				require.NoError(t, json.Unmarshal(event.Data, &data))
+				assert.Equal(t, "2024-01-20T10:00:00Z", data.PreviousDate)
+				assert.Equal(t, "2024-02-01T10:00:00Z", data.Appointment.Date)
+			}
+		})
+	}
+}
+
+func TestAppointments_DeleteAppointment_EmitsDeleted(t *testing.T) {
+	service, mockRepo, store := setupEventsTest()
+	ctx := context.Background()
+	mockRepo.On("GetByID", ctx, "appointment123").Return(createSampleAppointment("appointment123"), nil)
+	var event *events.Event
+	store.On("DeleteWithEvents", ctx, "appointment123", eventOfType(events.AppointmentDeleted, &event)).Return(nil)
+
+	require.NoError(t, service.DeleteAppointment(ctx, "appointment123"))
+
+	assert.Equal(t, "appointment123", event.AggregateID)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/availability"
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidHold  = errors.New("invalid slot hold")
+	ErrSlotHeld     = errors.New("slot is already held or booked")
+	ErrHoldNotFound = errors.New("slot hold not found or expired")
+)
+
+const (
+	defaultHoldDuration = 10 * time.Minute
+	maxHoldDuration     = 30 * time.Minute
+	// maxSlotMinutes es lo más largo que se puede reservar: cada franja de
+	// la agenda es un ítem de la transacción que la toma.
+	maxSlotMinutes = 6 * 60
+)
+
+// HoldStore guarda las reservas temporales; lo implementa el repositorio de
+// slotholds.
+type HoldStore interface {
+	Hold(ctx context.Context, h *models.SlotHold, now time.Time) (bool, error)
+	Get(ctx context.Context, slotKey string) (*models.SlotHold, error)
+	GetByDoctorID(ctx context.Context, doctorID string, now time.Time) ([]*models.SlotHold, error)
+	Confirm(ctx context.Context, h *models.SlotHold, appointment *models.Appointment, evts []*events.Event, now time.Time) (bool, error)
+	Book(ctx context.Context, appointment *models.Appointment, evts []*events.Event, now time.Time) (bool, error)
+	Release(ctx context.Context, slotKey, holdID string) error
+}
+
+// Holds configura las reservas temporales de horarios. Sin Default se
+// reserva por 10 minutos y nunca por más de Max (30 minutos si no se
+// configura).
+type Holds struct {
+	Store   HoldStore
+	Default time.Duration
+	Max     time.Duration
+	Now     func() time.Time
+}
+
+// HoldsFromEnv arma Holds sobre store con SLOT_HOLD_MINUTES y
+// SLOT_HOLD_MAX_MINUTES; sin ellas se usan los valores por defecto.
+func HoldsFromEnv(store HoldStore) (*Holds, error) {
+	holds := &Holds{Store: store}
+	for name, target := range map[string]*time.Duration{"SLOT_HOLD_MINUTES": &holds.Default, "SLOT_HOLD_MAX_MINUTES": &holds.Max} {
+		raw := os.Getenv(name)
+		if raw == "" {
+			continue
+		}
+		minutes, err := strconv.Atoi(raw)
+		if err != nil || minutes <= 0 {
+			return nil, fmt.Errorf("invalid %s: %q", name, raw)
+		}
+		*target = time.Duration(minutes) * time.Minute
+	}
+	return holds, nil
+}
+
+// HoldSlot reserva el horario si no se superpone con un turno ni con otra
+// reserva vigente. La reserva toma las franjas de la agenda que ocupa en la
+// misma transacción en que se guarda, así dos pedidos simultáneos que se
+// superponen, aunque empiecen a distinta hora, no pasan los dos.
+func (a *Appointments) HoldSlot(ctx context.Context, request *models.SlotHoldRequest) (*models.SlotHoldRequest, error) {
+	ctx, span := tracing.Start(ctx, "service.Appointments.HoldSlot")
+	defer span.End()
+	if a.Holds == nil {
+		return nil, fmt.Errorf("slot holds are not configured")
+	}
+	if request.ClientID == "" || request.DoctorID == "" || request.Duration <= 0 {
+		return nil, fmt.Errorf("%w: client_id, doctor_id and duration are required", ErrInvalidHold)
+	}
+	if request.Duration > maxSlotMinutes {
+		return nil, fmt.Errorf("%w: duration cannot exceed %d minutes", ErrInvalidHold, maxSlotMinutes)
+	}
+	start, err := time.Parse(time.RFC3339, request.Date)
+	if err != nil {
+		return nil, fmt.Errorf("%w: date must be RFC3339", ErrInvalidHold)
+	}
+	now := a.Holds.now()
+	if !start.After(now) {
+		return nil, fmt.Errorf("%w: date must be in the future", ErrInvalidHold)
+	}
+	hold := a.Holds.Default
+	if hold <= 0 {
+		hold = defaultHoldDuration
+	}
+	if request.Minutes > 0 {
+		hold = time.Duration(request.Minutes) * time.Minute
+	}
+	if limit := a.Holds.max(); hold > limit {
+		return nil, fmt.Errorf("%w: holds cannot last more than %s", ErrInvalidHold, limit)
+	}
+
+	busy, err := a.busy(ctx, request.DoctorID, now)
+	if err != nil {
+		return nil, err
+	}
+	requested := availability.Interval{Start: start, End: start.Add(time.Duration(request.Duration) * time.Minute)}
+	for _, interval := range busy {
+		if requested.Overlaps(interval) {
+			return nil, ErrSlotHeld
+		}
+	}
+
+	h := &models.SlotHold{
+		SlotKey:   slotKey(request.DoctorID, start),
+		HoldID:    uuid.NewString(),
+		ClientID:  request.ClientID,
+		DoctorID:  request.DoctorID,
+		Date:      request.Date,
+		Duration:  request.Duration,
+		CreatedAt: now.Format(time.RFC3339),
+		ExpiresAt: now.Add(hold).Unix(),
+	}
+	held, err := a.Holds.Store.Hold(ctx, h, now)
+	if err != nil {
+		a.log(ctx).Error("Error holding slot", zap.String("doctorID", request.DoctorID), zap.Error(err))
+		return nil, err
+	}
+	if !held {
+		return nil, ErrSlotHeld
+	}
+
+	a.log(ctx).Info("Slot held", zap.String("doctorID", h.DoctorID), zap.String("date", h.Date))
+	return mapHoldToRequest(h), nil
+}
+
+// ConfirmHold convierte la reserva en un turno. Los datos del horario salen
+// de la reserva; del pedido se toman el paciente, las notas y la metadata.
+// El turno y su AppointmentBooked se crean, la reserva se borra y sus franjas
+// pasan al turno en la misma transacción: si venció o se liberó en el medio y
+// otro pedido tomó alguna franja, no se crea nada.
+func (a *Appointments) ConfirmHold(ctx context.Context, holdID string, request *models.AppointmentRequest) (*models.AppointmentRequest, error) {
+	ctx, span := tracing.Start(ctx, "service.Appointments.ConfirmHold")
+	defer span.End()
+	if a.Holds == nil {
+		return nil, fmt.Errorf("slot holds are not configured")
+	}
+	key, nonce, ok := parseHoldID(holdID)
+	if !ok {
+		return nil, ErrHoldNotFound
+	}
+	now := a.Holds.now()
+	h, err := a.Holds.Store.Get(ctx, key)
+	if err != nil {
+		a.log(ctx).Error("Error getting slot hold", zap.Error(err))
+		return nil, err
+	}
+	if h == nil || h.HoldID != nonce || h.ExpiresAt <= now.Unix() {
+		return nil, ErrHoldNotFound
+	}
+
+	request.ClientID, request.DoctorID, request.Date, request.Duration = h.ClientID, h.DoctorID, h.Date, h.Duration
+	if request.Status == "" {
+		request.Status = models.AppointmentStatusScheduled
+	}
+	if err := validateStatus(request.Status); err != nil {
+		a.log(ctx).Error("Invalid status value", zap.String("status", string(request.Status)))
+		return nil, err
+	}
+	if err := a.checkNoShowPolicy(ctx, request); err != nil {
+		return nil, err
+	}
+
+	appointment := a.mapRequestToAppointment(request)
+	appointment.CreatedAt = now.Format(time.RFC3339)
+	appointment.UpdatedAt = appointment.CreatedAt
+	evts, err := appointmentEvents(appointment, events.AppointmentBooked, nil)
+	if err != nil {
+		return nil, err
+	}
+	confirmed, err := a.Holds.Store.Confirm(ctx, h, appointment, evts, now)
+	if err != nil {
+		a.log(ctx).Error("Error confirming slot hold", zap.Error(err))
+		return nil, err
+	}
+	if !confirmed {
+		return nil, ErrHoldNotFound
+	}
+	a.count(metricBooked, appointment)
+	a.log(ctx).Info("Slot hold confirmed", zap.String("appointmentID", appointment.ID))
+	return a.mapAppointmentToRequest(appointment), nil
+}
+
+// book crea un turno sin reserva previa. Como HoldSlot, revisa la agenda y
+// toma las franjas en la misma transacción que crea el turno.
+func (a *Appointments) book(ctx context.Context, appointment *models.Appointment) error {
+	requested, err := availability.AppointmentInterval(appointment)
+	if err != nil {
+		return err
+	}
+	now := a.Holds.now()
+	busy, err := a.busy(ctx, appointment.DoctorID, now)
+	if err != nil {
+		return err
+	}
+	for _, interval := range busy {
+		if requested.Overlaps(interval) {
+			return ErrSlotHeld
+		}
+	}
+
+	evts, err := appointmentEvents(appointment, events.AppointmentBooked, nil)
+	if err != nil {
+		return err
+	}
+	booked, err := a.Holds.Store.Book(ctx, appointment, evts, now)
+	if err != nil {
+		return err
+	}
+	if !booked {
+		return ErrSlotHeld
+	}
+	return nil
+}
+
+// ReleaseHold libera la reserva antes de que venza. Liberar una reserva que
+// ya no existe no es un error.
+func (a *Appointments) ReleaseHold(ctx context.Context, holdID string) error {
+	ctx, span := tracing.Start(ctx, "service.Appointments.ReleaseHold")
+	defer span.End()
+	if a.Holds == nil {
+		return fmt.Errorf("slot holds are not configured")
+	}
+	key, nonce, ok := parseHoldID(holdID)
+	if !ok {
+		return ErrHoldNotFound
+	}
+	if err := a.Holds.Store.Release(ctx, key, nonce); err != nil {
+		a.log(ctx).Error("Error releasing slot hold", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// busy devuelve lo que ocupa la agenda del médico: turnos y reservas
+// vigentes.
+func (a *Appointments) busy(ctx context.Context, doctorID string, now time.Time) ([]availability.Interval, error) {
+	appointments, err := a.AppointmentsRepository.GetByDoctorID(ctx, doctorID)
+	if err != nil {
+		a.log(ctx).Error("Error getting appointments by DoctorID", zap.String("doctorID", doctorID), zap.Error(err))
+		return nil, err
+	}
+	holds, err := a.Holds.Store.GetByDoctorID(ctx, doctorID, now)
+	if err != nil {
+		a.log(ctx).Error("Error getting slot holds by DoctorID", zap.String("doctorID", doctorID), zap.Error(err))
+		return nil, err
+	}
+	return append(availability.BusyFromAppointments(appointments), availability.BusyFromHolds(holds)...), nil
+}
+
+func (h *Holds) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
+
+func (h *Holds) max() time.Duration {
+	if h.Max > 0 {
+		return h.Max
+	}
+	return maxHoldDuration
+}
+
+// slotKey normaliza el inicio a UTC para que el mismo horario guardado con
+// otro offset caiga en la misma clave.
+func slotKey(doctorID string, start time.Time) string {
+	return doctorID + "#" + start.UTC().Format(time.RFC3339)
+}
+
+// El ID de la reserva que ve el cliente es la clave del horario en base64 más
+// el hold_id: alcanza para encontrarla sin un índice extra y sólo quien la
+// creó conoce el hold_id.
+func holdID(h *models.SlotHold) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(h.SlotKey)) + "." + h.HoldID
+}
+
+func parseHoldID(id string) (string, string, bool) {
+	encoded, nonce, found := strings.Cut(id, ".")
+	if !found || nonce == "" {
+		return "", "", false
+	}
+	key, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(key) == 0 {
+		return "", "", false
+	}
+	return string(key), nonce, true
+}
+
+func mapHoldToRequest(h *models.SlotHold) *models.SlotHoldRequest {
+	return &models.SlotHoldRequest{
+		ID:        holdID(h),
+		ClientID:  h.ClientID,
+		DoctorID:  h.DoctorID,
+		Date:      h.Date,
+		Duration:  h.Duration,
+		ExpiresAt: time.Unix(h.ExpiresAt, 0).UTC().Format(time.RFC3339),
+	}
+}
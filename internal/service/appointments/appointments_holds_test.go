@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockHoldStore struct {
+	mock.Mock
+}
+
+func (m *MockHoldStore) Hold(ctx context.Context, h *models.SlotHold, now time.Time) (bool, error) {
+	args := m.Called(ctx, h, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockHoldStore) Get(ctx context.Context, slotKey string) (*models.SlotHold, error) {
+	args := m.Called(ctx, slotKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SlotHold), args.Error(1)
+}
+
+func (m *MockHoldStore) GetByDoctorID(ctx context.Context, doctorID string, now time.Time) ([]*models.SlotHold, error) {
+	args := m.Called(ctx, doctorID, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SlotHold), args.Error(1)
+}
+
+func (m *MockHoldStore) Confirm(ctx context.Context, h *models.SlotHold, appointment *models.Appointment, evts []*events.Event, now time.Time) (bool, error) {
+	args := m.Called(ctx, h, appointment, evts, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockHoldStore) Book(ctx context.Context, appointment *models.Appointment, evts []*events.Event, now time.Time) (bool, error) {
+	args := m.Called(ctx, appointment, evts, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockHoldStore) Release(ctx context.Context, slotKey, holdID string) error {
+	args := m.Called(ctx, slotKey, holdID)
+	return args.Error(0)
+}
+
+var holdNow = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func setupHoldsTest() (*Appointments, *MockAppointmentsRepository, *MockHoldStore) {
+	service, mockRepo := setupTest()
+	store := new(MockHoldStore)
+	service.Holds = &Holds{Store: store, Now: func() time.Time { return holdNow }}
+	return service, mockRepo, store
+}
+
+func sampleHoldRequest() *models.SlotHoldRequest {
+	return &models.SlotHoldRequest{ClientID: "client123", DoctorID: "doctor123", Date: "2024-01-16T10:00:00-03:00", Duration: 30}
+}
+
+func sampleHold() *models.SlotHold {
+	return &models.SlotHold{
+		SlotKey:   "doctor123#2024-01-16T13:00:00Z",
+		HoldID:    "nonce",
+		ClientID:  "client123",
+		DoctorID:  "doctor123",
+		Date:      "2024-01-16T10:00:00-03:00",
+		Duration:  30,
+		ExpiresAt: holdNow.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func TestAppointments_HoldSlot_Success(t *testing.T) {
+	service, mockRepo, store := setupHoldsTest()
+	ctx := context.Background()
+	mockRepo.On("GetByDoctorID", ctx, "doctor123").Return([]*models.Appointment{}, nil)
+	store.On("GetByDoctorID", ctx, "doctor123", holdNow).Return([]*models.SlotHold{}, nil)
+	store.On("Hold", ctx, mock.MatchedBy(func(h *models.SlotHold) bool {
+		// La clave se normaliza a UTC y vence a los 10 minutos por defecto
+		return h.SlotKey == "doctor123#2024-01-16T13:00:00Z" && h.ExpiresAt == holdNow.Add(10*time.Minute).Unix()
+	}), holdNow).Return(true, nil)
+
+	result, err := service.HoldSlot(ctx, sampleHoldRequest())
+
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-15T12:10:00Z", result.ExpiresAt)
+	key, nonce, ok := parseHoldID(result.ID)
+	assert.True(t, ok)
+	assert.Equal(t, "doctor123#2024-01-16T13:00:00Z", key)
+	assert.NotEmpty(t, nonce)
+	store.AssertExpectations(t)
+}
+
+func TestAppointments_HoldSlot_Invalid(t *testing.T) {
+	service, _, _ := setupHoldsTest()
+	ctx := context.Background()
+
+	past := sampleHoldRequest()
+	past.Date = "2024-01-15T08:00:00Z"
+	tooLong := sampleHoldRequest()
+	tooLong.Minutes = 45
+	missing := sampleHoldRequest()
+	missing.DoctorID = ""
+	longSlot := sampleHoldRequest()
+	longSlot.Duration = 7 * 60
+
+	for _, request := range []*models.SlotHoldRequest{past, tooLong, missing, longSlot} {
+		_, err := service.HoldSlot(ctx, request)
+		assert.ErrorIs(t, err, ErrInvalidHold)
+	}
+}
+
+func TestAppointments_HoldSlot_OverlapsAppointment(t *testing.T) {
+	service, mockRepo, store := setupHoldsTest()
+	ctx := context.Background()
+	booked := createSampleAppointment("booked")
+	booked.Date = "2024-01-16T13:15:00Z"
+	mockRepo.On("GetByDoctorID", ctx, "doctor123").Return([]*models.Appointment{booked}, nil)
+	store.On("GetByDoctorID", ctx, "doctor123", holdNow).Return([]*models.SlotHold{}, nil)
+
+	_, err := service.HoldSlot(ctx, sampleHoldRequest())
+
+	assert.ErrorIs(t, err, ErrSlotHeld)
+	store.AssertNotCalled(t, "Hold", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAppointments_HoldSlot_OverlapsHold(t *testing.T) {
+	service, mockRepo, store := setupHoldsTest()
+	ctx := context.Background()
+	other := sampleHold()
+	other.Date = "2024-01-16T12:45:00Z"
+	mockRepo.On("GetByDoctorID", ctx, "doctor123").Return([]*models.Appointment{}, nil)
+	store.On("GetByDoctorID", ctx, "doctor123", holdNow).Return([]*models.SlotHold{other}, nil)
+
+	_, err := service.HoldSlot(ctx, sampleHoldRequest())
+
+	assert.ErrorIs(t, err, ErrSlotHeld)
+}
+
+func TestAppointments_HoldSlot_LostRace(t *testing.T) {
+	service, mockRepo, store := setupHoldsTest()
+	ctx := context.Background()
+	mockRepo.On("GetByDoctorID", ctx, "doctor123").Return([]*models.Appointment{}, nil)
+	store.On("GetByDoctorID", ctx, "doctor123", holdNow).Return([]*models.SlotHold{}, nil)
+	store.On("Hold", ctx, mock.Anything, holdNow).Return(false, nil)
+
+	_, err := service.HoldSlot(ctx, sampleHoldRequest())
+
+	assert.ErrorIs(t, err, ErrSlotHeld)
+}
+
+func sampleBookingRequest() *models.AppointmentRequest {
+	request := createSampleAppointmentRequest()
+	request.Date = "2024-01-16T10:15:00-03:00"
+	return request
+}
+
+// Con reservas configuradas, un alta directa que empieza en otro horario pero
+// se superpone con un turno no pasa
+func TestAppointments_CreateAppointment_WithHolds_Overlaps(t *testing.T) {
+	service, mockRepo, store := setupHoldsTest()
+	ctx := context.Background()
+	booked := createSampleAppointment("booked")
+	booked.Date = "2024-01-16T13:00:00Z"
+	mockRepo.On("GetByDoctorID", ctx, "doctor123").Return([]*models.Appointment{booked}, nil)
+	store.On("GetByDoctorID", ctx, "doctor123", holdNow).Return([]*models.SlotHold{}, nil)
+
+	_, err := service.CreateAppointment(ctx, sampleBookingRequest())
+
+	assert.ErrorIs(t, err, ErrSlotHeld)
+	store.AssertNotCalled(t, "Book", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+// La lectura no vio nada pero otro pedido tomó una franja antes de escribir
+func TestAppointments_CreateAppointment_WithHolds_LostRace(t *testing.T) {
+	service, mockRepo, store := setupHoldsTest()
+	ctx := context.Background()
+	mockRepo.On("GetByDoctorID", ctx, "doctor123").Return([]*models.Appointment{}, nil)
+	store.On("GetByDoctorID", ctx, "doctor123", holdNow).Return([]*models.SlotHold{}, nil)
+	store.On("Book", ctx, mock.Anything, mock.Anything, holdNow).Return(false, nil)
+
+	_, err := service.CreateAppointment(ctx, sampleBookingRequest())
+
+	assert.ErrorIs(t, err, ErrSlotHeld)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestAppointments_CreateAppointment_WithHolds_Success(t *testing.T) {
+	service, mockRepo, store := setupHoldsTest()
+	ctx := context.Background()
+	mockRepo.On("GetByDoctorID", ctx, "doctor123").Return([]*models.Appointment{}, nil)
+	store.On("GetByDoctorID", ctx, "doctor123", holdNow).Return([]*models.SlotHold{}, nil)
+	store.On("Book", ctx, mock.MatchedBy(func(a *models.Appointment) bool {
+		return a.DoctorID == "doctor123" && a.Date == "2024-01-16T10:15:00-03:00"
+	}), mock.MatchedBy(func(evts []*events.Event) bool {
+		return len(evts) == 1 && evts[0].Type == events.AppointmentBooked
+	}), holdNow).Return(true, nil)
+
+	created, err := service.CreateAppointment(ctx, sampleBookingRequest())
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	store.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestAppointments_ConfirmHold_Success(t *testing.T) {
+	service, _, store := setupHoldsTest()
+	ctx := context.Background()
+	hold := sampleHold()
+	store.On("Get", ctx, hold.SlotKey).Return(hold, nil)
+	store.On("Confirm", ctx, hold, mock.MatchedBy(func(a *models.Appointment) bool {
+		return a.DoctorID == "doctor123" && a.PatientID == "patient123" && a.Date == hold.Date &&
+			a.Status == models.AppointmentStatusScheduled
+	}), mock.MatchedBy(func(evts []*events.Event) bool {
+		return len(evts) == 1 && evts[0].Type == events.AppointmentBooked
+	}), holdNow).Return(true, nil)
+
+	// El pedido no puede mover el horario reservado
+	request := &models.AppointmentRequest{PatientID: "patient123", DoctorID: "other", Date: "2024-02-01T10:00:00Z", CreatedAt: "2020-01-01T00:00:00Z"}
+	result, err := service.ConfirmHold(ctx, holdID(hold), request)
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.ID)
+	assert.Equal(t, "doctor123", result.DoctorID)
+	assert.Equal(t, hold.Date, result.Date)
+	// Lo que se guardó, no lo que mandó el cliente
+	assert.Equal(t, holdNow.Format(time.RFC3339), result.CreatedAt)
+	assert.Equal(t, result.CreatedAt, result.UpdatedAt)
+	store.AssertExpectations(t)
+}
+
+func TestAppointments_ConfirmHold_NotFound(t *testing.T) {
+	service, _, store := setupHoldsTest()
+	ctx := context.Background()
+	hold := sampleHold()
+	expired := sampleHold()
+	expired.ExpiresAt = holdNow.Unix()
+	store.On("Get", ctx, hold.SlotKey).Return(expired, nil).Once()
+	store.On("Get", ctx, hold.SlotKey).Return(hold, nil)
+
+	// Vencida
+	_, err := service.ConfirmHold(ctx, holdID(hold), &models.AppointmentRequest{PatientID: "patient123"})
+	assert.ErrorIs(t, err, ErrHoldNotFound)
+
+	// De otro cliente
+	other := sampleHold()
+	other.HoldID = "someone-else"
+	_, err = service.ConfirmHold(ctx, holdID(other), &models.AppointmentRequest{PatientID: "patient123"})
+	assert.ErrorIs(t, err, ErrHoldNotFound)
+
+	// ID mal formado
+	_, err = service.ConfirmHold(ctx, "garbage", &models.AppointmentRequest{PatientID: "patient123"})
+	assert.ErrorIs(t, err, ErrHoldNotFound)
+
+	store.AssertNotCalled(t, "Confirm", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAppointments_ConfirmHold_ExpiredDuringConfirm(t *testing.T) {
+	service, _, store := setupHoldsTest()
+	ctx := context.Background()
+	hold := sampleHold()
+	store.On("Get", ctx, hold.SlotKey).Return(hold, nil)
+	store.On("Confirm", ctx, hold, mock.Anything, mock.Anything, holdNow).Return(false, nil)
+
+	_, err := service.ConfirmHold(ctx, holdID(hold), &models.AppointmentRequest{PatientID: "patient123"})
+
+	assert.ErrorIs(t, err, ErrHoldNotFound)
+}
+
+func TestAppointments_ReleaseHold(t *testing.T) {
+	service, _, store := setupHoldsTest()
+	ctx := context.Background()
+	hold := sampleHold()
+	store.On("Release", ctx, hold.SlotKey, "nonce").Return(nil)
+
+	assert.NoError(t, service.ReleaseHold(ctx, holdID(hold)))
+	assert.ErrorIs(t, service.ReleaseHold(ctx, "garbage"), ErrHoldNotFound)
+	store.AssertExpectations(t)
+
+	store.ExpectedCalls = nil
+	store.On("Release", ctx, hold.SlotKey, "nonce").Return(errors.New("dynamo down"))
+	assert.Error(t, service.ReleaseHold(ctx, holdID(hold)))
+}
+
+func TestHoldsFromEnv(t *testing.T) {
+	t.Setenv("SLOT_HOLD_MINUTES", "5")
+	t.Setenv("SLOT_HOLD_MAX_MINUTES", "")
+	holds, err := HoldsFromEnv(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, holds.Default)
+	assert.Equal(t, maxHoldDuration, holds.max())
+
+	t.Setenv("SLOT_HOLD_MAX_MINUTES", "nope")
+	_, err = HoldsFromEnv(nil)
+	assert.Error(t, err)
+}
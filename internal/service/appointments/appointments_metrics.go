@@ -0,0 +1,20 @@
+package service
+
+import (
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+)
+
+// Contadores de dominio por cliente. Un turno borrado que seguía vigente
+// cuenta como cancelado.
+const (
+	metricBooked    = "AppointmentsBooked"
+	metricCancelled = "AppointmentsCancelled"
+)
+
+func (a *Appointments) count(name string, appointment *models.Appointment) {
+	if a.Metrics == nil {
+		return
+	}
+	a.Metrics.Put(name, 1, metrics.Count, metrics.Dimensions{metrics.DimClientID: appointment.ClientID})
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockMetrics struct {
+	mock.Mock
+}
+
+func (m *MockMetrics) Put(name string, value float64, unit metrics.Unit, dimensions metrics.Dimensions) {
+	m.Called(name, value, unit, dimensions)
+}
+
+func setupMetricsTest() (*Appointments, *MockAppointmentsRepository, *MockMetrics) {
+	service, mockRepo := setupTest()
+	m := new(MockMetrics)
+	service.Metrics = m
+	return service, mockRepo, m
+}
+
+var client123 = metrics.Dimensions{metrics.DimClientID: "client123"}
+
+func TestAppointments_CreateAppointment_CountsBooked(t *testing.T) {
+	service, mockRepo, m := setupMetricsTest()
+	ctx := context.Background()
+	mockRepo.On("Save", ctx, mock.AnythingOfType("*models.Appointment")).Return(nil)
+	m.On("Put", metricBooked, float64(1), metrics.Count, client123).Return()
+
+	_, err := service.CreateAppointment(ctx, createSampleAppointmentRequest())
+
+	require.NoError(t, err)
+	m.AssertExpectations(t)
+}
+
+// Las reservas por hold (FHIR y POST /holds/{id}/confirm) se cuentan igual
+// que las del alta directa
+func TestAppointments_ConfirmHold_CountsBooked(t *testing.T) {
+	service, _, m := setupMetricsTest()
+	store := new(MockHoldStore)
+	service.Holds = &Holds{Store: store, Now: func() time.Time { return holdNow }}
+	ctx := context.Background()
+	hold := sampleHold()
+	store.On("Get", ctx, hold.SlotKey).Return(hold, nil)
+	store.On("Confirm", ctx, hold, mock.Anything, mock.Anything, holdNow).Return(true, nil)
+	m.On("Put", metricBooked, float64(1), metrics.Count, client123).Return().Once()
+
+	_, err := service.ConfirmHold(ctx, holdID(hold), &models.AppointmentRequest{PatientID: "patient123"})
+
+	require.NoError(t, err)
+	m.AssertExpectations(t)
+}
+
+func TestNewWithOptions_DefaultMetrics(t *testing.T) {
+	t.Setenv("METRICS_DISABLED", "true")
+	service := NewWithOptions(zap.NewNop().Sugar(), new(MockAppointmentsRepository), Options{})
+
+	assert.Equal(t, metrics.Nop{}, service.(*Appointments).Metrics)
+}
+
+func TestAppointments_UpdateAppointment_CountsCancelled(t *testing.T) {
+	service, mockRepo, m := setupMetricsTest()
+	ctx := context.Background()
+	existing := createSampleAppointment("appointment123")
+	mockRepo.On("GetByID", ctx, "appointment123").Return(existing, nil)
+	mockRepo.On("Patch", ctx, mock.Anything, mock.AnythingOfType("*models.Appointment"), mock.Anything).Return(true, nil)
+	m.On("Put", metricCancelled, float64(1), metrics.Count, client123).Return().Once()
+
+	req := createSampleAppointmentRequest()
+	req.ID, req.Date = "appointment123", existing.Date
+	req.Status = models.AppointmentStatusCancelled
+	_, err := service.UpdateAppointment(ctx, req)
+	require.NoError(t, err)
+
+	// Volver a guardar un turno ya cancelado no lo cuenta otra vez
+	cancelled := createSampleAppointment("appointment123")
+	cancelled.Status = models.AppointmentStatusCancelled
+	mockRepo.ExpectedCalls[0].Return(cancelled, nil)
+	_, err = service.UpdateAppointment(ctx, req)
+	require.NoError(t, err)
+
+	m.AssertExpectations(t)
+	m.AssertNumberOfCalls(t, "Put", 1)
+}
+
+func TestAppointments_DeleteAppointment_CountsCancelled(t *testing.T) {
+	service, mockRepo, m := setupMetricsTest()
+	ctx := context.Background()
+	mockRepo.On("GetByID", ctx, "appointment123").Return(createSampleAppointment("appointment123"), nil)
+	mockRepo.On("Delete", ctx, "appointment123").Return(nil)
+	m.On("Put", metricCancelled, float64(1), metrics.Count, client123).Return()
+
+	require.NoError(t, service.DeleteAppointment(ctx, "appointment123"))
+	m.AssertExpectations(t)
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"go.uber.org/zap"
+)
+
+var ErrPatientBlocked = errors.New("patient has too many no-shows")
+
+const (
+	NoShowActionBlock = "block"
+	NoShowActionFlag  = "flag"
+
+	defaultNoShowGrace    = time.Hour
+	defaultNoShowLookback = 7 * 24 * time.Hour
+	// noShowOffsetMargin compensa que el repositorio compare las fechas como
+	// strings con el offset de cada clínica
+	noShowOffsetMargin = 14 * time.Hour
+)
+
+// NoShowPatients lleva la cuenta de ausencias por paciente; lo implementa el
+// repositorio de pacientes.
+type NoShowPatients interface {
+	GetByID(ctx context.Context, id string) (*models.Patient, error)
+	AddNoShows(ctx context.Context, id string, delta int) error
+}
+
+// NoShowStore son las consultas de turnos que usa la marca automática; lo
+// implementa el repositorio de turnos.
+type NoShowStore interface {
+	GetDueBetween(ctx context.Context, from, to, cursor string) ([]*models.Appointment, string, error)
+	MarkNoShow(ctx context.Context, id, updatedAt string, evts []*events.Event) (bool, error)
+}
+
+// NoShowRule es la política de un tenant para pacientes que faltan: a partir
+// de Threshold ausencias se bloquean ("block") o se marcan ("flag") los turnos
+// nuevos. Sin Threshold sólo se marcan las ausencias.
+type NoShowRule struct {
+	Threshold int    `json:"threshold,omitempty"`
+	Action    string `json:"action,omitempty"`
+}
+
+// NoShows configura las ausencias. Un turno SCHEDULED o CONFIRMED de
+// cualquier tenant pasa a NO_SHOW cuando terminó hace más de Grace; Lookback
+// limita hasta cuándo atrás se buscan, para cubrir corridas perdidas. Tenants
+// sólo define la política de reservas de cada tenant.
+type NoShows struct {
+	Patients     NoShowPatients
+	Appointments NoShowStore
+	Grace        time.Duration
+	Lookback     time.Duration
+	Tenants      map[string]NoShowRule
+}
+
+// ParseNoShows lee NO_SHOW_CONFIG:
+//
+//	{"grace":"1h","lookback":"168h","tenants":{"c1":{"threshold":3,"action":"block"},"c2":{}}}
+func ParseNoShows(raw string) (*NoShows, error) {
+	var cfg struct {
+		Grace    string                `json:"grace"`
+		Lookback string                `json:"lookback"`
+		Tenants  map[string]NoShowRule `json:"tenants"`
+	}
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return nil, err
+	}
+	noShows := &NoShows{Grace: defaultNoShowGrace, Lookback: defaultNoShowLookback, Tenants: cfg.Tenants}
+	for _, d := range []struct {
+		raw    string
+		target *time.Duration
+	}{{cfg.Grace, &noShows.Grace}, {cfg.Lookback, &noShows.Lookback}} {
+		if d.raw == "" {
+			continue
+		}
+		value, err := time.ParseDuration(d.raw)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid duration %q", d.raw)
+		}
+		*d.target = value
+	}
+	for clientID, rule := range cfg.Tenants {
+		if rule.Threshold > 0 && rule.Action != NoShowActionBlock && rule.Action != NoShowActionFlag {
+			return nil, fmt.Errorf("invalid no-show action %q for client %s", rule.Action, clientID)
+		}
+	}
+	return noShows, nil
+}
+
+// NoShowsFromEnv arma NoShows desde NO_SHOW_CONFIG; devuelve nil si no está.
+func NoShowsFromEnv(patients NoShowPatients, appointments NoShowStore) (*NoShows, error) {
+	raw := os.Getenv("NO_SHOW_CONFIG")
+	if raw == "" {
+		return nil, nil
+	}
+	noShows, err := ParseNoShows(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NO_SHOW_CONFIG: %w", err)
+	}
+	noShows.Patients, noShows.Appointments = patients, appointments
+	return noShows, nil
+}
+
+// MarkNoShows pasa a NO_SHOW los turnos vencidos de todos los tenants, tengan
+// o no política, y suma la ausencia al paciente. Un error con un turno no
+// frena a los demás.
+func (a *Appointments) MarkNoShows(ctx context.Context, now time.Time) (*models.NoShowReport, error) {
+	ctx, span := tracing.Start(ctx, "service.Appointments.MarkNoShows")
+	defer span.End()
+	if a.NoShows == nil {
+		return nil, fmt.Errorf("no-show marking is not configured")
+	}
+	from := now.Add(-a.NoShows.Lookback - noShowOffsetMargin).UTC().Format(time.RFC3339)
+	to := now.Add(noShowOffsetMargin).UTC().Format(time.RFC3339)
+	updatedAt := now.Format(time.RFC3339)
+
+	report := &models.NoShowReport{}
+	clients := map[string]bool{}
+	var errs []error
+	cursor := ""
+	for {
+		appointments, next, err := a.NoShows.Appointments.GetDueBetween(ctx, from, to, cursor)
+		if err != nil {
+			a.log(ctx).Error("Error fetching due appointments", zap.Error(err))
+			errs = append(errs, err)
+			break
+		}
+		for _, appointment := range appointments {
+			if !a.NoShows.due(appointment, now) {
+				continue
+			}
+			if !clients[appointment.ClientID] {
+				clients[appointment.ClientID] = true
+				report.Clients++
+			}
+			if err := a.markNoShow(ctx, appointment, updatedAt, report); err != nil {
+				a.log(ctx).Error("Error marking no-show", zap.String("clientID", appointment.ClientID), zap.String("appointmentID", appointment.ID), zap.Error(err))
+				errs = append(errs, fmt.Errorf("appointment %s: %w", appointment.ID, err))
+			}
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	a.log(ctx).Info("No-shows marked", zap.Int("clients", report.Clients), zap.Int("marked", report.Marked), zap.Int("failed", report.Failed))
+	return report, errors.Join(errs...)
+}
+
+// due indica si el turno sigue SCHEDULED o CONFIRMED, terminó hace más de
+// Grace y está dentro de Lookback.
+func (n *NoShows) due(appointment *models.Appointment, now time.Time) bool {
+	if appointment.Status != models.AppointmentStatusScheduled && appointment.Status != models.AppointmentStatusConfirmed {
+		return false
+	}
+	start, err := time.Parse(time.RFC3339, appointment.Date)
+	if err != nil {
+		return false
+	}
+	end := start.Add(time.Duration(appointment.Duration) * time.Minute)
+	return !now.Before(end.Add(n.Grace)) && now.Sub(start) <= n.Lookback
+}
+
+func (a *Appointments) markNoShow(ctx context.Context, appointment *models.Appointment, updatedAt string, report *models.NoShowReport) error {
+	noShow := *appointment
+	noShow.Status, noShow.UpdatedAt, noShow.Sequence = models.AppointmentStatusNoShow, updatedAt, appointment.Sequence+1
+	evts, err := appointmentEvents(&noShow, events.AppointmentNoShow, nil)
+	if err != nil {
+		return err
+	}
+	marked, err := a.NoShows.Appointments.MarkNoShow(ctx, appointment.ID, updatedAt, evts)
+	if err != nil || !marked {
+		return err
+	}
+	report.Marked++
+	if err := a.NoShows.Patients.AddNoShows(ctx, appointment.PatientID, 1); err != nil {
+		// El turno ya quedó marcado; el contador se corrige a mano
+		a.log(ctx).Error("Error counting no-show", zap.String("appointmentID", appointment.ID), zap.String("patientID", appointment.PatientID), zap.Error(err))
+		report.Failed++
+	}
+	return nil
+}
+
+// checkNoShowPolicy aplica la política del tenant al reservar: bloquea el
+// turno o lo marca en Metadata para que la clínica lo vea.
+func (a *Appointments) checkNoShowPolicy(ctx context.Context, request *models.AppointmentRequest) error {
+	if a.NoShows == nil {
+		return nil
+	}
+	rule := a.NoShows.Tenants[request.ClientID]
+	if rule.Threshold <= 0 || request.PatientID == "" {
+		return nil
+	}
+	patient, err := a.NoShows.Patients.GetByID(ctx, request.PatientID)
+	if err != nil {
+		a.log(ctx).Error("Error fetching patient for no-show policy", zap.String("patientID", request.PatientID), zap.Error(err))
+		return err
+	}
+	if patient == nil || patient.NoShowCount < rule.Threshold {
+		return nil
+	}
+
+	if rule.Action == NoShowActionBlock {
+		a.log(ctx).Info("Booking blocked by no-show policy", zap.String("patientID", request.PatientID), zap.Int("noShows", patient.NoShowCount))
+		return fmt.Errorf("%w: %d no-shows", ErrPatientBlocked, patient.NoShowCount)
+	}
+	if request.Metadata == nil {
+		request.Metadata = map[string]interface{}{}
+	}
+	request.Metadata["no_show_flag"] = true
+	request.Metadata["no_show_count"] = patient.NoShowCount
+	return nil
+}
+
+// countNoShow mantiene el contador cuando la clínica marca o corrige una
+// ausencia a mano.
+func (a *Appointments) countNoShow(ctx context.Context, before, after *models.Appointment) {
+	delta := 0
+	switch {
+	case before.Status != models.AppointmentStatusNoShow && after.Status == models.AppointmentStatusNoShow:
+		delta = 1
+	case before.Status == models.AppointmentStatusNoShow && after.Status != models.AppointmentStatusNoShow:
+		delta = -1
+	default:
+		return
+	}
+	if err := a.NoShows.Patients.AddNoShows(ctx, before.PatientID, delta); err != nil {
+		a.log(ctx).Error("Error updating no-show count", zap.String("patientID", before.PatientID), zap.Error(err))
+	}
+}
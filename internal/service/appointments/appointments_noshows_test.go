@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockNoShowPatients struct {
+	mock.Mock
+}
+
+func (m *MockNoShowPatients) GetByID(ctx context.Context, id string) (*models.Patient, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Patient), args.Error(1)
+}
+
+func (m *MockNoShowPatients) AddNoShows(ctx context.Context, id string, delta int) error {
+	args := m.Called(ctx, id, delta)
+	return args.Error(0)
+}
+
+type MockNoShowStore struct {
+	mock.Mock
+}
+
+func (m *MockNoShowStore) GetDueBetween(ctx context.Context, from, to, cursor string) ([]*models.Appointment, string, error) {
+	args := m.Called(ctx, from, to, cursor)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).([]*models.Appointment), args.String(1), args.Error(2)
+}
+
+func (m *MockNoShowStore) MarkNoShow(ctx context.Context, id, updatedAt string, evts []*events.Event) (bool, error) {
+	args := m.Called(ctx, id, updatedAt, evts)
+	return args.Bool(0), args.Error(1)
+}
+
+var noShowNow = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func setupNoShowsTest(tenants map[string]NoShowRule) (*Appointments, *MockAppointmentsRepository, *MockNoShowPatients, *MockNoShowStore) {
+	service, mockRepo := setupTest()
+	patients, store := new(MockNoShowPatients), new(MockNoShowStore)
+	service.NoShows = &NoShows{
+		Patients:     patients,
+		Appointments: store,
+		Grace:        time.Hour,
+		Lookback:     7 * 24 * time.Hour,
+		Tenants:      tenants,
+	}
+	return service, mockRepo, patients, store
+}
+
+func noShowAppointment(id, date string, status models.AppointmentStatus) *models.Appointment {
+	appointment := createSampleAppointment(id)
+	appointment.Date, appointment.Status = date, status
+	return appointment
+}
+
+func TestAppointments_MarkNoShows(t *testing.T) {
+	service, _, patients, store := setupNoShowsTest(map[string]NoShowRule{"client123": {}})
+	ctx := context.Background()
+	store.On("GetDueBetween", ctx, "2024-01-07T22:00:00Z", "2024-01-16T02:00:00Z", "").Return([]*models.Appointment{
+		// Terminó 10:30 UTC: pasó la gracia
+		noShowAppointment("expired", "2024-01-15T07:00:00-03:00", models.AppointmentStatusScheduled),
+		noShowAppointment("confirmed", "2024-01-14T09:00:00Z", models.AppointmentStatusConfirmed),
+		// Terminó 11:30 UTC: todavía dentro de la gracia
+		noShowAppointment("grace", "2024-01-15T11:00:00Z", models.AppointmentStatusScheduled),
+		noShowAppointment("completed", "2024-01-15T08:00:00Z", models.AppointmentStatusCompleted),
+		// Otro proceso ya lo cambió
+		noShowAppointment("raced", "2024-01-15T08:00:00Z", models.AppointmentStatusScheduled),
+		noShowAppointment("old", "2024-01-01T08:00:00Z", models.AppointmentStatusScheduled),
+	}, "", nil)
+	noShowEvent := mock.MatchedBy(func(evts []*events.Event) bool {
+		return len(evts) == 1 && evts[0].Type == events.AppointmentNoShow
+	})
+	store.On("MarkNoShow", ctx, "expired", noShowNow.Format(time.RFC3339), noShowEvent).Return(true, nil).Once()
+	store.On("MarkNoShow", ctx, "confirmed", mock.Anything, noShowEvent).Return(true, nil).Once()
+	store.On("MarkNoShow", ctx, "raced", mock.Anything, noShowEvent).Return(false, nil).Once()
+	patients.On("AddNoShows", ctx, "patient123", 1).Return(nil).Twice()
+
+	report, err := service.MarkNoShows(ctx, noShowNow)
+
+	require.NoError(t, err)
+	assert.Equal(t, &models.NoShowReport{Clients: 1, Marked: 2}, report)
+	store.AssertExpectations(t)
+	patients.AssertExpectations(t)
+	store.AssertNotCalled(t, "MarkNoShow", ctx, "grace", mock.Anything, mock.Anything)
+	store.AssertNotCalled(t, "MarkNoShow", ctx, "old", mock.Anything, mock.Anything)
+}
+
+func TestAppointments_MarkNoShows_TenantsWithoutPolicy(t *testing.T) {
+	service, _, patients, store := setupNoShowsTest(nil)
+	ctx := context.Background()
+	other := noShowAppointment("other", "2024-01-15T08:00:00Z", models.AppointmentStatusScheduled)
+	other.ClientID = "client456"
+	store.On("GetDueBetween", ctx, mock.Anything, mock.Anything, "").Return([]*models.Appointment{
+		noShowAppointment("first", "2024-01-15T08:00:00Z", models.AppointmentStatusScheduled),
+	}, "page2", nil)
+	store.On("GetDueBetween", ctx, mock.Anything, mock.Anything, "page2").Return([]*models.Appointment{other}, "", nil)
+	store.On("MarkNoShow", ctx, "first", mock.Anything, mock.Anything).Return(true, nil).Once()
+	store.On("MarkNoShow", ctx, "other", mock.Anything, mock.Anything).Return(false, errors.New("throttled")).Once()
+	patients.On("AddNoShows", ctx, "patient123", 1).Return(nil).Once()
+
+	report, err := service.MarkNoShows(ctx, noShowNow)
+
+	assert.ErrorContains(t, err, "throttled")
+	assert.Equal(t, &models.NoShowReport{Clients: 2, Marked: 1}, report)
+	store.AssertExpectations(t)
+	patients.AssertExpectations(t)
+}
+
+func TestAppointments_MarkNoShows_NotConfigured(t *testing.T) {
+	service, _ := setupTest()
+
+	_, err := service.MarkNoShows(context.Background(), noShowNow)
+	assert.Error(t, err)
+}
+
+func TestAppointments_CreateAppointment_NoShowBlock(t *testing.T) {
+	service, mockRepo, patients, _ := setupNoShowsTest(map[string]NoShowRule{"client123": {Threshold: 3, Action: NoShowActionBlock}})
+	ctx := context.Background()
+	patients.On("GetByID", ctx, "patient123").Return(&models.Patient{ID: "patient123", NoShowCount: 3}, nil)
+
+	_, err := service.CreateAppointment(ctx, createSampleAppointmentRequest())
+
+	assert.ErrorIs(t, err, ErrPatientBlocked)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestAppointments_CreateAppointment_NoShowFlag(t *testing.T) {
+	service, mockRepo, patients, _ := setupNoShowsTest(map[string]NoShowRule{"client123": {Threshold: 2, Action: NoShowActionFlag}})
+	ctx := context.Background()
+	patients.On("GetByID", ctx, "patient123").Return(&models.Patient{ID: "patient123", NoShowCount: 4}, nil)
+	mockRepo.On("Save", ctx, mock.MatchedBy(func(a *models.Appointment) bool {
+		return a.Metadata["no_show_flag"] == true && a.Metadata["no_show_count"] == 4 && a.Metadata["key"] == "value"
+	})).Return(nil).Once()
+
+	_, err := service.CreateAppointment(ctx, createSampleAppointmentRequest())
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAppointments_CreateAppointment_NoShowBelowThreshold(t *testing.T) {
+	service, mockRepo, patients, _ := setupNoShowsTest(map[string]NoShowRule{"client123": {Threshold: 3, Action: NoShowActionBlock}})
+	ctx := context.Background()
+	patients.On("GetByID", ctx, "patient123").Return(&models.Patient{ID: "patient123", NoShowCount: 2}, nil)
+	mockRepo.On("Save", ctx, mock.AnythingOfType("*models.Appointment")).Return(nil).Once()
+
+	_, err := service.CreateAppointment(ctx, createSampleAppointmentRequest())
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAppointments_UpdateAppointment_CountsManualNoShows(t *testing.T) {
+	tests := []struct {
+		name   string
+		before models.AppointmentStatus
+		after  models.AppointmentStatus
+		delta  int
+	}{
+		{"marked", models.AppointmentStatusScheduled, models.AppointmentStatusNoShow, 1},
+		{"reverted", models.AppointmentStatusNoShow, models.AppointmentStatusCompleted, -1},
+		{"unrelated", models.AppointmentStatusScheduled, models.AppointmentStatusCompleted, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mockRepo, patients, _ := setupNoShowsTest(nil)
+			ctx := context.Background()
+			existing := createSampleAppointment("appointment123")
+			existing.Status = tt.before
+			mockRepo.On("GetByID", ctx, "appointment123").Return(existing, nil)
+			mockRepo.On("Patch", ctx, mock.Anything, mock.AnythingOfType("*models.Appointment"), mock.Anything).Return(true, nil)
+			if tt.delta != 0 {
+				patients.On("AddNoShows", ctx, "patient123", tt.delta).Return(nil).Once()
+			}
+
+			req := createSampleAppointmentRequest()
+			req.ID, req.Status = "appointment123", tt.after
+			_, err := service.UpdateAppointment(ctx, req)
+			require.NoError(t, err)
+
+			patients.AssertExpectations(t)
+			if tt.delta == 0 {
+				patients.AssertNotCalled(t, "AddNoShows", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestParseNoShows(t *testing.T) {
+	noShows, err := ParseNoShows(`{"grace":"30m","tenants":{"c1":{"threshold":3,"action":"block"},"c2":{}}}`)
+
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, noShows.Grace)
+	assert.Equal(t, defaultNoShowLookback, noShows.Lookback)
+	assert.Equal(t, NoShowRule{Threshold: 3, Action: NoShowActionBlock}, noShows.Tenants["c1"])
+	assert.Contains(t, noShows.Tenants, "c2")
+
+	for _, raw := range []string{
+		`{"grace":"soon"}`,
+		`{"lookback":"-1h"}`,
+		`{"tenants":{"c1":{"threshold":3,"action":"ban"}}}`,
+		`not json`,
+	} {
+		_, err := ParseNoShows(raw)
+		assert.Error(t, err, raw)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/jsonpatch"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"go.uber.org/zap"
+)
+
+// maxPatchAttempts es cuántas veces se reaplica un parche cuando otro proceso
+// modificó el turno entre la lectura y la escritura.
+const maxPatchAttempts = 3
+
+var (
+	ErrAppointmentNotFound = errors.New("appointment not found")
+	ErrPatchConflict       = errors.New("appointment was modified concurrently")
+)
+
+// readOnlyFields son los miembros de AppointmentRequest que un parche no
+// puede cambiar.
+var readOnlyFields = []string{"id", "client_id", "created_at", "updated_at"}
+
+// PatchAppointment aplica un JSON Merge Patch o un JSON Patch (según
+// contentType) sobre la versión guardada del turno y escribe sólo lo que
+// cambió. El resultado se valida y tiene los mismos efectos que
+// UpdateAppointment. Si otro proceso modificó el turno en el medio, el parche
+// se vuelve a aplicar sobre la versión nueva.
+func (a *Appointments) PatchAppointment(ctx context.Context, id, contentType string, patch []byte) (*models.AppointmentRequest, error) {
+	ctx, span := tracing.Start(ctx, "service.Appointments.PatchAppointment")
+	defer span.End()
+	if id == "" {
+		a.log(ctx).Error("Error: Missing appointment ID for patch")
+		return nil, fmt.Errorf("appointment ID is required for patch")
+	}
+
+	for attempt := 1; attempt <= maxPatchAttempts; attempt++ {
+		existing, err := a.AppointmentsRepository.GetByID(ctx, id)
+		if err != nil {
+			a.log(ctx).Error("Error fetching appointment to patch", zap.String("id", id), zap.Error(err))
+			return nil, fmt.Errorf("failed to find appointment with ID %s: %w", id, err)
+		}
+		if existing == nil {
+			return nil, fmt.Errorf("%w: %s", ErrAppointmentNotFound, id)
+		}
+
+		request, err := a.applyPatch(existing, contentType, patch)
+		if err != nil {
+			a.log(ctx).Error("Invalid appointment patch", zap.String("id", id), zap.Error(err))
+			return nil, err
+		}
+		updated := updateAppointment(existing, request)
+
+		var evts []*events.Event
+		if a.Events != nil {
+			eventType, data := updateEvent(existing, updated)
+			if evts, err = appointmentEvents(updated, eventType, data); err != nil {
+				a.log(ctx).Error("Error building appointment event", zap.String("type", eventType), zap.Error(err))
+				return nil, err
+			}
+		}
+		ok, err := a.AppointmentsRepository.Patch(ctx, existing, updated, evts)
+		if err != nil {
+			a.log(ctx).Error("Error patching appointment", zap.String("id", id), zap.Error(err))
+			return nil, fmt.Errorf("failed to patch appointment: %w", err)
+		}
+		if !ok {
+			a.log(ctx).Info("Appointment changed while patching, retrying", zap.String("id", id), zap.Int("attempt", attempt))
+			continue
+		}
+
+		a.updated(ctx, existing, updated)
+		a.log(ctx).Info("Appointment patched successfully", zap.String("id", id))
+		return a.mapAppointmentToRequest(updated), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPatchConflict, id)
+}
+
+// applyPatch devuelve el pedido que resulta de aplicar el parche sobre
+// existing, ya validado.
+func (a *Appointments) applyPatch(existing *models.Appointment, contentType string, patch []byte) (*models.AppointmentRequest, error) {
+	doc, err := json.Marshal(a.mapAppointmentToRequest(existing))
+	if err != nil {
+		return nil, err
+	}
+	patched, err := jsonpatch.Apply(contentType, doc, patch)
+	if err != nil {
+		return nil, err
+	}
+	var request models.AppointmentRequest
+	if err := jsonpatch.Decode(doc, patched, &request, readOnlyFields...); err != nil {
+		return nil, err
+	}
+	if err := validateStatus(request.Status); err != nil {
+		return nil, fmt.Errorf("%w: %v", jsonpatch.ErrInvalid, err)
+	}
+	return &request, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/jsonpatch"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func (m *MockAppointmentsRepository) Patch(ctx context.Context, before, after *models.Appointment, evts []*events.Event) (bool, error) {
+	args := m.Called(ctx, before, after, evts)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestAppointments_PatchAppointment_MergePatch(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	existing := createSampleAppointment("123")
+	existing.Sequence = 4
+	existing.RemindersSent = []string{"48h"}
+	existing.ActionTokensUsed = []string{"nonce"}
+	mockRepo.On("GetByID", ctx, "123").Return(existing, nil)
+	mockRepo.On("Patch", ctx, existing, mock.MatchedBy(func(a *models.Appointment) bool {
+		// Sólo cambian las notas; se conserva lo que el pedido no trae
+		return a.Notes == "Traer estudios" && a.DoctorID == "doctor123" && a.Duration == 30 && a.Sequence == 5 &&
+			len(a.RemindersSent) == 1 && len(a.ActionTokensUsed) == 1
+	}), []*events.Event(nil)).Return(true, nil)
+
+	result, err := service.PatchAppointment(ctx, "123", jsonpatch.MergePatchType, []byte(`{"notes":"Traer estudios"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, "Traer estudios", result.Notes)
+	assert.Equal(t, "patient123", result.PatientID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAppointments_PatchAppointment_CancelEmitsEvent(t *testing.T) {
+	service, mockRepo, _ := setupEventsTest()
+	ctx := context.Background()
+	existing := createSampleAppointment("123")
+	var event *events.Event
+	mockRepo.On("GetByID", ctx, "123").Return(existing, nil)
+	mockRepo.On("Patch", ctx, existing, mock.AnythingOfType("*models.Appointment"), eventOfType(events.AppointmentCancelled, &event)).Return(true, nil)
+
+	patch := `[{"op":"test","path":"/status","value":"SCHEDULED"},{"op":"replace","path":"/status","value":"CANCELLED"}]`
+	result, err := service.PatchAppointment(ctx, "123", jsonpatch.JSONPatchType, []byte(patch))
+
+	require.NoError(t, err)
+	assert.Equal(t, models.AppointmentStatusCancelled, result.Status)
+	assert.Equal(t, "123", event.AggregateID)
+}
+
+func TestAppointments_PatchAppointment_Invalid(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetByID", ctx, "123").Return(createSampleAppointment("123"), nil)
+
+	cases := []struct {
+		contentType string
+		patch       string
+		err         error
+	}{
+		{jsonpatch.MergePatchType, `{"client_id":"other"}`, jsonpatch.ErrReadOnly},
+		{jsonpatch.JSONPatchType, `[{"op":"remove","path":"/created_at"}]`, jsonpatch.ErrReadOnly},
+		{jsonpatch.JSONPatchType, `[{"op":"test","path":"/status","value":"CANCELLED"}]`, jsonpatch.ErrTestFailed},
+		{jsonpatch.MergePatchType, `{"sequence":9}`, jsonpatch.ErrInvalid},
+		{"application/xml", `{}`, jsonpatch.ErrUnsupportedType},
+	}
+	for _, c := range cases {
+		_, err := service.PatchAppointment(ctx, "123", c.contentType, []byte(c.patch))
+		assert.ErrorIs(t, err, c.err, "patch %s", c.patch)
+	}
+
+	_, err := service.PatchAppointment(ctx, "123", "", []byte(`{"status":"LOST"}`))
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAppointments_PatchAppointment_NotFound(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetByID", ctx, "123").Return(nil, nil)
+
+	_, err := service.PatchAppointment(ctx, "123", "", []byte(`{}`))
+
+	assert.ErrorIs(t, err, ErrAppointmentNotFound)
+}
+
+func TestAppointments_PatchAppointment_Conflict(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetByID", ctx, "123").Return(createSampleAppointment("123"), nil)
+	mockRepo.On("Patch", ctx, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+
+	_, err := service.PatchAppointment(ctx, "123", "", []byte(`{"notes":"x"}`))
+
+	assert.ErrorIs(t, err, ErrPatchConflict)
+	mockRepo.AssertNumberOfCalls(t, "GetByID", maxPatchAttempts)
+}
@@ -2,65 +2,139 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/metrics"
 	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
 type AppointmentsRepository interface {
 	Save(ctx context.Context, a *models.Appointment) error
 	GetByID(ctx context.Context, id string) (*models.Appointment, error)
 	GetByClientID(ctx context.Context, clientID string) ([]*models.Appointment, error)
+	GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Appointment, string, error)
 	GetByPatientID(ctx context.Context, patientID string) ([]*models.Appointment, error)
 	GetByDoctorID(ctx context.Context, doctorID string) ([]*models.Appointment, error)
 	Delete(ctx context.Context, id string) error
+	Patch(ctx context.Context, before, after *models.Appointment, evts []*events.Event) (bool, error)
 }
 
 type AppointmentsService interface {
 	CreateAppointment(context.Context, *models.AppointmentRequest) (*models.AppointmentRequest, error)
 	GetAppointment(context.Context, *models.GetAppointmentRequest) (*models.AppointmentRequest, error)
-	GetAllAppointments(context.Context, string) ([]*models.AppointmentRequest, error)
-	UpdateAppointment(context.Context, *models.AppointmentRequest) error
+	GetAllAppointments(ctx context.Context, clientID, cursor string, limit int) ([]*models.AppointmentRequest, string, error)
+	UpdateAppointment(context.Context, *models.AppointmentRequest) (*models.AppointmentRequest, error)
+	PatchAppointment(ctx context.Context, id, contentType string, patch []byte) (*models.AppointmentRequest, error)
 	DeleteAppointment(context.Context, string) error
+	CheckAction(context.Context, string) (*models.AppointmentAction, error)
+	ApplyAction(context.Context, string) (*models.AppointmentAction, error)
+	MarkNoShows(context.Context, time.Time) (*models.NoShowReport, error)
+	HoldSlot(context.Context, *models.SlotHoldRequest) (*models.SlotHoldRequest, error)
+	ConfirmHold(context.Context, string, *models.AppointmentRequest) (*models.AppointmentRequest, error)
+	ReleaseHold(context.Context, string) error
+}
+
+// WaitlistOfferer ofrece a la lista de espera el horario de un turno
+// cancelado. Es opcional.
+type WaitlistOfferer interface {
+	OfferSlot(ctx context.Context, freed *models.Appointment) error
 }
 
 type Appointments struct {
 	Logger                 *zap.SugaredLogger
 	AppointmentsRepository AppointmentsRepository
+	Actions                *Actions
+	NoShows                *NoShows
+	Waitlist               WaitlistOfferer
+	Holds                  *Holds
+	Events                 EventStore
+	Metrics                metrics.Metrics
+}
+
+// Options agrupa las funciones opcionales del servicio.
+type Options struct {
+	Actions  *Actions
+	NoShows  *NoShows
+	Waitlist WaitlistOfferer
+	Holds    *Holds
+	Events   EventStore
+	// Metrics recibe los contadores de turnos reservados y cancelados. Si es
+	// nil se usa metrics.FromEnv: los turnos se cuentan en el servicio, sea
+	// cual sea el Lambda que los reserva (API, FHIR, lista de espera).
+	Metrics metrics.Metrics
 }
 
 func New(logger *zap.SugaredLogger, repository AppointmentsRepository) AppointmentsService {
+	return NewWithOptions(logger, repository, Options{})
+}
+
+func NewWithOptions(logger *zap.SugaredLogger, repository AppointmentsRepository, options Options) AppointmentsService {
+	if options.Metrics == nil {
+		options.Metrics = metrics.FromEnv()
+	}
 	return &Appointments{
 		Logger:                 logger,
 		AppointmentsRepository: repository,
+		Actions:                options.Actions,
+		NoShows:                options.NoShows,
+		Waitlist:               options.Waitlist,
+		Holds:                  options.Holds,
+		Events:                 options.Events,
+		Metrics:                options.Metrics,
 	}
 }
 
 func (a *Appointments) CreateAppointment(ctx context.Context, request *models.AppointmentRequest) (*models.AppointmentRequest, error) {
+	ctx, span := tracing.Start(ctx, "service.Appointments.CreateAppointment")
+	defer span.End()
 	// Validar status
 	if err := validateStatus(request.Status); err != nil {
-		a.Logger.Error("Invalid status value", zap.String("status", string(request.Status)))
+		a.log(ctx).Error("Invalid status value", zap.String("status", string(request.Status)))
+		return nil, err
+	}
+	if err := a.checkNoShowPolicy(ctx, request); err != nil {
 		return nil, err
 	}
 
 	appointment := a.mapRequestToAppointment(request)
-	if err := a.AppointmentsRepository.Save(ctx, appointment); err != nil {
-		a.Logger.Error("Error on AppointmentsRepository.Save", zap.Error(err))
+	if a.Holds != nil {
+		// Con reservas configuradas el turno compite por la agenda con ellas
+		if err := a.book(ctx, appointment); err != nil {
+			if !errors.Is(err, ErrSlotHeld) {
+				a.log(ctx).Error("Error booking appointment", zap.Error(err))
+			}
+			return nil, err
+		}
+	} else if err := a.save(ctx, appointment, events.AppointmentBooked, nil); err != nil {
+		a.log(ctx).Error("Error on AppointmentsRepository.Save", zap.Error(err))
 		return nil, err
 	}
-	return request, nil
+
+	a.count(metricBooked, appointment)
+	return a.mapAppointmentToRequest(appointment), nil
 }
 
 func (a *Appointments) GetAppointment(ctx context.Context, params *models.GetAppointmentRequest) (*models.AppointmentRequest, error) {
+	ctx, span := tracing.Start(ctx, "service.Appointments.GetAppointment")
+	defer span.End()
 	// Si se proporciona un ID, buscar por ID
 	if params.ID != "" {
-		a.Logger.Info("Getting appointment by ID", zap.String("id", params.ID))
+		a.log(ctx).Info("Getting appointment by ID", zap.String("id", params.ID))
 		appointment, err := a.AppointmentsRepository.GetByID(ctx, params.ID)
 		if err != nil {
-			a.Logger.Error("Error getting appointment by ID", zap.String("id", params.ID), zap.Error(err))
+			a.log(ctx).Error("Error getting appointment by ID", zap.String("id", params.ID), zap.Error(err))
 			return nil, err
 		}
 		return a.mapAppointmentToRequest(appointment), nil
@@ -68,10 +142,10 @@ func (a *Appointments) GetAppointment(ctx context.Context, params *models.GetApp
 
 	// Si se proporciona un PatientID, buscar por PatientID
 	if params.PatientID != "" {
-		a.Logger.Info("Getting appointments by PatientID", zap.String("patientID", params.PatientID))
+		a.log(ctx).Info("Getting appointments by PatientID", zap.String("patientID", params.PatientID))
 		appointments, err := a.AppointmentsRepository.GetByPatientID(ctx, params.PatientID)
 		if err != nil {
-			a.Logger.Error("Error getting appointments by PatientID", zap.String("patientID", params.PatientID), zap.Error(err))
+			a.log(ctx).Error("Error getting appointments by PatientID", zap.String("patientID", params.PatientID), zap.Error(err))
 			return nil, err
 		}
 		if len(appointments) == 0 {
@@ -82,10 +156,10 @@ func (a *Appointments) GetAppointment(ctx context.Context, params *models.GetApp
 
 	// Si se proporciona un DoctorID, buscar por DoctorID
 	if params.DoctorID != "" {
-		a.Logger.Info("Getting appointments by DoctorID", zap.String("doctorID", params.DoctorID))
+		a.log(ctx).Info("Getting appointments by DoctorID", zap.String("doctorID", params.DoctorID))
 		appointments, err := a.AppointmentsRepository.GetByDoctorID(ctx, params.DoctorID)
 		if err != nil {
-			a.Logger.Error("Error getting appointments by DoctorID", zap.String("doctorID", params.DoctorID), zap.Error(err))
+			a.log(ctx).Error("Error getting appointments by DoctorID", zap.String("doctorID", params.DoctorID), zap.Error(err))
 			return nil, err
 		}
 		if len(appointments) == 0 {
@@ -95,24 +169,35 @@ func (a *Appointments) GetAppointment(ctx context.Context, params *models.GetApp
 	}
 
 	// Si no se proporcionó ningún parámetro válido para la búsqueda
-	a.Logger.Error("Invalid parameters for GetAppointment")
+	a.log(ctx).Error("Invalid parameters for GetAppointment")
 	return nil, fmt.Errorf("invalid parameters: must provide ID, ClientID, PatientID, or DoctorID")
 }
 
-func (a *Appointments) GetAllAppointments(ctx context.Context, identifier string) ([]*models.AppointmentRequest, error) {
+// GetAllAppointments devuelve una página de hasta limit citas del cliente y
+// el cursor de la siguiente ("" en la última). Sin limit se usan
+// defaultListLimit y nunca más de maxListLimit.
+func (a *Appointments) GetAllAppointments(ctx context.Context, identifier, cursor string, limit int) ([]*models.AppointmentRequest, string, error) {
+	ctx, span := tracing.Start(ctx, "service.Appointments.GetAllAppointments")
+	defer span.End()
 	// Verificar que el identificador no esté vacío
 	if identifier == "" {
-		a.Logger.Error("Error: empty client-id provided to GetAllAppointments")
-		return nil, fmt.Errorf("client-id cannot be empty")
+		a.log(ctx).Error("Error: empty client-id provided to GetAllAppointments")
+		return nil, "", fmt.Errorf("client-id cannot be empty")
+	}
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
 	}
 
-	a.Logger.Info("Getting all appointments by ClientID", zap.String("clientID", identifier))
+	a.log(ctx).Info("Getting appointments by ClientID", zap.String("clientID", identifier), zap.Int("limit", limit))
 
-	// Obtener citas del repositorio usando el cliente ID
-	appointments, err := a.AppointmentsRepository.GetByClientID(ctx, identifier)
+	// Obtener la página de citas del repositorio usando el cliente ID
+	appointments, next, err := a.AppointmentsRepository.GetPageByClientID(ctx, identifier, cursor, int32(limit))
 	if err != nil {
-		a.Logger.Error("Error getting appointments by ClientID", zap.String("clientID", identifier), zap.Error(err))
-		return nil, err
+		a.log(ctx).Error("Error getting appointments by ClientID", zap.String("clientID", identifier), zap.Error(err))
+		return nil, "", err
 	}
 
 	// Mapear las citas del modelo de BD al modelo de request
@@ -121,35 +206,72 @@ func (a *Appointments) GetAllAppointments(ctx context.Context, identifier string
 		appointmentRequests = append(appointmentRequests, a.mapAppointmentToRequest(appointment))
 	}
 
-	a.Logger.Info("Successfully retrieved appointments", zap.Int("count", len(appointmentRequests)))
-	return appointmentRequests, nil
+	a.log(ctx).Info("Successfully retrieved appointments", zap.Int("count", len(appointmentRequests)))
+	return appointmentRequests, next, nil
 }
 
-func (a *Appointments) UpdateAppointment(ctx context.Context, request *models.AppointmentRequest) error {
+func (a *Appointments) UpdateAppointment(ctx context.Context, request *models.AppointmentRequest) (*models.AppointmentRequest, error) {
+	ctx, span := tracing.Start(ctx, "service.Appointments.UpdateAppointment")
+	defer span.End()
 	// Verificar que la cita tenga un ID
 	if request.ID == "" {
-		a.Logger.Error("Error: Missing appointment ID for update")
-		return fmt.Errorf("appointment ID is required for update")
+		a.log(ctx).Error("Error: Missing appointment ID for update")
+		return nil, fmt.Errorf("appointment ID is required for update")
 	}
 
 	// Validar status
 	if err := validateStatus(request.Status); err != nil {
-		a.Logger.Error("Invalid status value", zap.String("status", string(request.Status)))
-		return err
+		a.log(ctx).Error("Invalid status value", zap.String("status", string(request.Status)))
+		return nil, err
 	}
 
-	a.Logger.Info("Updating appointment", zap.String("id", request.ID))
+	a.log(ctx).Info("Updating appointment", zap.String("id", request.ID))
 
 	// Obtener la cita existente
 	existingAppointment, err := a.AppointmentsRepository.GetByID(ctx, request.ID)
 	if err != nil {
-		a.Logger.Error("Error fetching appointment to update", zap.String("id", request.ID), zap.Error(err))
-		return fmt.Errorf("failed to find appointment with ID %s: %w", request.ID, err)
+		a.log(ctx).Error("Error fetching appointment to update", zap.String("id", request.ID), zap.Error(err))
+		return nil, fmt.Errorf("failed to find appointment with ID %s: %w", request.ID, err)
+	}
+	if existingAppointment == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAppointmentNotFound, request.ID)
 	}
 
 	// Actualizar los campos de la cita existente
-	updatedAppointment := &models.Appointment{
-		ID:        existingAppointment.ID,
+	updatedAppointment := updateAppointment(existingAppointment, request)
+
+	// Guardar la cita actualizada sólo si nadie la cambió desde la lectura:
+	// si no, se pisarían los recordatorios y enlaces que se registraron en
+	// el medio
+	var evts []*events.Event
+	if a.Events != nil {
+		eventType, data := updateEvent(existingAppointment, updatedAppointment)
+		if evts, err = appointmentEvents(updatedAppointment, eventType, data); err != nil {
+			a.log(ctx).Error("Error building appointment event", zap.String("type", eventType), zap.Error(err))
+			return nil, err
+		}
+	}
+	ok, err := a.AppointmentsRepository.Patch(ctx, existingAppointment, updatedAppointment, evts)
+	if err != nil {
+		a.log(ctx).Error("Error updating appointment", zap.String("id", request.ID), zap.Error(err))
+		return nil, fmt.Errorf("failed to update appointment: %w", err)
+	}
+	if !ok {
+		a.log(ctx).Info("Appointment changed while updating", zap.String("id", request.ID))
+		return nil, fmt.Errorf("%w: %s", ErrPatchConflict, request.ID)
+	}
+	a.updated(ctx, existingAppointment, updatedAppointment)
+
+	a.log(ctx).Info("Appointment updated successfully", zap.String("id", request.ID))
+	return a.mapAppointmentToRequest(updatedAppointment), nil
+}
+
+// updateAppointment arma la nueva versión de existing con los datos del
+// pedido. Conserva lo que el pedido no trae: la fecha de alta, los enlaces
+// ya usados y, si no cambia el horario, los recordatorios enviados.
+func updateAppointment(existing *models.Appointment, request *models.AppointmentRequest) *models.Appointment {
+	updated := &models.Appointment{
+		ID:        existing.ID,
 		ClientID:  request.ClientID,
 		PatientID: request.PatientID,
 		DoctorID:  request.DoctorID,
@@ -157,47 +279,82 @@ func (a *Appointments) UpdateAppointment(ctx context.Context, request *models.Ap
 		Duration:  request.Duration,
 		Status:    request.Status,
 		Notes:     request.Notes,
-		CreatedAt: existingAppointment.CreatedAt,
+		CreatedAt: existing.CreatedAt,
 		UpdatedAt: time.Now().Format(time.RFC3339),
+		Sequence:  existing.Sequence + 1,
 		Metadata:  request.Metadata,
 	}
-
-	// Guardar la cita actualizada
-	if err := a.AppointmentsRepository.Save(ctx, updatedAppointment); err != nil {
-		a.Logger.Error("Error updating appointment", zap.String("id", request.ID), zap.Error(err))
-		return fmt.Errorf("failed to update appointment: %w", err)
+	updated.ActionTokensUsed = existing.ActionTokensUsed
+	// Los recordatorios enviados valen mientras no cambie el horario
+	if updated.Date == existing.Date {
+		updated.RemindersSent = existing.RemindersSent
 	}
+	return updated
+}
 
-	a.Logger.Info("Appointment updated successfully", zap.String("id", request.ID))
-	return nil
+// updated avisa de una modificación ya guardada: cuenta las ausencias y las
+// cancelaciones y ofrece el horario si se canceló.
+func (a *Appointments) updated(ctx context.Context, before, after *models.Appointment) {
+	if a.NoShows != nil {
+		a.countNoShow(ctx, before, after)
+	}
+	if after.Status == models.AppointmentStatusCancelled && before.Status != models.AppointmentStatusCancelled {
+		a.count(metricCancelled, after)
+	}
+	if after.Status == models.AppointmentStatusCancelled {
+		a.offerSlot(ctx, before)
+	}
 }
 
 func (a *Appointments) DeleteAppointment(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "service.Appointments.DeleteAppointment")
+	defer span.End()
 	// Verificar que el ID no esté vacío
 	if id == "" {
-		a.Logger.Error("Error: empty ID provided for appointment deletion")
+		a.log(ctx).Error("Error: empty ID provided for appointment deletion")
 		return fmt.Errorf("appointment ID cannot be empty")
 	}
 
-	a.Logger.Info("Deleting appointment", zap.String("id", id))
+	a.log(ctx).Info("Deleting appointment", zap.String("id", id))
 
 	// Verificar primero si la cita existe
-	_, err := a.AppointmentsRepository.GetByID(ctx, id)
+	existingAppointment, err := a.AppointmentsRepository.GetByID(ctx, id)
 	if err != nil {
-		a.Logger.Error("Error finding appointment to delete", zap.String("id", id), zap.Error(err))
+		a.log(ctx).Error("Error finding appointment to delete", zap.String("id", id), zap.Error(err))
 		return fmt.Errorf("failed to find appointment with ID %s: %w", id, err)
 	}
 
 	// Eliminar la cita
-	if err := a.AppointmentsRepository.Delete(ctx, id); err != nil {
-		a.Logger.Error("Error deleting appointment", zap.String("id", id), zap.Error(err))
+	if err := a.delete(ctx, existingAppointment, id); err != nil {
+		a.log(ctx).Error("Error deleting appointment", zap.String("id", id), zap.Error(err))
 		return fmt.Errorf("failed to delete appointment: %w", err)
 	}
 
-	a.Logger.Info("Appointment deleted successfully", zap.String("id", id))
+	if existingAppointment != nil {
+		if existingAppointment.Status != models.AppointmentStatusCancelled {
+			a.count(metricCancelled, existingAppointment)
+		}
+		a.offerSlot(ctx, existingAppointment)
+	}
+
+	a.log(ctx).Info("Appointment deleted successfully", zap.String("id", id))
 	return nil
 }
 
+// offerSlot ofrece a la lista de espera el horario de un turno que seguía
+// vigente. Un error no hace fallar la cancelación: el turno ya se liberó.
+func (a *Appointments) offerSlot(ctx context.Context, freed *models.Appointment) {
+	if a.Waitlist == nil {
+		return
+	}
+	if freed.Status != models.AppointmentStatusScheduled && freed.Status != models.AppointmentStatusConfirmed {
+		return
+	}
+	if err := a.Waitlist.OfferSlot(ctx, freed); err != nil {
+		a.log(ctx).Error("Error offering slot to waitlist", zap.String("id", freed.ID), zap.Error(err))
+	}
+}
+
 func (a *Appointments) mapRequestToAppointment(req *models.AppointmentRequest) *models.Appointment {
 	return &models.Appointment{
 		ID:        uuid.NewString(),
@@ -232,14 +389,21 @@ func (a *Appointments) mapAppointmentToRequest(appointment *models.Appointment)
 
 func validateStatus(status models.AppointmentStatus) error {
 	switch status {
-	case models.AppointmentStatusScheduled, models.AppointmentStatusInProgress, models.AppointmentStatusCompleted, models.AppointmentStatusCancelled:
+	case models.AppointmentStatusScheduled, models.AppointmentStatusConfirmed, models.AppointmentStatusInProgress, models.AppointmentStatusCompleted, models.AppointmentStatusCancelled, models.AppointmentStatusNoShow:
 		return nil
 	default:
-		return fmt.Errorf("invalid status value: %s. Must be one of: %s, %s, %s, %s",
+		return fmt.Errorf("invalid status value: %s. Must be one of: %s, %s, %s, %s, %s, %s",
 			status,
 			models.AppointmentStatusScheduled,
+			models.AppointmentStatusConfirmed,
 			models.AppointmentStatusInProgress,
 			models.AppointmentStatusCompleted,
-			models.AppointmentStatusCancelled)
+			models.AppointmentStatusCancelled,
+			models.AppointmentStatusNoShow)
 	}
 }
+
+// log es el logger del pedido en ctx o, si no hay, el del servicio.
+func (a *Appointments) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, a.Logger)
+}
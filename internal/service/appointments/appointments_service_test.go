@@ -38,6 +38,14 @@ func (m *MockAppointmentsRepository) GetByClientID(ctx context.Context, clientID
 	return args.Get(0).([]*models.Appointment), args.Error(1)
 }
 
+func (m *MockAppointmentsRepository) GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Appointment, string, error) {
+	args := m.Called(ctx, clientID, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).([]*models.Appointment), args.String(1), args.Error(2)
+}
+
 func (m *MockAppointmentsRepository) GetByPatientID(ctx context.Context, patientID string) ([]*models.Appointment, error) {
 	args := m.Called(ctx, patientID)
 	if args.Get(0) == nil {
@@ -116,7 +124,11 @@ func TestAppointments_CreateAppointment_Success(t *testing.T) {
 
 	// Assert
 	assert.NoError(t, err)
-	assert.Equal(t, req, result)
+	// Devuelve lo que se guardó, con el ID y las fechas generadas
+	assert.NotEmpty(t, result.ID)
+	assert.NotEmpty(t, result.CreatedAt)
+	assert.Equal(t, result.CreatedAt, result.UpdatedAt)
+	assert.Equal(t, req.PatientID, result.PatientID)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -247,13 +259,14 @@ func TestAppointments_GetAllAppointments_Success(t *testing.T) {
 	appointment2 := createSampleAppointment("appointment2")
 	appointments := []*models.Appointment{appointment1, appointment2}
 
-	mockRepo.On("GetByClientID", ctx, clientID).Return(appointments, nil)
+	mockRepo.On("GetPageByClientID", ctx, clientID, "cursor1", int32(defaultListLimit)).Return(appointments, "cursor2", nil)
 
 	// Execute
-	result, err := service.GetAllAppointments(ctx, clientID)
+	result, next, err := service.GetAllAppointments(ctx, clientID, "cursor1", 0)
 
 	// Assert
 	assert.NoError(t, err)
+	assert.Equal(t, "cursor2", next)
 	assert.Len(t, result, 2)
 	assert.Equal(t, appointment1.ID, result[0].ID)
 	assert.Equal(t, appointment2.ID, result[1].ID)
@@ -266,7 +279,7 @@ func TestAppointments_GetAllAppointments_EmptyClientID(t *testing.T) {
 	ctx := context.Background()
 
 	// Execute
-	result, err := service.GetAllAppointments(ctx, "")
+	result, _, err := service.GetAllAppointments(ctx, "", "", 0)
 
 	// Assert
 	assert.Error(t, err)
@@ -281,10 +294,11 @@ func TestAppointments_GetAllAppointments_RepositoryError(t *testing.T) {
 	clientID := "client123"
 	expectedErr := errors.New("database error")
 
-	mockRepo.On("GetByClientID", ctx, clientID).Return(nil, expectedErr)
+	// El límite se acota a maxListLimit
+	mockRepo.On("GetPageByClientID", ctx, clientID, "", int32(maxListLimit)).Return(nil, "", expectedErr)
 
 	// Execute
-	result, err := service.GetAllAppointments(ctx, clientID)
+	result, _, err := service.GetAllAppointments(ctx, clientID, "", 500)
 
 	// Assert
 	assert.Error(t, err)
@@ -306,16 +320,47 @@ func TestAppointments_UpdateAppointment_Success(t *testing.T) {
 	existingAppointment := createSampleAppointment(appointmentID)
 
 	mockRepo.On("GetByID", ctx, appointmentID).Return(existingAppointment, nil)
-	mockRepo.On("Save", ctx, mock.AnythingOfType("*models.Appointment")).Return(nil)
+	mockRepo.On("Patch", ctx, mock.Anything, mock.AnythingOfType("*models.Appointment"), mock.Anything).Return(true, nil)
 
 	// Execute
-	err := service.UpdateAppointment(ctx, req)
+	result, err := service.UpdateAppointment(ctx, req)
 
 	// Assert
 	assert.NoError(t, err)
+	assert.Equal(t, existingAppointment.CreatedAt, result.CreatedAt)
+	assert.NotEmpty(t, result.UpdatedAt)
+	assert.Equal(t, req.Notes, result.Notes)
 	mockRepo.AssertExpectations(t)
 }
 
+func TestAppointments_UpdateAppointment_RemindersSent(t *testing.T) {
+	// Setup
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	existingAppointment := createSampleAppointment("appointment123")
+	existingAppointment.Date = "2024-01-15T10:00:00-03:00"
+	existingAppointment.RemindersSent = []string{"48h"}
+	mockRepo.On("GetByID", ctx, "appointment123").Return(existingAppointment, nil)
+
+	var saved []*models.Appointment
+	mockRepo.On("Patch", ctx, existingAppointment, mock.AnythingOfType("*models.Appointment"), mock.Anything).
+		Run(func(args mock.Arguments) { saved = append(saved, args.Get(2).(*models.Appointment)) }).
+		Return(true, nil)
+
+	// Execute: misma fecha, y después reprogramado
+	req := createSampleAppointmentRequest()
+	req.ID, req.Date = "appointment123", existingAppointment.Date
+	_, err := service.UpdateAppointment(ctx, req)
+	assert.NoError(t, err)
+	req.Date = "2024-01-16T10:00:00-03:00"
+	_, err = service.UpdateAppointment(ctx, req)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, []string{"48h"}, saved[0].RemindersSent)
+	assert.Empty(t, saved[1].RemindersSent)
+}
+
 func TestAppointments_UpdateAppointment_NotFound(t *testing.T) {
 	// Setup
 	service, mockRepo := setupTest()
@@ -328,7 +373,7 @@ func TestAppointments_UpdateAppointment_NotFound(t *testing.T) {
 	mockRepo.On("GetByID", ctx, appointmentID).Return(nil, errors.New("not found"))
 
 	// Execute
-	err := service.UpdateAppointment(ctx, req)
+	_, err := service.UpdateAppointment(ctx, req)
 
 	// Assert
 	assert.Error(t, err)
@@ -349,10 +394,10 @@ func TestAppointments_UpdateAppointment_SaveError(t *testing.T) {
 	expectedErr := errors.New("database error")
 
 	mockRepo.On("GetByID", ctx, appointmentID).Return(existingAppointment, nil)
-	mockRepo.On("Save", ctx, mock.AnythingOfType("*models.Appointment")).Return(expectedErr)
+	mockRepo.On("Patch", ctx, mock.Anything, mock.AnythingOfType("*models.Appointment"), mock.Anything).Return(false, expectedErr)
 
 	// Execute
-	err := service.UpdateAppointment(ctx, req)
+	_, err := service.UpdateAppointment(ctx, req)
 
 	// Assert
 	assert.Error(t, err)
@@ -361,6 +406,30 @@ func TestAppointments_UpdateAppointment_SaveError(t *testing.T) {
 }
 
 // Tests para DeleteAppointment
+// Un PUT sobre una versión vieja no pisa lo que se escribió en el medio
+func TestAppointments_UpdateAppointment_Conflict(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	existing := createSampleAppointment("appointment123")
+	existing.Sequence = 4
+	mockRepo.On("GetByID", ctx, "appointment123").Return(existing, nil).Once()
+	mockRepo.On("Patch", ctx, existing, mock.MatchedBy(func(a *models.Appointment) bool {
+		return a.Sequence == 5
+	}), mock.Anything).Return(false, nil).Once()
+	mockRepo.On("GetByID", ctx, "missing").Return(nil, nil).Once()
+
+	req := createSampleAppointmentRequest()
+	req.ID = "appointment123"
+	_, err := service.UpdateAppointment(ctx, req)
+	assert.ErrorIs(t, err, ErrPatchConflict)
+
+	req.ID = "missing"
+	_, err = service.UpdateAppointment(ctx, req)
+	assert.ErrorIs(t, err, ErrAppointmentNotFound)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
 func TestAppointments_DeleteAppointment_Success(t *testing.T) {
 	// Setup
 	service, mockRepo := setupTest()
@@ -459,3 +528,59 @@ func TestValidateStatus(t *testing.T) {
 		})
 	}
 }
+
+type MockWaitlistOfferer struct {
+	mock.Mock
+}
+
+func (m *MockWaitlistOfferer) OfferSlot(ctx context.Context, freed *models.Appointment) error {
+	return m.Called(ctx, freed).Error(0)
+}
+
+func TestAppointments_UpdateAppointment_CancelOffersSlot(t *testing.T) {
+	// Setup
+	service, mockRepo := setupTest()
+	waitlist := new(MockWaitlistOfferer)
+	service.Waitlist = waitlist
+	ctx := context.Background()
+	existing := createSampleAppointment("appointment123")
+
+	mockRepo.On("GetByID", ctx, "appointment123").Return(existing, nil)
+	mockRepo.On("Patch", ctx, mock.Anything, mock.AnythingOfType("*models.Appointment"), mock.Anything).Return(true, nil)
+	// Un error de la lista de espera no hace fallar la cancelación
+	waitlist.On("OfferSlot", ctx, existing).Return(errors.New("waitlist unavailable")).Once()
+
+	req := createSampleAppointmentRequest()
+	req.ID, req.Status = "appointment123", models.AppointmentStatusCancelled
+
+	// Execute
+	_, err := service.UpdateAppointment(ctx, req)
+
+	// Assert
+	assert.NoError(t, err)
+	waitlist.AssertExpectations(t)
+}
+
+func TestAppointments_DeleteAppointment_OffersSlot(t *testing.T) {
+	// Setup
+	service, mockRepo := setupTest()
+	waitlist := new(MockWaitlistOfferer)
+	service.Waitlist = waitlist
+	ctx := context.Background()
+	existing := createSampleAppointment("appointment123")
+	completed := createSampleAppointment("appointment456")
+	completed.Status = models.AppointmentStatusCompleted
+
+	mockRepo.On("GetByID", ctx, "appointment123").Return(existing, nil)
+	mockRepo.On("GetByID", ctx, "appointment456").Return(completed, nil)
+	mockRepo.On("Delete", ctx, mock.Anything).Return(nil)
+	waitlist.On("OfferSlot", ctx, existing).Return(nil).Once()
+
+	// Execute
+	assert.NoError(t, service.DeleteAppointment(ctx, "appointment123"))
+	assert.NoError(t, service.DeleteAppointment(ctx, "appointment456"))
+
+	// Assert
+	waitlist.AssertExpectations(t)
+	waitlist.AssertNotCalled(t, "OfferSlot", ctx, completed)
+}
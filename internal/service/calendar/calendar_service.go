@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/ical"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrFeedNotFound se devuelve tanto para feeds inexistentes como revocados o
+// con secreto inválido, para no revelar cuáles existen.
+var ErrFeedNotFound = errors.New("calendar feed not found")
+
+const (
+	uidDomain   = "iris.mezelaw.com"
+	feedHistory = 90 * 24 * time.Hour
+	feedPath    = "/calendar/feeds/"
+	feedSuffix  = ".ics"
+)
+
+type CalendarFeedsRepository interface {
+	Save(ctx context.Context, f *models.CalendarFeed) error
+	GetByID(ctx context.Context, id string) (*models.CalendarFeed, error)
+	GetByOwner(ctx context.Context, ownerType, ownerID string) ([]*models.CalendarFeed, error)
+}
+
+type AppointmentsRepository interface {
+	GetByPatientID(ctx context.Context, patientID string) ([]*models.Appointment, error)
+	GetByDoctorID(ctx context.Context, doctorID string) ([]*models.Appointment, error)
+}
+
+// PatientsRepository se usa para mostrar el nombre del paciente en la agenda
+// del médico. Es opcional.
+type PatientsRepository interface {
+	GetByID(ctx context.Context, id string) (*models.Patient, error)
+}
+
+type CalendarService interface {
+	CreateFeed(context.Context, *models.CalendarFeedRequest) (*models.CalendarFeedRequest, error)
+	GetFeeds(ctx context.Context, ownerType, ownerID string) ([]*models.CalendarFeedRequest, error)
+	RevokeFeed(ctx context.Context, id string) error
+	RenderFeed(ctx context.Context, token string) ([]byte, error)
+}
+
+type Calendar struct {
+	Logger                  *zap.SugaredLogger
+	CalendarFeedsRepository CalendarFeedsRepository
+	AppointmentsRepository  AppointmentsRepository
+	PatientsRepository      PatientsRepository
+	BaseURL                 string
+}
+
+func New(logger *zap.SugaredLogger, feeds CalendarFeedsRepository, appointments AppointmentsRepository, patients PatientsRepository, baseURL string) CalendarService {
+	return &Calendar{
+		Logger:                  logger,
+		CalendarFeedsRepository: feeds,
+		AppointmentsRepository:  appointments,
+		PatientsRepository:      patients,
+		BaseURL:                 baseURL,
+	}
+}
+
+func (c *Calendar) CreateFeed(ctx context.Context, request *models.CalendarFeedRequest) (*models.CalendarFeedRequest, error) {
+	if err := validateOwner(request.OwnerType, request.OwnerID); err != nil {
+		c.log(ctx).Error("Invalid calendar feed owner", zap.String("ownerType", request.OwnerType))
+		return nil, err
+	}
+	if request.ClientID == "" {
+		return nil, fmt.Errorf("client_id is required")
+	}
+	if request.TimeZone != "" {
+		if _, err := time.LoadLocation(request.TimeZone); err != nil {
+			return nil, fmt.Errorf("invalid time_zone: %s", request.TimeZone)
+		}
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		c.log(ctx).Error("Error generating calendar feed secret", zap.Error(err))
+		return nil, err
+	}
+	feed := &models.CalendarFeed{
+		ID:         uuid.NewString(),
+		ClientID:   request.ClientID,
+		OwnerType:  request.OwnerType,
+		OwnerID:    request.OwnerID,
+		Name:       request.Name,
+		TimeZone:   request.TimeZone,
+		SecretHash: hashSecret(secret),
+		CreatedAt:  time.Now().Format(time.RFC3339),
+	}
+	if err := c.CalendarFeedsRepository.Save(ctx, feed); err != nil {
+		c.log(ctx).Error("Error on CalendarFeedsRepository.Save", zap.Error(err))
+		return nil, err
+	}
+
+	response := mapFeedToRequest(feed)
+	response.URL = strings.TrimRight(c.BaseURL, "/") + feedPath + feed.ID + "." + secret + feedSuffix
+	c.log(ctx).Info("Calendar feed created", zap.String("id", feed.ID))
+	return response, nil
+}
+
+func (c *Calendar) GetFeeds(ctx context.Context, ownerType, ownerID string) ([]*models.CalendarFeedRequest, error) {
+	if err := validateOwner(ownerType, ownerID); err != nil {
+		return nil, err
+	}
+	feeds, err := c.CalendarFeedsRepository.GetByOwner(ctx, ownerType, ownerID)
+	if err != nil {
+		c.log(ctx).Error("Error getting calendar feeds by owner", zap.String("ownerID", ownerID), zap.Error(err))
+		return nil, err
+	}
+	results := make([]*models.CalendarFeedRequest, 0, len(feeds))
+	for _, feed := range feeds {
+		results = append(results, mapFeedToRequest(feed))
+	}
+	return results, nil
+}
+
+func (c *Calendar) RevokeFeed(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("calendar feed ID cannot be empty")
+	}
+	feed, err := c.CalendarFeedsRepository.GetByID(ctx, id)
+	if err != nil {
+		c.log(ctx).Error("Error finding calendar feed to revoke", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	if feed == nil {
+		return ErrFeedNotFound
+	}
+	if feed.RevokedAt != "" {
+		return nil
+	}
+
+	feed.RevokedAt = time.Now().Format(time.RFC3339)
+	if err := c.CalendarFeedsRepository.Save(ctx, feed); err != nil {
+		c.log(ctx).Error("Error revoking calendar feed", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("failed to revoke calendar feed: %w", err)
+	}
+	c.log(ctx).Info("Calendar feed revoked", zap.String("id", id))
+	return nil
+}
+
+// RenderFeed valida el token de la URL de suscripción y genera el .ics con
+// los turnos del dueño del feed dentro del tenant.
+func (c *Calendar) RenderFeed(ctx context.Context, token string) ([]byte, error) {
+	id, secret, ok := strings.Cut(strings.TrimSuffix(token, feedSuffix), ".")
+	if !ok || id == "" || secret == "" {
+		return nil, ErrFeedNotFound
+	}
+	feed, err := c.CalendarFeedsRepository.GetByID(ctx, id)
+	if err != nil {
+		c.log(ctx).Error("Error getting calendar feed", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	if feed == nil || feed.RevokedAt != "" ||
+		subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(feed.SecretHash)) != 1 {
+		return nil, ErrFeedNotFound
+	}
+
+	var appointments []*models.Appointment
+	if feed.OwnerType == models.CalendarOwnerDoctor {
+		appointments, err = c.AppointmentsRepository.GetByDoctorID(ctx, feed.OwnerID)
+	} else {
+		appointments, err = c.AppointmentsRepository.GetByPatientID(ctx, feed.OwnerID)
+	}
+	if err != nil {
+		c.log(ctx).Error("Error getting appointments for calendar feed", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+
+	loc := time.UTC
+	if feed.TimeZone != "" {
+		if loc, err = time.LoadLocation(feed.TimeZone); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	calendar := &ical.Calendar{Name: feed.Name, Location: loc}
+	patientNames := map[string]string{}
+	for _, a := range appointments {
+		// Nunca mezclar turnos de otro tenant en el feed
+		if a.ClientID != feed.ClientID {
+			continue
+		}
+		event, ok := c.eventFromAppointment(ctx, feed, a, patientNames)
+		if !ok || event.End.Before(now.Add(-feedHistory)) {
+			continue
+		}
+		calendar.Events = append(calendar.Events, event)
+	}
+	sort.Slice(calendar.Events, func(i, j int) bool { return calendar.Events[i].Start.Before(calendar.Events[j].Start) })
+
+	c.log(ctx).Info("Calendar feed rendered", zap.String("id", id), zap.Int("events", len(calendar.Events)))
+	return calendar.Encode(now), nil
+}
+
+func (c *Calendar) eventFromAppointment(ctx context.Context, feed *models.CalendarFeed, a *models.Appointment, patientNames map[string]string) (ical.Event, bool) {
+	start, err := time.Parse(time.RFC3339, a.Date)
+	if err != nil {
+		c.log(ctx).Warnw("Skipping appointment with invalid date", "id", a.ID, "date", a.Date)
+		return ical.Event{}, false
+	}
+
+	event := ical.Event{
+		UID:         a.ID + "@" + uidDomain,
+		Sequence:    a.Sequence,
+		Start:       start,
+		End:         start.Add(time.Duration(a.Duration) * time.Minute),
+		Summary:     "Turno médico",
+		Description: a.Notes,
+		Status:      ical.StatusConfirmed,
+	}
+	if a.Status == models.AppointmentStatusCancelled {
+		event.Status = ical.StatusCancelled
+	}
+	if created, err := time.Parse(time.RFC3339, a.CreatedAt); err == nil {
+		event.Created = created
+	}
+	if updated, err := time.Parse(time.RFC3339, a.UpdatedAt); err == nil {
+		event.LastModified = updated
+	}
+	if feed.OwnerType == models.CalendarOwnerDoctor {
+		event.Summary = "Turno: " + c.patientName(ctx, a.PatientID, patientNames)
+	}
+	return event, true
+}
+
+func (c *Calendar) patientName(ctx context.Context, patientID string, cache map[string]string) string {
+	if name, ok := cache[patientID]; ok {
+		return name
+	}
+	name := patientID
+	if c.PatientsRepository != nil {
+		if patient, err := c.PatientsRepository.GetByID(ctx, patientID); err == nil && patient != nil {
+			name = strings.TrimSpace(patient.FirstName + " " + patient.LastName)
+		}
+	}
+	cache[patientID] = name
+	return name
+}
+
+func validateOwner(ownerType, ownerID string) error {
+	if ownerType != models.CalendarOwnerDoctor && ownerType != models.CalendarOwnerPatient {
+		return fmt.Errorf("invalid owner_type value: %s. Must be one of: %s, %s", ownerType, models.CalendarOwnerDoctor, models.CalendarOwnerPatient)
+	}
+	if ownerID == "" {
+		return fmt.Errorf("owner_id is required")
+	}
+	return nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func mapFeedToRequest(feed *models.CalendarFeed) *models.CalendarFeedRequest {
+	return &models.CalendarFeedRequest{
+		ID:        feed.ID,
+		ClientID:  feed.ClientID,
+		OwnerType: feed.OwnerType,
+		OwnerID:   feed.OwnerID,
+		Name:      feed.Name,
+		TimeZone:  feed.TimeZone,
+		CreatedAt: feed.CreatedAt,
+		RevokedAt: feed.RevokedAt,
+	}
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del servicio.
+func (c *Calendar) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, c.Logger)
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockCalendarFeedsRepository struct {
+	mock.Mock
+}
+
+func (m *MockCalendarFeedsRepository) Save(ctx context.Context, f *models.CalendarFeed) error {
+	return m.Called(ctx, f).Error(0)
+}
+
+func (m *MockCalendarFeedsRepository) GetByID(ctx context.Context, id string) (*models.CalendarFeed, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CalendarFeed), args.Error(1)
+}
+
+func (m *MockCalendarFeedsRepository) GetByOwner(ctx context.Context, ownerType, ownerID string) ([]*models.CalendarFeed, error) {
+	args := m.Called(ctx, ownerType, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.CalendarFeed), args.Error(1)
+}
+
+type MockAppointmentsRepository struct {
+	mock.Mock
+}
+
+func (m *MockAppointmentsRepository) GetByPatientID(ctx context.Context, patientID string) ([]*models.Appointment, error) {
+	args := m.Called(ctx, patientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Appointment), args.Error(1)
+}
+
+func (m *MockAppointmentsRepository) GetByDoctorID(ctx context.Context, doctorID string) ([]*models.Appointment, error) {
+	args := m.Called(ctx, doctorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Appointment), args.Error(1)
+}
+
+type MockPatientsRepository struct {
+	mock.Mock
+}
+
+func (m *MockPatientsRepository) GetByID(ctx context.Context, id string) (*models.Patient, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Patient), args.Error(1)
+}
+
+func setupTest() (*Calendar, *MockCalendarFeedsRepository, *MockAppointmentsRepository, *MockPatientsRepository) {
+	feeds := new(MockCalendarFeedsRepository)
+	appointments := new(MockAppointmentsRepository)
+	patients := new(MockPatientsRepository)
+	logger, _ := zap.NewDevelopment()
+	service := &Calendar{
+		Logger:                  logger.Sugar(),
+		CalendarFeedsRepository: feeds,
+		AppointmentsRepository:  appointments,
+		PatientsRepository:      patients,
+		BaseURL:                 "https://api.example.com/",
+	}
+	return service, feeds, appointments, patients
+}
+
+// createFeed crea un feed con el servicio y devuelve el token y el feed guardado.
+func createFeed(t *testing.T, service *Calendar, feeds *MockCalendarFeedsRepository, ownerType string) (string, *models.CalendarFeed) {
+	var saved *models.CalendarFeed
+	feeds.On("Save", mock.Anything, mock.AnythingOfType("*models.CalendarFeed")).
+		Run(func(args mock.Arguments) { saved = args.Get(1).(*models.CalendarFeed) }).
+		Return(nil).Once()
+
+	created, err := service.CreateFeed(context.Background(), &models.CalendarFeedRequest{
+		ClientID:  "client123",
+		OwnerType: ownerType,
+		OwnerID:   "owner123",
+		Name:      "Agenda",
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(created.URL, "https://api.example.com/calendar/feeds/"+saved.ID+"."))
+	return strings.TrimPrefix(created.URL, "https://api.example.com/calendar/feeds/"), saved
+}
+
+func TestCalendar_CreateFeed_StoresOnlySecretHash(t *testing.T) {
+	service, feeds, _, _ := setupTest()
+
+	token, saved := createFeed(t, service, feeds, models.CalendarOwnerDoctor)
+
+	_, secret, _ := strings.Cut(strings.TrimSuffix(token, ".ics"), ".")
+	assert.NotEmpty(t, saved.SecretHash)
+	assert.NotContains(t, saved.SecretHash, secret)
+	assert.Equal(t, hashSecret(secret), saved.SecretHash)
+}
+
+func TestCalendar_CreateFeed_InvalidOwner(t *testing.T) {
+	service, _, _, _ := setupTest()
+
+	result, err := service.CreateFeed(context.Background(), &models.CalendarFeedRequest{ClientID: "client123", OwnerType: "NURSE", OwnerID: "x"})
+
+	assert.Nil(t, result)
+	assert.Error(t, err)
+}
+
+func TestCalendar_RenderFeed_Doctor(t *testing.T) {
+	service, feeds, appointments, patients := setupTest()
+	token, saved := createFeed(t, service, feeds, models.CalendarOwnerDoctor)
+	ctx := context.Background()
+	start := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Minute)
+
+	feeds.On("GetByID", ctx, saved.ID).Return(saved, nil)
+	appointments.On("GetByDoctorID", ctx, "owner123").Return([]*models.Appointment{
+		{ID: "a1", ClientID: "client123", PatientID: "p1", Date: start.Format(time.RFC3339), Duration: 30, Status: models.AppointmentStatusScheduled, Sequence: 1},
+		{ID: "a2", ClientID: "client123", PatientID: "p1", Date: start.Add(time.Hour).Format(time.RFC3339), Duration: 30, Status: models.AppointmentStatusCancelled, Sequence: 3},
+		{ID: "a3", ClientID: "other", PatientID: "p2", Date: start.Format(time.RFC3339), Duration: 30, Status: models.AppointmentStatusScheduled},
+	}, nil)
+	patients.On("GetByID", ctx, "p1").Return(&models.Patient{ID: "p1", FirstName: "Ana", LastName: "García"}, nil).Once()
+
+	body, err := service.RenderFeed(ctx, token)
+
+	require.NoError(t, err)
+	out := string(body)
+	assert.Contains(t, out, "UID:a1@iris.mezelaw.com\r\n")
+	assert.Contains(t, out, "SEQUENCE:1\r\n")
+	assert.Contains(t, out, "UID:a2@iris.mezelaw.com\r\n")
+	assert.Contains(t, out, "STATUS:CANCELLED\r\n")
+	assert.Contains(t, out, "SUMMARY:Turno: Ana García\r\n")
+	assert.NotContains(t, out, "a3@")
+	patients.AssertExpectations(t)
+}
+
+func TestCalendar_RenderFeed_RejectsBadTokens(t *testing.T) {
+	service, feeds, _, _ := setupTest()
+	token, saved := createFeed(t, service, feeds, models.CalendarOwnerPatient)
+	ctx := context.Background()
+
+	revoked := *saved
+	revoked.RevokedAt = time.Now().Format(time.RFC3339)
+	feeds.On("GetByID", ctx, saved.ID).Return(&revoked, nil)
+	feeds.On("GetByID", ctx, "unknown").Return(nil, nil)
+
+	tests := []string{
+		token,
+		saved.ID + ".wrong-secret.ics",
+		"unknown.secret.ics",
+		"no-secret",
+	}
+	for _, tt := range tests {
+		_, err := service.RenderFeed(ctx, tt)
+		assert.ErrorIs(t, err, ErrFeedNotFound, tt)
+	}
+}
+
+func TestCalendar_RevokeFeed(t *testing.T) {
+	service, feeds, _, _ := setupTest()
+	ctx := context.Background()
+	feed := &models.CalendarFeed{ID: "feed123", OwnerType: models.CalendarOwnerDoctor, OwnerID: "owner123"}
+
+	feeds.On("GetByID", ctx, "feed123").Return(feed, nil)
+	feeds.On("Save", ctx, mock.MatchedBy(func(f *models.CalendarFeed) bool { return f.RevokedAt != "" })).Return(nil)
+
+	err := service.RevokeFeed(ctx, "feed123")
+
+	assert.NoError(t, err)
+	feeds.AssertExpectations(t)
+}
+
+func TestCalendar_RevokeFeed_NotFound(t *testing.T) {
+	service, feeds, _, _ := setupTest()
+	ctx := context.Background()
+	feeds.On("GetByID", ctx, "missing").Return(nil, nil)
+
+	assert.ErrorIs(t, service.RevokeFeed(ctx, "missing"), ErrFeedNotFound)
+}
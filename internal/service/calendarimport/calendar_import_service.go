@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/ical"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/textnorm"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidImport se devuelve cuando la solicitud o el archivo .ics no se
+// pueden procesar; los problemas de eventos individuales van al reporte.
+var ErrInvalidImport = errors.New("invalid calendar import")
+
+const (
+	defaultHorizon = 365 * 24 * time.Hour
+	// maxOccurrences limita cada serie recurrente sin COUNT ni UNTIL
+	maxOccurrences = 500
+
+	metadataSource       = "source"
+	metadataUID          = "ical_uid"
+	metadataRecurrenceID = "ical_recurrence_id"
+	sourceICal           = "ical_import"
+)
+
+var (
+	defaultDocumentPattern = regexp.MustCompile(`(\d[\d.\-]{5,})`)
+	nonDigits              = regexp.MustCompile(`\D`)
+)
+
+type PatientsRepository interface {
+	GetByClientID(ctx context.Context, clientID string) ([]*models.Patient, error)
+}
+
+// AppointmentsRepository se usa para no duplicar turnos si el mismo archivo
+// se importa dos veces. Es opcional.
+type AppointmentsRepository interface {
+	GetByDoctorID(ctx context.Context, doctorID string) ([]*models.Appointment, error)
+}
+
+type AppointmentsService interface {
+	CreateAppointment(context.Context, *models.AppointmentRequest) (*models.AppointmentRequest, error)
+}
+
+type CalendarImportService interface {
+	Import(context.Context, *models.CalendarImportRequest) (*models.CalendarImportReport, error)
+}
+
+type CalendarImport struct {
+	Logger                 *zap.SugaredLogger
+	PatientsRepository     PatientsRepository
+	AppointmentsRepository AppointmentsRepository
+	Appointments           AppointmentsService
+}
+
+func New(logger *zap.SugaredLogger, patients PatientsRepository, appointmentsRepository AppointmentsRepository, appointments AppointmentsService) CalendarImportService {
+	return &CalendarImport{
+		Logger:                 logger,
+		PatientsRepository:     patients,
+		AppointmentsRepository: appointmentsRepository,
+		Appointments:           appointments,
+	}
+}
+
+// Import parsea el .ics, expande las series y crea un turno por ocurrencia a
+// través de CreateAppointment. Con DryRun sólo arma el reporte.
+func (c *CalendarImport) Import(ctx context.Context, request *models.CalendarImportRequest) (*models.CalendarImportReport, error) {
+	if request.ClientID == "" || request.DoctorID == "" {
+		return nil, fmt.Errorf("%w: client_id and doctor_id are required", ErrInvalidImport)
+	}
+	loc := time.UTC
+	if request.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(request.TimeZone); err != nil {
+			return nil, fmt.Errorf("%w: invalid time_zone %s", ErrInvalidImport, request.TimeZone)
+		}
+	}
+	now := time.Now()
+	until := now.Add(defaultHorizon)
+	if request.Until != "" {
+		var err error
+		if until, err = time.Parse(time.RFC3339, request.Until); err != nil {
+			return nil, fmt.Errorf("%w: invalid until %s", ErrInvalidImport, request.Until)
+		}
+	}
+	match, err := newMatcher(request.MatchRule, request.MatchPattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	parsed, err := ical.Parse(strings.NewReader(request.Calendar), loc)
+	if err != nil {
+		c.log(ctx).Error("Error parsing calendar to import", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	occurrences, expandErrs := ical.Expand(parsed, until, maxOccurrences)
+
+	patients, err := c.PatientsRepository.GetByClientID(ctx, request.ClientID)
+	if err != nil {
+		c.log(ctx).Error("Error getting patients for calendar import", zap.String("clientID", request.ClientID), zap.Error(err))
+		return nil, err
+	}
+	existing, err := c.importedKeys(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.CalendarImportReport{DryRun: request.DryRun}
+	for _, expandErr := range expandErrs {
+		item := &models.CalendarImportItem{Result: models.CalendarImportItemFailed, Reason: expandErr.Error()}
+		var e *ical.ExpandError
+		if errors.As(expandErr, &e) {
+			item.UID = e.UID
+		}
+		report.Items = append(report.Items, item)
+	}
+	for _, event := range occurrences {
+		report.Items = append(report.Items, c.importEvent(ctx, request, event, loc, now, match.patient(event, patients), existing))
+	}
+
+	for _, item := range report.Items {
+		switch item.Result {
+		case models.CalendarImportItemImported, models.CalendarImportItemWouldImport:
+			report.Imported++
+		case models.CalendarImportItemSkipped:
+			report.Skipped++
+		case models.CalendarImportItemFailed:
+			report.Failed++
+		}
+	}
+	report.Total = len(report.Items)
+
+	c.log(ctx).Info("Calendar import finished",
+		zap.String("clientID", request.ClientID),
+		zap.Bool("dryRun", request.DryRun),
+		zap.Int("imported", report.Imported),
+		zap.Int("skipped", report.Skipped),
+		zap.Int("failed", report.Failed))
+	return report, nil
+}
+
+func (c *CalendarImport) importEvent(ctx context.Context, request *models.CalendarImportRequest, event ical.Event, loc *time.Location, now time.Time, patient matchResult, existing map[string]bool) *models.CalendarImportItem {
+	item := &models.CalendarImportItem{
+		UID:     event.UID,
+		Start:   event.Start.In(loc).Format(time.RFC3339),
+		Summary: event.Summary,
+		Result:  models.CalendarImportItemSkipped,
+	}
+	duration := int(event.End.Sub(event.Start) / time.Minute)
+
+	switch {
+	case event.Status == ical.StatusCancelled:
+		item.Reason = "event is cancelled"
+	case event.AllDay:
+		item.Reason = "all-day events are not appointments"
+	case duration <= 0:
+		item.Reason = "event has no duration"
+	case existing[importKey(event.UID, item.Start)]:
+		item.Reason = "already imported"
+	case patient.reason != "":
+		item.Reason = patient.reason
+	}
+	if item.Reason != "" {
+		return item
+	}
+
+	status := models.AppointmentStatusScheduled
+	if event.End.Before(now) {
+		status = models.AppointmentStatusCompleted
+	}
+	metadata := map[string]interface{}{
+		metadataSource: sourceICal,
+		metadataUID:    event.UID,
+	}
+	if !event.RecurrenceID.IsZero() {
+		metadata[metadataRecurrenceID] = event.RecurrenceID.In(loc).Format(time.RFC3339)
+	}
+	item.Appointment = &models.AppointmentRequest{
+		ClientID:  request.ClientID,
+		PatientID: patient.id,
+		DoctorID:  request.DoctorID,
+		Date:      item.Start,
+		Duration:  duration,
+		Status:    status,
+		Notes:     event.Description,
+		Metadata:  metadata,
+	}
+
+	existing[importKey(event.UID, item.Start)] = true
+	if request.DryRun {
+		item.Result = models.CalendarImportItemWouldImport
+		return item
+	}
+	created, err := c.Appointments.CreateAppointment(ctx, item.Appointment)
+	if err != nil {
+		c.log(ctx).Error("Error creating imported appointment", zap.String("uid", event.UID), zap.Error(err))
+		item.Result = models.CalendarImportItemFailed
+		item.Reason = err.Error()
+		return item
+	}
+	item.Appointment = created
+	item.Result = models.CalendarImportItemImported
+	return item
+}
+
+// importedKeys devuelve las ocurrencias ya importadas para el médico, por
+// UID y fecha.
+func (c *CalendarImport) importedKeys(ctx context.Context, request *models.CalendarImportRequest) (map[string]bool, error) {
+	keys := map[string]bool{}
+	if c.AppointmentsRepository == nil {
+		return keys, nil
+	}
+	appointments, err := c.AppointmentsRepository.GetByDoctorID(ctx, request.DoctorID)
+	if err != nil {
+		c.log(ctx).Error("Error getting doctor appointments for calendar import", zap.String("doctorID", request.DoctorID), zap.Error(err))
+		return nil, err
+	}
+	for _, a := range appointments {
+		if a.ClientID != request.ClientID {
+			continue
+		}
+		if uid, ok := a.Metadata[metadataUID].(string); ok {
+			if date, err := time.Parse(time.RFC3339, a.Date); err == nil {
+				keys[importKey(uid, date.Format(time.RFC3339))] = true
+			}
+		}
+	}
+	return keys, nil
+}
+
+func importKey(uid, start string) string {
+	if t, err := time.Parse(time.RFC3339, start); err == nil {
+		start = t.UTC().Format(time.RFC3339)
+	}
+	return uid + "|" + start
+}
+
+type matcher struct {
+	rule    string
+	pattern *regexp.Regexp
+}
+
+type matchResult struct {
+	id     string
+	reason string
+}
+
+func newMatcher(rule, pattern string) (*matcher, error) {
+	m := &matcher{rule: rule}
+	switch rule {
+	case models.CalendarImportMatchAttendeeEmail, models.CalendarImportMatchSummaryName:
+	case models.CalendarImportMatchSummaryDocument:
+		m.pattern = defaultDocumentPattern
+	default:
+		return nil, fmt.Errorf("invalid match_rule value: %s. Must be one of: %s, %s, %s", rule,
+			models.CalendarImportMatchAttendeeEmail,
+			models.CalendarImportMatchSummaryName,
+			models.CalendarImportMatchSummaryDocument)
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match_pattern: %v", err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("match_pattern must have a capture group")
+		}
+		m.pattern = re
+	}
+	return m, nil
+}
+
+// patient busca al paciente del evento. Si hay más de un candidato no se
+// elige ninguno: importar un turno al paciente equivocado es peor que
+// saltearlo.
+func (m *matcher) patient(event ical.Event, patients []*models.Patient) matchResult {
+	var candidates []*models.Patient
+	switch m.rule {
+	case models.CalendarImportMatchAttendeeEmail:
+		for _, attendee := range event.Attendees {
+			email := strings.ToLower(attendee.Email)
+			if email == "" {
+				continue
+			}
+			candidates = filter(patients, func(p *models.Patient) bool { return strings.ToLower(p.Email) == email })
+			if len(candidates) > 0 {
+				break
+			}
+		}
+	case models.CalendarImportMatchSummaryName:
+		if m.pattern != nil {
+			text, ok := m.extract(event.Summary)
+			if !ok {
+				return matchResult{reason: "summary does not match pattern"}
+			}
+			key := textnorm.Key(text)
+			candidates = filter(patients, func(p *models.Patient) bool {
+				return key == textnorm.Key(p.FirstName+" "+p.LastName) || key == textnorm.Key(p.LastName+" "+p.FirstName)
+			})
+			break
+		}
+		summary := " " + textnorm.Key(event.Summary) + " "
+		candidates = filter(patients, func(p *models.Patient) bool {
+			return strings.Contains(summary, " "+textnorm.Key(p.FirstName+" "+p.LastName)+" ") ||
+				strings.Contains(summary, " "+textnorm.Key(p.LastName+" "+p.FirstName)+" ")
+		})
+	case models.CalendarImportMatchSummaryDocument:
+		text, ok := m.extract(event.Summary)
+		document := nonDigits.ReplaceAllString(text, "")
+		if !ok || document == "" {
+			return matchResult{reason: "no document number in summary"}
+		}
+		candidates = filter(patients, func(p *models.Patient) bool {
+			return nonDigits.ReplaceAllString(p.DocNumber, "") == document
+		})
+	}
+
+	switch len(candidates) {
+	case 0:
+		return matchResult{reason: "no matching patient"}
+	case 1:
+		return matchResult{id: candidates[0].ID}
+	default:
+		return matchResult{reason: fmt.Sprintf("%d patients match", len(candidates))}
+	}
+}
+
+func (m *matcher) extract(summary string) (string, bool) {
+	groups := m.pattern.FindStringSubmatch(summary)
+	if len(groups) < 2 {
+		return "", false
+	}
+	return groups[1], true
+}
+
+func filter(patients []*models.Patient, keep func(*models.Patient) bool) []*models.Patient {
+	var result []*models.Patient
+	for _, p := range patients {
+		if keep(p) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del servicio.
+func (c *CalendarImport) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, c.Logger)
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/ical"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockPatientsRepository struct {
+	mock.Mock
+}
+
+func (m *MockPatientsRepository) GetByClientID(ctx context.Context, clientID string) ([]*models.Patient, error) {
+	args := m.Called(ctx, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Patient), args.Error(1)
+}
+
+type MockAppointmentsRepository struct {
+	mock.Mock
+}
+
+func (m *MockAppointmentsRepository) GetByDoctorID(ctx context.Context, doctorID string) ([]*models.Appointment, error) {
+	args := m.Called(ctx, doctorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Appointment), args.Error(1)
+}
+
+type MockAppointmentsService struct {
+	mock.Mock
+}
+
+func (m *MockAppointmentsService) CreateAppointment(ctx context.Context, request *models.AppointmentRequest) (*models.AppointmentRequest, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AppointmentRequest), args.Error(1)
+}
+
+const calendar = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly\r\n" +
+	"DTSTART:20300107T130000Z\r\n" +
+	"DTEND:20300107T133000Z\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=3\r\n" +
+	"EXDATE:20300114T130000Z\r\n" +
+	"SUMMARY:Control - Martinez Ana\r\n" +
+	"DESCRIPTION:Seguimiento\r\n" +
+	"ATTENDEE;CN=Ana:mailto:ANA@example.com\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:unknown\r\n" +
+	"DTSTART:20300108T130000Z\r\n" +
+	"DURATION:PT20M\r\n" +
+	"SUMMARY:Consulta Pedro\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:cancelled\r\n" +
+	"DTSTART:20300109T130000Z\r\n" +
+	"DURATION:PT20M\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"SUMMARY:Ana Martínez\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:broken\r\n" +
+	"DTSTART:20300110T130000Z\r\n" +
+	"RRULE:FREQ=SECONDLY\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+var patients = []*models.Patient{
+	{ID: "p1", ClientID: "client123", FirstName: "Ana", LastName: "Martínez", Email: "ana@example.com", DocNumber: "30.123.456"},
+	{ID: "p2", ClientID: "client123", FirstName: "Juan", LastName: "Pérez", Email: "juan@example.com", DocNumber: "28999888"},
+}
+
+func setupTest() (*CalendarImport, *MockPatientsRepository, *MockAppointmentsRepository, *MockAppointmentsService) {
+	patientsRepo := new(MockPatientsRepository)
+	appointmentsRepo := new(MockAppointmentsRepository)
+	appointments := new(MockAppointmentsService)
+	logger, _ := zap.NewDevelopment()
+	service := &CalendarImport{
+		Logger:                 logger.Sugar(),
+		PatientsRepository:     patientsRepo,
+		AppointmentsRepository: appointmentsRepo,
+		Appointments:           appointments,
+	}
+	return service, patientsRepo, appointmentsRepo, appointments
+}
+
+func TestCalendarImport_DryRun(t *testing.T) {
+	service, patientsRepo, appointmentsRepo, appointments := setupTest()
+	ctx := context.Background()
+	patientsRepo.On("GetByClientID", ctx, "client123").Return(patients, nil)
+	appointmentsRepo.On("GetByDoctorID", ctx, "doc1").Return([]*models.Appointment{}, nil)
+
+	report, err := service.Import(ctx, &models.CalendarImportRequest{
+		ClientID:  "client123",
+		DoctorID:  "doc1",
+		Calendar:  calendar,
+		Until:     "2031-01-01T00:00:00Z",
+		MatchRule: models.CalendarImportMatchAttendeeEmail,
+		DryRun:    true,
+	})
+
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 5, report.Total)
+	assert.Equal(t, 2, report.Imported) // la serie semanal sin el EXDATE
+	assert.Equal(t, 2, report.Skipped)  // paciente desconocido y cancelado
+	assert.Equal(t, 1, report.Failed)   // RRULE no soportado
+
+	var wouldImport []*models.CalendarImportItem
+	for _, item := range report.Items {
+		if item.Result == models.CalendarImportItemWouldImport {
+			wouldImport = append(wouldImport, item)
+		}
+	}
+	require.Len(t, wouldImport, 2)
+	first := wouldImport[0].Appointment
+	assert.Equal(t, "p1", first.PatientID)
+	assert.Equal(t, "doc1", first.DoctorID)
+	assert.Equal(t, "2030-01-07T13:00:00Z", first.Date)
+	assert.Equal(t, 30, first.Duration)
+	assert.Equal(t, models.AppointmentStatusScheduled, first.Status)
+	assert.Equal(t, "Seguimiento", first.Notes)
+	assert.Equal(t, "weekly", first.Metadata[metadataUID])
+	assert.Equal(t, "2030-01-21T13:00:00Z", wouldImport[1].Appointment.Date)
+
+	appointments.AssertNotCalled(t, "CreateAppointment", mock.Anything, mock.Anything)
+}
+
+func TestCalendarImport_CreatesAndSkipsAlreadyImported(t *testing.T) {
+	service, patientsRepo, appointmentsRepo, appointments := setupTest()
+	ctx := context.Background()
+	patientsRepo.On("GetByClientID", ctx, "client123").Return(patients, nil)
+	appointmentsRepo.On("GetByDoctorID", ctx, "doc1").Return([]*models.Appointment{
+		{ID: "a1", ClientID: "client123", Date: "2030-01-07T10:00:00-03:00", Metadata: map[string]interface{}{metadataUID: "weekly"}},
+	}, nil)
+	appointments.On("CreateAppointment", ctx, mock.MatchedBy(func(r *models.AppointmentRequest) bool {
+		return r.Date == "2030-01-21T13:00:00Z"
+	})).Return(&models.AppointmentRequest{ID: "new"}, nil).Once()
+
+	report, err := service.Import(ctx, &models.CalendarImportRequest{
+		ClientID:  "client123",
+		DoctorID:  "doc1",
+		Calendar:  calendar,
+		Until:     "2031-01-01T00:00:00Z",
+		MatchRule: models.CalendarImportMatchSummaryName,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Imported)
+	reasons := map[string]string{}
+	for _, item := range report.Items {
+		reasons[item.UID+" "+item.Start] = item.Reason
+	}
+	assert.Equal(t, "already imported", reasons["weekly 2030-01-07T13:00:00Z"])
+	assert.Equal(t, "no matching patient", reasons["unknown 2030-01-08T13:00:00Z"])
+	appointments.AssertExpectations(t)
+}
+
+func TestCalendarImport_CreateErrorIsReported(t *testing.T) {
+	service, patientsRepo, _, appointments := setupTest()
+	service.AppointmentsRepository = nil
+	ctx := context.Background()
+	patientsRepo.On("GetByClientID", ctx, "client123").Return(patients, nil)
+	appointments.On("CreateAppointment", ctx, mock.Anything).Return(nil, errors.New("database error"))
+
+	report, err := service.Import(ctx, &models.CalendarImportRequest{
+		ClientID:     "client123",
+		DoctorID:     "doc1",
+		Calendar:     "BEGIN:VEVENT\r\nUID:x\r\nDTSTART:20300107T130000Z\r\nDURATION:PT30M\r\nSUMMARY:Turno DNI 28.999.888\r\nEND:VEVENT\r\n",
+		MatchRule:    models.CalendarImportMatchSummaryDocument,
+		MatchPattern: `DNI ([\d.]+)`,
+		Until:        "2031-01-01T00:00:00Z",
+	})
+
+	require.NoError(t, err)
+	require.Len(t, report.Items, 1)
+	assert.Equal(t, models.CalendarImportItemFailed, report.Items[0].Result)
+	assert.Equal(t, "database error", report.Items[0].Reason)
+	assert.Equal(t, "p2", report.Items[0].Appointment.PatientID)
+}
+
+func TestCalendarImport_InvalidRequest(t *testing.T) {
+	service, _, _, _ := setupTest()
+
+	tests := []*models.CalendarImportRequest{
+		{DoctorID: "doc1", MatchRule: models.CalendarImportMatchSummaryName},
+		{ClientID: "client123", DoctorID: "doc1", MatchRule: "PHONE"},
+		{ClientID: "client123", DoctorID: "doc1", MatchRule: models.CalendarImportMatchSummaryName, MatchPattern: "no-group"},
+		{ClientID: "client123", DoctorID: "doc1", MatchRule: models.CalendarImportMatchSummaryName, TimeZone: "Mars/Olympus"},
+		{ClientID: "client123", DoctorID: "doc1", MatchRule: models.CalendarImportMatchSummaryName, Calendar: "BEGIN:VEVENT\r\n"},
+	}
+	for _, tt := range tests {
+		_, err := service.Import(context.Background(), tt)
+		assert.ErrorIs(t, err, ErrInvalidImport)
+	}
+}
+
+func TestMatcher_AmbiguousSummary(t *testing.T) {
+	m, err := newMatcher(models.CalendarImportMatchSummaryName, "")
+	require.NoError(t, err)
+	twins := []*models.Patient{
+		{ID: "a", FirstName: "Ana", LastName: "Martínez"},
+		{ID: "b", FirstName: "ANA", LastName: "martinez"},
+	}
+
+	result := m.patient(ical.Event{Summary: "Control Ana Martinez"}, twins)
+
+	assert.Empty(t, result.id)
+	assert.Equal(t, "2 patients match", result.reason)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"go.uber.org/zap"
+)
+
+// Las fusiones cambian pacientes y turnos como cualquier otra operación, así
+// que dejan los mismos eventos: quien proyecta pacientes o turnos ve pasar el
+// turno al sobreviviente y desaparecer al fusionado, y al revertir lo ve
+// volver como un alta. El sobreviviente y los turnos se escriben condicionados
+// a su versión: si alguien los editó durante la fusión se corta con
+// ErrMergeConflict en vez de pisar ese cambio.
+
+func (d *Duplicates) savePatient(ctx context.Context, patient *models.Patient, eventType string) error {
+	event, err := events.New(eventType, events.AggregatePatient, patient.ID, patient.ClientID, patient)
+	if err != nil {
+		d.log(ctx).Error("Error building patient event", zap.String("type", eventType), zap.Error(err))
+		return err
+	}
+	return d.PatientsRepository.SaveWithEvents(ctx, patient, []*events.Event{event})
+}
+
+func (d *Duplicates) deletePatient(ctx context.Context, patient *models.Patient) error {
+	event, err := events.New(events.PatientDeleted, events.AggregatePatient, patient.ID, patient.ClientID, patient)
+	if err != nil {
+		d.log(ctx).Error("Error building patient event", zap.String("type", events.PatientDeleted), zap.Error(err))
+		return err
+	}
+	return d.PatientsRepository.DeleteWithEvents(ctx, patient.ID, []*events.Event{event})
+}
+
+func (d *Duplicates) patchPatient(ctx context.Context, before, after *models.Patient) error {
+	event, err := events.New(events.PatientUpdated, events.AggregatePatient, after.ID, after.ClientID, after)
+	if err != nil {
+		d.log(ctx).Error("Error building patient event", zap.String("type", events.PatientUpdated), zap.Error(err))
+		return err
+	}
+	ok, err := d.PatientsRepository.Patch(ctx, before, after, []*events.Event{event})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: patient %s", ErrMergeConflict, after.ID)
+	}
+	return nil
+}
+
+func (d *Duplicates) patchAppointment(ctx context.Context, before, after *models.Appointment) error {
+	event, err := events.New(events.AppointmentUpdated, events.AggregateAppointment, after.ID, after.ClientID, after)
+	if err != nil {
+		d.log(ctx).Error("Error building appointment event", zap.String("type", events.AppointmentUpdated), zap.Error(err))
+		return err
+	}
+	ok, err := d.AppointmentsRepository.Patch(ctx, before, after, []*events.Event{event})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: appointment %s", ErrMergeConflict, after.ID)
+	}
+	return nil
+}
@@ -0,0 +1,526 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/documents"
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/textnorm"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidMerge     = errors.New("invalid patient merge")
+	ErrPatientNotFound  = errors.New("patient not found")
+	ErrMergeNotFound    = errors.New("patient merge not found")
+	ErrMergeReverted    = errors.New("patient merge already reverted")
+	ErrDocumentConflict = errors.New("another patient already has the merged patient's document")
+	ErrMergeConflict    = errors.New("patient or appointment was modified during the merge")
+)
+
+const (
+	DefaultThreshold = 0.85
+
+	// metadataMergedFrom lista en el sobreviviente los pacientes fusionados
+	metadataMergedFrom = "merged_from"
+
+	weightName      = 0.5
+	weightBirthDate = 0.2
+	weightPhone     = 0.15
+	weightEmail     = 0.15
+	// phoneSuffix compara sólo los últimos dígitos para que "+54 9 11..." y
+	// "011 15..." del mismo número coincidan
+	phoneSuffix = 8
+)
+
+var nonDigits = regexp.MustCompile(`\D`)
+
+type PatientsRepository interface {
+	SaveWithEvents(ctx context.Context, p *models.Patient, evts []*events.Event) error
+	Patch(ctx context.Context, before, after *models.Patient, evts []*events.Event) (bool, error)
+	GetByID(ctx context.Context, id string) (*models.Patient, error)
+	GetByClientID(ctx context.Context, clientID string) ([]*models.Patient, error)
+	GetByDocument(ctx context.Context, docType, docNumber string) (*models.Patient, error)
+	DeleteWithEvents(ctx context.Context, id string, evts []*events.Event) error
+}
+
+type AppointmentsRepository interface {
+	Patch(ctx context.Context, before, after *models.Appointment, evts []*events.Event) (bool, error)
+	GetByID(ctx context.Context, id string) (*models.Appointment, error)
+	GetByPatientID(ctx context.Context, patientID string) ([]*models.Appointment, error)
+}
+
+type PatientMergesRepository interface {
+	Save(ctx context.Context, m *models.PatientMerge) error
+	GetByID(ctx context.Context, id string) (*models.PatientMerge, error)
+}
+
+type DuplicatesService interface {
+	FindDuplicates(ctx context.Context, clientID string, threshold float64) ([]*models.DuplicateCandidate, error)
+	MergePatients(context.Context, *models.PatientMergeRequest) (*models.PatientMergeRequest, error)
+	RevertMerge(ctx context.Context, id string) (*models.PatientMergeRequest, error)
+}
+
+type Duplicates struct {
+	Logger                  *zap.SugaredLogger
+	PatientsRepository      PatientsRepository
+	AppointmentsRepository  AppointmentsRepository
+	PatientMergesRepository PatientMergesRepository
+}
+
+func New(logger *zap.SugaredLogger, patients PatientsRepository, appointments AppointmentsRepository, merges PatientMergesRepository) DuplicatesService {
+	return &Duplicates{
+		Logger:                  logger,
+		PatientsRepository:      patients,
+		AppointmentsRepository:  appointments,
+		PatientMergesRepository: merges,
+	}
+}
+
+// FindDuplicates compara los pacientes del tenant y devuelve los pares con
+// puntaje mayor o igual a threshold, de mayor a menor. Sólo se comparan los
+// pares que comparten algún dato (inicio del nombre, fecha de nacimiento,
+// teléfono o email) para no hacer todas las combinaciones.
+func (d *Duplicates) FindDuplicates(ctx context.Context, clientID string, threshold float64) ([]*models.DuplicateCandidate, error) {
+	if clientID == "" {
+		return nil, fmt.Errorf("client-id cannot be empty")
+	}
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	patients, err := d.PatientsRepository.GetByClientID(ctx, clientID)
+	if err != nil {
+		d.log(ctx).Error("Error getting patients for duplicate detection", zap.String("clientID", clientID), zap.Error(err))
+		return nil, err
+	}
+
+	profiles := make([]profile, len(patients))
+	blocks := map[string][]int{}
+	for i, p := range patients {
+		profiles[i] = newProfile(p)
+		for _, key := range profiles[i].blockingKeys() {
+			blocks[key] = append(blocks[key], i)
+		}
+	}
+
+	compared := map[[2]int]bool{}
+	candidates := []*models.DuplicateCandidate{}
+	for _, block := range blocks {
+		for x := 0; x < len(block); x++ {
+			for y := x + 1; y < len(block); y++ {
+				pair := [2]int{block[x], block[y]}
+				if compared[pair] {
+					continue
+				}
+				compared[pair] = true
+				a, b := profiles[pair[0]], profiles[pair[1]]
+				score, reasons := compare(a, b)
+				if score < threshold {
+					continue
+				}
+				candidates = append(candidates, &models.DuplicateCandidate{
+					Patient:   mapPatientToRequest(a.patient),
+					Duplicate: mapPatientToRequest(b.patient),
+					Score:     score,
+					Reasons:   reasons,
+				})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Patient.ID+candidates[i].Duplicate.ID < candidates[j].Patient.ID+candidates[j].Duplicate.ID
+	})
+
+	d.log(ctx).Info("Duplicate detection finished", zap.String("clientID", clientID), zap.Int("candidates", len(candidates)))
+	return candidates, nil
+}
+
+// MergePatients pasa los turnos de MergedID a SurvivorID, completa los datos
+// vacíos del sobreviviente con los del otro y elimina a MergedID. Antes de
+// tocar nada se guarda el registro de auditoría con todo lo necesario para
+// RevertMerge.
+func (d *Duplicates) MergePatients(ctx context.Context, request *models.PatientMergeRequest) (*models.PatientMergeRequest, error) {
+	if request.ClientID == "" || request.SurvivorID == "" || request.MergedID == "" {
+		return nil, fmt.Errorf("%w: client_id, survivor_id and merged_id are required", ErrInvalidMerge)
+	}
+	if request.SurvivorID == request.MergedID {
+		return nil, fmt.Errorf("%w: survivor_id and merged_id must be different", ErrInvalidMerge)
+	}
+	survivor, err := d.tenantPatient(ctx, request.ClientID, request.SurvivorID)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := d.tenantPatient(ctx, request.ClientID, request.MergedID)
+	if err != nil {
+		return nil, err
+	}
+	// El sobreviviente se vuelve a guardar: si tiene un documento anterior al
+	// catálogo que no valida, hay que corregirlo antes de fusionar
+	if _, _, err := documents.Normalize(survivor.DocType, survivor.DocNumber); err != nil {
+		return nil, fmt.Errorf("%w: survivor %v", ErrInvalidMerge, err)
+	}
+	appointments, err := d.AppointmentsRepository.GetByPatientID(ctx, merged.ID)
+	if err != nil {
+		d.log(ctx).Error("Error getting appointments to merge", zap.String("patientID", merged.ID), zap.Error(err))
+		return nil, err
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	record := &models.PatientMerge{
+		ID:            uuid.NewString(),
+		ClientID:      request.ClientID,
+		SurvivorID:    survivor.ID,
+		MergedID:      merged.ID,
+		Status:        models.PatientMergeStatusMerged,
+		MergedPatient: merged,
+		CreatedAt:     now,
+	}
+	for _, a := range appointments {
+		if a.ClientID == request.ClientID {
+			record.AppointmentIDs = append(record.AppointmentIDs, a.ID)
+		}
+	}
+	before := clonePatient(survivor)
+	record.FilledFields = fillBlanks(survivor, merged)
+	if err := d.PatientMergesRepository.Save(ctx, record); err != nil {
+		d.log(ctx).Error("Error on PatientMergesRepository.Save", zap.Error(err))
+		return nil, err
+	}
+
+	if err := d.repoint(ctx, appointments, request.ClientID, merged.ID, survivor.ID); err != nil {
+		return nil, err
+	}
+	if survivor.Metadata == nil {
+		survivor.Metadata = map[string]interface{}{}
+	}
+	survivor.Metadata[metadataMergedFrom] = appendID(survivor.Metadata[metadataMergedFrom], merged.ID)
+	survivor.UpdatedAt = now
+	survivor.Version++
+	if err := d.patchPatient(ctx, before, survivor); err != nil {
+		d.log(ctx).Error("Error saving merge survivor", zap.String("id", survivor.ID), zap.Error(err))
+		return nil, err
+	}
+	if err := d.deletePatient(ctx, merged); err != nil {
+		d.log(ctx).Error("Error deleting merged patient", zap.String("id", merged.ID), zap.Error(err))
+		return nil, err
+	}
+
+	d.log(ctx).Info("Patients merged",
+		zap.String("mergeID", record.ID),
+		zap.String("survivorID", survivor.ID),
+		zap.String("mergedID", merged.ID),
+		zap.Int("appointments", len(record.AppointmentIDs)))
+	return mapMergeToRequest(record), nil
+}
+
+// RevertMerge vuelve a crear al paciente eliminado y le devuelve sus turnos.
+// Los datos del sobreviviente que se completaron en la fusión se vacían sólo
+// si nadie los cambió después.
+func (d *Duplicates) RevertMerge(ctx context.Context, id string) (*models.PatientMergeRequest, error) {
+	record, err := d.PatientMergesRepository.GetByID(ctx, id)
+	if err != nil {
+		d.log(ctx).Error("Error on PatientMergesRepository.GetByID", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrMergeNotFound
+	}
+	if record.Status == models.PatientMergeStatusReverted {
+		return nil, ErrMergeReverted
+	}
+	merged := record.MergedPatient
+	if other, err := d.PatientsRepository.GetByDocument(ctx, merged.DocType, merged.DocNumber); err != nil {
+		d.log(ctx).Error("Error checking merged patient document", zap.Error(err))
+		return nil, err
+	} else if other != nil && other.ID != merged.ID {
+		return nil, ErrDocumentConflict
+	}
+
+	if err := d.savePatient(ctx, merged, events.PatientRegistered); err != nil {
+		d.log(ctx).Error("Error restoring merged patient", zap.String("id", merged.ID), zap.Error(err))
+		return nil, err
+	}
+	for _, appointmentID := range record.AppointmentIDs {
+		a, err := d.AppointmentsRepository.GetByID(ctx, appointmentID)
+		if err != nil {
+			d.log(ctx).Error("Error getting merged appointment", zap.String("id", appointmentID), zap.Error(err))
+			return nil, err
+		}
+		// Si el turno se borró o se reasignó a mano después de la fusión, se deja
+		if a == nil || a.PatientID != record.SurvivorID {
+			continue
+		}
+		if err := d.repoint(ctx, []*models.Appointment{a}, record.ClientID, record.SurvivorID, merged.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	survivor, err := d.PatientsRepository.GetByID(ctx, record.SurvivorID)
+	if err != nil {
+		d.log(ctx).Error("Error getting merge survivor", zap.String("id", record.SurvivorID), zap.Error(err))
+		return nil, err
+	}
+	now := time.Now().Format(time.RFC3339)
+	if survivor != nil {
+		before := clonePatient(survivor)
+		clearFilled(survivor, merged, record.FilledFields)
+		if survivor.Metadata != nil {
+			if ids := removeID(survivor.Metadata[metadataMergedFrom], merged.ID); len(ids) > 0 {
+				survivor.Metadata[metadataMergedFrom] = ids
+			} else {
+				delete(survivor.Metadata, metadataMergedFrom)
+			}
+		}
+		survivor.UpdatedAt = now
+		survivor.Version++
+		if err := d.patchPatient(ctx, before, survivor); err != nil {
+			d.log(ctx).Error("Error saving merge survivor", zap.String("id", survivor.ID), zap.Error(err))
+			return nil, err
+		}
+	}
+
+	record.Status = models.PatientMergeStatusReverted
+	record.RevertedAt = now
+	if err := d.PatientMergesRepository.Save(ctx, record); err != nil {
+		d.log(ctx).Error("Error on PatientMergesRepository.Save", zap.Error(err))
+		return nil, err
+	}
+
+	d.log(ctx).Info("Patient merge reverted", zap.String("mergeID", record.ID))
+	return mapMergeToRequest(record), nil
+}
+
+func (d *Duplicates) tenantPatient(ctx context.Context, clientID, id string) (*models.Patient, error) {
+	patient, err := d.PatientsRepository.GetByID(ctx, id)
+	if err != nil {
+		d.log(ctx).Error("Error on PatientsRepository.GetByID", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	if patient == nil || patient.ClientID != clientID {
+		return nil, fmt.Errorf("%w: %s", ErrPatientNotFound, id)
+	}
+	return patient, nil
+}
+
+func (d *Duplicates) repoint(ctx context.Context, appointments []*models.Appointment, clientID, from, to string) error {
+	now := time.Now().Format(time.RFC3339)
+	for _, a := range appointments {
+		if a.ClientID != clientID || a.PatientID != from {
+			continue
+		}
+		updated := *a
+		updated.PatientID = to
+		updated.UpdatedAt = now
+		updated.Sequence++
+		if err := d.patchAppointment(ctx, a, &updated); err != nil {
+			d.log(ctx).Error("Error re-pointing appointment", zap.String("id", a.ID), zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeableFields son los datos que se copian al sobreviviente cuando los
+// tiene vacíos. El documento no: es la identidad del paciente.
+var mergeableFields = map[string]func(*models.Patient) *string{
+	"birth_date":      func(p *models.Patient) *string { return &p.BirthDate },
+	"gender":          func(p *models.Patient) *string { return &p.Gender },
+	"country_code":    func(p *models.Patient) *string { return &p.CountryCode },
+	"phone_number":    func(p *models.Patient) *string { return &p.PhoneNumber },
+	"email":           func(p *models.Patient) *string { return &p.Email },
+	"address_street":  func(p *models.Patient) *string { return &p.AddressStreet },
+	"address_number":  func(p *models.Patient) *string { return &p.AddressNumber },
+	"address_city":    func(p *models.Patient) *string { return &p.AddressCity },
+	"address_country": func(p *models.Patient) *string { return &p.AddressCountry },
+	"zip_code":        func(p *models.Patient) *string { return &p.ZipCode },
+}
+
+func fillBlanks(survivor, merged *models.Patient) []string {
+	var filled []string
+	for name, field := range mergeableFields {
+		if *field(survivor) == "" && *field(merged) != "" {
+			*field(survivor) = *field(merged)
+			filled = append(filled, name)
+		}
+	}
+	sort.Strings(filled)
+	return filled
+}
+
+func clearFilled(survivor, merged *models.Patient, filled []string) {
+	for _, name := range filled {
+		field, ok := mergeableFields[name]
+		if ok && *field(survivor) == *field(merged) {
+			*field(survivor) = ""
+		}
+	}
+}
+
+// appendID y removeID manejan la lista de metadata, que vuelve de DynamoDB
+// como []interface{}.
+// clonePatient copia al paciente antes de modificarlo para poder condicionar
+// la escritura a que nadie lo haya cambiado. Metadata es lo único mutable que
+// la fusión toca por referencia.
+func clonePatient(p *models.Patient) *models.Patient {
+	clone := *p
+	if p.Metadata != nil {
+		clone.Metadata = make(map[string]interface{}, len(p.Metadata))
+		for k, v := range p.Metadata {
+			clone.Metadata[k] = v
+		}
+	}
+	return &clone
+}
+
+func appendID(list interface{}, id string) []string {
+	return append(removeID(list, id), id)
+}
+
+func removeID(list interface{}, id string) []string {
+	var ids []string
+	switch values := list.(type) {
+	case []string:
+		ids = values
+	case []interface{}:
+		for _, v := range values {
+			if s, ok := v.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+	}
+	result := []string{}
+	for _, v := range ids {
+		if v != id {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// profile tiene los datos del paciente ya normalizados para comparar.
+type profile struct {
+	patient   *models.Patient
+	name      string
+	reversed  string
+	birthDate string
+	phone     string
+	email     string
+}
+
+func newProfile(p *models.Patient) profile {
+	phone := nonDigits.ReplaceAllString(p.PhoneNumber, "")
+	if len(phone) > phoneSuffix {
+		phone = phone[len(phone)-phoneSuffix:]
+	}
+	return profile{
+		patient:   p,
+		name:      textnorm.Key(p.FirstName + " " + p.LastName),
+		reversed:  textnorm.Key(p.LastName + " " + p.FirstName),
+		birthDate: strings.TrimSpace(p.BirthDate),
+		phone:     phone,
+		email:     strings.ToLower(strings.TrimSpace(p.Email)),
+	}
+}
+
+func (p profile) blockingKeys() []string {
+	var keys []string
+	for _, name := range []string{p.name, p.reversed} {
+		if prefix := []rune(name); len(prefix) >= 2 {
+			keys = append(keys, "n:"+string(prefix[:2]))
+		}
+	}
+	if p.birthDate != "" {
+		keys = append(keys, "b:"+p.birthDate)
+	}
+	if p.phone != "" {
+		keys = append(keys, "p:"+p.phone)
+	}
+	if p.email != "" {
+		keys = append(keys, "e:"+p.email)
+	}
+	return keys
+}
+
+// compare pondera sólo los datos que tienen los dos pacientes: que a uno le
+// falte el email no lo hace menos parecido.
+func compare(a, b profile) (float64, []string) {
+	var reasons []string
+	name := max(textnorm.JaroWinkler(a.name, b.name), textnorm.JaroWinkler(a.name, b.reversed))
+	score, total := name*weightName, weightName
+	if name >= 0.9 {
+		reasons = append(reasons, "name")
+	}
+	for _, field := range []struct {
+		reason string
+		a, b   string
+		weight float64
+	}{
+		{"birth_date", a.birthDate, b.birthDate, weightBirthDate},
+		{"phone", a.phone, b.phone, weightPhone},
+		{"email", a.email, b.email, weightEmail},
+	} {
+		if field.a == "" || field.b == "" {
+			continue
+		}
+		total += field.weight
+		if field.a == field.b {
+			score += field.weight
+			reasons = append(reasons, field.reason)
+		}
+	}
+	return score / total, reasons
+}
+
+func mapMergeToRequest(m *models.PatientMerge) *models.PatientMergeRequest {
+	return &models.PatientMergeRequest{
+		ID:           m.ID,
+		ClientID:     m.ClientID,
+		SurvivorID:   m.SurvivorID,
+		MergedID:     m.MergedID,
+		Status:       m.Status,
+		Appointments: m.AppointmentIDs,
+		CreatedAt:    m.CreatedAt,
+		RevertedAt:   m.RevertedAt,
+	}
+}
+
+func mapPatientToRequest(patient *models.Patient) *models.PatientRequest {
+	return &models.PatientRequest{
+		ClientID:       patient.ClientID,
+		ID:             patient.ID,
+		FirstName:      patient.FirstName,
+		LastName:       patient.LastName,
+		DocType:        patient.DocType,
+		DocNumber:      patient.DocNumber,
+		BirthDate:      patient.BirthDate,
+		Gender:         patient.Gender,
+		CountryCode:    patient.CountryCode,
+		PhoneNumber:    patient.PhoneNumber,
+		Email:          patient.Email,
+		AddressStreet:  patient.AddressStreet,
+		AddressNumber:  patient.AddressNumber,
+		AddressCity:    patient.AddressCity,
+		AddressCountry: patient.AddressCountry,
+		ZipCode:        patient.ZipCode,
+		CreatedAt:      patient.CreatedAt,
+		UpdatedAt:      patient.UpdatedAt,
+		Metadata:       patient.Metadata,
+	}
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del servicio.
+func (d *Duplicates) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, d.Logger)
+}
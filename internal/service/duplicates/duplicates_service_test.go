@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockPatientsRepository struct {
+	mock.Mock
+}
+
+func (m *MockPatientsRepository) SaveWithEvents(ctx context.Context, p *models.Patient, evts []*events.Event) error {
+	args := m.Called(ctx, p, evts)
+	return args.Error(0)
+}
+
+func (m *MockPatientsRepository) Patch(ctx context.Context, before, after *models.Patient, evts []*events.Event) (bool, error) {
+	args := m.Called(ctx, before, after, evts)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPatientsRepository) GetByID(ctx context.Context, id string) (*models.Patient, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Patient), args.Error(1)
+}
+
+func (m *MockPatientsRepository) GetByClientID(ctx context.Context, clientID string) ([]*models.Patient, error) {
+	args := m.Called(ctx, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Patient), args.Error(1)
+}
+
+func (m *MockPatientsRepository) GetByDocument(ctx context.Context, docType, docNumber string) (*models.Patient, error) {
+	args := m.Called(ctx, docType, docNumber)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Patient), args.Error(1)
+}
+
+func (m *MockPatientsRepository) DeleteWithEvents(ctx context.Context, id string, evts []*events.Event) error {
+	args := m.Called(ctx, id, evts)
+	return args.Error(0)
+}
+
+type MockAppointmentsRepository struct {
+	mock.Mock
+}
+
+func (m *MockAppointmentsRepository) Patch(ctx context.Context, before, after *models.Appointment, evts []*events.Event) (bool, error) {
+	args := m.Called(ctx, before, after, evts)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAppointmentsRepository) GetByID(ctx context.Context, id string) (*models.Appointment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Appointment), args.Error(1)
+}
+
+func (m *MockAppointmentsRepository) GetByPatientID(ctx context.Context, patientID string) ([]*models.Appointment, error) {
+	args := m.Called(ctx, patientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Appointment), args.Error(1)
+}
+
+type MockPatientMergesRepository struct {
+	mock.Mock
+}
+
+func (m *MockPatientMergesRepository) Save(ctx context.Context, merge *models.PatientMerge) error {
+	args := m.Called(ctx, merge)
+	return args.Error(0)
+}
+
+func (m *MockPatientMergesRepository) GetByID(ctx context.Context, id string) (*models.PatientMerge, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PatientMerge), args.Error(1)
+}
+
+// eventOfType acepta un único evento del tipo dado sobre el agregado id.
+func eventOfType(eventType, id string) interface{} {
+	return mock.MatchedBy(func(evts []*events.Event) bool {
+		return len(evts) == 1 && evts[0].Type == eventType && evts[0].AggregateID == id
+	})
+}
+
+func setupTest() (*Duplicates, *MockPatientsRepository, *MockAppointmentsRepository, *MockPatientMergesRepository) {
+	patientsRepo := new(MockPatientsRepository)
+	appointmentsRepo := new(MockAppointmentsRepository)
+	mergesRepo := new(MockPatientMergesRepository)
+	logger, _ := zap.NewDevelopment()
+	service := &Duplicates{
+		Logger:                  logger.Sugar(),
+		PatientsRepository:      patientsRepo,
+		AppointmentsRepository:  appointmentsRepo,
+		PatientMergesRepository: mergesRepo,
+	}
+	return service, patientsRepo, appointmentsRepo, mergesRepo
+}
+
+func TestFindDuplicates(t *testing.T) {
+	service, patientsRepo, _, _ := setupTest()
+	ctx := context.Background()
+	patientsRepo.On("GetByClientID", ctx, "client123").Return([]*models.Patient{
+		{ID: "p1", FirstName: "María", LastName: "Gonzalez", BirthDate: "1980-05-01", PhoneNumber: "+54 9 11 5555-1234"},
+		{ID: "p2", FirstName: "MARIA", LastName: "Gonzales", BirthDate: "1980-05-01", PhoneNumber: "011 15 5555 1234"},
+		{ID: "p3", FirstName: "Gonzalez", LastName: "Maria", Email: "mg@example.com"},
+		{ID: "p4", FirstName: "Pedro", LastName: "Gómez", BirthDate: "1980-05-01"},
+	}, nil)
+
+	candidates, err := service.FindDuplicates(ctx, "client123", 0)
+
+	require.NoError(t, err)
+	require.Len(t, candidates, 3)
+	pairs := map[string]*models.DuplicateCandidate{}
+	for i, c := range candidates {
+		pairs[c.Patient.ID+"-"+c.Duplicate.ID] = c
+		if i > 0 {
+			assert.LessOrEqual(t, c.Score, candidates[i-1].Score)
+		}
+	}
+	require.Contains(t, pairs, "p1-p2")
+	assert.Equal(t, []string{"name", "birth_date", "phone"}, pairs["p1-p2"].Reasons)
+	assert.Greater(t, pairs["p1-p2"].Score, 0.95)
+	// Nombre y apellido invertidos y sin otros datos en común
+	assert.Contains(t, pairs, "p1-p3")
+	assert.Contains(t, pairs, "p2-p3")
+}
+
+func TestMergePatients(t *testing.T) {
+	service, patientsRepo, appointmentsRepo, mergesRepo := setupTest()
+	ctx := context.Background()
+	survivor := &models.Patient{ID: "p1", ClientID: "client123", FirstName: "Ana", DocType: "DNI", DocNumber: "30123456"}
+	merged := &models.Patient{ID: "p2", ClientID: "client123", FirstName: "Ana", DocType: "DNI", DocNumber: "30123465", Email: "ana@example.com"}
+	patientsRepo.On("GetByID", ctx, "p1").Return(survivor, nil)
+	patientsRepo.On("GetByID", ctx, "p2").Return(merged, nil)
+	appointmentsRepo.On("GetByPatientID", ctx, "p2").Return([]*models.Appointment{
+		{ID: "a1", ClientID: "client123", PatientID: "p2"},
+		{ID: "a2", ClientID: "other", PatientID: "p2"},
+	}, nil)
+	var record *models.PatientMerge
+	mergesRepo.On("Save", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		record = args.Get(1).(*models.PatientMerge)
+	}).Return(nil)
+	appointmentsRepo.On("Patch", ctx, mock.MatchedBy(func(a *models.Appointment) bool {
+		return a.ID == "a1" && a.PatientID == "p2" && a.Sequence == 0
+	}), mock.MatchedBy(func(a *models.Appointment) bool {
+		return a.ID == "a1" && a.PatientID == "p1" && a.Sequence == 1
+	}), eventOfType(events.AppointmentUpdated, "a1")).Return(true, nil).Once()
+	patientsRepo.On("Patch", ctx, mock.MatchedBy(func(p *models.Patient) bool {
+		return p.ID == "p1" && p.Email == "" && p.Version == 0 && p.Metadata == nil
+	}), mock.MatchedBy(func(p *models.Patient) bool {
+		return p.ID == "p1" && p.Email == "ana@example.com" && p.Version == 1
+	}), eventOfType(events.PatientUpdated, "p1")).Return(true, nil)
+	patientsRepo.On("DeleteWithEvents", ctx, "p2", eventOfType(events.PatientDeleted, "p2")).Return(nil)
+
+	result, err := service.MergePatients(ctx, &models.PatientMergeRequest{ClientID: "client123", SurvivorID: "p1", MergedID: "p2"})
+
+	require.NoError(t, err)
+	assert.Equal(t, models.PatientMergeStatusMerged, result.Status)
+	assert.Equal(t, []string{"a1"}, result.Appointments)
+	assert.Equal(t, []string{"email"}, record.FilledFields)
+	assert.Equal(t, "30123465", record.MergedPatient.DocNumber)
+	assert.Equal(t, []string{"p2"}, survivor.Metadata[metadataMergedFrom])
+	appointmentsRepo.AssertExpectations(t)
+	patientsRepo.AssertExpectations(t)
+}
+
+func TestMergePatients_OtherTenant(t *testing.T) {
+	service, patientsRepo, _, mergesRepo := setupTest()
+	ctx := context.Background()
+	patientsRepo.On("GetByID", ctx, "p1").Return(&models.Patient{ID: "p1", ClientID: "client123"}, nil)
+	patientsRepo.On("GetByID", ctx, "p2").Return(&models.Patient{ID: "p2", ClientID: "other"}, nil)
+
+	_, err := service.MergePatients(ctx, &models.PatientMergeRequest{ClientID: "client123", SurvivorID: "p1", MergedID: "p2"})
+
+	assert.ErrorIs(t, err, ErrPatientNotFound)
+	mergesRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+
+	_, err = service.MergePatients(ctx, &models.PatientMergeRequest{ClientID: "client123", SurvivorID: "p1", MergedID: "p1"})
+	assert.ErrorIs(t, err, ErrInvalidMerge)
+}
+
+func TestMergePatients_Conflict(t *testing.T) {
+	service, patientsRepo, appointmentsRepo, mergesRepo := setupTest()
+	ctx := context.Background()
+	patientsRepo.On("GetByID", ctx, "p1").Return(&models.Patient{ID: "p1", ClientID: "client123", DocType: "DNI", DocNumber: "30123456"}, nil)
+	patientsRepo.On("GetByID", ctx, "p2").Return(&models.Patient{ID: "p2", ClientID: "client123"}, nil)
+	appointmentsRepo.On("GetByPatientID", ctx, "p2").Return([]*models.Appointment{
+		{ID: "a1", ClientID: "client123", PatientID: "p2"},
+	}, nil)
+	mergesRepo.On("Save", ctx, mock.Anything).Return(nil)
+	appointmentsRepo.On("Patch", ctx, mock.Anything, mock.Anything, mock.Anything).Return(false, nil).Once()
+
+	_, err := service.MergePatients(ctx, &models.PatientMergeRequest{ClientID: "client123", SurvivorID: "p1", MergedID: "p2"})
+
+	assert.ErrorIs(t, err, ErrMergeConflict)
+	patientsRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	patientsRepo.AssertNotCalled(t, "DeleteWithEvents", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRevertMerge(t *testing.T) {
+	service, patientsRepo, appointmentsRepo, mergesRepo := setupTest()
+	ctx := context.Background()
+	merged := &models.Patient{ID: "p2", ClientID: "client123", DocType: "DNI", DocNumber: "30123465", Email: "ana@example.com"}
+	record := &models.PatientMerge{
+		ID: "m1", ClientID: "client123", SurvivorID: "p1", MergedID: "p2", Status: models.PatientMergeStatusMerged,
+		MergedPatient: merged, AppointmentIDs: []string{"a1", "a2"}, FilledFields: []string{"email", "phone_number"},
+	}
+	survivor := &models.Patient{
+		ID: "p1", ClientID: "client123", Email: "ana@example.com", PhoneNumber: "1122334455",
+		Metadata: map[string]interface{}{metadataMergedFrom: []interface{}{"p2"}},
+	}
+	mergesRepo.On("GetByID", ctx, "m1").Return(record, nil)
+	patientsRepo.On("GetByDocument", ctx, "DNI", "30123465").Return(nil, nil)
+	patientsRepo.On("SaveWithEvents", ctx, merged, eventOfType(events.PatientRegistered, "p2")).Return(nil)
+	appointmentsRepo.On("GetByID", ctx, "a1").Return(&models.Appointment{ID: "a1", ClientID: "client123", PatientID: "p1"}, nil)
+	appointmentsRepo.On("GetByID", ctx, "a2").Return(&models.Appointment{ID: "a2", ClientID: "client123", PatientID: "p9"}, nil)
+	appointmentsRepo.On("Patch", ctx, mock.Anything, mock.MatchedBy(func(a *models.Appointment) bool {
+		return a.ID == "a1" && a.PatientID == "p2"
+	}), eventOfType(events.AppointmentUpdated, "a1")).Return(true, nil).Once()
+	patientsRepo.On("GetByID", ctx, "p1").Return(survivor, nil)
+	patientsRepo.On("Patch", ctx, mock.MatchedBy(func(p *models.Patient) bool {
+		return p.Email == "ana@example.com" && p.Metadata[metadataMergedFrom] != nil
+	}), survivor, eventOfType(events.PatientUpdated, "p1")).Return(true, nil)
+	mergesRepo.On("Save", ctx, record).Return(nil)
+
+	result, err := service.RevertMerge(ctx, "m1")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.PatientMergeStatusReverted, result.Status)
+	assert.NotEmpty(t, result.RevertedAt)
+	assert.Empty(t, survivor.Email)
+	// El teléfono se cambió después de la fusión: se conserva
+	assert.Equal(t, "1122334455", survivor.PhoneNumber)
+	assert.NotContains(t, survivor.Metadata, metadataMergedFrom)
+	appointmentsRepo.AssertExpectations(t)
+	patientsRepo.AssertExpectations(t)
+
+	record.Status = models.PatientMergeStatusReverted
+	_, err = service.RevertMerge(ctx, "m1")
+	assert.ErrorIs(t, err, ErrMergeReverted)
+}
+
+func TestRevertMerge_DocumentTaken(t *testing.T) {
+	service, patientsRepo, _, mergesRepo := setupTest()
+	ctx := context.Background()
+	mergesRepo.On("GetByID", ctx, "m1").Return(&models.PatientMerge{
+		ID: "m1", Status: models.PatientMergeStatusMerged,
+		MergedPatient: &models.Patient{ID: "p2", DocType: "DNI", DocNumber: "1"},
+	}, nil)
+	mergesRepo.On("GetByID", ctx, "missing").Return(nil, nil)
+	patientsRepo.On("GetByDocument", ctx, "DNI", "1").Return(&models.Patient{ID: "p7"}, nil)
+
+	_, err := service.RevertMerge(ctx, "m1")
+	assert.ErrorIs(t, err, ErrDocumentConflict)
+	patientsRepo.AssertNotCalled(t, "SaveWithEvents", mock.Anything, mock.Anything, mock.Anything)
+
+	_, err = service.RevertMerge(ctx, "missing")
+	assert.ErrorIs(t, err, ErrMergeNotFound)
+}
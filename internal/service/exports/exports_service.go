@@ -0,0 +1,464 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/blobstore"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidExport  = errors.New("invalid export request")
+	ErrExportNotFound = errors.New("export not found")
+	// ErrExportRunning indica que otro worker tiene la exportación tomada y
+	// su lease no venció: el reintento la vuelve a mirar más tarde.
+	ErrExportRunning = errors.New("export is running in another worker")
+)
+
+const (
+	defaultURLTTL   = 15 * time.Minute
+	defaultPageSize = 500
+	// leaseDuration es el timeout máximo de Lambda: un lease vencido
+	// significa que el worker que la tenía ya no está corriendo.
+	leaseDuration = 15 * time.Minute
+	// maxAttempts corta las exportaciones que agotan el lease una y otra vez
+	maxAttempts = 3
+)
+
+type ExportsRepository interface {
+	Save(ctx context.Context, job *models.ExportJob) error
+	GetByID(ctx context.Context, id string) (*models.ExportJob, error)
+	Claim(ctx context.Context, id, key string, now, leaseUntil int64) (bool, error)
+	Finish(ctx context.Context, job *models.ExportJob, leaseUntil int64) (bool, error)
+}
+
+type PatientsRepository interface {
+	GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Patient, string, error)
+}
+
+type AppointmentsRepository interface {
+	GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Appointment, string, error)
+}
+
+type ExportsService interface {
+	CreateExport(context.Context, *models.ExportRequest) (*models.ExportRequest, error)
+	GetExport(ctx context.Context, id string) (*models.ExportRequest, error)
+	RunExport(ctx context.Context, id string) error
+}
+
+type Export struct {
+	Logger                 *zap.SugaredLogger
+	ExportsRepository      ExportsRepository
+	PatientsRepository     PatientsRepository
+	AppointmentsRepository AppointmentsRepository
+	Store                  blobstore.Store
+	URLTTL                 time.Duration
+	PageSize               int32
+	Now                    func() time.Time
+}
+
+func New(logger *zap.SugaredLogger, exports ExportsRepository, patients PatientsRepository, appointments AppointmentsRepository, store blobstore.Store, urlTTL time.Duration) ExportsService {
+	return &Export{
+		Logger:                 logger,
+		ExportsRepository:      exports,
+		PatientsRepository:     patients,
+		AppointmentsRepository: appointments,
+		Store:                  store,
+		URLTTL:                 urlTTL,
+		PageSize:               defaultPageSize,
+	}
+}
+
+// CreateExport registra la exportación como PENDING. El archivo se genera
+// después, en RunExport, para no depender del timeout de API Gateway.
+func (e *Export) CreateExport(ctx context.Context, request *models.ExportRequest) (*models.ExportRequest, error) {
+	if request.ClientID == "" {
+		return nil, fmt.Errorf("%w: client_id is required", ErrInvalidExport)
+	}
+	if request.Format == "" {
+		request.Format = models.ExportFormatCSV
+	}
+	if _, ok := columns[request.Resource]; !ok {
+		return nil, fmt.Errorf("%w: invalid resource value: %s. Must be one of: %s, %s", ErrInvalidExport, request.Resource,
+			models.ExportResourcePatients, models.ExportResourceAppointments)
+	}
+	if request.Format != models.ExportFormatCSV && request.Format != models.ExportFormatNDJSON {
+		return nil, fmt.Errorf("%w: invalid format value: %s. Must be one of: %s, %s", ErrInvalidExport, request.Format,
+			models.ExportFormatCSV, models.ExportFormatNDJSON)
+	}
+
+	job := &models.ExportJob{
+		ID:        uuid.New().String(),
+		ClientID:  request.ClientID,
+		Resource:  request.Resource,
+		Format:    request.Format,
+		Status:    models.ExportStatusPending,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	if err := e.ExportsRepository.Save(ctx, job); err != nil {
+		e.log(ctx).Error("Error on ExportsRepository.Save", zap.Error(err))
+		return nil, err
+	}
+	return mapJobToRequest(job), nil
+}
+
+// GetExport devuelve el estado de la exportación y, si terminó, un enlace de
+// descarga nuevo que vence a los URLTTL.
+func (e *Export) GetExport(ctx context.Context, id string) (*models.ExportRequest, error) {
+	job, err := e.ExportsRepository.GetByID(ctx, id)
+	if err != nil {
+		e.log(ctx).Error("Error on ExportsRepository.GetByID", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrExportNotFound
+	}
+	response := mapJobToRequest(job)
+	if job.Status == models.ExportStatusCompleted {
+		ttl := e.urlTTL()
+		url, err := e.Store.URL(ctx, job.Key, ttl)
+		if err != nil {
+			e.log(ctx).Error("Error signing export download URL", zap.String("id", id), zap.Error(err))
+			return nil, err
+		}
+		response.DownloadURL = url
+		response.DownloadExpiresAt = time.Now().Add(ttl).Format(time.RFC3339)
+	}
+	return response, nil
+}
+
+// RunExport genera el archivo recorriendo los registros del cliente de a
+// una página por vez, sin cargarlos todos en memoria. Antes la toma con un
+// lease condicional: si otro worker la tiene y el lease sigue vigente
+// devuelve ErrExportRunning; si el lease venció la retoma, hasta maxAttempts
+// intentos, y después la marca FAILED. Si ya terminó no hace nada, así los
+// reintentos no la repiten.
+func (e *Export) RunExport(ctx context.Context, id string) error {
+	job, err := e.ExportsRepository.GetByID(ctx, id)
+	if err != nil {
+		e.log(ctx).Error("Error on ExportsRepository.GetByID", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	if job == nil {
+		return ErrExportNotFound
+	}
+	now := e.now()
+	switch {
+	case job.Status == models.ExportStatusRunning && job.LeaseUntil >= now.Unix():
+		e.log(ctx).Info("Export running in another worker", zap.String("id", id))
+		return ErrExportRunning
+	case job.Status == models.ExportStatusRunning && job.Attempts >= maxAttempts:
+		return e.abandon(ctx, job, now)
+	case job.Status != models.ExportStatusPending && job.Status != models.ExportStatusRunning:
+		e.log(ctx).Info("Export already processed", zap.String("id", id), zap.String("status", job.Status))
+		return nil
+	}
+
+	key := fmt.Sprintf("exports/%s/%s.%s", job.ClientID, job.ID, job.Format)
+	lease := now.Add(leaseDuration).Unix()
+	claimed, err := e.ExportsRepository.Claim(ctx, id, key, now.Unix(), lease)
+	if err != nil {
+		e.log(ctx).Error("Error on ExportsRepository.Claim", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	if !claimed {
+		e.log(ctx).Info("Export claimed by another worker", zap.String("id", id))
+		return ErrExportRunning
+	}
+	if job.Status == models.ExportStatusRunning {
+		e.log(ctx).Warn("Taking over export with an expired lease", zap.String("id", id), zap.Int("attempt", job.Attempts+1))
+	}
+	job.Status, job.Key, job.LeaseUntil = models.ExportStatusRunning, key, lease
+	job.Attempts++
+
+	rows, err := e.write(ctx, job)
+	job.Rows = rows
+	job.CompletedAt = e.now().Format(time.RFC3339)
+	if err != nil {
+		e.log(ctx).Error("Error running export", zap.String("id", id), zap.Error(err))
+		job.Status = models.ExportStatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = models.ExportStatusCompleted
+	}
+	finished, saveErr := e.ExportsRepository.Finish(ctx, job, lease)
+	if saveErr != nil {
+		e.log(ctx).Error("Error on ExportsRepository.Finish", zap.Error(saveErr))
+		return saveErr
+	}
+	if !finished {
+		// Tardó más que el lease y otro worker la retomó: manda su resultado
+		e.log(ctx).Warn("Export lease lost before finishing", zap.String("id", id))
+		return nil
+	}
+
+	e.log(ctx).Info("Export finished",
+		zap.String("id", id),
+		zap.String("status", job.Status),
+		zap.Int("rows", rows))
+	return nil
+}
+
+// abandon marca FAILED una exportación que agotó maxAttempts leases sin
+// terminar, condicionado al lease vencido que se leyó.
+func (e *Export) abandon(ctx context.Context, job *models.ExportJob, now time.Time) error {
+	lease := job.LeaseUntil
+	job.Status = models.ExportStatusFailed
+	job.Error = fmt.Sprintf("export did not finish after %d attempts", job.Attempts)
+	job.CompletedAt = now.Format(time.RFC3339)
+	if _, err := e.ExportsRepository.Finish(ctx, job, lease); err != nil {
+		e.log(ctx).Error("Error on ExportsRepository.Finish", zap.Error(err))
+		return err
+	}
+	e.log(ctx).Error("Export abandoned", zap.String("id", job.ID), zap.Int("attempts", job.Attempts))
+	return nil
+}
+
+func (e *Export) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
+
+func (e *Export) write(ctx context.Context, job *models.ExportJob) (int, error) {
+	contentType := "text/csv; charset=utf-8"
+	if job.Format == models.ExportFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	w, err := e.Store.Create(ctx, job.Key, contentType)
+	if err != nil {
+		return 0, err
+	}
+	enc := newEncoder(w, job.Format, columns[job.Resource])
+
+	rows, err := e.each(ctx, job, func(record map[string]interface{}) error {
+		return enc.encode(record)
+	})
+	if err == nil {
+		err = enc.flush()
+	}
+	if err != nil {
+		if abortErr := w.Abort(); abortErr != nil {
+			e.log(ctx).Error("Error aborting export upload", zap.String("id", job.ID), zap.Error(abortErr))
+		}
+		return rows, err
+	}
+	return rows, w.Close()
+}
+
+// each recorre las páginas del recurso pedido y llama a fn por registro.
+func (e *Export) each(ctx context.Context, job *models.ExportJob, fn func(map[string]interface{}) error) (int, error) {
+	pageSize := e.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	rows, cursor := 0, ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return rows, err
+		}
+		var (
+			records []map[string]interface{}
+			next    string
+			err     error
+		)
+		switch job.Resource {
+		case models.ExportResourcePatients:
+			var page []*models.Patient
+			page, next, err = e.PatientsRepository.GetPageByClientID(ctx, job.ClientID, cursor, pageSize)
+			for _, p := range page {
+				records = append(records, patientRecord(p))
+			}
+		case models.ExportResourceAppointments:
+			var page []*models.Appointment
+			page, next, err = e.AppointmentsRepository.GetPageByClientID(ctx, job.ClientID, cursor, pageSize)
+			for _, a := range page {
+				records = append(records, appointmentRecord(a))
+			}
+		}
+		if err != nil {
+			return rows, err
+		}
+		for _, record := range records {
+			if err := fn(record); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+		if next == "" {
+			return rows, nil
+		}
+		cursor = next
+	}
+}
+
+func (e *Export) urlTTL() time.Duration {
+	if e.URLTTL > 0 {
+		return e.URLTTL
+	}
+	return defaultURLTTL
+}
+
+func mapJobToRequest(job *models.ExportJob) *models.ExportRequest {
+	return &models.ExportRequest{
+		ID:          job.ID,
+		ClientID:    job.ClientID,
+		Resource:    job.Resource,
+		Format:      job.Format,
+		Status:      job.Status,
+		Rows:        job.Rows,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		CompletedAt: job.CompletedAt,
+	}
+}
+
+// columns define, por recurso, el orden de las columnas del CSV.
+var columns = map[string][]string{
+	models.ExportResourcePatients: {
+		"id", "first_name", "last_name", "doc_type", "doc_number", "birth_date", "gender",
+		"country_code", "phone_number", "email", "address_street", "address_number",
+		"address_city", "address_country", "zip_code", "metadata", "created_at", "updated_at",
+	},
+	models.ExportResourceAppointments: {
+		"id", "patient_id", "doctor_id", "date", "duration", "status", "notes",
+		"metadata", "created_at", "updated_at",
+	},
+}
+
+func patientRecord(p *models.Patient) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              p.ID,
+		"first_name":      p.FirstName,
+		"last_name":       p.LastName,
+		"doc_type":        p.DocType,
+		"doc_number":      p.DocNumber,
+		"birth_date":      p.BirthDate,
+		"gender":          p.Gender,
+		"country_code":    p.CountryCode,
+		"phone_number":    p.PhoneNumber,
+		"email":           p.Email,
+		"address_street":  p.AddressStreet,
+		"address_number":  p.AddressNumber,
+		"address_city":    p.AddressCity,
+		"address_country": p.AddressCountry,
+		"zip_code":        p.ZipCode,
+		"metadata":        p.Metadata,
+		"created_at":      p.CreatedAt,
+		"updated_at":      p.UpdatedAt,
+	}
+}
+
+func appointmentRecord(a *models.Appointment) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         a.ID,
+		"patient_id": a.PatientID,
+		"doctor_id":  a.DoctorID,
+		"date":       a.Date,
+		"duration":   a.Duration,
+		"status":     string(a.Status),
+		"notes":      a.Notes,
+		"metadata":   a.Metadata,
+		"created_at": a.CreatedAt,
+		"updated_at": a.UpdatedAt,
+	}
+}
+
+type encoder struct {
+	csv     *csv.Writer
+	json    *json.Encoder
+	columns []string
+	header  bool
+}
+
+func newEncoder(w io.Writer, format string, columns []string) *encoder {
+	if format == models.ExportFormatNDJSON {
+		return &encoder{json: json.NewEncoder(w)}
+	}
+	return &encoder{csv: csv.NewWriter(w), columns: columns}
+}
+
+func (e *encoder) encode(record map[string]interface{}) error {
+	if e.json != nil {
+		return e.json.Encode(record)
+	}
+	if !e.header {
+		e.header = true
+		if err := e.csv.Write(e.columns); err != nil {
+			return err
+		}
+	}
+	values := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		values[i] = csvValue(record[column])
+	}
+	return e.csv.Write(values)
+}
+
+func (e *encoder) flush() error {
+	if e.csv == nil {
+		return nil
+	}
+	if !e.header {
+		// Un CSV vacío igual lleva encabezado
+		e.header = true
+		if err := e.csv.Write(e.columns); err != nil {
+			return err
+		}
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+func csvValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return escapeFormula(value)
+	case int:
+		return strconv.Itoa(value)
+	case map[string]interface{}:
+		if len(value) == 0 {
+			return ""
+		}
+		b, _ := json.Marshal(value)
+		return escapeFormula(string(b))
+	default:
+		return escapeFormula(fmt.Sprint(value))
+	}
+}
+
+// escapeFormula evita que Excel interprete el valor como fórmula. Los
+// teléfonos (+54...) y números negativos se dejan como están.
+func escapeFormula(s string) string {
+	if s == "" {
+		return s
+	}
+	switch s[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + s
+	case '+', '-':
+		if len(s) > 1 && (s[1] >= '0' && s[1] <= '9' || s[1] == ' ') {
+			return s
+		}
+		return "'" + s
+	}
+	return s
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del servicio.
+func (e *Export) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, e.Logger)
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/blobstore"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockExportsRepository struct {
+	mock.Mock
+	saved []models.ExportJob
+}
+
+func (m *MockExportsRepository) Save(ctx context.Context, job *models.ExportJob) error {
+	m.saved = append(m.saved, *job)
+	args := m.Called(ctx, job)
+	return args.Error(0)
+}
+
+func (m *MockExportsRepository) GetByID(ctx context.Context, id string) (*models.ExportJob, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExportJob), args.Error(1)
+}
+
+func (m *MockExportsRepository) Claim(ctx context.Context, id, key string, now, leaseUntil int64) (bool, error) {
+	args := m.Called(ctx, id, key, now, leaseUntil)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockExportsRepository) Finish(ctx context.Context, job *models.ExportJob, leaseUntil int64) (bool, error) {
+	m.saved = append(m.saved, *job)
+	args := m.Called(ctx, job, leaseUntil)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockPatientsRepository struct {
+	mock.Mock
+}
+
+func (m *MockPatientsRepository) GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Patient, string, error) {
+	args := m.Called(ctx, clientID, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).([]*models.Patient), args.String(1), args.Error(2)
+}
+
+type MockAppointmentsRepository struct {
+	mock.Mock
+}
+
+func (m *MockAppointmentsRepository) GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Appointment, string, error) {
+	args := m.Called(ctx, clientID, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).([]*models.Appointment), args.String(1), args.Error(2)
+}
+
+var (
+	now   = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	lease = now.Add(leaseDuration).Unix()
+)
+
+func setupTest(t *testing.T) (*Export, *MockExportsRepository, *MockPatientsRepository, *MockAppointmentsRepository, string) {
+	exportsRepo := new(MockExportsRepository)
+	patientsRepo := new(MockPatientsRepository)
+	appointmentsRepo := new(MockAppointmentsRepository)
+	dir := t.TempDir()
+	logger, _ := zap.NewDevelopment()
+	service := &Export{
+		Logger:                 logger.Sugar(),
+		ExportsRepository:      exportsRepo,
+		PatientsRepository:     patientsRepo,
+		AppointmentsRepository: appointmentsRepo,
+		Store:                  blobstore.NewFileStore(dir, "https://files.example.com", []byte("secret")),
+		PageSize:               2,
+		Now:                    func() time.Time { return now },
+	}
+	return service, exportsRepo, patientsRepo, appointmentsRepo, dir
+}
+
+func TestCreateExport(t *testing.T) {
+	service, exportsRepo, _, _, _ := setupTest(t)
+	ctx := context.Background()
+	exportsRepo.On("Save", ctx, mock.Anything).Return(nil)
+
+	result, err := service.CreateExport(ctx, &models.ExportRequest{ClientID: "client123", Resource: models.ExportResourcePatients})
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.ID)
+	assert.Equal(t, models.ExportStatusPending, result.Status)
+	assert.Equal(t, models.ExportFormatCSV, result.Format)
+}
+
+func TestCreateExport_Invalid(t *testing.T) {
+	service, _, _, _, _ := setupTest(t)
+
+	tests := []*models.ExportRequest{
+		{Resource: models.ExportResourcePatients},
+		{ClientID: "client123", Resource: "doctors"},
+		{ClientID: "client123", Resource: models.ExportResourcePatients, Format: "xlsx"},
+	}
+	for _, tt := range tests {
+		_, err := service.CreateExport(context.Background(), tt)
+		assert.ErrorIs(t, err, ErrInvalidExport)
+	}
+}
+
+func TestRunExport_PatientsCSV(t *testing.T) {
+	service, exportsRepo, patientsRepo, _, dir := setupTest(t)
+	ctx := context.Background()
+	job := &models.ExportJob{ID: "e1", ClientID: "client123", Resource: models.ExportResourcePatients, Format: models.ExportFormatCSV, Status: models.ExportStatusPending}
+	exportsRepo.On("GetByID", ctx, "e1").Return(job, nil)
+	exportsRepo.On("Claim", ctx, "e1", mock.Anything, now.Unix(), lease).Return(true, nil)
+	exportsRepo.On("Finish", ctx, mock.Anything, lease).Return(true, nil)
+	patientsRepo.On("GetPageByClientID", ctx, "client123", "", int32(2)).Return([]*models.Patient{
+		{ID: "p1", FirstName: "Ana", LastName: "=HYPERLINK(\"x\")", PhoneNumber: "+5491155551234"},
+		{ID: "p2", FirstName: "Juan", Metadata: map[string]interface{}{"origen": "csv"}},
+	}, "next", nil)
+	patientsRepo.On("GetPageByClientID", ctx, "client123", "next", int32(2)).Return([]*models.Patient{
+		{ID: "p3", FirstName: "Luz"},
+	}, "", nil)
+
+	err := service.RunExport(ctx, "e1")
+
+	require.NoError(t, err)
+	last := exportsRepo.saved[len(exportsRepo.saved)-1]
+	assert.Equal(t, models.ExportStatusCompleted, last.Status)
+	assert.Equal(t, 3, last.Rows)
+	assert.Equal(t, "exports/client123/e1.csv", last.Key)
+
+	f, err := os.Open(filepath.Join(dir, last.Key))
+	require.NoError(t, err)
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, columns[models.ExportResourcePatients], records[0])
+	assert.Equal(t, "'=HYPERLINK(\"x\")", records[1][2])
+	assert.Equal(t, "+5491155551234", records[1][8])
+	assert.Equal(t, `{"origen":"csv"}`, records[2][15])
+}
+
+func TestRunExport_AppointmentsNDJSON(t *testing.T) {
+	service, exportsRepo, _, appointmentsRepo, dir := setupTest(t)
+	ctx := context.Background()
+	job := &models.ExportJob{ID: "e2", ClientID: "client123", Resource: models.ExportResourceAppointments, Format: models.ExportFormatNDJSON, Status: models.ExportStatusPending}
+	exportsRepo.On("GetByID", ctx, "e2").Return(job, nil)
+	exportsRepo.On("Claim", ctx, "e2", mock.Anything, now.Unix(), lease).Return(true, nil)
+	exportsRepo.On("Finish", ctx, mock.Anything, lease).Return(true, nil)
+	appointmentsRepo.On("GetPageByClientID", ctx, "client123", "", int32(2)).Return([]*models.Appointment{
+		{ID: "a1", PatientID: "p1", Duration: 30, Status: models.AppointmentStatusScheduled},
+	}, "", nil)
+
+	require.NoError(t, service.RunExport(ctx, "e2"))
+
+	f, err := os.Open(filepath.Join(dir, "exports/client123/e2.ndjson"))
+	require.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	require.True(t, scanner.Scan())
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+	assert.Equal(t, "a1", line["id"])
+	assert.Equal(t, float64(30), line["duration"])
+	assert.False(t, scanner.Scan())
+}
+
+func TestRunExport_FailureAbortsUpload(t *testing.T) {
+	service, exportsRepo, patientsRepo, _, dir := setupTest(t)
+	ctx := context.Background()
+	job := &models.ExportJob{ID: "e3", ClientID: "client123", Resource: models.ExportResourcePatients, Format: models.ExportFormatCSV, Status: models.ExportStatusPending}
+	exportsRepo.On("GetByID", ctx, "e3").Return(job, nil)
+	exportsRepo.On("Claim", ctx, "e3", mock.Anything, now.Unix(), lease).Return(true, nil)
+	exportsRepo.On("Finish", ctx, mock.Anything, lease).Return(true, nil)
+	patientsRepo.On("GetPageByClientID", ctx, "client123", "", int32(2)).Return(nil, "", errors.New("throttled"))
+
+	require.NoError(t, service.RunExport(ctx, "e3"))
+
+	last := exportsRepo.saved[len(exportsRepo.saved)-1]
+	assert.Equal(t, models.ExportStatusFailed, last.Status)
+	assert.Equal(t, "throttled", last.Error)
+	_, err := os.Stat(filepath.Join(dir, "exports/client123/e3.csv"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRunExport_SkipsProcessedJobs(t *testing.T) {
+	service, exportsRepo, patientsRepo, _, _ := setupTest(t)
+	ctx := context.Background()
+	exportsRepo.On("GetByID", ctx, "e4").Return(&models.ExportJob{ID: "e4", Status: models.ExportStatusCompleted}, nil)
+
+	require.NoError(t, service.RunExport(ctx, "e4"))
+
+	exportsRepo.AssertNotCalled(t, "Claim", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	patientsRepo.AssertNotCalled(t, "GetPageByClientID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunExport_LiveLease(t *testing.T) {
+	service, exportsRepo, patientsRepo, _, _ := setupTest(t)
+	ctx := context.Background()
+	exportsRepo.On("GetByID", ctx, "e5").Return(&models.ExportJob{ID: "e5", Status: models.ExportStatusRunning, LeaseUntil: now.Add(time.Minute).Unix(), Attempts: 1}, nil)
+
+	err := service.RunExport(ctx, "e5")
+
+	assert.ErrorIs(t, err, ErrExportRunning)
+	exportsRepo.AssertNotCalled(t, "Claim", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	patientsRepo.AssertNotCalled(t, "GetPageByClientID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunExport_TakesOverExpiredLease(t *testing.T) {
+	service, exportsRepo, patientsRepo, _, _ := setupTest(t)
+	ctx := context.Background()
+	job := &models.ExportJob{ID: "e6", ClientID: "client123", Resource: models.ExportResourcePatients, Format: models.ExportFormatCSV, Status: models.ExportStatusRunning, LeaseUntil: now.Add(-time.Minute).Unix(), Attempts: 1}
+	exportsRepo.On("GetByID", ctx, "e6").Return(job, nil)
+	exportsRepo.On("Claim", ctx, "e6", "exports/client123/e6.csv", now.Unix(), lease).Return(true, nil)
+	exportsRepo.On("Finish", ctx, mock.Anything, lease).Return(true, nil)
+	patientsRepo.On("GetPageByClientID", ctx, "client123", "", int32(2)).Return([]*models.Patient{{ID: "p1"}}, "", nil)
+
+	require.NoError(t, service.RunExport(ctx, "e6"))
+
+	last := exportsRepo.saved[len(exportsRepo.saved)-1]
+	assert.Equal(t, models.ExportStatusCompleted, last.Status)
+	assert.Equal(t, 2, last.Attempts)
+}
+
+func TestRunExport_FailsAfterMaxAttempts(t *testing.T) {
+	service, exportsRepo, patientsRepo, _, _ := setupTest(t)
+	ctx := context.Background()
+	expired := now.Add(-time.Minute).Unix()
+	exportsRepo.On("GetByID", ctx, "e7").Return(&models.ExportJob{ID: "e7", Status: models.ExportStatusRunning, LeaseUntil: expired, Attempts: maxAttempts}, nil)
+	exportsRepo.On("Finish", ctx, mock.Anything, expired).Return(true, nil)
+
+	require.NoError(t, service.RunExport(ctx, "e7"))
+
+	last := exportsRepo.saved[len(exportsRepo.saved)-1]
+	assert.Equal(t, models.ExportStatusFailed, last.Status)
+	assert.NotEmpty(t, last.Error)
+	exportsRepo.AssertNotCalled(t, "Claim", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	patientsRepo.AssertNotCalled(t, "GetPageByClientID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunExport_ClaimLost(t *testing.T) {
+	service, exportsRepo, patientsRepo, _, _ := setupTest(t)
+	ctx := context.Background()
+	exportsRepo.On("GetByID", ctx, "e8").Return(&models.ExportJob{ID: "e8", ClientID: "client123", Resource: models.ExportResourcePatients, Format: models.ExportFormatCSV, Status: models.ExportStatusPending}, nil)
+	exportsRepo.On("Claim", ctx, "e8", mock.Anything, now.Unix(), lease).Return(false, nil)
+
+	assert.ErrorIs(t, service.RunExport(ctx, "e8"), ErrExportRunning)
+	patientsRepo.AssertNotCalled(t, "GetPageByClientID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetExport(t *testing.T) {
+	service, exportsRepo, _, _, _ := setupTest(t)
+	ctx := context.Background()
+	exportsRepo.On("GetByID", ctx, "e1").Return(&models.ExportJob{ID: "e1", Status: models.ExportStatusCompleted, Key: "exports/client123/e1.csv", Rows: 3}, nil)
+	exportsRepo.On("GetByID", ctx, "missing").Return(nil, nil)
+
+	result, err := service.GetExport(ctx, "e1")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(result.DownloadURL, "https://files.example.com/exports/client123/e1.csv?"))
+	assert.NotEmpty(t, result.DownloadExpiresAt)
+
+	_, err = service.GetExport(ctx, "missing")
+	assert.ErrorIs(t, err, ErrExportNotFound)
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/availability"
+	"github.com/MezeLaw/iris-services/internal/fhir"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	appointments "github.com/MezeLaw/iris-services/internal/service/appointments"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotFound          = errors.New("resource not found")
+	ErrSlotNotAvailable  = errors.New("requested time is not available")
+	ErrInvalidParameters = errors.New("invalid search parameters")
+	ErrInvalidResource   = errors.New("invalid resource")
+)
+
+type AppointmentsRepository interface {
+	GetByID(ctx context.Context, id string) (*models.Appointment, error)
+	GetByClientID(ctx context.Context, clientID string) ([]*models.Appointment, error)
+	GetByPatientID(ctx context.Context, patientID string) ([]*models.Appointment, error)
+	GetByDoctorID(ctx context.Context, doctorID string) ([]*models.Appointment, error)
+}
+
+// AppointmentsService es el subconjunto de service.Appointments que usamos
+// para reservar, así las validaciones de negocio no se duplican. Se reserva
+// con una reserva temporal que se confirma enseguida: la escritura
+// condicional sobre el horario es la que evita que dos pedidos simultáneos
+// lo tomen a la vez.
+type AppointmentsService interface {
+	HoldSlot(ctx context.Context, request *models.SlotHoldRequest) (*models.SlotHoldRequest, error)
+	ConfirmHold(ctx context.Context, holdID string, request *models.AppointmentRequest) (*models.AppointmentRequest, error)
+	ReleaseHold(ctx context.Context, holdID string) error
+}
+
+// HoldsReader devuelve las reservas temporales vigentes de un médico, que
+// ocupan la agenda igual que un turno. Es opcional.
+type HoldsReader interface {
+	GetByDoctorID(ctx context.Context, doctorID string, now time.Time) ([]*models.SlotHold, error)
+}
+
+type FHIRService interface {
+	ReadAppointment(ctx context.Context, id string) (*fhir.Appointment, error)
+	SearchAppointments(ctx context.Context, search *fhir.AppointmentSearch) (*fhir.Bundle, error)
+	BookAppointment(ctx context.Context, resource *fhir.Appointment) (*fhir.Appointment, error)
+	ReadSchedule(ctx context.Context, clientID, doctorID string) (*fhir.Schedule, error)
+	SearchSlots(ctx context.Context, search *fhir.SlotSearch) (*fhir.Bundle, error)
+}
+
+type FHIR struct {
+	Logger                 *zap.SugaredLogger
+	AppointmentsRepository AppointmentsRepository
+	Appointments           AppointmentsService
+	WorkingHours           availability.WorkingHours
+	Holds                  HoldsReader
+}
+
+func New(logger *zap.SugaredLogger, repository AppointmentsRepository, appointments AppointmentsService, hours availability.WorkingHours, holds HoldsReader) FHIRService {
+	return &FHIR{
+		Logger:                 logger,
+		AppointmentsRepository: repository,
+		Appointments:           appointments,
+		WorkingHours:           hours,
+		Holds:                  holds,
+	}
+}
+
+func (f *FHIR) ReadAppointment(ctx context.Context, id string) (*fhir.Appointment, error) {
+	appointment, err := f.AppointmentsRepository.GetByID(ctx, id)
+	if err != nil {
+		f.log(ctx).Error("Error getting appointment by ID", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	if appointment == nil {
+		return nil, fmt.Errorf("Appointment/%s: %w", id, ErrNotFound)
+	}
+	return fhir.AppointmentFromModel(appointment), nil
+}
+
+func (f *FHIR) SearchAppointments(ctx context.Context, search *fhir.AppointmentSearch) (*fhir.Bundle, error) {
+	// Usar el índice más selectivo disponible y filtrar el resto en memoria
+	var (
+		appointments []*models.Appointment
+		err          error
+	)
+	switch {
+	case search.PatientID != "":
+		appointments, err = f.AppointmentsRepository.GetByPatientID(ctx, search.PatientID)
+	case search.PractitionerID != "":
+		appointments, err = f.AppointmentsRepository.GetByDoctorID(ctx, search.PractitionerID)
+	case search.ClientID != "":
+		appointments, err = f.AppointmentsRepository.GetByClientID(ctx, search.ClientID)
+	default:
+		f.log(ctx).Error("Appointment search without patient, practitioner or clientId")
+		return nil, fmt.Errorf("%w: must provide patient, practitioner or clientId", ErrInvalidParameters)
+	}
+	if err != nil {
+		f.log(ctx).Error("Error searching appointments", zap.Error(err))
+		return nil, err
+	}
+
+	matches := make([]*models.Appointment, 0, len(appointments))
+	for _, appointment := range appointments {
+		if search.Matches(appointment) {
+			matches = append(matches, appointment)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Date < matches[j].Date })
+
+	entries := make([]fhir.BundleEntry, 0, len(matches))
+	for _, appointment := range matches {
+		entries = append(entries, fhir.BundleEntry{
+			FullURL:  "Appointment/" + appointment.ID,
+			Resource: fhir.AppointmentFromModel(appointment),
+		})
+	}
+
+	f.log(ctx).Info("Appointment search completed", zap.Int("count", len(entries)))
+	return fhir.NewSearchBundle(entries), nil
+}
+
+func (f *FHIR) BookAppointment(ctx context.Context, resource *fhir.Appointment) (*fhir.Appointment, error) {
+	request, err := fhir.AppointmentToRequest(resource)
+	if err != nil {
+		f.log(ctx).Error("Invalid FHIR Appointment", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResource, err)
+	}
+	if resource.Status != fhir.AppointmentStatusBooked {
+		return nil, fmt.Errorf("%w: only appointments with status %s can be booked", ErrInvalidResource, fhir.AppointmentStatusBooked)
+	}
+
+	if _, err := availability.AppointmentInterval(&models.Appointment{Date: request.Date, Duration: request.Duration}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResource, err)
+	}
+
+	hold, err := f.Appointments.HoldSlot(ctx, &models.SlotHoldRequest{
+		ClientID: request.ClientID,
+		DoctorID: request.DoctorID,
+		Date:     request.Date,
+		Duration: request.Duration,
+	})
+	switch {
+	case errors.Is(err, appointments.ErrSlotHeld):
+		f.log(ctx).Info("Requested time overlaps an existing appointment", zap.String("doctorID", request.DoctorID), zap.String("date", request.Date))
+		return nil, ErrSlotNotAvailable
+	case errors.Is(err, appointments.ErrInvalidHold):
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResource, err)
+	case err != nil:
+		f.log(ctx).Error("Error holding slot", zap.Error(err))
+		return nil, err
+	}
+
+	created, err := f.Appointments.ConfirmHold(ctx, hold.ID, request)
+	if err != nil {
+		f.log(ctx).Error("Error booking appointment", zap.Error(err))
+		// Sin liberarla, la reserva bloquearía el horario hasta vencer
+		if releaseErr := f.Appointments.ReleaseHold(ctx, hold.ID); releaseErr != nil {
+			f.log(ctx).Error("Error releasing slot hold", zap.Error(releaseErr))
+		}
+		return nil, err
+	}
+
+	return fhir.AppointmentFromModel(&models.Appointment{
+		ID:        created.ID,
+		ClientID:  created.ClientID,
+		PatientID: created.PatientID,
+		DoctorID:  created.DoctorID,
+		Date:      created.Date,
+		Duration:  created.Duration,
+		Status:    created.Status,
+		Notes:     created.Notes,
+		CreatedAt: created.CreatedAt,
+		UpdatedAt: created.UpdatedAt,
+	}), nil
+}
+
+func (f *FHIR) ReadSchedule(ctx context.Context, clientID, doctorID string) (*fhir.Schedule, error) {
+	if doctorID == "" {
+		return nil, fmt.Errorf("%w: schedule id is required", ErrInvalidParameters)
+	}
+	return fhir.ScheduleFromDoctor(clientID, doctorID, f.WorkingHours, availability.Interval{}), nil
+}
+
+func (f *FHIR) SearchSlots(ctx context.Context, search *fhir.SlotSearch) (*fhir.Bundle, error) {
+	busy, err := f.busy(ctx, search.ScheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	slots, err := availability.Slots(search.Start, search.End, f.WorkingHours, busy)
+	if err != nil {
+		f.log(ctx).Error("Error calculating slots", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrInvalidParameters, err)
+	}
+
+	entries := make([]fhir.BundleEntry, 0, len(slots))
+	for _, slot := range slots {
+		resource := fhir.SlotFromAvailability(search.ScheduleID, slot)
+		if search.Status != "" && resource.Status != search.Status {
+			continue
+		}
+		entries = append(entries, fhir.BundleEntry{FullURL: "Slot/" + resource.ID, Resource: resource})
+	}
+
+	f.log(ctx).Info("Slot search completed", zap.String("schedule", search.ScheduleID), zap.Int("count", len(entries)), zap.Time("start", search.Start), zap.Time("end", search.End))
+	return fhir.NewSearchBundle(entries), nil
+}
+
+// busy devuelve lo que ocupa la agenda del médico: sus turnos y, si hay
+// reservas configuradas, las reservas temporales vigentes.
+func (f *FHIR) busy(ctx context.Context, doctorID string) ([]availability.Interval, error) {
+	appointments, err := f.AppointmentsRepository.GetByDoctorID(ctx, doctorID)
+	if err != nil {
+		f.log(ctx).Error("Error getting appointments by DoctorID", zap.String("doctorID", doctorID), zap.Error(err))
+		return nil, err
+	}
+	busy := availability.BusyFromAppointments(appointments)
+	if f.Holds == nil {
+		return busy, nil
+	}
+	holds, err := f.Holds.GetByDoctorID(ctx, doctorID, time.Now())
+	if err != nil {
+		f.log(ctx).Error("Error getting slot holds by DoctorID", zap.String("doctorID", doctorID), zap.Error(err))
+		return nil, err
+	}
+	return append(busy, availability.BusyFromHolds(holds)...), nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del servicio.
+func (f *FHIR) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, f.Logger)
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/availability"
+	"github.com/MezeLaw/iris-services/internal/fhir"
+	"github.com/MezeLaw/iris-services/internal/models"
+	appointments "github.com/MezeLaw/iris-services/internal/service/appointments"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type MockAppointmentsRepository struct {
+	mock.Mock
+}
+
+func (m *MockAppointmentsRepository) GetByID(ctx context.Context, id string) (*models.Appointment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Appointment), args.Error(1)
+}
+
+func (m *MockAppointmentsRepository) GetByClientID(ctx context.Context, clientID string) ([]*models.Appointment, error) {
+	args := m.Called(ctx, clientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Appointment), args.Error(1)
+}
+
+func (m *MockAppointmentsRepository) GetByPatientID(ctx context.Context, patientID string) ([]*models.Appointment, error) {
+	args := m.Called(ctx, patientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Appointment), args.Error(1)
+}
+
+func (m *MockAppointmentsRepository) GetByDoctorID(ctx context.Context, doctorID string) ([]*models.Appointment, error) {
+	args := m.Called(ctx, doctorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Appointment), args.Error(1)
+}
+
+type MockAppointmentsService struct {
+	mock.Mock
+}
+
+func (m *MockAppointmentsService) HoldSlot(ctx context.Context, request *models.SlotHoldRequest) (*models.SlotHoldRequest, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SlotHoldRequest), args.Error(1)
+}
+
+func (m *MockAppointmentsService) ConfirmHold(ctx context.Context, holdID string, request *models.AppointmentRequest) (*models.AppointmentRequest, error) {
+	args := m.Called(ctx, holdID, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AppointmentRequest), args.Error(1)
+}
+
+func (m *MockAppointmentsService) ReleaseHold(ctx context.Context, holdID string) error {
+	args := m.Called(ctx, holdID)
+	return args.Error(0)
+}
+
+func setupTest() (*FHIR, *MockAppointmentsRepository, *MockAppointmentsService) {
+	mockRepo := new(MockAppointmentsRepository)
+	mockService := new(MockAppointmentsService)
+	logger, _ := zap.NewDevelopment()
+	service := &FHIR{
+		Logger:                 logger.Sugar(),
+		AppointmentsRepository: mockRepo,
+		Appointments:           mockService,
+		WorkingHours: availability.WorkingHours{
+			Start:       "09:00",
+			End:         "11:00",
+			Weekdays:    []time.Weekday{time.Monday},
+			SlotMinutes: 30,
+		},
+	}
+	return service, mockRepo, mockService
+}
+
+func createSampleAppointment(id, date string, status models.AppointmentStatus) *models.Appointment {
+	return &models.Appointment{
+		ID:        id,
+		ClientID:  "client123",
+		PatientID: "patient123",
+		DoctorID:  "doctor123",
+		Date:      date,
+		Duration:  30,
+		Status:    status,
+	}
+}
+
+func TestFHIR_ReadAppointment_NotFound(t *testing.T) {
+	service, mockRepo, _ := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetByID", ctx, "missing").Return(nil, nil)
+
+	result, err := service.ReadAppointment(ctx, "missing")
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFHIR_SearchAppointments_FiltersByDateAndStatus(t *testing.T) {
+	service, mockRepo, _ := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetByDoctorID", ctx, "doctor123").Return([]*models.Appointment{
+		createSampleAppointment("a2", "2024-01-16T10:00:00Z", models.AppointmentStatusScheduled),
+		createSampleAppointment("a1", "2024-01-15T10:00:00Z", models.AppointmentStatusScheduled),
+		createSampleAppointment("a3", "2024-01-15T11:00:00Z", models.AppointmentStatusCancelled),
+		createSampleAppointment("a4", "2024-02-01T10:00:00Z", models.AppointmentStatusScheduled),
+	}, nil)
+
+	search, _ := fhir.ParseAppointmentSearch(map[string][]string{
+		"practitioner": {"Practitioner/doctor123"},
+		"date":         {"ge2024-01-15", "lt2024-02-01"},
+		"status":       {"booked"},
+	})
+	bundle, err := service.SearchAppointments(ctx, search)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, bundle.Total)
+	assert.Equal(t, "a1", bundle.Entry[0].Resource.(*fhir.Appointment).ID)
+	assert.Equal(t, "a2", bundle.Entry[1].Resource.(*fhir.Appointment).ID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFHIR_SearchAppointments_RequiresScope(t *testing.T) {
+	service, _, _ := setupTest()
+
+	bundle, err := service.SearchAppointments(context.Background(), &fhir.AppointmentSearch{})
+
+	assert.Nil(t, bundle)
+	assert.ErrorIs(t, err, ErrInvalidParameters)
+}
+
+func TestFHIR_BookAppointment_Success(t *testing.T) {
+	service, _, mockService := setupTest()
+	ctx := context.Background()
+	resource := fhir.AppointmentFromModel(createSampleAppointment("", "2024-01-15T10:00:00Z", models.AppointmentStatusScheduled))
+
+	mockService.On("HoldSlot", ctx, &models.SlotHoldRequest{
+		ClientID: "client123",
+		DoctorID: "doctor123",
+		Date:     "2024-01-15T10:00:00Z",
+		Duration: 30,
+	}).Return(&models.SlotHoldRequest{ID: "hold123"}, nil)
+	created := &models.AppointmentRequest{
+		ID:        "appointment123",
+		ClientID:  "client123",
+		PatientID: "patient123",
+		DoctorID:  "doctor123",
+		Date:      "2024-01-15T10:00:00Z",
+		Duration:  30,
+		Status:    models.AppointmentStatusScheduled,
+	}
+	mockService.On("ConfirmHold", ctx, "hold123", mock.AnythingOfType("*models.AppointmentRequest")).Return(created, nil)
+
+	result, err := service.BookAppointment(ctx, resource)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "appointment123", result.ID)
+	assert.Equal(t, fhir.AppointmentStatusBooked, result.Status)
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "ReleaseHold", mock.Anything, mock.Anything)
+}
+
+// created y meta.lastUpdated salen del turno que guardó ConfirmHold: el
+// recurso que manda el cliente no los trae
+func TestFHIR_BookAppointment_Timestamps(t *testing.T) {
+	service, _, mockService := setupTest()
+	ctx := context.Background()
+	resource := fhir.AppointmentFromModel(createSampleAppointment("", "2024-01-15T10:00:00Z", models.AppointmentStatusScheduled))
+	resource.Created, resource.Meta.LastUpdated = "", ""
+
+	mockService.On("HoldSlot", ctx, mock.AnythingOfType("*models.SlotHoldRequest")).Return(&models.SlotHoldRequest{ID: "hold123"}, nil)
+	mockService.On("ConfirmHold", ctx, "hold123", mock.MatchedBy(func(r *models.AppointmentRequest) bool {
+		return r.CreatedAt == "" && r.UpdatedAt == ""
+	})).Return(&models.AppointmentRequest{
+		ID:        "appointment123",
+		ClientID:  "client123",
+		PatientID: "patient123",
+		DoctorID:  "doctor123",
+		Date:      "2024-01-15T10:00:00Z",
+		Duration:  30,
+		Status:    models.AppointmentStatusScheduled,
+		CreatedAt: "2024-01-10T12:00:00Z",
+		UpdatedAt: "2024-01-10T12:00:00Z",
+	}, nil)
+
+	result, err := service.BookAppointment(ctx, resource)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.Created)
+	assert.NotEmpty(t, result.Meta.LastUpdated)
+	assert.Equal(t, "2024-01-10T12:00:00Z", result.Created)
+	assert.Equal(t, "2024-01-10T12:00:00Z", result.Meta.LastUpdated)
+	mockService.AssertExpectations(t)
+}
+
+func TestFHIR_BookAppointment_Conflict(t *testing.T) {
+	service, _, mockService := setupTest()
+	ctx := context.Background()
+	resource := fhir.AppointmentFromModel(createSampleAppointment("", "2024-01-15T10:00:00Z", models.AppointmentStatusScheduled))
+
+	mockService.On("HoldSlot", ctx, mock.AnythingOfType("*models.SlotHoldRequest")).Return(nil, appointments.ErrSlotHeld)
+
+	result, err := service.BookAppointment(ctx, resource)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrSlotNotAvailable)
+	mockService.AssertNotCalled(t, "ConfirmHold", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFHIR_BookAppointment_InvalidHold(t *testing.T) {
+	service, _, mockService := setupTest()
+	ctx := context.Background()
+	resource := fhir.AppointmentFromModel(createSampleAppointment("", "2024-01-15T10:00:00Z", models.AppointmentStatusScheduled))
+
+	mockService.On("HoldSlot", ctx, mock.AnythingOfType("*models.SlotHoldRequest")).Return(nil, fmt.Errorf("%w: date must be in the future", appointments.ErrInvalidHold))
+
+	result, err := service.BookAppointment(ctx, resource)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrInvalidResource)
+}
+
+func TestFHIR_BookAppointment_ReleasesHoldOnError(t *testing.T) {
+	service, _, mockService := setupTest()
+	ctx := context.Background()
+	resource := fhir.AppointmentFromModel(createSampleAppointment("", "2024-01-15T10:00:00Z", models.AppointmentStatusScheduled))
+
+	mockService.On("HoldSlot", ctx, mock.AnythingOfType("*models.SlotHoldRequest")).Return(&models.SlotHoldRequest{ID: "hold123"}, nil)
+	mockService.On("ConfirmHold", ctx, "hold123", mock.AnythingOfType("*models.AppointmentRequest")).Return(nil, appointments.ErrPatientBlocked)
+	mockService.On("ReleaseHold", ctx, "hold123").Return(nil)
+
+	result, err := service.BookAppointment(ctx, resource)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, appointments.ErrPatientBlocked)
+	mockService.AssertExpectations(t)
+}
+
+func TestFHIR_SearchSlots(t *testing.T) {
+	service, mockRepo, _ := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetByDoctorID", ctx, "doctor123").Return([]*models.Appointment{
+		createSampleAppointment("a1", "2024-01-15T09:30:00Z", models.AppointmentStatusScheduled),
+	}, nil)
+
+	start := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	bundle, err := service.SearchSlots(ctx, &fhir.SlotSearch{
+		ScheduleID: "doctor123",
+		Start:      start,
+		End:        start.AddDate(0, 0, 1),
+		Status:     fhir.SlotStatusFree,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, bundle.Total)
+	mockRepo.AssertExpectations(t)
+}
+
+type MockHoldsReader struct {
+	mock.Mock
+}
+
+func (m *MockHoldsReader) GetByDoctorID(ctx context.Context, doctorID string, now time.Time) ([]*models.SlotHold, error) {
+	args := m.Called(ctx, doctorID, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SlotHold), args.Error(1)
+}
+
+func TestFHIR_SearchSlots_HoldsAreBusy(t *testing.T) {
+	service, mockRepo, _ := setupTest()
+	holds := new(MockHoldsReader)
+	service.Holds = holds
+	ctx := context.Background()
+	mockRepo.On("GetByDoctorID", ctx, "doctor123").Return([]*models.Appointment{
+		createSampleAppointment("a1", "2024-01-15T09:30:00Z", models.AppointmentStatusScheduled),
+	}, nil)
+	holds.On("GetByDoctorID", ctx, "doctor123", mock.Anything).Return([]*models.SlotHold{
+		{DoctorID: "doctor123", Date: "2024-01-15T10:00:00Z", Duration: 30},
+	}, nil)
+
+	start := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	bundle, err := service.SearchSlots(ctx, &fhir.SlotSearch{
+		ScheduleID: "doctor123",
+		Start:      start,
+		End:        start.AddDate(0, 0, 1),
+		Status:     fhir.SlotStatusFree,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, bundle.Total)
+	holds.AssertExpectations(t)
+}
+
+func TestFHIR_SearchSlots_RepositoryError(t *testing.T) {
+	service, mockRepo, _ := setupTest()
+	ctx := context.Background()
+	expectedErr := errors.New("database error")
+	mockRepo.On("GetByDoctorID", ctx, "doctor123").Return(nil, expectedErr)
+
+	bundle, err := service.SearchSlots(ctx, &fhir.SlotSearch{ScheduleID: "doctor123"})
+
+	assert.Nil(t, bundle)
+	assert.Equal(t, expectedErr, err)
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchSize   = 100
+	defaultMaxAttempts = 10
+)
+
+type OutboxRepository interface {
+	Pending(ctx context.Context, limit int32) ([]*models.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id string, now time.Time) error
+	MarkFailed(ctx context.Context, id string, delivered []string, cause error) (int, error)
+	MarkDead(ctx context.Context, id string) error
+}
+
+type OutboxService interface {
+	Relay(ctx context.Context, now time.Time) (*models.OutboxReport, error)
+}
+
+// Outbox publica los eventos que los servicios dejaron en el outbox en cada
+// uno de sus destinos. La entrega es al menos una vez: si se corta entre
+// Publish y la marca el evento sale de nuevo en la próxima corrida, y los
+// consumidores deduplican por ID. Un evento que falla en un destino se
+// reintenta sólo en los que todavía no lo recibieron.
+type Outbox struct {
+	Logger       *zap.SugaredLogger
+	Repository   OutboxRepository
+	Destinations []events.Destination
+	BatchSize    int32
+	// MaxAttempts es cuántas veces puede rechazarse un evento antes de
+	// pasarlo al dead letter. Los pendientes salen del más viejo al más
+	// nuevo, así que sin este corte un lote de eventos que nunca se pueden
+	// publicar trabaría para siempre a los que vienen detrás.
+	MaxAttempts int
+}
+
+func New(logger *zap.SugaredLogger, repository OutboxRepository, destinations ...events.Destination) OutboxService {
+	return &Outbox{
+		Logger:       logger,
+		Repository:   repository,
+		Destinations: destinations,
+		BatchSize:    defaultBatchSize,
+		MaxAttempts:  defaultMaxAttempts,
+	}
+}
+
+// Relay publica un lote de pendientes, del más viejo al más nuevo, en cada
+// destino que todavía no los recibió. Los que algún destino rechaza quedan
+// pendientes con el error y los destinos que sí los recibieron, para el
+// próximo intento, hasta agotar MaxAttempts.
+func (o *Outbox) Relay(ctx context.Context, now time.Time) (*models.OutboxReport, error) {
+	limit := o.BatchSize
+	if limit <= 0 {
+		limit = defaultBatchSize
+	}
+	pending, err := o.Repository.Pending(ctx, limit)
+	if err != nil {
+		o.log(ctx).Error("Error reading outbox", zap.Error(err))
+		return nil, err
+	}
+	report := &models.OutboxReport{Pending: len(pending), More: int32(len(pending)) == limit}
+	if len(pending) == 0 {
+		return report, nil
+	}
+
+	failed, delivered, err := o.publish(ctx, pending)
+	if err != nil {
+		o.log(ctx).Error("Error publishing events", zap.Int("count", len(pending)), zap.Error(err))
+		return nil, err
+	}
+
+	var errs []error
+	for _, p := range pending {
+		event := p.Event
+		if cause, ok := failed[event.ID]; ok {
+			report.Failed++
+			o.log(ctx).Error("Event rejected by publisher", zap.String("id", event.ID), zap.String("type", event.Type), zap.Error(cause))
+			o.markFailed(ctx, event, delivered[event.ID], cause, report)
+			continue
+		}
+		if err := o.Repository.MarkPublished(ctx, event.ID, now); err != nil {
+			// Se publicó pero va a salir de nuevo: no es grave, el consumidor deduplica
+			o.log(ctx).Error("Error marking event as published", zap.String("id", event.ID), zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+		report.Published++
+	}
+	o.log(ctx).Info("Outbox relayed", zap.Int("published", report.Published), zap.Int("failed", report.Failed))
+	return report, errors.Join(errs...)
+}
+
+// publish manda a cada destino los pendientes que todavía no recibió.
+// Devuelve por ID el error de los que fallaron en algún destino y los
+// destinos que los recibieron en esta corrida. Si todos los destinos que
+// tenían algo para mandar se caen, devuelve el error y no marca nada.
+func (o *Outbox) publish(ctx context.Context, pending []*models.OutboxEvent) (map[string]error, map[string][]string, error) {
+	failed := map[string]error{}
+	delivered := map[string][]string{}
+	var errs []error
+	attempted := 0
+	for _, destination := range o.Destinations {
+		var batch []*events.Event
+		for _, p := range pending {
+			if !slices.Contains(p.Delivered, destination.Name) {
+				batch = append(batch, p.Event)
+			}
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		attempted++
+		rejected, err := destination.Publisher.Publish(ctx, batch)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", destination.Name, err)
+			errs = append(errs, err)
+			for _, event := range batch {
+				failed[event.ID] = errors.Join(failed[event.ID], err)
+			}
+			continue
+		}
+		for _, event := range batch {
+			if cause, ok := rejected[event.ID]; ok {
+				failed[event.ID] = errors.Join(failed[event.ID], fmt.Errorf("%s: %w", destination.Name, cause))
+				continue
+			}
+			delivered[event.ID] = append(delivered[event.ID], destination.Name)
+		}
+	}
+	if attempted > 0 && len(errs) == attempted {
+		return nil, nil, errors.Join(errs...)
+	}
+	return failed, delivered, nil
+}
+
+func (o *Outbox) markFailed(ctx context.Context, event *events.Event, delivered []string, cause error, report *models.OutboxReport) {
+	attempts, err := o.Repository.MarkFailed(ctx, event.ID, delivered, cause)
+	if err != nil {
+		o.log(ctx).Error("Error recording event failure", zap.String("id", event.ID), zap.Error(err))
+		return
+	}
+	maxAttempts := o.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	if attempts < maxAttempts {
+		return
+	}
+	if err := o.Repository.MarkDead(ctx, event.ID); err != nil {
+		o.log(ctx).Error("Error moving event to dead letter", zap.String("id", event.ID), zap.Error(err))
+		return
+	}
+	report.Dead++
+	o.log(ctx).Error("Event moved to dead letter", zap.String("id", event.ID), zap.String("type", event.Type), zap.Int("attempts", attempts))
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del servicio.
+func (o *Outbox) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, o.Logger)
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepository) Pending(ctx context.Context, limit int32) ([]*models.OutboxEvent, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.OutboxEvent), args.Error(1)
+}
+
+func (m *MockOutboxRepository) MarkPublished(ctx context.Context, id string, now time.Time) error {
+	args := m.Called(ctx, id, now)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) MarkFailed(ctx context.Context, id string, delivered []string, cause error) (int, error) {
+	args := m.Called(ctx, id, delivered, cause)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOutboxRepository) MarkDead(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) Publish(ctx context.Context, evts []*events.Event) (map[string]error, error) {
+	args := m.Called(ctx, evts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]error), args.Error(1)
+}
+
+var relayNow = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func setupTest() (*Outbox, *MockOutboxRepository, *MockPublisher) {
+	logger, _ := zap.NewDevelopment()
+	repo, publisher := new(MockOutboxRepository), new(MockPublisher)
+	return &Outbox{Logger: logger.Sugar(), Repository: repo, Destinations: []events.Destination{{Name: "bus", Publisher: publisher}}, BatchSize: 2}, repo, publisher
+}
+
+// outboxEvents arma los pendientes sin destinos previos.
+func outboxEvents(evts ...*events.Event) []*models.OutboxEvent {
+	pending := make([]*models.OutboxEvent, 0, len(evts))
+	for _, event := range evts {
+		pending = append(pending, &models.OutboxEvent{Event: event})
+	}
+	return pending
+}
+
+func TestOutbox_Relay(t *testing.T) {
+	service, repo, publisher := setupTest()
+	ctx := context.Background()
+	pending := []*events.Event{{ID: "e1", Type: events.AppointmentBooked}, {ID: "e2", Type: events.PatientUpdated}}
+	rejected := errors.New("throttled")
+	repo.On("Pending", ctx, int32(2)).Return(outboxEvents(pending...), nil)
+	publisher.On("Publish", ctx, pending).Return(map[string]error{"e2": rejected}, nil)
+	repo.On("MarkPublished", ctx, "e1", relayNow).Return(nil)
+	repo.On("MarkFailed", ctx, "e2", []string(nil), mock.MatchedBy(func(err error) bool { return errors.Is(err, rejected) })).Return(1, nil)
+
+	report, err := service.Relay(ctx, relayNow)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Pending)
+	assert.Equal(t, 1, report.Published)
+	assert.Equal(t, 1, report.Failed)
+	assert.True(t, report.More)
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "MarkDead", mock.Anything, mock.Anything)
+}
+
+func TestOutbox_Relay_Empty(t *testing.T) {
+	service, repo, publisher := setupTest()
+	ctx := context.Background()
+	repo.On("Pending", ctx, int32(2)).Return([]*models.OutboxEvent{}, nil)
+
+	report, err := service.Relay(ctx, relayNow)
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Pending)
+	publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestOutbox_Relay_PublisherDown(t *testing.T) {
+	service, repo, publisher := setupTest()
+	ctx := context.Background()
+	pending := []*events.Event{{ID: "e1"}}
+	repo.On("Pending", ctx, int32(2)).Return(outboxEvents(pending...), nil)
+	publisher.On("Publish", ctx, pending).Return(nil, errors.New("bus unavailable"))
+
+	_, err := service.Relay(ctx, relayNow)
+
+	assert.Error(t, err)
+	repo.AssertNotCalled(t, "MarkPublished", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// memoryOutbox guarda los eventos en orden como el índice de pendientes.
+type memoryOutbox struct {
+	events    []*events.Event
+	status    map[string]string
+	attempts  map[string]int
+	delivered map[string][]string
+}
+
+func (m *memoryOutbox) Pending(_ context.Context, limit int32) ([]*models.OutboxEvent, error) {
+	var pending []*models.OutboxEvent
+	for _, event := range m.events {
+		if m.status[event.ID] == "" && int32(len(pending)) < limit {
+			pending = append(pending, &models.OutboxEvent{Event: event, Delivered: m.delivered[event.ID]})
+		}
+	}
+	return pending, nil
+}
+
+func (m *memoryOutbox) MarkPublished(_ context.Context, id string, _ time.Time) error {
+	m.status[id] = "PUBLISHED"
+	return nil
+}
+
+func (m *memoryOutbox) MarkFailed(_ context.Context, id string, delivered []string, _ error) (int, error) {
+	if m.delivered == nil {
+		m.delivered = map[string][]string{}
+	}
+	m.delivered[id] = append(m.delivered[id], delivered...)
+	m.attempts[id]++
+	return m.attempts[id], nil
+}
+
+func (m *memoryOutbox) MarkDead(_ context.Context, id string) error {
+	m.status[id] = "FAILED"
+	return nil
+}
+
+type poisonPublisher struct{}
+
+func (poisonPublisher) Publish(_ context.Context, evts []*events.Event) (map[string]error, error) {
+	failed := map[string]error{}
+	for _, event := range evts {
+		if event.Type == "Poison" {
+			failed[event.ID] = errors.New("invalid detail")
+		}
+	}
+	return failed, nil
+}
+
+func TestOutbox_Relay_PoisonBatch(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := &memoryOutbox{
+		events: []*events.Event{
+			{ID: "p1", Type: "Poison"}, {ID: "p2", Type: "Poison"},
+			{ID: "e1", Type: events.AppointmentBooked},
+		},
+		status:   map[string]string{},
+		attempts: map[string]int{},
+	}
+	service := &Outbox{Logger: logger.Sugar(), Repository: repo, Destinations: []events.Destination{{Name: "bus", Publisher: poisonPublisher{}}}, BatchSize: 2, MaxAttempts: 3}
+	ctx := context.Background()
+
+	// Mientras el lote son sólo los dos rechazados, e1 no sale
+	for i := 0; i < 2; i++ {
+		report, err := service.Relay(ctx, relayNow)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Failed)
+		assert.Zero(t, report.Dead)
+	}
+	report, err := service.Relay(ctx, relayNow)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Dead)
+
+	report, err = service.Relay(ctx, relayNow)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Published)
+	assert.Equal(t, "PUBLISHED", repo.status["e1"])
+	assert.Equal(t, "FAILED", repo.status["p1"])
+}
+
+// Un destino que ya recibió el evento no lo vuelve a recibir cuando otro falla
+func TestOutbox_Relay_PerDestination(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := &memoryOutbox{
+		events:   []*events.Event{{ID: "e1", Type: events.AppointmentBooked}},
+		status:   map[string]string{},
+		attempts: map[string]int{},
+	}
+	bus := &events.MemoryPublisher{}
+	hl7 := new(MockPublisher)
+	hl7.On("Publish", mock.Anything, mock.Anything).Return(nil, errors.New("connection refused")).Twice()
+	hl7.On("Publish", mock.Anything, mock.Anything).Return(map[string]error{}, nil).Once()
+	service := &Outbox{
+		Logger:       logger.Sugar(),
+		Repository:   repo,
+		Destinations: []events.Destination{{Name: "bus", Publisher: bus}, {Name: "hl7", Publisher: hl7}},
+		BatchSize:    2,
+		MaxAttempts:  5,
+	}
+	ctx := context.Background()
+
+	report, err := service.Relay(ctx, relayNow)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, []string{"bus"}, repo.delivered["e1"])
+
+	// Sólo le falta hl7 y sigue caído: la corrida falla sin contar un intento
+	_, err = service.Relay(ctx, relayNow)
+	assert.Error(t, err)
+	assert.Equal(t, 1, repo.attempts["e1"])
+
+	report, err = service.Relay(ctx, relayNow)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Published)
+
+	assert.Len(t, bus.Events(), 1)
+	hl7.AssertNumberOfCalls(t, "Publish", 3)
+	assert.Equal(t, "PUBLISHED", repo.status["e1"])
+}
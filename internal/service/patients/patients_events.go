@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"go.uber.org/zap"
+)
+
+// EventStore guarda el paciente junto con sus eventos de dominio en una sola
+// transacción. Es opcional: sin él los pacientes se guardan sin eventos.
+// AppendEvents es para la importación, que guarda en lote y escribe los
+// eventos después.
+type EventStore interface {
+	SaveWithEvents(ctx context.Context, p *models.Patient, evts []*events.Event) error
+	DeleteWithEvents(ctx context.Context, id string, evts []*events.Event) error
+	AppendEvents(ctx context.Context, evts []*events.Event) error
+}
+
+// Options agrupa las funciones opcionales del servicio.
+type Options struct {
+	Events EventStore
+}
+
+func (p *Patients) save(ctx context.Context, patient *models.Patient, eventType string) error {
+	if p.Events == nil {
+		return p.PatientsRepository.Save(ctx, patient)
+	}
+	evts, err := patientEvents(patient, eventType)
+	if err != nil {
+		p.log(ctx).Error("Error building patient event", zap.String("type", eventType), zap.Error(err))
+		return err
+	}
+	return p.Events.SaveWithEvents(ctx, patient, evts)
+}
+
+func (p *Patients) delete(ctx context.Context, existing *models.Patient, id string) error {
+	if p.Events == nil || existing == nil {
+		return p.PatientsRepository.Delete(ctx, id)
+	}
+	evts, err := patientEvents(existing, events.PatientDeleted)
+	if err != nil {
+		p.log(ctx).Error("Error building patient event", zap.String("type", events.PatientDeleted), zap.Error(err))
+		return err
+	}
+	return p.Events.DeleteWithEvents(ctx, id, evts)
+}
+
+func patientEvents(patient *models.Patient, eventType string) ([]*events.Event, error) {
+	event, err := events.New(eventType, events.AggregatePatient, patient.ID, patient.ClientID, patient)
+	if err != nil {
+		return nil, err
+	}
+	return []*events.Event{event}, nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockEventStore struct {
+	mock.Mock
+}
+
+func (m *MockEventStore) SaveWithEvents(ctx context.Context, p *models.Patient, evts []*events.Event) error {
+	args := m.Called(ctx, p, evts)
+	return args.Error(0)
+}
+
+func (m *MockEventStore) DeleteWithEvents(ctx context.Context, id string, evts []*events.Event) error {
+	args := m.Called(ctx, id, evts)
+	return args.Error(0)
+}
+
+func (m *MockEventStore) AppendEvents(ctx context.Context, evts []*events.Event) error {
+	args := m.Called(ctx, evts)
+	return args.Error(0)
+}
+
+func eventsOfType(eventType string) interface{} {
+	return mock.MatchedBy(func(evts []*events.Event) bool {
+		return len(evts) == 1 && evts[0].Type == eventType && evts[0].AggregateType == events.AggregatePatient
+	})
+}
+
+func setupEventsTest() (*Patients, *MockPatientsRepository, *MockEventStore) {
+	mockRepo, store := new(MockPatientsRepository), new(MockEventStore)
+	logger, _ := zap.NewDevelopment()
+	service := NewWithOptions(logger.Sugar(), mockRepo, Options{Events: store}).(*Patients)
+	return service, mockRepo, store
+}
+
+func TestPatients_Events(t *testing.T) {
+	service, mockRepo, store := setupEventsTest()
+	ctx := context.Background()
+	request := &models.PatientRequest{ClientID: "client1", FirstName: "Ana", LastName: "Pérez", DocType: "DNI", DocNumber: "12345678", Gender: "F"}
+	store.On("SaveWithEvents", ctx, mock.AnythingOfType("*models.Patient"), eventsOfType(events.PatientRegistered)).Return(nil)
+
+	_, err := service.CreatePatient(ctx, request)
+	require.NoError(t, err)
+
+	existing := &models.Patient{ID: "p1", ClientID: "client1"}
+	mockRepo.On("GetByID", ctx, "p1").Return(existing, nil)
+	store.On("SaveWithEvents", ctx, mock.AnythingOfType("*models.Patient"), eventsOfType(events.PatientUpdated)).Return(nil)
+	request.ID = "p1"
+	_, err = service.UpdatePatient(ctx, request)
+	require.NoError(t, err)
+
+	store.On("DeleteWithEvents", ctx, "p1", eventsOfType(events.PatientDeleted)).Return(nil)
+	require.NoError(t, service.DeletePatient(ctx, "p1"))
+
+	store.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestPatients_ImportPatients_Events(t *testing.T) {
+	service, mockRepo, store := setupEventsTest()
+	ctx := context.Background()
+	mockRepo.On("GetByClientID", ctx, "client1").Return([]*models.Patient{}, nil)
+	// El segundo no se pudo guardar: no tiene que salir su evento
+	mockRepo.On("BatchSave", ctx, mock.Anything).Return(func(patients []*models.Patient) map[string]error {
+		return map[string]error{patients[1].ID: errors.New("unprocessed after 5 attempts")}
+	}, nil)
+	var appended []*events.Event
+	store.On("AppendEvents", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		appended = args.Get(1).([]*events.Event)
+	}).Return(nil)
+
+	report, err := service.ImportPatients(ctx, &models.PatientImportRequest{
+		ClientID: "client1",
+		CSV: "first_name,last_name,doc_type,doc_number,gender,email" + contactColumns + "\n" +
+			"Ana,García,DNI,30000001,F,ana@example.com" + contactValues + "\n" +
+			"Juan,Pérez,DNI,30000002,M,juan@example.com" + contactValues + "\n",
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Created)
+	require.Len(t, appended, 1)
+	assert.Equal(t, events.PatientRegistered, appended[0].Type)
+	assert.Equal(t, report.Rows[0].PatientID, appended[0].AggregateID)
+}
+
+func TestPatients_NormalizePhones_Events(t *testing.T) {
+	service, mockRepo, store := setupEventsTest()
+	ctx := context.Background()
+	mockRepo.On("GetPageByClientID", ctx, "client1", "", int32(phoneMigrationPageSize)).Return([]*models.Patient{
+		{ID: "p1", ClientID: "client1", CountryCode: "54", PhoneNumber: "(011) 15-5555-1234"},
+	}, "", nil)
+	mockRepo.On("Patch", ctx, mock.Anything, mock.MatchedBy(func(p *models.Patient) bool {
+		return p.ID == "p1" && p.PhoneNumber == "+5491155551234"
+	}), eventsOfType(events.PatientUpdated)).Return(true, nil).Once()
+
+	_, err := service.NormalizePhones(ctx, &models.PhoneMigrationRequest{ClientID: "client1"})
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	store.AssertNotCalled(t, "SaveWithEvents", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
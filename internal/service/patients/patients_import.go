@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/MezeLaw/iris-services/internal/documents"
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/openapi"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"go.uber.org/zap"
+)
+
+// createPatientOperation es la operación OpenAPI cuyas reglas sigue cada
+// fila importada.
+const createPatientOperation = "createPatient"
+
+// ErrInvalidImport se devuelve cuando el CSV completo no se puede procesar;
+// los errores de cada fila van al reporte.
+var ErrInvalidImport = errors.New("invalid patient import")
+
+// importFields asigna cada columna reconocida a su campo de PatientRequest.
+// client_id no se toma del CSV: el tenant es el de la solicitud.
+var importFields = map[string]func(*models.PatientRequest, string){
+	"first_name":      func(r *models.PatientRequest, v string) { r.FirstName = v },
+	"last_name":       func(r *models.PatientRequest, v string) { r.LastName = v },
+	"doc_type":        func(r *models.PatientRequest, v string) { r.DocType = strings.ToUpper(v) },
+	"doc_number":      func(r *models.PatientRequest, v string) { r.DocNumber = v },
+	"birth_date":      func(r *models.PatientRequest, v string) { r.BirthDate = v },
+	"gender":          func(r *models.PatientRequest, v string) { r.Gender = strings.ToUpper(v) },
+	"country_code":    func(r *models.PatientRequest, v string) { r.CountryCode = v },
+	"phone_number":    func(r *models.PatientRequest, v string) { r.PhoneNumber = v },
+	"email":           func(r *models.PatientRequest, v string) { r.Email = v },
+	"address_street":  func(r *models.PatientRequest, v string) { r.AddressStreet = v },
+	"address_number":  func(r *models.PatientRequest, v string) { r.AddressNumber = v },
+	"address_city":    func(r *models.PatientRequest, v string) { r.AddressCity = v },
+	"address_country": func(r *models.PatientRequest, v string) { r.AddressCountry = v },
+	"zip_code":        func(r *models.PatientRequest, v string) { r.ZipCode = v },
+}
+
+// ImportPatients carga un CSV de pacientes del tenant. Cada fila se valida
+// como en CreatePatient, se descartan los documentos que ya existen (en la
+// base o más arriba en el mismo archivo) y el resto se guarda con BatchSave.
+func (p *Patients) ImportPatients(ctx context.Context, request *models.PatientImportRequest) (*models.PatientImportReport, error) {
+	ctx, span := tracing.Start(ctx, "service.Patients.ImportPatients")
+	defer span.End()
+	if request.ClientID == "" {
+		return nil, fmt.Errorf("%w: client_id is required", ErrInvalidImport)
+	}
+	reader, header, err := newImportReader(request)
+	if err != nil {
+		p.log(ctx).Error("Invalid patients CSV", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	existing, err := p.PatientsRepository.GetByClientID(ctx, request.ClientID)
+	if err != nil {
+		p.log(ctx).Error("Error getting patients for import", zap.String("clientID", request.ClientID), zap.Error(err))
+		return nil, err
+	}
+	seen := map[string]int{}
+	for _, patient := range existing {
+		seen[documentKey(patient.DocType, patient.DocNumber)] = 0
+		// Los guardados antes del catálogo pueden tener otro formato
+		if docType, docNumber, err := documents.Normalize(patient.DocType, patient.DocNumber); err == nil {
+			seen[documentKey(docType, docNumber)] = 0
+		}
+	}
+
+	report := &models.PatientImportReport{}
+	var (
+		toSave []*models.Patient
+		rows   = map[string]*models.PatientImportRow{}
+	)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			report.Rows = append(report.Rows, &models.PatientImportRow{Row: parseErr.StartLine, Result: models.PatientImportFailed, Reason: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			p.log(ctx).Error("Error reading patients CSV", zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		if isBlank(record) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		patientRequest := mapImportRecord(request.ClientID, header, record)
+		row := &models.PatientImportRow{Row: line, DocType: patientRequest.DocType, DocNumber: patientRequest.DocNumber}
+		report.Rows = append(report.Rows, row)
+
+		if err := validateImportRow(patientRequest); err != nil {
+			row.Result, row.Reason = models.PatientImportFailed, err.Error()
+			continue
+		}
+		key := documentKey(patientRequest.DocType, patientRequest.DocNumber)
+		if firstRow, ok := seen[key]; ok {
+			row.Result = models.PatientImportSkipped
+			row.Reason = "patient already exists"
+			if firstRow > 0 {
+				row.Reason = fmt.Sprintf("duplicate of row %d", firstRow)
+			}
+			continue
+		}
+		seen[key] = line
+
+		patient := p.mapRequestToPatient(patientRequest)
+		row.PatientID = patient.ID
+		rows[patient.ID] = row
+		toSave = append(toSave, patient)
+	}
+
+	failed, err := p.PatientsRepository.BatchSave(ctx, toSave)
+	var registered []*models.Patient
+	if err != nil {
+		p.log(ctx).Error("Error on PatientsRepository.BatchSave", zap.Error(err))
+	}
+	for _, patient := range toSave {
+		row := rows[patient.ID]
+		if saveErr, ok := failed[patient.ID]; ok {
+			row.Result, row.Reason, row.PatientID = models.PatientImportFailed, saveErr.Error(), ""
+			continue
+		}
+		if err != nil {
+			// Cancelado antes de llegar a este lote
+			row.Result, row.Reason, row.PatientID = models.PatientImportFailed, err.Error(), ""
+			continue
+		}
+		row.Result = models.PatientImportCreated
+		registered = append(registered, patient)
+	}
+	p.appendRegistered(ctx, registered)
+
+	for _, row := range report.Rows {
+		switch row.Result {
+		case models.PatientImportCreated:
+			report.Created++
+		case models.PatientImportSkipped:
+			report.Skipped++
+		case models.PatientImportFailed:
+			report.Failed++
+		}
+	}
+	report.Total = len(report.Rows)
+
+	p.log(ctx).Info("Patients import finished",
+		zap.String("clientID", request.ClientID),
+		zap.Int("created", report.Created),
+		zap.Int("skipped", report.Skipped),
+		zap.Int("failed", report.Failed))
+	return report, nil
+}
+
+// newImportReader lee el encabezado y devuelve, por columna, el campo de
+// PatientRequest al que corresponde (o el encabezado original si no
+// corresponde a ninguno).
+func newImportReader(request *models.PatientImportRequest) (*csv.Reader, []string, error) {
+	content := strings.TrimPrefix(request.CSV, "\ufeff")
+	if strings.TrimSpace(content) == "" {
+		return nil, nil, fmt.Errorf("csv is empty")
+	}
+
+	delimiter := request.Delimiter
+	if delimiter == "" {
+		// Excel en español exporta con ';'
+		firstLine, _, _ := strings.Cut(content, "\n")
+		delimiter = ","
+		if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+			delimiter = ";"
+		}
+	}
+	if len(delimiter) != 1 {
+		return nil, nil, fmt.Errorf("invalid delimiter %q", delimiter)
+	}
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.Comma = rune(delimiter[0])
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading header: %v", err)
+	}
+	mapping := map[string]string{}
+	for from, to := range request.Columns {
+		mapping[normalizeColumn(from)] = normalizeColumn(to)
+	}
+
+	header := make([]string, len(columns))
+	present := map[string]bool{}
+	for i, column := range columns {
+		name := normalizeColumn(column)
+		if mapped, ok := mapping[name]; ok {
+			name = mapped
+		}
+		if _, ok := importFields[name]; !ok {
+			name = strings.TrimSpace(column)
+		}
+		header[i] = name
+		present[name] = true
+	}
+	if !present["doc_type"] || !present["doc_number"] {
+		return nil, nil, fmt.Errorf("doc_type and doc_number columns are required")
+	}
+	return reader, header, nil
+}
+
+func mapImportRecord(clientID string, header, record []string) *models.PatientRequest {
+	request := &models.PatientRequest{ClientID: clientID}
+	for i, value := range record {
+		if i >= len(header) {
+			break
+		}
+		value = strings.TrimSpace(value)
+		if set, ok := importFields[header[i]]; ok {
+			set(request, value)
+			continue
+		}
+		if value != "" && header[i] != "" && normalizeColumn(header[i]) != "client_id" {
+			if request.Metadata == nil {
+				request.Metadata = map[string]interface{}{}
+			}
+			request.Metadata[header[i]] = value
+		}
+	}
+	return request
+}
+
+// validateImportRow aplica las reglas de CreatePatient: los obligatorios y
+// los tipos salen del esquema OpenAPI de createPatient, el mismo que valida
+// el alta por la API, y después se normalizan documento y teléfono.
+func validateImportRow(request *models.PatientRequest) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	if missing := openapi.Spec().Missing(createPatientOperation, body); len(missing) > 0 {
+		return fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+	if err := validateGender(request.Gender); err != nil {
+		return err
+	}
+	if err := openapi.Spec().ValidateBody(createPatientOperation, body); err != nil {
+		return err
+	}
+	if err := normalizeDocument(request); err != nil {
+		return err
+	}
+	return normalizePhone(request)
+}
+
+func documentKey(docType, docNumber string) string {
+	return strings.ToUpper(strings.TrimSpace(docType)) + "#" + strings.TrimSpace(docNumber)
+}
+
+func normalizeColumn(column string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(column)), " ", "_")
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// appendRegistered deja en el outbox un PatientRegistered por cada paciente
+// importado. BatchWriteItem no es transaccional, así que los eventos se
+// escriben después de los pacientes: si falla, los pacientes quedan creados
+// y sólo se registra el error.
+func (p *Patients) appendRegistered(ctx context.Context, patients []*models.Patient) {
+	if p.Events == nil || len(patients) == 0 {
+		return
+	}
+	evts := make([]*events.Event, 0, len(patients))
+	for _, patient := range patients {
+		event, err := patientEvents(patient, events.PatientRegistered)
+		if err != nil {
+			p.log(ctx).Error("Error building patient event", zap.String("id", patient.ID), zap.Error(err))
+			continue
+		}
+		evts = append(evts, event...)
+	}
+	if err := p.Events.AppendEvents(ctx, evts); err != nil {
+		p.log(ctx).Error("Error writing imported patients events", zap.Int("count", len(evts)), zap.Error(err))
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// contactColumns y contactValues completan los datos que createPatient
+// exige y que no hacen a cada caso.
+const (
+	contactColumns = ",birth_date,country_code,phone_number,address_street,address_number,address_city,address_country,zip_code"
+	contactValues  = ",1990-01-01,54,1155551234,Corrientes,1234,CABA,Argentina,1043"
+)
+
+const patientsCSV = "\ufeffNombre;Apellido;doc_type;Doc Number;gender;email;obra_social;birth_date;country_code;phone_number;address_street;address_number;address_city;address_country;zip_code\n" +
+	"Ana;García;dni;30123456;f;ana@example.com;OSDE;1990-01-01;54;1155551234;Corrientes;1234;CABA;Argentina;1043\n" +
+	"Juan;Pérez;DNI;28999888;M;juan@example.com;;1985-05-20;54;1155551235;Corrientes;1234;CABA;Argentina;1043\n" +
+	"Ana;García;DNI;30123456;F;otra@example.com;;1990-01-01;54;1155551234;Corrientes;1234;CABA;Argentina;1043\n" +
+	"Sin;Documento;DNI;;M;sin@example.com;;1990-01-01;54;1155551236;Corrientes;1234;CABA;Argentina;1043\n" +
+	"Mal;Genero;DNI;11222333;X;mal@example.com;;1990-01-01;54;1155551237;Corrientes;1234;CABA;Argentina;1043\n" +
+	";;;;;;;;;;;;;;\n" +
+	"Luis;López;DNI;40111222;M;luis@example.com;;1990-01-01;54;1155551238;Corrientes;1234;CABA;Argentina;1043\n" +
+	"Sin;Contacto;DNI;33444555;F;;;;;;;;;;\n"
+
+func TestPatients_ImportPatients(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+
+	mockRepo.On("GetByClientID", ctx, "client123").Return([]*models.Patient{
+		{ID: "existing", ClientID: "client123", DocType: "DNI", DocNumber: "28999888"},
+	}, nil)
+
+	var saved []*models.Patient
+	mockRepo.On("BatchSave", ctx, mock.AnythingOfType("[]*models.Patient")).
+		Run(func(args mock.Arguments) { saved = args.Get(1).([]*models.Patient) }).
+		Return(map[string]error{}, nil).Once()
+
+	report, err := service.ImportPatients(ctx, &models.PatientImportRequest{
+		ClientID: "client123",
+		CSV:      patientsCSV,
+		Columns:  map[string]string{"Nombre": "first_name", "Apellido": "last_name"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 7, report.Total)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, 3, report.Failed)
+
+	require.Len(t, saved, 2)
+	assert.Equal(t, "client123", saved[0].ClientID)
+	assert.Equal(t, "Ana", saved[0].FirstName)
+	assert.Equal(t, "DNI", saved[0].DocType)
+	assert.Equal(t, "F", saved[0].Gender)
+	assert.Equal(t, "OSDE", saved[0].Metadata["obra_social"])
+
+	byRow := map[int]*models.PatientImportRow{}
+	for _, row := range report.Rows {
+		byRow[row.Row] = row
+	}
+	assert.Equal(t, models.PatientImportCreated, byRow[2].Result)
+	assert.Equal(t, saved[0].ID, byRow[2].PatientID)
+	assert.Equal(t, "patient already exists", byRow[3].Reason)
+	assert.Equal(t, "duplicate of row 2", byRow[4].Reason)
+	assert.Equal(t, "missing required fields: doc_number", byRow[5].Reason)
+	assert.Contains(t, byRow[6].Reason, "invalid gender value")
+	assert.Equal(t, models.PatientImportCreated, byRow[8].Result)
+	// Se exigen los mismos datos que en el alta por la API
+	assert.Equal(t, "missing required fields: birth_date, country_code, phone_number, email, address_street, address_number, address_city, address_country, zip_code", byRow[9].Reason)
+}
+
+func TestPatients_ImportPatients_UnprocessedRowsFail(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetByClientID", ctx, "client123").Return([]*models.Patient{}, nil)
+	// El segundo paciente queda en UnprocessedItems tras todos los reintentos
+	mockRepo.On("BatchSave", ctx, mock.Anything).Return(func(patients []*models.Patient) map[string]error {
+		return map[string]error{patients[1].ID: errors.New("unprocessed after 5 attempts")}
+	}, nil)
+
+	report, err := service.ImportPatients(ctx, &models.PatientImportRequest{
+		ClientID: "client123",
+		CSV: "first_name,last_name,doc_type,doc_number,gender,email" + contactColumns + "\n" +
+			"Ana,García,DNI,30000001,F,ana@example.com" + contactValues + "\n" +
+			"Juan,Pérez,DNI,30000002,M,juan@example.com" + contactValues + "\n",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, "unprocessed after 5 attempts", report.Rows[1].Reason)
+	assert.Empty(t, report.Rows[1].PatientID)
+}
+
+func TestPatients_ImportPatients_NormalizesDocuments(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	// Guardado antes del catálogo, con puntos
+	mockRepo.On("GetByClientID", ctx, "client123").Return([]*models.Patient{
+		{ID: "existing", ClientID: "client123", DocType: "DNI", DocNumber: "30.123.456"},
+	}, nil)
+	var saved []*models.Patient
+	mockRepo.On("BatchSave", ctx, mock.Anything).
+		Run(func(args mock.Arguments) { saved = args.Get(1).([]*models.Patient) }).
+		Return(map[string]error{}, nil)
+
+	report, err := service.ImportPatients(ctx, &models.PatientImportRequest{
+		ClientID: "client123",
+		CSV: "first_name,last_name,doc_type,doc_number,gender,email" + contactColumns + "\n" +
+			"Ana,García,d.n.i.,30123456,F,ana@example.com" + contactValues + "\n" +
+			"Juan,Pérez,CUIT,20-12345678-5,M,juan@example.com" + contactValues + "\n" +
+			"Luz,Díaz,cuil,20-12345678-6,F,luz@example.com" + contactValues + "\n",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "patient already exists", report.Rows[0].Reason)
+	assert.Contains(t, report.Rows[1].Reason, "invalid document number")
+	require.Len(t, saved, 1)
+	assert.Equal(t, "CUIL", saved[0].DocType)
+	assert.Equal(t, "20123456786", saved[0].DocNumber)
+}
+
+func TestPatients_ImportPatients_InvalidCSV(t *testing.T) {
+	service, _ := setupTest()
+
+	tests := []*models.PatientImportRequest{
+		{CSV: "doc_type,doc_number\n"},
+		{ClientID: "client123"},
+		{ClientID: "client123", CSV: "first_name,last_name\nAna,García\n"},
+		{ClientID: "client123", CSV: "doc_type,doc_number\n", Delimiter: "||"},
+	}
+	for _, tt := range tests {
+		_, err := service.ImportPatients(context.Background(), tt)
+		assert.ErrorIs(t, err, ErrInvalidImport)
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/jsonpatch"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"go.uber.org/zap"
+)
+
+// maxPatchAttempts es cuántas veces se reaplica un parche cuando otro proceso
+// modificó el paciente entre la lectura y la escritura.
+const maxPatchAttempts = 3
+
+var (
+	ErrPatientNotFound = errors.New("patient not found")
+	ErrPatchConflict   = errors.New("patient was modified concurrently")
+	ErrRequiredField   = errors.New("required field cannot be removed")
+)
+
+// readOnlyFields son los miembros de PatientRequest que un parche no puede
+// cambiar.
+var readOnlyFields = []string{"id", "client_id", "no_show_count", "created_at", "updated_at"}
+
+// PatchPatient aplica un JSON Merge Patch o un JSON Patch (según contentType)
+// sobre la versión guardada del paciente y escribe sólo lo que cambió. El
+// resultado se valida y normaliza como en UpdatePatient. Si otro proceso
+// modificó el paciente en el medio, el parche se vuelve a aplicar sobre la
+// versión nueva.
+func (p *Patients) PatchPatient(ctx context.Context, id, contentType string, patch []byte) (*models.PatientRequest, error) {
+	ctx, span := tracing.Start(ctx, "service.Patients.PatchPatient")
+	defer span.End()
+	if id == "" {
+		p.log(ctx).Error("Error: Missing patient ID for patch")
+		return nil, fmt.Errorf("patient ID is required for patch")
+	}
+
+	for attempt := 1; attempt <= maxPatchAttempts; attempt++ {
+		existing, err := p.PatientsRepository.GetByID(ctx, id)
+		if err != nil {
+			p.log(ctx).Error("Error fetching patient to patch", zap.String("id", id), zap.Error(err))
+			return nil, fmt.Errorf("failed to find patient with ID %s: %w", id, err)
+		}
+		if existing == nil {
+			return nil, fmt.Errorf("%w: %s", ErrPatientNotFound, id)
+		}
+
+		request, err := p.applyPatch(existing, contentType, patch)
+		if err != nil {
+			p.log(ctx).Error("Invalid patient patch", zap.String("id", id), zap.Error(err))
+			return nil, err
+		}
+		updated := updatePatient(existing, request)
+
+		var evts []*events.Event
+		if p.Events != nil {
+			if evts, err = patientEvents(updated, events.PatientUpdated); err != nil {
+				p.log(ctx).Error("Error building patient event", zap.String("type", events.PatientUpdated), zap.Error(err))
+				return nil, err
+			}
+		}
+		ok, err := p.PatientsRepository.Patch(ctx, existing, updated, evts)
+		if err != nil {
+			p.log(ctx).Error("Error patching patient", zap.String("id", id), zap.Error(err))
+			return nil, fmt.Errorf("failed to patch patient: %w", err)
+		}
+		if !ok {
+			p.log(ctx).Info("Patient changed while patching, retrying", zap.String("id", id), zap.Int("attempt", attempt))
+			continue
+		}
+
+		p.log(ctx).Info("Patient patched successfully", zap.String("id", id))
+		return p.mapPatientToRequest(updated), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPatchConflict, id)
+}
+
+// applyPatch devuelve el pedido que resulta de aplicar el parche sobre
+// existing, validado y normalizado.
+func (p *Patients) applyPatch(existing *models.Patient, contentType string, patch []byte) (*models.PatientRequest, error) {
+	current := p.mapPatientToRequest(existing)
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := jsonpatch.Apply(contentType, doc, patch)
+	if err != nil {
+		return nil, err
+	}
+	var request models.PatientRequest
+	if err := jsonpatch.Decode(doc, patched, &request, readOnlyFields...); err != nil {
+		return nil, err
+	}
+	if err := checkRequired(current, &request); err != nil {
+		return nil, err
+	}
+
+	// Un teléfono nuevo sin su versión original reemplaza también la original
+	if request.PhoneNumber != current.PhoneNumber && request.PhoneNumberRaw == current.PhoneNumberRaw {
+		request.CountryCodeRaw, request.PhoneNumberRaw = "", ""
+	}
+	if err := validateGender(request.Gender); err != nil {
+		return nil, fmt.Errorf("%w: %v", jsonpatch.ErrInvalid, err)
+	}
+	if err := normalizeDocument(&request); err != nil {
+		return nil, err
+	}
+	if err := normalizePhone(&request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// checkRequired rechaza los parches que vacían un campo obligatorio
+// (required:"true") que el paciente tenía cargado.
+func checkRequired(before, after *models.PatientRequest) error {
+	b, a := reflect.ValueOf(before).Elem(), reflect.ValueOf(after).Elem()
+	for i := 0; i < b.NumField(); i++ {
+		field := b.Type().Field(i)
+		if field.Tag.Get("required") != "true" {
+			continue
+		}
+		if !b.Field(i).IsZero() && a.Field(i).IsZero() {
+			return fmt.Errorf("%w: %s", ErrRequiredField, field.Tag.Get("json"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/jsonpatch"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func (m *MockPatientsRepository) Patch(ctx context.Context, before, after *models.Patient, evts []*events.Event) (bool, error) {
+	args := m.Called(ctx, before, after, evts)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestPatients_PatchPatient_MergePatch(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	existing := createSamplePatient("123")
+	existing.PhoneNumber = "+5491155551234"
+	existing.NoShowCount = 2
+	mockRepo.On("GetByID", ctx, "123").Return(existing, nil)
+	mockRepo.On("Patch", ctx, existing, mock.MatchedBy(func(p *models.Patient) bool {
+		// Sólo cambia el email; el resto queda como estaba y sube la versión
+		return p.Email == "john@example.org" && p.FirstName == "John" && p.PhoneNumber == "+5491155551234" &&
+			p.NoShowCount == 2 && p.CreatedAt == existing.CreatedAt && p.Version == existing.Version+1
+	}), []*events.Event(nil)).Return(true, nil)
+
+	result, err := service.PatchPatient(ctx, "123", jsonpatch.MergePatchType, []byte(`{"email":"john@example.org"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, "john@example.org", result.Email)
+	assert.Equal(t, "Doe", result.LastName)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatients_PatchPatient_JSONPatch(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	existing := createSamplePatient("123")
+	mockRepo.On("GetByID", ctx, "123").Return(existing, nil)
+	mockRepo.On("Patch", ctx, existing, mock.MatchedBy(func(p *models.Patient) bool {
+		// El teléfono nuevo se normaliza y reemplaza también el original
+		return p.PhoneNumber == "+541166667777" && p.PhoneNumberRaw == "11 6666-7777" && p.Metadata["key"] == nil
+	}), mock.Anything).Return(true, nil)
+
+	patch := `[{"op":"replace","path":"/phone_number","value":"11 6666-7777"},{"op":"remove","path":"/metadata/key"}]`
+	_, err := service.PatchPatient(ctx, "123", jsonpatch.JSONPatchType, []byte(patch))
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatients_PatchPatient_Invalid(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetByID", ctx, "123").Return(createSamplePatient("123"), nil)
+
+	cases := []struct {
+		contentType string
+		patch       string
+		err         error
+	}{
+		{jsonpatch.MergePatchType, `{"id":"456"}`, jsonpatch.ErrReadOnly},
+		{jsonpatch.MergePatchType, `{"no_show_count":0,"client_id":"other"}`, jsonpatch.ErrReadOnly},
+		{jsonpatch.MergePatchType, `{"first_name":null}`, ErrRequiredField},
+		{jsonpatch.MergePatchType, `{"nickname":"Johnny"}`, jsonpatch.ErrInvalid},
+		{jsonpatch.MergePatchType, `not json`, jsonpatch.ErrInvalid},
+		{"text/plain", `{}`, jsonpatch.ErrUnsupportedType},
+	}
+	for _, c := range cases {
+		_, err := service.PatchPatient(ctx, "123", c.contentType, []byte(c.patch))
+		assert.ErrorIs(t, err, c.err, "patch %s", c.patch)
+	}
+
+	_, err := service.PatchPatient(ctx, "123", "", []byte(`{"gender":"X"}`))
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPatients_PatchPatient_NotFound(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetByID", ctx, "123").Return(nil, nil)
+
+	_, err := service.PatchPatient(ctx, "123", "", []byte(`{}`))
+
+	assert.ErrorIs(t, err, ErrPatientNotFound)
+}
+
+func TestPatients_PatchPatient_RetriesOnConflict(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	stale := createSamplePatient("123")
+	fresh := createSamplePatient("123")
+	fresh.LastName = "Smith"
+	mockRepo.On("GetByID", ctx, "123").Return(stale, nil).Once()
+	mockRepo.On("GetByID", ctx, "123").Return(fresh, nil)
+	mockRepo.On("Patch", ctx, stale, mock.Anything, mock.Anything).Return(false, nil).Once()
+	mockRepo.On("Patch", ctx, fresh, mock.MatchedBy(func(p *models.Patient) bool {
+		// El parche se reaplica sobre la versión nueva
+		return p.LastName == "Smith" && p.Email == "john@example.org"
+	}), mock.Anything).Return(true, nil)
+
+	result, err := service.PatchPatient(ctx, "123", "", []byte(`{"email":"john@example.org"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, "Smith", result.LastName)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatients_PatchPatient_Conflict(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetByID", ctx, "123").Return(createSamplePatient("123"), nil)
+	mockRepo.On("Patch", ctx, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+
+	_, err := service.PatchPatient(ctx, "123", "", []byte(`{"email":"john@example.org"}`))
+
+	assert.ErrorIs(t, err, ErrPatchConflict)
+	mockRepo.AssertNumberOfCalls(t, "Patch", maxPatchAttempts)
+
+	mockRepo.ExpectedCalls = nil
+	mockRepo.On("GetByID", ctx, "123").Return(createSamplePatient("123"), nil)
+	mockRepo.On("Patch", ctx, mock.Anything, mock.Anything, mock.Anything).Return(false, errors.New("dynamo down"))
+	_, err = service.PatchPatient(ctx, "123", "", []byte(`{"email":"john@example.org"}`))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrPatchConflict)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"go.uber.org/zap"
+)
+
+const phoneMigrationPageSize = 200
+
+// NormalizePhones pasa a E.164 los teléfonos de los pacientes del cliente,
+// guardando lo que había en los campos raw. Los números que no se pueden
+// interpretar quedan como estaban y se informan para corregirlos a mano.
+// Se puede correr más de una vez: los que ya están normalizados no se tocan.
+func (p *Patients) NormalizePhones(ctx context.Context, request *models.PhoneMigrationRequest) (*models.PhoneMigrationReport, error) {
+	ctx, span := tracing.Start(ctx, "service.Patients.NormalizePhones")
+	defer span.End()
+	if request.ClientID == "" {
+		return nil, fmt.Errorf("client_id is required")
+	}
+
+	report := &models.PhoneMigrationReport{Cursor: request.Cursor}
+	for {
+		patients, next, err := p.PatientsRepository.GetPageByClientID(ctx, request.ClientID, report.Cursor, phoneMigrationPageSize)
+		if err != nil {
+			p.log(ctx).Error("Error reading patients to normalize phones", zap.String("clientID", request.ClientID), zap.Error(err))
+			return report, err
+		}
+		for _, patient := range patients {
+			report.Scanned++
+			if err := p.normalizePatientPhone(ctx, patient, request.DryRun, report); err != nil {
+				return report, err
+			}
+		}
+		report.Cursor = next
+		if next == "" {
+			p.log(ctx).Info("Phones normalized", zap.String("clientID", request.ClientID),
+				zap.Int("normalized", report.Normalized), zap.Int("failed", report.Failed), zap.Bool("dryRun", request.DryRun))
+			return report, nil
+		}
+	}
+}
+
+func (p *Patients) normalizePatientPhone(ctx context.Context, patient *models.Patient, dryRun bool, report *models.PhoneMigrationReport) error {
+	request := &models.PatientRequest{
+		CountryCode:    patient.CountryCode,
+		PhoneNumber:    patient.PhoneNumber,
+		CountryCodeRaw: patient.CountryCodeRaw,
+		PhoneNumberRaw: patient.PhoneNumberRaw,
+	}
+	if err := normalizePhone(request); err != nil {
+		report.Failed++
+		report.Failures = append(report.Failures, &models.PhoneMigrationFailure{
+			PatientID:   patient.ID,
+			CountryCode: patient.CountryCode,
+			PhoneNumber: patient.PhoneNumber,
+			Reason:      err.Error(),
+		})
+		return nil
+	}
+	if request.PhoneNumber == patient.PhoneNumber && request.CountryCode == patient.CountryCode {
+		report.Unchanged++
+		return nil
+	}
+
+	if dryRun {
+		report.Normalized++
+		return nil
+	}
+	updated := *patient
+	updated.CountryCode, updated.PhoneNumber = request.CountryCode, request.PhoneNumber
+	updated.CountryCodeRaw, updated.PhoneNumberRaw = request.CountryCodeRaw, request.PhoneNumberRaw
+	updated.UpdatedAt = time.Now().Format(time.RFC3339)
+	updated.Version++
+
+	var evts []*events.Event
+	if p.Events != nil {
+		var err error
+		if evts, err = patientEvents(&updated, events.PatientUpdated); err != nil {
+			p.log(ctx).Error("Error building patient event", zap.String("type", events.PatientUpdated), zap.Error(err))
+			return err
+		}
+	}
+	ok, err := p.PatientsRepository.Patch(ctx, patient, &updated, evts)
+	if err != nil {
+		p.log(ctx).Error("Error saving normalized phone", zap.String("id", patient.ID), zap.Error(err))
+		return fmt.Errorf("failed to save patient %s: %w", patient.ID, err)
+	}
+	// Si alguien editó al paciente mientras corría la migración no se pisa
+	// su cambio: queda informado y la próxima corrida lo vuelve a evaluar
+	if !ok {
+		report.Failed++
+		report.Failures = append(report.Failures, &models.PhoneMigrationFailure{
+			PatientID:   patient.ID,
+			CountryCode: patient.CountryCode,
+			PhoneNumber: patient.PhoneNumber,
+			Reason:      ErrPatchConflict.Error(),
+		})
+		return nil
+	}
+	report.Normalized++
+	return nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/phones"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatients_CreatePatient_NormalizesPhone(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	req := createSamplePatientRequest()
+	req.CountryCode, req.PhoneNumber = "+54", "011 15 5555-1234"
+
+	mockRepo.On("Save", ctx, mock.MatchedBy(func(p *models.Patient) bool {
+		return p.CountryCode == "54" && p.PhoneNumber == "+5491155551234" &&
+			p.CountryCodeRaw == "+54" && p.PhoneNumberRaw == "011 15 5555-1234"
+	})).Return(nil)
+
+	result, err := service.CreatePatient(ctx, req)
+
+	require.NoError(t, err)
+	assert.Equal(t, "+5491155551234", result.PhoneNumber)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatients_CreatePatient_InvalidPhone(t *testing.T) {
+	service, mockRepo := setupTest()
+	req := createSamplePatientRequest()
+	req.PhoneNumber = "15 5555"
+
+	result, err := service.CreatePatient(context.Background(), req)
+
+	assert.ErrorIs(t, err, phones.ErrInvalidNumber)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPatients_NormalizePhones_Conflict(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetPageByClientID", ctx, "client123", "", int32(phoneMigrationPageSize)).Return([]*models.Patient{
+		{ID: "p1", CountryCode: "54", PhoneNumber: "11 5555-1234"},
+	}, "", nil)
+	mockRepo.On("Patch", ctx, mock.Anything, mock.Anything, mock.Anything).Return(false, nil).Once()
+
+	report, err := service.NormalizePhones(ctx, &models.PhoneMigrationRequest{ClientID: "client123"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Normalized)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, ErrPatchConflict.Error(), report.Failures[0].Reason)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatients_UpdatePatient_KeepsRawPhone(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	existing := createSamplePatient("patient123")
+	existing.PhoneNumber, existing.CountryCodeRaw, existing.PhoneNumberRaw = "+541155551234", "54", "11 5555-1234"
+	mockRepo.On("GetByID", ctx, "patient123").Return(existing, nil)
+	mockRepo.On("Save", ctx, mock.MatchedBy(func(p *models.Patient) bool {
+		return p.PhoneNumber == "+541155551234" && p.PhoneNumberRaw == "11 5555-1234"
+	})).Return(nil)
+
+	// Reenvía lo que devolvió el GET, sin los campos raw
+	req := createSamplePatientRequest()
+	req.ID, req.PhoneNumber = "patient123", "+541155551234"
+
+	_, err := service.UpdatePatient(ctx, req)
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatients_NormalizePhones(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetPageByClientID", ctx, "client123", "", int32(phoneMigrationPageSize)).Return([]*models.Patient{
+		{ID: "p1", CountryCode: "54", PhoneNumber: "(011) 15-5555-1234"},
+		{ID: "p2", CountryCode: "54", PhoneNumber: "+5491155551234"},
+		{ID: "p3", CountryCode: "54", PhoneNumber: "llamar a la tarde"},
+	}, "next", nil).Once()
+	mockRepo.On("GetPageByClientID", ctx, "client123", "next", int32(phoneMigrationPageSize)).Return([]*models.Patient{
+		{ID: "p4"},
+	}, "", nil).Once()
+	mockRepo.On("Patch", ctx, mock.MatchedBy(func(p *models.Patient) bool {
+		return p.ID == "p1" && p.PhoneNumber == "(011) 15-5555-1234"
+	}), mock.MatchedBy(func(p *models.Patient) bool {
+		return p.ID == "p1" && p.PhoneNumber == "+5491155551234" && p.PhoneNumberRaw == "(011) 15-5555-1234" && p.Version == 1
+	}), []*events.Event(nil)).Return(true, nil).Once()
+
+	report, err := service.NormalizePhones(ctx, &models.PhoneMigrationRequest{ClientID: "client123"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Scanned)
+	assert.Equal(t, 1, report.Normalized)
+	assert.Equal(t, 2, report.Unchanged)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, "p3", report.Failures[0].PatientID)
+	assert.Empty(t, report.Cursor)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatients_NormalizePhones_DryRun(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("GetPageByClientID", ctx, "client123", "", int32(phoneMigrationPageSize)).Return([]*models.Patient{
+		{ID: "p1", CountryCode: "54", PhoneNumber: "11 5555-1234"},
+	}, "", nil)
+
+	report, err := service.NormalizePhones(ctx, &models.PhoneMigrationRequest{ClientID: "client123", DryRun: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Normalized)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
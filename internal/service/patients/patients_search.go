@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/textnorm"
+	"github.com/MezeLaw/iris-services/internal/tracing"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidSearch se devuelve cuando la búsqueda no tiene un criterio
+// válido.
+var ErrInvalidSearch = errors.New("invalid patient search")
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// minNamePrefix evita que una sola letra recorra medio índice
+	minNamePrefix = 2
+)
+
+// SearchPatients busca pacientes del tenant por prefijo de nombre o
+// apellido, o por teléfono o email exactos, usando el índice de búsqueda.
+func (p *Patients) SearchPatients(ctx context.Context, request *models.PatientSearchRequest) (*models.PatientSearchResult, error) {
+	ctx, span := tracing.Start(ctx, "service.Patients.SearchPatients")
+	defer span.End()
+	if request.ClientID == "" {
+		return nil, fmt.Errorf("%w: client_id is required", ErrInvalidSearch)
+	}
+	field, value, err := searchCriteria(request)
+	if err != nil {
+		return nil, err
+	}
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	patients, next, err := p.PatientsRepository.Search(ctx, request.ClientID, field, value, request.Cursor, int32(limit))
+	if err != nil {
+		p.log(ctx).Error("Error on PatientsRepository.Search", zap.String("clientID", request.ClientID), zap.String("field", field), zap.Error(err))
+		return nil, err
+	}
+
+	result := &models.PatientSearchResult{
+		Patients:   make([]*models.PatientRequest, 0, len(patients)),
+		NextCursor: next,
+	}
+	for _, patient := range patients {
+		result.Patients = append(result.Patients, p.mapPatientToRequest(patient))
+	}
+	return result, nil
+}
+
+func searchCriteria(request *models.PatientSearchRequest) (string, string, error) {
+	var criteria [][2]string
+	if value := strings.TrimSpace(request.Name); value != "" {
+		criteria = append(criteria, [2]string{models.PatientSearchByName, value})
+	}
+	if value := strings.TrimSpace(request.Phone); value != "" {
+		criteria = append(criteria, [2]string{models.PatientSearchByPhone, value})
+	}
+	if value := strings.TrimSpace(request.Email); value != "" {
+		criteria = append(criteria, [2]string{models.PatientSearchByEmail, value})
+	}
+	if len(criteria) != 1 {
+		return "", "", fmt.Errorf("%w: exactly one of name, phone or email is required", ErrInvalidSearch)
+	}
+
+	field, value := criteria[0][0], criteria[0][1]
+	switch field {
+	case models.PatientSearchByName:
+		longest := 0
+		for _, token := range textnorm.Tokens(value) {
+			longest = max(longest, len([]rune(token)))
+		}
+		if longest < minNamePrefix {
+			return "", "", fmt.Errorf("%w: name must have at least %d letters", ErrInvalidSearch, minNamePrefix)
+		}
+	case models.PatientSearchByPhone:
+		if !strings.ContainsAny(value, "0123456789") {
+			return "", "", fmt.Errorf("%w: phone must have digits", ErrInvalidSearch)
+		}
+	}
+	return field, value, nil
+}
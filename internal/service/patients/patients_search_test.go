@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatients_SearchPatients(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("Search", ctx, "client123", models.PatientSearchByName, "Mart", "cursor1", int32(defaultSearchLimit)).
+		Return([]*models.Patient{{ID: "p1", ClientID: "client123", FirstName: "Ana", LastName: "Martínez"}}, "cursor2", nil)
+
+	result, err := service.SearchPatients(ctx, &models.PatientSearchRequest{ClientID: "client123", Name: " Mart ", Cursor: "cursor1"})
+
+	require.NoError(t, err)
+	require.Len(t, result.Patients, 1)
+	assert.Equal(t, "Martínez", result.Patients[0].LastName)
+	assert.Equal(t, "cursor2", result.NextCursor)
+}
+
+func TestPatients_SearchPatients_LimitIsCapped(t *testing.T) {
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	mockRepo.On("Search", ctx, "client123", models.PatientSearchByPhone, "+54 11 5555-1234", "", int32(maxSearchLimit)).
+		Return([]*models.Patient{}, "", nil)
+
+	result, err := service.SearchPatients(ctx, &models.PatientSearchRequest{ClientID: "client123", Phone: "+54 11 5555-1234", Limit: 1000})
+
+	require.NoError(t, err)
+	assert.Empty(t, result.Patients)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatients_SearchPatients_Invalid(t *testing.T) {
+	service, mockRepo := setupTest()
+
+	tests := []*models.PatientSearchRequest{
+		{Name: "Ana"},
+		{ClientID: "client123"},
+		{ClientID: "client123", Name: "Ana", Email: "ana@example.com"},
+		{ClientID: "client123", Name: "á"},
+		{ClientID: "client123", Phone: "sin número"},
+	}
+	for _, tt := range tests {
+		_, err := service.SearchPatients(context.Background(), tt)
+		assert.ErrorIs(t, err, ErrInvalidSearch)
+	}
+	mockRepo.AssertNotCalled(t, "Search")
+}
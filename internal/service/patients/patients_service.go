@@ -3,9 +3,15 @@ package service
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/MezeLaw/iris-services/internal/documents"
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/logging"
 	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/phones"
+	"github.com/MezeLaw/iris-services/internal/tracing"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
@@ -16,50 +22,76 @@ type PatientsRepository interface {
 	GetByClientID(ctx context.Context, clientID string) ([]*models.Patient, error)
 	GetByDocument(ctx context.Context, docType, docNumber string) (*models.Patient, error)
 	Delete(ctx context.Context, id string) error
+	BatchSave(ctx context.Context, patients []*models.Patient) (map[string]error, error)
+	Search(ctx context.Context, clientID, field, value, cursor string, limit int32) ([]*models.Patient, string, error)
+	GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Patient, string, error)
+	Patch(ctx context.Context, before, after *models.Patient, evts []*events.Event) (bool, error)
 }
 
 type PatientsService interface {
 	CreatePatient(context.Context, *models.PatientRequest) (*models.PatientRequest, error)
 	GetPatient(context.Context, *models.GetPatientRequest) (*models.PatientRequest, error)
-	GetAllPatients(context.Context, string) ([]*models.PatientRequest, error)
-	UpdatePatient(context.Context, *models.PatientRequest) error
+	GetAllPatients(ctx context.Context, clientID, cursor string, limit int) ([]*models.PatientRequest, string, error)
+	UpdatePatient(context.Context, *models.PatientRequest) (*models.PatientRequest, error)
+	PatchPatient(ctx context.Context, id, contentType string, patch []byte) (*models.PatientRequest, error)
 	DeletePatient(context.Context, string) error
+	ImportPatients(context.Context, *models.PatientImportRequest) (*models.PatientImportReport, error)
+	SearchPatients(context.Context, *models.PatientSearchRequest) (*models.PatientSearchResult, error)
+	NormalizePhones(context.Context, *models.PhoneMigrationRequest) (*models.PhoneMigrationReport, error)
 }
 
 type Patients struct {
 	Logger             *zap.SugaredLogger
 	PatientsRepository PatientsRepository
+	Events             EventStore
 }
 
 func New(logger *zap.SugaredLogger, repository PatientsRepository) PatientsService {
+	return NewWithOptions(logger, repository, Options{})
+}
+
+func NewWithOptions(logger *zap.SugaredLogger, repository PatientsRepository, options Options) PatientsService {
 	return &Patients{
 		Logger:             logger,
 		PatientsRepository: repository,
+		Events:             options.Events,
 	}
 }
 
 func (p *Patients) CreatePatient(ctx context.Context, request *models.PatientRequest) (*models.PatientRequest, error) {
+	ctx, span := tracing.Start(ctx, "service.Patients.CreatePatient")
+	defer span.End()
 	// Validar género
 	if err := validateGender(request.Gender); err != nil {
-		p.Logger.Error("Invalid gender value", zap.String("gender", request.Gender))
+		p.log(ctx).Error("Invalid gender value", zap.String("gender", request.Gender))
+		return nil, err
+	}
+	if err := normalizeDocument(request); err != nil {
+		p.log(ctx).Error("Invalid document", zap.String("docType", request.DocType), zap.Error(err))
+		return nil, err
+	}
+	if err := normalizePhone(request); err != nil {
+		p.log(ctx).Error("Invalid phone", zap.String("phone", request.PhoneNumber), zap.Error(err))
 		return nil, err
 	}
 
 	patient := p.mapRequestToPatient(request)
-	if err := p.PatientsRepository.Save(ctx, patient); err != nil {
-		p.Logger.Error("Error on PatientsRepository.Save", zap.Error(err))
+	if err := p.save(ctx, patient, events.PatientRegistered); err != nil {
+		p.log(ctx).Error("Error on PatientsRepository.Save", zap.Error(err))
 		return nil, err
 	}
-	return request, nil
+	return p.mapPatientToRequest(patient), nil
 }
 
 func (p *Patients) GetPatient(ctx context.Context, params *models.GetPatientRequest) (*models.PatientRequest, error) {
+	ctx, span := tracing.Start(ctx, "service.Patients.GetPatient")
+	defer span.End()
 	// Si se proporciona un ID, buscar por ID
 	if params.ID != "" {
-		p.Logger.Info("Getting patient by ID", zap.String("id", params.ID))
+		p.log(ctx).Info("Getting patient by ID", zap.String("id", params.ID))
 		patient, err := p.PatientsRepository.GetByID(ctx, params.ID)
 		if err != nil {
-			p.Logger.Error("Error getting patient by ID", zap.String("id", params.ID), zap.Error(err))
+			p.log(ctx).Error("Error getting patient by ID", zap.String("id", params.ID), zap.Error(err))
 			return nil, err
 		}
 		return p.mapPatientToRequest(patient), nil
@@ -67,13 +99,13 @@ func (p *Patients) GetPatient(ctx context.Context, params *models.GetPatientRequ
 
 	// Si se proporcionan DocType y DocNumber, buscar por documento
 	if params.DocType != "" && params.DocNumber != "" {
-		p.Logger.Info("Getting patient by Document",
+		p.log(ctx).Info("Getting patient by Document",
 			zap.String("docType", params.DocType),
 			zap.String("docNumber", params.DocNumber))
 
 		patient, err := p.PatientsRepository.GetByDocument(ctx, params.DocType, params.DocNumber)
 		if err != nil {
-			p.Logger.Error("Error getting patient by Document",
+			p.log(ctx).Error("Error getting patient by Document",
 				zap.String("docType", params.DocType),
 				zap.String("docNumber", params.DocNumber),
 				zap.Error(err))
@@ -84,24 +116,35 @@ func (p *Patients) GetPatient(ctx context.Context, params *models.GetPatientRequ
 	}
 
 	// Si no se proporcionó ningún parámetro válido para la búsqueda
-	p.Logger.Error("Invalid parameters for GetPatient")
+	p.log(ctx).Error("Invalid parameters for GetPatient")
 	return nil, fmt.Errorf("invalid parameters: must provide ID, ClientID, or DocType/DocNumber")
 }
 
-func (p *Patients) GetAllPatients(ctx context.Context, identifier string) ([]*models.PatientRequest, error) {
+// GetAllPatients devuelve una página de hasta limit pacientes del cliente y
+// el cursor de la siguiente ("" en la última). El límite se acota igual que
+// en la búsqueda.
+func (p *Patients) GetAllPatients(ctx context.Context, identifier, cursor string, limit int) ([]*models.PatientRequest, string, error) {
+	ctx, span := tracing.Start(ctx, "service.Patients.GetAllPatients")
+	defer span.End()
 	// Verificar que el identificador no esté vacío
 	if identifier == "" {
-		p.Logger.Error("Error: empty client-id provided to GetAllPatients")
-		return nil, fmt.Errorf("client-id cannot be empty")
+		p.log(ctx).Error("Error: empty client-id provided to GetAllPatients")
+		return nil, "", fmt.Errorf("client-id cannot be empty")
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
 	}
 
-	p.Logger.Info("Getting all patients by ClientID", zap.String("clientID", identifier))
+	p.log(ctx).Info("Getting patients by ClientID", zap.String("clientID", identifier), zap.Int("limit", limit))
 
-	// Obtener pacientes del repositorio usando el cliente ID
-	patients, err := p.PatientsRepository.GetByClientID(ctx, identifier)
+	// Obtener la página de pacientes del repositorio usando el cliente ID
+	patients, next, err := p.PatientsRepository.GetPageByClientID(ctx, identifier, cursor, int32(limit))
 	if err != nil {
-		p.Logger.Error("Error getting patients by ClientID", zap.String("clientID", identifier), zap.Error(err))
-		return nil, err
+		p.log(ctx).Error("Error getting patients by ClientID", zap.String("clientID", identifier), zap.Error(err))
+		return nil, "", err
 	}
 
 	// Mapear los pacientes del modelo de BD al modelo de request
@@ -110,35 +153,60 @@ func (p *Patients) GetAllPatients(ctx context.Context, identifier string) ([]*mo
 		patientRequests = append(patientRequests, p.mapPatientToRequest(patient))
 	}
 
-	p.Logger.Info("Successfully retrieved patients", zap.Int("count", len(patientRequests)))
-	return patientRequests, nil
+	p.log(ctx).Info("Successfully retrieved patients", zap.Int("count", len(patientRequests)))
+	return patientRequests, next, nil
 }
 
-func (p *Patients) UpdatePatient(ctx context.Context, request *models.PatientRequest) error {
+func (p *Patients) UpdatePatient(ctx context.Context, request *models.PatientRequest) (*models.PatientRequest, error) {
+	ctx, span := tracing.Start(ctx, "service.Patients.UpdatePatient")
+	defer span.End()
 	// Verificar que el paciente tenga un ID
 	if request.ID == "" {
-		p.Logger.Error("Error: Missing patient ID for update")
-		return fmt.Errorf("patient ID is required for update")
+		p.log(ctx).Error("Error: Missing patient ID for update")
+		return nil, fmt.Errorf("patient ID is required for update")
 	}
 
 	// Validar género
 	if err := validateGender(request.Gender); err != nil {
-		p.Logger.Error("Invalid gender value", zap.String("gender", request.Gender))
-		return err
+		p.log(ctx).Error("Invalid gender value", zap.String("gender", request.Gender))
+		return nil, err
+	}
+	if err := normalizeDocument(request); err != nil {
+		p.log(ctx).Error("Invalid document", zap.String("docType", request.DocType), zap.Error(err))
+		return nil, err
+	}
+	if err := normalizePhone(request); err != nil {
+		p.log(ctx).Error("Invalid phone", zap.String("phone", request.PhoneNumber), zap.Error(err))
+		return nil, err
 	}
 
-	p.Logger.Info("Updating patient", zap.String("id", request.ID))
+	p.log(ctx).Info("Updating patient", zap.String("id", request.ID))
 
 	// Obtener el paciente existente
 	existingPatient, err := p.PatientsRepository.GetByID(ctx, request.ID)
 	if err != nil {
-		p.Logger.Error("Error fetching patient to update", zap.String("id", request.ID), zap.Error(err))
-		return fmt.Errorf("failed to find patient with ID %s: %w", request.ID, err)
+		p.log(ctx).Error("Error fetching patient to update", zap.String("id", request.ID), zap.Error(err))
+		return nil, fmt.Errorf("failed to find patient with ID %s: %w", request.ID, err)
 	}
 
 	// Actualizar los campos del paciente existente
-	updatedPatient := &models.Patient{
-		ID:             existingPatient.ID,
+	updatedPatient := updatePatient(existingPatient, request)
+
+	// Guardar el paciente actualizado
+	if err := p.save(ctx, updatedPatient, events.PatientUpdated); err != nil {
+		p.log(ctx).Error("Error updating patient", zap.String("id", request.ID), zap.Error(err))
+		return nil, fmt.Errorf("failed to update patient: %w", err)
+	}
+
+	p.log(ctx).Info("Patient updated successfully", zap.String("id", request.ID))
+	return p.mapPatientToRequest(updatedPatient), nil
+}
+
+// updatePatient arma la nueva versión de existing con los datos del pedido.
+// El ID, el contador de ausencias y la fecha de alta no los cambia el pedido.
+func updatePatient(existing *models.Patient, request *models.PatientRequest) *models.Patient {
+	updated := &models.Patient{
+		ID:             existing.ID,
 		ClientID:       request.ClientID,
 		FirstName:      request.FirstName,
 		LastName:       request.LastName,
@@ -148,50 +216,53 @@ func (p *Patients) UpdatePatient(ctx context.Context, request *models.PatientReq
 		Gender:         request.Gender,
 		CountryCode:    request.CountryCode,
 		PhoneNumber:    request.PhoneNumber,
+		CountryCodeRaw: request.CountryCodeRaw,
+		PhoneNumberRaw: request.PhoneNumberRaw,
 		Email:          request.Email,
 		AddressStreet:  request.AddressStreet,
 		AddressNumber:  request.AddressNumber,
 		AddressCity:    request.AddressCity,
 		AddressCountry: request.AddressCountry,
 		ZipCode:        request.ZipCode,
-		CreatedAt:      existingPatient.CreatedAt,
+		NoShowCount:    existing.NoShowCount,
+		CreatedAt:      existing.CreatedAt,
 		UpdatedAt:      time.Now().Format(time.RFC3339),
+		Version:        existing.Version + 1,
 		Metadata:       request.Metadata,
 	}
-
-	// Guardar el paciente actualizado
-	if err := p.PatientsRepository.Save(ctx, updatedPatient); err != nil {
-		p.Logger.Error("Error updating patient", zap.String("id", request.ID), zap.Error(err))
-		return fmt.Errorf("failed to update patient: %w", err)
+	// Si reenvían el teléfono que ya estaba guardado, se conserva el original
+	if updated.PhoneNumberRaw == "" && updated.PhoneNumber == existing.PhoneNumber {
+		updated.CountryCodeRaw = existing.CountryCodeRaw
+		updated.PhoneNumberRaw = existing.PhoneNumberRaw
 	}
-
-	p.Logger.Info("Patient updated successfully", zap.String("id", request.ID))
-	return nil
+	return updated
 }
 
 func (p *Patients) DeletePatient(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "service.Patients.DeletePatient")
+	defer span.End()
 	// Verificar que el ID no esté vacío
 	if id == "" {
-		p.Logger.Error("Error: empty ID provided for patient deletion")
+		p.log(ctx).Error("Error: empty ID provided for patient deletion")
 		return fmt.Errorf("patient ID cannot be empty")
 	}
 
-	p.Logger.Info("Deleting patient", zap.String("id", id))
+	p.log(ctx).Info("Deleting patient", zap.String("id", id))
 
 	// Verificar primero si el paciente existe
-	_, err := p.PatientsRepository.GetByID(ctx, id)
+	existingPatient, err := p.PatientsRepository.GetByID(ctx, id)
 	if err != nil {
-		p.Logger.Error("Error finding patient to delete", zap.String("id", id), zap.Error(err))
+		p.log(ctx).Error("Error finding patient to delete", zap.String("id", id), zap.Error(err))
 		return fmt.Errorf("failed to find patient with ID %s: %w", id, err)
 	}
 
 	// Eliminar el paciente
-	if err := p.PatientsRepository.Delete(ctx, id); err != nil {
-		p.Logger.Error("Error deleting patient", zap.String("id", id), zap.Error(err))
+	if err := p.delete(ctx, existingPatient, id); err != nil {
+		p.log(ctx).Error("Error deleting patient", zap.String("id", id), zap.Error(err))
 		return fmt.Errorf("failed to delete patient: %w", err)
 	}
 
-	p.Logger.Info("Patient deleted successfully", zap.String("id", id))
+	p.log(ctx).Info("Patient deleted successfully", zap.String("id", id))
 	return nil
 }
 
@@ -207,6 +278,8 @@ func (p *Patients) mapRequestToPatient(req *models.PatientRequest) *models.Patie
 		Gender:         req.Gender,
 		CountryCode:    req.CountryCode,
 		PhoneNumber:    req.PhoneNumber,
+		CountryCodeRaw: req.CountryCodeRaw,
+		PhoneNumberRaw: req.PhoneNumberRaw,
 		Email:          req.Email,
 		AddressStreet:  req.AddressStreet,
 		AddressNumber:  req.AddressNumber,
@@ -231,18 +304,53 @@ func (p *Patients) mapPatientToRequest(patient *models.Patient) *models.PatientR
 		Gender:         patient.Gender,
 		CountryCode:    patient.CountryCode,
 		PhoneNumber:    patient.PhoneNumber,
+		CountryCodeRaw: patient.CountryCodeRaw,
+		PhoneNumberRaw: patient.PhoneNumberRaw,
 		Email:          patient.Email,
 		AddressStreet:  patient.AddressStreet,
 		AddressNumber:  patient.AddressNumber,
 		AddressCity:    patient.AddressCity,
 		AddressCountry: patient.AddressCountry,
 		ZipCode:        patient.ZipCode,
+		NoShowCount:    patient.NoShowCount,
 		CreatedAt:      patient.CreatedAt,
 		UpdatedAt:      patient.UpdatedAt,
 		Metadata:       patient.Metadata,
 	}
 }
 
+// normalizeDocument deja el documento como lo guarda el repositorio, para
+// rechazarlo antes de escribir y devolverlo ya normalizado.
+func normalizeDocument(request *models.PatientRequest) error {
+	docType, docNumber, err := documents.Normalize(request.DocType, request.DocNumber)
+	if err != nil {
+		return err
+	}
+	request.DocType, request.DocNumber = docType, docNumber
+	return nil
+}
+
+// normalizePhone deja el teléfono en E.164 y conserva lo que escribió el
+// usuario. Un número que ya viene normalizado (por ejemplo, el que devolvió un
+// GET) no pisa el original.
+func normalizePhone(request *models.PatientRequest) error {
+	if strings.TrimSpace(request.PhoneNumber) == "" {
+		return nil
+	}
+	e164, err := phones.Normalize(request.CountryCode, request.PhoneNumber)
+	if err != nil {
+		return err
+	}
+	if e164 != request.PhoneNumber {
+		request.CountryCodeRaw, request.PhoneNumberRaw = request.CountryCode, request.PhoneNumber
+	}
+	if code := phones.CountryCode(e164); code != "" {
+		request.CountryCode = code
+	}
+	request.PhoneNumber = e164
+	return nil
+}
+
 func validateGender(gender string) error {
 	switch gender {
 	case models.GenderMale, models.GenderFemale, models.GenderNonBinary:
@@ -251,3 +359,8 @@ func validateGender(gender string) error {
 		return fmt.Errorf("invalid gender value: %s. Must be one of: %s, %s, %s", gender, models.GenderMale, models.GenderFemale, models.GenderNonBinary)
 	}
 }
+
+// log es el logger del pedido en ctx o, si no hay, el del servicio.
+func (p *Patients) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, p.Logger)
+}
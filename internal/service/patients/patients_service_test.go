@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/MezeLaw/iris-services/internal/documents"
 	"github.com/MezeLaw/iris-services/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -51,6 +52,33 @@ func (m *MockPatientsRepository) Delete(ctx context.Context, id string) error {
 	return args.Error(0)
 }
 
+func (m *MockPatientsRepository) BatchSave(ctx context.Context, patients []*models.Patient) (map[string]error, error) {
+	args := m.Called(ctx, patients)
+	if fn, ok := args.Get(0).(func([]*models.Patient) map[string]error); ok {
+		return fn(patients), args.Error(1)
+	}
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]error), args.Error(1)
+}
+
+func (m *MockPatientsRepository) Search(ctx context.Context, clientID, field, value, cursor string, limit int32) ([]*models.Patient, string, error) {
+	args := m.Called(ctx, clientID, field, value, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).([]*models.Patient), args.String(1), args.Error(2)
+}
+
+func (m *MockPatientsRepository) GetPageByClientID(ctx context.Context, clientID, cursor string, limit int32) ([]*models.Patient, string, error) {
+	args := m.Called(ctx, clientID, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).([]*models.Patient), args.String(1), args.Error(2)
+}
+
 // Test setup helper function
 func setupTest() (*Patients, *MockPatientsRepository) {
 	mockRepo := new(MockPatientsRepository)
@@ -75,7 +103,7 @@ func createSamplePatientRequest() *models.PatientRequest {
 		BirthDate:      "1990-01-01",
 		Gender:         "M",
 		CountryCode:    "54",
-		PhoneNumber:    "1234567890",
+		PhoneNumber:    "1155551234",
 		Email:          "john.doe@example.com",
 		AddressStreet:  "Main St",
 		AddressNumber:  "123",
@@ -98,7 +126,7 @@ func createSamplePatient(id string) *models.Patient {
 		BirthDate:      "1990-01-01",
 		Gender:         "M",
 		CountryCode:    "54",
-		PhoneNumber:    "1234567890",
+		PhoneNumber:    "1155551234",
 		Email:          "john.doe@example.com",
 		AddressStreet:  "Main St",
 		AddressNumber:  "123",
@@ -126,7 +154,11 @@ func TestPatients_CreatePatient_Success(t *testing.T) {
 	
 	// Assert
 	assert.NoError(t, err)
-	assert.Equal(t, req, result)
+	// Devuelve lo que se guardó, con el ID y las fechas generadas
+	assert.NotEmpty(t, result.ID)
+	assert.NotEmpty(t, result.CreatedAt)
+	assert.Equal(t, result.CreatedAt, result.UpdatedAt)
+	assert.Equal(t, req.FirstName, result.FirstName)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -150,6 +182,41 @@ func TestPatients_CreatePatient_Error(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestPatients_CreatePatient_NormalizesDocument(t *testing.T) {
+	// Setup
+	service, mockRepo := setupTest()
+	ctx := context.Background()
+	req := createSamplePatientRequest()
+	req.DocType, req.DocNumber = "d.n.i.", "30.123.456"
+
+	mockRepo.On("Save", ctx, mock.MatchedBy(func(p *models.Patient) bool {
+		return p.DocType == "DNI" && p.DocNumber == "30123456"
+	})).Return(nil)
+
+	// Execute
+	result, err := service.CreatePatient(ctx, req)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "30123456", result.DocNumber)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPatients_CreatePatient_InvalidDocument(t *testing.T) {
+	// Setup
+	service, mockRepo := setupTest()
+	req := createSamplePatientRequest()
+	req.DocType, req.DocNumber = "CPF", "529.982.247-26"
+
+	// Execute
+	result, err := service.CreatePatient(context.Background(), req)
+
+	// Assert
+	assert.ErrorIs(t, err, documents.ErrInvalidNumber)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
 // Tests for GetPatient
 func TestPatients_GetPatient_ByID_Success(t *testing.T) {
 	// Setup
@@ -260,13 +327,14 @@ func TestPatients_GetAllPatients_Success(t *testing.T) {
 	patient2 := createSamplePatient("patient2")
 	patients := []*models.Patient{patient1, patient2}
 	
-	mockRepo.On("GetByClientID", ctx, clientID).Return(patients, nil)
+	mockRepo.On("GetPageByClientID", ctx, clientID, "cursor1", int32(defaultSearchLimit)).Return(patients, "cursor2", nil)
 	
 	// Execute
-	result, err := service.GetAllPatients(ctx, clientID)
+	result, next, err := service.GetAllPatients(ctx, clientID, "cursor1", 0)
 	
 	// Assert
 	assert.NoError(t, err)
+	assert.Equal(t, "cursor2", next)
 	assert.Len(t, result, 2)
 	assert.Equal(t, patient1.ID, result[0].ID)
 	assert.Equal(t, patient2.ID, result[1].ID)
@@ -279,7 +347,7 @@ func TestPatients_GetAllPatients_EmptyClientID(t *testing.T) {
 	ctx := context.Background()
 	
 	// Execute
-	result, err := service.GetAllPatients(ctx, "")
+	result, _, err := service.GetAllPatients(ctx, "", "", 0)
 	
 	// Assert
 	assert.Error(t, err)
@@ -294,10 +362,11 @@ func TestPatients_GetAllPatients_RepositoryError(t *testing.T) {
 	clientID := "client123"
 	expectedErr := errors.New("database error")
 	
-	mockRepo.On("GetByClientID", ctx, clientID).Return(nil, expectedErr)
+	// El límite se acota a maxSearchLimit
+	mockRepo.On("GetPageByClientID", ctx, clientID, "", int32(maxSearchLimit)).Return(nil, "", expectedErr)
 	
 	// Execute
-	result, err := service.GetAllPatients(ctx, clientID)
+	result, _, err := service.GetAllPatients(ctx, clientID, "", 500)
 	
 	// Assert
 	assert.Error(t, err)
@@ -322,7 +391,7 @@ func TestPatients_UpdatePatient_Success(t *testing.T) {
 	mockRepo.On("Save", ctx, mock.AnythingOfType("*models.Patient")).Return(nil)
 	
 	// Execute
-	err := service.UpdatePatient(ctx, req)
+	_, err := service.UpdatePatient(ctx, req)
 	
 	// Assert
 	assert.NoError(t, err)
@@ -337,7 +406,7 @@ func TestPatients_UpdatePatient_NoID(t *testing.T) {
 	// ID intentionally left empty
 	
 	// Execute
-	err := service.UpdatePatient(ctx, req)
+	_, err := service.UpdatePatient(ctx, req)
 	
 	// Assert
 	assert.Error(t, err)
@@ -356,7 +425,7 @@ func TestPatients_UpdatePatient_NotFound(t *testing.T) {
 	mockRepo.On("GetByID", ctx, patientID).Return(nil, expectedErr)
 	
 	// Execute
-	err := service.UpdatePatient(ctx, req)
+	_, err := service.UpdatePatient(ctx, req)
 	
 	// Assert
 	assert.Error(t, err)
@@ -379,7 +448,7 @@ func TestPatients_UpdatePatient_SaveError(t *testing.T) {
 	mockRepo.On("Save", ctx, mock.AnythingOfType("*models.Patient")).Return(expectedErr)
 	
 	// Execute
-	err := service.UpdatePatient(ctx, req)
+	_, err := service.UpdatePatient(ctx, req)
 	
 	// Assert
 	assert.Error(t, err)
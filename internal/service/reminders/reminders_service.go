@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/actiontoken"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/notify"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidConfig = errors.New("invalid reminder config")
+
+// offsetMargin amplía la consulta por fecha: los turnos se guardan con el
+// offset de cada clínica y el repositorio compara las fechas como strings.
+const offsetMargin = 14 * time.Hour
+
+type AppointmentsRepository interface {
+	GetByClientIDBetween(ctx context.Context, clientID, from, to string) ([]*models.Appointment, error)
+	ClaimReminder(ctx context.Context, id, window string) (bool, error)
+	ReleaseReminder(ctx context.Context, id, window string) error
+}
+
+type PatientsRepository interface {
+	GetByID(ctx context.Context, id string) (*models.Patient, error)
+}
+
+// ActionLinks arma los enlaces firmados para confirmar o cancelar desde el
+// recordatorio. Es opcional.
+type ActionLinks interface {
+	URL(appointmentID, action string, expiresAt time.Time) (string, error)
+}
+
+type RemindersService interface {
+	Dispatch(ctx context.Context, now time.Time) (*models.ReminderReport, error)
+}
+
+// Config define a quién se le mandan recordatorios. Windows son duraciones
+// ("48h", "2h") antes del turno; un tenant sin Windows o Channels usa los de
+// por defecto. Sólo se procesan los tenants listados.
+type Config struct {
+	Windows  []string       `json:"windows"`
+	Channels []string       `json:"channels"`
+	Tenants  []TenantConfig `json:"tenants"`
+}
+
+type TenantConfig struct {
+	ClientID string   `json:"client_id"`
+	Windows  []string `json:"windows,omitempty"`
+	Channels []string `json:"channels,omitempty"`
+}
+
+// ParseConfig lee la configuración en JSON (REMINDER_CONFIG) y la valida.
+func ParseConfig(raw string) (*Config, error) {
+	var cfg Config
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	for _, tenant := range cfg.Tenants {
+		if tenant.ClientID == "" {
+			return nil, fmt.Errorf("%w: tenant without client_id", ErrInvalidConfig)
+		}
+		if _, err := parseWindows(cfg.windows(tenant)); err != nil {
+			return nil, fmt.Errorf("%w: client %s: %v", ErrInvalidConfig, tenant.ClientID, err)
+		}
+		if len(cfg.channels(tenant)) == 0 {
+			return nil, fmt.Errorf("%w: client %s has no channels", ErrInvalidConfig, tenant.ClientID)
+		}
+	}
+	return &cfg, nil
+}
+
+func (c *Config) windows(tenant TenantConfig) []string {
+	if len(tenant.Windows) > 0 {
+		return tenant.Windows
+	}
+	return c.Windows
+}
+
+func (c *Config) channels(tenant TenantConfig) []string {
+	if len(tenant.Channels) > 0 {
+		return tenant.Channels
+	}
+	return c.Channels
+}
+
+type window struct {
+	label    string
+	duration time.Duration
+}
+
+// parseWindows devuelve las ventanas ordenadas de la más corta a la más larga.
+func parseWindows(labels []string) ([]window, error) {
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("no windows")
+	}
+	var windows []window
+	for _, label := range labels {
+		d, err := time.ParseDuration(strings.TrimSpace(label))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid window %q", label)
+		}
+		windows = append(windows, window{label: strings.TrimSpace(label), duration: d})
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].duration < windows[j].duration })
+	return windows, nil
+}
+
+type Reminders struct {
+	Logger                 *zap.SugaredLogger
+	AppointmentsRepository AppointmentsRepository
+	PatientsRepository     PatientsRepository
+	Notifiers              map[string]notify.Notifier // Por canal
+	Config                 *Config
+	Links                  ActionLinks
+}
+
+func New(logger *zap.SugaredLogger, appointments AppointmentsRepository, patients PatientsRepository, notifiers map[string]notify.Notifier, cfg *Config, links ActionLinks) RemindersService {
+	return &Reminders{
+		Logger:                 logger,
+		AppointmentsRepository: appointments,
+		PatientsRepository:     patients,
+		Notifiers:              notifiers,
+		Config:                 cfg,
+		Links:                  links,
+	}
+}
+
+// Dispatch manda los recordatorios que correspondan a now. A cada turno le
+// toca la ventana más corta en la que ya entró: un turno sacado con poca
+// anticipación recibe sólo el recordatorio de 2 h, no también el de 48 h.
+// Cada ventana se marca en el turno antes de enviar, así las corridas
+// repetidas o superpuestas no duplican mensajes. Un error con un tenant no
+// frena a los demás.
+func (r *Reminders) Dispatch(ctx context.Context, now time.Time) (*models.ReminderReport, error) {
+	report := &models.ReminderReport{}
+	var errs []error
+	for _, tenant := range r.Config.Tenants {
+		report.Clients++
+		if err := r.dispatchTenant(ctx, tenant, now, report); err != nil {
+			r.log(ctx).Error("Error dispatching reminders", zap.String("clientID", tenant.ClientID), zap.Error(err))
+			errs = append(errs, fmt.Errorf("client %s: %w", tenant.ClientID, err))
+		}
+	}
+	r.log(ctx).Info("Reminders dispatched", zap.Int("sent", report.Sent), zap.Int("skipped", report.Skipped), zap.Int("failed", report.Failed))
+	return report, errors.Join(errs...)
+}
+
+func (r *Reminders) dispatchTenant(ctx context.Context, tenant TenantConfig, now time.Time, report *models.ReminderReport) error {
+	windows, err := parseWindows(r.Config.windows(tenant))
+	if err != nil {
+		return err
+	}
+	longest := windows[len(windows)-1].duration
+
+	appointments, err := r.AppointmentsRepository.GetByClientIDBetween(ctx, tenant.ClientID,
+		now.Add(-offsetMargin).UTC().Format(time.RFC3339),
+		now.Add(longest+offsetMargin).UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+
+	for _, appointment := range appointments {
+		if appointment.Status != models.AppointmentStatusScheduled && appointment.Status != models.AppointmentStatusConfirmed {
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, appointment.Date)
+		if err != nil || !start.After(now) {
+			continue
+		}
+		w, ok := dueWindow(windows, start.Sub(now))
+		if !ok {
+			continue
+		}
+		report.Checked++
+		if err := r.remind(ctx, appointment, start, w, r.Config.channels(tenant), report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dueWindow(windows []window, untilStart time.Duration) (window, bool) {
+	for _, w := range windows {
+		if untilStart <= w.duration {
+			return w, true
+		}
+	}
+	return window{}, false
+}
+
+func (r *Reminders) remind(ctx context.Context, appointment *models.Appointment, start time.Time, w window, channels []string, report *models.ReminderReport) error {
+	for _, sent := range appointment.RemindersSent {
+		if sent == w.label {
+			report.Skipped++
+			return nil
+		}
+	}
+	claimed, err := r.AppointmentsRepository.ClaimReminder(ctx, appointment.ID, w.label)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		report.Skipped++
+		return nil
+	}
+
+	patient, err := r.PatientsRepository.GetByID(ctx, appointment.PatientID)
+	if err != nil || patient == nil {
+		r.log(ctx).Error("Error fetching patient for reminder", zap.String("appointmentID", appointment.ID), zap.Error(err))
+		report.Failed++
+		return r.AppointmentsRepository.ReleaseReminder(ctx, appointment.ID, w.label)
+	}
+
+	msg := message(patient, start)
+	if r.Links != nil {
+		links, err := r.actionLinks(appointment, start)
+		if err != nil {
+			// Sin enlaces el recordatorio sigue sirviendo
+			r.log(ctx).Error("Error building action links", zap.String("appointmentID", appointment.ID), zap.Error(err))
+		}
+		msg.Body += links
+	}
+	var sent, attempted int
+	for _, channel := range channels {
+		msg.To = notify.Recipient(patient, channel)
+		if msg.To == "" {
+			continue
+		}
+		attempted++
+		// Un canal sin proveedor cuenta como fallido para liberar el recordatorio
+		notifier, ok := r.Notifiers[channel]
+		if !ok {
+			r.log(ctx).Error("No notifier for reminder channel", zap.String("channel", channel))
+			continue
+		}
+		if err := notifier.Send(ctx, msg); err != nil {
+			r.log(ctx).Error("Error sending reminder", zap.String("appointmentID", appointment.ID), zap.String("channel", channel), zap.Error(err))
+			continue
+		}
+		sent++
+	}
+
+	switch {
+	case sent > 0:
+		// Si falló algún canal no se reintenta: los otros ya llegaron
+		report.Sent++
+		return nil
+	case attempted == 0:
+		// El paciente no tiene datos de contacto; si los carga, sale en la próxima corrida
+		report.Skipped++
+	default:
+		report.Failed++
+	}
+	return r.AppointmentsRepository.ReleaseReminder(ctx, appointment.ID, w.label)
+}
+
+// actionLinks devuelve el texto con los enlaces para confirmar (si todavía no
+// lo hizo) y cancelar. Vencen al empezar el turno.
+func (r *Reminders) actionLinks(appointment *models.Appointment, start time.Time) (string, error) {
+	var text string
+	if appointment.Status == models.AppointmentStatusScheduled {
+		confirm, err := r.Links.URL(appointment.ID, actiontoken.ActionConfirm, start)
+		if err != nil {
+			return "", err
+		}
+		text += "\nConfirmar: " + confirm
+	}
+	cancel, err := r.Links.URL(appointment.ID, actiontoken.ActionCancel, start)
+	if err != nil {
+		return "", err
+	}
+	return text + "\nCancelar: " + cancel, nil
+}
+
+// message usa la hora en el offset con que se guardó el turno, que es el de
+// la clínica.
+func message(patient *models.Patient, start time.Time) notify.Message {
+	return notify.Message{
+		Subject: "Recordatorio de turno",
+		Body: fmt.Sprintf("Hola %s, te recordamos tu turno del %s a las %s.",
+			patient.FirstName, start.Format("02/01/2006"), start.Format("15:04")),
+	}
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del servicio.
+func (r *Reminders) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, r.Logger)
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockAppointmentsRepository struct {
+	mock.Mock
+}
+
+func (m *MockAppointmentsRepository) GetByClientIDBetween(ctx context.Context, clientID, from, to string) ([]*models.Appointment, error) {
+	args := m.Called(ctx, clientID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Appointment), args.Error(1)
+}
+
+func (m *MockAppointmentsRepository) ClaimReminder(ctx context.Context, id, window string) (bool, error) {
+	args := m.Called(ctx, id, window)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAppointmentsRepository) ReleaseReminder(ctx context.Context, id, window string) error {
+	args := m.Called(ctx, id, window)
+	return args.Error(0)
+}
+
+type MockPatientsRepository struct {
+	mock.Mock
+}
+
+func (m *MockPatientsRepository) GetByID(ctx context.Context, id string) (*models.Patient, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Patient), args.Error(1)
+}
+
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Send(ctx context.Context, msg notify.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+var now = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func setupTest(cfg *Config) (*Reminders, *MockAppointmentsRepository, *MockPatientsRepository, *MockNotifier, *MockNotifier) {
+	appointments := new(MockAppointmentsRepository)
+	patients := new(MockPatientsRepository)
+	sms, email := new(MockNotifier), new(MockNotifier)
+	logger, _ := zap.NewDevelopment()
+	return &Reminders{
+		Logger:                 logger.Sugar(),
+		AppointmentsRepository: appointments,
+		PatientsRepository:     patients,
+		Notifiers:              map[string]notify.Notifier{models.ReminderChannelSMS: sms, models.ReminderChannelEmail: email},
+		Config:                 cfg,
+	}, appointments, patients, sms, email
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig(`{"windows":["48h","2h"],"channels":["sms"],"tenants":[{"client_id":"c1"},{"client_id":"c2","windows":["24h"]}]}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"24h"}, cfg.windows(cfg.Tenants[1]))
+	assert.Equal(t, []string{"sms"}, cfg.channels(cfg.Tenants[1]))
+
+	for _, raw := range []string{
+		`{"channels":["sms"],"tenants":[{"client_id":"c1"}]}`,
+		`{"windows":["dos horas"],"channels":["sms"],"tenants":[{"client_id":"c1"}]}`,
+		`{"windows":["2h"],"tenants":[{"client_id":"c1"}]}`,
+		`{"windows":["2h"],"channels":["sms"],"tenants":[{}]}`,
+		`not json`,
+	} {
+		_, err := ParseConfig(raw)
+		assert.ErrorIs(t, err, ErrInvalidConfig, raw)
+	}
+}
+
+func TestReminders_Dispatch(t *testing.T) {
+	service, appointments, patients, sms, email := setupTest(&Config{
+		Windows:  []string{"48h", "2h"},
+		Channels: []string{models.ReminderChannelSMS, models.ReminderChannelEmail},
+		Tenants:  []TenantConfig{{ClientID: "c1"}},
+	})
+	ctx := context.Background()
+	appointments.On("GetByClientIDBetween", ctx, "c1", "2024-01-14T22:00:00Z", "2024-01-18T02:00:00Z").Return([]*models.Appointment{
+		// Dentro de 30 h: le toca el de 48 h
+		{ID: "a1", PatientID: "p1", Date: "2024-01-16T15:00:00-03:00", Status: models.AppointmentStatusScheduled},
+		// Dentro de 1 h: sólo el de 2 h, aunque nunca recibió el de 48 h
+		{ID: "a2", PatientID: "p1", Date: "2024-01-15T13:00:00Z", Status: models.AppointmentStatusScheduled},
+		// Ya enviado
+		{ID: "a3", PatientID: "p1", Date: "2024-01-15T13:30:00Z", Status: models.AppointmentStatusScheduled, RemindersSent: []string{"2h"}},
+		// Cancelado, pasado y fuera de ventana
+		{ID: "a4", PatientID: "p1", Date: "2024-01-15T13:00:00Z", Status: models.AppointmentStatusCancelled},
+		{ID: "a5", PatientID: "p1", Date: "2024-01-15T11:00:00Z", Status: models.AppointmentStatusScheduled},
+		{ID: "a6", PatientID: "p1", Date: "2024-01-17T13:00:00Z", Status: models.AppointmentStatusScheduled},
+	}, nil)
+	appointments.On("ClaimReminder", ctx, "a1", "48h").Return(true, nil).Once()
+	appointments.On("ClaimReminder", ctx, "a2", "2h").Return(true, nil).Once()
+	patients.On("GetByID", ctx, "p1").Return(&models.Patient{ID: "p1", FirstName: "Ana", PhoneNumber: "+5491155551234", Email: "ana@example.com"}, nil)
+	sms.On("Send", ctx, mock.MatchedBy(func(msg notify.Message) bool {
+		return msg.To == "+5491155551234"
+	})).Return(nil).Twice()
+	email.On("Send", ctx, notify.Message{
+		To:      "ana@example.com",
+		Subject: "Recordatorio de turno",
+		Body:    "Hola Ana, te recordamos tu turno del 16/01/2024 a las 15:00.",
+	}).Return(nil).Once()
+	email.On("Send", ctx, mock.Anything).Return(errors.New("ses throttled")).Once()
+
+	report, err := service.Dispatch(ctx, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Checked)
+	assert.Equal(t, 2, report.Sent)
+	assert.Equal(t, 1, report.Skipped)
+	appointments.AssertExpectations(t)
+	sms.AssertExpectations(t)
+	email.AssertExpectations(t)
+	appointments.AssertNotCalled(t, "ReleaseReminder", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReminders_Dispatch_AlreadyClaimed(t *testing.T) {
+	service, appointments, patients, sms, _ := setupTest(&Config{
+		Windows: []string{"2h"}, Channels: []string{models.ReminderChannelSMS}, Tenants: []TenantConfig{{ClientID: "c1"}},
+	})
+	ctx := context.Background()
+	appointments.On("GetByClientIDBetween", ctx, "c1", mock.Anything, mock.Anything).Return([]*models.Appointment{
+		{ID: "a1", PatientID: "p1", Date: "2024-01-15T13:00:00Z", Status: models.AppointmentStatusScheduled},
+	}, nil)
+	// Otra corrida lo marcó entre la consulta y el envío
+	appointments.On("ClaimReminder", ctx, "a1", "2h").Return(false, nil)
+
+	report, err := service.Dispatch(ctx, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Skipped)
+	patients.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	sms.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestReminders_Dispatch_SendFailureReleasesClaim(t *testing.T) {
+	service, appointments, patients, sms, _ := setupTest(&Config{
+		Windows: []string{"2h"}, Channels: []string{models.ReminderChannelSMS},
+		Tenants: []TenantConfig{{ClientID: "c1"}, {ClientID: "c2"}},
+	})
+	ctx := context.Background()
+	appointments.On("GetByClientIDBetween", ctx, "c1", mock.Anything, mock.Anything).Return(nil, errors.New("throttled"))
+	appointments.On("GetByClientIDBetween", ctx, "c2", mock.Anything, mock.Anything).Return([]*models.Appointment{
+		{ID: "a1", PatientID: "p1", Date: "2024-01-15T13:00:00Z", Status: models.AppointmentStatusScheduled},
+		{ID: "a2", PatientID: "p2", Date: "2024-01-15T13:00:00Z", Status: models.AppointmentStatusScheduled},
+	}, nil)
+	appointments.On("ClaimReminder", ctx, mock.Anything, "2h").Return(true, nil)
+	appointments.On("ReleaseReminder", ctx, "a1", "2h").Return(nil).Once()
+	appointments.On("ReleaseReminder", ctx, "a2", "2h").Return(nil).Once()
+	patients.On("GetByID", ctx, "p1").Return(&models.Patient{ID: "p1", PhoneNumber: "+5491155551234"}, nil)
+	// Sin teléfono normalizado no hay a quién mandarle
+	patients.On("GetByID", ctx, "p2").Return(&models.Patient{ID: "p2", PhoneNumber: "11 5555-1234"}, nil)
+	sms.On("Send", ctx, mock.Anything).Return(errors.New("sns down")).Once()
+
+	report, err := service.Dispatch(ctx, now)
+
+	assert.ErrorContains(t, err, "client c1")
+	assert.Equal(t, 2, report.Clients)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 1, report.Skipped)
+	appointments.AssertExpectations(t)
+	sms.AssertExpectations(t)
+}
+
+func TestReminders_Dispatch_NoNotifierReleasesClaim(t *testing.T) {
+	service, appointments, patients, _, _ := setupTest(&Config{
+		Windows: []string{"2h"}, Channels: []string{models.ReminderChannelWhatsApp},
+		Tenants: []TenantConfig{{ClientID: "c1"}},
+	})
+	ctx := context.Background()
+	appointments.On("GetByClientIDBetween", ctx, "c1", mock.Anything, mock.Anything).Return([]*models.Appointment{
+		{ID: "a1", PatientID: "p1", Date: "2024-01-15T13:00:00Z", Status: models.AppointmentStatusScheduled},
+	}, nil)
+	appointments.On("ClaimReminder", ctx, "a1", "2h").Return(true, nil)
+	appointments.On("ReleaseReminder", ctx, "a1", "2h").Return(nil).Once()
+	patients.On("GetByID", ctx, "p1").Return(&models.Patient{ID: "p1", PhoneNumber: "+5491155551234"}, nil)
+
+	report, err := service.Dispatch(ctx, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 0, report.Sent)
+	appointments.AssertExpectations(t)
+}
+
+type fakeLinks struct{}
+
+func (fakeLinks) URL(appointmentID, action string, _ time.Time) (string, error) {
+	return "https://iris.example.com/a?" + action + "=" + appointmentID, nil
+}
+
+func TestReminders_Dispatch_ActionLinks(t *testing.T) {
+	service, appointments, patients, sms, _ := setupTest(&Config{
+		Windows: []string{"2h"}, Channels: []string{models.ReminderChannelSMS}, Tenants: []TenantConfig{{ClientID: "c1"}},
+	})
+	service.Links = fakeLinks{}
+	ctx := context.Background()
+	appointments.On("GetByClientIDBetween", ctx, "c1", mock.Anything, mock.Anything).Return([]*models.Appointment{
+		{ID: "a1", PatientID: "p1", Date: "2024-01-15T13:00:00Z", Status: models.AppointmentStatusScheduled},
+		{ID: "a2", PatientID: "p1", Date: "2024-01-15T13:00:00Z", Status: models.AppointmentStatusConfirmed},
+	}, nil)
+	appointments.On("ClaimReminder", ctx, mock.Anything, "2h").Return(true, nil)
+	patients.On("GetByID", ctx, "p1").Return(&models.Patient{ID: "p1", FirstName: "Ana", PhoneNumber: "+5491155551234"}, nil)
+	var bodies []string
+	sms.On("Send", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		bodies = append(bodies, args.Get(1).(notify.Message).Body)
+	}).Return(nil)
+
+	_, err := service.Dispatch(ctx, now)
+
+	require.NoError(t, err)
+	require.Len(t, bodies, 2)
+	assert.Equal(t, "Hola Ana, te recordamos tu turno del 15/01/2024 a las 13:00."+
+		"\nConfirmar: https://iris.example.com/a?confirm=a1\nCancelar: https://iris.example.com/a?cancel=a1", bodies[0])
+	// Ya confirmado: sólo el enlace para cancelar
+	assert.NotContains(t, bodies[1], "Confirmar")
+	assert.Contains(t, bodies[1], "cancel=a2")
+}
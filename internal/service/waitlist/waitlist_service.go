@@ -0,0 +1,660 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/availability"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/notify"
+	appointments "github.com/MezeLaw/iris-services/internal/service/appointments"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidEntry   = errors.New("invalid waitlist entry")
+	ErrAlreadyWaiting = errors.New("patient is already on the waitlist")
+	ErrEntryNotFound  = errors.New("waitlist entry not found")
+	ErrOfferNotFound  = errors.New("waitlist offer not found")
+	ErrOfferExpired   = errors.New("waitlist offer expired")
+	ErrOfferTaken     = errors.New("waitlist offer already taken")
+	ErrInvalidConfig  = errors.New("invalid waitlist config")
+)
+
+const (
+	dateLayout   = "2006-01-02"
+	defaultRound = 30 * time.Minute
+	defaultBatch = 1
+)
+
+type WaitlistRepository interface {
+	Save(ctx context.Context, e *models.WaitlistEntry) error
+	GetByID(ctx context.Context, id string) (*models.WaitlistEntry, error)
+	GetByDoctorID(ctx context.Context, doctorID string) ([]*models.WaitlistEntry, error)
+	Delete(ctx context.Context, id string) error
+	SaveOffer(ctx context.Context, o *models.WaitlistOffer) error
+	GetOffer(ctx context.Context, id string) (*models.WaitlistOffer, error)
+	GetOpenOffers(ctx context.Context) ([]*models.WaitlistOffer, error)
+	ClaimOffer(ctx context.Context, id, entryID string) (bool, error)
+	ReleaseOffer(ctx context.Context, id string) error
+	ExpireOffer(ctx context.Context, id string) (bool, error)
+	MarkNotified(ctx context.Context, id, entryID string) (bool, error)
+	UnmarkNotified(ctx context.Context, id, entryID string) error
+}
+
+// AppointmentsRepository se usa para confirmar que el horario sigue libre al
+// aceptar una oferta.
+type AppointmentsRepository interface {
+	GetByDoctorID(ctx context.Context, doctorID string) ([]*models.Appointment, error)
+}
+
+// AppointmentCreator crea el turno del paciente que acepta; lo implementa el
+// servicio de turnos, así se aplican sus validaciones y queda el evento del
+// alta.
+type AppointmentCreator interface {
+	CreateAppointment(context.Context, *models.AppointmentRequest) (*models.AppointmentRequest, error)
+}
+
+// HoldsReader devuelve las reservas temporales vigentes de un médico: un
+// horario reservado no se puede aceptar. Es opcional.
+type HoldsReader interface {
+	GetByDoctorID(ctx context.Context, doctorID string, now time.Time) ([]*models.SlotHold, error)
+}
+
+type PatientsRepository interface {
+	GetByID(ctx context.Context, id string) (*models.Patient, error)
+}
+
+type WaitlistService interface {
+	Join(context.Context, *models.WaitlistEntryRequest) (*models.WaitlistEntryRequest, error)
+	GetByDoctorID(ctx context.Context, doctorID string) ([]*models.WaitlistEntryRequest, error)
+	Leave(ctx context.Context, id string) error
+	OfferSlot(ctx context.Context, freed *models.Appointment) error
+	CheckAccept(context.Context, *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error)
+	Accept(context.Context, *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error)
+	NotifyOffers(ctx context.Context, now time.Time) (*models.WaitlistReport, error)
+}
+
+// Config define cómo se ofrecen los turnos: a Batch pacientes por vez, cada
+// tanda con Round para aceptar antes de pasar a la siguiente. Con AcceptURL
+// el aviso lleva el enlace para aceptar.
+type Config struct {
+	Round     time.Duration
+	Batch     int
+	Channels  []string
+	AcceptURL string
+}
+
+// ParseConfig lee WAITLIST_CONFIG; sin configuración usa tandas de un
+// paciente cada 30 minutos y no manda avisos.
+//
+//	{"round":"30m","batch":1,"channels":["sms","email"],"accept_url":"https://..."}
+func ParseConfig(raw string) (*Config, error) {
+	cfg := &Config{Round: defaultRound, Batch: defaultBatch}
+	if raw == "" {
+		return cfg, nil
+	}
+	var parsed struct {
+		Round     string   `json:"round"`
+		Batch     int      `json:"batch"`
+		Channels  []string `json:"channels"`
+		AcceptURL string   `json:"accept_url"`
+	}
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if parsed.Round != "" {
+		round, err := time.ParseDuration(parsed.Round)
+		if err != nil || round <= 0 {
+			return nil, fmt.Errorf("%w: invalid round %q", ErrInvalidConfig, parsed.Round)
+		}
+		cfg.Round = round
+	}
+	if parsed.Batch < 0 {
+		return nil, fmt.Errorf("%w: invalid batch %d", ErrInvalidConfig, parsed.Batch)
+	}
+	if parsed.Batch > 0 {
+		cfg.Batch = parsed.Batch
+	}
+	cfg.Channels, cfg.AcceptURL = parsed.Channels, parsed.AcceptURL
+	return cfg, nil
+}
+
+type Waitlist struct {
+	Logger                 *zap.SugaredLogger
+	WaitlistRepository     WaitlistRepository
+	AppointmentsRepository AppointmentsRepository
+	Holds                  HoldsReader
+	Appointments           AppointmentCreator // Sólo hace falta para Accept
+	PatientsRepository     PatientsRepository
+	Notifiers              map[string]notify.Notifier // Por canal
+	Config                 *Config
+	Now                    func() time.Time
+}
+
+func New(logger *zap.SugaredLogger, repository WaitlistRepository, appointmentsRepository AppointmentsRepository, holds HoldsReader, appointments AppointmentCreator, patients PatientsRepository, notifiers map[string]notify.Notifier, cfg *Config) WaitlistService {
+	return &Waitlist{
+		Logger:                 logger,
+		WaitlistRepository:     repository,
+		AppointmentsRepository: appointmentsRepository,
+		Holds:                  holds,
+		Appointments:           appointments,
+		PatientsRepository:     patients,
+		Notifiers:              notifiers,
+		Config:                 cfg,
+		Now:                    time.Now,
+	}
+}
+
+func (w *Waitlist) Join(ctx context.Context, request *models.WaitlistEntryRequest) (*models.WaitlistEntryRequest, error) {
+	if request.ClientID == "" || request.DoctorID == "" || request.PatientID == "" {
+		return nil, fmt.Errorf("%w: client_id, doctor_id and patient_id are required", ErrInvalidEntry)
+	}
+	if err := validateRange(request.From, request.To); err != nil {
+		return nil, err
+	}
+
+	entries, err := w.WaitlistRepository.GetByDoctorID(ctx, request.DoctorID)
+	if err != nil {
+		w.log(ctx).Error("Error getting waitlist by doctor", zap.String("doctorID", request.DoctorID), zap.Error(err))
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.PatientID == request.PatientID && entry.ClientID == request.ClientID && entry.Status == models.WaitlistStatusWaiting {
+			return nil, ErrAlreadyWaiting
+		}
+	}
+
+	now := w.Now().Format(time.RFC3339)
+	entry := &models.WaitlistEntry{
+		ID:        uuid.NewString(),
+		ClientID:  request.ClientID,
+		DoctorID:  request.DoctorID,
+		PatientID: request.PatientID,
+		From:      request.From,
+		To:        request.To,
+		Priority:  request.Priority,
+		Status:    models.WaitlistStatusWaiting,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := w.WaitlistRepository.Save(ctx, entry); err != nil {
+		w.log(ctx).Error("Error on WaitlistRepository.Save", zap.Error(err))
+		return nil, err
+	}
+	w.log(ctx).Info("Patient joined waitlist", zap.String("id", entry.ID), zap.String("doctorID", entry.DoctorID))
+	return mapEntryToRequest(entry), nil
+}
+
+// GetByDoctorID devuelve la lista del médico en el orden en que se ofrecen
+// los turnos.
+func (w *Waitlist) GetByDoctorID(ctx context.Context, doctorID string) ([]*models.WaitlistEntryRequest, error) {
+	if doctorID == "" {
+		return nil, fmt.Errorf("%w: doctor_id is required", ErrInvalidEntry)
+	}
+	entries, err := w.WaitlistRepository.GetByDoctorID(ctx, doctorID)
+	if err != nil {
+		w.log(ctx).Error("Error getting waitlist by doctor", zap.String("doctorID", doctorID), zap.Error(err))
+		return nil, err
+	}
+	sortEntries(entries)
+	results := make([]*models.WaitlistEntryRequest, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, mapEntryToRequest(entry))
+	}
+	return results, nil
+}
+
+func (w *Waitlist) Leave(ctx context.Context, id string) error {
+	entry, err := w.WaitlistRepository.GetByID(ctx, id)
+	if err != nil {
+		w.log(ctx).Error("Error finding waitlist entry", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	if entry == nil {
+		return ErrEntryNotFound
+	}
+	if err := w.WaitlistRepository.Delete(ctx, id); err != nil {
+		w.log(ctx).Error("Error deleting waitlist entry", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	w.log(ctx).Info("Patient left waitlist", zap.String("id", id))
+	return nil
+}
+
+// OfferSlot ofrece el horario de un turno cancelado a los pacientes en espera
+// del mismo médico cuyo rango incluye la fecha. Sólo se guardan los
+// candidatos que llegan a tener su tanda antes del turno; la primera tanda se
+// avisa en el momento y las siguientes las avisa NotifyOffers.
+func (w *Waitlist) OfferSlot(ctx context.Context, freed *models.Appointment) error {
+	now := w.Now()
+	start, err := time.Parse(time.RFC3339, freed.Date)
+	if err != nil || !start.After(now) {
+		return nil
+	}
+
+	entries, err := w.WaitlistRepository.GetByDoctorID(ctx, freed.DoctorID)
+	if err != nil {
+		return err
+	}
+	day := start.Format(dateLayout)
+	var candidates []*models.WaitlistEntry
+	for _, entry := range entries {
+		if entry.ClientID == freed.ClientID && entry.Status == models.WaitlistStatusWaiting &&
+			entry.PatientID != freed.PatientID && inRange(entry, day) {
+			candidates = append(candidates, entry)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sortEntries(candidates)
+
+	batch, round := w.Config.Batch, w.Config.Round
+	rounds := int((start.Sub(now) + round - 1) / round)
+	if limit := rounds * batch; len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	expiresAt := now.Add(time.Duration((len(candidates)+batch-1)/batch) * round)
+	if expiresAt.After(start) {
+		expiresAt = start
+	}
+
+	offer := &models.WaitlistOffer{
+		ID:                  uuid.NewString(),
+		ClientID:            freed.ClientID,
+		DoctorID:            freed.DoctorID,
+		Date:                freed.Date,
+		Duration:            freed.Duration,
+		SourceAppointmentID: freed.ID,
+		Batch:               batch,
+		RoundSeconds:        int(round / time.Second),
+		Status:              models.WaitlistOfferOpen,
+		CreatedAt:           now.Format(time.RFC3339),
+		ExpiresAt:           expiresAt.Format(time.RFC3339),
+	}
+	for _, candidate := range candidates {
+		offer.Candidates = append(offer.Candidates, candidate.ID)
+	}
+	if err := w.WaitlistRepository.SaveOffer(ctx, offer); err != nil {
+		w.log(ctx).Error("Error on WaitlistRepository.SaveOffer", zap.Error(err))
+		return err
+	}
+	w.log(ctx).Info("Slot offered to waitlist", zap.String("offerID", offer.ID), zap.Int("candidates", len(offer.Candidates)))
+
+	if err := w.notifyRound(ctx, offer, now, &models.WaitlistReport{}); err != nil {
+		// La oferta ya está guardada: NotifyOffers reintenta los avisos
+		w.log(ctx).Error("Error notifying waitlist offer", zap.String("offerID", offer.ID), zap.Error(err))
+	}
+	return nil
+}
+
+// CheckAccept valida la oferta sin tomarla y devuelve el turno que se
+// crearía. Es lo que muestra la página antes de que el paciente confirme.
+func (w *Waitlist) CheckAccept(ctx context.Context, request *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error) {
+	offer, entry, err := w.acceptable(ctx, request, w.Now())
+	if err != nil {
+		return nil, err
+	}
+	return offerAppointment(offer, entry), nil
+}
+
+// Accept le da el turno al paciente si la oferta sigue abierta, es su tanda y
+// el horario sigue libre. La oferta se toma con una escritura condicional: si
+// dos pacientes aceptan a la vez, sólo uno la obtiene. Que el horario siga
+// libre lo asegura CreateAppointment, que toma las franjas de la agenda al
+// crear el turno; la revisión previa sólo evita tomar la oferta en vano.
+func (w *Waitlist) Accept(ctx context.Context, request *models.WaitlistAcceptRequest) (*models.AppointmentRequest, error) {
+	if w.Appointments == nil {
+		return nil, fmt.Errorf("accepting waitlist offers is not configured")
+	}
+	now := w.Now()
+	offer, entry, err := w.acceptable(ctx, request, now)
+	if err != nil {
+		return nil, err
+	}
+
+	free, err := w.slotFree(ctx, offer)
+	if err != nil {
+		return nil, err
+	}
+	if !free {
+		if _, err := w.WaitlistRepository.ExpireOffer(ctx, offer.ID); err != nil {
+			w.log(ctx).Error("Error expiring waitlist offer", zap.String("offerID", offer.ID), zap.Error(err))
+		}
+		return nil, ErrOfferTaken
+	}
+
+	claimed, err := w.WaitlistRepository.ClaimOffer(ctx, offer.ID, entry.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrOfferTaken
+	}
+
+	created, err := w.Appointments.CreateAppointment(ctx, offerAppointment(offer, entry))
+	if errors.Is(err, appointments.ErrSlotHeld) {
+		// Otro pedido tomó el horario entre la revisión y el alta: la
+		// escritura del turno lo revisa de nuevo con la agenda bloqueada
+		if _, err := w.WaitlistRepository.ExpireOffer(ctx, offer.ID); err != nil {
+			w.log(ctx).Error("Error expiring waitlist offer", zap.String("offerID", offer.ID), zap.Error(err))
+		}
+		return nil, ErrOfferTaken
+	}
+	if err != nil {
+		w.log(ctx).Error("Error creating appointment from waitlist", zap.String("offerID", offer.ID), zap.Error(err))
+		if releaseErr := w.WaitlistRepository.ReleaseOffer(ctx, offer.ID); releaseErr != nil {
+			w.log(ctx).Error("Error releasing waitlist offer", zap.String("offerID", offer.ID), zap.Error(releaseErr))
+		}
+		return nil, err
+	}
+
+	entry.Status = models.WaitlistStatusBooked
+	entry.AppointmentID = created.ID
+	entry.UpdatedAt = now.Format(time.RFC3339)
+	if err := w.WaitlistRepository.Save(ctx, entry); err != nil {
+		// El turno ya está creado; la entrada queda en espera hasta que la saquen
+		w.log(ctx).Error("Error marking waitlist entry as booked", zap.String("id", entry.ID), zap.Error(err))
+	}
+	w.log(ctx).Info("Waitlist offer accepted", zap.String("offerID", offer.ID), zap.String("appointmentID", created.ID))
+	return created, nil
+}
+
+// acceptable devuelve la oferta y la entrada si entryID puede aceptarla en now.
+func (w *Waitlist) acceptable(ctx context.Context, request *models.WaitlistAcceptRequest, now time.Time) (*models.WaitlistOffer, *models.WaitlistEntry, error) {
+	if request.OfferID == "" || request.EntryID == "" {
+		return nil, nil, ErrOfferNotFound
+	}
+	offer, err := w.WaitlistRepository.GetOffer(ctx, request.OfferID)
+	if err != nil {
+		w.log(ctx).Error("Error getting waitlist offer", zap.String("offerID", request.OfferID), zap.Error(err))
+		return nil, nil, err
+	}
+	if offer == nil {
+		return nil, nil, ErrOfferNotFound
+	}
+	switch offer.Status {
+	case models.WaitlistOfferClaimed:
+		return nil, nil, ErrOfferTaken
+	case models.WaitlistOfferExpired:
+		return nil, nil, ErrOfferExpired
+	}
+
+	candidateRound := roundOf(offer, request.EntryID)
+	current, open := currentRound(offer, now)
+	switch {
+	case candidateRound < 0 || (open && candidateRound > current):
+		// Todavía no se le ofreció: para el paciente la oferta no existe
+		return nil, nil, ErrOfferNotFound
+	case !open || candidateRound < current:
+		return nil, nil, ErrOfferExpired
+	}
+
+	entry, err := w.WaitlistRepository.GetByID(ctx, request.EntryID)
+	if err != nil {
+		w.log(ctx).Error("Error getting waitlist entry", zap.String("id", request.EntryID), zap.Error(err))
+		return nil, nil, err
+	}
+	if entry == nil || entry.Status != models.WaitlistStatusWaiting {
+		return nil, nil, ErrEntryNotFound
+	}
+	return offer, entry, nil
+}
+
+func offerAppointment(offer *models.WaitlistOffer, entry *models.WaitlistEntry) *models.AppointmentRequest {
+	return &models.AppointmentRequest{
+		ClientID:  offer.ClientID,
+		PatientID: entry.PatientID,
+		DoctorID:  offer.DoctorID,
+		Date:      offer.Date,
+		Duration:  offer.Duration,
+		Status:    models.AppointmentStatusScheduled,
+		Metadata:  map[string]interface{}{"waitlist_entry_id": entry.ID, "waitlist_offer_id": offer.ID},
+	}
+}
+
+// NotifyOffers avisa a la tanda en curso de cada oferta abierta y cierra las
+// que vencieron. Se corre con una regla programada.
+func (w *Waitlist) NotifyOffers(ctx context.Context, now time.Time) (*models.WaitlistReport, error) {
+	offers, err := w.WaitlistRepository.GetOpenOffers(ctx)
+	if err != nil {
+		w.log(ctx).Error("Error getting open waitlist offers", zap.Error(err))
+		return nil, err
+	}
+	report := &models.WaitlistReport{}
+	var errs []error
+	for _, offer := range offers {
+		report.Offers++
+		if _, open := currentRound(offer, now); !open {
+			expired, err := w.WaitlistRepository.ExpireOffer(ctx, offer.ID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("offer %s: %w", offer.ID, err))
+			} else if expired {
+				report.Expired++
+			}
+			continue
+		}
+		if err := w.notifyRound(ctx, offer, now, report); err != nil {
+			w.log(ctx).Error("Error notifying waitlist offer", zap.String("offerID", offer.ID), zap.Error(err))
+			errs = append(errs, fmt.Errorf("offer %s: %w", offer.ID, err))
+		}
+	}
+	w.log(ctx).Info("Waitlist offers notified", zap.Int("notified", report.Notified), zap.Int("expired", report.Expired), zap.Int("failed", report.Failed))
+	return report, errors.Join(errs...)
+}
+
+// notifyRound avisa a los candidatos de la tanda en curso que todavía no
+// recibieron la oferta. Cada aviso se marca antes de enviarse para no
+// duplicarlo entre corridas.
+func (w *Waitlist) notifyRound(ctx context.Context, offer *models.WaitlistOffer, now time.Time, report *models.WaitlistReport) error {
+	round, open := currentRound(offer, now)
+	if !open || len(w.Config.Channels) == 0 {
+		return nil
+	}
+	first := round * offer.Batch
+	if first >= len(offer.Candidates) {
+		return nil
+	}
+	last := min(first+offer.Batch, len(offer.Candidates))
+	start, _ := time.Parse(time.RFC3339, offer.Date)
+	deadline := roundEnd(offer, round)
+
+	for _, entryID := range offer.Candidates[first:last] {
+		if contains(offer.Notified, entryID) {
+			continue
+		}
+		marked, err := w.WaitlistRepository.MarkNotified(ctx, offer.ID, entryID)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			continue
+		}
+		if !w.notify(ctx, offer, entryID, start, deadline) {
+			report.Failed++
+			if err := w.WaitlistRepository.UnmarkNotified(ctx, offer.ID, entryID); err != nil {
+				return err
+			}
+			continue
+		}
+		report.Notified++
+	}
+	return nil
+}
+
+// notify devuelve false sólo si había a quién avisar y fallaron todos los
+// canales; un paciente sin datos de contacto no se reintenta.
+func (w *Waitlist) notify(ctx context.Context, offer *models.WaitlistOffer, entryID string, start, deadline time.Time) bool {
+	entry, err := w.WaitlistRepository.GetByID(ctx, entryID)
+	if err != nil {
+		w.log(ctx).Error("Error getting waitlist entry", zap.String("id", entryID), zap.Error(err))
+		return false
+	}
+	if entry == nil || entry.Status != models.WaitlistStatusWaiting {
+		return true
+	}
+	patient, err := w.PatientsRepository.GetByID(ctx, entry.PatientID)
+	if err != nil || patient == nil {
+		w.log(ctx).Error("Error fetching patient for waitlist offer", zap.String("entryID", entryID), zap.Error(err))
+		return false
+	}
+
+	msg := notify.Message{
+		Subject: "Turno disponible",
+		Body: fmt.Sprintf("Hola %s, se liberó un turno el %s a las %s. Si lo querés, aceptalo antes de las %s.",
+			patient.FirstName, start.Format("02/01/2006"), start.Format("15:04"), deadline.In(start.Location()).Format("15:04")),
+	}
+	if w.Config.AcceptURL != "" {
+		msg.Body += "\nAceptar: " + w.Config.AcceptURL + "?" + url.Values{"offer_id": {offer.ID}, "entry_id": {entryID}}.Encode()
+	}
+
+	var sent, attempted int
+	for _, channel := range w.Config.Channels {
+		msg.To = notify.Recipient(patient, channel)
+		if msg.To == "" {
+			continue
+		}
+		attempted++
+		notifier, ok := w.Notifiers[channel]
+		if !ok {
+			w.log(ctx).Error("No notifier for waitlist channel", zap.String("channel", channel))
+			continue
+		}
+		if err := notifier.Send(ctx, msg); err != nil {
+			w.log(ctx).Error("Error sending waitlist offer", zap.String("offerID", offer.ID), zap.String("channel", channel), zap.Error(err))
+			continue
+		}
+		sent++
+	}
+	return sent > 0 || attempted == 0
+}
+
+func (w *Waitlist) slotFree(ctx context.Context, offer *models.WaitlistOffer) (bool, error) {
+	slot, err := availability.AppointmentInterval(&models.Appointment{Date: offer.Date, Duration: offer.Duration})
+	if err != nil {
+		return false, err
+	}
+	appointments, err := w.AppointmentsRepository.GetByDoctorID(ctx, offer.DoctorID)
+	if err != nil {
+		w.log(ctx).Error("Error getting appointments by doctor", zap.String("doctorID", offer.DoctorID), zap.Error(err))
+		return false, err
+	}
+	busy := availability.BusyFromAppointments(appointments)
+	if w.Holds != nil {
+		holds, err := w.Holds.GetByDoctorID(ctx, offer.DoctorID, w.Now())
+		if err != nil {
+			w.log(ctx).Error("Error getting slot holds by doctor", zap.String("doctorID", offer.DoctorID), zap.Error(err))
+			return false, err
+		}
+		busy = append(busy, availability.BusyFromHolds(holds)...)
+	}
+	for _, interval := range busy {
+		if interval.Overlaps(slot) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// currentRound devuelve la tanda en curso y si la oferta sigue vigente.
+func currentRound(offer *models.WaitlistOffer, now time.Time) (int, bool) {
+	created, err := time.Parse(time.RFC3339, offer.CreatedAt)
+	if err != nil || offer.RoundSeconds <= 0 {
+		return 0, false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, offer.ExpiresAt)
+	if err != nil || !now.Before(expiresAt) {
+		return 0, false
+	}
+	return int(now.Sub(created) / (time.Duration(offer.RoundSeconds) * time.Second)), true
+}
+
+// roundEnd es el límite para aceptar de la tanda: su fin o el vencimiento de
+// la oferta, lo que ocurra antes.
+func roundEnd(offer *models.WaitlistOffer, round int) time.Time {
+	created, _ := time.Parse(time.RFC3339, offer.CreatedAt)
+	expiresAt, _ := time.Parse(time.RFC3339, offer.ExpiresAt)
+	end := created.Add(time.Duration(round+1) * time.Duration(offer.RoundSeconds) * time.Second)
+	if end.After(expiresAt) {
+		return expiresAt
+	}
+	return end
+}
+
+func roundOf(offer *models.WaitlistOffer, entryID string) int {
+	for i, candidate := range offer.Candidates {
+		if candidate == entryID {
+			return i / max(offer.Batch, 1)
+		}
+	}
+	return -1
+}
+
+// sortEntries ordena por prioridad y, a igual prioridad, por antigüedad.
+func sortEntries(entries []*models.WaitlistEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Priority != entries[j].Priority {
+			return entries[i].Priority > entries[j].Priority
+		}
+		return entries[i].CreatedAt < entries[j].CreatedAt
+	})
+}
+
+// inRange compara el día del turno, en el offset de la clínica, con el rango
+// de la entrada. Las fechas YYYY-MM-DD se pueden comparar como strings.
+func inRange(entry *models.WaitlistEntry, day string) bool {
+	return (entry.From == "" || day >= entry.From) && (entry.To == "" || day <= entry.To)
+}
+
+func validateRange(from, to string) error {
+	for _, value := range []string{from, to} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, value); err != nil {
+			return fmt.Errorf("%w: invalid date %q, expected YYYY-MM-DD", ErrInvalidEntry, value)
+		}
+	}
+	if from != "" && to != "" && from > to {
+		return fmt.Errorf("%w: from must not be after to", ErrInvalidEntry)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func mapEntryToRequest(entry *models.WaitlistEntry) *models.WaitlistEntryRequest {
+	return &models.WaitlistEntryRequest{
+		ID:            entry.ID,
+		ClientID:      entry.ClientID,
+		DoctorID:      entry.DoctorID,
+		PatientID:     entry.PatientID,
+		From:          entry.From,
+		To:            entry.To,
+		Priority:      entry.Priority,
+		Status:        entry.Status,
+		AppointmentID: entry.AppointmentID,
+		CreatedAt:     entry.CreatedAt,
+		UpdatedAt:     entry.UpdatedAt,
+	}
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del servicio.
+func (w *Waitlist) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, w.Logger)
+}
@@ -0,0 +1,455 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/notify"
+	appointments "github.com/MezeLaw/iris-services/internal/service/appointments"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type MockWaitlistRepository struct {
+	mock.Mock
+}
+
+func (m *MockWaitlistRepository) Save(ctx context.Context, e *models.WaitlistEntry) error {
+	return m.Called(ctx, e).Error(0)
+}
+
+func (m *MockWaitlistRepository) GetByID(ctx context.Context, id string) (*models.WaitlistEntry, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockWaitlistRepository) GetByDoctorID(ctx context.Context, doctorID string) ([]*models.WaitlistEntry, error) {
+	args := m.Called(ctx, doctorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockWaitlistRepository) Delete(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockWaitlistRepository) SaveOffer(ctx context.Context, o *models.WaitlistOffer) error {
+	return m.Called(ctx, o).Error(0)
+}
+
+func (m *MockWaitlistRepository) GetOffer(ctx context.Context, id string) (*models.WaitlistOffer, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WaitlistOffer), args.Error(1)
+}
+
+func (m *MockWaitlistRepository) GetOpenOffers(ctx context.Context) ([]*models.WaitlistOffer, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.WaitlistOffer), args.Error(1)
+}
+
+func (m *MockWaitlistRepository) ClaimOffer(ctx context.Context, id, entryID string) (bool, error) {
+	args := m.Called(ctx, id, entryID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWaitlistRepository) ReleaseOffer(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockWaitlistRepository) ExpireOffer(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWaitlistRepository) MarkNotified(ctx context.Context, id, entryID string) (bool, error) {
+	args := m.Called(ctx, id, entryID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWaitlistRepository) UnmarkNotified(ctx context.Context, id, entryID string) error {
+	return m.Called(ctx, id, entryID).Error(0)
+}
+
+type MockAppointmentsRepository struct {
+	mock.Mock
+}
+
+func (m *MockAppointmentsRepository) GetByDoctorID(ctx context.Context, doctorID string) ([]*models.Appointment, error) {
+	args := m.Called(ctx, doctorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Appointment), args.Error(1)
+}
+
+type MockAppointmentCreator struct {
+	mock.Mock
+}
+
+func (m *MockAppointmentCreator) CreateAppointment(ctx context.Context, request *models.AppointmentRequest) (*models.AppointmentRequest, error) {
+	args := m.Called(ctx, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AppointmentRequest), args.Error(1)
+}
+
+type MockPatientsRepository struct {
+	mock.Mock
+}
+
+func (m *MockPatientsRepository) GetByID(ctx context.Context, id string) (*models.Patient, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Patient), args.Error(1)
+}
+
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Send(ctx context.Context, msg notify.Message) error {
+	return m.Called(ctx, msg).Error(0)
+}
+
+// now es un lunes a las 9:00 en Buenos Aires
+var now = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+type mocks struct {
+	repo         *MockWaitlistRepository
+	appointments *MockAppointmentsRepository
+	creator      *MockAppointmentCreator
+	patients     *MockPatientsRepository
+	sms          *MockNotifier
+}
+
+func setupTest(t *testing.T) (*Waitlist, *mocks) {
+	m := &mocks{
+		repo:         new(MockWaitlistRepository),
+		appointments: new(MockAppointmentsRepository),
+		creator:      new(MockAppointmentCreator),
+		patients:     new(MockPatientsRepository),
+		sms:          new(MockNotifier),
+	}
+	w := &Waitlist{
+		Logger:                 zaptest.NewLogger(t).Sugar(),
+		WaitlistRepository:     m.repo,
+		AppointmentsRepository: m.appointments,
+		Appointments:           m.creator,
+		PatientsRepository:     m.patients,
+		Notifiers:              map[string]notify.Notifier{models.ReminderChannelSMS: m.sms},
+		Config:                 &Config{Round: 30 * time.Minute, Batch: 1, Channels: []string{models.ReminderChannelSMS}, AcceptURL: "https://example.com/waitlist/accept"},
+		Now:                    func() time.Time { return now },
+	}
+	return w, m
+}
+
+func waitingEntry(id, patientID string, priority int, createdAt string) *models.WaitlistEntry {
+	return &models.WaitlistEntry{
+		ID:        id,
+		ClientID:  "client1",
+		DoctorID:  "doc1",
+		PatientID: patientID,
+		Priority:  priority,
+		Status:    models.WaitlistStatusWaiting,
+		CreatedAt: createdAt,
+	}
+}
+
+func openOffer(candidates ...string) *models.WaitlistOffer {
+	return &models.WaitlistOffer{
+		ID:           "offer1",
+		ClientID:     "client1",
+		DoctorID:     "doc1",
+		Date:         "2024-01-15T14:00:00-03:00",
+		Duration:     30,
+		Candidates:   candidates,
+		Batch:        1,
+		RoundSeconds: 1800,
+		Status:       models.WaitlistOfferOpen,
+		CreatedAt:    now.Add(-40 * time.Minute).Format(time.RFC3339),
+		ExpiresAt:    "2024-01-15T14:00:00-03:00",
+	}
+}
+
+func TestWaitlist_Join(t *testing.T) {
+	w, m := setupTest(t)
+	ctx := context.Background()
+	m.repo.On("GetByDoctorID", ctx, "doc1").Return([]*models.WaitlistEntry{waitingEntry("e1", "p1", 0, "")}, nil)
+	m.repo.On("Save", ctx, mock.AnythingOfType("*models.WaitlistEntry")).Return(nil).Once()
+
+	result, err := w.Join(ctx, &models.WaitlistEntryRequest{ClientID: "client1", DoctorID: "doc1", PatientID: "p2", From: "2024-01-15", To: "2024-01-31"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.ID)
+	assert.Equal(t, models.WaitlistStatusWaiting, result.Status)
+
+	_, err = w.Join(ctx, &models.WaitlistEntryRequest{ClientID: "client1", DoctorID: "doc1", PatientID: "p1"})
+	assert.ErrorIs(t, err, ErrAlreadyWaiting)
+	m.repo.AssertNumberOfCalls(t, "Save", 1)
+}
+
+func TestWaitlist_Join_Invalid(t *testing.T) {
+	w, _ := setupTest(t)
+
+	tests := []*models.WaitlistEntryRequest{
+		{DoctorID: "doc1", PatientID: "p1"},
+		{ClientID: "client1", DoctorID: "doc1", PatientID: "p1", From: "15/01/2024"},
+		{ClientID: "client1", DoctorID: "doc1", PatientID: "p1", From: "2024-02-01", To: "2024-01-01"},
+	}
+	for _, tt := range tests {
+		_, err := w.Join(context.Background(), tt)
+		assert.ErrorIs(t, err, ErrInvalidEntry)
+	}
+}
+
+func TestWaitlist_OfferSlot(t *testing.T) {
+	w, m := setupTest(t)
+	ctx := context.Background()
+	outOfRange := waitingEntry("range", "p5", 9, "2024-01-01T00:00:00Z")
+	outOfRange.From, outOfRange.To = "2024-01-20", "2024-01-31"
+	booked := waitingEntry("booked", "p6", 9, "2024-01-01T00:00:00Z")
+	booked.Status = models.WaitlistStatusBooked
+	otherClient := waitingEntry("other", "p7", 9, "2024-01-01T00:00:00Z")
+	otherClient.ClientID = "client2"
+	m.repo.On("GetByDoctorID", ctx, "doc1").Return([]*models.WaitlistEntry{
+		waitingEntry("late", "p2", 0, "2024-01-10T00:00:00Z"),
+		waitingEntry("early", "p3", 0, "2024-01-05T00:00:00Z"),
+		waitingEntry("urgent", "p4", 5, "2024-01-12T00:00:00Z"),
+		waitingEntry("same", "p1", 9, "2024-01-01T00:00:00Z"),
+		outOfRange, booked, otherClient,
+	}, nil)
+	var saved *models.WaitlistOffer
+	m.repo.On("SaveOffer", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*models.WaitlistOffer)
+	}).Return(nil).Once()
+	m.repo.On("MarkNotified", ctx, mock.Anything, "urgent").Return(true, nil).Once()
+	m.repo.On("GetByID", ctx, "urgent").Return(waitingEntry("urgent", "p4", 5, ""), nil)
+	m.patients.On("GetByID", ctx, "p4").Return(&models.Patient{FirstName: "Ana", PhoneNumber: "+5491155551234"}, nil)
+	m.sms.On("Send", ctx, mock.MatchedBy(func(msg notify.Message) bool {
+		return msg.To == "+5491155551234" &&
+			assert.Contains(t, msg.Body, "el 15/01/2024 a las 14:00") &&
+			assert.Contains(t, msg.Body, "antes de las 09:30") &&
+			assert.Contains(t, msg.Body, "entry_id=urgent")
+	})).Return(nil).Once()
+
+	// Turno de las 14:00 en Buenos Aires (17:00 UTC) del paciente p1
+	err := w.OfferSlot(ctx, &models.Appointment{ID: "appt1", ClientID: "client1", DoctorID: "doc1", PatientID: "p1", Date: "2024-01-15T14:00:00-03:00", Duration: 30})
+
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.Equal(t, []string{"urgent", "early", "late"}, saved.Candidates)
+	assert.Equal(t, "appt1", saved.SourceAppointmentID)
+	// Tres tandas de 30 minutos
+	assert.Equal(t, now.Add(90*time.Minute).Format(time.RFC3339), saved.ExpiresAt)
+	m.sms.AssertExpectations(t)
+}
+
+func TestWaitlist_OfferSlot_LimitsCandidatesToRoundsBeforeStart(t *testing.T) {
+	w, m := setupTest(t)
+	ctx := context.Background()
+	m.repo.On("GetByDoctorID", ctx, "doc1").Return([]*models.WaitlistEntry{
+		waitingEntry("e1", "p1", 0, "1"), waitingEntry("e2", "p2", 0, "2"), waitingEntry("e3", "p3", 0, "3"),
+	}, nil)
+	var saved *models.WaitlistOffer
+	m.repo.On("SaveOffer", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*models.WaitlistOffer)
+	}).Return(nil)
+	w.Config.Channels = nil
+
+	// Faltan 40 minutos: alcanzan para dos tandas
+	err := w.OfferSlot(ctx, &models.Appointment{ID: "appt1", ClientID: "client1", DoctorID: "doc1", Date: now.Add(40 * time.Minute).Format(time.RFC3339), Duration: 30})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e1", "e2"}, saved.Candidates)
+	assert.Equal(t, now.Add(40*time.Minute).Format(time.RFC3339), saved.ExpiresAt)
+}
+
+func TestWaitlist_OfferSlot_NoCandidates(t *testing.T) {
+	w, m := setupTest(t)
+	ctx := context.Background()
+	m.repo.On("GetByDoctorID", ctx, "doc1").Return([]*models.WaitlistEntry{}, nil)
+
+	// Un turno pasado no se ofrece
+	require.NoError(t, w.OfferSlot(ctx, &models.Appointment{DoctorID: "doc1", Date: now.Add(-time.Hour).Format(time.RFC3339)}))
+	require.NoError(t, w.OfferSlot(ctx, &models.Appointment{DoctorID: "doc1", Date: now.Add(time.Hour).Format(time.RFC3339)}))
+
+	m.repo.AssertNumberOfCalls(t, "GetByDoctorID", 1)
+	m.repo.AssertNotCalled(t, "SaveOffer", mock.Anything, mock.Anything)
+}
+
+func TestWaitlist_Accept(t *testing.T) {
+	w, m := setupTest(t)
+	ctx := context.Background()
+	// Pasaron 40 minutos: es la tanda de e2
+	m.repo.On("GetOffer", ctx, "offer1").Return(openOffer("e1", "e2", "e3"), nil)
+	m.repo.On("GetByID", ctx, "e2").Return(waitingEntry("e2", "p2", 0, ""), nil)
+	m.appointments.On("GetByDoctorID", ctx, "doc1").Return([]*models.Appointment{
+		{ID: "appt1", Date: "2024-01-15T14:00:00-03:00", Duration: 30, Status: models.AppointmentStatusCancelled},
+		{ID: "appt2", Date: "2024-01-15T14:30:00-03:00", Duration: 30, Status: models.AppointmentStatusScheduled},
+	}, nil)
+	m.repo.On("ClaimOffer", ctx, "offer1", "e2").Return(true, nil).Once()
+	m.creator.On("CreateAppointment", ctx, mock.MatchedBy(func(r *models.AppointmentRequest) bool {
+		return r.PatientID == "p2" && r.DoctorID == "doc1" && r.Date == "2024-01-15T14:00:00-03:00" && r.Duration == 30 &&
+			r.Status == models.AppointmentStatusScheduled && r.Metadata["waitlist_offer_id"] == "offer1"
+	})).Return(&models.AppointmentRequest{ID: "new1", Date: "2024-01-15T14:00:00-03:00"}, nil).Once()
+	m.repo.On("Save", ctx, mock.MatchedBy(func(e *models.WaitlistEntry) bool {
+		return e.Status == models.WaitlistStatusBooked && e.AppointmentID == "new1"
+	})).Return(nil).Once()
+
+	created, err := w.Accept(ctx, &models.WaitlistAcceptRequest{OfferID: "offer1", EntryID: "e2"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "new1", created.ID)
+	m.repo.AssertExpectations(t)
+	m.creator.AssertExpectations(t)
+}
+
+func TestWaitlist_Accept_Rounds(t *testing.T) {
+	w, m := setupTest(t)
+	ctx := context.Background()
+	m.repo.On("GetOffer", ctx, "offer1").Return(openOffer("e1", "e2", "e3"), nil)
+
+	// e1 tuvo su tanda y venció; e3 todavía no recibió la oferta
+	_, err := w.Accept(ctx, &models.WaitlistAcceptRequest{OfferID: "offer1", EntryID: "e1"})
+	assert.ErrorIs(t, err, ErrOfferExpired)
+	_, err = w.Accept(ctx, &models.WaitlistAcceptRequest{OfferID: "offer1", EntryID: "e3"})
+	assert.ErrorIs(t, err, ErrOfferNotFound)
+	_, err = w.Accept(ctx, &models.WaitlistAcceptRequest{OfferID: "offer1", EntryID: "stranger"})
+	assert.ErrorIs(t, err, ErrOfferNotFound)
+	m.repo.AssertNotCalled(t, "ClaimOffer", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWaitlist_Accept_AlreadyTaken(t *testing.T) {
+	w, m := setupTest(t)
+	ctx := context.Background()
+	offer := openOffer("e1", "e2")
+	offer.Batch, offer.RoundSeconds = 2, 3600
+	m.repo.On("GetOffer", ctx, "offer1").Return(offer, nil)
+	m.repo.On("GetByID", ctx, "e2").Return(waitingEntry("e2", "p2", 0, ""), nil)
+	m.appointments.On("GetByDoctorID", ctx, "doc1").Return([]*models.Appointment{}, nil)
+	// e1 aceptó entre la lectura y la escritura
+	m.repo.On("ClaimOffer", ctx, "offer1", "e2").Return(false, nil).Once()
+
+	_, err := w.Accept(ctx, &models.WaitlistAcceptRequest{OfferID: "offer1", EntryID: "e2"})
+
+	assert.ErrorIs(t, err, ErrOfferTaken)
+	m.creator.AssertNotCalled(t, "CreateAppointment", mock.Anything, mock.Anything)
+}
+
+func TestWaitlist_Accept_SlotBookedMeanwhile(t *testing.T) {
+	w, m := setupTest(t)
+	ctx := context.Background()
+	m.repo.On("GetOffer", ctx, "offer1").Return(openOffer("e1", "e2"), nil)
+	m.repo.On("GetByID", ctx, "e2").Return(waitingEntry("e2", "p2", 0, ""), nil)
+	m.appointments.On("GetByDoctorID", ctx, "doc1").Return([]*models.Appointment{
+		{ID: "walkin", Date: "2024-01-15T17:15:00Z", Duration: 30, Status: models.AppointmentStatusScheduled},
+	}, nil)
+	m.repo.On("ExpireOffer", ctx, "offer1").Return(true, nil).Once()
+
+	_, err := w.Accept(ctx, &models.WaitlistAcceptRequest{OfferID: "offer1", EntryID: "e2"})
+
+	assert.ErrorIs(t, err, ErrOfferTaken)
+	m.repo.AssertExpectations(t)
+	m.repo.AssertNotCalled(t, "ClaimOffer", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Otro alta tomó el horario entre la revisión y la escritura del turno
+func TestWaitlist_Accept_SlotLockedOnCreate(t *testing.T) {
+	w, m := setupTest(t)
+	ctx := context.Background()
+	m.repo.On("GetOffer", ctx, "offer1").Return(openOffer("e1", "e2"), nil)
+	m.repo.On("GetByID", ctx, "e2").Return(waitingEntry("e2", "p2", 0, ""), nil)
+	m.appointments.On("GetByDoctorID", ctx, "doc1").Return([]*models.Appointment{}, nil)
+	m.repo.On("ClaimOffer", ctx, "offer1", "e2").Return(true, nil).Once()
+	m.creator.On("CreateAppointment", ctx, mock.Anything).Return(nil, appointments.ErrSlotHeld).Once()
+	m.repo.On("ExpireOffer", ctx, "offer1").Return(true, nil).Once()
+
+	_, err := w.Accept(ctx, &models.WaitlistAcceptRequest{OfferID: "offer1", EntryID: "e2"})
+
+	assert.ErrorIs(t, err, ErrOfferTaken)
+	m.repo.AssertExpectations(t)
+	m.repo.AssertNotCalled(t, "ReleaseOffer", mock.Anything, mock.Anything)
+	m.repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestWaitlist_Accept_CreateFailsReleasesOffer(t *testing.T) {
+	w, m := setupTest(t)
+	ctx := context.Background()
+	m.repo.On("GetOffer", ctx, "offer1").Return(openOffer("e1", "e2"), nil)
+	m.repo.On("GetByID", ctx, "e2").Return(waitingEntry("e2", "p2", 0, ""), nil)
+	m.appointments.On("GetByDoctorID", ctx, "doc1").Return([]*models.Appointment{}, nil)
+	m.repo.On("ClaimOffer", ctx, "offer1", "e2").Return(true, nil).Once()
+	m.creator.On("CreateAppointment", ctx, mock.Anything).Return(nil, errors.New("patient has too many no-shows")).Once()
+	m.repo.On("ReleaseOffer", ctx, "offer1").Return(nil).Once()
+
+	_, err := w.Accept(ctx, &models.WaitlistAcceptRequest{OfferID: "offer1", EntryID: "e2"})
+
+	assert.Error(t, err)
+	m.repo.AssertExpectations(t)
+	m.repo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestWaitlist_NotifyOffers(t *testing.T) {
+	w, m := setupTest(t)
+	ctx := context.Background()
+	current := openOffer("e1", "e2")
+	current.Notified = []string{"e1"}
+	expired := openOffer("e3")
+	expired.ID, expired.ExpiresAt = "offer2", now.Add(-time.Minute).Format(time.RFC3339)
+	failing := openOffer("e4", "e5")
+	failing.ID = "offer3"
+	m.repo.On("GetOpenOffers", ctx).Return([]*models.WaitlistOffer{current, expired, failing}, nil)
+	m.repo.On("ExpireOffer", ctx, "offer2").Return(true, nil).Once()
+
+	m.repo.On("MarkNotified", ctx, "offer1", "e2").Return(true, nil).Once()
+	m.repo.On("GetByID", ctx, "e2").Return(waitingEntry("e2", "p2", 0, ""), nil)
+	m.patients.On("GetByID", ctx, "p2").Return(&models.Patient{FirstName: "Juan", PhoneNumber: "+5491155550000"}, nil)
+	m.sms.On("Send", ctx, mock.MatchedBy(func(msg notify.Message) bool { return msg.To == "+5491155550000" })).Return(nil).Once()
+
+	m.repo.On("MarkNotified", ctx, "offer3", "e5").Return(true, nil).Once()
+	m.repo.On("GetByID", ctx, "e5").Return(waitingEntry("e5", "p5", 0, ""), nil)
+	m.patients.On("GetByID", ctx, "p5").Return(&models.Patient{FirstName: "Luz", PhoneNumber: "+5491155559999"}, nil)
+	m.sms.On("Send", ctx, mock.MatchedBy(func(msg notify.Message) bool { return msg.To == "+5491155559999" })).Return(errors.New("throttled")).Once()
+	m.repo.On("UnmarkNotified", ctx, "offer3", "e5").Return(nil).Once()
+
+	report, err := w.NotifyOffers(ctx, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, &models.WaitlistReport{Offers: 3, Notified: 1, Expired: 1, Failed: 1}, report)
+	m.repo.AssertExpectations(t)
+	m.sms.AssertExpectations(t)
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, &Config{Round: defaultRound, Batch: defaultBatch}, cfg)
+
+	cfg, err = ParseConfig(`{"round":"15m","batch":3,"channels":["sms"]}`)
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, cfg.Round)
+	assert.Equal(t, 3, cfg.Batch)
+
+	for _, raw := range []string{`{"round":"0s"}`, `{"batch":-1}`, `nope`} {
+		_, err := ParseConfig(raw)
+		assert.ErrorIs(t, err, ErrInvalidConfig, raw)
+	}
+}
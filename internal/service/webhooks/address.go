@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress es un endpoint que resuelve a una dirección interna:
+// loopback, red privada, link-local (como el servicio de metadata de la
+// instancia en 169.254.169.254) o sin especificar.
+var ErrForbiddenAddress = errors.New("webhook address not allowed")
+
+// reservedNetworks son rangos internos que net.IP no marca como privados:
+// "esta red" (RFC 1122), que en Linux llega al host local; CGNAT (RFC 6598);
+// y los prefijos de NAT64 (RFC 6052 y 8215), que traducen a cualquier IPv4,
+// incluidas las privadas.
+var reservedNetworks = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+	{IP: net.ParseIP("64:ff9b::"), Mask: net.CIDRMask(96, 128)},
+	{IP: net.ParseIP("64:ff9b:1::"), Mask: net.CIDRMask(48, 128)},
+}
+
+func forbidden(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() {
+		return true
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost resuelve host y falla si alguna de sus direcciones es interna.
+func checkHost(ctx context.Context, lookup func(context.Context, string, string) ([]net.IP, error), host string) error {
+	ips, err := lookup(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if forbidden(ip) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenAddress, host, ip)
+		}
+	}
+	return nil
+}
+
+// NewHTTPClient es el cliente de las entregas. Revisa la dirección al
+// conectar, después de resolver, así un DNS que cambia después de Subscribe
+// o una redirección no llegan a la red interna. No usa proxy: la conexión
+// tiene que ir directo a la dirección revisada.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || forbidden(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
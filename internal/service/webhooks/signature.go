@@ -0,0 +1,60 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader lleva la firma de cada entrega: "t=<unix>,v1=<hex>", con
+// v1 = HMAC-SHA256(secret, "<unix>.<body>"). El timestamp entra en la firma
+// para que el receptor pueda rechazar entregas viejas repetidas.
+const SignatureHeader = "X-Iris-Signature"
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign firma body con secret para el instante timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac(secret, unix, body))
+}
+
+// Verify comprueba una firma de SignatureHeader como lo haría la clínica:
+// que coincida y que no tenga más de tolerance respecto de now.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var unix, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signature = value
+		}
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing timestamp", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp out of tolerance", ErrInvalidSignature)
+	}
+	decoded, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(decoded, mac(secret, unix, body)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func mac(secret, unix string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(unix))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,554 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/secrets"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidSubscription  = errors.New("invalid webhook subscription")
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+)
+
+const (
+	// AllEvents en el filtro de una suscripción recibe todos los tipos
+	AllEvents = "*"
+
+	EventHeader    = "X-Iris-Event"
+	DeliveryHeader = "X-Iris-Delivery"
+
+	defaultMaxAttempts = 8
+	defaultBaseBackoff = 30 * time.Second
+	defaultMaxBackoff  = 6 * time.Hour
+	defaultBatchSize   = 50
+	defaultTimeout     = 10 * time.Second
+	// claimTimeout es cuánto se reserva una entrega mientras se envía; tiene
+	// que superar al timeout del cliente HTTP
+	claimTimeout = time.Minute
+	// deliveredRetention es cuánto queda en el registro una entrega exitosa
+	deliveredRetention = 30 * 24 * time.Hour
+	// maxErrorBody es cuánto de la respuesta del receptor se guarda como error
+	maxErrorBody = 256
+)
+
+type WebhooksRepository interface {
+	SaveSubscription(ctx context.Context, s *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error)
+	GetSubscriptionsByClientID(ctx context.Context, clientID string) ([]*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	SealSecret(ctx context.Context, id, ciphertext string) error
+	CreateDelivery(ctx context.Context, d *models.WebhookDelivery) (bool, error)
+	SaveDelivery(ctx context.Context, d *models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	GetDeliveriesByClientID(ctx context.Context, clientID, status string) ([]*models.WebhookDelivery, error)
+	GetDueDeliveries(ctx context.Context, now string, limit int32) ([]*models.WebhookDelivery, error)
+	ClaimDelivery(ctx context.Context, id, due, until string) (bool, error)
+}
+
+type WebhooksService interface {
+	Subscribe(context.Context, *models.WebhookSubscriptionRequest) (*models.WebhookSubscriptionRequest, error)
+	GetSubscriptions(ctx context.Context, clientID string) ([]*models.WebhookSubscriptionRequest, error)
+	Unsubscribe(ctx context.Context, clientID, id string) error
+	Publish(ctx context.Context, evts []*events.Event) (map[string]error, error)
+	Deliver(ctx context.Context, now time.Time) (*models.WebhookReport, error)
+	GetDeliveries(ctx context.Context, clientID, status string) ([]*models.WebhookDeliveryRequest, error)
+	Replay(ctx context.Context, clientID, id string) (*models.WebhookDeliveryRequest, error)
+}
+
+// Retry es la política de reintentos: el intento n falla y el siguiente sale
+// a Base*2^(n-1), como mucho a Max. Después de MaxAttempts fallas la entrega
+// pasa a DEAD.
+type Retry struct {
+	MaxAttempts int
+	Base        time.Duration
+	Max         time.Duration
+}
+
+// RetryFromEnv lee WEBHOOK_MAX_ATTEMPTS, WEBHOOK_BACKOFF_BASE y
+// WEBHOOK_BACKOFF_MAX (duraciones de Go, por ejemplo "30s" o "6h").
+func RetryFromEnv() (Retry, error) {
+	retry := Retry{MaxAttempts: defaultMaxAttempts, Base: defaultBaseBackoff, Max: defaultMaxBackoff}
+	if raw := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); raw != "" {
+		attempts, err := strconv.Atoi(raw)
+		if err != nil || attempts <= 0 {
+			return Retry{}, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS %q", raw)
+		}
+		retry.MaxAttempts = attempts
+	}
+	for _, d := range []struct {
+		name   string
+		target *time.Duration
+	}{{"WEBHOOK_BACKOFF_BASE", &retry.Base}, {"WEBHOOK_BACKOFF_MAX", &retry.Max}} {
+		raw := os.Getenv(d.name)
+		if raw == "" {
+			continue
+		}
+		value, err := time.ParseDuration(raw)
+		if err != nil || value <= 0 {
+			return Retry{}, fmt.Errorf("invalid %s %q", d.name, raw)
+		}
+		*d.target = value
+	}
+	return retry, nil
+}
+
+// backoff es la espera después de attempts intentos fallidos.
+func (r Retry) backoff(attempts int) time.Duration {
+	wait := r.Base
+	for i := 1; i < attempts && wait < r.Max; i++ {
+		wait *= 2
+	}
+	return min(wait, r.Max)
+}
+
+// Webhooks avisa a los sistemas de las clínicas de los eventos de dominio.
+// Publish lo conecta al relay del outbox y deja una entrega pendiente por
+// suscripción; Deliver las envía firmadas y reintenta las que fallan.
+type Webhooks struct {
+	Logger     *zap.SugaredLogger
+	Repository WebhooksRepository
+	HTTPClient *http.Client
+	Retry      Retry
+	BatchSize  int32
+	Now        func() time.Time
+	// LookupIP resuelve el host de una suscripción; por defecto, el DNS del
+	// sistema
+	LookupIP func(ctx context.Context, network, host string) ([]net.IP, error)
+	// Secrets cifra los secretos de firma; lo necesitan Subscribe y Deliver
+	Secrets secrets.Cipher
+}
+
+func New(logger *zap.SugaredLogger, repository WebhooksRepository, client *http.Client, retry Retry, cipher secrets.Cipher) WebhooksService {
+	if client == nil {
+		client = NewHTTPClient(defaultTimeout)
+	}
+	return &Webhooks{
+		Logger:     logger,
+		Repository: repository,
+		HTTPClient: client,
+		Retry:      retry,
+		BatchSize:  defaultBatchSize,
+		Now:        time.Now,
+		LookupIP:   net.DefaultResolver.LookupIP,
+		Secrets:    cipher,
+	}
+}
+
+// Subscribe registra el endpoint de la clínica. Si no manda secreto se
+// genera uno; es la única vez que se devuelve en claro, se guarda cifrado
+// con KMS. El host tiene que resolver a
+// direcciones públicas: las entregas salen desde nuestra red.
+func (w *Webhooks) Subscribe(ctx context.Context, request *models.WebhookSubscriptionRequest) (*models.WebhookSubscriptionRequest, error) {
+	if request.ClientID == "" {
+		return nil, fmt.Errorf("%w: client_id is required", ErrInvalidSubscription)
+	}
+	endpoint, err := url.Parse(request.URL)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute https URL", ErrInvalidSubscription)
+	}
+	if err := checkHost(ctx, w.lookupIP(), endpoint.Hostname()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+	}
+	if len(request.Events) == 0 {
+		return nil, fmt.Errorf("%w: events is required", ErrInvalidSubscription)
+	}
+	eventTypes := slices.Compact(slices.Sorted(slices.Values(request.Events)))
+	for _, eventType := range eventTypes {
+		if eventType != AllEvents && !slices.Contains(events.Types, eventType) {
+			return nil, fmt.Errorf("%w: unknown event type %s", ErrInvalidSubscription, eventType)
+		}
+	}
+	secret := request.Secret
+	if secret == "" {
+		if secret, err = newSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	subscription := &models.WebhookSubscription{
+		ID:        uuid.New().String(),
+		ClientID:  request.ClientID,
+		URL:       request.URL,
+		Events:    eventTypes,
+		CreatedAt: w.now().Format(time.RFC3339),
+	}
+	if subscription.SecretCiphertext, err = w.encrypt(ctx, subscription, secret); err != nil {
+		w.log(ctx).Error("Error encrypting webhook secret", zap.Error(err))
+		return nil, err
+	}
+	if err := w.Repository.SaveSubscription(ctx, subscription); err != nil {
+		w.log(ctx).Error("Error on WebhooksRepository.SaveSubscription", zap.Error(err))
+		return nil, err
+	}
+	response := mapSubscriptionToRequest(subscription)
+	response.Secret = secret
+	return response, nil
+}
+
+func (w *Webhooks) GetSubscriptions(ctx context.Context, clientID string) ([]*models.WebhookSubscriptionRequest, error) {
+	subscriptions, err := w.Repository.GetSubscriptionsByClientID(ctx, clientID)
+	if err != nil {
+		w.log(ctx).Error("Error on WebhooksRepository.GetSubscriptionsByClientID", zap.String("clientID", clientID), zap.Error(err))
+		return nil, err
+	}
+	results := make([]*models.WebhookSubscriptionRequest, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		results = append(results, mapSubscriptionToRequest(subscription))
+	}
+	return results, nil
+}
+
+// Unsubscribe borra la suscripción. Sus entregas pendientes pasan a DEAD en
+// el próximo intento.
+func (w *Webhooks) Unsubscribe(ctx context.Context, clientID, id string) error {
+	subscription, err := w.Repository.GetSubscription(ctx, id)
+	if err != nil {
+		w.log(ctx).Error("Error on WebhooksRepository.GetSubscription", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	if subscription == nil || subscription.ClientID != clientID {
+		return ErrSubscriptionNotFound
+	}
+	if err := w.Repository.DeleteSubscription(ctx, id); err != nil {
+		w.log(ctx).Error("Error on WebhooksRepository.DeleteSubscription", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Publish deja una entrega pendiente por cada suscripción del tenant que
+// filtra el evento. Implementa events.Publisher para sumarse al relay.
+// Reintentar es seguro: el relay vuelve a publicar un evento si falló en
+// otro publicador o si se cortó antes de marcarlo, pero el ID de la entrega
+// se deriva de la suscripción y el evento, y CreateDelivery no pisa una que
+// ya existe, así que cada suscripción lo recibe una sola vez.
+func (w *Webhooks) Publish(ctx context.Context, evts []*events.Event) (map[string]error, error) {
+	failed := map[string]error{}
+	subscriptions := map[string][]*models.WebhookSubscription{}
+	now := w.now().UTC()
+	for _, event := range evts {
+		if _, ok := subscriptions[event.ClientID]; !ok {
+			found, err := w.Repository.GetSubscriptionsByClientID(ctx, event.ClientID)
+			if err != nil {
+				failed[event.ID] = err
+				continue
+			}
+			subscriptions[event.ClientID] = found
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			failed[event.ID] = err
+			continue
+		}
+		for _, subscription := range subscriptions[event.ClientID] {
+			if !matches(subscription, event.Type) {
+				continue
+			}
+			delivery := &models.WebhookDelivery{
+				ID:             uuid.NewSHA1(uuid.NameSpaceOID, []byte(subscription.ID+"#"+event.ID)).String(),
+				SubscriptionID: subscription.ID,
+				ClientID:       event.ClientID,
+				EventID:        event.ID,
+				EventType:      event.Type,
+				Payload:        string(payload),
+				Status:         models.WebhookDeliveryPending,
+				NextAttemptAt:  now.Format(time.RFC3339),
+				CreatedAt:      now.Format(time.RFC3339),
+			}
+			if _, err := w.Repository.CreateDelivery(ctx, delivery); err != nil {
+				failed[event.ID] = err
+			}
+		}
+	}
+	return failed, nil
+}
+
+// Deliver envía las entregas que ya tocan. Cada una se reserva antes de
+// enviarla, así dos corridas superpuestas no avisan dos veces.
+func (w *Webhooks) Deliver(ctx context.Context, now time.Time) (*models.WebhookReport, error) {
+	limit := w.BatchSize
+	if limit <= 0 {
+		limit = defaultBatchSize
+	}
+	due, err := w.Repository.GetDueDeliveries(ctx, now.UTC().Format(time.RFC3339), limit)
+	if err != nil {
+		w.log(ctx).Error("Error on WebhooksRepository.GetDueDeliveries", zap.Error(err))
+		return nil, err
+	}
+
+	report := &models.WebhookReport{}
+	var errs []error
+	for _, delivery := range due {
+		claimed, err := w.Repository.ClaimDelivery(ctx, delivery.ID, delivery.NextAttemptAt, now.Add(claimTimeout).UTC().Format(time.RFC3339))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		report.Due++
+		if err := w.attempt(ctx, delivery, now); err != nil {
+			w.log(ctx).Error("Error saving webhook delivery", zap.String("id", delivery.ID), zap.Error(err))
+			errs = append(errs, err)
+			continue
+		}
+		switch delivery.Status {
+		case models.WebhookDeliveryDelivered:
+			report.Delivered++
+		case models.WebhookDeliveryDead:
+			report.Dead++
+		default:
+			report.Retrying++
+		}
+	}
+	w.log(ctx).Info("Webhooks delivered", zap.Int("delivered", report.Delivered), zap.Int("retrying", report.Retrying), zap.Int("dead", report.Dead))
+	return report, errors.Join(errs...)
+}
+
+func (w *Webhooks) GetDeliveries(ctx context.Context, clientID, status string) ([]*models.WebhookDeliveryRequest, error) {
+	deliveries, err := w.Repository.GetDeliveriesByClientID(ctx, clientID, status)
+	if err != nil {
+		w.log(ctx).Error("Error on WebhooksRepository.GetDeliveriesByClientID", zap.String("clientID", clientID), zap.Error(err))
+		return nil, err
+	}
+	results := make([]*models.WebhookDeliveryRequest, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		results = append(results, mapDeliveryToRequest(delivery))
+	}
+	return results, nil
+}
+
+// Replay vuelve a enviar una entrega del registro, por ejemplo una DEAD
+// después de que la clínica arregló su endpoint. Se registra como una
+// entrega nueva que se intenta en el momento y, si falla, sigue los
+// reintentos normales.
+func (w *Webhooks) Replay(ctx context.Context, clientID, id string) (*models.WebhookDeliveryRequest, error) {
+	original, err := w.Repository.GetDelivery(ctx, id)
+	if err != nil {
+		w.log(ctx).Error("Error on WebhooksRepository.GetDelivery", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	if original == nil || original.ClientID != clientID {
+		return nil, ErrDeliveryNotFound
+	}
+
+	now := w.now().UTC()
+	delivery := &models.WebhookDelivery{
+		ID:             uuid.New().String(),
+		SubscriptionID: original.SubscriptionID,
+		ClientID:       original.ClientID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  now.Add(claimTimeout).Format(time.RFC3339),
+		ReplayOf:       original.ID,
+		CreatedAt:      now.Format(time.RFC3339),
+	}
+	if _, err := w.Repository.CreateDelivery(ctx, delivery); err != nil {
+		w.log(ctx).Error("Error on WebhooksRepository.CreateDelivery", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	if err := w.attempt(ctx, delivery, now); err != nil {
+		w.log(ctx).Error("Error saving webhook delivery", zap.String("id", delivery.ID), zap.Error(err))
+		return nil, err
+	}
+	return mapDeliveryToRequest(delivery), nil
+}
+
+// attempt envía la entrega y guarda el resultado. El error es sólo el de
+// guardarla: que el receptor falle se registra en la entrega.
+func (w *Webhooks) attempt(ctx context.Context, delivery *models.WebhookDelivery, now time.Time) error {
+	subscription, err := w.Repository.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	delivery.Attempts++
+	delivery.LastStatusCode = 0
+	switch {
+	case subscription == nil:
+		delivery.Status = models.WebhookDeliveryDead
+		delivery.LastError = "subscription removed"
+	default:
+		delivery.LastStatusCode, err = w.send(ctx, subscription, delivery, now)
+		switch {
+		case err == nil:
+			delivery.Status = models.WebhookDeliveryDelivered
+			delivery.LastError = ""
+			delivery.DeliveredAt = now.UTC().Format(time.RFC3339)
+			delivery.ExpiresAt = now.Add(deliveredRetention).Unix()
+		case delivery.Attempts >= w.maxAttempts():
+			delivery.Status = models.WebhookDeliveryDead
+			delivery.LastError = err.Error()
+			w.log(ctx).Warn("Webhook delivery dead-lettered", zap.String("id", delivery.ID), zap.String("subscriptionID", subscription.ID), zap.Error(err))
+		default:
+			delivery.LastError = err.Error()
+			delivery.NextAttemptAt = now.Add(w.retry().backoff(delivery.Attempts)).UTC().Format(time.RFC3339)
+		}
+	}
+	return w.Repository.SaveDelivery(ctx, delivery)
+}
+
+// send hace el POST firmado. Cualquier respuesta fuera de 2xx es una falla.
+func (w *Webhooks) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "iris-webhooks/1")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	secret, err := w.secret(ctx, subscription)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(SignatureHeader, Sign(secret, now, body))
+
+	resp, err := w.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, fmt.Errorf("status %d: %s", resp.StatusCode, snippet)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// encrypt cifra el secreto de subscription con su ID y cliente como
+// contexto, así el texto cifrado no sirve copiado a otra suscripción.
+func (w *Webhooks) encrypt(ctx context.Context, subscription *models.WebhookSubscription, secret string) (string, error) {
+	if w.Secrets == nil {
+		return "", secrets.ErrNotConfigured
+	}
+	return w.Secrets.Encrypt(ctx, secret, secretScope(subscription))
+}
+
+// secret descifra el secreto de firma. Las suscripciones anteriores al
+// cifrado lo tienen en claro: se usa y se guarda cifrado para la próxima.
+func (w *Webhooks) secret(ctx context.Context, subscription *models.WebhookSubscription) (string, error) {
+	if subscription.SecretCiphertext == "" {
+		ciphertext, err := w.encrypt(ctx, subscription, subscription.Secret)
+		if err == nil {
+			err = w.Repository.SealSecret(ctx, subscription.ID, ciphertext)
+		}
+		if err != nil {
+			w.log(ctx).Warn("Error sealing webhook secret", zap.String("subscriptionID", subscription.ID), zap.Error(err))
+		}
+		return subscription.Secret, nil
+	}
+	if w.Secrets == nil {
+		return "", secrets.ErrNotConfigured
+	}
+	return w.Secrets.Decrypt(ctx, subscription.SecretCiphertext, secretScope(subscription))
+}
+
+func secretScope(subscription *models.WebhookSubscription) map[string]string {
+	return map[string]string{"subscription_id": subscription.ID, "client_id": subscription.ClientID}
+}
+
+func (w *Webhooks) now() time.Time {
+	if w.Now == nil {
+		return time.Now()
+	}
+	return w.Now()
+}
+
+func (w *Webhooks) lookupIP() func(context.Context, string, string) ([]net.IP, error) {
+	if w.LookupIP == nil {
+		return net.DefaultResolver.LookupIP
+	}
+	return w.LookupIP
+}
+
+func (w *Webhooks) retry() Retry {
+	retry := w.Retry
+	if retry.Base <= 0 {
+		retry.Base = defaultBaseBackoff
+	}
+	if retry.Max <= 0 {
+		retry.Max = defaultMaxBackoff
+	}
+	return retry
+}
+
+func (w *Webhooks) maxAttempts() int {
+	if w.Retry.MaxAttempts <= 0 {
+		return defaultMaxAttempts
+	}
+	return w.Retry.MaxAttempts
+}
+
+func matches(subscription *models.WebhookSubscription, eventType string) bool {
+	return slices.Contains(subscription.Events, AllEvents) || slices.Contains(subscription.Events, eventType)
+}
+
+func newSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+func mapSubscriptionToRequest(s *models.WebhookSubscription) *models.WebhookSubscriptionRequest {
+	return &models.WebhookSubscriptionRequest{
+		ID:        s.ID,
+		ClientID:  s.ClientID,
+		URL:       s.URL,
+		Events:    s.Events,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+func mapDeliveryToRequest(d *models.WebhookDelivery) *models.WebhookDeliveryRequest {
+	response := &models.WebhookDeliveryRequest{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		ReplayOf:       d.ReplayOf,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+	if d.Status == models.WebhookDeliveryPending {
+		response.NextAttemptAt = d.NextAttemptAt
+	}
+	return response
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del servicio.
+func (w *Webhooks) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, w.Logger)
+}
@@ -0,0 +1,505 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MezeLaw/iris-services/internal/events"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/MezeLaw/iris-services/internal/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// memoryRepository guarda todo en memoria para recorrer el circuito completo
+// contra servidores httptest, con las mismas condiciones que el repositorio
+// de DynamoDB.
+type memoryRepository struct {
+	mu            sync.Mutex
+	subscriptions map[string]*models.WebhookSubscription
+	deliveries    map[string]*models.WebhookDelivery
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{subscriptions: map[string]*models.WebhookSubscription{}, deliveries: map[string]*models.WebhookDelivery{}}
+}
+
+func (m *memoryRepository) SaveSubscription(_ context.Context, s *models.WebhookSubscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *s
+	m.subscriptions[s.ID] = &copied
+	return nil
+}
+
+func (m *memoryRepository) GetSubscription(_ context.Context, id string) (*models.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.subscriptions[id]; ok {
+		copied := *s
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (m *memoryRepository) SealSecret(_ context.Context, id, ciphertext string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.subscriptions[id]; ok {
+		s.Secret, s.SecretCiphertext = "", ciphertext
+	}
+	return nil
+}
+
+// scopedCipher "cifra" anteponiendo el contexto y exige el mismo al
+// descifrar, como KMS.
+type scopedCipher struct{}
+
+func (scopedCipher) Encrypt(_ context.Context, plaintext string, scope map[string]string) (string, error) {
+	return scope["client_id"] + "/" + scope["subscription_id"] + ":" + hex.EncodeToString([]byte(plaintext)), nil
+}
+
+func (scopedCipher) Decrypt(_ context.Context, ciphertext string, scope map[string]string) (string, error) {
+	prefix := scope["client_id"] + "/" + scope["subscription_id"] + ":"
+	if !strings.HasPrefix(ciphertext, prefix) {
+		return "", errors.New("encryption context mismatch")
+	}
+	plaintext, err := hex.DecodeString(strings.TrimPrefix(ciphertext, prefix))
+	return string(plaintext), err
+}
+
+func (m *memoryRepository) GetSubscriptionsByClientID(_ context.Context, clientID string) ([]*models.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var results []*models.WebhookSubscription
+	for _, s := range m.subscriptions {
+		if s.ClientID == clientID {
+			copied := *s
+			results = append(results, &copied)
+		}
+	}
+	return results, nil
+}
+
+func (m *memoryRepository) DeleteSubscription(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.subscriptions, id)
+	return nil
+}
+
+func (m *memoryRepository) CreateDelivery(_ context.Context, d *models.WebhookDelivery) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.deliveries[d.ID]; ok {
+		return false, nil
+	}
+	copied := *d
+	m.deliveries[d.ID] = &copied
+	return true, nil
+}
+
+func (m *memoryRepository) SaveDelivery(_ context.Context, d *models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *d
+	m.deliveries[d.ID] = &copied
+	return nil
+}
+
+func (m *memoryRepository) GetDelivery(_ context.Context, id string) (*models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if d, ok := m.deliveries[id]; ok {
+		copied := *d
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (m *memoryRepository) GetDeliveriesByClientID(_ context.Context, clientID, status string) ([]*models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var results []*models.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.ClientID == clientID && (status == "" || d.Status == status) {
+			copied := *d
+			results = append(results, &copied)
+		}
+	}
+	return results, nil
+}
+
+func (m *memoryRepository) GetDueDeliveries(_ context.Context, now string, limit int32) ([]*models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var results []*models.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.Status == models.WebhookDeliveryPending && d.NextAttemptAt <= now {
+			copied := *d
+			results = append(results, &copied)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].NextAttemptAt < results[j].NextAttemptAt })
+	if int32(len(results)) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (m *memoryRepository) ClaimDelivery(_ context.Context, id, due, until string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.deliveries[id]
+	if !ok || d.Status != models.WebhookDeliveryPending || d.NextAttemptAt != due {
+		return false, nil
+	}
+	d.NextAttemptAt = until
+	return true, nil
+}
+
+// receiver es el endpoint de una clínica: verifica la firma y responde con
+// los códigos de statuses en orden (200 cuando se acaban).
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	statuses []int
+	received []*events.Event
+	server   *httptest.Server
+}
+
+func newReceiver(t *testing.T, secret string, statuses ...int) *receiver {
+	r := &receiver{secret: secret, statuses: statuses}
+	r.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		assert.NoError(t, Verify(r.secret, req.Header.Get(SignatureHeader), body, webhookNow, 5*time.Minute))
+		assert.NotEmpty(t, req.Header.Get(DeliveryHeader))
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		var event events.Event
+		require.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, event.Type, req.Header.Get(EventHeader))
+		r.received = append(r.received, &event)
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.received)
+}
+
+var webhookNow = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func setupWebhooks(t *testing.T, r *receiver) (*Webhooks, *memoryRepository) {
+	repo := newMemoryRepository()
+	w := New(zap.NewNop().Sugar(), repo, r.server.Client(), Retry{MaxAttempts: 3, Base: time.Minute, Max: time.Hour}, scopedCipher{}).(*Webhooks)
+	w.Now = func() time.Time { return webhookNow }
+	// El receptor escucha en loopback; se resuelve como si fuera público
+	w.LookupIP = resolveTo("203.0.113.10")
+	return w, repo
+}
+
+func resolveTo(addresses ...string) func(context.Context, string, string) ([]net.IP, error) {
+	return func(context.Context, string, string) ([]net.IP, error) {
+		ips := make([]net.IP, 0, len(addresses))
+		for _, address := range addresses {
+			ips = append(ips, net.ParseIP(address))
+		}
+		return ips, nil
+	}
+}
+
+func subscribe(t *testing.T, w *Webhooks, r *receiver, eventTypes ...string) *models.WebhookSubscriptionRequest {
+	subscription, err := w.Subscribe(context.Background(), &models.WebhookSubscriptionRequest{
+		ClientID: "client123",
+		URL:      r.server.URL + "/hooks",
+		Events:   eventTypes,
+		Secret:   r.secret,
+	})
+	require.NoError(t, err)
+	return subscription
+}
+
+func sampleEvent(t *testing.T, eventType, clientID string) *events.Event {
+	event, err := events.New(eventType, events.AggregateAppointment, "appointment123", clientID, map[string]string{"id": "appointment123"})
+	require.NoError(t, err)
+	return event
+}
+
+func TestWebhooks_DeliverSigned(t *testing.T) {
+	r := newReceiver(t, "s3cret")
+	w, _ := setupWebhooks(t, r)
+	subscribe(t, w, r, events.AppointmentBooked, events.AppointmentCancelled)
+	ctx := context.Background()
+
+	booked := sampleEvent(t, events.AppointmentBooked, "client123")
+	failed, err := w.Publish(ctx, []*events.Event{
+		booked,
+		sampleEvent(t, events.AppointmentUpdated, "client123"), // Fuera del filtro
+		sampleEvent(t, events.AppointmentBooked, "other"),      // De otro tenant
+	})
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+	// Publicar dos veces el mismo evento no duplica la entrega
+	_, err = w.Publish(ctx, []*events.Event{booked})
+	require.NoError(t, err)
+
+	report, err := w.Deliver(ctx, webhookNow)
+
+	require.NoError(t, err)
+	assert.Equal(t, &models.WebhookReport{Due: 1, Delivered: 1}, report)
+	require.Equal(t, 1, r.count())
+	assert.Equal(t, booked.ID, r.received[0].ID)
+
+	log, err := w.GetDeliveries(ctx, "client123", "")
+	require.NoError(t, err)
+	require.Len(t, log, 1)
+	assert.Equal(t, models.WebhookDeliveryDelivered, log[0].Status)
+	assert.Equal(t, 200, log[0].LastStatusCode)
+}
+
+func TestWebhooks_Publish_Twice(t *testing.T) {
+	r := newReceiver(t, "s3cret")
+	w, repo := setupWebhooks(t, r)
+	first := subscribe(t, w, r, events.AppointmentBooked)
+	second := subscribe(t, w, r, AllEvents)
+	ctx := context.Background()
+	booked := sampleEvent(t, events.AppointmentBooked, "client123")
+
+	for range 2 {
+		failed, err := w.Publish(ctx, []*events.Event{booked})
+		require.NoError(t, err)
+		assert.Empty(t, failed)
+	}
+
+	perSubscription := map[string]int{}
+	for _, delivery := range repo.deliveries {
+		perSubscription[delivery.SubscriptionID]++
+	}
+	assert.Equal(t, map[string]int{first.ID: 1, second.ID: 1}, perSubscription)
+}
+
+func TestWebhooks_RetryBackoffAndDeadLetter(t *testing.T) {
+	r := newReceiver(t, "s3cret", 500, 503, 500)
+	w, repo := setupWebhooks(t, r)
+	subscribe(t, w, r, AllEvents)
+	ctx := context.Background()
+	_, err := w.Publish(ctx, []*events.Event{sampleEvent(t, events.AppointmentBooked, "client123")})
+	require.NoError(t, err)
+
+	report, err := w.Deliver(ctx, webhookNow)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Retrying)
+	pending, _ := repo.GetDeliveriesByClientID(ctx, "client123", models.WebhookDeliveryPending)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "2024-01-15T12:01:00Z", pending[0].NextAttemptAt)
+	assert.Contains(t, pending[0].LastError, "status 500")
+
+	// Antes del backoff no se reintenta
+	report, err = w.Deliver(ctx, webhookNow.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Due)
+
+	report, err = w.Deliver(ctx, webhookNow.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Retrying)
+	pending, _ = repo.GetDeliveriesByClientID(ctx, "client123", models.WebhookDeliveryPending)
+	assert.Equal(t, "2024-01-15T12:03:00Z", pending[0].NextAttemptAt)
+
+	report, err = w.Deliver(ctx, webhookNow.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Dead)
+	assert.Equal(t, 3, r.count())
+
+	dead, err := w.GetDeliveries(ctx, "client123", models.WebhookDeliveryDead)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 3, dead[0].Attempts)
+	assert.Empty(t, dead[0].NextAttemptAt)
+
+	// La clínica arregla su endpoint y reenvía desde el registro
+	replayed, err := w.Replay(ctx, "client123", dead[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookDeliveryDelivered, replayed.Status)
+	assert.Equal(t, dead[0].ID, replayed.ReplayOf)
+	assert.Equal(t, 4, r.count())
+	assert.Equal(t, r.received[0].ID, r.received[3].ID)
+}
+
+func TestWebhooks_Replay_OtherTenant(t *testing.T) {
+	r := newReceiver(t, "s3cret")
+	w, _ := setupWebhooks(t, r)
+	subscribe(t, w, r, AllEvents)
+	ctx := context.Background()
+	_, err := w.Publish(ctx, []*events.Event{sampleEvent(t, events.AppointmentBooked, "client123")})
+	require.NoError(t, err)
+	log, _ := w.GetDeliveries(ctx, "client123", "")
+
+	_, err = w.Replay(ctx, "other", log[0].ID)
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+	_, err = w.Replay(ctx, "client123", "missing")
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+}
+
+func TestWebhooks_Unsubscribe(t *testing.T) {
+	r := newReceiver(t, "s3cret")
+	w, _ := setupWebhooks(t, r)
+	subscription := subscribe(t, w, r, AllEvents)
+	ctx := context.Background()
+	_, err := w.Publish(ctx, []*events.Event{sampleEvent(t, events.AppointmentBooked, "client123")})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, w.Unsubscribe(ctx, "other", subscription.ID), ErrSubscriptionNotFound)
+	require.NoError(t, w.Unsubscribe(ctx, "client123", subscription.ID))
+
+	// Lo pendiente de una suscripción borrada no se envía
+	report, err := w.Deliver(ctx, webhookNow)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Dead)
+	assert.Equal(t, 0, r.count())
+}
+
+func TestWebhooks_Subscribe(t *testing.T) {
+	r := newReceiver(t, "")
+	w, repo := setupWebhooks(t, r)
+	ctx := context.Background()
+
+	created, err := w.Subscribe(ctx, &models.WebhookSubscriptionRequest{
+		ClientID: "client123",
+		URL:      "https://clinic.example.com/hooks",
+		Events:   []string{events.AppointmentBooked, events.AppointmentBooked},
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, "^whsec_[0-9a-f]{64}$", created.Secret)
+	assert.Equal(t, []string{events.AppointmentBooked}, created.Events)
+
+	listed, err := w.GetSubscriptions(ctx, "client123")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Empty(t, listed[0].Secret)
+	stored := repo.subscriptions[created.ID]
+	assert.Empty(t, stored.Secret)
+	assert.NotContains(t, stored.SecretCiphertext, created.Secret)
+	secret, err := w.secret(ctx, stored)
+	require.NoError(t, err)
+	assert.Equal(t, created.Secret, secret)
+
+	for _, invalid := range []*models.WebhookSubscriptionRequest{
+		{URL: "https://clinic.example.com/hooks", Events: []string{AllEvents}},
+		{ClientID: "client123", URL: "http://clinic.example.com/hooks", Events: []string{AllEvents}},
+		{ClientID: "client123", URL: "https://clinic.example.com/hooks"},
+		{ClientID: "client123", URL: "https://clinic.example.com/hooks", Events: []string{"AppointmentExploded"}},
+	} {
+		_, err := w.Subscribe(ctx, invalid)
+		assert.ErrorIs(t, err, ErrInvalidSubscription)
+	}
+}
+
+func TestWebhooks_DeliverLegacySecret(t *testing.T) {
+	r := newReceiver(t, "s3cret")
+	w, repo := setupWebhooks(t, r)
+	ctx := context.Background()
+	// Suscripción guardada antes del cifrado, con el secreto en claro
+	require.NoError(t, repo.SaveSubscription(ctx, &models.WebhookSubscription{
+		ID: "legacy", ClientID: "client123", URL: r.server.URL + "/hooks", Events: []string{AllEvents}, Secret: "s3cret",
+	}))
+	_, err := w.Publish(ctx, []*events.Event{sampleEvent(t, events.AppointmentBooked, "client123")})
+	require.NoError(t, err)
+
+	report, err := w.Deliver(ctx, webhookNow)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Delivered)
+	stored := repo.subscriptions["legacy"]
+	assert.Empty(t, stored.Secret)
+	assert.NotEmpty(t, stored.SecretCiphertext)
+}
+
+func TestWebhooks_Subscribe_WithoutCipher(t *testing.T) {
+	w := New(zap.NewNop().Sugar(), newMemoryRepository(), nil, Retry{}, nil).(*Webhooks)
+	w.LookupIP = resolveTo("203.0.113.10")
+
+	_, err := w.Subscribe(context.Background(), &models.WebhookSubscriptionRequest{ClientID: "client123", URL: "https://clinic.example.com/hooks", Events: []string{AllEvents}})
+
+	assert.ErrorIs(t, err, secrets.ErrNotConfigured)
+}
+
+func TestWebhooks_Subscribe_InternalAddress(t *testing.T) {
+	w := New(zap.NewNop().Sugar(), newMemoryRepository(), nil, Retry{}, scopedCipher{}).(*Webhooks)
+	ctx := context.Background()
+	for _, url := range []string{
+		"https://169.254.169.254/latest/meta-data",
+		"https://127.0.0.1/hooks",
+		"https://10.0.0.5/hooks",
+		"https://[::1]/hooks",
+		"https://0.1.2.3/hooks",
+		"https://[64:ff9b::a9fe:a9fe]/hooks",
+	} {
+		_, err := w.Subscribe(ctx, &models.WebhookSubscriptionRequest{ClientID: "client123", URL: url, Events: []string{AllEvents}})
+		assert.ErrorIs(t, err, ErrInvalidSubscription, url)
+	}
+
+	// Un nombre que resuelve a una dirección interna tampoco
+	w.LookupIP = resolveTo("203.0.113.10", "169.254.169.254")
+	_, err := w.Subscribe(ctx, &models.WebhookSubscriptionRequest{ClientID: "client123", URL: "https://metadata.example.com/hooks", Events: []string{AllEvents}})
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+}
+
+func TestNewHTTPClient_InternalAddress(t *testing.T) {
+	r := newReceiver(t, "s3cret")
+
+	_, err := NewHTTPClient(time.Second).Post(r.server.URL, "application/json", nil)
+
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+	assert.Equal(t, 0, r.count())
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	header := Sign("s3cret", webhookNow, body)
+
+	assert.NoError(t, Verify("s3cret", header, body, webhookNow.Add(time.Minute), 5*time.Minute))
+	assert.ErrorIs(t, Verify("other", header, body, webhookNow, 5*time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("s3cret", header, []byte(`{"id":"2"}`), webhookNow, 5*time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("s3cret", header, body, webhookNow.Add(time.Hour), 5*time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("s3cret", "garbage", body, webhookNow, 5*time.Minute), ErrInvalidSignature)
+}
+
+func TestRetry_Backoff(t *testing.T) {
+	retry := Retry{MaxAttempts: 10, Base: 30 * time.Second, Max: 10 * time.Minute}
+	assert.Equal(t, 30*time.Second, retry.backoff(1))
+	assert.Equal(t, 2*time.Minute, retry.backoff(3))
+	assert.Equal(t, 10*time.Minute, retry.backoff(9))
+
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "5")
+	t.Setenv("WEBHOOK_BACKOFF_BASE", "")
+	t.Setenv("WEBHOOK_BACKOFF_MAX", "1h")
+	loaded, err := RetryFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, Retry{MaxAttempts: 5, Base: defaultBaseBackoff, Max: time.Hour}, loaded)
+
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "0")
+	_, err = RetryFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package streams
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Las imágenes de los streams llegan con los tipos de aws-lambda-go; se pasan
+// a los del SDK para poder usar attributevalue y las mismas etiquetas
+// dynamodbav que los repositorios.
+
+func toAttributeMap(image map[string]events.DynamoDBAttributeValue) (map[string]types.AttributeValue, error) {
+	if image == nil {
+		return nil, nil
+	}
+	out := make(map[string]types.AttributeValue, len(image))
+	for name, value := range image {
+		converted, err := toAttributeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		out[name] = converted
+	}
+	return out, nil
+}
+
+func toAttributeValue(value events.DynamoDBAttributeValue) (types.AttributeValue, error) {
+	switch value.DataType() {
+	case events.DataTypeString:
+		return &types.AttributeValueMemberS{Value: value.String()}, nil
+	case events.DataTypeNumber:
+		return &types.AttributeValueMemberN{Value: value.Number()}, nil
+	case events.DataTypeBoolean:
+		return &types.AttributeValueMemberBOOL{Value: value.Boolean()}, nil
+	case events.DataTypeNull:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case events.DataTypeBinary:
+		return &types.AttributeValueMemberB{Value: value.Binary()}, nil
+	case events.DataTypeStringSet:
+		return &types.AttributeValueMemberSS{Value: value.StringSet()}, nil
+	case events.DataTypeNumberSet:
+		return &types.AttributeValueMemberNS{Value: value.NumberSet()}, nil
+	case events.DataTypeBinarySet:
+		return &types.AttributeValueMemberBS{Value: value.BinarySet()}, nil
+	case events.DataTypeList:
+		list := value.List()
+		out := make([]types.AttributeValue, 0, len(list))
+		for _, item := range list {
+			converted, err := toAttributeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, converted)
+		}
+		return &types.AttributeValueMemberL{Value: out}, nil
+	case events.DataTypeMap:
+		converted, err := toAttributeMap(value.Map())
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberM{Value: converted}, nil
+	default:
+		return nil, fmt.Errorf("unsupported attribute type %d", value.DataType())
+	}
+}
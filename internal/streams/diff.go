@@ -0,0 +1,52 @@
+package streams
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// FieldDiff es un atributo que cambió. Field es el nombre en DynamoDB (el
+// mismo que en el JSON de la API); Old o New son nil si el atributo no
+// existía antes o después.
+type FieldDiff struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// diff compara las imágenes atributo por atributo, ordenado por nombre. En
+// un alta o una baja aparecen todos los atributos.
+func diff(before, after map[string]types.AttributeValue) ([]FieldDiff, error) {
+	var old, updated map[string]interface{}
+	if err := attributevalue.UnmarshalMap(before, &old); err != nil {
+		return nil, err
+	}
+	if err := attributevalue.UnmarshalMap(after, &updated); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]bool{}
+	for field := range old {
+		fields[field] = true
+	}
+	for field := range updated {
+		fields[field] = true
+	}
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	var diffs []FieldDiff
+	for _, field := range names {
+		if reflect.DeepEqual(old[field], updated[field]) {
+			continue
+		}
+		diffs = append(diffs, FieldDiff{Field: field, Old: old[field], New: updated[field]})
+	}
+	return diffs, nil
+}
@@ -0,0 +1,22 @@
+package streams
+
+import (
+	"context"
+)
+
+// ExportProjection genera cada exportación nueva de ExportsTable. Los
+// cambios de estado que hace el propio worker llegan como MODIFY y se
+// ignoran. Run tiene que tolerar repeticiones: RunExport saltea las
+// exportaciones terminadas y las que otro worker tiene tomadas.
+type ExportProjection struct {
+	Run func(ctx context.Context, id string) error
+}
+
+func (e *ExportProjection) Name() string { return "exports" }
+
+func (e *ExportProjection) Apply(ctx context.Context, change *Change) error {
+	if change.Operation != OperationInsert {
+		return nil
+	}
+	return e.Run(ctx, change.ID)
+}
@@ -0,0 +1,29 @@
+package streams
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"go.uber.org/zap"
+)
+
+// LogProjection registra qué campos cambiaron en cada registro. No registra
+// los valores: las imágenes tienen datos personales de los pacientes.
+type LogProjection struct {
+	Logger *zap.SugaredLogger
+}
+
+func (l *LogProjection) Name() string { return "log" }
+
+func (l *LogProjection) Apply(ctx context.Context, change *Change) error {
+	fields := make([]string, 0, len(change.Diff))
+	for _, d := range change.Diff {
+		fields = append(fields, d.Field)
+	}
+	logging.FromContext(ctx, l.Logger).Info("Stream change",
+		zap.String("entity", change.Entity),
+		zap.String("operation", change.Operation),
+		zap.String("id", change.ID),
+		zap.Strings("fields", fields))
+	return nil
+}
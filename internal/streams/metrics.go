@@ -0,0 +1,40 @@
+package streams
+
+import (
+	"context"
+
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+)
+
+const (
+	metricStatusChanges = "AppointmentStatusChanges"
+	dimStatus           = "Status"
+)
+
+// StatusMetrics cuenta los turnos que entran a cada estado, altas incluidas,
+// por cliente. Sale del stream y no del servicio para cubrir todos los
+// caminos que escriben turnos: la API, FHIR, las reservas de horario, la
+// marca de ausencias y los merges. Si un lote se reintenta después de que
+// Apply publicó, el cambio se cuenta de nuevo; es una métrica, no un
+// contador exacto.
+type StatusMetrics struct {
+	Metrics metrics.Metrics
+}
+
+func (s *StatusMetrics) Name() string { return "status_metrics" }
+
+func (s *StatusMetrics) Apply(_ context.Context, change *Change) error {
+	appointment, ok := change.New.(*models.Appointment)
+	if !ok || appointment == nil {
+		return nil
+	}
+	if change.Operation != OperationInsert && !change.Changed("status") {
+		return nil
+	}
+	s.Metrics.Put(metricStatusChanges, 1, metrics.Count, metrics.Dimensions{
+		metrics.DimClientID: appointment.ClientID,
+		dimStatus:           string(appointment.Status),
+	})
+	return nil
+}
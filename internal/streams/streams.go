@@ -0,0 +1,200 @@
+package streams
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MezeLaw/iris-services/internal/logging"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"go.uber.org/zap"
+)
+
+// Procesamiento de los DynamoDB Streams de pacientes y turnos: cada registro
+// se decodifica en la entidad de antes y de después, se calcula qué campos
+// cambiaron y se entrega a las proyecciones registradas para esa entidad.
+
+const (
+	EntityPatient     = "patient"
+	EntityAppointment = "appointment"
+	EntityExport      = "export"
+
+	OperationInsert = "INSERT"
+	OperationModify = "MODIFY"
+	OperationRemove = "REMOVE"
+)
+
+// Change es un registro del stream ya decodificado. Old y New son
+// *models.Patient, *models.Appointment o *models.ExportJob según Entity; Old
+// es nil en un alta y New en una baja.
+type Change struct {
+	Entity         string
+	Operation      string
+	ID             string
+	EventID        string
+	SequenceNumber string
+	Old            interface{}
+	New            interface{}
+	Diff           []FieldDiff
+}
+
+// Changed dice si field está entre los atributos que cambiaron.
+func (c *Change) Changed(field string) bool {
+	for _, d := range c.Diff {
+		if d.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Projection mantiene una vista derivada a partir de los cambios. Un mismo
+// cambio puede llegar más de una vez, así que Apply tiene que ser idempotente.
+type Projection interface {
+	Name() string
+	Apply(ctx context.Context, change *Change) error
+}
+
+// Processor reparte los registros entre las proyecciones. Tables dice a qué
+// entidad corresponde cada tabla; los registros de otras tablas se ignoran.
+type Processor struct {
+	Logger      *zap.SugaredLogger
+	Tables      map[string]string
+	Projections map[string][]Projection
+}
+
+func NewProcessor(logger *zap.SugaredLogger, tables map[string]string) *Processor {
+	return &Processor{Logger: logger, Tables: tables, Projections: map[string][]Projection{}}
+}
+
+// Register suma una proyección para los cambios de entity.
+func (p *Processor) Register(entity string, projection Projection) {
+	p.Projections[entity] = append(p.Projections[entity], projection)
+}
+
+// Handle procesa los registros en orden y se detiene en el primero que
+// falla. Lo informa como falla parcial: Lambda da por procesados los
+// anteriores y reintenta desde ése, así un registro malo no hace repetir el
+// lote entero. Con MaximumRetryAttempts y un destino OnFailure en el event
+// source mapping, un registro que nunca se puede procesar termina en la DLQ
+// y no traba el shard.
+func (p *Processor) Handle(ctx context.Context, event events.DynamoDBEvent) events.DynamoDBEventResponse {
+	response := events.DynamoDBEventResponse{BatchItemFailures: []events.DynamoDBBatchItemFailure{}}
+	for _, record := range event.Records {
+		if err := p.process(ctx, record); err != nil {
+			p.log(ctx).Error("Error processing stream record",
+				zap.String("eventID", record.EventID),
+				zap.String("sequenceNumber", record.Change.SequenceNumber),
+				zap.Error(err))
+			response.BatchItemFailures = append(response.BatchItemFailures, events.DynamoDBBatchItemFailure{
+				ItemIdentifier: record.Change.SequenceNumber,
+			})
+			return response
+		}
+	}
+	return response
+}
+
+func (p *Processor) process(ctx context.Context, record events.DynamoDBEventRecord) error {
+	table := tableName(record.EventSourceArn)
+	entity, ok := p.Tables[table]
+	if !ok {
+		p.log(ctx).Warn("Ignoring stream record from unknown table", zap.String("table", table))
+		return nil
+	}
+	projections := p.Projections[entity]
+	if len(projections) == 0 {
+		return nil
+	}
+
+	change, err := Decode(entity, record)
+	if err != nil {
+		return fmt.Errorf("decoding record: %w", err)
+	}
+	for _, projection := range projections {
+		if err := projection.Apply(ctx, change); err != nil {
+			return fmt.Errorf("projection %s: %w", projection.Name(), err)
+		}
+	}
+	return nil
+}
+
+// log es el logger del pedido en ctx o, si no hay, el del procesador.
+func (p *Processor) log(ctx context.Context) *zap.SugaredLogger {
+	return logging.FromContext(ctx, p.Logger)
+}
+
+// Decode arma el Change de un registro de la tabla de entity.
+func Decode(entity string, record events.DynamoDBEventRecord) (*Change, error) {
+	oldImage, err := toAttributeMap(record.Change.OldImage)
+	if err != nil {
+		return nil, err
+	}
+	newImage, err := toAttributeMap(record.Change.NewImage)
+	if err != nil {
+		return nil, err
+	}
+	change := &Change{
+		Entity:         entity,
+		Operation:      record.EventName,
+		EventID:        record.EventID,
+		SequenceNumber: record.Change.SequenceNumber,
+	}
+	if keys := record.Change.Keys; keys != nil {
+		if id, ok := keys["id"]; ok && id.DataType() == events.DataTypeString {
+			change.ID = id.String()
+		}
+	}
+	if change.Old, err = decodeImage(entity, oldImage); err != nil {
+		return nil, err
+	}
+	if change.New, err = decodeImage(entity, newImage); err != nil {
+		return nil, err
+	}
+	if change.Diff, err = diff(oldImage, newImage); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func decodeImage(entity string, image map[string]types.AttributeValue) (interface{}, error) {
+	if image == nil {
+		return nil, nil
+	}
+	switch entity {
+	case EntityPatient:
+		var patient models.Patient
+		if err := attributevalue.UnmarshalMap(image, &patient); err != nil {
+			return nil, err
+		}
+		return &patient, nil
+	case EntityAppointment:
+		var appointment models.Appointment
+		if err := attributevalue.UnmarshalMap(image, &appointment); err != nil {
+			return nil, err
+		}
+		return &appointment, nil
+	case EntityExport:
+		var job models.ExportJob
+		if err := attributevalue.UnmarshalMap(image, &job); err != nil {
+			return nil, err
+		}
+		return &job, nil
+	default:
+		return nil, fmt.Errorf("unknown entity %q", entity)
+	}
+}
+
+// tableName saca el nombre de la tabla del ARN del stream:
+// arn:aws:dynamodb:<región>:<cuenta>:table/<tabla>/stream/<fecha>
+func tableName(arn string) string {
+	_, rest, found := strings.Cut(arn, ":table/")
+	if !found {
+		return ""
+	}
+	table, _, _ := strings.Cut(rest, "/")
+	return table
+}
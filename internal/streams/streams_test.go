@@ -0,0 +1,235 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MezeLaw/iris-services/internal/metrics"
+	"github.com/MezeLaw/iris-services/internal/models"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	patientsArn     = "arn:aws:dynamodb:us-east-1:123456789012:table/PatientsTable/stream/2024-01-01T00:00:00.000"
+	appointmentsArn = "arn:aws:dynamodb:us-east-1:123456789012:table/AppointmentsTable/stream/2024-01-01T00:00:00.000"
+)
+
+type recordingProjection struct {
+	changes []*Change
+	failOn  string
+}
+
+func (r *recordingProjection) Name() string { return "recording" }
+
+func (r *recordingProjection) Apply(_ context.Context, change *Change) error {
+	if change.ID == r.failOn {
+		return errors.New("projection failed")
+	}
+	r.changes = append(r.changes, change)
+	return nil
+}
+
+func str(value string) events.DynamoDBAttributeValue {
+	return events.NewStringAttribute(value)
+}
+
+func setupProcessor() (*Processor, *recordingProjection, *recordingProjection) {
+	processor := NewProcessor(zap.NewNop().Sugar(), map[string]string{
+		"PatientsTable":     EntityPatient,
+		"AppointmentsTable": EntityAppointment,
+	})
+	patients, appointments := &recordingProjection{}, &recordingProjection{}
+	processor.Register(EntityPatient, patients)
+	processor.Register(EntityAppointment, appointments)
+	return processor, patients, appointments
+}
+
+func patientImage(email string) map[string]events.DynamoDBAttributeValue {
+	return map[string]events.DynamoDBAttributeValue{
+		"id":            str("patient123"),
+		"client_id":     str("client123"),
+		"first_name":    str("Juan"),
+		"email":         str(email),
+		"no_show_count": events.NewNumberAttribute("2"),
+		"metadata": events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{
+			"tags": events.NewListAttribute([]events.DynamoDBAttributeValue{str("vip")}),
+		}),
+	}
+}
+
+func record(arn, name, sequence string, keys, old, updated map[string]events.DynamoDBAttributeValue) events.DynamoDBEventRecord {
+	return events.DynamoDBEventRecord{
+		EventID:        "event-" + sequence,
+		EventName:      name,
+		EventSourceArn: arn,
+		Change: events.DynamoDBStreamRecord{
+			Keys:           keys,
+			OldImage:       old,
+			NewImage:       updated,
+			SequenceNumber: sequence,
+		},
+	}
+}
+
+func TestHandle_ModifyPatient(t *testing.T) {
+	processor, patients, appointments := setupProcessor()
+	keys := map[string]events.DynamoDBAttributeValue{"id": str("patient123")}
+
+	response := processor.Handle(context.Background(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		record(patientsArn, OperationModify, "1", keys, patientImage("old@example.com"), patientImage("new@example.com")),
+	}})
+
+	assert.Empty(t, response.BatchItemFailures)
+	assert.Empty(t, appointments.changes)
+	require.Len(t, patients.changes, 1)
+	change := patients.changes[0]
+	assert.Equal(t, "patient123", change.ID)
+	assert.Equal(t, OperationModify, change.Operation)
+	assert.Equal(t, "old@example.com", change.Old.(*models.Patient).Email)
+	assert.Equal(t, "new@example.com", change.New.(*models.Patient).Email)
+	assert.Equal(t, 2, change.New.(*models.Patient).NoShowCount)
+	assert.Equal(t, []FieldDiff{{Field: "email", Old: "old@example.com", New: "new@example.com"}}, change.Diff)
+	assert.True(t, change.Changed("email"))
+	assert.False(t, change.Changed("first_name"))
+}
+
+func TestHandle_InsertAndRemoveAppointment(t *testing.T) {
+	processor, _, appointments := setupProcessor()
+	keys := map[string]events.DynamoDBAttributeValue{"id": str("appointment123")}
+	image := map[string]events.DynamoDBAttributeValue{
+		"id":       str("appointment123"),
+		"status":   str(string(models.AppointmentStatusScheduled)),
+		"duration": events.NewNumberAttribute("30"),
+	}
+
+	response := processor.Handle(context.Background(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		record(appointmentsArn, OperationInsert, "1", keys, nil, image),
+		record(appointmentsArn, OperationRemove, "2", keys, image, nil),
+	}})
+
+	assert.Empty(t, response.BatchItemFailures)
+	require.Len(t, appointments.changes, 2)
+	inserted, removed := appointments.changes[0], appointments.changes[1]
+	assert.Nil(t, inserted.Old)
+	assert.Equal(t, 30, inserted.New.(*models.Appointment).Duration)
+	assert.Len(t, inserted.Diff, 3)
+	assert.Nil(t, removed.New)
+	assert.Equal(t, "appointment123", removed.Old.(*models.Appointment).ID)
+	assert.Equal(t, FieldDiff{Field: "status", Old: string(models.AppointmentStatusScheduled)}, removed.Diff[2])
+}
+
+func TestHandle_PartialBatchFailure(t *testing.T) {
+	processor, patients, _ := setupProcessor()
+	patients.failOn = "bad"
+	image := patientImage("a@example.com")
+
+	response := processor.Handle(context.Background(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		record(patientsArn, OperationInsert, "1", map[string]events.DynamoDBAttributeValue{"id": str("ok")}, nil, image),
+		record(patientsArn, OperationInsert, "2", map[string]events.DynamoDBAttributeValue{"id": str("bad")}, nil, image),
+		record(patientsArn, OperationInsert, "3", map[string]events.DynamoDBAttributeValue{"id": str("later")}, nil, image),
+	}})
+
+	// Se informa sólo el primero que falló; Lambda reintenta desde ahí
+	assert.Equal(t, []events.DynamoDBBatchItemFailure{{ItemIdentifier: "2"}}, response.BatchItemFailures)
+	require.Len(t, patients.changes, 1)
+	assert.Equal(t, "ok", patients.changes[0].ID)
+}
+
+func TestHandle_UndecodableRecord(t *testing.T) {
+	processor, patients, _ := setupProcessor()
+	image := map[string]events.DynamoDBAttributeValue{"no_show_count": str("not a number")}
+
+	response := processor.Handle(context.Background(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		record(patientsArn, OperationInsert, "7", nil, nil, image),
+	}})
+
+	assert.Equal(t, []events.DynamoDBBatchItemFailure{{ItemIdentifier: "7"}}, response.BatchItemFailures)
+	assert.Empty(t, patients.changes)
+}
+
+func TestHandle_UnknownTable(t *testing.T) {
+	processor, patients, appointments := setupProcessor()
+
+	response := processor.Handle(context.Background(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		record("arn:aws:dynamodb:us-east-1:123456789012:table/OtherTable/stream/x", OperationInsert, "1", nil, nil, patientImage("a@example.com")),
+	}})
+
+	assert.Empty(t, response.BatchItemFailures)
+	assert.Empty(t, patients.changes)
+	assert.Empty(t, appointments.changes)
+}
+
+type recordingMetrics struct {
+	dimensions []metrics.Dimensions
+}
+
+func (r *recordingMetrics) Put(name string, value float64, unit metrics.Unit, dimensions metrics.Dimensions) {
+	r.dimensions = append(r.dimensions, dimensions)
+}
+
+func TestStatusMetrics(t *testing.T) {
+	processor := NewProcessor(zap.NewNop().Sugar(), map[string]string{"AppointmentsTable": EntityAppointment})
+	recorded := &recordingMetrics{}
+	processor.Register(EntityAppointment, &StatusMetrics{Metrics: recorded})
+	keys := map[string]events.DynamoDBAttributeValue{"id": str("appointment123")}
+	image := func(status models.AppointmentStatus, notes string) map[string]events.DynamoDBAttributeValue {
+		return map[string]events.DynamoDBAttributeValue{
+			"id":        str("appointment123"),
+			"client_id": str("client123"),
+			"status":    str(string(status)),
+			"notes":     str(notes),
+		}
+	}
+	scheduled := image(models.AppointmentStatusScheduled, "")
+
+	response := processor.Handle(context.Background(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		record(appointmentsArn, OperationInsert, "1", keys, nil, scheduled),
+		record(appointmentsArn, OperationModify, "2", keys, scheduled, image(models.AppointmentStatusScheduled, "llamar antes")),
+		record(appointmentsArn, OperationModify, "3", keys, scheduled, image(models.AppointmentStatusNoShow, "")),
+		record(appointmentsArn, OperationRemove, "4", keys, scheduled, nil),
+	}})
+
+	assert.Empty(t, response.BatchItemFailures)
+	assert.Equal(t, []metrics.Dimensions{
+		{metrics.DimClientID: "client123", dimStatus: string(models.AppointmentStatusScheduled)},
+		{metrics.DimClientID: "client123", dimStatus: string(models.AppointmentStatusNoShow)},
+	}, recorded.dimensions)
+}
+
+func TestExportProjection(t *testing.T) {
+	processor := NewProcessor(zap.NewNop().Sugar(), map[string]string{"ExportsTable": EntityExport})
+	var run []string
+	processor.Register(EntityExport, &ExportProjection{Run: func(_ context.Context, id string) error {
+		run = append(run, id)
+		if id == "e2" {
+			return errors.New("throttled")
+		}
+		return nil
+	}})
+	arn := "arn:aws:dynamodb:us-east-1:123456789012:table/ExportsTable/stream/2024-01-01T00:00:00.000"
+	image := func(id, status string) map[string]events.DynamoDBAttributeValue {
+		return map[string]events.DynamoDBAttributeValue{"id": str(id), "status": str(status)}
+	}
+	keys := func(id string) map[string]events.DynamoDBAttributeValue {
+		return map[string]events.DynamoDBAttributeValue{"id": str(id)}
+	}
+
+	response := processor.Handle(context.Background(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		record(arn, OperationInsert, "1", keys("e1"), nil, image("e1", models.ExportStatusPending)),
+		record(arn, OperationModify, "2", keys("e1"), image("e1", models.ExportStatusPending), image("e1", models.ExportStatusRunning)),
+		record(arn, OperationInsert, "3", keys("e2"), nil, image("e2", models.ExportStatusPending)),
+		record(arn, OperationInsert, "4", keys("e3"), nil, image("e3", models.ExportStatusPending)),
+	}})
+
+	assert.Equal(t, []string{"e1", "e2"}, run)
+	assert.Equal(t, []events.DynamoDBBatchItemFailure{{ItemIdentifier: "3"}}, response.BatchItemFailures)
+}
+
+func TestTableName(t *testing.T) {
+	assert.Equal(t, "PatientsTable", tableName(patientsArn))
+	assert.Equal(t, "", tableName("not-an-arn"))
+}
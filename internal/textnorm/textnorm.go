@@ -0,0 +1,37 @@
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalización de texto para comparar nombres sin importar mayúsculas ni
+// acentos ("Martínez" == "martinez").
+
+var foldReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c", "ý", "y", "ÿ", "y",
+)
+
+// Fold pasa a minúsculas y quita los diacríticos latinos.
+func Fold(s string) string {
+	return foldReplacer.Replace(strings.ToLower(s))
+}
+
+// Tokens devuelve las palabras de s normalizadas con Fold, separando por
+// cualquier carácter que no sea letra o dígito.
+func Tokens(s string) []string {
+	return strings.FieldsFunc(Fold(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Key une los tokens de s con espacios simples; sirve como clave de
+// comparación de nombres completos.
+func Key(s string) string {
+	return strings.Join(Tokens(s), " ")
+}
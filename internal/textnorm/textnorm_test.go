@@ -0,0 +1,26 @@
+package textnorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFold(t *testing.T) {
+	assert.Equal(t, "martinez nunez", Fold("MARTÍNEZ Núñez"))
+	assert.Equal(t, "joao conceicao", Fold("João Conceição"))
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "perez juan", Key("  Pérez,  Juan "))
+	assert.Equal(t, []string{"o", "brien", "ana", "maria"}, Tokens("O'Brien, Ana-María"))
+}
+
+func TestJaroWinkler(t *testing.T) {
+	assert.InDelta(t, 0.961, JaroWinkler("martha", "marhta"), 0.001)
+	assert.InDelta(t, 0.840, JaroWinkler("dwayne", "duane"), 0.001)
+	assert.InDelta(t, 0.813, JaroWinkler("dixon", "dicksonx"), 0.001)
+	assert.Equal(t, 1.0, JaroWinkler("ana", "ana"))
+	assert.Equal(t, 0.0, JaroWinkler("abc", "xyz"))
+	assert.Equal(t, 0.0, JaroWinkler("", "ana"))
+}
@@ -0,0 +1,192 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trazas OpenTelemetry del camino handler → service → repository y de las
+// llamadas al SDK de AWS. El contexto llega en los headers traceparent (W3C)
+// o X-Amzn-Trace-Id (X-Ray) del evento de API Gateway.
+
+const instrumentationName = "github.com/MezeLaw/iris-services"
+
+// Tracer es el tracer de los paquetes de la API. Usa el proveedor global, así
+// que hasta que se llame a Setup las trazas no se registran.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start abre un span hijo del que venga en ctx. Si el span no se registra
+// (no hay exportador) devuelve el mismo ctx, que ya lleva el contexto
+// recibido para propagarlo.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	spanCtx, span := Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+	if !span.IsRecording() {
+		return ctx, span
+	}
+	return spanCtx, span
+}
+
+// Error marca el span como fallido con err.
+func Error(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Setup instala el proveedor de trazas y los propagadores. En los Lambdas
+// desplegados va TRACES_EXPORTER=otlp: las trazas salen por OTLP/HTTP al
+// collector de ADOT, que las manda a X-Ray. Con TRACES_EXPORTER=stdout se
+// escriben por stdout, para desarrollo local; sin exportador los spans no se
+// registran pero el contexto recibido se sigue propagando.
+func Setup(service string) {
+	otel.SetTextMapPropagator(Propagator())
+	switch os.Getenv("TRACES_EXPORTER") {
+	case "stdout":
+		otel.SetTracerProvider(NewProvider(service, os.Stdout))
+	case "otlp":
+		provider, err := NewOTLPProvider(context.Background(), service)
+		if err != nil {
+			otel.Handle(err)
+			return
+		}
+		otel.SetTracerProvider(provider)
+	}
+}
+
+// NewOTLPProvider exporta los spans por OTLP/HTTP. Sin opciones va al
+// collector de la capa de ADOT en localhost:4318, o a donde digan las
+// variables OTEL_EXPORTER_OTLP_*. Los IDs de traza llevan el timestamp que
+// exige X-Ray. El exportador es en lote y Wrap/WrapEvent lo vacían al
+// terminar cada invocación, antes de que Lambda congele el proceso.
+func NewOTLPProvider(ctx context.Context, service string, opts ...otlptracehttp.Option) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithIDGenerator(xray.NewIDGenerator()),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	), nil
+}
+
+// flush exporta los spans pendientes del proveedor en lote. ctx puede estar
+// cancelado si la invocación llegó al timeout, así que no se hereda.
+func flush(ctx context.Context) {
+	provider, ok := otel.GetTracerProvider().(interface{ ForceFlush(context.Context) error })
+	if !ok {
+		return
+	}
+	if err := provider.ForceFlush(context.WithoutCancel(ctx)); err != nil {
+		otel.Handle(err)
+	}
+}
+
+// NewProvider exporta cada span a w como JSON al terminar. El exportador es
+// sincrónico: Lambda congela el proceso entre invocaciones y un exportador
+// en lote podría no llegar a escribir.
+func NewProvider(service string, w io.Writer) *sdktrace.TracerProvider {
+	exporter, _ := stdouttrace.New(stdouttrace.WithWriter(w))
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	)
+}
+
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}, xray.Propagator{})
+}
+
+type Handler = func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Wrap abre el span de servidor de operation con el contexto de los headers
+// del pedido. Las respuestas 5xx marcan el span como fallido.
+func Wrap(operation string, next Handler) Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(headers(req)))
+		ctx, span := Tracer().Start(ctx, operation,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(req.HTTPMethod),
+				semconv.HTTPRoute(req.Resource),
+				semconv.FaaSInvocationID(req.RequestContext.RequestID),
+			))
+		defer flush(ctx)
+		defer span.End()
+
+		resp, err := next(ctx, req)
+		if err != nil {
+			Error(span, err)
+			return resp, err
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		if resp.StatusCode >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		}
+		return resp, nil
+	}
+}
+
+// lambdaTraceID es la clave con que aws-lambda-go guarda en el contexto el
+// header X-Amzn-Trace-Id de cada invocación.
+const lambdaTraceID = "x-amzn-trace-id"
+
+// WrapEvent abre el span de operation en los Lambdas que no atienden API
+// Gateway: eventos programados, streams de DynamoDB e invocaciones directas.
+// No hay headers; el contexto sale del X-Amzn-Trace-Id de la invocación
+// cuando Lambda tiene el tracing activo, y si no el span es la raíz de su
+// traza.
+func WrapEvent[E, R any](operation string, next func(context.Context, E) (R, error)) func(context.Context, E) (R, error) {
+	return func(ctx context.Context, event E) (R, error) {
+		if id, _ := ctx.Value(lambdaTraceID).(string); id != "" {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{"X-Amzn-Trace-Id": id})
+		}
+		var attrs []attribute.KeyValue
+		if lc, ok := lambdacontext.FromContext(ctx); ok {
+			attrs = append(attrs, semconv.FaaSInvocationID(lc.AwsRequestID))
+		}
+		ctx, span := Tracer().Start(ctx, operation, trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(attrs...))
+		defer flush(ctx)
+		defer span.End()
+
+		result, err := next(ctx, event)
+		if err != nil {
+			Error(span, err)
+		}
+		return result, err
+	}
+}
+
+// headers pasa los headers del evento a http.Header, que normaliza las
+// mayúsculas como espera HeaderCarrier.
+func headers(req events.APIGatewayProxyRequest) http.Header {
+	h := http.Header{}
+	for name, values := range req.MultiValueHeaders {
+		for _, value := range values {
+			h.Add(name, value)
+		}
+	}
+	for name, value := range req.Headers {
+		if h.Get(name) == "" {
+			h.Set(name, value)
+		}
+	}
+	return h
+}
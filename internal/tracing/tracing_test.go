@@ -0,0 +1,201 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		TraceID string
+		SpanID  string
+	}
+	Status struct {
+		Code string
+	}
+}
+
+// setupTest instala el exportador stdout sobre un buffer y devuelve los
+// spans que se exportaron, en el orden en que terminaron.
+func setupTest(t *testing.T) func() []exportedSpan {
+	var out bytes.Buffer
+	provider := NewProvider("iris-test", &out)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(Propagator())
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return func() []exportedSpan {
+		var spans []exportedSpan
+		decoder := json.NewDecoder(&out)
+		for {
+			var span exportedSpan
+			if err := decoder.Decode(&span); err != nil {
+				require.ErrorIs(t, err, io.EOF)
+				return spans
+			}
+			spans = append(spans, span)
+		}
+	}
+}
+
+func handle(status int) Handler {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		_, span := Start(ctx, "service.Patients.GetPatient")
+		span.End()
+		return events.APIGatewayProxyResponse{StatusCode: status}, nil
+	}
+}
+
+func TestWrap_Traceparent(t *testing.T) {
+	spans := setupTest(t)
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Resource:   "/patients/{id}",
+		Headers:    map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+	}
+
+	_, err := Wrap("getPatient", handle(200))(context.Background(), req)
+	require.NoError(t, err)
+
+	exported := spans()
+	require.Len(t, exported, 2)
+	child, server := exported[0], exported[1]
+	assert.Equal(t, "getPatient", server.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID)
+	assert.Equal(t, server.SpanContext.SpanID, child.Parent.SpanID)
+	assert.Equal(t, "Unset", server.Status.Code)
+}
+
+func TestWrap_XRay(t *testing.T) {
+	spans := setupTest(t)
+	req := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"x-amzn-trace-id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"},
+	}
+
+	_, err := Wrap("getPatient", handle(503))(context.Background(), req)
+	require.NoError(t, err)
+
+	exported := spans()
+	require.Len(t, exported, 2)
+	server := exported[1]
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", server.SpanContext.TraceID)
+	assert.Equal(t, "53995c3f42cd8ad8", server.Parent.SpanID)
+	assert.Equal(t, "Error", server.Status.Code)
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestInstrumentAWS(t *testing.T) {
+	spans := setupTest(t)
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		HTTPClient: &http.Client{Transport: roundTripper(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"X-Amzn-Requestid": []string{"req-1"}},
+				Body:       io.NopCloser(strings.NewReader(`{}`)),
+			}, nil
+		})},
+	}
+	InstrumentAWS(&cfg)
+	client := dynamodb.NewFromConfig(cfg)
+
+	ctx, parent := Start(context.Background(), "repository.Patients.GetByID")
+	_, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("PatientsTable"),
+		Key:       map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "p1"}},
+	})
+	parent.End()
+	require.NoError(t, err)
+
+	exported := spans()
+	require.Len(t, exported, 2)
+	call := exported[0]
+	assert.Equal(t, "DynamoDB.GetItem", call.Name)
+	assert.Equal(t, exported[1].SpanContext.SpanID, call.Parent.SpanID)
+}
+
+func TestWrapEvent(t *testing.T) {
+	spans := setupTest(t)
+	ctx := context.WithValue(context.Background(), lambdaTraceID, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	relay := WrapEvent("relayOutbox", func(ctx context.Context, event events.CloudWatchEvent) (int, error) {
+		_, span := Start(ctx, "service.Outbox.Relay")
+		span.End()
+		return 0, errors.New("outbox unavailable")
+	})
+
+	_, err := relay(ctx, events.CloudWatchEvent{})
+	require.Error(t, err)
+
+	exported := spans()
+	require.Len(t, exported, 2)
+	child, consumer := exported[0], exported[1]
+	assert.Equal(t, "relayOutbox", consumer.Name)
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", consumer.SpanContext.TraceID)
+	assert.Equal(t, "53995c3f42cd8ad8", consumer.Parent.SpanID)
+	assert.Equal(t, consumer.SpanContext.SpanID, child.Parent.SpanID)
+	assert.Equal(t, "Error", consumer.Status.Code)
+}
+
+func TestNewOTLPProvider(t *testing.T) {
+	received := make(chan string, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.URL.Path
+	}))
+	defer collector.Close()
+	provider, err := NewOTLPProvider(context.Background(), "iris-test", otlptracehttp.WithEndpointURL(collector.URL))
+	require.NoError(t, err)
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var traceID string
+	relay := WrapEvent("relayOutbox", func(ctx context.Context, event events.CloudWatchEvent) (int, error) {
+		traceID = trace.SpanContextFromContext(ctx).TraceID().String()
+		return 0, nil
+	})
+	_, err = relay(context.Background(), events.CloudWatchEvent{})
+	require.NoError(t, err)
+
+	// WrapEvent vacía el lote antes de devolver
+	select {
+	case path := <-received:
+		assert.Equal(t, "/v1/traces", path)
+	default:
+		t.Fatal("spans were not exported before returning")
+	}
+	// Los primeros 8 dígitos del ID de X-Ray son el epoch de la traza
+	epoch, err := strconv.ParseInt(traceID[:8], 16, 64)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), time.Unix(epoch, 0), time.Minute)
+}